@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"strings"
+	"sync"
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/modules"
@@ -130,6 +131,14 @@ type API struct {
 	tpool    modules.TransactionPool
 	wallet   modules.Wallet
 
+	// namedWallets are the wallets that can be selected with the 'wallet'
+	// query string parameter of wallet calls. namedWalletAPIs holds an API
+	// for each named wallet that has been selected.
+	namedWallets     modules.NamedWallets
+	namedWalletAPIs  map[string]*API
+	requiredPassword string
+
+	mu     sync.Mutex
 	router http.Handler
 }
 
@@ -141,7 +150,7 @@ func (api *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 // New creates a new Sia API from the provided modules.  The API will require
 // authentication using HTTP basic auth for certain endpoints of the supplied
 // password is not the empty string.  Usernames are ignored for authentication.
-func New(requiredUserAgent string, requiredPassword string, cs modules.ConsensusSet, e modules.Explorer, g modules.Gateway, h modules.Host, m modules.Miner, r modules.Renter, tp modules.TransactionPool, w modules.Wallet) *API {
+func New(requiredUserAgent string, requiredPassword string, cs modules.ConsensusSet, e modules.Explorer, g modules.Gateway, h modules.Host, m modules.Miner, r modules.Renter, tp modules.TransactionPool, w modules.Wallet, nw modules.NamedWallets) *API {
 	api := &API{
 		cs:       cs,
 		explorer: e,
@@ -151,6 +160,10 @@ func New(requiredUserAgent string, requiredPassword string, cs modules.Consensus
 		renter:   r,
 		tpool:    tp,
 		wallet:   w,
+
+		namedWallets:     nw,
+		namedWalletAPIs:  make(map[string]*API),
+		requiredPassword: requiredPassword,
 	}
 
 	// Register API handlers
@@ -162,6 +175,12 @@ func New(requiredUserAgent string, requiredPassword string, cs modules.Consensus
 	if api.cs != nil {
 		router.GET("/consensus", api.consensusHandler)
 		router.POST("/consensus/validate/transactionset", api.consensusValidateTransactionsetHandler)
+		router.GET("/consensus/statehash/:height", RequirePassword(api.consensusStateHashHandler, requiredPassword))
+		router.POST("/consensus/verify", RequirePassword(api.consensusVerifyHandler, requiredPassword))
+		router.GET("/consensus/badblocks", api.consensusBadBlocksHandler)
+		router.POST("/consensus/badblocks/clear", RequirePassword(api.consensusBadBlocksClearHandler, requiredPassword))
+		router.GET("/consensus/stats", api.consensusStatsHandler)
+		router.GET("/consensus/deployments", api.consensusDeploymentsHandler)
 	}
 
 	// Explorer API Calls
@@ -207,10 +226,26 @@ func New(requiredUserAgent string, requiredPassword string, cs modules.Consensus
 	if api.renter != nil {
 		router.GET("/renter", api.renterHandlerGET)
 		router.POST("/renter", RequirePassword(api.renterHandlerPOST, requiredPassword))
+		router.GET("/renter/backup", RequirePassword(api.renterBackupHandler, requiredPassword))
+		router.GET("/renter/classes", api.renterClassesHandlerGET)
+		router.POST("/renter/classes/:name", RequirePassword(api.renterClassesHandlerPOST, requiredPassword))
 		router.GET("/renter/contracts", api.renterContractsHandler)
 		router.GET("/renter/downloads", api.renterDownloadsHandler)
+		router.GET("/renter/estimate", api.renterEstimateHandler)
+		router.GET("/renter/events", api.renterEventsHandler)
 		router.GET("/renter/files", api.renterFilesHandler)
+		router.GET("/renter/forecast", api.renterForecastHandler)
+		router.GET("/renter/keys", RequirePassword(api.renterKeysHandler, requiredPassword))
+		router.POST("/renter/pause", RequirePassword(api.renterPauseHandler, requiredPassword))
 		router.GET("/renter/prices", api.renterPricesHandler)
+		router.POST("/renter/restore", RequirePassword(api.renterRestoreHandler, requiredPassword))
+		router.POST("/renter/resume", RequirePassword(api.renterResumeHandler, requiredPassword))
+		router.GET("/renter/snapshots", api.renterSnapshotsHandlerGET)
+		router.POST("/renter/snapshots", RequirePassword(api.renterSnapshotsHandlerPOST, requiredPassword))
+		router.POST("/renter/snapshots/recover", RequirePassword(api.renterSnapshotsRecoverHandler, requiredPassword))
+		router.GET("/renter/spending", api.renterSpendingHandler)
+		router.GET("/renter/uploads", api.renterUploadsHandler)
+		router.POST("/renter/uploads/:id", RequirePassword(api.renterUploadsHandlerPOST, requiredPassword))
 
 		// TODO: re-enable these routes once the new .sia format has been
 		// standardized and implemented.
@@ -219,15 +254,25 @@ func New(requiredUserAgent string, requiredPassword string, cs modules.Consensus
 		// router.GET("/renter/share", RequirePassword(api.renterShareHandler, requiredPassword))
 		// router.GET("/renter/shareascii", RequirePassword(api.renterShareAsciiHandler, requiredPassword))
 
+		router.POST("/renter/class/*siapath", RequirePassword(api.renterClassHandler, requiredPassword))
 		router.POST("/renter/delete/*siapath", RequirePassword(api.renterDeleteHandler, requiredPassword))
+		router.GET("/renter/dir/*siapath", api.renterDirHandlerGET)
+		router.POST("/renter/dir/*siapath", RequirePassword(api.renterDirHandlerPOST, requiredPassword))
 		router.GET("/renter/download/*siapath", RequirePassword(api.renterDownloadHandler, requiredPassword))
 		router.GET("/renter/downloadasync/*siapath", RequirePassword(api.renterDownloadAsyncHandler, requiredPassword))
+		router.GET("/renter/link/:link", RequirePassword(api.renterLinkHandler, requiredPassword))
 		router.POST("/renter/rename/*siapath", RequirePassword(api.renterRenameHandler, requiredPassword))
+		router.GET("/renter/sharelink/*siapath", RequirePassword(api.renterShareLinkHandler, requiredPassword))
+		router.GET("/renter/stream/*siapath", RequirePassword(api.renterStreamHandler, requiredPassword))
 		router.POST("/renter/upload/*siapath", RequirePassword(api.renterUploadHandler, requiredPassword))
+		router.POST("/renter/uploadstream/*siapath", RequirePassword(api.renterUploadStreamHandler, requiredPassword))
+		router.POST("/renter/verify/*siapath", RequirePassword(api.renterVerifyHandler, requiredPassword))
 
 		// HostDB endpoints.
 		router.GET("/hostdb/active", api.hostdbActiveHandler)
 		router.GET("/hostdb/all", api.hostdbAllHandler)
+		router.GET("/hostdb/filter", api.hostdbFilterHandlerGET)
+		router.POST("/hostdb/filter", RequirePassword(api.hostdbFilterHandlerPOST, requiredPassword))
 		router.GET("/hostdb/hosts/:pubkey", api.hostdbHostsHandler)
 	}
 
@@ -243,33 +288,88 @@ func New(requiredUserAgent string, requiredPassword string, cs modules.Consensus
 
 	// Wallet API Calls
 	if api.wallet != nil {
-		router.GET("/wallet", api.walletHandler)
-		router.POST("/wallet/033x", RequirePassword(api.wallet033xHandler, requiredPassword))
-		router.GET("/wallet/address", RequirePassword(api.walletAddressHandler, requiredPassword))
-		router.GET("/wallet/addresses", api.walletAddressesHandler)
-		router.GET("/wallet/backup", RequirePassword(api.walletBackupHandler, requiredPassword))
-		router.POST("/wallet/init", RequirePassword(api.walletInitHandler, requiredPassword))
-		router.POST("/wallet/init/seed", RequirePassword(api.walletInitSeedHandler, requiredPassword))
-		router.POST("/wallet/lock", RequirePassword(api.walletLockHandler, requiredPassword))
-		router.POST("/wallet/seed", RequirePassword(api.walletSeedHandler, requiredPassword))
-		router.GET("/wallet/seeds", RequirePassword(api.walletSeedsHandler, requiredPassword))
-		router.POST("/wallet/siacoins", RequirePassword(api.walletSiacoinsHandler, requiredPassword))
-		router.POST("/wallet/siafunds", RequirePassword(api.walletSiafundsHandler, requiredPassword))
-		router.POST("/wallet/siagkey", RequirePassword(api.walletSiagkeyHandler, requiredPassword))
-		router.POST("/wallet/sweep/seed", RequirePassword(api.walletSweepSeedHandler, requiredPassword))
-		router.GET("/wallet/transaction/:id", api.walletTransactionHandler)
-		router.GET("/wallet/transactions", api.walletTransactionsHandler)
-		router.GET("/wallet/transactions/:addr", api.walletTransactionsAddrHandler)
-		router.GET("/wallet/verify/address/:addr", api.walletVerifyAddressHandler)
-		router.POST("/wallet/unlock", RequirePassword(api.walletUnlockHandler, requiredPassword))
-		router.POST("/wallet/changepassword", RequirePassword(api.walletChangePasswordHandler, requiredPassword))
+		api.buildWalletRoutes(router, requiredPassword)
+	}
+	if api.namedWallets != nil {
+		router.GET("/wallets", api.walletsHandler)
+		router.POST("/wallets/create", RequirePassword(api.walletsCreateHandler, requiredPassword))
 	}
 
 	// Apply UserAgent middleware and return the API
-	api.router = RequireUserAgent(router, requiredUserAgent)
+	api.router = RequireUserAgent(api.namedWalletRouter(router), requiredUserAgent)
 	return api
 }
 
+// buildWalletRoutes registers the wallet API calls of api with router.
+func (api *API) buildWalletRoutes(router *httprouter.Router, requiredPassword string) {
+	router.GET("/wallet", api.walletHandler)
+	router.POST("/wallet/033x", RequirePassword(api.wallet033xHandler, requiredPassword))
+	router.GET("/wallet/address", RequirePassword(api.walletAddressHandler, requiredPassword))
+	router.GET("/wallet/addresses", api.walletAddressesHandler)
+	router.GET("/wallet/addressbook", api.walletAddressBookHandlerGET)
+	router.POST("/wallet/addressbook", RequirePassword(api.walletAddressBookHandlerPOST, requiredPassword))
+	router.GET("/wallet/autolock", api.walletAutoLockHandlerGET)
+	router.POST("/wallet/autolock", RequirePassword(api.walletAutoLockHandlerPOST, requiredPassword))
+	router.GET("/wallet/autolock/events", api.walletAutoLockEventsHandler)
+	router.GET("/wallet/balance", api.walletBalanceHandler)
+	router.GET("/wallet/history", api.walletHistoryHandler)
+	router.GET("/wallet/backup", RequirePassword(api.walletBackupHandler, requiredPassword))
+	router.POST("/wallet/build", RequirePassword(api.walletBuildHandler, requiredPassword))
+	router.POST("/wallet/broadcast", RequirePassword(api.walletBroadcastHandler, requiredPassword))
+	router.GET("/wallet/bumpfee", api.walletBumpFeeHandlerGET)
+	router.POST("/wallet/bumpfee", RequirePassword(api.walletBumpFeeHandlerPOST, requiredPassword))
+	router.GET("/wallet/defrag", api.walletDefragHandlerGET)
+	router.POST("/wallet/defrag", RequirePassword(api.walletDefragHandlerPOST, requiredPassword))
+	router.POST("/wallet/defragment", RequirePassword(api.walletDefragmentHandler, requiredPassword))
+	router.GET("/wallet/fee", api.walletFeeHandler)
+	router.GET("/wallet/gaplimit", api.walletGapLimitHandlerGET)
+	router.POST("/wallet/gaplimit", RequirePassword(api.walletGapLimitHandlerPOST, requiredPassword))
+	router.POST("/wallet/init", RequirePassword(api.walletInitHandler, requiredPassword))
+	router.POST("/wallet/init/seed", RequirePassword(api.walletInitSeedHandler, requiredPassword))
+	router.POST("/wallet/recover", RequirePassword(api.walletRecoverHandler, requiredPassword))
+	router.POST("/wallet/rescan", RequirePassword(api.walletRescanHandler, requiredPassword))
+	router.POST("/wallet/restore", RequirePassword(api.walletRestoreHandler, requiredPassword))
+	router.GET("/wallet/labels", api.walletLabelsHandlerGET)
+	router.POST("/wallet/labels", RequirePassword(api.walletLabelsHandlerPOST, requiredPassword))
+	router.POST("/wallet/lock", RequirePassword(api.walletLockHandler, requiredPassword))
+	router.GET("/wallet/memos", api.walletMemosHandlerGET)
+	router.POST("/wallet/memos", RequirePassword(api.walletMemosHandlerPOST, requiredPassword))
+	router.POST("/wallet/message/sign", RequirePassword(api.walletMessageSignHandler, requiredPassword))
+	router.POST("/wallet/message/verify", api.walletMessageVerifyHandler)
+	router.POST("/wallet/multisig/address", RequirePassword(api.walletMultisigAddressHandler, requiredPassword))
+	router.POST("/wallet/multisig/merge", api.walletMultisigMergeHandler)
+	router.GET("/wallet/payments", api.walletPaymentsHandler)
+	router.GET("/wallet/scheduled", api.walletScheduledHandlerGET)
+	router.POST("/wallet/scheduled", RequirePassword(api.walletScheduledHandlerPOST, requiredPassword))
+	router.POST("/wallet/seed", RequirePassword(api.walletSeedHandler, requiredPassword))
+	router.GET("/wallet/seeds", RequirePassword(api.walletSeedsHandler, requiredPassword))
+	router.POST("/wallet/siacoins", RequirePassword(api.walletSiacoinsHandler, requiredPassword))
+	router.POST("/wallet/siafunds", RequirePassword(api.walletSiafundsHandler, requiredPassword))
+	router.POST("/wallet/siagkey", RequirePassword(api.walletSiagkeyHandler, requiredPassword))
+	router.GET("/wallet/signers", api.walletSignersHandler)
+	router.POST("/wallet/sign", RequirePassword(api.walletSignHandler, requiredPassword))
+	router.GET("/wallet/swaps", RequirePassword(api.walletSwapsHandler, requiredPassword))
+	router.POST("/wallet/swap/audit", RequirePassword(api.walletSwapAuditHandler, requiredPassword))
+	router.POST("/wallet/swap/create", RequirePassword(api.walletSwapCreateHandler, requiredPassword))
+	router.POST("/wallet/swap/redeem", RequirePassword(api.walletSwapRedeemHandler, requiredPassword))
+	router.POST("/wallet/sweep/seed", RequirePassword(api.walletSweepSeedHandler, requiredPassword))
+	router.GET("/wallet/templates", api.walletTemplatesHandlerGET)
+	router.POST("/wallet/templates", RequirePassword(api.walletTemplatesHandlerPOST, requiredPassword))
+	router.POST("/wallet/templates/address", RequirePassword(api.walletTemplatesAddressHandler, requiredPassword))
+	router.GET("/wallet/timelocked", api.walletTimelockedHandler)
+	router.POST("/wallet/timelocked/address", RequirePassword(api.walletTimelockedAddressHandler, requiredPassword))
+	router.GET("/wallet/transaction/:id", api.walletTransactionHandler)
+	router.GET("/wallet/transactions", api.walletTransactionsHandler)
+	router.GET("/wallet/transactions/:addr", api.walletTransactionsAddrHandler)
+	router.GET("/wallet/verify/address/:addr", api.walletVerifyAddressHandler)
+	router.POST("/wallet/unlock", RequirePassword(api.walletUnlockHandler, requiredPassword))
+	router.GET("/wallet/unspent", api.walletUnspentHandler)
+	router.POST("/wallet/unsignedtransaction", RequirePassword(api.walletUnsignedTransactionHandler, requiredPassword))
+	router.GET("/wallet/watch", api.walletWatchHandlerGET)
+	router.POST("/wallet/watch", RequirePassword(api.walletWatchHandlerPOST, requiredPassword))
+	router.POST("/wallet/changepassword", RequirePassword(api.walletChangePasswordHandler, requiredPassword))
+}
+
 // UnrecognizedCallHandler handles calls to unknown pages (404).
 func UnrecognizedCallHandler(w http.ResponseWriter, req *http.Request) {
 	WriteError(w, Error{"404 - Refer to API.md"}, http.StatusNotFound)
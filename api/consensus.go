@@ -2,8 +2,12 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"time"
 
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
 
 	"github.com/julienschmidt/httprouter"
@@ -17,6 +21,41 @@ type ConsensusGET struct {
 	CurrentBlock types.BlockID     `json:"currentblock"`
 	Target       types.Target      `json:"target"`
 	Difficulty   types.Currency    `json:"difficulty"`
+
+	MedianTimestamp types.Timestamp `json:"mediantimestamp"`
+	ClockSkew       int64           `json:"clockskew"`
+}
+
+// ConsensusBadBlocksGET contains the ids of the blocks that failed expensive
+// validation.
+type ConsensusBadBlocksGET struct {
+	BadBlocks []types.BlockID `json:"badblocks"`
+}
+
+// ConsensusDeploymentsGET contains the version bits parameters and the state
+// of every known deployment.
+type ConsensusDeploymentsGET struct {
+	Window      types.BlockHeight          `json:"window"`
+	Threshold   uint64                     `json:"threshold"`
+	Deployments []modules.DeploymentStatus `json:"deployments"`
+}
+
+// ConsensusStatsGET contains aggregate statistics about the consensus state.
+type ConsensusStatsGET struct {
+	modules.ConsensusStats
+}
+
+// ConsensusStateHashGET contains the state hash of the consensus set at a
+// block height.
+type ConsensusStateHashGET struct {
+	Height    types.BlockHeight `json:"height"`
+	BlockID   types.BlockID     `json:"blockid"`
+	StateHash crypto.Hash       `json:"statehash"`
+}
+
+// ConsensusVerifyPOST contains the result of verifying the consensus database.
+type ConsensusVerifyPOST struct {
+	Height types.BlockHeight `json:"height"`
 }
 
 // consensusHandler handles the API calls to /consensus.
@@ -29,6 +68,9 @@ func (api *API) consensusHandler(w http.ResponseWriter, req *http.Request, _ htt
 		CurrentBlock: cbid,
 		Target:       currentTarget,
 		Difficulty:   currentTarget.Difficulty(),
+
+		MedianTimestamp: api.cs.MedianTimestamp(),
+		ClockSkew:       int64(api.cs.ClockSkew() / time.Second),
 	})
 }
 
@@ -48,3 +90,75 @@ func (api *API) consensusValidateTransactionsetHandler(w http.ResponseWriter, re
 	}
 	WriteSuccess(w)
 }
+
+// consensusStateHashHandler handles the API calls to
+// /consensus/statehash/:height.
+func (api *API) consensusStateHashHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	var height types.BlockHeight
+	_, err := fmt.Sscan(ps.ByName("height"), &height)
+	if err != nil {
+		WriteError(w, Error{"could not parse height: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	id, hash, err := api.cs.StateHashAtHeight(height)
+	if err != nil {
+		WriteError(w, Error{"could not compute state hash: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, ConsensusStateHashGET{
+		Height:    height,
+		BlockID:   id,
+		StateHash: hash,
+	})
+}
+
+// consensusVerifyHandler handles the API calls to /consensus/verify.
+func (api *API) consensusVerifyHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	height, err := api.cs.VerifyChain()
+	if err != nil {
+		WriteError(w, Error{"consensus verification failed: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	WriteJSON(w, ConsensusVerifyPOST{
+		Height: height,
+	})
+}
+
+// consensusBadBlocksHandler handles the API calls to /consensus/badblocks.
+func (api *API) consensusBadBlocksHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	WriteJSON(w, ConsensusBadBlocksGET{
+		BadBlocks: api.cs.BadBlocks(),
+	})
+}
+
+// consensusDeploymentsHandler handles the API calls to /consensus/deployments.
+func (api *API) consensusDeploymentsHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	deployments, err := api.cs.Deployments()
+	if err != nil {
+		WriteError(w, Error{"could not get deployments: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	WriteJSON(w, ConsensusDeploymentsGET{
+		Window:      types.VersionBitsWindow,
+		Threshold:   types.VersionBitsThreshold,
+		Deployments: deployments,
+	})
+}
+
+// consensusStatsHandler handles the API calls to /consensus/stats.
+func (api *API) consensusStatsHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	WriteJSON(w, ConsensusStatsGET{
+		ConsensusStats: api.cs.Stats(),
+	})
+}
+
+// consensusBadBlocksClearHandler handles the API calls to
+// /consensus/badblocks/clear.
+func (api *API) consensusBadBlocksClearHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	err := api.cs.ClearBadBlocks()
+	if err != nil {
+		WriteError(w, Error{"could not clear bad blocks: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	WriteSuccess(w)
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/url"
 	"testing"
 
@@ -109,3 +110,133 @@ func TestConsensusValidateTransactionSet(t *testing.T) {
 		t.Fatal("expected validation error")
 	}
 }
+
+// TestIntegrationConsensusStateHash probes the GET call to
+// /consensus/statehash/:height.
+func TestIntegrationConsensusStateHash(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	var csh ConsensusStateHashGET
+	err = st.getAPI(fmt.Sprintf("/consensus/statehash/%v", st.cs.Height()), &csh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if csh.BlockID != st.cs.CurrentBlock().ID() {
+		t.Error("wrong block returned in state hash call")
+	}
+	if csh.StateHash != st.cs.StateHash() {
+		t.Error("wrong state hash returned in state hash call")
+	}
+	err = st.getAPI(fmt.Sprintf("/consensus/statehash/%v", st.cs.Height()+1), &csh)
+	if err == nil {
+		t.Error("state hash call succeeded for a height above the current height")
+	}
+}
+
+// TestIntegrationConsensusVerify probes the POST call to /consensus/verify.
+func TestIntegrationConsensusVerify(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	var cvp ConsensusVerifyPOST
+	err = st.postAPI("/consensus/verify", nil, &cvp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cvp.Height != st.server.api.cs.Height() {
+		t.Error("wrong height returned in consensus verify call")
+	}
+}
+
+// TestIntegrationConsensusBadBlocks probes the /consensus/badblocks calls.
+func TestIntegrationConsensusBadBlocks(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	var cbbg ConsensusBadBlocksGET
+	err = st.getAPI("/consensus/badblocks", &cbbg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cbbg.BadBlocks) != 0 {
+		t.Error("new consensus set has bad blocks")
+	}
+	err = st.stdPostAPI("/consensus/badblocks/clear", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestIntegrationConsensusStats probes the /consensus/stats endpoint.
+func TestIntegrationConsensusStats(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	var csg ConsensusStatsGET
+	err = st.getAPI("/consensus/stats", &csg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if csg.SiacoinOutputs == 0 || csg.SiafundOutputs == 0 {
+		t.Error("stats are missing the outputs of the server tester:", csg)
+	}
+	stats := st.cs.Stats()
+	if csg.SiacoinOutputs != stats.SiacoinOutputs || !csg.LockedCoins.Equals(stats.LockedCoins) {
+		t.Error("stats do not match the consensus set")
+	}
+}
+
+// TestIntegrationConsensusDeployments probes the /consensus/deployments
+// endpoint.
+func TestIntegrationConsensusDeployments(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	var cdg ConsensusDeploymentsGET
+	err = st.getAPI("/consensus/deployments", &cdg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cdg.Window != types.VersionBitsWindow || cdg.Threshold != types.VersionBitsThreshold {
+		t.Error("wrong version bits parameters:", cdg.Window, cdg.Threshold)
+	}
+	if len(cdg.Deployments) != len(types.Deployments) {
+		t.Error("wrong number of deployments:", len(cdg.Deployments))
+	}
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
@@ -29,6 +30,14 @@ type (
 		Hosts []ExtendedHostDBEntry `json:"hosts"`
 	}
 
+	// HostdbFilterGET contains the filter that restricts the hosts used by
+	// the renter. Hosts are given by their public key strings.
+	HostdbFilterGET struct {
+		Mode      string   `json:"mode"`
+		Hosts     []string `json:"hosts"`
+		NetRanges []string `json:"netranges"`
+	}
+
 	// HostdbHostsGET lists detailed statistics for a particular host, selected
 	// by pubkey.
 	HostdbHostsGET struct {
@@ -114,3 +123,53 @@ func (api *API) hostdbHostsHandler(w http.ResponseWriter, req *http.Request, ps
 		ScoreBreakdown: breakdown,
 	})
 }
+
+// hostdbFilterHandlerGET handles the API call asking for the filter that
+// restricts the hosts used by the renter.
+func (api *API) hostdbFilterHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	filter := api.renter.HostFilter()
+	hosts := []string{}
+	for _, spk := range filter.Hosts {
+		hosts = append(hosts, spk.String())
+	}
+	netRanges := filter.NetRanges
+	if netRanges == nil {
+		netRanges = []string{}
+	}
+	WriteJSON(w, HostdbFilterGET{
+		Mode:      filter.Mode,
+		Hosts:     hosts,
+		NetRanges: netRanges,
+	})
+}
+
+// hostdbFilterHandlerPOST handles the API call to set the filter that
+// restricts the hosts used by the renter. Hosts and net ranges are given as
+// comma-separated lists.
+func (api *API) hostdbFilterHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	filter := modules.HostFilter{
+		Mode: req.FormValue("mode"),
+	}
+	for _, s := range strings.Split(req.FormValue("hosts"), ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		var spk types.SiaPublicKey
+		spk.LoadString(s)
+		if len(spk.Key) == 0 {
+			WriteError(w, Error{"error when calling /hostdb/filter: could not parse host public key " + s}, http.StatusBadRequest)
+			return
+		}
+		filter.Hosts = append(filter.Hosts, spk)
+	}
+	for _, s := range strings.Split(req.FormValue("netranges"), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			filter.NetRanges = append(filter.NetRanges, s)
+		}
+	}
+	if err := api.renter.SetHostFilter(filter); err != nil {
+		WriteError(w, Error{"error when calling /hostdb/filter: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
@@ -1291,3 +1291,51 @@ func TestHostDBAndRenterRenewDynamicIPs(t *testing.T) {
 		t.Fatal("data mismatch when downloading a file")
 	}
 }
+
+// TestHostDBFilter checks that the host filter can be set and read through
+// the API.
+func TestHostDBFilter(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	var hf HostdbFilterGET
+	if err := st.getAPI("/hostdb/filter", &hf); err != nil {
+		t.Fatal(err)
+	}
+	if hf.Mode != modules.HostFilterModeDisable || len(hf.Hosts) != 0 || len(hf.NetRanges) != 0 {
+		t.Fatal("expected a disabled filter, got", hf)
+	}
+
+	spk := st.host.PublicKey()
+	err = st.stdPostAPI("/hostdb/filter", url.Values{
+		"mode":      {"blacklist"},
+		"hosts":     {spk.String()},
+		"netranges": {"10.0.0.0/8, 192.168.0.0/16"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.getAPI("/hostdb/filter", &hf); err != nil {
+		t.Fatal(err)
+	}
+	if hf.Mode != modules.HostFilterModeBlacklist || len(hf.Hosts) != 1 || hf.Hosts[0] != spk.String() || len(hf.NetRanges) != 2 {
+		t.Fatal("filter was not set:", hf)
+	}
+
+	// Invalid filters are rejected.
+	if err := st.stdPostAPI("/hostdb/filter", url.Values{"mode": {"foo"}}); err == nil {
+		t.Fatal("invalid mode was accepted")
+	}
+	if err := st.stdPostAPI("/hostdb/filter", url.Values{"mode": {"blacklist"}, "hosts": {"foo"}}); err == nil {
+		t.Fatal("invalid host was accepted")
+	}
+	if err := st.stdPostAPI("/hostdb/filter", url.Values{"mode": {"whitelist"}}); err == nil {
+		t.Fatal("empty whitelist was accepted")
+	}
+}
@@ -4,14 +4,22 @@ package api
 // zeroing them out.
 
 import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/internal/httpstream"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/modules/renter"
 	"github.com/NebulousLabs/Sia/types"
@@ -66,12 +74,18 @@ var (
 	}).(types.BlockHeight)
 )
 
+// transferEventQueueSize is the number of events of /renter/events that are
+// queued for a client before further events are dropped.
+const transferEventQueueSize = 256
+
 type (
 	// RenterGET contains various renter metrics.
 	RenterGET struct {
-		Settings         modules.RenterSettings `json:"settings"`
-		FinancialMetrics RenterFinancialMetrics `json:"financialmetrics"`
-		CurrentPeriod    types.BlockHeight      `json:"currentperiod"`
+		Settings         modules.RenterSettings       `json:"settings"`
+		FinancialMetrics RenterFinancialMetrics       `json:"financialmetrics"`
+		CurrentPeriod    types.BlockHeight            `json:"currentperiod"`
+		DownloadCache    modules.DownloadCacheMetrics `json:"downloadcache"`
+		Paused           bool                         `json:"paused"`
 	}
 
 	// RenterFinancialMetrics contains metrics about how much the Renter has
@@ -120,6 +134,11 @@ type (
 		UploadSpending types.Currency `json:"uploadspending"`
 	}
 
+	// RenterClasses lists the redundancy classes of the renter.
+	RenterClasses struct {
+		Classes []modules.RedundancyClass `json:"classes"`
+	}
+
 	// RenterContracts contains the renter's contracts.
 	RenterContracts struct {
 		Contracts []RenterContract `json:"contracts"`
@@ -130,11 +149,42 @@ type (
 		Downloads []DownloadInfo `json:"downloads"`
 	}
 
+	// RenterUploadQueue contains the renter's upload queue.
+	RenterUploadQueue struct {
+		Uploads []modules.UploadInfo `json:"uploads"`
+	}
+
+	// RenterDirectory lists the contents of a directory of the renter.
+	RenterDirectory struct {
+		Directory   modules.DirectoryInfo   `json:"directory"`
+		Directories []modules.DirectoryInfo `json:"directories"`
+		Files       []modules.FileInfo      `json:"files"`
+	}
+
 	// RenterFiles lists the files known to the renter.
 	RenterFiles struct {
 		Files []modules.FileInfo `json:"files"`
 	}
 
+	// RenterEstimateGET lists the data that is returned when a GET call is
+	// made to /renter/estimate.
+	RenterEstimateGET struct {
+		modules.RenterCostEstimate
+	}
+
+	// RenterFileKey contains the hex-encoded key of a file.
+	RenterFileKey struct {
+		SiaPath string `json:"siapath"`
+		Key     string `json:"key"`
+	}
+
+	// RenterKeys contains the hex-encoded keys that the renter encrypts files
+	// with.
+	RenterKeys struct {
+		MasterKey string          `json:"masterkey"`
+		Files     []RenterFileKey `json:"files"`
+	}
+
 	// RenterLoad lists files that were loaded into the renter.
 	RenterLoad struct {
 		FilesAdded []string `json:"filesadded"`
@@ -146,19 +196,43 @@ type (
 		modules.RenterPriceEstimation
 	}
 
+	// RenterSnapshots lists the snapshots of the renter that are stored on
+	// its hosts, most recent first.
+	RenterSnapshots struct {
+		Snapshots []modules.RenterSnapshot `json:"snapshots"`
+	}
+
 	// RenterShareASCII contains an ASCII-encoded .sia file.
 	RenterShareASCII struct {
 		ASCIIsia string `json:"asciisia"`
 	}
 
+	// RenterShareLink contains a share link, from which other renters can
+	// download a file.
+	RenterShareLink struct {
+		Link string `json:"link"`
+	}
+
 	// DownloadInfo contains all client-facing information of a file.
 	DownloadInfo struct {
-		SiaPath     string    `json:"siapath"`
-		Destination string    `json:"destination"`
-		Filesize    uint64    `json:"filesize"`
-		Received    uint64    `json:"received"`
-		StartTime   time.Time `json:"starttime"`
-		Error       string    `json:"error"`
+		ID          uint64                     `json:"id"`
+		SiaPath     string                     `json:"siapath"`
+		Destination string                     `json:"destination"`
+		Filesize    uint64                     `json:"filesize"`
+		Received    uint64                     `json:"received"`
+		StartTime   time.Time                  `json:"starttime"`
+		Complete    bool                       `json:"complete"`
+		Speed       uint64                     `json:"speed"`
+		ETA         uint64                     `json:"eta"`
+		Hosts       []modules.TransferHostInfo `json:"hosts"`
+		Error       string                     `json:"error"`
+	}
+
+	// RenterTransferEvent is an event of the stream of /renter/events.
+	// Exactly one of Download and Upload is set.
+	RenterTransferEvent struct {
+		Download *DownloadInfo       `json:"download,omitempty"`
+		Upload   *modules.UploadInfo `json:"upload,omitempty"`
 	}
 )
 
@@ -192,11 +266,51 @@ func (api *API) renterHandlerGET(w http.ResponseWriter, req *http.Request, _ htt
 		Settings:         settings,
 		FinancialMetrics: fm,
 		CurrentPeriod:    periodStart,
+		DownloadCache:    api.renter.DownloadCacheMetrics(),
+		Paused:           api.renter.Paused(),
 	})
 }
 
 // renterHandlerPOST handles the API call to set the Renter's settings.
 func (api *API) renterHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	// Scan the rate limits. (optional parameters) Limits that are not
+	// supplied keep their current value.
+	settings := api.renter.Settings()
+	speeds := []struct {
+		param string
+		speed *int64
+	}{
+		{"maxdownloadspeed", &settings.RateLimits.MaxDownloadSpeed},
+		{"maxuploadspeed", &settings.RateLimits.MaxUploadSpeed},
+		{"maxhostdownloadspeed", &settings.RateLimits.MaxHostDownloadSpeed},
+		{"maxhostuploadspeed", &settings.RateLimits.MaxHostUploadSpeed},
+	}
+	var limitsSupplied bool
+	for _, s := range speeds {
+		if req.FormValue(s.param) == "" {
+			continue
+		}
+		_, err := fmt.Sscan(req.FormValue(s.param), s.speed)
+		if err != nil {
+			WriteError(w, Error{"unable to parse " + s.param + ": " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		limitsSupplied = true
+	}
+
+	// If only the rate limits are supplied, leave the allowance untouched.
+	_, fundsSupplied := req.Form["funds"]
+	_, periodSupplied := req.Form["period"]
+	if limitsSupplied && !fundsSupplied && !periodSupplied {
+		err := api.renter.SetRateLimits(settings.RateLimits)
+		if err != nil {
+			WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+			return
+		}
+		WriteSuccess(w)
+		return
+	}
+
 	// Scan the allowance amount.
 	funds, ok := scanAmount(req.FormValue("funds"))
 	if !ok {
@@ -245,14 +359,13 @@ func (api *API) renterHandlerPOST(w http.ResponseWriter, req *http.Request, _ ht
 	}
 
 	// Set the settings in the renter.
-	err = api.renter.SetSettings(modules.RenterSettings{
-		Allowance: modules.Allowance{
-			Funds:       funds,
-			Hosts:       hosts,
-			Period:      period,
-			RenewWindow: renewWindow,
-		},
-	})
+	settings.Allowance = modules.Allowance{
+		Funds:       funds,
+		Hosts:       hosts,
+		Period:      period,
+		RenewWindow: renewWindow,
+	}
+	err = api.renter.SetSettings(settings)
 	if err != nil {
 		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
 		return
@@ -285,18 +398,28 @@ func (api *API) renterContractsHandler(w http.ResponseWriter, _ *http.Request, _
 	})
 }
 
+// downloadInfo converts a modules.DownloadInfo to a DownloadInfo.
+func downloadInfo(d modules.DownloadInfo) DownloadInfo {
+	return DownloadInfo{
+		ID:          d.ID,
+		SiaPath:     d.SiaPath,
+		Destination: d.Destination.Destination(),
+		Filesize:    d.Filesize,
+		StartTime:   d.StartTime,
+		Received:    d.Received,
+		Complete:    d.Complete,
+		Speed:       d.Speed,
+		ETA:         d.ETA,
+		Hosts:       d.Hosts,
+		Error:       d.Error,
+	}
+}
+
 // renterDownloadsHandler handles the API call to request the download queue.
 func (api *API) renterDownloadsHandler(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
 	var downloads []DownloadInfo
 	for _, d := range api.renter.DownloadQueue() {
-		downloads = append(downloads, DownloadInfo{
-			SiaPath:     d.SiaPath,
-			Destination: d.Destination.Destination(),
-			Filesize:    d.Filesize,
-			StartTime:   d.StartTime,
-			Received:    d.Received,
-			Error:       d.Error,
-		})
+		downloads = append(downloads, downloadInfo(d))
 	}
 	// sort the downloads by newest first
 	sort.Slice(downloads, func(i, j int) bool { return downloads[i].StartTime.After(downloads[j].StartTime) })
@@ -305,6 +428,95 @@ func (api *API) renterDownloadsHandler(w http.ResponseWriter, _ *http.Request, _
 	})
 }
 
+// renterUploadsHandler handles the API call to request the upload queue.
+func (api *API) renterUploadsHandler(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	WriteJSON(w, RenterUploadQueue{
+		Uploads: api.renter.UploadQueue(),
+	})
+}
+
+// renterUploadsHandlerPOST handles the API call to pause, resume, cancel or
+// set the priority of an upload.
+func (api *API) renterUploadsHandlerPOST(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	id, err := strconv.ParseUint(ps.ByName("id"), 10, 64)
+	if err != nil {
+		WriteError(w, Error{"error when calling /renter/uploads: unable to parse id: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	switch action := req.FormValue("action"); action {
+	case "pause":
+		err = api.renter.PauseUpload(id)
+	case "resume":
+		err = api.renter.ResumeUpload(id)
+	case "cancel":
+		err = api.renter.CancelUpload(id)
+	case "priority":
+		var priority int
+		if _, err = fmt.Sscan(req.FormValue("priority"), &priority); err != nil {
+			err = errors.New("unable to read parameter 'priority': " + err.Error())
+			break
+		}
+		err = api.renter.SetUploadPriority(id, priority)
+	default:
+		err = errors.New("unknown action: " + action)
+	}
+	if err != nil {
+		WriteError(w, Error{"error when calling /renter/uploads: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// transferEventQueue is a transfer subscriber that queues the events for a
+// client of /renter/events. Events are dropped while the queue is full, so
+// that a slow client does not hold up the transfers.
+type transferEventQueue chan modules.TransferEvent
+
+// ProcessTransferEvent implements modules.TransferSubscriber.
+func (q transferEventQueue) ProcessTransferEvent(event modules.TransferEvent) {
+	select {
+	case q <- event:
+	default:
+	}
+}
+
+// renterEventsHandler handles the API call to stream the progress of uploads
+// and downloads. The events are written as a sequence of JSON objects until
+// the client closes the connection.
+func (api *API) renterEventsHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		WriteError(w, Error{"error when calling /renter/events: streaming is not supported"}, http.StatusInternalServerError)
+		return
+	}
+	events := make(transferEventQueue, transferEventQueueSize)
+	api.renter.TransferSubscribe(events)
+	defer api.renter.TransferUnsubscribe(events)
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	enc := json.NewEncoder(w)
+	for {
+		var event modules.TransferEvent
+		select {
+		case event = <-events:
+		case <-req.Context().Done():
+			return
+		}
+		var e RenterTransferEvent
+		if event.Download != nil {
+			di := downloadInfo(*event.Download)
+			e.Download = &di
+		}
+		e.Upload = event.Upload
+		if err := enc.Encode(e); err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
+
 // renterLoadHandler handles the API call to load a '.sia' file.
 func (api *API) renterLoadHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
 	source := req.FormValue("source")
@@ -346,6 +558,91 @@ func (api *API) renterRenameHandler(w http.ResponseWriter, req *http.Request, ps
 	WriteSuccess(w)
 }
 
+// renterDirHandlerGET handles the API call to list the contents of a
+// directory.
+func (api *API) renterDirHandlerGET(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	dir, dirs, files, err := api.renter.DirList(strings.Trim(ps.ByName("siapath"), "/"))
+	if err != nil {
+		WriteError(w, Error{"error when calling /renter/dir: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, RenterDirectory{
+		Directory:   dir,
+		Directories: dirs,
+		Files:       files,
+	})
+}
+
+// renterDirHandlerPOST handles the API call to create, delete or rename a
+// directory.
+func (api *API) renterDirHandlerPOST(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	siapath := strings.Trim(ps.ByName("siapath"), "/")
+	var err error
+	switch action := req.FormValue("action"); action {
+	case "create":
+		err = api.renter.CreateDir(siapath)
+	case "delete":
+		err = api.renter.DeleteDir(siapath)
+	case "rename":
+		err = api.renter.RenameDir(siapath, strings.Trim(req.FormValue("newsiapath"), "/"))
+	default:
+		err = errors.New("unknown action: " + action)
+	}
+	if err != nil {
+		WriteError(w, Error{"error when calling /renter/dir: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// renterClassesHandlerGET handles the API call to list the redundancy
+// classes of the renter.
+func (api *API) renterClassesHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	WriteJSON(w, RenterClasses{Classes: api.renter.RedundancyClasses()})
+}
+
+// renterClassesHandlerPOST handles the API call to define, change or delete a
+// redundancy class.
+func (api *API) renterClassesHandlerPOST(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	name := ps.ByName("name")
+	var err error
+	switch action := req.FormValue("action"); action {
+	case "set":
+		var dataPieces, parityPieces int
+		if _, err = fmt.Sscan(req.FormValue("datapieces"), &dataPieces); err != nil {
+			err = errors.New("unable to read parameter 'datapieces': " + err.Error())
+		} else if _, err = fmt.Sscan(req.FormValue("paritypieces"), &parityPieces); err != nil {
+			err = errors.New("unable to read parameter 'paritypieces': " + err.Error())
+		} else if err = checkRedundancy(dataPieces, parityPieces); err == nil {
+			err = api.renter.SetRedundancyClass(modules.RedundancyClass{
+				Name:         name,
+				DataPieces:   dataPieces,
+				ParityPieces: parityPieces,
+			})
+		}
+	case "delete":
+		err = api.renter.DeleteRedundancyClass(name)
+	default:
+		err = errors.New("unknown action: " + action)
+	}
+	if err != nil {
+		WriteError(w, Error{"error when calling /renter/classes: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// renterClassHandler handles the API call to assign a file to a redundancy
+// class.
+func (api *API) renterClassHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	err := api.renter.SetFileClass(strings.TrimPrefix(ps.ByName("siapath"), "/"), req.FormValue("class"))
+	if err != nil {
+		WriteError(w, Error{"error when calling /renter/class: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
 // renterFilesHandler handles the API call to list all of the files.
 func (api *API) renterFilesHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
 	WriteJSON(w, RenterFiles{
@@ -353,6 +650,151 @@ func (api *API) renterFilesHandler(w http.ResponseWriter, req *http.Request, _ h
 	})
 }
 
+// renterForecastHandler handles the API call to report the utilization of
+// the renter's contracts and forecast when their funds run out.
+func (api *API) renterForecastHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	WriteJSON(w, api.renter.Forecast())
+}
+
+// renterEstimateHandler handles the API call to estimate the cost of uploading
+// and storing data.
+func (api *API) renterEstimateHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var size uint64
+	if _, err := fmt.Sscan(req.FormValue("size"), &size); err != nil {
+		WriteError(w, Error{"unable to parse size: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	var redundancy float64
+	if _, err := fmt.Sscan(req.FormValue("redundancy"), &redundancy); err != nil {
+		WriteError(w, Error{"unable to parse redundancy: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	var duration types.BlockHeight
+	if _, err := fmt.Sscan(req.FormValue("duration"), &duration); err != nil {
+		WriteError(w, Error{"unable to parse duration: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	est, err := api.renter.EstimateCost(size, redundancy, duration)
+	if err != nil {
+		WriteError(w, Error{"error when calling /renter/estimate: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, RenterEstimateGET{
+		RenterCostEstimate: est,
+	})
+}
+
+// renterKeysHandler handles the API call to export the keys that the renter
+// encrypts files with.
+func (api *API) renterKeysHandler(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	keys, err := api.renter.ExportKeys()
+	if err != nil {
+		WriteError(w, Error{"error when calling /renter/keys: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	files := []RenterFileKey{}
+	for siapath, key := range keys.FileKeys {
+		files = append(files, RenterFileKey{
+			SiaPath: siapath,
+			Key:     hex.EncodeToString(key[:]),
+		})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].SiaPath < files[j].SiaPath
+	})
+	WriteJSON(w, RenterKeys{
+		MasterKey: hex.EncodeToString(keys.MasterKey[:]),
+		Files:     files,
+	})
+}
+
+// renterBackupHandler handles the API call to write a backup of the renter to
+// a file.
+func (api *API) renterBackupHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	destination := req.FormValue("destination")
+	if !filepath.IsAbs(destination) {
+		WriteError(w, Error{"error when calling /renter/backup: destination must be an absolute path"}, http.StatusBadRequest)
+		return
+	}
+	if err := api.renter.CreateBackup(destination); err != nil {
+		WriteError(w, Error{"error when calling /renter/backup: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// renterPauseHandler handles the API call to pause all background activity of
+// the renter.
+func (api *API) renterPauseHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	err := api.renter.Pause()
+	if err != nil {
+		WriteError(w, Error{"error when calling /renter/pause: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// renterResumeHandler handles the API call to resume the background activity
+// of the renter.
+func (api *API) renterResumeHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	err := api.renter.Resume()
+	if err != nil {
+		WriteError(w, Error{"error when calling /renter/resume: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// renterRestoreHandler handles the API call to restore a backup of the
+// renter.
+func (api *API) renterRestoreHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	source := req.FormValue("source")
+	if !filepath.IsAbs(source) {
+		WriteError(w, Error{"error when calling /renter/restore: source must be an absolute path"}, http.StatusBadRequest)
+		return
+	}
+	files, err := api.renter.RestoreBackup(source)
+	if err != nil {
+		WriteError(w, Error{"error when calling /renter/restore: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, RenterLoad{FilesAdded: files})
+}
+
+// renterSnapshotsHandlerGET handles the API call to list the snapshots of the
+// renter.
+func (api *API) renterSnapshotsHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	WriteJSON(w, RenterSnapshots{Snapshots: api.renter.Snapshots()})
+}
+
+// renterSnapshotsHandlerPOST handles the API call to store a snapshot of the
+// renter on its hosts.
+func (api *API) renterSnapshotsHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	snapshot, err := api.renter.CreateSnapshot()
+	if err != nil {
+		WriteError(w, Error{"error when calling /renter/snapshots: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, snapshot)
+}
+
+// renterSnapshotsRecoverHandler handles the API call to restore the most
+// recent snapshot of the renter.
+func (api *API) renterSnapshotsRecoverHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	files, err := api.renter.RecoverSnapshot()
+	if err != nil {
+		WriteError(w, Error{"error when calling /renter/snapshots/recover: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, RenterLoad{FilesAdded: files})
+}
+
+// renterSpendingHandler handles the API call to report the spending of the
+// renter's contracts, and the share of it that is attributed to each file.
+func (api *API) renterSpendingHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	WriteJSON(w, api.renter.SpendingReport())
+}
+
 // renterPricesHandler reports the expected costs of various actions given the
 // renter settings and the set of available hosts.
 func (api *API) renterPricesHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
@@ -420,6 +862,102 @@ func (api *API) renterDownloadAsyncHandler(w http.ResponseWriter, req *http.Requ
 	api.renterDownloadHandler(w, req, ps)
 }
 
+// renterStreamHandler handles the API call to stream a file. A single byte
+// range may be requested with the Range header, so that media players can
+// seek within a file without downloading all of it.
+func (api *API) renterStreamHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	siapath := strings.TrimPrefix(ps.ByName("siapath"), "/")
+	var file modules.FileInfo
+	var exists bool
+	for _, fi := range api.renter.FileList() {
+		if fi.SiaPath == siapath {
+			file, exists = fi, true
+			break
+		}
+	}
+	if !exists {
+		WriteError(w, Error{"error when calling /renter/stream: no file with that path"}, http.StatusBadRequest)
+		return
+	}
+	serveStream(w, req, "/renter/stream", siapath, file.Filesize, func(offset, length uint64, sw io.Writer) error {
+		return api.renter.DownloadSection(siapath, offset, length, sw)
+	})
+}
+
+// serveStream writes the byte range requested by req of the file at siapath
+// to w, downloading it with download. route is used in error messages.
+func serveStream(w http.ResponseWriter, req *http.Request, route, siapath string, filesize uint64, download func(offset, length uint64, w io.Writer) error) {
+	offset, length, partial, err := httpstream.ParseRange(req.Header.Get("Range"), filesize)
+	if err != nil {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", filesize))
+		WriteError(w, Error{"error when calling " + route + ": " + err.Error()}, http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+	contentType := mime.TypeByExtension(filepath.Ext(siapath))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	sw := httpstream.NewWriter(w)
+	sw.Header().Set("Accept-Ranges", "bytes")
+	sw.Header().Set("Content-Type", contentType)
+	sw.Header().Set("Content-Length", strconv.FormatUint(length, 10))
+	if partial {
+		sw.SetStatus(http.StatusPartialContent)
+		sw.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, offset+length-1, filesize))
+	}
+	if length == 0 {
+		sw.Write(nil)
+		return
+	}
+
+	err = download(offset, length, sw)
+	if err != nil && !sw.Started() {
+		WriteError(w, Error{"error when calling " + route + ": " + err.Error()}, http.StatusInternalServerError)
+	}
+	// A download that fails after the stream has started can only be
+	// reported by ending the response early, which the client detects from
+	// the Content-Length header.
+}
+
+// renterShareLinkHandler handles the API call to mint a share link for a
+// file.
+func (api *API) renterShareLinkHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	link, err := api.renter.ShareLink(strings.TrimPrefix(ps.ByName("siapath"), "/"))
+	if err != nil {
+		WriteError(w, Error{"error when calling /renter/sharelink: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, RenterShareLink{
+		Link: link,
+	})
+}
+
+// renterVerifyHandler handles the API call to challenge the hosts of a file to
+// prove that they still store its pieces.
+func (api *API) renterVerifyHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	verification, err := api.renter.Verify(strings.TrimPrefix(ps.ByName("siapath"), "/"))
+	if err != nil {
+		WriteError(w, Error{"error when calling /renter/verify: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, verification)
+}
+
+// renterLinkHandler handles the API call to download the file that a share
+// link points to. Like /renter/stream, a single byte range may be requested
+// with the Range header.
+func (api *API) renterLinkHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	link := ps.ByName("link")
+	info, err := api.renter.ShareLinkInfo(link)
+	if err != nil {
+		WriteError(w, Error{"error when calling /renter/link: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	serveStream(w, req, "/renter/link", info.SiaPath, info.Filesize, func(offset, length uint64, sw io.Writer) error {
+		return api.renter.DownloadShareLink(link, offset, length, sw)
+	})
+}
+
 // parseDownloadParameters parses the download parameters passed to the
 // /renter/download endpoint. Validation of these parameters is done by the
 // renter.
@@ -512,65 +1050,132 @@ func (api *API) renterShareAsciiHandler(w http.ResponseWriter, req *http.Request
 	})
 }
 
-// renterUploadHandler handles the API call to upload a file.
-func (api *API) renterUploadHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
-	source := req.FormValue("source")
-	if !filepath.IsAbs(source) {
-		WriteError(w, Error{"source must be an absolute path"}, http.StatusBadRequest)
-		return
+// checkRedundancy checks that an erasure coding layout has the required
+// number of parity pieces and the required redundancy.
+func checkRedundancy(dataPieces, parityPieces int) error {
+	if parityPieces < requiredParityPieces {
+		return fmt.Errorf("a minimum of %v parity pieces is required, but %v parity pieces requested", parityPieces, requiredParityPieces)
+	}
+	redundancy := float64(dataPieces+parityPieces) / float64(dataPieces)
+	if float64(dataPieces+parityPieces)/float64(dataPieces) < requiredRedundancy {
+		return fmt.Errorf("a redundancy of %.2f is required, but redundancy of %.2f supplied", redundancy, requiredRedundancy)
+	}
+	return nil
+}
+
+// parseUploadParams parses the optional redundancy class, erasure coding,
+// piece size and priority parameters of an upload, reading each parameter
+// with get.
+func parseUploadParams(get func(string) string) (modules.FileUploadParams, error) {
+	// A redundancy class determines the erasure coding of the file.
+	if get("class") != "" && (get("datapieces") != "" || get("paritypieces") != "") {
+		return modules.FileUploadParams{}, errors.New("cannot provide the class parameter together with erasure coding parameters")
 	}
 
 	// Check whether the erasure coding parameters have been supplied.
 	var ec modules.ErasureCoder
-	if req.FormValue("datapieces") != "" || req.FormValue("paritypieces") != "" {
+	if get("datapieces") != "" || get("paritypieces") != "" {
 		// Check that both values have been supplied.
-		if req.FormValue("datapieces") == "" || req.FormValue("paritypieces") == "" {
-			WriteError(w, Error{"must provide both the datapieces paramaeter and the paritypieces parameter if specifying erasure coding parameters"}, http.StatusBadRequest)
-			return
+		if get("datapieces") == "" || get("paritypieces") == "" {
+			return modules.FileUploadParams{}, errors.New("must provide both the datapieces paramaeter and the paritypieces parameter if specifying erasure coding parameters")
 		}
 
 		// Parse the erasure coding parameters.
 		var dataPieces, parityPieces int
-		_, err := fmt.Sscan(req.FormValue("datapieces"), &dataPieces)
+		_, err := fmt.Sscan(get("datapieces"), &dataPieces)
 		if err != nil {
-			WriteError(w, Error{"unable to read parameter 'datapieces': " + err.Error()}, http.StatusBadRequest)
-			return
+			return modules.FileUploadParams{}, errors.New("unable to read parameter 'datapieces': " + err.Error())
 		}
-		_, err = fmt.Sscan(req.FormValue("paritypieces"), &parityPieces)
+		_, err = fmt.Sscan(get("paritypieces"), &parityPieces)
 		if err != nil {
-			WriteError(w, Error{"unable to read parameter 'paritypieces': " + err.Error()}, http.StatusBadRequest)
-			return
+			return modules.FileUploadParams{}, errors.New("unable to read parameter 'paritypieces': " + err.Error())
 		}
 
 		// Verify that sane values for parityPieces and redundancy are being
 		// supplied.
-		if parityPieces < requiredParityPieces {
-			WriteError(w, Error{fmt.Sprintf("a minimum of %v parity pieces is required, but %v parity pieces requested", parityPieces, requiredParityPieces)}, http.StatusBadRequest)
-			return
-		}
-		redundancy := float64(dataPieces+parityPieces) / float64(dataPieces)
-		if float64(dataPieces+parityPieces)/float64(dataPieces) < requiredRedundancy {
-			WriteError(w, Error{fmt.Sprintf("a redundancy of %.2f is required, but redundancy of %.2f supplied", redundancy, requiredRedundancy)}, http.StatusBadRequest)
-			return
+		if err := checkRedundancy(dataPieces, parityPieces); err != nil {
+			return modules.FileUploadParams{}, err
 		}
 
 		// Create the erasure coder.
 		ec, err = renter.NewRSCode(dataPieces, parityPieces)
 		if err != nil {
-			WriteError(w, Error{"unable to encode file using the provided parameters: " + err.Error()}, http.StatusBadRequest)
-			return
+			return modules.FileUploadParams{}, errors.New("unable to encode file using the provided parameters: " + err.Error())
 		}
 	}
 
-	// Call the renter to upload the file.
-	err := api.renter.Upload(modules.FileUploadParams{
-		Source:      source,
-		SiaPath:     strings.TrimPrefix(ps.ByName("siapath"), "/"),
+	// Parse the optional piece size.
+	var pieceSize uint64
+	if get("piecesize") != "" {
+		_, err := fmt.Sscan(get("piecesize"), &pieceSize)
+		if err != nil || pieceSize == 0 {
+			return modules.FileUploadParams{}, errors.New("unable to read parameter 'piecesize'")
+		}
+	}
+
+	// Parse the optional priority.
+	var priority int
+	if get("priority") != "" {
+		if _, err := fmt.Sscan(get("priority"), &priority); err != nil {
+			return modules.FileUploadParams{}, errors.New("unable to read parameter 'priority': " + err.Error())
+		}
+	}
+	return modules.FileUploadParams{
+		Class:       get("class"),
 		ErasureCode: ec,
-	})
+		PieceSize:   pieceSize,
+		Priority:    priority,
+	}, nil
+}
+
+// renterUploadHandler handles the API call to upload a file.
+func (api *API) renterUploadHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	source := req.FormValue("source")
+	if !filepath.IsAbs(source) {
+		WriteError(w, Error{"source must be an absolute path"}, http.StatusBadRequest)
+		return
+	}
+
+	up, err := parseUploadParams(req.FormValue)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	// Call the renter to upload the file, or the files of the directory. The
+	// include and exclude patterns may be given several times.
+	up.Source = source
+	up.SiaPath = strings.TrimPrefix(ps.ByName("siapath"), "/")
+	up.Include = req.Form["include"]
+	up.Exclude = req.Form["exclude"]
+	if finfo, statErr := os.Stat(source); statErr == nil && finfo.IsDir() {
+		up.SiaPath = strings.TrimSuffix(up.SiaPath, "/")
+		err = api.renter.UploadDirectory(up)
+	} else {
+		err = api.renter.Upload(up)
+	}
 	if err != nil {
 		WriteError(w, Error{"upload failed: " + err.Error()}, http.StatusInternalServerError)
 		return
 	}
 	WriteSuccess(w)
 }
+
+// renterUploadStreamHandler handles the API call to upload the body of the
+// request as a file, without the data being written to the disk of siad.
+// The upload parameters are read from the query string, as the body is the
+// data of the file.
+func (api *API) renterUploadStreamHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	up, err := parseUploadParams(req.URL.Query().Get)
+	if err != nil {
+		WriteError(w, Error{"error when calling /renter/uploadstream: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	up.SiaPath = strings.TrimPrefix(ps.ByName("siapath"), "/")
+	err = api.renter.UploadStreamFromReader(up, req.Body)
+	if err != nil {
+		WriteError(w, Error{"error when calling /renter/uploadstream: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	WriteSuccess(w)
+}
@@ -2,10 +2,12 @@ package api
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -492,6 +494,201 @@ func TestRenterAsyncDownload(t *testing.T) {
 	}
 }
 
+// TestRenterStream checks that /renter/stream serves whole files and the
+// byte ranges requested by the Range header.
+func TestRenterStream(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	size := int(modules.SectorSize * 2)
+	st, path := setupTestDownload(t, size, "stream.dat", true)
+	defer func() {
+		st.server.panicClose()
+		os.Remove(path)
+	}()
+	orig, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stream := func(rangeHeader string) (*http.Response, []byte) {
+		req, err := http.NewRequest("GET", "http://"+st.server.listener.Addr().String()+"/renter/stream/stream.dat", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("User-Agent", "Sia-Agent")
+		if rangeHeader != "" {
+			req.Header.Set("Range", rangeHeader)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp, body
+	}
+
+	resp, body := stream("")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Accept-Ranges") != "bytes" || !bytes.Equal(body, orig) {
+		t.Fatal("whole file was not streamed:", resp.Status, len(body))
+	}
+	// Request a range spanning both chunks of the file.
+	first, last := size/2-100, size/2+99
+	resp, body = stream(fmt.Sprintf("bytes=%d-%d", first, last))
+	if resp.StatusCode != http.StatusPartialContent || !bytes.Equal(body, orig[first:last+1]) {
+		t.Fatal("range was not streamed:", resp.Status, len(body))
+	}
+	if cr := resp.Header.Get("Content-Range"); cr != fmt.Sprintf("bytes %d-%d/%d", first, last, size) {
+		t.Fatal("wrong Content-Range:", cr)
+	}
+	resp, body = stream("bytes=-50")
+	if resp.StatusCode != http.StatusPartialContent || !bytes.Equal(body, orig[size-50:]) {
+		t.Fatal("suffix range was not streamed:", resp.Status, len(body))
+	}
+	resp, _ = stream(fmt.Sprintf("bytes=%d-", size))
+	if resp.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+		t.Fatal("expected an unsatisfiable range, got", resp.Status)
+	}
+}
+
+// TestRenterUploadStream uploads the body of a request as a file, and checks
+// that the file can be downloaded.
+func TestRenterUploadStream(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	st, path := setupTestDownload(t, int(modules.SectorSize), "first.dat", true)
+	defer func() {
+		st.server.panicClose()
+		os.Remove(path)
+	}()
+
+	// Upload more than two chunks, the last of which is partial.
+	data := fastrand.Bytes(int(modules.SectorSize*2) + 100)
+	req, err := http.NewRequest("POST", "http://"+st.server.listener.Addr().String()+"/renter/uploadstream/streamed.dat?datapieces=1&paritypieces=1", bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("User-Agent", "Sia-Agent")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatal("upload failed:", resp.Status)
+	}
+
+	var rf RenterFiles
+	if err := st.getAPI("/renter/files", &rf); err != nil {
+		t.Fatal(err)
+	}
+	var file modules.FileInfo
+	for _, fi := range rf.Files {
+		if fi.SiaPath == "streamed.dat" {
+			file = fi
+		}
+	}
+	if file.Filesize != uint64(len(data)) || !file.Available {
+		t.Fatal("streamed file is wrong:", file)
+	}
+
+	resp, err = HttpGET("http://" + st.server.listener.Addr().String() + "/renter/download/streamed.dat?httpresp=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	downloaded, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(downloaded, data) {
+		t.Fatal("downloaded data does not match the streamed data")
+	}
+}
+
+// TestRenterDirAPI probes the /renter/dir endpoints and the upload of a
+// directory.
+func TestRenterDirAPI(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	st, path := setupTestDownload(t, 1024, "top.dat", false)
+	defer func() {
+		st.server.panicClose()
+		os.Remove(path)
+	}()
+
+	// Upload a local directory with a subdirectory.
+	source := filepath.Join(st.dir, "source")
+	if err := os.MkdirAll(filepath.Join(source, "sub"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"one.dat", filepath.Join("sub", "two.dat")} {
+		if err := createRandFile(filepath.Join(source, name), 1024); err != nil {
+			t.Fatal(err)
+		}
+	}
+	uploadValues := url.Values{}
+	uploadValues.Set("source", source)
+	uploadValues.Set("datapieces", "1")
+	uploadValues.Set("paritypieces", "1")
+	if err := st.stdPostAPI("/renter/upload/up", uploadValues); err != nil {
+		t.Fatal(err)
+	}
+
+	var rd RenterDirectory
+	if err := st.getAPI("/renter/dir/", &rd); err != nil {
+		t.Fatal(err)
+	}
+	if rd.Directory.NumFiles != 3 || rd.Directory.Size != 3*1024 {
+		t.Fatal("wrong root directory:", rd.Directory)
+	}
+	if len(rd.Directories) != 1 || rd.Directories[0].SiaPath != "up" || len(rd.Files) != 1 {
+		t.Fatal("wrong root directory contents:", rd.Directories, rd.Files)
+	}
+	if err := st.getAPI("/renter/dir/up/sub", &rd); err != nil {
+		t.Fatal(err)
+	}
+	if len(rd.Files) != 1 || rd.Files[0].SiaPath != "up/sub/two.dat" {
+		t.Fatal("wrong files in subdirectory:", rd.Files)
+	}
+
+	// Create, rename and delete directories.
+	if err := st.stdPostAPI("/renter/dir/up/empty", url.Values{"action": {"create"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.stdPostAPI("/renter/dir/up", url.Values{"action": {"rename"}, "newsiapath": {"moved"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.getAPI("/renter/dir/moved", &rd); err != nil {
+		t.Fatal(err)
+	}
+	if len(rd.Directories) != 2 || rd.Directories[0].SiaPath != "moved/empty" || rd.Directory.NumFiles != 2 {
+		t.Fatal("directory was not renamed:", rd)
+	}
+	if err := st.stdPostAPI("/renter/dir/moved", url.Values{"action": {"delete"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.getAPI("/renter/dir/moved", &rd); err == nil {
+		t.Fatal("deleted directory was listed")
+	}
+	var rf RenterFiles
+	if err := st.getAPI("/renter/files", &rf); err != nil {
+		t.Fatal(err)
+	}
+	if len(rf.Files) != 1 || rf.Files[0].SiaPath != "top.dat" {
+		t.Fatal("files of the deleted directory remain:", rf.Files)
+	}
+	if err := st.stdPostAPI("/renter/dir/top", url.Values{"action": {"move"}}); err == nil {
+		t.Fatal("unknown action was accepted")
+	}
+}
+
 // TestRenterPaths tests that the /renter routes handle path parameters
 // properly.
 func TestRenterPaths(t *testing.T) {
@@ -1504,3 +1701,789 @@ func TestRenterPricesHandlerPricey(t *testing.T) {
 		t.Error("price did not drop from single to multi")
 	}
 }
+
+// TestRenterRateLimits checks that the rate limits of the renter can be set
+// through the API without changing the allowance, and that setting the
+// allowance keeps the rate limits.
+func TestRenterRateLimits(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+	if err := st.announceHost(); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.acceptContracts(); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.setHostStorage(); err != nil {
+		t.Fatal(err)
+	}
+
+	allowanceValues := url.Values{}
+	allowanceValues.Set("funds", testFunds)
+	allowanceValues.Set("period", testPeriod)
+	if err = st.stdPostAPI("/renter", allowanceValues); err != nil {
+		t.Fatal(err)
+	}
+
+	// Set some of the rate limits.
+	limitValues := url.Values{}
+	limitValues.Set("maxdownloadspeed", "1000000")
+	limitValues.Set("maxhostuploadspeed", "200000")
+	if err = st.stdPostAPI("/renter", limitValues); err != nil {
+		t.Fatal(err)
+	}
+	var get RenterGET
+	if err = st.getAPI("/renter", &get); err != nil {
+		t.Fatal(err)
+	}
+	expected := modules.RateLimits{MaxDownloadSpeed: 1e6, MaxHostUploadSpeed: 2e5}
+	if get.Settings.RateLimits != expected {
+		t.Fatal("rate limits were not set:", get.Settings.RateLimits)
+	}
+	if fmt.Sprint(get.Settings.Allowance.Period) != testPeriod || get.Settings.Allowance.Funds.IsZero() {
+		t.Fatal("allowance was changed:", get.Settings.Allowance)
+	}
+
+	// Limits that are not supplied keep their value.
+	limitValues = url.Values{}
+	limitValues.Set("maxuploadspeed", "300000")
+	if err = st.stdPostAPI("/renter", limitValues); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.stdPostAPI("/renter", allowanceValues); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.getAPI("/renter", &get); err != nil {
+		t.Fatal(err)
+	}
+	expected.MaxUploadSpeed = 3e5
+	if get.Settings.RateLimits != expected {
+		t.Fatal("rate limits were not kept:", get.Settings.RateLimits)
+	}
+
+	// Invalid limits are rejected.
+	limitValues.Set("maxuploadspeed", "-1")
+	if err = st.stdPostAPI("/renter", limitValues); err == nil {
+		t.Fatal("expected negative limit to be rejected")
+	}
+	limitValues.Set("maxuploadspeed", "fast")
+	if err = st.stdPostAPI("/renter", limitValues); err == nil || !strings.HasPrefix(err.Error(), "unable to parse maxuploadspeed") {
+		t.Fatal("expected invalid limit to be rejected, got", err)
+	}
+}
+
+// TestRenterKeys checks that /renter/keys exports the master key and the key
+// of the uploaded file, and that the file can still be downloaded.
+func TestRenterKeys(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	st, path := setupTestDownload(t, 1e4, "test.dat", true)
+	defer func() {
+		st.server.panicClose()
+		os.Remove(path)
+	}()
+
+	var keys RenterKeys
+	if err := st.getAPI("/renter/keys", &keys); err != nil {
+		t.Fatal(err)
+	}
+	if len(keys.MasterKey) != 64 {
+		t.Fatal("expected a hex-encoded master key, got", keys.MasterKey)
+	}
+	if len(keys.Files) != 1 || keys.Files[0].SiaPath != "test.dat" || len(keys.Files[0].Key) != 64 {
+		t.Fatal("expected the key of test.dat, got", keys.Files)
+	}
+	if keys.Files[0].Key == keys.MasterKey {
+		t.Fatal("file key is the master key")
+	}
+
+	// The pieces on the host are encrypted, but the download is not.
+	downpath := filepath.Join(st.dir, "testdown.dat")
+	if err := st.stdGetAPI("/renter/download/test.dat?destination=" + downpath); err != nil {
+		t.Fatal(err)
+	}
+	orig, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	download, err := ioutil.ReadFile(downpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(orig, download) {
+		t.Fatal("data mismatch when downloading a file")
+	}
+}
+
+// TestRenterEstimateHandler checks that /renter/estimate validates its
+// parameters, and that the estimated costs scale with the size of the data.
+func TestRenterEstimateHandler(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	// Without hosts, no estimate can be made.
+	var est RenterEstimateGET
+	if err = st.getAPI("/renter/estimate?size=1000000&redundancy=3&duration=1000", &est); err == nil {
+		t.Fatal("expected an error when there are no hosts")
+	}
+
+	if err = st.announceHost(); err != nil {
+		t.Fatal(err)
+	}
+	for _, query := range []string{
+		"redundancy=3&duration=1000",
+		"size=1000000&duration=1000",
+		"size=1000000&redundancy=3",
+		"size=1000000&redundancy=-1&duration=1000",
+	} {
+		if err = st.getAPI("/renter/estimate?"+query, &est); err == nil {
+			t.Fatal("expected an error for", query)
+		}
+	}
+
+	if err = st.getAPI("/renter/estimate?size=1000000&redundancy=3&duration=1000", &est); err != nil {
+		t.Fatal(err)
+	}
+	if est.Storage.IsZero() || est.Upload.IsZero() || est.ContractFees.IsZero() {
+		t.Fatal("expected nonzero costs:", est)
+	}
+	if !est.Total.Equals(est.Storage.Add(est.Upload).Add(est.ContractFees)) {
+		t.Fatal("total is not the sum of the costs")
+	}
+	var est2 RenterEstimateGET
+	if err = st.getAPI("/renter/estimate?size=2000000&redundancy=3&duration=1000", &est2); err != nil {
+		t.Fatal(err)
+	}
+	if !est2.Storage.Equals(est.Storage.Mul64(2)) || !est2.Upload.Equals(est.Upload.Mul64(2)) {
+		t.Fatal("costs did not double with the size:", est, est2)
+	}
+}
+
+// TestRenterBackup checks that a file deleted from the renter can be
+// downloaded again after restoring a backup.
+func TestRenterBackup(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	st, path := setupTestDownload(t, 1e4, "test.dat", true)
+	defer func() {
+		st.server.panicClose()
+		os.Remove(path)
+	}()
+
+	// The destination must be absolute.
+	backupAbsoluteError := "error when calling /renter/backup: destination must be an absolute path"
+	if err := st.stdGetAPI("/renter/backup?destination=renter.backup"); err == nil || err.Error() != backupAbsoluteError {
+		t.Fatal(err)
+	}
+	backup := filepath.Join(st.dir, "renter.backup")
+	if err := st.stdGetAPI("/renter/backup?destination=" + backup); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := st.stdPostAPI("/renter/delete/test.dat", url.Values{}); err != nil {
+		t.Fatal(err)
+	}
+	var rl RenterLoad
+	if err := st.postAPI("/renter/restore", url.Values{"source": {backup}}, &rl); err != nil {
+		t.Fatal(err)
+	}
+	if len(rl.FilesAdded) != 1 || rl.FilesAdded[0] != "test.dat" {
+		t.Fatal("expected test.dat to be restored, got", rl.FilesAdded)
+	}
+
+	downpath := filepath.Join(st.dir, "testdown.dat")
+	if err := st.stdGetAPI("/renter/download/test.dat?destination=" + downpath); err != nil {
+		t.Fatal(err)
+	}
+	orig, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	download, err := ioutil.ReadFile(downpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(orig, download) {
+		t.Fatal("data mismatch when downloading a restored file")
+	}
+}
+
+// TestRenterShareLink checks that a file can be downloaded from its share
+// link, even after it has been deleted from the renter.
+func TestRenterShareLink(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	st, path := setupTestDownload(t, 1e4, "share.txt", true)
+	defer func() {
+		st.server.panicClose()
+		os.Remove(path)
+	}()
+	orig, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rsl RenterShareLink
+	if err := st.getAPI("/renter/sharelink/share.txt", &rsl); err != nil {
+		t.Fatal(err)
+	}
+	if rsl.Link == "" || url.PathEscape(rsl.Link) != rsl.Link {
+		t.Fatal("share link is not URL-safe:", rsl.Link)
+	}
+	if err := st.getAPI("/renter/sharelink/nonexistent", &rsl); err == nil {
+		t.Fatal("minted a share link for a nonexistent file")
+	}
+	if err := st.stdPostAPI("/renter/delete/share.txt", url.Values{}); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(link string) (*http.Response, []byte) {
+		req, err := http.NewRequest("GET", "http://"+st.server.listener.Addr().String()+"/renter/link/"+link, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("User-Agent", "Sia-Agent")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp, body
+	}
+	resp, body := get(rsl.Link)
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(body, orig) {
+		t.Fatal("file was not served from its share link:", resp.Status, len(body))
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Fatal("wrong Content-Type:", ct)
+	}
+	if resp, _ = get("foo"); resp.StatusCode != http.StatusBadRequest {
+		t.Fatal("expected an invalid link to be rejected, got", resp.Status)
+	}
+}
+
+// TestRenterTransferProgress checks that the progress of uploads and
+// downloads is listed by /renter/uploads and /renter/downloads, and streamed
+// by /renter/events.
+func TestRenterTransferProgress(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	st, path := setupTestDownload(t, 1024, "progress.dat", true)
+	defer func() {
+		st.server.panicClose()
+		os.Remove(path)
+	}()
+
+	var ruq RenterUploadQueue
+	if err := st.getAPI("/renter/uploads", &ruq); err != nil {
+		t.Fatal(err)
+	}
+	if len(ruq.Uploads) != 1 {
+		t.Fatal("expected 1 upload, got", len(ruq.Uploads))
+	}
+	up := ruq.Uploads[0]
+	if up.SiaPath != "progress.dat" || up.Source != path || up.Filesize != 1024 || up.Uploaded == 0 || up.Total < up.Uploaded {
+		t.Fatal("wrong upload info:", up)
+	}
+	if len(up.Hosts) != 1 || up.Hosts[0].Pieces == 0 {
+		t.Fatal("upload has wrong host status:", up.Hosts)
+	}
+
+	// Follow the events while the file is downloaded.
+	resp, err := HttpGET("http://" + st.server.listener.Addr().String() + "/renter/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatal("could not stream events:", resp.Status)
+	}
+	downloadPath := filepath.Join(st.dir, "progress.dat")
+	if err := st.stdGetAPI("/renter/download/progress.dat?destination=" + downloadPath); err != nil {
+		t.Fatal(err)
+	}
+	dec := json.NewDecoder(resp.Body)
+	var last DownloadInfo
+	for !last.Complete {
+		var event RenterTransferEvent
+		if err := dec.Decode(&event); err != nil {
+			t.Fatal(err)
+		}
+		if event.Download == nil {
+			t.Fatal("expected a download event:", event)
+		}
+		last = *event.Download
+	}
+	if last.ID == 0 || last.SiaPath != "progress.dat" || last.Received != 1024 || last.Error != "" {
+		t.Fatal("wrong download event:", last)
+	}
+
+	var rdq RenterDownloadQueue
+	if err := st.getAPI("/renter/downloads", &rdq); err != nil {
+		t.Fatal(err)
+	}
+	if len(rdq.Downloads) != 1 {
+		t.Fatal("expected 1 download, got", len(rdq.Downloads))
+	}
+	dl := rdq.Downloads[0]
+	if dl.ID != last.ID || !dl.Complete || dl.Speed != 0 || dl.ETA != 0 {
+		t.Fatal("wrong download info:", dl)
+	}
+	if len(dl.Hosts) != 1 || dl.Hosts[0].Pieces != 1 || dl.Hosts[0].Failures != 0 {
+		t.Fatal("download has wrong host status:", dl.Hosts)
+	}
+}
+
+// TestRenterDownloadCache checks that a file that is downloaded a second time
+// is served from the download cache instead of the host.
+func TestRenterDownloadCache(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	st, path := setupTestDownload(t, 1024, "cache.dat", true)
+	defer func() {
+		st.server.panicClose()
+		os.Remove(path)
+	}()
+	orig, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rg RenterGET
+	for i := 0; i < 2; i++ {
+		downloadPath := filepath.Join(st.dir, fmt.Sprintf("cache%v.dat", i))
+		if err := st.stdGetAPI("/renter/download/cache.dat?destination=" + downloadPath); err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadFile(downloadPath)
+		if err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(data, orig) {
+			t.Fatal("downloaded file does not match the original")
+		}
+		spending := rg.FinancialMetrics.DownloadSpending
+		if err := st.getAPI("/renter", &rg); err != nil {
+			t.Fatal(err)
+		}
+		dc := rg.DownloadCache
+		if dc.Chunks != 1 || dc.Size == 0 || dc.Size > dc.MaxSize || dc.Hits != uint64(i) || dc.Misses != 1 {
+			t.Fatal("wrong download cache metrics:", dc)
+		}
+		if i == 1 && rg.FinancialMetrics.DownloadSpending.Cmp(spending) != 0 {
+			t.Fatal("renter paid for a cached download")
+		}
+	}
+}
+
+// TestRenterSnapshots checks that a snapshot of the renter can be stored on its
+// hosts, and that its files can be recovered from the snapshot.
+func TestRenterSnapshots(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	st, path := setupTestDownload(t, 1e4, "snapshot.dat", true)
+	defer func() {
+		st.server.panicClose()
+		os.Remove(path)
+	}()
+
+	// No snapshot has been recorded yet.
+	var rl RenterLoad
+	if err := st.postAPI("/renter/snapshots/recover", url.Values{}, &rl); err == nil {
+		t.Fatal("expected recovery to fail without a snapshot")
+	}
+
+	var snapshot modules.RenterSnapshot
+	if err := st.postAPI("/renter/snapshots", url.Values{}, &snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.NumFiles != 1 || snapshot.Size == 0 || len(snapshot.Hosts) != 1 {
+		t.Fatal("wrong snapshot:", snapshot)
+	}
+	var rs RenterSnapshots
+	if err := st.getAPI("/renter/snapshots", &rs); err != nil {
+		t.Fatal(err)
+	}
+	if len(rs.Snapshots) != 1 || rs.Snapshots[0].TransactionID != snapshot.TransactionID {
+		t.Fatal("snapshot was not listed:", rs.Snapshots)
+	}
+
+	// Confirm the transaction that records the snapshot, and recover the
+	// deleted file from the snapshot.
+	if _, err := st.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.stdPostAPI("/renter/delete/snapshot.dat", url.Values{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.postAPI("/renter/snapshots/recover", url.Values{}, &rl); err != nil {
+		t.Fatal(err)
+	}
+	if len(rl.FilesAdded) != 1 || rl.FilesAdded[0] != "snapshot.dat" {
+		t.Fatal("expected snapshot.dat to be recovered, got", rl.FilesAdded)
+	}
+
+	downpath := filepath.Join(st.dir, "snapshotdown.dat")
+	if err := st.stdGetAPI("/renter/download/snapshot.dat?destination=" + downpath); err != nil {
+		t.Fatal(err)
+	}
+	orig, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	download, err := ioutil.ReadFile(downpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(orig, download) {
+		t.Fatal("data mismatch when downloading a recovered file")
+	}
+}
+
+// TestRenterUploadScheduling checks that uploads that are in progress can be
+// paused, resumed, reprioritized and canceled.
+func TestRenterUploadScheduling(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	// With one host, the parity piece of the file is never uploaded, so the
+	// upload stays in progress.
+	st, path := setupTestDownload(t, 1024, "sched.dat", true)
+	defer func() {
+		st.server.panicClose()
+		os.Remove(path)
+	}()
+	upload := func() modules.UploadInfo {
+		var ruq RenterUploadQueue
+		if err := st.getAPI("/renter/uploads", &ruq); err != nil {
+			t.Fatal(err)
+		}
+		if len(ruq.Uploads) != 1 {
+			t.Fatal("expected 1 upload, got", len(ruq.Uploads))
+		}
+		return ruq.Uploads[0]
+	}
+	up := upload()
+	if up.Complete || up.Error != "" || up.Paused || up.Priority != 0 {
+		t.Fatal("upload should be in progress:", up)
+	}
+	call := "/renter/uploads/" + strconv.FormatUint(up.ID, 10)
+
+	if err := st.stdPostAPI(call, url.Values{"action": {"pause"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.stdPostAPI(call, url.Values{"action": {"priority"}, "priority": {"-3"}}); err != nil {
+		t.Fatal(err)
+	}
+	if up = upload(); !up.Paused || up.Priority != -3 {
+		t.Fatal("upload was not paused and reprioritized:", up)
+	}
+	if err := st.stdPostAPI(call, url.Values{"action": {"resume"}}); err != nil {
+		t.Fatal(err)
+	}
+	if up = upload(); up.Paused {
+		t.Fatal("upload was not resumed:", up)
+	}
+
+	// Invalid requests are rejected.
+	if err := st.stdPostAPI(call, url.Values{"action": {"stop"}}); err == nil || err.Error() != "error when calling /renter/uploads: unknown action: stop" {
+		t.Fatal("expected an unknown action to be rejected, got", err)
+	}
+	if err := st.stdPostAPI(call, url.Values{"action": {"priority"}, "priority": {"high"}}); err == nil {
+		t.Fatal("expected an invalid priority to be rejected")
+	}
+	if err := st.stdPostAPI("/renter/uploads/1000", url.Values{"action": {"pause"}}); err == nil {
+		t.Fatal("expected an unknown upload to be rejected")
+	}
+
+	if err := st.stdPostAPI(call, url.Values{"action": {"cancel"}}); err != nil {
+		t.Fatal(err)
+	}
+	if up = upload(); up.Error != "upload was canceled" {
+		t.Fatal("upload was not canceled:", up)
+	}
+	var rf RenterFiles
+	if err := st.getAPI("/renter/files", &rf); err != nil {
+		t.Fatal(err)
+	}
+	if len(rf.Files) != 0 {
+		t.Fatal("file of a canceled upload was not deleted:", rf.Files)
+	}
+}
+
+// TestRenterSpending checks that the spending of the renter's contract is
+// attributed to the file that was uploaded and downloaded.
+func TestRenterSpending(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	st, path := setupTestDownload(t, 1024, "spending.dat", true)
+	defer func() {
+		st.server.panicClose()
+		os.Remove(path)
+	}()
+	downloadPath := filepath.Join(st.dir, "spending.dat")
+	if err := st.stdGetAPI("/renter/download/spending.dat?destination=" + downloadPath); err != nil {
+		t.Fatal(err)
+	}
+
+	var report modules.RenterSpendingReport
+	if err := st.getAPI("/renter/spending", &report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Contracts) != 1 || len(report.Files) != 1 {
+		t.Fatal("wrong spending report:", report)
+	}
+	c, f := report.Contracts[0], report.Files[0]
+	if c.Sectors != 1 || c.UploadSpending.IsZero() || c.DownloadSpending.IsZero() {
+		t.Fatal("wrong contract spending:", c)
+	}
+	// The file is the only data stored in and downloaded from the contract.
+	if f.SiaPath != "spending.dat" || f.Sectors != 1 || f.StorageSpending.Cmp(c.StorageSpending) != 0 ||
+		f.UploadSpending.Cmp(c.UploadSpending) != 0 || f.DownloadSpending.Cmp(c.DownloadSpending) != 0 {
+		t.Fatal("wrong file spending:", f)
+	}
+	if u := report.Unattributed; !u.StorageSpending.IsZero() || !u.UploadSpending.IsZero() || !u.DownloadSpending.IsZero() {
+		t.Fatal("spending was left unattributed:", u)
+	}
+}
+
+// TestRenterVerify tests that the host of an uploaded file proves that it
+// stores the pieces of the file.
+func TestRenterVerify(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	st, path := setupTestDownload(t, 1024, "verify.dat", true)
+	defer func() {
+		st.server.panicClose()
+		os.Remove(path)
+	}()
+
+	var fv modules.FileVerification
+	if err := st.postAPI("/renter/verify/verify.dat", nil, &fv); err != nil {
+		t.Fatal(err)
+	}
+	if fv.SiaPath != "verify.dat" || len(fv.Hosts) != 1 {
+		t.Fatal("wrong verification:", fv)
+	}
+	if hv := fv.Hosts[0]; hv.Error != "" || hv.Pieces != 1 || hv.Challenges != 1 || hv.Failures != 0 {
+		t.Fatal("host failed verification:", hv)
+	}
+
+	// Unknown files cannot be verified.
+	if err := st.stdPostAPI("/renter/verify/unknown.dat", nil); err == nil {
+		t.Fatal("verified an unknown file")
+	}
+}
+
+// TestRenterPause tests that no contracts are formed while the renter is
+// paused, and that they are formed after it is resumed.
+func TestRenterPause(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+	if err := st.announceHost(); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.acceptContracts(); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.setHostStorage(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = st.stdPostAPI("/renter/pause", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.stdPostAPI("/renter/pause", nil); err == nil {
+		t.Fatal("paused the renter twice")
+	}
+	var get RenterGET
+	if err = st.getAPI("/renter", &get); err != nil {
+		t.Fatal(err)
+	}
+	if !get.Paused {
+		t.Fatal("renter was not paused")
+	}
+
+	// The allowance is recorded, but no contracts are formed.
+	allowanceValues := url.Values{}
+	allowanceValues.Set("funds", testFunds)
+	allowanceValues.Set("period", testPeriod)
+	if err = st.stdPostAPI("/renter", allowanceValues); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	var contracts RenterContracts
+	if err = st.getAPI("/renter/contracts", &contracts); err != nil {
+		t.Fatal(err)
+	}
+	if len(contracts.Contracts) != 0 {
+		t.Fatal("contracts were formed while the renter was paused:", contracts.Contracts)
+	}
+
+	// Contracts are formed after the next block once the renter is resumed.
+	if err = st.stdPostAPI("/renter/resume", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.getAPI("/renter", &get); err != nil {
+		t.Fatal(err)
+	}
+	if get.Paused || get.Settings.Allowance.Funds.IsZero() {
+		t.Fatal("renter was not resumed with its allowance:", get)
+	}
+	if _, err := st.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 50 && len(contracts.Contracts) == 0; i++ {
+		time.Sleep(100 * time.Millisecond)
+		if err = st.getAPI("/renter/contracts", &contracts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(contracts.Contracts) != 1 {
+		t.Fatal("contracts were not formed after resuming:", contracts.Contracts)
+	}
+}
+
+// TestRenterClasses tests defining redundancy classes and assigning files to
+// them.
+func TestRenterClasses(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+	if err = st.announceHost(); err != nil {
+		t.Fatal(err)
+	}
+
+	classValues := url.Values{}
+	classValues.Set("action", "set")
+	classValues.Set("datapieces", "1")
+	classValues.Set("paritypieces", "2")
+	if err = st.stdPostAPI("/renter/classes/hot", classValues); err != nil {
+		t.Fatal(err)
+	}
+	classValues.Set("paritypieces", "0")
+	if err = st.stdPostAPI("/renter/classes/cold", classValues); err == nil {
+		t.Fatal("class without parity pieces was accepted")
+	}
+	var rc RenterClasses
+	if err = st.getAPI("/renter/classes", &rc); err != nil {
+		t.Fatal(err)
+	}
+	if len(rc.Classes) != 1 || rc.Classes[0] != (modules.RedundancyClass{Name: "hot", DataPieces: 1, ParityPieces: 2}) {
+		t.Fatal("wrong classes:", rc.Classes)
+	}
+
+	// Upload a file with the class.
+	path := filepath.Join(build.SiaTestingDir, "api", t.Name(), "test.dat")
+	if err = createRandFile(path, 1024); err != nil {
+		t.Fatal(err)
+	}
+	uploadValues := url.Values{}
+	uploadValues.Set("source", path)
+	uploadValues.Set("class", "hot")
+	uploadValues.Set("datapieces", "1")
+	uploadValues.Set("paritypieces", "1")
+	if err = st.stdPostAPI("/renter/upload/test", uploadValues); err == nil {
+		t.Fatal("upload with a class and erasure coding parameters was accepted")
+	}
+	uploadValues.Del("datapieces")
+	uploadValues.Del("paritypieces")
+	if err = st.stdPostAPI("/renter/upload/test", uploadValues); err != nil {
+		t.Fatal(err)
+	}
+	var rf RenterFiles
+	if err = st.getAPI("/renter/files", &rf); err != nil {
+		t.Fatal(err)
+	}
+	if len(rf.Files) != 1 || rf.Files[0].Class != "hot" {
+		t.Fatal("file was not uploaded with its class:", rf.Files)
+	}
+
+	// A class cannot be deleted while it is assigned to a file.
+	deleteValues := url.Values{}
+	deleteValues.Set("action", "delete")
+	if err = st.stdPostAPI("/renter/classes/hot", deleteValues); err == nil {
+		t.Fatal("class of a file was deleted")
+	}
+	if err = st.stdPostAPI("/renter/class/test", url.Values{"class": {"cold"}}); err == nil {
+		t.Fatal("file was assigned to an unknown class")
+	}
+	if err = st.stdPostAPI("/renter/class/test", url.Values{"class": {""}}); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.stdPostAPI("/renter/classes/hot", deleteValues); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.getAPI("/renter/classes", &rc); err != nil {
+		t.Fatal(err)
+	}
+	if len(rc.Classes) != 0 {
+		t.Fatal("class was not deleted:", rc.Classes)
+	}
+}
+
+// TestRenterForecast checks that the forecast of the renter's contract
+// reports the data that was uploaded to it and the rate of its spending.
+func TestRenterForecast(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	st, path := setupTestDownload(t, 1024, "forecast.dat", true)
+	defer func() {
+		st.server.panicClose()
+		os.Remove(path)
+	}()
+
+	var forecast modules.RenterForecast
+	if err := st.getAPI("/renter/forecast", &forecast); err != nil {
+		t.Fatal(err)
+	}
+	if forecast.Height != st.cs.Height() || len(forecast.Contracts) != 1 {
+		t.Fatal("wrong forecast:", forecast)
+	}
+	cf := forecast.Contracts[0]
+	if cf.Size != modules.SectorSize || cf.RemainingFunds.IsZero() || cf.SpendingRate.IsZero() {
+		t.Fatal("wrong contract utilization:", cf)
+	}
+	if cf.RemainingBlocks != cf.EndHeight-forecast.Height || cf.DepletionHeight <= forecast.Height {
+		t.Fatal("wrong contract forecast:", cf)
+	}
+}
@@ -873,6 +873,91 @@ func TestRenterUploadDownload(t *testing.T) {
 	}
 }
 
+// TestRenterUploadPieceSize checks that a file uploaded with custom erasure
+// coding parameters and piece size reports them, and can be downloaded.
+func TestRenterUploadPieceSize(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	// Announce the host and start accepting contracts.
+	if err := st.announceHost(); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.acceptContracts(); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.setHostStorage(); err != nil {
+		t.Fatal(err)
+	}
+	allowanceValues := url.Values{}
+	allowanceValues.Set("funds", "10000000000000000000000000000") // 10k SC
+	allowanceValues.Set("period", "10")
+	if err := st.stdPostAPI("/renter", allowanceValues); err != nil {
+		t.Fatal(err)
+	}
+
+	// Create a file that spans several chunks of 1 KiB pieces.
+	path := filepath.Join(st.dir, "test.dat")
+	if err := createRandFile(path, 3000); err != nil {
+		t.Fatal(err)
+	}
+
+	// Piece sizes that do not fit in a sector, and erasure codes that need
+	// more hosts than are available, are rejected.
+	uploadValues := url.Values{}
+	uploadValues.Set("source", path)
+	uploadValues.Set("piecesize", strconv.FormatUint(modules.SectorSize, 10))
+	if err := st.stdPostAPI("/renter/upload/test", uploadValues); err == nil {
+		t.Fatal("expected an error for a piece size larger than a sector")
+	}
+	uploadValues.Set("piecesize", "1024")
+	uploadValues.Set("datapieces", "2")
+	uploadValues.Set("paritypieces", "2")
+	if err := st.stdPostAPI("/renter/upload/test", uploadValues); err == nil {
+		t.Fatal("expected an error for more data pieces than hosts")
+	}
+
+	uploadValues.Set("datapieces", "1")
+	uploadValues.Set("paritypieces", "1")
+	if err := st.stdPostAPI("/renter/upload/test", uploadValues); err != nil {
+		t.Fatal(err)
+	}
+	// Only one piece of each chunk will be uploaded.
+	var rf RenterFiles
+	for i := 0; i < 200 && (len(rf.Files) != 1 || rf.Files[0].UploadProgress < 50); i++ {
+		st.getAPI("/renter/files", &rf)
+		time.Sleep(100 * time.Millisecond)
+	}
+	if len(rf.Files) != 1 || rf.Files[0].UploadProgress < 50 {
+		t.Fatal("the uploading is not succeeding for some reason:", rf.Files)
+	}
+	if f := rf.Files[0]; f.DataPieces != 1 || f.ParityPieces != 1 || f.PieceSize != 1024 {
+		t.Fatal("wrong erasure coding parameters:", f)
+	}
+
+	downpath := filepath.Join(st.dir, "testdown.dat")
+	if err := st.stdGetAPI("/renter/download/test?destination=" + downpath); err != nil {
+		t.Fatal(err)
+	}
+	orig, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	download, err := ioutil.ReadFile(downpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(orig, download) {
+		t.Fatal("data mismatch when downloading a file")
+	}
+}
+
 // TestRenterCancelAllowance tests that setting an empty allowance causes
 // uploads, downloads, and renewals to cease.
 func TestRenterCancelAllowance(t *testing.T) {
@@ -121,7 +121,7 @@ func NewServer(APIaddr string, requiredUserAgent string, requiredPassword string
 		return nil, err
 	}
 
-	a := New(requiredUserAgent, requiredPassword, cs, e, g, h, m, r, tp, w)
+	a := New(requiredUserAgent, requiredPassword, cs, e, g, h, m, r, tp, w, nil)
 	srv := &Server{
 		api: a,
 
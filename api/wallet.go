@@ -1,18 +1,25 @@
 package api
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"io"
+	"io/ioutil"
+	"math"
 	"net/http"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
 
 	"github.com/NebulousLabs/entropy-mnemonics"
 	"github.com/julienschmidt/httprouter"
+	"golang.org/x/net/websocket"
 )
 
 type (
@@ -36,30 +43,203 @@ type (
 		Address types.UnlockHash `json:"address"`
 	}
 
+	// WalletAddressBookGET contains the named payee addresses returned by a
+	// GET call to /wallet/addressbook.
+	WalletAddressBookGET struct {
+		Entries []modules.AddressBookEntry `json:"entries"`
+	}
+
 	// WalletAddressesGET contains the list of wallet addresses returned by a
 	// GET call to /wallet/addresses.
 	WalletAddressesGET struct {
 		Addresses []types.UnlockHash `json:"addresses"`
 	}
 
+	// WalletAutoLockGET contains the state of the auto-lock timer returned by
+	// a GET call to /wallet/autolock. Durations are in seconds.
+	WalletAutoLockGET struct {
+		Timeout       uint64          `json:"timeout"`
+		TimeUntilLock uint64          `json:"timeuntillock"`
+		UnlockedSince types.Timestamp `json:"unlockedsince"`
+		LastAutoLock  types.Timestamp `json:"lastautolock"`
+	}
+
+	// WalletBalanceGET contains the balance breakdown returned by a GET call
+	// to /wallet/balance.
+	WalletBalanceGET struct {
+		Confirmed           types.Currency                 `json:"confirmed"`
+		UnconfirmedIncoming types.Currency                 `json:"unconfirmedincoming"`
+		UnconfirmedOutgoing types.Currency                 `json:"unconfirmedoutgoing"`
+		Maturing            types.Currency                 `json:"maturing"`
+		MaturingOutputs     []modules.DelayedSiacoinOutput `json:"maturingoutputs"`
+	}
+
+	// WalletBuildPOST contains the transaction built by a POST call to
+	// /wallet/build and its parents, encoded as in /tpool/raw.
+	WalletBuildPOST struct {
+		Parents     []byte `json:"parents"`
+		Transaction []byte `json:"transaction"`
+	}
+
+	// WalletBumpFeeGET contains the IDs of the unconfirmed transactions whose
+	// fee can be bumped, returned by a GET call to /wallet/bumpfee.
+	WalletBumpFeeGET struct {
+		TransactionIDs []types.TransactionID `json:"transactionids"`
+	}
+
+	// WalletBumpFeePOST contains the IDs of the parent and child
+	// transactions submitted by a POST call to /wallet/bumpfee.
+	WalletBumpFeePOST struct {
+		TransactionIDs []types.TransactionID `json:"transactionids"`
+	}
+
+	// WalletDefragGET contains the defrag settings returned by a GET call to
+	// /wallet/defrag.
+	WalletDefragGET struct {
+		Settings modules.DefragSettings `json:"settings"`
+	}
+
+	// WalletDefragmentPOST contains the ids of the transactions created by a
+	// POST call to /wallet/defragment.
+	WalletDefragmentPOST struct {
+		TransactionIDs []types.TransactionID `json:"transactionids"`
+	}
+
+	// WalletFeeGET contains the fee per byte recommended by a GET call to
+	// /wallet/fee.
+	WalletFeeGET struct {
+		FeePerByte types.Currency `json:"feeperbyte"`
+	}
+
+	// WalletGapLimitGET contains the scan gap limit returned by a GET call to
+	// /wallet/gaplimit.
+	WalletGapLimitGET struct {
+		GapLimit uint64 `json:"gaplimit"`
+	}
+
+	// WalletHistoryGET contains the wallet history returned by a GET call to
+	// /wallet/history with format=json.
+	WalletHistoryGET struct {
+		History []modules.HistoryEntry `json:"history"`
+	}
+
 	// WalletInitPOST contains the primary seed that gets generated during a
 	// POST call to /wallet/init.
 	WalletInitPOST struct {
 		PrimarySeed string `json:"primaryseed"`
 	}
 
+	// WalletSignersGET contains the public keys of the external signers
+	// returned by a GET call to /wallet/signers.
+	WalletSignersGET struct {
+		PublicKeys []types.SiaPublicKey `json:"publickeys"`
+	}
+
 	// WalletSiacoinsPOST contains the transaction sent in the POST call to
 	// /wallet/siacoins.
 	WalletSiacoinsPOST struct {
 		TransactionIDs []types.TransactionID `json:"transactionids"`
 	}
 
+	// WalletSiacoinsPreviewPOST contains the description of the transaction
+	// set built by a POST call to /wallet/siacoins with preview set. The set
+	// is not broadcast.
+	WalletSiacoinsPreviewPOST struct {
+		Size   uint64                  `json:"size"`
+		Fee    types.Currency          `json:"fee"`
+		Inputs []types.SiacoinOutputID `json:"inputs"`
+		Change types.Currency          `json:"change"`
+	}
+
+	// WalletLabelsGET contains the address labels returned by a GET call to
+	// /wallet/labels.
+	WalletLabelsGET struct {
+		Labels []modules.AddressLabel `json:"labels"`
+	}
+
+	// WalletMemosGET contains the transaction memos returned by a GET call to
+	// /wallet/memos.
+	WalletMemosGET struct {
+		Memos []modules.TransactionMemo `json:"memos"`
+	}
+
+	// WalletMultisigAddressPOST contains the unlock conditions and address
+	// created by a POST call to /wallet/multisig/address.
+	WalletMultisigAddressPOST struct {
+		UnlockConditions types.UnlockConditions `json:"unlockconditions"`
+		Address          types.UnlockHash       `json:"address"`
+	}
+
+	// WalletMessageSignPOST contains the signature returned by a POST call
+	// to /wallet/message/sign.
+	WalletMessageSignPOST struct {
+		Signature types.MessageSignature `json:"signature"`
+	}
+
+	// WalletMessageVerifyPOST contains the result of a POST call to
+	// /wallet/message/verify.
+	WalletMessageVerifyPOST struct {
+		Valid bool `json:"valid"`
+	}
+
+	// WalletSwapsGET contains the swaps returned by a GET call to
+	// /wallet/swaps.
+	WalletSwapsGET struct {
+		Swaps []modules.AtomicSwap `json:"swaps"`
+	}
+
+	// WalletSwapCreatePOST contains the swap created by a POST call to
+	// /wallet/swap/create, along with the transaction holding its contract,
+	// encoded as in /tpool/raw, and the ids of the transactions that were
+	// broadcast.
+	WalletSwapCreatePOST struct {
+		Swap           modules.AtomicSwap    `json:"swap"`
+		Transaction    []byte                `json:"transaction"`
+		TransactionIDs []types.TransactionID `json:"transactionids"`
+	}
+
+	// WalletSwapAuditPOST contains the swap returned by a POST call to
+	// /wallet/swap/audit.
+	WalletSwapAuditPOST struct {
+		Swap modules.AtomicSwap `json:"swap"`
+	}
+
+	// WalletSwapRedeemPOST contains the ids of the transactions broadcast by
+	// a POST call to /wallet/swap/redeem.
+	WalletSwapRedeemPOST struct {
+		TransactionIDs []types.TransactionID `json:"transactionids"`
+	}
+
+	// WalletMultisigMergePOST contains the transaction returned by a POST
+	// call to /wallet/multisig/merge, encoded as in /tpool/raw.
+	WalletMultisigMergePOST struct {
+		Transaction []byte `json:"transaction"`
+	}
+
+	// WalletSignPOST contains the transaction returned by a POST call to
+	// /wallet/sign, encoded as in /tpool/raw.
+	WalletSignPOST struct {
+		Transaction []byte `json:"transaction"`
+	}
+
 	// WalletSiafundsPOST contains the transaction sent in the POST call to
 	// /wallet/siafunds.
 	WalletSiafundsPOST struct {
 		TransactionIDs []types.TransactionID `json:"transactionids"`
 	}
 
+	// WalletScheduledGET contains the transaction sets returned by a GET call
+	// to /wallet/scheduled.
+	WalletScheduledGET struct {
+		Scheduled []modules.ScheduledTransaction `json:"scheduled"`
+	}
+
+	// WalletScheduledPOST contains the id of the transaction set scheduled by
+	// a POST call to /wallet/scheduled.
+	WalletScheduledPOST struct {
+		ID types.TransactionID `json:"id"`
+	}
+
 	// WalletSeedsGET contains the seeds used by the wallet.
 	WalletSeedsGET struct {
 		PrimarySeed        string   `json:"primaryseed"`
@@ -74,10 +254,38 @@ type (
 		Funds types.Currency `json:"funds"`
 	}
 
+	// WalletTemplatesGET contains the multisig templates returned by a GET
+	// call to /wallet/templates.
+	WalletTemplatesGET struct {
+		Templates []modules.UnlockConditionsTemplate `json:"templates"`
+	}
+
+	// WalletTemplatesAddressPOST contains the unlock conditions and address
+	// created by a POST call to /wallet/templates/address.
+	WalletTemplatesAddressPOST struct {
+		UnlockConditions types.UnlockConditions `json:"unlockconditions"`
+		Address          types.UnlockHash       `json:"address"`
+	}
+
+	// WalletTimelockedGET contains the timelocked balances returned by a GET
+	// call to /wallet/timelocked.
+	WalletTimelockedGET struct {
+		Balances []modules.TimelockedBalance `json:"balances"`
+	}
+
+	// WalletTimelockedAddressPOST contains the unlock conditions and address
+	// created by a POST call to /wallet/timelocked/address.
+	WalletTimelockedAddressPOST struct {
+		UnlockConditions types.UnlockConditions `json:"unlockconditions"`
+		Address          types.UnlockHash       `json:"address"`
+	}
+
 	// WalletTransactionGETid contains the transaction returned by a call to
 	// /wallet/transaction/:id
 	WalletTransactionGETid struct {
 		Transaction modules.ProcessedTransaction `json:"transaction"`
+		Summary     modules.TransactionSummary   `json:"summary"`
+		Memo        string                       `json:"memo"`
 	}
 
 	// WalletTransactionsGET contains the specified set of confirmed and
@@ -85,6 +293,7 @@ type (
 	WalletTransactionsGET struct {
 		ConfirmedTransactions   []modules.ProcessedTransaction `json:"confirmedtransactions"`
 		UnconfirmedTransactions []modules.ProcessedTransaction `json:"unconfirmedtransactions"`
+		Memos                   []modules.TransactionMemo      `json:"memos"`
 	}
 
 	// WalletTransactionsGETaddr contains the set of wallet transactions
@@ -93,6 +302,26 @@ type (
 	WalletTransactionsGETaddr struct {
 		ConfirmedTransactions   []modules.ProcessedTransaction `json:"confirmedtransactions"`
 		UnconfirmedTransactions []modules.ProcessedTransaction `json:"unconfirmedtransactions"`
+		Memos                   []modules.TransactionMemo      `json:"memos"`
+	}
+
+	// WalletUnsignedTransactionPOST contains the transaction returned by a
+	// POST call to /wallet/unsignedtransaction, encoded as in /tpool/raw. The
+	// transaction signatures have their covered fields set, but are empty.
+	WalletUnsignedTransactionPOST struct {
+		Transaction []byte `json:"transaction"`
+	}
+
+	// WalletUnspentGET contains the unspent outputs returned by a GET call to
+	// /wallet/unspent.
+	WalletUnspentGET struct {
+		Outputs []modules.UnspentOutput `json:"outputs"`
+	}
+
+	// WalletWatchGET contains the watch-only addresses returned by a GET call
+	// to /wallet/watch.
+	WalletWatchGET struct {
+		Addresses []types.UnlockHash `json:"addresses"`
 	}
 
 	// WalletVerifyAddressGET contains a bool indicating if the address passed to
@@ -105,8 +334,7 @@ type (
 // encryptionKeys enumerates the possible encryption keys that can be derived
 // from an input string.
 func encryptionKeys(seedStr string) (validKeys []crypto.TwofishKey) {
-	dicts := []mnemonics.DictionaryID{"english", "german", "japanese"}
-	for _, dict := range dicts {
+	for _, dict := range modules.SeedDictionaries {
 		seed, err := modules.StringToSeed(seedStr, dict)
 		if err != nil {
 			continue
@@ -117,6 +345,17 @@ func encryptionKeys(seedStr string) (validKeys []crypto.TwofishKey) {
 	return validKeys
 }
 
+// scanSeed parses the seed phrase of the request. If no dictionary is given,
+// the dictionary is detected from the phrase.
+func scanSeed(req *http.Request) (modules.Seed, error) {
+	dictID := mnemonics.DictionaryID(req.FormValue("dictionary"))
+	if dictID == "" {
+		seed, _, err := modules.StringToSeedAnyDictionary(req.FormValue("seed"))
+		return seed, err
+	}
+	return modules.StringToSeed(req.FormValue("seed"), dictID)
+}
+
 // walletHander handles API calls to /wallet.
 func (api *API) walletHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
 	siacoinBal, siafundBal, siaclaimBal := api.wallet.ConfirmedBalance()
@@ -177,6 +416,34 @@ func (api *API) walletAddressesHandler(w http.ResponseWriter, req *http.Request,
 	})
 }
 
+// walletAddressBookHandlerGET handles GET calls to /wallet/addressbook.
+func (api *API) walletAddressBookHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	WriteJSON(w, WalletAddressBookGET{
+		Entries: api.wallet.AddressBook(),
+	})
+}
+
+// walletAddressBookHandlerPOST handles POST calls to /wallet/addressbook.
+func (api *API) walletAddressBookHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	name := req.FormValue("name")
+	var err error
+	if req.FormValue("remove") == "true" {
+		err = api.wallet.RemoveAddressBookEntry(name)
+	} else {
+		addr, scanErr := scanAddress(req.FormValue("address"))
+		if scanErr != nil {
+			WriteError(w, Error{"could not read address from POST call to /wallet/addressbook"}, http.StatusBadRequest)
+			return
+		}
+		err = api.wallet.SetAddressBookEntry(name, addr)
+	}
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/addressbook: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
 // walletBackupHandler handles API calls to /wallet/backup.
 func (api *API) walletBackupHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
 	destination := req.FormValue("destination")
@@ -193,6 +460,146 @@ func (api *API) walletBackupHandler(w http.ResponseWriter, req *http.Request, _
 	WriteSuccess(w)
 }
 
+// walletRestoreHandler handles API calls to /wallet/restore.
+func (api *API) walletRestoreHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	source := req.FormValue("source")
+	// Check that the source is absolute.
+	if !filepath.IsAbs(source) {
+		WriteError(w, Error{"error when calling /wallet/restore: source must be an absolute path"}, http.StatusBadRequest)
+		return
+	}
+	for _, key := range encryptionKeys(req.FormValue("encryptionpassword")) {
+		err := api.wallet.RestoreBackup(key, source)
+		if err == nil {
+			WriteSuccess(w)
+			return
+		}
+		if err != modules.ErrBadEncryptionKey {
+			WriteError(w, Error{"error when calling /wallet/restore: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	WriteError(w, Error{"error when calling /wallet/restore: " + modules.ErrBadEncryptionKey.Error()}, http.StatusBadRequest)
+}
+
+// walletDefragHandlerGET handles GET calls to /wallet/defrag.
+func (api *API) walletDefragHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	WriteJSON(w, WalletDefragGET{
+		Settings: api.wallet.DefragSettings(),
+	})
+}
+
+// walletDefragHandlerPOST handles POST calls to /wallet/defrag. Settings that
+// are not supplied keep their current values.
+func (api *API) walletDefragHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	settings := api.wallet.DefragSettings()
+	if req.FormValue("enabled") != "" {
+		enabled, err := strconv.ParseBool(req.FormValue("enabled"))
+		if err != nil {
+			WriteError(w, Error{"could not read enabled from POST call to /wallet/defrag"}, http.StatusBadRequest)
+			return
+		}
+		settings.Enabled = enabled
+	}
+	for _, field := range []struct {
+		name  string
+		value *uint64
+	}{
+		{"threshold", &settings.Threshold},
+		{"batchsize", &settings.BatchSize},
+		{"startindex", &settings.StartIndex},
+	} {
+		if req.FormValue(field.name) == "" {
+			continue
+		}
+		x, err := strconv.ParseUint(req.FormValue(field.name), 10, 64)
+		if err != nil {
+			WriteError(w, Error{"could not read " + field.name + " from POST call to /wallet/defrag"}, http.StatusBadRequest)
+			return
+		}
+		*field.value = x
+	}
+	if req.FormValue("maxfeeperbyte") != "" {
+		fee, ok := scanAmount(req.FormValue("maxfeeperbyte"))
+		if !ok {
+			WriteError(w, Error{"could not read maxfeeperbyte from POST call to /wallet/defrag"}, http.StatusBadRequest)
+			return
+		}
+		settings.MaxFeePerByte = fee
+	}
+
+	if err := api.wallet.SetDefragSettings(settings); err != nil {
+		WriteError(w, Error{"error when calling /wallet/defrag: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// walletDefragmentHandler handles API calls to /wallet/defragment.
+func (api *API) walletDefragmentHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	txns, err := api.wallet.Defragment()
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/defragment: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	var txids []types.TransactionID
+	for _, txn := range txns {
+		txids = append(txids, txn.ID())
+	}
+	WriteJSON(w, WalletDefragmentPOST{
+		TransactionIDs: txids,
+	})
+}
+
+// walletBumpFeeHandlerGET handles GET calls to /wallet/bumpfee.
+func (api *API) walletBumpFeeHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	WriteJSON(w, WalletBumpFeeGET{
+		TransactionIDs: api.wallet.BumpableTransactions(),
+	})
+}
+
+// walletBumpFeeHandlerPOST handles POST calls to /wallet/bumpfee.
+func (api *API) walletBumpFeeHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	id, err := scanHash(req.FormValue("id"))
+	if err != nil {
+		WriteError(w, Error{"could not read id from POST call to /wallet/bumpfee: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	txns, err := api.wallet.BumpFee(types.TransactionID(id))
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/bumpfee: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	var txids []types.TransactionID
+	for _, txn := range txns {
+		txids = append(txids, txn.ID())
+	}
+	WriteJSON(w, WalletBumpFeePOST{
+		TransactionIDs: txids,
+	})
+}
+
+// walletFeeHandler handles API calls to /wallet/fee.
+func (api *API) walletFeeHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	targetBlocks := 2
+	if req.FormValue("targetblocks") != "" {
+		var err error
+		targetBlocks, err = strconv.Atoi(req.FormValue("targetblocks"))
+		if err != nil {
+			WriteError(w, Error{"could not read 'targetblocks' from GET call to /wallet/fee"}, http.StatusBadRequest)
+			return
+		}
+	}
+	fee, err := api.wallet.EstimateFee(targetBlocks)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/fee: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, WalletFeeGET{
+		FeePerByte: fee,
+	})
+}
+
 // walletInitHandler handles API calls to /wallet/init.
 func (api *API) walletInitHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
 	var encryptionKey crypto.TwofishKey
@@ -233,11 +640,7 @@ func (api *API) walletInitSeedHandler(w http.ResponseWriter, req *http.Request,
 	if req.FormValue("encryptionpassword") != "" {
 		encryptionKey = crypto.TwofishKey(crypto.HashObject(req.FormValue("encryptionpassword")))
 	}
-	dictID := mnemonics.DictionaryID(req.FormValue("dictionary"))
-	if dictID == "" {
-		dictID = "english"
-	}
-	seed, err := modules.StringToSeed(req.FormValue("seed"), dictID)
+	seed, err := scanSeed(req)
 	if err != nil {
 		WriteError(w, Error{"error when calling /wallet/init/seed: " + err.Error()}, http.StatusBadRequest)
 		return
@@ -259,102 +662,342 @@ func (api *API) walletInitSeedHandler(w http.ResponseWriter, req *http.Request,
 	WriteSuccess(w)
 }
 
-// walletSeedHandler handles API calls to /wallet/seed.
-func (api *API) walletSeedHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
-	// Get the seed using the ditionary + phrase
-	dictID := mnemonics.DictionaryID(req.FormValue("dictionary"))
-	if dictID == "" {
-		dictID = "english"
+// walletRecoverHandler handles API calls to /wallet/recover.
+func (api *API) walletRecoverHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var encryptionKey crypto.TwofishKey
+	if req.FormValue("encryptionpassword") != "" {
+		encryptionKey = crypto.TwofishKey(crypto.HashObject(req.FormValue("encryptionpassword")))
 	}
-	seed, err := modules.StringToSeed(req.FormValue("seed"), dictID)
+	seed, err := scanSeed(req)
 	if err != nil {
-		WriteError(w, Error{"error when calling /wallet/seed: " + err.Error()}, http.StatusBadRequest)
+		WriteError(w, Error{"error when calling /wallet/recover: " + err.Error()}, http.StatusBadRequest)
 		return
 	}
 
-	potentialKeys := encryptionKeys(req.FormValue("encryptionpassword"))
-	for _, key := range potentialKeys {
-		err := api.wallet.LoadSeed(key, seed)
-		if err == nil {
-			WriteSuccess(w)
-			return
-		}
-		if err != nil && err != modules.ErrBadEncryptionKey {
-			WriteError(w, Error{"error when calling /wallet/seed: " + err.Error()}, http.StatusBadRequest)
+	if req.FormValue("force") == "true" {
+		err = api.wallet.Reset()
+		if err != nil {
+			WriteError(w, Error{"error when calling /wallet/recover: " + err.Error()}, http.StatusBadRequest)
 			return
 		}
 	}
-	WriteError(w, Error{"error when calling /wallet/seed: " + modules.ErrBadEncryptionKey.Error()}, http.StatusBadRequest)
-}
 
-// walletSiagkeyHandler handles API calls to /wallet/siagkey.
-func (api *API) walletSiagkeyHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
-	// Fetch the list of keyfiles from the post body.
-	keyfiles := strings.Split(req.FormValue("keyfiles"), ",")
-	potentialKeys := encryptionKeys(req.FormValue("encryptionpassword"))
+	err = api.wallet.Recover(encryptionKey, seed)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/recover: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
 
-	for _, keypath := range keyfiles {
-		// Check that all key paths are absolute paths.
-		if !filepath.IsAbs(keypath) {
-			WriteError(w, Error{"error when calling /wallet/siagkey: keyfiles contains a non-absolute path"}, http.StatusBadRequest)
+// walletRescanHandler handles API calls to /wallet/rescan.
+func (api *API) walletRescanHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var startHeight uint64
+	if req.FormValue("startheight") != "" {
+		var err error
+		startHeight, err = strconv.ParseUint(req.FormValue("startheight"), 10, 64)
+		if err != nil {
+			WriteError(w, Error{"error when calling /wallet/rescan: could not read 'startheight': " + err.Error()}, http.StatusBadRequest)
 			return
 		}
 	}
+	err := api.wallet.Rescan(types.BlockHeight(startHeight))
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/rescan: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
 
-	for _, key := range potentialKeys {
-		err := api.wallet.LoadSiagKeys(key, keyfiles)
-		if err == nil {
-			WriteSuccess(w)
-			return
-		}
-		if err != nil && err != modules.ErrBadEncryptionKey {
-			WriteError(w, Error{"error when calling /wallet/siagkey: " + err.Error()}, http.StatusBadRequest)
-			return
-		}
+// decodeTransaction decodes a transaction that has been encoded as in
+// /tpool/raw, accepting both base64 and clean values.
+func decodeTransaction(s string) (txn types.Transaction, err error) {
+	rawTransaction, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		rawTransaction = []byte(s)
 	}
-	WriteError(w, Error{"error when calling /wallet/siagkey: " + modules.ErrBadEncryptionKey.Error()}, http.StatusBadRequest)
+	err = encoding.Unmarshal(rawTransaction, &txn)
+	return txn, err
 }
 
-// walletLockHanlder handles API calls to /wallet/lock.
-func (api *API) walletLockHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
-	err := api.wallet.Lock()
+// walletLabelsHandlerGET handles GET calls to /wallet/labels.
+func (api *API) walletLabelsHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	WriteJSON(w, WalletLabelsGET{
+		Labels: api.wallet.AddressLabels(),
+	})
+}
+
+// walletLabelsHandlerPOST handles POST calls to /wallet/labels.
+func (api *API) walletLabelsHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	addr, err := scanAddress(req.FormValue("address"))
 	if err != nil {
-		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		WriteError(w, Error{"could not read address from POST call to /wallet/labels"}, http.StatusBadRequest)
+		return
+	}
+	if err := api.wallet.SetAddressLabel(addr, req.FormValue("label")); err != nil {
+		WriteError(w, Error{"error when calling /wallet/labels: " + err.Error()}, http.StatusBadRequest)
 		return
 	}
 	WriteSuccess(w)
 }
 
-// walletSeedsHandler handles API calls to /wallet/seeds.
-func (api *API) walletSeedsHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
-	dictionary := mnemonics.DictionaryID(req.FormValue("dictionary"))
-	if dictionary == "" {
-		dictionary = mnemonics.English
-	}
+// walletMemosHandlerGET handles GET calls to /wallet/memos.
+func (api *API) walletMemosHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	WriteJSON(w, WalletMemosGET{
+		Memos: api.wallet.TransactionMemos(),
+	})
+}
 
-	// Get the primary seed information.
-	primarySeed, addrsRemaining, err := api.wallet.PrimarySeed()
+// walletMemosHandlerPOST handles POST calls to /wallet/memos.
+func (api *API) walletMemosHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	id, err := scanHash(req.FormValue("transactionid"))
 	if err != nil {
-		WriteError(w, Error{"error after call to /wallet/seeds: " + err.Error()}, http.StatusBadRequest)
+		WriteError(w, Error{"could not read transaction id from POST call to /wallet/memos"}, http.StatusBadRequest)
 		return
 	}
-	primarySeedStr, err := modules.SeedToString(primarySeed, dictionary)
-	if err != nil {
-		WriteError(w, Error{"error after call to /wallet/seeds: " + err.Error()}, http.StatusBadRequest)
+	if err := api.wallet.SetTransactionMemo(types.TransactionID(id), req.FormValue("memo")); err != nil {
+		WriteError(w, Error{"error when calling /wallet/memos: " + err.Error()}, http.StatusBadRequest)
 		return
 	}
+	WriteSuccess(w)
+}
 
-	// Get the list of seeds known to the wallet.
-	allSeeds, err := api.wallet.AllSeeds()
+// walletMultisigAddressHandler handles API calls to /wallet/multisig/address.
+func (api *API) walletMultisigAddressHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	signaturesRequired, err := strconv.ParseUint(req.FormValue("signaturesrequired"), 10, 64)
 	if err != nil {
-		WriteError(w, Error{"error after call to /wallet/seeds: " + err.Error()}, http.StatusBadRequest)
+		WriteError(w, Error{"could not read 'signaturesrequired' from POST call to /wallet/multisig/address"}, http.StatusBadRequest)
 		return
 	}
-	var allSeedsStrs []string
-	for _, seed := range allSeeds {
-		str, err := modules.SeedToString(seed, dictionary)
-		if err != nil {
-			WriteError(w, Error{"error after call to /wallet/seeds: " + err.Error()}, http.StatusBadRequest)
+	var cosigners []types.SiaPublicKey
+	for _, s := range strings.Split(req.FormValue("publickeys"), ",") {
+		var spk types.SiaPublicKey
+		spk.LoadString(strings.TrimSpace(s))
+		if spk.Key == nil {
+			WriteError(w, Error{"could not read public key '" + s + "' from POST call to /wallet/multisig/address"}, http.StatusBadRequest)
+			return
+		}
+		cosigners = append(cosigners, spk)
+	}
+
+	uc, err := api.wallet.NewMultisigAddress(signaturesRequired, cosigners)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/multisig/address: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, WalletMultisigAddressPOST{
+		UnlockConditions: uc,
+		Address:          uc.UnlockHash(),
+	})
+}
+
+// walletMultisigMergeHandler handles API calls to /wallet/multisig/merge.
+func (api *API) walletMultisigMergeHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var txns []types.Transaction
+	for _, s := range strings.Split(req.FormValue("transactions"), ",") {
+		txn, err := decodeTransaction(strings.TrimSpace(s))
+		if err != nil {
+			WriteError(w, Error{"error decoding transaction:" + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		txns = append(txns, txn)
+	}
+
+	merged, err := modules.MergeTransactionSignatures(txns)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/multisig/merge: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, WalletMultisigMergePOST{
+		Transaction: encoding.Marshal(merged),
+	})
+}
+
+// walletSignHandler handles API calls to /wallet/sign.
+func (api *API) walletSignHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	txn, err := decodeTransaction(req.FormValue("transaction"))
+	if err != nil {
+		WriteError(w, Error{"error decoding transaction:" + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	var toSign []crypto.Hash
+	if req.FormValue("tosign") != "" {
+		for _, s := range strings.Split(req.FormValue("tosign"), ",") {
+			var parentID crypto.Hash
+			if err := parentID.LoadString(strings.TrimSpace(s)); err != nil {
+				WriteError(w, Error{"could not read parent id '" + s + "' from POST call to /wallet/sign"}, http.StatusBadRequest)
+				return
+			}
+			toSign = append(toSign, parentID)
+		}
+	}
+
+	err = api.wallet.SignTransaction(&txn, toSign)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/sign: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, WalletSignPOST{
+		Transaction: encoding.Marshal(txn),
+	})
+}
+
+// walletSignersHandler handles API calls to /wallet/signers.
+func (api *API) walletSignersHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	WriteJSON(w, WalletSignersGET{
+		PublicKeys: api.wallet.SignerPublicKeys(),
+	})
+}
+
+// walletScheduledHandlerGET handles GET calls to /wallet/scheduled.
+func (api *API) walletScheduledHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	WriteJSON(w, WalletScheduledGET{
+		Scheduled: api.wallet.ScheduledTransactions(),
+	})
+}
+
+// walletScheduledHandlerPOST handles POST calls to /wallet/scheduled.
+func (api *API) walletScheduledHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	if req.FormValue("cancel") != "" {
+		var id crypto.Hash
+		if err := id.LoadString(req.FormValue("cancel")); err != nil {
+			WriteError(w, Error{"could not read 'cancel' from POST call to /wallet/scheduled: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		if err := api.wallet.CancelScheduledTransaction(types.TransactionID(id)); err != nil {
+			WriteError(w, Error{"error when calling /wallet/scheduled: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		WriteSuccess(w)
+		return
+	}
+
+	var txns []types.Transaction
+	for _, s := range strings.Split(req.FormValue("transactions"), ",") {
+		txn, err := decodeTransaction(strings.TrimSpace(s))
+		if err != nil {
+			WriteError(w, Error{"error decoding transaction:" + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		txns = append(txns, txn)
+	}
+	var height, timestamp uint64
+	var err error
+	if req.FormValue("height") != "" {
+		height, err = strconv.ParseUint(req.FormValue("height"), 10, 64)
+		if err != nil {
+			WriteError(w, Error{"could not read 'height' from POST call to /wallet/scheduled"}, http.StatusBadRequest)
+			return
+		}
+	}
+	if req.FormValue("timestamp") != "" {
+		timestamp, err = strconv.ParseUint(req.FormValue("timestamp"), 10, 64)
+		if err != nil {
+			WriteError(w, Error{"could not read 'timestamp' from POST call to /wallet/scheduled"}, http.StatusBadRequest)
+			return
+		}
+	}
+	st, err := api.wallet.ScheduleTransaction(txns, types.BlockHeight(height), types.Timestamp(timestamp))
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/scheduled: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, WalletScheduledPOST{
+		ID: st.ID,
+	})
+}
+
+// walletSeedHandler handles API calls to /wallet/seed.
+func (api *API) walletSeedHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	seed, err := scanSeed(req)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/seed: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	potentialKeys := encryptionKeys(req.FormValue("encryptionpassword"))
+	for _, key := range potentialKeys {
+		err := api.wallet.LoadSeed(key, seed)
+		if err == nil {
+			WriteSuccess(w)
+			return
+		}
+		if err != nil && err != modules.ErrBadEncryptionKey {
+			WriteError(w, Error{"error when calling /wallet/seed: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	WriteError(w, Error{"error when calling /wallet/seed: " + modules.ErrBadEncryptionKey.Error()}, http.StatusBadRequest)
+}
+
+// walletSiagkeyHandler handles API calls to /wallet/siagkey.
+func (api *API) walletSiagkeyHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	// Fetch the list of keyfiles from the post body.
+	keyfiles := strings.Split(req.FormValue("keyfiles"), ",")
+	potentialKeys := encryptionKeys(req.FormValue("encryptionpassword"))
+
+	for _, keypath := range keyfiles {
+		// Check that all key paths are absolute paths.
+		if !filepath.IsAbs(keypath) {
+			WriteError(w, Error{"error when calling /wallet/siagkey: keyfiles contains a non-absolute path"}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	for _, key := range potentialKeys {
+		err := api.wallet.LoadSiagKeys(key, keyfiles)
+		if err == nil {
+			WriteSuccess(w)
+			return
+		}
+		if err != nil && err != modules.ErrBadEncryptionKey {
+			WriteError(w, Error{"error when calling /wallet/siagkey: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	WriteError(w, Error{"error when calling /wallet/siagkey: " + modules.ErrBadEncryptionKey.Error()}, http.StatusBadRequest)
+}
+
+// walletLockHanlder handles API calls to /wallet/lock.
+func (api *API) walletLockHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	err := api.wallet.Lock()
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// walletSeedsHandler handles API calls to /wallet/seeds.
+func (api *API) walletSeedsHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	dictionary := mnemonics.DictionaryID(req.FormValue("dictionary"))
+	if dictionary == "" {
+		dictionary = mnemonics.English
+	}
+
+	// Get the primary seed information.
+	primarySeed, addrsRemaining, err := api.wallet.PrimarySeed()
+	if err != nil {
+		WriteError(w, Error{"error after call to /wallet/seeds: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	primarySeedStr, err := modules.SeedToString(primarySeed, dictionary)
+	if err != nil {
+		WriteError(w, Error{"error after call to /wallet/seeds: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	// Get the list of seeds known to the wallet.
+	allSeeds, err := api.wallet.AllSeeds()
+	if err != nil {
+		WriteError(w, Error{"error after call to /wallet/seeds: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	var allSeedsStrs []string
+	for _, seed := range allSeeds {
+		str, err := modules.SeedToString(seed, dictionary)
+		if err != nil {
+			WriteError(w, Error{"error after call to /wallet/seeds: " + err.Error()}, http.StatusBadRequest)
 			return
 		}
 		allSeedsStrs = append(allSeedsStrs, str)
@@ -369,6 +1012,7 @@ func (api *API) walletSeedsHandler(w http.ResponseWriter, req *http.Request, _ h
 // walletSiacoinsHandler handles API calls to /wallet/siacoins.
 func (api *API) walletSiacoinsHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
 	var txns []types.Transaction
+	preview := req.FormValue("preview") == "true"
 	if req.FormValue("outputs") != "" {
 		// multiple amounts + destinations
 		if req.FormValue("amount") != "" || req.FormValue("destination") != "" {
@@ -382,6 +1026,15 @@ func (api *API) walletSiacoinsHandler(w http.ResponseWriter, req *http.Request,
 			WriteError(w, Error{"could not decode outputs: " + err.Error()}, http.StatusInternalServerError)
 			return
 		}
+		if preview {
+			tp, err := api.wallet.PreviewSiacoinsMulti(outputs)
+			if err != nil {
+				WriteError(w, Error{"error after call to /wallet/siacoins: " + err.Error()}, http.StatusInternalServerError)
+				return
+			}
+			writeSiacoinsPreview(w, tp)
+			return
+		}
 		txns, err = api.wallet.SendSiacoinsMulti(outputs)
 		if err != nil {
 			WriteError(w, Error{"error after call to /wallet/siacoins: " + err.Error()}, http.StatusInternalServerError)
@@ -400,7 +1053,50 @@ func (api *API) walletSiacoinsHandler(w http.ResponseWriter, req *http.Request,
 			return
 		}
 
-		txns, err = api.wallet.SendSiacoins(amount, dest)
+		// Optionally restrict the outputs that may be spent.
+		var inputs []types.SiacoinOutputID
+		if req.FormValue("inputs") != "" {
+			for _, s := range strings.Split(req.FormValue("inputs"), ",") {
+				var id crypto.Hash
+				if err := id.LoadString(strings.TrimSpace(s)); err != nil {
+					WriteError(w, Error{"could not read input '" + s + "' from POST call to /wallet/siacoins"}, http.StatusBadRequest)
+					return
+				}
+				inputs = append(inputs, types.SiacoinOutputID(id))
+			}
+		}
+
+		// Optionally only spend outputs with enough confirmations.
+		var minConfirmations uint64
+		if req.FormValue("minconfirmations") != "" {
+			minConfirmations, err = strconv.ParseUint(req.FormValue("minconfirmations"), 10, 64)
+			if err != nil {
+				WriteError(w, Error{"parsing integer value for parameter `minconfirmations` failed: " + err.Error()}, http.StatusBadRequest)
+				return
+			}
+			if inputs != nil {
+				WriteError(w, Error{"cannot supply both 'inputs' and 'minconfirmations'"}, http.StatusBadRequest)
+				return
+			}
+		}
+
+		if preview {
+			tp, err := api.wallet.PreviewSiacoins(amount, dest, inputs, types.BlockHeight(minConfirmations))
+			if err != nil {
+				WriteError(w, Error{"error after call to /wallet/siacoins: " + err.Error()}, http.StatusInternalServerError)
+				return
+			}
+			writeSiacoinsPreview(w, tp)
+			return
+		}
+		switch {
+		case inputs != nil:
+			txns, err = api.wallet.SendSiacoinsFromOutputs(amount, dest, inputs)
+		case minConfirmations != 0:
+			txns, err = api.wallet.SendSiacoinsWithConfirmations(amount, dest, types.BlockHeight(minConfirmations))
+		default:
+			txns, err = api.wallet.SendSiacoins(amount, dest)
+		}
 		if err != nil {
 			WriteError(w, Error{"error after call to /wallet/siacoins: " + err.Error()}, http.StatusInternalServerError)
 			return
@@ -417,6 +1113,17 @@ func (api *API) walletSiacoinsHandler(w http.ResponseWriter, req *http.Request,
 	})
 }
 
+// writeSiacoinsPreview writes the preview of a siacoin transfer as the
+// response to a call to /wallet/siacoins.
+func writeSiacoinsPreview(w http.ResponseWriter, tp modules.TransactionPreview) {
+	WriteJSON(w, WalletSiacoinsPreviewPOST{
+		Size:   tp.Size,
+		Fee:    tp.Fee,
+		Inputs: tp.Inputs,
+		Change: tp.Change,
+	})
+}
+
 // walletSiafundsHandler handles API calls to /wallet/siafunds.
 func (api *API) walletSiafundsHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
 	amount, ok := scanAmount(req.FormValue("amount"))
@@ -446,12 +1153,7 @@ func (api *API) walletSiafundsHandler(w http.ResponseWriter, req *http.Request,
 
 // walletSweepSeedHandler handles API calls to /wallet/sweep/seed.
 func (api *API) walletSweepSeedHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
-	// Get the seed using the ditionary + phrase
-	dictID := mnemonics.DictionaryID(req.FormValue("dictionary"))
-	if dictID == "" {
-		dictID = "english"
-	}
-	seed, err := modules.StringToSeed(req.FormValue("seed"), dictID)
+	seed, err := scanSeed(req)
 	if err != nil {
 		WriteError(w, Error{"error when calling /wallet/sweep/seed: " + err.Error()}, http.StatusBadRequest)
 		return
@@ -468,6 +1170,115 @@ func (api *API) walletSweepSeedHandler(w http.ResponseWriter, req *http.Request,
 	})
 }
 
+// walletTemplatesHandlerGET handles GET calls to /wallet/templates.
+func (api *API) walletTemplatesHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	WriteJSON(w, WalletTemplatesGET{
+		Templates: api.wallet.UnlockConditionsTemplates(),
+	})
+}
+
+// walletTemplatesHandlerPOST handles POST calls to /wallet/templates.
+func (api *API) walletTemplatesHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	name := req.FormValue("name")
+	if req.FormValue("remove") == "true" {
+		if err := api.wallet.RemoveUnlockConditionsTemplate(name); err != nil {
+			WriteError(w, Error{"error when calling /wallet/templates: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		WriteSuccess(w)
+		return
+	}
+
+	signaturesRequired, err := strconv.ParseUint(req.FormValue("signaturesrequired"), 10, 64)
+	if err != nil {
+		WriteError(w, Error{"could not read 'signaturesrequired' from POST call to /wallet/templates"}, http.StatusBadRequest)
+		return
+	}
+	var cosigners []types.SiaPublicKey
+	for _, s := range strings.Split(req.FormValue("publickeys"), ",") {
+		var spk types.SiaPublicKey
+		spk.LoadString(strings.TrimSpace(s))
+		if spk.Key == nil {
+			WriteError(w, Error{"could not read public key '" + s + "' from POST call to /wallet/templates"}, http.StatusBadRequest)
+			return
+		}
+		cosigners = append(cosigners, spk)
+	}
+	err = api.wallet.SetUnlockConditionsTemplate(modules.UnlockConditionsTemplate{
+		Name:               name,
+		SignaturesRequired: signaturesRequired,
+		Cosigners:          cosigners,
+	})
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/templates: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// walletTemplatesAddressHandler handles API calls to
+// /wallet/templates/address.
+func (api *API) walletTemplatesAddressHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	uc, err := api.wallet.NewTemplateAddress(req.FormValue("name"))
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/templates/address: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, WalletTemplatesAddressPOST{
+		UnlockConditions: uc,
+		Address:          uc.UnlockHash(),
+	})
+}
+
+// walletTimelockedHandler handles API calls to /wallet/timelocked.
+func (api *API) walletTimelockedHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	balances, err := api.wallet.TimelockedBalances()
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/timelocked: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, WalletTimelockedGET{
+		Balances: balances,
+	})
+}
+
+// walletTimelockedAddressHandler handles API calls to
+// /wallet/timelocked/address. If a public key is provided, the address is
+// that of the public key rather than of a new wallet key, and is not tracked
+// by the wallet.
+func (api *API) walletTimelockedAddressHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	timelock, err := strconv.ParseUint(req.FormValue("timelock"), 10, 64)
+	if err != nil {
+		WriteError(w, Error{"could not read 'timelock' from POST call to /wallet/timelocked/address"}, http.StatusBadRequest)
+		return
+	}
+
+	var uc types.UnlockConditions
+	if req.FormValue("publickey") != "" {
+		var spk types.SiaPublicKey
+		spk.LoadString(req.FormValue("publickey"))
+		if spk.Key == nil {
+			WriteError(w, Error{"could not read 'publickey' from POST call to /wallet/timelocked/address"}, http.StatusBadRequest)
+			return
+		}
+		uc = types.UnlockConditions{
+			Timelock:           types.BlockHeight(timelock),
+			PublicKeys:         []types.SiaPublicKey{spk},
+			SignaturesRequired: 1,
+		}
+	} else {
+		uc, err = api.wallet.NewTimelockedAddress(types.BlockHeight(timelock))
+		if err != nil {
+			WriteError(w, Error{"error when calling /wallet/timelocked/address: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	WriteJSON(w, WalletTimelockedAddressPOST{
+		UnlockConditions: uc,
+		Address:          uc.UnlockHash(),
+	})
+}
+
 // walletTransactionHandler handles API calls to /wallet/transaction/:id.
 func (api *API) walletTransactionHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
 	// Parse the id from the url.
@@ -484,8 +1295,16 @@ func (api *API) walletTransactionHandler(w http.ResponseWriter, req *http.Reques
 		WriteError(w, Error{"error when calling /wallet/transaction/:id  :  transaction not found"}, http.StatusBadRequest)
 		return
 	}
+	var memo string
+	for _, tm := range api.wallet.TransactionMemos() {
+		if tm.TransactionID == id {
+			memo = tm.Memo
+		}
+	}
 	WriteJSON(w, WalletTransactionGETid{
 		Transaction: txn,
+		Summary:     txn.Summary(),
+		Memo:        memo,
 	})
 }
 
@@ -517,6 +1336,7 @@ func (api *API) walletTransactionsHandler(w http.ResponseWriter, req *http.Reque
 	WriteJSON(w, WalletTransactionsGET{
 		ConfirmedTransactions:   confirmedTxns,
 		UnconfirmedTransactions: unconfirmedTxns,
+		Memos:                   api.transactionMemos(confirmedTxns, unconfirmedTxns),
 	})
 }
 
@@ -537,14 +1357,49 @@ func (api *API) walletTransactionsAddrHandler(w http.ResponseWriter, req *http.R
 	WriteJSON(w, WalletTransactionsGETaddr{
 		ConfirmedTransactions:   confirmedATs,
 		UnconfirmedTransactions: unconfirmedATs,
+		Memos:                   api.transactionMemos(confirmedATs, unconfirmedATs),
 	})
 }
 
+// transactionMemos returns the memos of the wallet that are attached to the
+// transactions in pts.
+func (api *API) transactionMemos(pts ...[]modules.ProcessedTransaction) []modules.TransactionMemo {
+	ids := make(map[types.TransactionID]struct{})
+	for _, list := range pts {
+		for _, pt := range list {
+			ids[pt.TransactionID] = struct{}{}
+		}
+	}
+	memos := []modules.TransactionMemo{}
+	for _, tm := range api.wallet.TransactionMemos() {
+		if _, exists := ids[tm.TransactionID]; exists {
+			memos = append(memos, tm)
+		}
+	}
+	return memos
+}
+
 // walletUnlockHandler handles API calls to /wallet/unlock.
 func (api *API) walletUnlockHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	// An optional timeout, in seconds, locks the wallet again once it elapses.
+	var timeout time.Duration
+	if req.FormValue("timeout") != "" {
+		seconds, err := strconv.ParseUint(req.FormValue("timeout"), 10, 32)
+		if err != nil || seconds == 0 {
+			WriteError(w, Error{"could not read 'timeout' from POST call to /wallet/unlock"}, http.StatusBadRequest)
+			return
+		}
+		timeout = time.Duration(seconds) * time.Second
+	}
+
 	potentialKeys := encryptionKeys(req.FormValue("encryptionpassword"))
 	for _, key := range potentialKeys {
-		err := api.wallet.Unlock(key)
+		var err error
+		if timeout != 0 {
+			err = api.wallet.UnlockWithTimeout(key, timeout)
+		} else {
+			err = api.wallet.Unlock(key)
+		}
 		if err == nil {
 			WriteSuccess(w)
 			return
@@ -589,3 +1444,530 @@ func (api *API) walletVerifyAddressHandler(w http.ResponseWriter, req *http.Requ
 	err := new(types.UnlockHash).LoadString(addrString)
 	WriteJSON(w, WalletVerifyAddressGET{Valid: err == nil})
 }
+
+// walletBalanceHandler handles API calls to /wallet/balance.
+func (api *API) walletBalanceHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	bb, err := api.wallet.BalanceBreakdown()
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/balance: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, WalletBalanceGET{
+		Confirmed:           bb.Confirmed,
+		UnconfirmedIncoming: bb.UnconfirmedIncoming,
+		UnconfirmedOutgoing: bb.UnconfirmedOutgoing,
+		Maturing:            bb.Maturing,
+		MaturingOutputs:     bb.MaturingOutputs,
+	})
+}
+
+// walletGapLimitHandlerGET handles GET calls to /wallet/gaplimit.
+func (api *API) walletGapLimitHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	WriteJSON(w, WalletGapLimitGET{
+		GapLimit: api.wallet.ScanGapLimit(),
+	})
+}
+
+// walletGapLimitHandlerPOST handles POST calls to /wallet/gaplimit.
+func (api *API) walletGapLimitHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	limit, err := strconv.ParseUint(req.FormValue("gaplimit"), 10, 64)
+	if err != nil {
+		WriteError(w, Error{"could not read gaplimit from POST call to /wallet/gaplimit"}, http.StatusBadRequest)
+		return
+	}
+	if err := api.wallet.SetScanGapLimit(limit); err != nil {
+		WriteError(w, Error{"error when calling /wallet/gaplimit: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// walletAutoLockHandlerGET handles GET calls to /wallet/autolock. The time
+// until the wallet locks itself is rounded up, so that it is only zero when
+// no timer is running.
+func (api *API) walletAutoLockHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	status := api.wallet.AutoLock()
+	WriteJSON(w, WalletAutoLockGET{
+		Timeout:       uint64(status.Timeout / time.Second),
+		TimeUntilLock: uint64((status.TimeUntilLock + time.Second - 1) / time.Second),
+		UnlockedSince: status.UnlockedSince,
+		LastAutoLock:  status.LastAutoLock,
+	})
+}
+
+// walletAutoLockHandlerPOST handles POST calls to /wallet/autolock.
+func (api *API) walletAutoLockHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	seconds, err := strconv.ParseUint(req.FormValue("timeout"), 10, 32)
+	if err != nil {
+		WriteError(w, Error{"could not read 'timeout' from POST call to /wallet/autolock"}, http.StatusBadRequest)
+		return
+	}
+	if err := api.wallet.SetAutoLockTimeout(time.Duration(seconds) * time.Second); err != nil {
+		WriteError(w, Error{"error when calling /wallet/autolock: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// walletMessageSignHandler handles API calls to /wallet/message/sign.
+func (api *API) walletMessageSignHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	addr, err := scanAddress(req.FormValue("address"))
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/message/sign: could not read address: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	sig, err := api.wallet.SignMessage(addr, []byte(req.FormValue("message")))
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/message/sign: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, WalletMessageSignPOST{Signature: sig})
+}
+
+// walletMessageVerifyHandler handles API calls to /wallet/message/verify.
+func (api *API) walletMessageVerifyHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	addr, err := scanAddress(req.FormValue("address"))
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/message/verify: could not read address: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	var sig types.MessageSignature
+	if err := json.Unmarshal([]byte(req.FormValue("signature")), &sig); err != nil {
+		WriteError(w, Error{"error when calling /wallet/message/verify: could not decode signature: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	err = types.VerifyMessage(addr, []byte(req.FormValue("message")), sig)
+	WriteJSON(w, WalletMessageVerifyPOST{Valid: err == nil})
+}
+
+// walletSwapsHandler handles API calls to /wallet/swaps.
+func (api *API) walletSwapsHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	WriteJSON(w, WalletSwapsGET{
+		Swaps: api.wallet.AtomicSwaps(),
+	})
+}
+
+// walletSwapCreateHandler handles API calls to /wallet/swap/create.
+func (api *API) walletSwapCreateHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	amount, ok := scanAmount(req.FormValue("amount"))
+	if !ok {
+		WriteError(w, Error{"error when calling /wallet/swap/create: could not read amount"}, http.StatusBadRequest)
+		return
+	}
+	addr, err := scanAddress(req.FormValue("redeemaddress"))
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/swap/create: could not read redeemaddress: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	timelock, err := strconv.ParseUint(req.FormValue("timelock"), 10, 64)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/swap/create: could not read timelock: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	var secretHash crypto.Hash
+	if s := req.FormValue("secrethash"); s != "" {
+		if secretHash, err = scanHash(s); err != nil {
+			WriteError(w, Error{"error when calling /wallet/swap/create: could not read secrethash: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	swap, txns, err := api.wallet.CreateAtomicSwap(amount, addr, secretHash, types.BlockHeight(timelock))
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/swap/create: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	var txids []types.TransactionID
+	for _, txn := range txns {
+		txids = append(txids, txn.ID())
+	}
+	WriteJSON(w, WalletSwapCreatePOST{
+		Swap:           swap,
+		Transaction:    encoding.Marshal(txns[len(txns)-1]),
+		TransactionIDs: txids,
+	})
+}
+
+// walletSwapAuditHandler handles API calls to /wallet/swap/audit.
+func (api *API) walletSwapAuditHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	txn, err := decodeTransaction(req.FormValue("transaction"))
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/swap/audit: could not decode transaction: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	swap, err := api.wallet.AuditAtomicSwap(txn)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/swap/audit: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, WalletSwapAuditPOST{Swap: swap})
+}
+
+// walletSwapRedeemHandler handles API calls to /wallet/swap/redeem.
+func (api *API) walletSwapRedeemHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	id, err := scanHash(req.FormValue("id"))
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/swap/redeem: could not read id: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	secret, err := scanHash(req.FormValue("secret"))
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/swap/redeem: could not read secret: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	txns, err := api.wallet.RedeemAtomicSwap(types.FileContractID(id), secret)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/swap/redeem: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	var txids []types.TransactionID
+	for _, txn := range txns {
+		txids = append(txids, txn.ID())
+	}
+	WriteJSON(w, WalletSwapRedeemPOST{TransactionIDs: txids})
+}
+
+// walletPaymentsHandler handles API calls to /wallet/payments. The connection
+// is upgraded to a WebSocket, over which each payment event of the wallet is
+// sent as a JSON object until the client closes the connection.
+func (api *API) walletPaymentsHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	confirmations := uint64(1)
+	if s := req.FormValue("confirmations"); s != "" {
+		var err error
+		confirmations, err = strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			WriteError(w, Error{"parsing integer value for parameter `confirmations` failed: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	events, unsubscribe, err := api.wallet.SubscribePayments(types.BlockHeight(confirmations))
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/payments: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	defer unsubscribe()
+
+	// The WebSocket origin is not checked, as the API is not meant to be
+	// accessed from browsers.
+	websocket.Server{Handler: func(ws *websocket.Conn) {
+		// Messages from the client are ignored, but reading is necessary to
+		// notice that the client closed the connection.
+		go func() {
+			io.Copy(ioutil.Discard, ws)
+			unsubscribe()
+		}()
+		for pe := range events {
+			if err := websocket.JSON.Send(ws, pe); err != nil {
+				return
+			}
+		}
+	}}.ServeHTTP(w, req)
+}
+
+// walletAutoLockEventsHandler handles API calls to /wallet/autolock/events.
+// The connection is upgraded to a WebSocket, over which an event is sent as a
+// JSON object whenever the wallet locks itself, until the client closes the
+// connection.
+func (api *API) walletAutoLockEventsHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	events, unsubscribe, err := api.wallet.SubscribeLockEvents()
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/autolock/events: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	defer unsubscribe()
+
+	websocket.Server{Handler: func(ws *websocket.Conn) {
+		go func() {
+			io.Copy(ioutil.Discard, ws)
+			unsubscribe()
+		}()
+		for le := range events {
+			if err := websocket.JSON.Send(ws, le); err != nil {
+				return
+			}
+		}
+	}}.ServeHTTP(w, req)
+}
+
+// walletHistoryHandler handles API calls to /wallet/history.
+func (api *API) walletHistoryHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	// Parse the optional height range. The running balances are computed
+	// from the full history regardless of the range.
+	start, end := types.BlockHeight(0), types.BlockHeight(math.MaxUint64)
+	if s := req.FormValue("startheight"); s != "" {
+		h, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			WriteError(w, Error{"parsing integer value for parameter `startheight` failed: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		start = types.BlockHeight(h)
+	}
+	if s := req.FormValue("endheight"); s != "" {
+		h, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			WriteError(w, Error{"parsing integer value for parameter `endheight` failed: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		end = types.BlockHeight(h)
+	}
+	format := req.FormValue("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" {
+		WriteError(w, Error{"error when calling /wallet/history: format must be 'csv' or 'json'"}, http.StatusBadRequest)
+		return
+	}
+
+	pts, err := api.wallet.Transactions(0, end)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/history: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	history := modules.WalletHistory(pts)
+	for len(history) > 0 && history[0].ConfirmationHeight < start {
+		history = history[1:]
+	}
+	memos := make(map[types.TransactionID]string)
+	for _, tm := range api.wallet.TransactionMemos() {
+		memos[tm.TransactionID] = tm.Memo
+	}
+	for i := range history {
+		history[i].Memo = memos[history[i].TransactionID]
+	}
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="wallet-history.csv"`)
+		modules.WriteHistoryCSV(w, history)
+		return
+	}
+	WriteJSON(w, WalletHistoryGET{History: history})
+}
+
+// walletBuildHandler handles API calls to /wallet/build.
+func (api *API) walletBuildHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	// Decode the transaction being extended and its parents, if any.
+	var txn types.Transaction
+	var parents []types.Transaction
+	var err error
+	if req.FormValue("transaction") != "" {
+		txn, err = decodeTransaction(req.FormValue("transaction"))
+		if err != nil {
+			WriteError(w, Error{"error decoding transaction:" + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	if req.FormValue("parents") != "" {
+		rawParents, err := base64.StdEncoding.DecodeString(req.FormValue("parents"))
+		if err != nil {
+			rawParents = []byte(req.FormValue("parents"))
+		}
+		if err := encoding.Unmarshal(rawParents, &parents); err != nil {
+			WriteError(w, Error{"error decoding parents:" + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Decode the elements to add.
+	var outputs []types.SiacoinOutput
+	if req.FormValue("outputs") != "" {
+		if err := json.Unmarshal([]byte(req.FormValue("outputs")), &outputs); err != nil {
+			WriteError(w, Error{"could not decode outputs: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	var contracts []types.FileContract
+	if req.FormValue("filecontracts") != "" {
+		if err := json.Unmarshal([]byte(req.FormValue("filecontracts")), &contracts); err != nil {
+			WriteError(w, Error{"could not decode filecontracts: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	var data [][]byte
+	if req.FormValue("arbitrarydata") != "" {
+		for _, s := range strings.Split(req.FormValue("arbitrarydata"), ",") {
+			arb, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+			if err != nil {
+				WriteError(w, Error{"could not decode arbitrarydata: " + err.Error()}, http.StatusBadRequest)
+				return
+			}
+			// Prefix the data so that it is relayed by the transaction pool.
+			data = append(data, append(modules.PrefixNonSia[:], arb...))
+		}
+	}
+	fee := types.ZeroCurrency
+	if req.FormValue("fee") != "" {
+		var ok bool
+		fee, ok = scanAmount(req.FormValue("fee"))
+		if !ok {
+			WriteError(w, Error{"could not read 'fee' from POST call to /wallet/build"}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Unless told otherwise, the wallet pays for the outputs, contracts and
+	// fee that it adds.
+	fund := fee
+	for _, sco := range outputs {
+		fund = fund.Add(sco.Value)
+	}
+	for _, fc := range contracts {
+		fund = fund.Add(fc.Payout)
+	}
+	if req.FormValue("fund") != "" {
+		var ok bool
+		fund, ok = scanAmount(req.FormValue("fund"))
+		if !ok {
+			WriteError(w, Error{"could not read 'fund' from POST call to /wallet/build"}, http.StatusBadRequest)
+			return
+		}
+	}
+	var sign bool
+	if req.FormValue("sign") != "" {
+		sign, err = strconv.ParseBool(req.FormValue("sign"))
+		if err != nil {
+			WriteError(w, Error{"could not read 'sign' from POST call to /wallet/build: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	tb := api.wallet.RegisterTransaction(txn, parents)
+	if !fund.IsZero() {
+		if err := tb.FundSiacoins(fund); err != nil {
+			tb.Drop()
+			WriteError(w, Error{"error when calling /wallet/build: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	if !fee.IsZero() {
+		tb.AddMinerFee(fee)
+	}
+	for _, sco := range outputs {
+		tb.AddSiacoinOutput(sco)
+	}
+	for _, fc := range contracts {
+		tb.AddFileContract(fc)
+	}
+	for _, arb := range data {
+		tb.AddArbitraryData(arb)
+	}
+
+	if sign {
+		txnSet, err := tb.Sign(true)
+		if err != nil {
+			tb.Drop()
+			WriteError(w, Error{"error when calling /wallet/build: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		txn, parents = txnSet[len(txnSet)-1], txnSet[:len(txnSet)-1]
+	} else {
+		txn, parents = tb.View()
+	}
+	WriteJSON(w, WalletBuildPOST{
+		Parents:     encoding.Marshal(parents),
+		Transaction: encoding.Marshal(txn),
+	})
+}
+
+// walletBroadcastHandler handles API calls to /wallet/broadcast.
+func (api *API) walletBroadcastHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	txn, err := decodeTransaction(req.FormValue("transaction"))
+	if err != nil {
+		WriteError(w, Error{"error decoding transaction:" + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	err = api.wallet.BroadcastSigned(txn)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/broadcast: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// walletUnsignedTransactionHandler handles API calls to
+// /wallet/unsignedtransaction.
+func (api *API) walletUnsignedTransactionHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var outputs []types.SiacoinOutput
+	err := json.Unmarshal([]byte(req.FormValue("outputs")), &outputs)
+	if err != nil {
+		WriteError(w, Error{"could not decode outputs: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	txn, err := api.wallet.BuildUnsignedTransaction(outputs)
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/unsignedtransaction: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, WalletUnsignedTransactionPOST{
+		Transaction: encoding.Marshal(txn),
+	})
+}
+
+// walletUnspentHandler handles API calls to /wallet/unspent.
+func (api *API) walletUnspentHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	outputs, err := api.wallet.UnspentOutputs()
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/unspent: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, WalletUnspentGET{
+		Outputs: outputs,
+	})
+}
+
+// walletWatchHandlerGET handles GET calls to /wallet/watch.
+func (api *API) walletWatchHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	WriteJSON(w, WalletWatchGET{
+		Addresses: api.wallet.WatchAddresses(),
+	})
+}
+
+// walletWatchHandlerPOST handles POST calls to /wallet/watch.
+func (api *API) walletWatchHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var addrs []types.UnlockHash
+	if req.FormValue("addresses") != "" {
+		for _, s := range strings.Split(req.FormValue("addresses"), ",") {
+			addr, err := scanAddress(strings.TrimSpace(s))
+			if err != nil {
+				WriteError(w, Error{"could not read address '" + s + "' from POST call to /wallet/watch"}, http.StatusBadRequest)
+				return
+			}
+			addrs = append(addrs, addr)
+		}
+	}
+	var spks []types.SiaPublicKey
+	if req.FormValue("publickeys") != "" {
+		for _, s := range strings.Split(req.FormValue("publickeys"), ",") {
+			var spk types.SiaPublicKey
+			spk.LoadString(strings.TrimSpace(s))
+			if spk.Key == nil {
+				WriteError(w, Error{"could not read public key '" + s + "' from POST call to /wallet/watch"}, http.StatusBadRequest)
+				return
+			}
+			spks = append(spks, spk)
+		}
+	}
+	unused := req.FormValue("unused") == "true"
+
+	var err error
+	if req.FormValue("remove") == "true" {
+		if len(spks) != 0 {
+			WriteError(w, Error{"public keys cannot be removed from the watch-only set; remove their addresses instead"}, http.StatusBadRequest)
+			return
+		}
+		err = api.wallet.RemoveWatchAddresses(addrs, unused)
+	} else {
+		if len(addrs) != 0 {
+			err = api.wallet.AddWatchAddresses(addrs, unused)
+		}
+		if err == nil && len(spks) != 0 {
+			_, err = api.wallet.AddWatchPublicKeys(spks, unused)
+		}
+	}
+	if err != nil {
+		WriteError(w, Error{"error when calling /wallet/watch: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
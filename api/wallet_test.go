@@ -1,17 +1,23 @@
 package api
 
 import (
+	"encoding/base64"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/modules/consensus"
 	"github.com/NebulousLabs/Sia/modules/gateway"
@@ -20,6 +26,8 @@ import (
 	"github.com/NebulousLabs/Sia/modules/wallet"
 	"github.com/NebulousLabs/Sia/types"
 	"github.com/NebulousLabs/fastrand"
+
+	"golang.org/x/net/websocket"
 )
 
 // TestWalletGETEncrypted probes the GET call to /wallet when the
@@ -152,6 +160,129 @@ func TestWalletEncrypt(t *testing.T) {
 	}
 }
 
+// TestWalletUnlockTimeout checks that the wallet locks itself when it is
+// unlocked with a timeout.
+func TestWalletUnlockTimeout(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	walletPassword := "testpass"
+	key := crypto.TwofishKey(crypto.HashObject(walletPassword))
+	st, err := assembleServerTester(key, build.TempDir("api", t.Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	err = st.stdPostAPI("/wallet/lock", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlockValues := url.Values{}
+	unlockValues.Set("encryptionpassword", walletPassword)
+	unlockValues.Set("timeout", "-1")
+	err = st.stdPostAPI("/wallet/unlock", unlockValues)
+	if err == nil {
+		t.Fatal("expected an error for a bad timeout")
+	}
+	unlockValues.Set("timeout", "1")
+	err = st.stdPostAPI("/wallet/unlock", unlockValues)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.wallet.Unlocked() {
+		t.Fatal("wallet is not unlocked")
+	}
+	time.Sleep(2 * time.Second)
+	if st.wallet.Unlocked() {
+		t.Fatal("wallet did not lock itself after the timeout")
+	}
+}
+
+// TestWalletAutoLock checks that the auto-lock timeout can be set and queried
+// through the API, and that an event is sent when the wallet locks itself.
+func TestWalletAutoLock(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	walletPassword := "testpass"
+	key := crypto.TwofishKey(crypto.HashObject(walletPassword))
+	st, err := assembleServerTester(key, build.TempDir("api", t.Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	var wag WalletAutoLockGET
+	if err := st.getAPI("/wallet/autolock", &wag); err != nil {
+		t.Fatal(err)
+	}
+	if wag.Timeout != 0 || wag.TimeUntilLock != 0 {
+		t.Fatal("wallet should not have an auto-lock timer:", wag)
+	}
+	if err := st.stdPostAPI("/wallet/autolock", url.Values{"timeout": {"-1"}}); err == nil {
+		t.Fatal("expected an error for a bad timeout")
+	}
+
+	addr := st.server.listener.Addr().String()
+	config, err := websocket.NewConfig("ws://"+addr+"/wallet/autolock/events", "http://"+addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Header.Set("User-Agent", "Sia-Agent")
+	ws, err := websocket.DialConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+
+	// Setting the timeout starts the timer of the unlocked wallet.
+	if err := st.stdPostAPI("/wallet/autolock", url.Values{"timeout": {"1"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.getAPI("/wallet/autolock", &wag); err != nil {
+		t.Fatal(err)
+	}
+	if wag.Timeout != 1 || wag.TimeUntilLock > 1 || wag.UnlockedSince == 0 {
+		t.Fatal("wrong auto-lock state:", wag)
+	}
+	var le modules.LockEvent
+	ws.SetReadDeadline(time.Now().Add(10 * time.Second))
+	if err := websocket.JSON.Receive(ws, &le); err != nil {
+		t.Fatal(err)
+	}
+	if le.UnlockedSince != wag.UnlockedSince || le.Timestamp < le.UnlockedSince {
+		t.Fatal("wrong lock event:", le)
+	}
+	if st.wallet.Unlocked() {
+		t.Fatal("wallet did not lock itself after the timeout")
+	}
+	if err := st.getAPI("/wallet/autolock", &wag); err != nil {
+		t.Fatal(err)
+	}
+	if wag.TimeUntilLock != 0 || wag.LastAutoLock != le.Timestamp {
+		t.Fatal("wrong auto-lock state after locking:", wag)
+	}
+
+	// Disabling the auto-lock keeps the wallet unlocked.
+	if err := st.stdPostAPI("/wallet/autolock", url.Values{"timeout": {"0"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.stdPostAPI("/wallet/unlock", url.Values{"encryptionpassword": {walletPassword}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.getAPI("/wallet/autolock", &wag); err != nil {
+		t.Fatal(err)
+	}
+	if wag.Timeout != 0 || wag.TimeUntilLock != 0 {
+		t.Fatal("auto-lock was not disabled:", wag)
+	}
+}
+
 // TestWalletBlankEncrypt tries to encrypt and unlock the wallet
 // through the api using a blank encryption key - meaning that the wallet seed
 // returned by the encryption call can be used as the encryption key.
@@ -426,8 +557,10 @@ func TestIntegrationWalletSweepSeedPOST(t *testing.T) {
 	st.wallet.SendSiacoins(types.SiacoinPrecision.Mul64(100), addr.UnlockHash())
 	st.miner.AddBlock()
 
+	// Encode the seed in a dictionary other than the default. The dictionary
+	// should be detected from the seed.
 	seed, _, _ := w.PrimarySeed()
-	seedStr, _ := modules.SeedToString(seed, "english")
+	seedStr, _ := modules.SeedToString(seed, "german")
 
 	// Sweep the coins we sent
 	var wsp WalletSweepPOST
@@ -606,6 +739,13 @@ func TestWalletTransactionGETid(t *testing.T) {
 	} else if exp := txn.Inputs[0].Value.Sub(sentValue); !txn.Outputs[1].Value.Equals(exp) {
 		t.Errorf("expected first output to equal %v, got %v", exp, txn.Outputs[1].Value)
 	}
+	// The summary should show the sent value and the fees leaving the wallet.
+	summary := wtgid2.Summary
+	if net, exp := summary.OutgoingSiacoins.Sub(summary.IncomingSiacoins), sentValue.Add(summary.MinerFees); !net.Equals(exp) {
+		t.Errorf("expected the summary to show %v leaving the wallet, got %v", exp, net)
+	} else if len(summary.RelatedAddresses) != 1 || summary.RelatedAddresses[0] != (types.UnlockHash{}) {
+		t.Error("expected the destination to be the only related address, got", summary.RelatedAddresses)
+	}
 
 	// Create a second wallet and send money to that wallet.
 	st2, err := blankServerTester(t.Name() + "w2")
@@ -821,6 +961,20 @@ func TestWalletRelativePathErrorBackup(t *testing.T) {
 	if errStat != nil {
 		t.Error(errStat)
 	}
+
+	// Restoring the backup should error if its source is a relative path, or
+	// if the password is wrong.
+	err = st.stdPostAPI("/wallet/restore", url.Values{"source": {"test_wallet.backup"}})
+	if err == nil || err.Error() != "error when calling /wallet/restore: source must be an absolute path" {
+		t.Fatal(err)
+	}
+	err = st.stdPostAPI("/wallet/restore", url.Values{
+		"source":             {filepath.Join(walletTestDir, "test_wallet.backup")},
+		"encryptionpassword": {"wrong"},
+	})
+	if err == nil || err.Error() != "error when calling /wallet/restore: "+modules.ErrBadEncryptionKey.Error() {
+		t.Fatal(err)
+	}
 }
 
 // Tests that the /wallet/033x call checks for relative paths.
@@ -1420,3 +1574,1208 @@ func TestWalletSiacoins(t *testing.T) {
 		}
 	}
 }
+
+// TestWalletMultisig creates a multisig address through the api and checks
+// that the signing and merging endpoints reject bad transactions.
+func TestWalletMultisig(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	// Get a public key to use as the cosigner.
+	uc, err := st.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cosigner := uc.PublicKeys[0].String()
+
+	values := url.Values{}
+	values.Set("signaturesrequired", "3")
+	values.Set("publickeys", cosigner)
+	if err := st.stdPostAPI("/wallet/multisig/address", values); err == nil {
+		t.Fatal("expected an error when requiring more signatures than keys")
+	}
+	values.Set("signaturesrequired", "2")
+	values.Set("publickeys", "notakey")
+	if err := st.stdPostAPI("/wallet/multisig/address", values); err == nil {
+		t.Fatal("expected an error for a bad public key")
+	}
+	values.Set("publickeys", cosigner)
+	var wmap WalletMultisigAddressPOST
+	if err := st.postAPI("/wallet/multisig/address", values, &wmap); err != nil {
+		t.Fatal(err)
+	}
+	if wmap.UnlockConditions.SignaturesRequired != 2 || len(wmap.UnlockConditions.PublicKeys) != 2 {
+		t.Fatal("wrong unlock conditions:", wmap.UnlockConditions)
+	}
+	if wmap.Address != wmap.UnlockConditions.UnlockHash() {
+		t.Fatal("address does not match unlock conditions")
+	}
+
+	// The wallet has nothing to sign in a transaction without inputs.
+	values = url.Values{}
+	values.Set("transaction", base64.StdEncoding.EncodeToString(encoding.Marshal(types.Transaction{})))
+	if err := st.stdPostAPI("/wallet/sign", values); err == nil {
+		t.Fatal("expected an error when signing a transaction without inputs")
+	}
+	values.Set("transaction", "garbage")
+	if err := st.stdPostAPI("/wallet/sign", values); err == nil {
+		t.Fatal("expected an error for a bad transaction")
+	}
+
+	// Merging two different transactions should fail.
+	txn1 := base64.StdEncoding.EncodeToString(encoding.Marshal(types.Transaction{}))
+	txn2 := base64.StdEncoding.EncodeToString(encoding.Marshal(types.Transaction{ArbitraryData: [][]byte{{1}}}))
+	values = url.Values{}
+	values.Set("transactions", txn1+","+txn1)
+	var wmmp WalletMultisigMergePOST
+	if err := st.postAPI("/wallet/multisig/merge", values, &wmmp); err != nil {
+		t.Fatal(err)
+	}
+	values.Set("transactions", txn1+","+txn2)
+	if err := st.stdPostAPI("/wallet/multisig/merge", values); err == nil {
+		t.Fatal("expected an error when merging different transactions")
+	}
+}
+
+// TestWalletWatch adds and removes watch-only addresses through the api, and
+// checks the endpoints used for offline signing.
+func TestWalletWatch(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	// Watch a public key that does not belong to the wallet.
+	_, pk := crypto.GenerateKeyPair()
+	spk := types.Ed25519PublicKey(pk)
+	uc := types.UnlockConditions{
+		PublicKeys:         []types.SiaPublicKey{spk},
+		SignaturesRequired: 1,
+	}
+	values := url.Values{}
+	values.Set("publickeys", spk.String())
+	values.Set("unused", "true")
+	if err := st.stdPostAPI("/wallet/watch", values); err != nil {
+		t.Fatal(err)
+	}
+	var wwg WalletWatchGET
+	if err := st.getAPI("/wallet/watch", &wwg); err != nil {
+		t.Fatal(err)
+	}
+	if len(wwg.Addresses) != 1 || wwg.Addresses[0] != uc.UnlockHash() {
+		t.Fatal("wrong watch addresses:", wwg.Addresses)
+	}
+
+	// The address is unused, so there is nothing to spend.
+	values = url.Values{}
+	values.Set("outputs", `[{"value":"1000","unlockhash":"`+uc.UnlockHash().String()+`"}]`)
+	if err := st.stdPostAPI("/wallet/unsignedtransaction", values); err == nil {
+		t.Fatal("expected an error when spending from an unused watch-only address")
+	}
+
+	// Transactions with missing signatures cannot be broadcast.
+	unsigned := types.Transaction{
+		TransactionSignatures: []types.TransactionSignature{{CoveredFields: types.CoveredFields{WholeTransaction: true}}},
+	}
+	values = url.Values{}
+	values.Set("transaction", base64.StdEncoding.EncodeToString(encoding.Marshal(unsigned)))
+	if err := st.stdPostAPI("/wallet/broadcast", values); err == nil {
+		t.Fatal("expected an error when broadcasting an unsigned transaction")
+	}
+
+	// Remove the address.
+	values = url.Values{}
+	values.Set("addresses", uc.UnlockHash().String())
+	values.Set("remove", "true")
+	values.Set("unused", "true")
+	if err := st.stdPostAPI("/wallet/watch", values); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.getAPI("/wallet/watch", &wwg); err != nil {
+		t.Fatal(err)
+	}
+	if len(wwg.Addresses) != 0 {
+		t.Fatal("watch address was not removed:", wwg.Addresses)
+	}
+}
+
+// TestWalletUnspent lists the unspent outputs of the wallet and sends siacoins
+// from one of them.
+func TestWalletUnspent(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	var wug WalletUnspentGET
+	if err := st.getAPI("/wallet/unspent", &wug); err != nil {
+		t.Fatal(err)
+	}
+	if len(wug.Outputs) == 0 {
+		t.Fatal("wallet has no unspent outputs")
+	}
+	var chosen modules.UnspentOutput
+	for _, uo := range wug.Outputs {
+		if uo.FundType == types.SpecifierSiacoinOutput {
+			chosen = uo
+			break
+		}
+	}
+	if chosen.Value.IsZero() {
+		t.Fatal("wallet has no siacoin outputs:", wug.Outputs)
+	}
+
+	// Send from an unknown output.
+	values := url.Values{}
+	values.Set("amount", "1000")
+	values.Set("destination", types.UnlockHash{}.String())
+	values.Set("inputs", types.SiacoinOutputID{1}.String())
+	if err := st.stdPostAPI("/wallet/siacoins", values); err == nil {
+		t.Fatal("expected an error when sending from an unknown output")
+	}
+
+	// Send from the chosen output.
+	values.Set("inputs", chosen.ID.String())
+	var wsp WalletSiacoinsPOST
+	if err := st.postAPI("/wallet/siacoins", values, &wsp); err != nil {
+		t.Fatal(err)
+	}
+	if len(wsp.TransactionIDs) == 0 {
+		t.Fatal("no transactions were created")
+	}
+
+	// Send only from outputs with enough confirmations.
+	values.Set("minconfirmations", "1")
+	if err := st.stdPostAPI("/wallet/siacoins", values); err == nil {
+		t.Fatal("expected an error when combining inputs and minconfirmations")
+	}
+	values.Del("inputs")
+	values.Set("minconfirmations", fmt.Sprint(st.cs.Height()+2))
+	if err := st.stdPostAPI("/wallet/siacoins", values); err == nil {
+		t.Fatal("expected an error when no output has enough confirmations")
+	}
+	if _, err := st.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	values.Set("minconfirmations", "1")
+	if err := st.postAPI("/wallet/siacoins", values, &wsp); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestWalletSiacoinsPreview previews siacoin transfers without sending them.
+func TestWalletSiacoinsPreview(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	values := url.Values{}
+	values.Set("amount", types.SiacoinPrecision.String())
+	values.Set("destination", types.UnlockHash{}.String())
+	values.Set("preview", "true")
+	var wspp WalletSiacoinsPreviewPOST
+	if err := st.postAPI("/wallet/siacoins", values, &wspp); err != nil {
+		t.Fatal(err)
+	}
+	if wspp.Size == 0 || wspp.Fee.IsZero() || len(wspp.Inputs) == 0 {
+		t.Fatal("incomplete preview:", wspp)
+	}
+
+	outputs, err := json.Marshal([]types.SiacoinOutput{
+		{Value: types.SiacoinPrecision, UnlockHash: types.UnlockHash{1}},
+		{Value: types.SiacoinPrecision, UnlockHash: types.UnlockHash{2}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	values = url.Values{}
+	values.Set("outputs", string(outputs))
+	values.Set("preview", "true")
+	if err := st.postAPI("/wallet/siacoins", values, &wspp); err != nil {
+		t.Fatal(err)
+	}
+	if wspp.Size == 0 || wspp.Fee.IsZero() || len(wspp.Inputs) == 0 {
+		t.Fatal("incomplete preview:", wspp)
+	}
+	if len(st.tpool.TransactionList()) != 0 {
+		t.Fatal("preview was broadcast")
+	}
+}
+
+// TestWalletDefrag changes the defrag settings and defragments the wallet.
+func TestWalletDefrag(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	values := url.Values{}
+	values.Set("batchsize", "1")
+	if err := st.stdPostAPI("/wallet/defrag", values); err == nil {
+		t.Fatal("expected an error when setting a batch size of 1")
+	}
+	values = url.Values{}
+	values.Set("enabled", "false")
+	values.Set("startindex", "0")
+	values.Set("batchsize", "2")
+	if err := st.stdPostAPI("/wallet/defrag", values); err != nil {
+		t.Fatal(err)
+	}
+	var wdg WalletDefragGET
+	if err := st.getAPI("/wallet/defrag", &wdg); err != nil {
+		t.Fatal(err)
+	}
+	if wdg.Settings.Enabled || wdg.Settings.StartIndex != 0 || wdg.Settings.BatchSize != 2 || wdg.Settings.Threshold == 0 {
+		t.Fatal("wrong defrag settings:", wdg.Settings)
+	}
+
+	// Mine a block so that the wallet has at least two outputs.
+	if _, err := st.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	var wdp WalletDefragmentPOST
+	if err := st.postAPI("/wallet/defragment", url.Values{}, &wdp); err != nil {
+		t.Fatal(err)
+	}
+	if len(wdp.TransactionIDs) != 2 {
+		t.Fatal("expected 2 transactions, got", len(wdp.TransactionIDs))
+	}
+}
+
+// TestWalletAddressBook labels an address of the wallet and manages the
+// address book.
+func TestWalletAddressBook(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	// Label an address of the wallet.
+	var wag WalletAddressGET
+	if err := st.getAPI("/wallet/address", &wag); err != nil {
+		t.Fatal(err)
+	}
+	values := url.Values{}
+	values.Set("address", wag.Address.String())
+	values.Set("label", "my savings")
+	if err := st.stdPostAPI("/wallet/labels", values); err != nil {
+		t.Fatal(err)
+	}
+	var wlg WalletLabelsGET
+	if err := st.getAPI("/wallet/labels", &wlg); err != nil {
+		t.Fatal(err)
+	}
+	if len(wlg.Labels) != 1 || wlg.Labels[0].Address != wag.Address || wlg.Labels[0].Label != "my savings" {
+		t.Fatal("wrong labels:", wlg.Labels)
+	}
+
+	// Add and remove a payee.
+	values = url.Values{}
+	values.Set("name", "alice")
+	values.Set("address", types.UnlockHash{1}.String())
+	if err := st.stdPostAPI("/wallet/addressbook", values); err != nil {
+		t.Fatal(err)
+	}
+	var wabg WalletAddressBookGET
+	if err := st.getAPI("/wallet/addressbook", &wabg); err != nil {
+		t.Fatal(err)
+	}
+	if len(wabg.Entries) != 1 || wabg.Entries[0].Name != "alice" || wabg.Entries[0].Address != (types.UnlockHash{1}) {
+		t.Fatal("wrong address book:", wabg.Entries)
+	}
+	values = url.Values{}
+	values.Set("name", "alice")
+	values.Set("remove", "true")
+	if err := st.stdPostAPI("/wallet/addressbook", values); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.stdPostAPI("/wallet/addressbook", values); err == nil {
+		t.Fatal("expected an error when removing an unknown entry")
+	}
+	if err := st.getAPI("/wallet/addressbook", &wabg); err != nil {
+		t.Fatal(err)
+	}
+	if len(wabg.Entries) != 0 {
+		t.Fatal("entry was not removed:", wabg.Entries)
+	}
+}
+
+// TestWalletTemplates probes the /wallet/templates endpoints.
+func TestWalletTemplates(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	uc, err := st.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cosigner := uc.PublicKeys[0].String()
+
+	// Store a template.
+	values := url.Values{}
+	values.Set("name", "treasury")
+	values.Set("signaturesrequired", "3")
+	values.Set("publickeys", cosigner)
+	if err := st.stdPostAPI("/wallet/templates", values); err == nil {
+		t.Fatal("expected an error when requiring more signatures than keys")
+	}
+	values.Set("signaturesrequired", "2")
+	if err := st.stdPostAPI("/wallet/templates", values); err != nil {
+		t.Fatal(err)
+	}
+	var wtg WalletTemplatesGET
+	if err := st.getAPI("/wallet/templates", &wtg); err != nil {
+		t.Fatal(err)
+	}
+	if len(wtg.Templates) != 1 || wtg.Templates[0].Name != "treasury" || wtg.Templates[0].SignaturesRequired != 2 {
+		t.Fatal("wrong templates:", wtg.Templates)
+	}
+
+	// Generate two addresses from the template.
+	values = url.Values{}
+	values.Set("name", "treasury")
+	var wtap1, wtap2 WalletTemplatesAddressPOST
+	if err := st.postAPI("/wallet/templates/address", values, &wtap1); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.postAPI("/wallet/templates/address", values, &wtap2); err != nil {
+		t.Fatal(err)
+	}
+	if wtap1.Address != wtap1.UnlockConditions.UnlockHash() || len(wtap1.UnlockConditions.PublicKeys) != 2 {
+		t.Fatal("wrong unlock conditions:", wtap1)
+	}
+	if wtap1.Address == wtap2.Address {
+		t.Fatal("template generated the same address twice")
+	}
+
+	// Remove the template.
+	values.Set("remove", "true")
+	if err := st.stdPostAPI("/wallet/templates", values); err != nil {
+		t.Fatal(err)
+	}
+	values.Del("remove")
+	if err := st.stdPostAPI("/wallet/templates/address", values); err == nil {
+		t.Fatal("expected an error when using a removed template")
+	}
+}
+
+// TestWalletTimelocked probes the /wallet/timelocked endpoints.
+func TestWalletTimelocked(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	// Create a timelocked address of the wallet and fund it.
+	timelock := st.cs.Height() + 10
+	values := url.Values{}
+	values.Set("timelock", fmt.Sprint(timelock))
+	var wta WalletTimelockedAddressPOST
+	if err := st.postAPI("/wallet/timelocked/address", values, &wta); err != nil {
+		t.Fatal(err)
+	}
+	if wta.UnlockConditions.Timelock != timelock || wta.Address != wta.UnlockConditions.UnlockHash() {
+		t.Fatal("wrong timelocked address:", wta)
+	}
+	amount := types.SiacoinPrecision.Mul64(100)
+	if _, err := st.wallet.SendSiacoins(amount, wta.Address); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	var wtg WalletTimelockedGET
+	if err := st.getAPI("/wallet/timelocked", &wtg); err != nil {
+		t.Fatal(err)
+	}
+	if len(wtg.Balances) != 1 || wtg.Balances[0].UnlockHeight != timelock || !wtg.Balances[0].Siacoins.Equals(amount) {
+		t.Fatal("wrong timelocked balances:", wtg.Balances)
+	}
+
+	// The timelocked address of a public key is not tracked by the wallet.
+	spk := wta.UnlockConditions.PublicKeys[0]
+	values.Set("publickey", spk.String())
+	if err := st.postAPI("/wallet/timelocked/address", values, &wta); err != nil {
+		t.Fatal(err)
+	}
+	expected := types.UnlockConditions{
+		Timelock:           timelock,
+		PublicKeys:         []types.SiaPublicKey{spk},
+		SignaturesRequired: 1,
+	}
+	if wta.Address != expected.UnlockHash() {
+		t.Fatal("wrong address for the public key:", wta.Address)
+	}
+	values.Set("publickey", "foo")
+	if err := st.postAPI("/wallet/timelocked/address", values, &wta); err == nil {
+		t.Fatal("expected an error for an invalid public key")
+	}
+}
+
+// TestWalletScheduled probes the GET and POST calls to /wallet/scheduled.
+func TestWalletScheduled(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	// Sign a transaction set without broadcasting it.
+	amount := types.SiacoinPrecision.Mul64(100)
+	tb := st.wallet.StartTransaction()
+	if err := tb.FundSiacoins(amount); err != nil {
+		t.Fatal(err)
+	}
+	tb.AddSiacoinOutput(types.SiacoinOutput{Value: amount, UnlockHash: types.UnlockHash{}})
+	txns, err := tb.Sign(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var encoded []string
+	for _, txn := range txns {
+		encoded = append(encoded, base64.StdEncoding.EncodeToString(encoding.Marshal(txn)))
+	}
+
+	// A schedule needs a height or a timestamp.
+	values := url.Values{}
+	values.Set("transactions", strings.Join(encoded, ","))
+	var wsp WalletScheduledPOST
+	if err := st.postAPI("/wallet/scheduled", values, &wsp); err == nil {
+		t.Fatal("expected an error for a schedule without a height or timestamp")
+	}
+	height := st.cs.Height() + 2
+	values.Set("height", fmt.Sprint(height))
+	if err := st.postAPI("/wallet/scheduled", values, &wsp); err != nil {
+		t.Fatal(err)
+	}
+	if wsp.ID != txns[len(txns)-1].ID() {
+		t.Fatal("wrong id for the scheduled set:", wsp.ID)
+	}
+	var wsg WalletScheduledGET
+	if err := st.getAPI("/wallet/scheduled", &wsg); err != nil {
+		t.Fatal(err)
+	}
+	if len(wsg.Scheduled) != 1 || wsg.Scheduled[0].ID != wsp.ID || wsg.Scheduled[0].Height != height {
+		t.Fatal("wrong scheduled sets:", wsg.Scheduled)
+	}
+
+	// Cancel the set.
+	values = url.Values{}
+	values.Set("cancel", wsp.ID.String())
+	if err := st.stdPostAPI("/wallet/scheduled", values); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.getAPI("/wallet/scheduled", &wsg); err != nil {
+		t.Fatal(err)
+	}
+	if len(wsg.Scheduled) != 0 {
+		t.Fatal("set was not cancelled:", wsg.Scheduled)
+	}
+	if err := st.stdPostAPI("/wallet/scheduled", values); err == nil {
+		t.Fatal("expected an error when cancelling an unknown set")
+	}
+}
+
+// TestWalletBuild builds transactions with arbitrary data through
+// /wallet/build, including a transaction that is funded by two calls.
+func TestWalletBuild(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	// Embed data in a signed transaction and submit it.
+	data := []byte("proof of existence")
+	values := url.Values{}
+	values.Set("arbitrarydata", base64.StdEncoding.EncodeToString(data))
+	values.Set("fee", types.SiacoinPrecision.String())
+	values.Set("sign", "true")
+	var wbp WalletBuildPOST
+	if err := st.postAPI("/wallet/build", values, &wbp); err != nil {
+		t.Fatal(err)
+	}
+	var txn types.Transaction
+	if err := encoding.Unmarshal(wbp.Transaction, &txn); err != nil {
+		t.Fatal(err)
+	}
+	if len(txn.ArbitraryData) != 1 || string(txn.ArbitraryData[0]) != string(append(modules.PrefixNonSia[:], data...)) {
+		t.Fatal("wrong arbitrary data:", txn.ArbitraryData)
+	}
+	values = url.Values{}
+	values.Set("parents", base64.StdEncoding.EncodeToString(wbp.Parents))
+	values.Set("transaction", base64.StdEncoding.EncodeToString(wbp.Transaction))
+	if err := st.stdPostAPI("/tpool/raw", values); err != nil {
+		t.Fatal(err)
+	}
+
+	// Build an unsigned transaction paying an output, then fund it further
+	// with a second call, as a cosigner would, and sign it.
+	amount := types.SiacoinPrecision.Mul64(100)
+	outputs := []types.SiacoinOutput{{Value: amount, UnlockHash: types.UnlockHash{1}}}
+	outputsJSON, err := json.Marshal(outputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values = url.Values{}
+	values.Set("outputs", string(outputsJSON))
+	if err := st.postAPI("/wallet/build", values, &wbp); err != nil {
+		t.Fatal(err)
+	}
+	values = url.Values{}
+	values.Set("parents", base64.StdEncoding.EncodeToString(wbp.Parents))
+	values.Set("transaction", base64.StdEncoding.EncodeToString(wbp.Transaction))
+	values.Set("fund", amount.String())
+	values.Set("fee", amount.String())
+	for i := 0; i < 3; i++ {
+		if _, err := st.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := st.postAPI("/wallet/build", values, &wbp); err != nil {
+		t.Fatal(err)
+	}
+	txn = types.Transaction{}
+	if err := encoding.Unmarshal(wbp.Transaction, &txn); err != nil {
+		t.Fatal(err)
+	}
+	if len(txn.SiacoinInputs) < 2 || len(txn.MinerFees) != 1 || len(txn.TransactionSignatures) != 0 {
+		t.Fatal("wrong transaction after the second call:", txn)
+	}
+	if err := st.wallet.SignTransaction(&txn, nil); err != nil {
+		t.Fatal(err)
+	}
+	values = url.Values{}
+	values.Set("parents", base64.StdEncoding.EncodeToString(wbp.Parents))
+	values.Set("transaction", base64.StdEncoding.EncodeToString(encoding.Marshal(txn)))
+	if err := st.stdPostAPI("/tpool/raw", values); err != nil {
+		t.Fatal(err)
+	}
+
+	// Invalid parameters are rejected.
+	values = url.Values{}
+	values.Set("arbitrarydata", "not base64!")
+	if err := st.postAPI("/wallet/build", values, &wbp); err == nil {
+		t.Fatal("expected an error for invalid arbitrary data")
+	}
+	values = url.Values{}
+	values.Set("fund", "foo")
+	if err := st.postAPI("/wallet/build", values, &wbp); err == nil {
+		t.Fatal("expected an error for an invalid fund amount")
+	}
+}
+
+// TestWalletBalance checks that /wallet/balance reports the maturing miner
+// payouts of the wallet separately from its confirmed balance.
+func TestWalletBalance(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	var wbg WalletBalanceGET
+	if err := st.getAPI("/wallet/balance", &wbg); err != nil {
+		t.Fatal(err)
+	}
+	var wg WalletGET
+	if err := st.getAPI("/wallet", &wg); err != nil {
+		t.Fatal(err)
+	}
+	if !wbg.Confirmed.Equals(wg.ConfirmedSiacoinBalance) {
+		t.Fatal("confirmed balance does not match /wallet:", wbg.Confirmed, wg.ConfirmedSiacoinBalance)
+	}
+	if len(wbg.MaturingOutputs) == 0 || wbg.Maturing.IsZero() {
+		t.Fatal("expected maturing miner payouts:", wbg)
+	}
+}
+
+// TestWalletMemos attaches a memo to a transaction and checks that it is
+// returned with the transaction history.
+func TestWalletMemos(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	var whg WalletHistoryGET
+	if err := st.getAPI("/wallet/history", &whg); err != nil {
+		t.Fatal(err)
+	}
+	if len(whg.History) == 0 {
+		t.Fatal("expected a non-empty history")
+	}
+	txid := whg.History[len(whg.History)-1].TransactionID
+
+	values := url.Values{}
+	values.Set("transactionid", types.TransactionID{1}.String())
+	values.Set("memo", "rent")
+	if err := st.stdPostAPI("/wallet/memos", values); err == nil {
+		t.Fatal("expected an error for an unknown transaction")
+	}
+	values.Set("transactionid", txid.String())
+	if err := st.stdPostAPI("/wallet/memos", values); err != nil {
+		t.Fatal(err)
+	}
+
+	var wmg WalletMemosGET
+	if err := st.getAPI("/wallet/memos", &wmg); err != nil {
+		t.Fatal(err)
+	}
+	if len(wmg.Memos) != 1 || wmg.Memos[0].TransactionID != txid || wmg.Memos[0].Memo != "rent" {
+		t.Fatal("wrong memos:", wmg.Memos)
+	}
+	var wtg WalletTransactionGETid
+	if err := st.getAPI("/wallet/transaction/"+txid.String(), &wtg); err != nil {
+		t.Fatal(err)
+	}
+	if wtg.Memo != "rent" {
+		t.Fatal("transaction was returned without its memo:", wtg.Memo)
+	}
+	var wtsg WalletTransactionsGET
+	if err := st.getAPI("/wallet/transactions?startheight=0&endheight=10000", &wtsg); err != nil {
+		t.Fatal(err)
+	}
+	if len(wtsg.Memos) != 1 || wtsg.Memos[0].Memo != "rent" {
+		t.Fatal("transactions were returned without their memos:", wtsg.Memos)
+	}
+	if err := st.getAPI("/wallet/history", &whg); err != nil {
+		t.Fatal(err)
+	}
+	if memo := whg.History[len(whg.History)-1].Memo; memo != "rent" {
+		t.Fatal("history entry was returned without its memo:", memo)
+	}
+}
+
+// TestWalletHistory checks the JSON and CSV exports of the wallet history.
+func TestWalletHistory(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	// The final running balance includes the miner payouts that have not
+	// matured yet.
+	var whg WalletHistoryGET
+	if err := st.getAPI("/wallet/history", &whg); err != nil {
+		t.Fatal(err)
+	}
+	var wbg WalletBalanceGET
+	if err := st.getAPI("/wallet/balance", &wbg); err != nil {
+		t.Fatal(err)
+	}
+	if len(whg.History) == 0 {
+		t.Fatal("expected a non-empty history")
+	}
+	last := whg.History[len(whg.History)-1]
+	if !last.SiacoinBalance.Equals(wbg.Confirmed.Add(wbg.Maturing)) {
+		t.Fatal("final balance does not match /wallet/balance:", last.SiacoinBalance, wbg.Confirmed, wbg.Maturing)
+	}
+
+	// The height range filters the entries without resetting the balances.
+	start := whg.History[len(whg.History)-1].ConfirmationHeight
+	var ranged WalletHistoryGET
+	if err := st.getAPI(fmt.Sprintf("/wallet/history?startheight=%v", start), &ranged); err != nil {
+		t.Fatal(err)
+	}
+	if len(ranged.History) == 0 || len(ranged.History) >= len(whg.History) {
+		t.Fatal("wrong number of entries in range:", len(ranged.History))
+	}
+	if !ranged.History[len(ranged.History)-1].SiacoinBalance.Equals(last.SiacoinBalance) {
+		t.Fatal("ranged history has a different running balance")
+	}
+
+	// The CSV export has a header and a record for each entry.
+	resp, err := HttpGET("http://" + st.server.listener.Addr().String() + "/wallet/history?format=csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/csv" {
+		t.Fatal("wrong content type:", ct)
+	}
+	records, err := csv.NewReader(resp.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != len(whg.History)+1 {
+		t.Fatal("wrong number of CSV records:", len(records), len(whg.History))
+	}
+
+	if err := st.getAPI("/wallet/history?format=xml", &whg); err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+}
+
+// TestNamedWallets creates a named wallet through the API and checks that
+// wallet calls with a 'wallet' parameter are passed to it.
+func TestNamedWallets(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	nw, err := wallet.NewNamedWallets(st.cs, st.tpool, filepath.Join(st.dir, modules.NamedWalletsDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nw.Close()
+	ts := httptest.NewServer(New("Sia-Agent", "", st.cs, nil, st.gateway, nil, nil, nil, st.tpool, st.wallet, nw))
+	defer ts.Close()
+
+	// call makes a GET call, or a POST call if values is not nil, and
+	// decodes the response into obj if obj is not nil.
+	call := func(path string, values url.Values, obj interface{}) error {
+		var resp *http.Response
+		var err error
+		if values == nil {
+			resp, err = HttpGET(ts.URL + path)
+		} else {
+			resp, err = HttpPOST(ts.URL+path, values.Encode())
+		}
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if non2xx(resp.StatusCode) {
+			return decodeError(resp)
+		}
+		if obj == nil {
+			return nil
+		}
+		return json.NewDecoder(resp.Body).Decode(obj)
+	}
+
+	if err := call("/wallets/create", url.Values{"name": {"hot"}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := call("/wallets/create", url.Values{"name": {"hot"}}, nil); err == nil {
+		t.Fatal("expected an error when creating a wallet twice")
+	}
+	var wsg WalletsGET
+	if err := call("/wallets", nil, &wsg); err != nil {
+		t.Fatal(err)
+	}
+	if len(wsg.Wallets) != 1 || wsg.Wallets[0].Name != "hot" || wsg.Wallets[0].Encrypted {
+		t.Fatal("wrong named wallets:", wsg.Wallets)
+	}
+
+	// Initialize and unlock the named wallet.
+	var wip WalletInitPOST
+	if err := call("/wallet/init?wallet=hot", url.Values{}, &wip); err != nil {
+		t.Fatal(err)
+	}
+	if err := call("/wallet/unlock?wallet=hot", url.Values{"encryptionpassword": {wip.PrimarySeed}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := call("/wallets", nil, &wsg); err != nil {
+		t.Fatal(err)
+	}
+	if !wsg.Wallets[0].Encrypted || !wsg.Wallets[0].Unlocked {
+		t.Fatal("named wallet should be encrypted and unlocked:", wsg.Wallets)
+	}
+
+	// The named wallet has its own addresses and balance.
+	var hotAddr, mainAddr WalletAddressGET
+	if err := call("/wallet/address?wallet=hot", nil, &hotAddr); err != nil {
+		t.Fatal(err)
+	}
+	if err := call("/wallet/address", nil, &mainAddr); err != nil {
+		t.Fatal(err)
+	}
+	var hotSeeds, mainSeeds WalletSeedsGET
+	if err := call("/wallet/seeds?wallet=hot", nil, &hotSeeds); err != nil {
+		t.Fatal(err)
+	}
+	if err := call("/wallet/seeds", nil, &mainSeeds); err != nil {
+		t.Fatal(err)
+	}
+	if hotSeeds.PrimarySeed != wip.PrimarySeed || mainSeeds.PrimarySeed == wip.PrimarySeed {
+		t.Fatal("wallet calls were not passed to the selected wallet")
+	}
+	var wg WalletGET
+	if err := call("/wallet?wallet=hot", nil, &wg); err != nil {
+		t.Fatal(err)
+	}
+	if !wg.ConfirmedSiacoinBalance.IsZero() {
+		t.Fatal("new named wallet should have no balance:", wg.ConfirmedSiacoinBalance)
+	}
+
+	if err := call("/wallet?wallet=mining", nil, &wg); err == nil {
+		t.Fatal("expected an error for an unknown wallet")
+	}
+}
+
+// TestWalletGapLimit checks that the scan gap limit can be read and set.
+func TestWalletGapLimit(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	if err := st.stdPostAPI("/wallet/gaplimit", url.Values{"gaplimit": {"5000"}}); err != nil {
+		t.Fatal(err)
+	}
+	var wgg WalletGapLimitGET
+	if err := st.getAPI("/wallet/gaplimit", &wgg); err != nil {
+		t.Fatal(err)
+	}
+	if wgg.GapLimit != 5000 {
+		t.Fatal("wrong gap limit:", wgg.GapLimit)
+	}
+	if err := st.stdPostAPI("/wallet/gaplimit", url.Values{"gaplimit": {"0"}}); err == nil {
+		t.Fatal("expected an error for a gap limit of 0")
+	}
+}
+
+// TestWalletMessage signs a message with a wallet address and verifies it.
+func TestWalletMessage(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	var wag WalletAddressGET
+	if err := st.getAPI("/wallet/address", &wag); err != nil {
+		t.Fatal(err)
+	}
+	msg := "I control this address"
+	var wmsp WalletMessageSignPOST
+	err = st.postAPI("/wallet/message/sign", url.Values{
+		"address": {wag.Address.String()},
+		"message": {msg},
+	}, &wmsp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sigJSON, err := json.Marshal(wmsp.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		message string
+		valid   bool
+	}{
+		{msg, true},
+		{msg + "!", false},
+	} {
+		var wmvp WalletMessageVerifyPOST
+		err = st.postAPI("/wallet/message/verify", url.Values{
+			"address":   {wag.Address.String()},
+			"message":   {test.message},
+			"signature": {string(sigJSON)},
+		}, &wmvp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if wmvp.Valid != test.valid {
+			t.Fatalf("expected valid to be %v for %q", test.valid, test.message)
+		}
+	}
+}
+
+// TestWalletSwap creates, audits and redeems a swap through the API.
+func TestWalletSwap(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+	// Storage proofs of partial segments are only valid after the hardfork
+	// at height 10 of the testing release.
+	for st.cs.Height() < 10 {
+		if _, err := st.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var wag WalletAddressGET
+	if err := st.getAPI("/wallet/address", &wag); err != nil {
+		t.Fatal(err)
+	}
+	var wscp WalletSwapCreatePOST
+	err = st.postAPI("/wallet/swap/create", url.Values{
+		"amount":        {types.SiacoinPrecision.Mul64(100).String()},
+		"redeemaddress": {wag.Address.String()},
+		"timelock":      {fmt.Sprint(st.cs.Height() + 20)},
+	}, &wscp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wscp.Swap.Status != modules.AtomicSwapPending || wscp.Swap.RedeemAddress != wag.Address {
+		t.Fatal("wrong swap:", wscp.Swap)
+	}
+
+	var wsap WalletSwapAuditPOST
+	err = st.postAPI("/wallet/swap/audit", url.Values{
+		"transaction": {base64.StdEncoding.EncodeToString(wscp.Transaction)},
+	}, &wsap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wsap.Swap.ID != wscp.Swap.ID || wsap.Swap.SecretHash != wscp.Swap.SecretHash {
+		t.Fatal("audit does not match the swap:", wsap.Swap)
+	}
+
+	// Redeem the swap once its window opens.
+	for st.cs.Height()+1 < wscp.Swap.WindowStart {
+		if _, err := st.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = st.stdPostAPI("/wallet/swap/redeem", url.Values{
+		"id":     {wscp.Swap.ID.String()},
+		"secret": {crypto.Hash{}.String()},
+	})
+	if err == nil {
+		t.Fatal("expected an error when redeeming with the wrong secret")
+	}
+	var wsrp WalletSwapRedeemPOST
+	err = st.postAPI("/wallet/swap/redeem", url.Values{
+		"id":     {wscp.Swap.ID.String()},
+		"secret": {wscp.Swap.Secret.String()},
+	}, &wsrp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wsrp.TransactionIDs) == 0 {
+		t.Fatal("no transactions were broadcast")
+	}
+	if _, err := st.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	var wsg WalletSwapsGET
+	if err := st.getAPI("/wallet/swaps", &wsg); err != nil {
+		t.Fatal(err)
+	}
+	if len(wsg.Swaps) != 1 || wsg.Swaps[0].Status != modules.AtomicSwapRedeemed {
+		t.Fatal("swap should be redeemed:", wsg.Swaps)
+	}
+}
+
+// TestWalletPayments checks that payments to the wallet are streamed over the
+// /wallet/payments websocket.
+func TestWalletPayments(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	// Create and fund a second wallet that will pay the server's wallet.
+	payer, err := wallet.New(st.cs, st.tpool, filepath.Join(st.dir, "payer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer payer.Close()
+	key := crypto.TwofishKey{1}
+	if _, err := payer.Encrypt(key); err != nil {
+		t.Fatal(err)
+	}
+	if err := payer.Unlock(key); err != nil {
+		t.Fatal(err)
+	}
+	uc, err := payer.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.wallet.SendSiacoins(types.SiacoinPrecision.Mul64(1000), uc.UnlockHash()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	addr := st.server.listener.Addr().String()
+	config, err := websocket.NewConfig("ws://"+addr+"/wallet/payments?confirmations=1", "http://"+addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Header.Set("User-Agent", "Sia-Agent")
+	ws, err := websocket.DialConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	nextEvent := func() modules.PaymentEvent {
+		var pe modules.PaymentEvent
+		ws.SetReadDeadline(time.Now().Add(10 * time.Second))
+		if err := websocket.JSON.Receive(ws, &pe); err != nil {
+			t.Fatal(err)
+		}
+		return pe
+	}
+
+	var wag WalletAddressGET
+	if err := st.getAPI("/wallet/address", &wag); err != nil {
+		t.Fatal(err)
+	}
+	amount := types.SiacoinPrecision.Mul64(100)
+	if _, err := payer.SendSiacoins(amount, wag.Address); err != nil {
+		t.Fatal(err)
+	}
+	pe := nextEvent()
+	if pe.Confirmations != 0 || pe.Address != wag.Address || pe.Value.Cmp(amount) != 0 {
+		t.Fatal("wrong unconfirmed payment event:", pe)
+	}
+	if _, err := st.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	if next := nextEvent(); next.ID != pe.ID || next.Confirmations != 1 {
+		t.Fatal("wrong confirmed payment event:", next)
+	}
+
+	// Too many confirmations are rejected.
+	resp, err := HttpGET("http://" + addr + "/wallet/payments?confirmations=1000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatal("expected a bad request, got", resp.Status)
+	}
+}
+
+// TestWalletBumpFee checks that /wallet/bumpfee lists and bumps the fee of
+// stuck transactions.
+func TestWalletBumpFee(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	// Send coins without a fee.
+	amount := types.SiacoinPrecision.Mul64(100)
+	tb := st.wallet.StartTransaction()
+	if err := tb.FundSiacoins(amount); err != nil {
+		t.Fatal(err)
+	}
+	tb.AddSiacoinOutput(types.SiacoinOutput{Value: amount})
+	txnSet, err := tb.Sign(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.tpool.AcceptTransactionSet(txnSet); err != nil {
+		t.Fatal(err)
+	}
+
+	var wbg WalletBumpFeeGET
+	if err := st.getAPI("/wallet/bumpfee", &wbg); err != nil {
+		t.Fatal(err)
+	}
+	if len(wbg.TransactionIDs) != 1 || wbg.TransactionIDs[0] != txnSet[0].ID() {
+		t.Fatal("wrong bumpable transactions:", wbg.TransactionIDs)
+	}
+	if err := st.stdPostAPI("/wallet/bumpfee", url.Values{"id": {txnSet[1].ID().String()}}); err == nil {
+		t.Fatal("expected an error when bumping a transaction without change")
+	}
+	var wbp WalletBumpFeePOST
+	if err := st.postAPI("/wallet/bumpfee", url.Values{"id": {wbg.TransactionIDs[0].String()}}, &wbp); err != nil {
+		t.Fatal(err)
+	}
+	if len(wbp.TransactionIDs) != 2 || wbp.TransactionIDs[0] != txnSet[0].ID() {
+		t.Fatal("wrong bump transactions:", wbp.TransactionIDs)
+	}
+	if err := st.getAPI("/wallet/bumpfee", &wbg); err != nil {
+		t.Fatal(err)
+	}
+	if len(wbg.TransactionIDs) != 0 {
+		t.Fatal("no transaction should be bumpable after the bump:", wbg.TransactionIDs)
+	}
+}
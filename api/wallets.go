@@ -0,0 +1,103 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type (
+	// NamedWalletGET describes a named wallet.
+	NamedWalletGET struct {
+		Name      string `json:"name"`
+		Encrypted bool   `json:"encrypted"`
+		Unlocked  bool   `json:"unlocked"`
+	}
+
+	// WalletsGET contains the named wallets returned by a GET call to
+	// /wallets.
+	WalletsGET struct {
+		Wallets []NamedWalletGET `json:"wallets"`
+	}
+)
+
+// namedWalletAPI returns an API that serves the wallet calls of the named
+// wallet.
+func (api *API) namedWalletAPI(name string) (*API, error) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	if a, exists := api.namedWalletAPIs[name]; exists {
+		return a, nil
+	}
+	w, err := api.namedWallets.NamedWallet(name)
+	if err != nil {
+		return nil, err
+	}
+	a := &API{
+		cs:       api.cs,
+		explorer: api.explorer,
+		gateway:  api.gateway,
+		host:     api.host,
+		miner:    api.miner,
+		renter:   api.renter,
+		tpool:    api.tpool,
+		wallet:   w,
+	}
+	router := httprouter.New()
+	router.NotFound = http.HandlerFunc(UnrecognizedCallHandler)
+	router.RedirectTrailingSlash = false
+	a.buildWalletRoutes(router, api.requiredPassword)
+	a.router = router
+	api.namedWalletAPIs[name] = a
+	return a, nil
+}
+
+// namedWalletRouter returns a handler that passes wallet calls with a
+// 'wallet' query string parameter to the API of the named wallet, and all
+// other calls to h.
+func (api *API) namedWalletRouter(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		name := req.URL.Query().Get("wallet")
+		if name == "" || (req.URL.Path != "/wallet" && !strings.HasPrefix(req.URL.Path, "/wallet/")) {
+			h.ServeHTTP(w, req)
+			return
+		}
+		if api.namedWallets == nil {
+			WriteError(w, Error{"named wallets are not enabled"}, http.StatusBadRequest)
+			return
+		}
+		a, err := api.namedWalletAPI(name)
+		if err != nil {
+			WriteError(w, Error{"error when selecting wallet " + name + ": " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		a.router.ServeHTTP(w, req)
+	})
+}
+
+// walletsHandler handles API calls to /wallets.
+func (api *API) walletsHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var wg WalletsGET
+	for _, name := range api.namedWallets.NamedWalletNames() {
+		nw, err := api.namedWallets.NamedWallet(name)
+		if err != nil {
+			continue
+		}
+		wg.Wallets = append(wg.Wallets, NamedWalletGET{
+			Name:      name,
+			Encrypted: nw.Encrypted(),
+			Unlocked:  nw.Unlocked(),
+		})
+	}
+	WriteJSON(w, wg)
+}
+
+// walletsCreateHandler handles API calls to /wallets/create.
+func (api *API) walletsCreateHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	if _, err := api.namedWallets.CreateNamedWallet(req.FormValue("name")); err != nil {
+		WriteError(w, Error{"error when calling /wallets/create: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
@@ -0,0 +1,107 @@
+// Package httpstream implements the parts of streaming a download over HTTP
+// that are shared by the API and the S3 gateway: parsing the Range header of a
+// request, and deferring the status and headers of the response until the
+// stream produces its first byte.
+package httpstream
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidRange is returned by ParseRange for a Range header that cannot be
+// satisfied.
+var ErrInvalidRange = errors.New("invalid range")
+
+// A Writer writes a stream to an http response. The status and headers of the
+// response are only sent with the first byte of the stream, so that a
+// download that fails to start can still be reported as an error.
+type Writer struct {
+	w       http.ResponseWriter
+	status  int
+	header  http.Header
+	started bool
+}
+
+// NewWriter returns a Writer that streams to w with the status 200 OK.
+func NewWriter(w http.ResponseWriter) *Writer {
+	return &Writer{
+		w:      w,
+		status: http.StatusOK,
+		header: make(http.Header),
+	}
+}
+
+// Header returns the headers that are sent with the first byte of the stream.
+func (sw *Writer) Header() http.Header {
+	return sw.header
+}
+
+// SetStatus sets the status that is sent with the first byte of the stream.
+func (sw *Writer) SetStatus(status int) {
+	sw.status = status
+}
+
+// Started reports whether the status and headers of the response have been
+// sent.
+func (sw *Writer) Started() bool {
+	return sw.started
+}
+
+// Write implements io.Writer.
+func (sw *Writer) Write(b []byte) (int, error) {
+	if !sw.started {
+		for k, v := range sw.header {
+			sw.w.Header()[k] = v
+		}
+		sw.w.WriteHeader(sw.status)
+		sw.started = true
+	}
+	return sw.w.Write(b)
+}
+
+// ParseRange parses the Range header of a request for a file of the given
+// size, returning the offset and length of the requested section and whether
+// the request is for part of the file. Only a single byte range is supported;
+// requests for multiple ranges are served the whole file.
+func ParseRange(header string, size uint64) (offset, length uint64, partial bool, err error) {
+	if header == "" || strings.Contains(header, ",") {
+		return 0, size, false, nil
+	}
+	if !strings.HasPrefix(header, "bytes=") {
+		return 0, 0, false, ErrInvalidRange
+	}
+	spec := strings.SplitN(strings.TrimPrefix(header, "bytes="), "-", 2)
+	if len(spec) != 2 {
+		return 0, 0, false, ErrInvalidRange
+	}
+	first, last := strings.TrimSpace(spec[0]), strings.TrimSpace(spec[1])
+	if first == "" {
+		// A suffix range requests the last bytes of the file.
+		n, err := strconv.ParseUint(last, 10, 64)
+		if err != nil || n == 0 || size == 0 {
+			return 0, 0, false, ErrInvalidRange
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, n, true, nil
+	}
+	offset, err = strconv.ParseUint(first, 10, 64)
+	if err != nil || offset >= size {
+		return 0, 0, false, ErrInvalidRange
+	}
+	end := size - 1
+	if last != "" {
+		end, err = strconv.ParseUint(last, 10, 64)
+		if err != nil || end < offset {
+			return 0, 0, false, ErrInvalidRange
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	return offset, end - offset + 1, true, nil
+}
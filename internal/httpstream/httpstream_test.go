@@ -0,0 +1,37 @@
+package httpstream
+
+import (
+	"testing"
+)
+
+// TestParseRange probes the parsing of Range headers.
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		header  string
+		offset  uint64
+		length  uint64
+		partial bool
+		valid   bool
+	}{
+		{"", 0, 100, false, true},
+		{"bytes=0-9", 0, 10, true, true},
+		{"bytes=90-", 90, 10, true, true},
+		{"bytes=90-200", 90, 10, true, true},
+		{"bytes=-10", 90, 10, true, true},
+		{"bytes=-200", 0, 100, true, true},
+		{"bytes=0-9,20-29", 0, 100, false, true},
+		{"bytes=100-", 0, 0, false, false},
+		{"bytes=10-5", 0, 0, false, false},
+		{"bytes=-0", 0, 0, false, false},
+		{"bytes=a-b", 0, 0, false, false},
+		{"lines=0-9", 0, 0, false, false},
+	}
+	for _, test := range tests {
+		offset, length, partial, err := ParseRange(test.header, 100)
+		if (err == nil) != test.valid {
+			t.Errorf("%q: unexpected error %v", test.header, err)
+		} else if test.valid && (offset != test.offset || length != test.length || partial != test.partial) {
+			t.Errorf("%q: got %v %v %v, expected %v %v %v", test.header, offset, length, partial, test.offset, test.length, test.partial)
+		}
+	}
+}
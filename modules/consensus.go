@@ -2,6 +2,7 @@ package modules
 
 import (
 	"errors"
+	"time"
 
 	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/types"
@@ -18,6 +19,43 @@ const (
 	// DiffRevert indicates that a diff is being reverted from the consensus
 	// set.
 	DiffRevert DiffDirection = false
+
+	// ContractEventFormed indicates that a file contract was added to the
+	// consensus set.
+	ContractEventFormed ContractEventType = "formed"
+
+	// ContractEventRevised indicates that a file contract was replaced by a
+	// file contract revision.
+	ContractEventRevised ContractEventType = "revised"
+
+	// ContractEventProofSubmitted indicates that a storage proof was
+	// submitted for a file contract, resolving it with the valid proof
+	// outputs.
+	ContractEventProofSubmitted ContractEventType = "proofsubmitted"
+
+	// ContractEventExpired indicates that a file contract reached the end of
+	// its proof window without a storage proof, resolving it with the missed
+	// proof outputs.
+	ContractEventExpired ContractEventType = "expired"
+
+	// DeploymentDefined indicates that blocks have not started signaling for
+	// a deployment.
+	DeploymentDefined DeploymentState = "defined"
+
+	// DeploymentStarted indicates that blocks are signaling for a deployment.
+	DeploymentStarted DeploymentState = "started"
+
+	// DeploymentLockedIn indicates that enough blocks of a window signaled
+	// for a deployment. The deployment becomes active after another window.
+	DeploymentLockedIn DeploymentState = "lockedin"
+
+	// DeploymentActive indicates that the rules of a deployment are in
+	// effect.
+	DeploymentActive DeploymentState = "active"
+
+	// DeploymentFailed indicates that a deployment reached its timeout
+	// without locking in.
+	DeploymentFailed DeploymentState = "failed"
 )
 
 var (
@@ -46,6 +84,12 @@ var (
 	// should be handled by the module, and not reported to the user.
 	ErrInvalidConsensusChangeID = errors.New("consensus subscription has invalid id - files are inconsistent")
 
+	// ErrPrunedConsensusChange indicates that ConsensusSetSubscribe was
+	// called with a consensus change that precedes the blocks that a pruned
+	// consensus set has kept. The changes that the subscriber is missing can
+	// no longer be computed, so the subscriber cannot be caught up.
+	ErrPrunedConsensusChange = errors.New("consensus subscription starts before the pruned blocks of the consensus set")
+
 	// ErrNonExtendingBlock indicates that a block is valid but does not result
 	// in a fork that is the heaviest known fork - the consensus set has not
 	// changed as a result of seeing the block.
@@ -70,6 +114,69 @@ type (
 		ProcessConsensusChange(ConsensusChange)
 	}
 
+	// A ContractEventType identifies a stage in the lifecycle of a file
+	// contract.
+	ContractEventType string
+
+	// A ContractEvent describes a single change to a file contract caused by
+	// a block. FileContract is the contract after the event, except for
+	// ContractEventProofSubmitted and ContractEventExpired, where it is the
+	// contract that was resolved.
+	ContractEvent struct {
+		Type         ContractEventType    `json:"type"`
+		ID           types.FileContractID `json:"id"`
+		FileContract types.FileContract   `json:"filecontract"`
+		BlockID      types.BlockID        `json:"blockid"`
+		Height       types.BlockHeight    `json:"height"`
+	}
+
+	// A ContractEventUpdate contains the contract events that were reverted
+	// and applied by a consensus change. Reverted events are presented in the
+	// order that they were reverted, and always precede the applied events.
+	ContractEventUpdate struct {
+		ID             ConsensusChangeID
+		RevertedEvents []ContractEvent
+		AppliedEvents  []ContractEvent
+	}
+
+	// ConsensusStats contains aggregate statistics about the consensus
+	// state. Dust outputs are siacoin outputs worth less than one siacoin.
+	// LockedCoins is the sum of the payouts of the open file contracts.
+	ConsensusStats struct {
+		SiacoinOutputs uint64         `json:"siacoinoutputs"`
+		DustOutputs    uint64         `json:"dustoutputs"`
+		SiafundOutputs uint64         `json:"siafundoutputs"`
+		FileContracts  uint64         `json:"filecontracts"`
+		LockedCoins    types.Currency `json:"lockedcoins"`
+	}
+
+	// A DeploymentState is the activation state of a deployment.
+	DeploymentState string
+
+	// A DeploymentStatus describes the state of a deployment for the next
+	// block. Signals is the number of blocks in the current, incomplete
+	// window that signal for the deployment.
+	DeploymentStatus struct {
+		Name          string            `json:"name"`
+		Bit           uint8             `json:"bit"`
+		StartHeight   types.BlockHeight `json:"startheight"`
+		TimeoutHeight types.BlockHeight `json:"timeoutheight"`
+		State         DeploymentState   `json:"state"`
+		Signals       uint64            `json:"signals"`
+	}
+
+	// A ContractEventSubscriber is an object that receives the file contract
+	// events of every consensus change. Because the events of reverted blocks
+	// are sent as reverted events, a subscriber that tracks the resolution of
+	// its contracts stays correct across reorgs.
+	ContractEventSubscriber interface {
+		// ProcessContractEvents sends the contract events of a consensus
+		// change to a module. Updates will always be sent in the correct
+		// order, and are sent even when they contain no events, so that the
+		// subscriber can track the most recent consensus change id.
+		ProcessContractEvents(ContractEventUpdate)
+	}
+
 	// A ConsensusChange enumerates a set of changes that occurred to the consensus set.
 	ConsensusChange struct {
 		// ID is a unique id for the consensus change derived from the reverted
@@ -131,6 +238,17 @@ type (
 		TryTransactionSet func([]types.Transaction) (ConsensusChange, error)
 	}
 
+	// A FilteredBlock contains the header of a block and proofs for the subset
+	// of the block's transactions that are relevant to a set of addresses. A
+	// FilteredBlock allows lightweight clients to learn about transactions
+	// affecting their addresses without downloading full blocks. The proofs
+	// are only meaningful against a header that the client has validated
+	// itself, and do not show that no relevant transaction was left out.
+	FilteredBlock struct {
+		Header types.BlockHeader
+		Proofs []types.TransactionProof
+	}
+
 	// A SiacoinOutputDiff indicates the addition or removal of a SiacoinOutput in
 	// the consensus set.
 	SiacoinOutputDiff struct {
@@ -165,6 +283,23 @@ type (
 		MaturityHeight types.BlockHeight
 	}
 
+	// A DelayedSiacoinOutput is a siacoin output, such as a miner payout or a
+	// missed storage proof payout, that cannot be spent until it reaches its
+	// maturity height.
+	DelayedSiacoinOutput struct {
+		ID             types.SiacoinOutputID `json:"id"`
+		SiacoinOutput  types.SiacoinOutput   `json:"siacoinoutput"`
+		MaturityHeight types.BlockHeight     `json:"maturityheight"`
+	}
+
+	// An IndexedBlock is a block of the current path along with its id and
+	// height.
+	IndexedBlock struct {
+		ID     types.BlockID     `json:"id"`
+		Height types.BlockHeight `json:"height"`
+		Block  types.Block       `json:"block"`
+	}
+
 	// A SiafundPoolDiff contains the value of the siafundPool before the block
 	// was applied, and after the block was applied. When applying the diff, set
 	// siafundPool to 'Adjusted'. When reverting the diff, set siafundPool to
@@ -186,15 +321,41 @@ type (
 		// still be returned.
 		AcceptBlock(types.Block) error
 
+		// AcceptBlocks adds a contiguous range of blocks to consensus in a
+		// single batch. The blocks must be ordered such that each block is the
+		// parent of the next. Valid blocks preceding an invalid block are
+		// still added, but an error is returned.
+		AcceptBlocks([]types.Block) error
+
+		// BadBlocks returns the ids of the blocks that failed expensive
+		// validation. These blocks are rejected without being validated
+		// again.
+		BadBlocks() []types.BlockID
+
 		// BlockAtHeight returns the block found at the input height, with a
 		// bool to indicate whether that block exists.
 		BlockAtHeight(types.BlockHeight) (types.Block, bool)
 
+		// BlocksInRange returns the blocks of the current path between the
+		// start and end heights, inclusive, along with their ids. An error
+		// is returned if the range is not part of the current path.
+		BlocksInRange(start, end types.BlockHeight) ([]IndexedBlock, error)
+
 		// ChildTarget returns the target required to extend the current heaviest
 		// fork. This function is typically used by miners looking to extend the
 		// heaviest fork.
 		ChildTarget(types.BlockID) (types.Target, bool)
 
+		// ClearBadBlocks forgets every block that failed expensive
+		// validation, so that the blocks are fully validated if they are
+		// seen again.
+		ClearBadBlocks() error
+
+		// ClockSkew returns the local time minus the median timestamp of
+		// recent blocks. A negative skew beyond FutureThreshold means that
+		// the local clock is behind the network.
+		ClockSkew() time.Duration
+
 		// Close will shut down the consensus set, giving the module enough time to
 		// run any required closing routines.
 		Close() error
@@ -205,17 +366,59 @@ type (
 		// described by the ConsensusChangeX variables in this package.
 		ConsensusSetSubscribe(ConsensusSetSubscriber, ConsensusChangeID) error
 
+		// ContractEventSubscribe adds a subscriber to the list of contract
+		// event subscribers and gives them the contract events of every
+		// consensus change that has occurred since the change with the
+		// provided id.
+		ContractEventSubscribe(ContractEventSubscriber, ConsensusChangeID) error
+
+		// ContractEventUnsubscribe removes a contract event subscriber. If
+		// the subscriber is not found, no action is taken.
+		ContractEventUnsubscribe(ContractEventSubscriber)
+
 		// CurrentBlock returns the latest block in the heaviest known
 		// blockchain.
 		CurrentBlock() types.Block
 
+		// DelayedOutputsAtHeight returns the delayed siacoin outputs that
+		// will mature at the provided height.
+		DelayedOutputsAtHeight(types.BlockHeight) []DelayedSiacoinOutput
+
+		// Deployments returns the state of every known deployment for the
+		// next block.
+		Deployments() ([]DeploymentStatus, error)
+
+		// EstimateNextTarget returns the projected target after the next
+		// difficulty adjustment, along with the height of the first block
+		// that must meet it.
+		EstimateNextTarget() (types.Target, types.BlockHeight)
+
+		// FilteredBlock returns the header of the block with the given id
+		// along with proofs for every transaction in the block that spends
+		// from or sends to one of the provided addresses. A bool indicates
+		// whether the block exists.
+		FilteredBlock(types.BlockID, []types.UnlockHash) (FilteredBlock, bool)
+
 		// Flush will cause the consensus set to finish all in-progress
 		// routines.
 		Flush() error
 
+		// HeaderAtHeight returns the header at the given height of the
+		// header chain. Unless the consensus set only syncs headers, the
+		// header chain is the current path.
+		HeaderAtHeight(types.BlockHeight) (types.BlockHeader, bool)
+
+		// HeaderHeight returns the height of the header chain.
+		HeaderHeight() types.BlockHeight
+
 		// Height returns the current height of consensus.
 		Height() types.BlockHeight
 
+		// RequestFilteredBlock requests the filtered block for the header at
+		// the given height of the header chain from a peer. The filtered
+		// block is checked against the header chain.
+		RequestFilteredBlock(NetAddress, types.BlockHeight, []types.UnlockHash) (FilteredBlock, error)
+
 		// Synced returns true if the consensus set is synced with the network.
 		Synced() bool
 
@@ -223,16 +426,52 @@ type (
 		// current path, false otherwise.
 		InCurrentPath(types.BlockID) bool
 
+		// MedianTimestamp returns the median timestamp of the most recent
+		// MedianTimestampWindow blocks of the current path.
+		MedianTimestamp() types.Timestamp
+
 		// MinimumValidChildTimestamp returns the earliest timestamp that is
 		// valid on the current longest fork according to the consensus set. This is
 		// a required piece of information for the miner, who could otherwise be at
 		// risk of mining invalid blocks.
 		MinimumValidChildTimestamp(types.BlockID) (types.Timestamp, bool)
 
+		// SiafundClaim returns the siacoins that the siafund output with the
+		// given id would claim if it were spent in the next block.
+		SiafundClaim(types.SiafundOutputID) (types.Currency, error)
+
+		// SiafundPool returns the current value of the siafund pool.
+		SiafundPool() types.Currency
+
+		// SiafundPoolAtHeight returns the value of the siafund pool after
+		// the block at the given height of the current path was applied.
+		SiafundPoolAtHeight(types.BlockHeight) (types.Currency, error)
+
+		// StateHash returns a deterministic hash of the current consensus
+		// state, covering every siacoin output, file contract, siafund
+		// output and delayed siacoin output. Nodes that agree on consensus
+		// have identical state hashes.
+		StateHash() crypto.Hash
+
+		// Stats returns aggregate statistics about the current consensus
+		// state. The statistics are maintained as blocks are applied and
+		// reverted, so they are cheap to query.
+		Stats() ConsensusStats
+
+		// StateHashAtHeight returns the id of the block at the given height
+		// of the current path and the state hash of the consensus set at
+		// that block.
+		StateHashAtHeight(types.BlockHeight) (types.BlockID, crypto.Hash, error)
+
 		// StorageProofSegment returns the segment to be used in the storage proof for
 		// a given file contract.
 		StorageProofSegment(types.FileContractID) (uint64, error)
 
+		// TargetAtHeight returns the child target of the block at the given
+		// height of the current path, with a bool to indicate whether that
+		// block exists.
+		TargetAtHeight(types.BlockHeight) (types.Target, bool)
+
 		// TryTransactionSet checks whether the transaction set would be valid if
 		// it were added in the next block. A consensus change is returned
 		// detailing the diffs that would result from the application of the
@@ -243,20 +482,36 @@ type (
 		// allowing for garbage collection and rescanning. If the subscriber is
 		// not found in the subscriber database, no action is taken.
 		Unsubscribe(ConsensusSetSubscriber)
+
+		// VerifyChain replays every block in the current path from the
+		// genesis block and compares the result to the stored consensus
+		// state. An error describing the first divergence is returned if the
+		// stored state does not match. On success, the verified height is
+		// returned.
+		VerifyChain() (types.BlockHeight, error)
 	}
 )
 
 // Append takes to ConsensusChange objects and adds all of their diffs together.
+// The ID, child target, timestamp, and sync status of the combined change are
+// taken from cc2, as cc2 describes the state of the consensus set after both
+// changes have been applied.
 //
 // NOTE: It is possible for diffs to overlap or be inconsistent. This function
 // should only be used with consecutive or disjoint consensus change objects.
 func (cc ConsensusChange) Append(cc2 ConsensusChange) ConsensusChange {
 	return ConsensusChange{
-		RevertedBlocks:            append(cc.RevertedBlocks, cc2.RevertedBlocks...),
-		AppliedBlocks:             append(cc.AppliedBlocks, cc2.AppliedBlocks...),
-		SiacoinOutputDiffs:        append(cc.SiacoinOutputDiffs, cc2.SiacoinOutputDiffs...),
-		FileContractDiffs:         append(cc.FileContractDiffs, cc2.FileContractDiffs...),
-		SiafundOutputDiffs:        append(cc.SiafundOutputDiffs, cc2.SiafundOutputDiffs...),
-		DelayedSiacoinOutputDiffs: append(cc.DelayedSiacoinOutputDiffs, cc2.DelayedSiacoinOutputDiffs...),
+		ID:                         cc2.ID,
+		RevertedBlocks:             append(cc.RevertedBlocks, cc2.RevertedBlocks...),
+		AppliedBlocks:              append(cc.AppliedBlocks, cc2.AppliedBlocks...),
+		SiacoinOutputDiffs:         append(cc.SiacoinOutputDiffs, cc2.SiacoinOutputDiffs...),
+		FileContractDiffs:          append(cc.FileContractDiffs, cc2.FileContractDiffs...),
+		SiafundOutputDiffs:         append(cc.SiafundOutputDiffs, cc2.SiafundOutputDiffs...),
+		DelayedSiacoinOutputDiffs:  append(cc.DelayedSiacoinOutputDiffs, cc2.DelayedSiacoinOutputDiffs...),
+		SiafundPoolDiffs:           append(cc.SiafundPoolDiffs, cc2.SiafundPoolDiffs...),
+		ChildTarget:                cc2.ChildTarget,
+		MinimumValidChildTimestamp: cc2.MinimumValidChildTimestamp,
+		Synced:                     cc2.Synced,
+		TryTransactionSet:          cc2.TryTransactionSet,
 	}
 }
@@ -3,7 +3,6 @@ package consensus
 import (
 	"bytes"
 	"errors"
-	"time"
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/modules"
@@ -20,10 +19,30 @@ var (
 	errNonLinearChain  = errors.New("block set is not a contiguous chain")
 )
 
+// A RelayFilter decides which peers an accepted block is relayed to. It is
+// called with the block and the currently connected peers, and returns the
+// peers that should receive the block first, followed by the peers that
+// should receive it once the first relay has completed. Peers that are not
+// returned do not receive the block.
+type RelayFilter func(b types.Block, peers []modules.Peer) (priority, rest []modules.Peer)
+
 // managedBroadcastBlock will broadcast a block to the consensus set's peers.
 func (cs *ConsensusSet) managedBroadcastBlock(b types.Block) {
-	// broadcast the block header to all peers
-	go cs.gateway.Broadcast("RelayHeader", b.Header(), cs.gateway.Peers())
+	// broadcast the block header to all peers, unless a relay filter has
+	// been set.
+	if cs.relayFilter == nil {
+		go cs.gateway.Broadcast("RelayHeader", b.Header(), cs.gateway.Peers())
+		return
+	}
+	go func() {
+		priority, rest := cs.relayFilter(b, cs.gateway.Peers())
+		if len(priority) != 0 {
+			cs.gateway.Broadcast("RelayHeader", b.Header(), priority)
+		}
+		if len(rest) != 0 {
+			cs.gateway.Broadcast("RelayHeader", b.Header(), rest)
+		}
+	}()
 }
 
 // validateHeaderAndBlock does some early, low computation verification on the
@@ -57,6 +76,12 @@ func (cs *ConsensusSet) validateHeaderAndBlock(tx dbTx, b types.Block, id types.
 	if err != nil {
 		return nil, err
 	}
+	// Check that the ancestors needed to validate the block have not been
+	// pruned.
+	err = cs.checkPrunedParent(tx, parent)
+	if err != nil {
+		return nil, err
+	}
 	// Check that the timestamp is not too far in the past to be acceptable.
 	minTimestamp := cs.blockRuleHelper.minimumValidChildTimestamp(blockMap, parent)
 
@@ -106,6 +131,13 @@ func (cs *ConsensusSet) validateHeader(tx dbTx, h types.BlockHeader) error {
 		return err
 	}
 
+	// Check that the ancestors needed to validate the header have not been
+	// pruned.
+	err = cs.checkPrunedParent(tx, &parent)
+	if err != nil {
+		return err
+	}
+
 	// Check that the target of the new block is sufficient.
 	if !checkHeaderTarget(h, parent.ChildTarget) {
 		return modules.ErrBlockUnsolved
@@ -146,6 +178,12 @@ func (cs *ConsensusSet) validateHeader(tx dbTx, h types.BlockHeader) error {
 // caller. Switching to a managed tx through bolt will make this complexity
 // unneeded.
 func (cs *ConsensusSet) addBlockToTree(tx *bolt.Tx, b types.Block, parent *processedBlock) (ce changeEntry, err error) {
+	// A pruned consensus set can only fork from recent blocks.
+	err = cs.checkPrunedFork(tx, parent)
+	if err != nil {
+		return changeEntry{}, err
+	}
+
 	// Prepare the child processed block associated with the parent block.
 	newNode := cs.newChild(tx, parent, b)
 
@@ -177,35 +215,6 @@ func (cs *ConsensusSet) addBlockToTree(tx *bolt.Tx, b types.Block, parent *proce
 	return ce, nil
 }
 
-// threadedSleepOnFutureBlock will sleep until the timestamp of a future block
-// has arrived.
-//
-// TODO: An attacker can broadcast a future block multiple times, resulting in a
-// goroutine spinup for each future block.  Need to prevent that.
-//
-// TODO: An attacker could produce a very large number of future blocks,
-// consuming memory. Need to prevent that.
-func (cs *ConsensusSet) threadedSleepOnFutureBlock(b types.Block) {
-	// Add this thread to the threadgroup.
-	err := cs.tg.Add()
-	if err != nil {
-		return
-	}
-	defer cs.tg.Done()
-
-	// Perform a soft-sleep while we wait for the block to become valid.
-	select {
-	case <-cs.tg.StopChan():
-		return
-	case <-time.After(time.Duration(b.Timestamp-(types.CurrentTimestamp()+types.FutureThreshold)) * time.Second):
-		_, err := cs.managedAcceptBlocks([]types.Block{b})
-		if err != nil {
-			cs.log.Debugln("WARN: failed to accept a future block:", err)
-		}
-		cs.managedBroadcastBlock(b)
-	}
-}
-
 // managedAcceptBlocks will try to add blocks to the consensus set. If the
 // blocks do not extend the longest currently known chain, an error is
 // returned but the blocks are still kept in memory. If the blocks extend a fork
@@ -218,9 +227,21 @@ func (cs *ConsensusSet) threadedSleepOnFutureBlock(b types.Block) {
 // consecutive calls to AcceptBlock with each successive call accepting the
 // child block of the previous call.
 func (cs *ConsensusSet) managedAcceptBlocks(blocks []types.Block) (blockchainExtended bool, err error) {
-	// Grab a lock on the consensus set.
+	// Grab a lock on the consensus set. The lock is demoted to a read lock
+	// while the subscribers are updated, so that queries can proceed while
+	// the modules process the change.
 	cs.mu.Lock()
-	defer cs.mu.Unlock()
+	demoted := false
+	defer func() {
+		if demoted {
+			cs.mu.DemotedUnlock()
+		} else {
+			cs.mu.Unlock()
+		}
+	}()
+	if cs.headersOnly {
+		return false, errHeadersOnly
+	}
 
 	// Make sure that blocks are consecutive. Though this isn't a strict
 	// requirement, if blocks are not consecutive then it becomes a lot harder
@@ -241,6 +262,9 @@ func (cs *ConsensusSet) managedAcceptBlocks(blocks []types.Block) (blockchainExt
 	changes := make([]changeEntry, 0, len(blocks))
 	validBlocks := make([]types.Block, 0, len(blocks))
 	parents := make([]*processedBlock, 0, len(blocks))
+	// The metrics are updated while the blocks are processed, and are
+	// restored whenever the transaction is rolled back.
+	metrics := cs.metrics
 	setErr := cs.db.Update(func(tx *bolt.Tx) error {
 		for i := 0; i < len(blocks); i++ {
 			// Start by checking the header of the block.
@@ -251,7 +275,15 @@ func (cs *ConsensusSet) managedAcceptBlocks(blocks []types.Block) (blockchainExt
 			}
 			if err == errFutureTimestamp {
 				// Queue the block to be tried again if it is a future block.
-				go cs.threadedSleepOnFutureBlock(blocks[i])
+				cs.queueFutureBlock(blocks[i], blockIDs[i])
+			}
+			if err == errOrphan {
+				// Hold on to the block until its parents arrive, unless it
+				// does not carry enough work to be worth holding.
+				if !checkOrphanTarget(tx, blocks[i], blockIDs[i]) {
+					return modules.ErrBlockUnsolved
+				}
+				cs.addOrphan(blocks[i], blockIDs[i])
 			}
 			if err != nil {
 				return err
@@ -280,7 +312,11 @@ func (cs *ConsensusSet) managedAcceptBlocks(blocks []types.Block) (blockchainExt
 		}
 		return nil
 	})
+	// Blocks that failed expensive validation were marked inside of the
+	// transaction, which may have been rolled back.
+	cs.persistDoSBlocks()
 	if setErr != nil {
+		cs.metrics = metrics
 		// Check if any blocks were valid.
 		if len(validBlocks) < 1 {
 			// Nothing more to do, the first block was invalid.
@@ -312,10 +348,16 @@ func (cs *ConsensusSet) managedAcceptBlocks(blocks []types.Block) (blockchainExt
 		// reached. If it is, return early because both attempts to add blocks
 		// have failed.
 		if err != nil {
+			cs.metrics = metrics
 			return false, err
 		}
 	}
 
+	// Any orphans that are children of the new blocks can now be accepted.
+	if children := cs.popOrphanChildren(validBlocks); len(children) > 0 {
+		go cs.threadedAcceptOrphans(children)
+	}
+
 	// Stop here if the blocks did not extend the longest blockchain.
 	if !chainExtended {
 		return false, modules.ErrNonExtendingBlock
@@ -355,7 +397,36 @@ func (cs *ConsensusSet) managedAcceptBlocks(blocks []types.Block) (blockchainExt
 	if build.DEBUG && len(fullChange.AppliedBlocks) == 0 {
 		panic("should not be updating subscribers witha blank change")
 	}
+	cs.checkClockSkew()
+	cs.mu.Demote()
+	demoted = true
 	cs.updateSubscribers(fullChange)
+	_ = cs.db.View(func(tx *bolt.Tx) error {
+		cs.publish(tx)
+		return nil
+	})
+	cs.alertDeepReorg(fullChange)
+
+	// Prune the blocks that have fallen out of the prune window. Pruning
+	// happens after the subscribers have been updated, because the change
+	// sent to the subscribers can refer to blocks that are being pruned.
+	// Pruning modifies the block map and the pruned height, so the write
+	// lock is reacquired for it.
+	if cs.pruneDepth != 0 {
+		cs.mu.DemotedUnlock()
+		cs.mu.Lock()
+		demoted = false
+		var prunedHeight types.BlockHeight
+		err := cs.db.Update(func(tx *bolt.Tx) (err error) {
+			prunedHeight, err = cs.pruneBlocks(tx)
+			return err
+		})
+		if err != nil {
+			cs.log.Println("WARN: unable to prune blocks:", err)
+		} else {
+			cs.prunedHeight = prunedHeight
+		}
+	}
 
 	// If there were valid blocks and invalid blocks in the set that was
 	// provided, then the setErr is not going to be nil. Return the set error to
@@ -381,6 +452,10 @@ func (cs *ConsensusSet) AcceptBlock(b types.Block) error {
 	defer cs.tg.Done()
 
 	chainExtended, err := cs.managedAcceptBlocks([]types.Block{b})
+	if err == errOrphan {
+		// The block has been added to the orphan pool, fetch its parents.
+		go cs.threadedFetchOrphanParents("")
+	}
 	if err != nil {
 		return err
 	}
@@ -389,3 +464,31 @@ func (cs *ConsensusSet) AcceptBlock(b types.Block) error {
 	}
 	return nil
 }
+
+// AcceptBlocks will try to add a contiguous range of blocks to the consensus
+// set, where each block is the parent of the block that follows it. All of the
+// blocks are validated and committed under a single lock acquisition and a
+// single database transaction, with diffs generated for each block. This is
+// much cheaper than calling AcceptBlock once per block when many blocks are
+// available at once, for example during initial blockchain download.
+//
+// If some of the blocks are invalid, the valid blocks preceding the first
+// invalid block are still added and the error is returned. If the blocks
+// extend the longest known chain, only the new current block is relayed to
+// peers.
+func (cs *ConsensusSet) AcceptBlocks(blocks []types.Block) error {
+	err := cs.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	chainExtended, err := cs.managedAcceptBlocks(blocks)
+	if chainExtended {
+		cs.managedBroadcastBlock(cs.managedCurrentBlock())
+	}
+	return err
+}
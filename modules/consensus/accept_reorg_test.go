@@ -328,3 +328,81 @@ func TestBuriedBadFork(t *testing.T) {
 		t.Fatal("a bad block failed to cause an error")
 	}
 }
+
+// TestIntegrationFailedReorgIsAtomic checks that a reorg that fails partway
+// through, after reverting the current blocks and applying some of the new
+// blocks, leaves the consensus set unchanged.
+func TestIntegrationFailedReorgIsAtomic(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+	cstAlt, err := blankConsensusSetTester(t.Name() + " - alt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cstAlt.Close()
+
+	// Give the alternate consensus set the same chain.
+	for h := types.BlockHeight(1); h <= cst.cs.Height(); h++ {
+		b, _ := cst.cs.BlockAtHeight(h)
+		err = cstAlt.cs.AcceptBlock(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Extend each chain by one block. Both blocks have the same parent and
+	// the same weight, so the alternate block does not cause a reorg.
+	_, err = cst.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	altBlock, err := cstAlt.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cst.cs.AcceptBlock(altBlock)
+	if err != modules.ErrNonExtendingBlock {
+		t.Fatal("expected ErrNonExtendingBlock, got", err)
+	}
+	currentID := cst.cs.CurrentBlock().ID()
+	checksum := cst.cs.dbConsensusChecksum()
+	metrics := cst.cs.Metrics()
+
+	// Mine a block on the alternate chain that spends a nonexistent output.
+	// The block is only found to be invalid after the reorg has reverted the
+	// current block and applied the alternate block.
+	block, target, err := cstAlt.miner.BlockForWork()
+	if err != nil {
+		t.Fatal(err)
+	}
+	block.Transactions = append(block.Transactions, types.Transaction{
+		SiacoinInputs: []types.SiacoinInput{{}},
+	})
+	badBlock, _ := cstAlt.miner.SolveBlock(block, target)
+	err = cst.cs.AcceptBlock(badBlock)
+	if err != errMissingSiacoinOutput {
+		t.Fatalf("expected %v, got %v", errMissingSiacoinOutput, err)
+	}
+	if cst.cs.CurrentBlock().ID() != currentID {
+		t.Fatal("failed reorg changed the current block")
+	}
+	if cst.cs.dbConsensusChecksum() != checksum {
+		t.Fatal("failed reorg changed the consensus state")
+	}
+	if cst.cs.Metrics() != metrics {
+		t.Fatal("failed reorg changed the metrics")
+	}
+
+	// The consensus set should continue to work normally.
+	_, err = cst.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
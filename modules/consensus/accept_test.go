@@ -1031,6 +1031,89 @@ func TestAcceptBlockBroadcasts(t *testing.T) {
 	}
 }
 
+// mockGatewayRecordBroadcasts implements modules.Gateway to mock the Peers and
+// Broadcast methods.
+type mockGatewayRecordBroadcasts struct {
+	modules.Gateway
+	peers      []modules.Peer
+	broadcasts chan []modules.Peer
+}
+
+// Peers is a mock implementation of modules.Gateway.Peers that returns a fixed
+// set of peers.
+func (g *mockGatewayRecordBroadcasts) Peers() []modules.Peer {
+	return g.peers
+}
+
+// Broadcast is a mock implementation of modules.Gateway.Broadcast that sends
+// the peers of each broadcast down a channel instead of contacting them.
+func (g *mockGatewayRecordBroadcasts) Broadcast(name string, obj interface{}, peers []modules.Peer) {
+	g.broadcasts <- peers
+}
+
+// TestRelayFilter checks that the relay filter controls which peers accepted
+// blocks are relayed to, and in which order.
+func TestRelayFilter(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := blankConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+	mg := &mockGatewayRecordBroadcasts{
+		Gateway:    cst.cs.gateway,
+		peers:      []modules.Peer{{NetAddress: "foo.com:9981"}, {NetAddress: "bar.com:9981"}, {NetAddress: "baz.com:9981"}},
+		broadcasts: make(chan []modules.Peer, 2),
+	}
+	cst.cs.gateway = mg
+
+	// Relay to the last peer first, then to the first peer, and never to the
+	// second peer. Blocks with an odd nonce are not relayed at all.
+	cst.cs.relayFilter = func(b types.Block, peers []modules.Peer) ([]modules.Peer, []modules.Peer) {
+		if b.Nonce[0]%2 == 1 {
+			return nil, nil
+		}
+		return peers[2:], peers[:1]
+	}
+
+	b, _ := cst.miner.FindBlock()
+	for b.Nonce[0]%2 == 1 {
+		b, _ = cst.miner.FindBlock()
+	}
+	err = cst.cs.AcceptBlock(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []modules.NetAddress{"baz.com:9981", "foo.com:9981"} {
+		select {
+		case peers := <-mg.broadcasts:
+			if len(peers) != 1 || peers[0].NetAddress != expected {
+				t.Fatal("block was relayed to the wrong peers:", peers)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("block was not relayed")
+		}
+	}
+
+	// A block that the filter rejects should not be relayed.
+	b, _ = cst.miner.FindBlock()
+	for b.Nonce[0]%2 == 0 {
+		b, _ = cst.miner.FindBlock()
+	}
+	err = cst.cs.AcceptBlock(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case peers := <-mg.broadcasts:
+		t.Fatal("filtered block was relayed:", peers)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
 // blockCountingSubscriber counts the number of blocks that get submitted to the
 // subscriber, as well as the number of times that the subscriber has been given
 // changes at all.
@@ -1149,3 +1232,57 @@ func TestChainedAcceptBlock(t *testing.T) {
 		t.Error("consensus changes do not seem to be getting passed to subscribers correctly")
 	}
 }
+
+// TestAcceptBlocks checks that a contiguous range of blocks can be added to
+// the consensus set in a single batch, and that subscribers receive the whole
+// batch as a single change.
+func TestAcceptBlocks(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+	cst2, err := blankConsensusSetTester(t.Name() + "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst2.Close()
+	var bcs blockCountingSubscriber
+	cst2.cs.ConsensusSetSubscribe(&bcs, modules.ConsensusChangeBeginning)
+
+	// Grab all of the blocks after the genesis block in cst.
+	var blocks []types.Block
+	for i := types.BlockHeight(1); i <= cst.cs.Height(); i++ {
+		b, exists := cst.cs.BlockAtHeight(i)
+		if !exists {
+			t.Fatal("block at height", i, "does not exist")
+		}
+		blocks = append(blocks, b)
+	}
+
+	// An empty batch should be a no-op.
+	if err := cst2.cs.AcceptBlocks(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Submit the blocks in one batch.
+	if err := cst2.cs.AcceptBlocks(blocks); err != nil {
+		t.Fatal(err)
+	}
+	if cst2.cs.CurrentBlock().ID() != cst.cs.CurrentBlock().ID() {
+		t.Fatal("batch of blocks was not accepted")
+	}
+	if bcs.changes != 2 || bcs.appliedBlocks != int(cst2.cs.Height()+1) || bcs.revertedBlocks != 0 {
+		t.Error("batch was not delivered to subscribers as a single change")
+	}
+
+	// Submitting the same batch again should report that the blocks are
+	// known.
+	if err := cst2.cs.AcceptBlocks(blocks); err != modules.ErrNonExtendingBlock {
+		t.Fatal("expected ErrNonExtendingBlock, got", err)
+	}
+}
@@ -0,0 +1,127 @@
+package consensus
+
+import (
+	"bytes"
+	"container/list"
+	"sync"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/NebulousLabs/bolt"
+)
+
+// blockcache.go implements a memory-bounded cache of block bodies. Processed
+// blocks live in the block map on disk, and every lookup decodes the full
+// processed block, including its diffs. Block lookups that only need the body,
+// such as serving blocks to peers and answering block queries, go through the
+// cache instead. The body of a block is fixed by its id, so cached blocks
+// never go stale, and the cache only needs to drop blocks that are pruned.
+
+const (
+	// defaultBlockCacheSize is the memory cap of the block cache when no
+	// size is configured.
+	defaultBlockCacheSize = 64 << 20 // 64 MiB
+)
+
+type (
+	// blockCache is a least-recently-used cache of block bodies, bounded by
+	// the encoded size of the cached blocks. It has its own lock.
+	blockCache struct {
+		maxSize uint64
+		size    uint64
+		entries map[types.BlockID]*list.Element
+		lru     *list.List
+		mu      sync.Mutex
+	}
+
+	// blockCacheEntry is an element of the lru list of a blockCache.
+	blockCacheEntry struct {
+		id    types.BlockID
+		block types.Block
+		size  uint64
+	}
+)
+
+// newBlockCache returns a block cache that holds at most maxSize bytes of
+// blocks.
+func newBlockCache(maxSize uint64) *blockCache {
+	return &blockCache{
+		maxSize: maxSize,
+		entries: make(map[types.BlockID]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// get returns the cached block with the given id, marking it as recently used.
+func (bc *blockCache) get(id types.BlockID) (types.Block, bool) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	elem, exists := bc.entries[id]
+	if !exists {
+		return types.Block{}, false
+	}
+	bc.lru.MoveToFront(elem)
+	return elem.Value.(*blockCacheEntry).block, true
+}
+
+// add adds a block of the given encoded size to the cache, evicting the least
+// recently used blocks until the cache fits in its memory cap. Blocks that are
+// larger than the cap are not cached.
+func (bc *blockCache) add(id types.BlockID, b types.Block, size uint64) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	if _, exists := bc.entries[id]; exists || size > bc.maxSize {
+		return
+	}
+	bc.entries[id] = bc.lru.PushFront(&blockCacheEntry{
+		id:    id,
+		block: b,
+		size:  size,
+	})
+	bc.size += size
+	for bc.size > bc.maxSize {
+		bc.removeElement(bc.lru.Back())
+	}
+}
+
+// remove drops the block with the given id from the cache.
+func (bc *blockCache) remove(id types.BlockID) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	if elem, exists := bc.entries[id]; exists {
+		bc.removeElement(elem)
+	}
+}
+
+// removeElement drops an element of the lru list from the cache. The caller
+// must hold the lock.
+func (bc *blockCache) removeElement(elem *list.Element) {
+	entry := bc.lru.Remove(elem).(*blockCacheEntry)
+	delete(bc.entries, entry.id)
+	bc.size -= entry.size
+}
+
+// getBlock returns the body of the block with the given id, using the block
+// cache. On a cache miss, only the block is decoded from the processed block,
+// and the block is added to the cache.
+func (cs *ConsensusSet) getBlock(tx *bolt.Tx, id types.BlockID) (types.Block, error) {
+	if b, exists := cs.blockCache.get(id); exists {
+		return b, nil
+	}
+	pbBytes := tx.Bucket(BlockMap).Get(id[:])
+	if pbBytes == nil {
+		return types.Block{}, errNilItem
+	}
+
+	// The block is the first field of the processed block, so the rest of the
+	// processed block does not need to be decoded.
+	var b types.Block
+	err := encoding.NewDecoder(bytes.NewReader(pbBytes)).Decode(&b)
+	if build.DEBUG && err != nil {
+		panic(err)
+	}
+	cs.blockCache.add(id, b, uint64(len(pbBytes)))
+	return b, nil
+}
@@ -0,0 +1,85 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestBlockCacheEviction checks that the block cache stays within its memory
+// cap by evicting the least recently used blocks.
+func TestBlockCacheEviction(t *testing.T) {
+	bc := newBlockCache(100)
+	ids := make([]types.BlockID, 4)
+	for i := range ids {
+		ids[i][0] = byte(i)
+		bc.add(ids[i], types.Block{Timestamp: types.Timestamp(i)}, 30)
+		if i == 1 {
+			// Use the first block so that the second block is the least
+			// recently used.
+			bc.get(ids[0])
+		}
+	}
+	if bc.size != 90 {
+		t.Fatal("wrong cache size:", bc.size)
+	}
+	if _, exists := bc.get(ids[1]); exists {
+		t.Fatal("least recently used block was not evicted")
+	}
+	for _, i := range []int{0, 2, 3} {
+		b, exists := bc.get(ids[i])
+		if !exists || b.Timestamp != types.Timestamp(i) {
+			t.Fatal("recently used block was evicted or corrupted:", i)
+		}
+	}
+
+	// Blocks larger than the cap are not cached.
+	bc.add(types.BlockID{9}, types.Block{}, 101)
+	if _, exists := bc.get(types.BlockID{9}); exists {
+		t.Fatal("oversized block was cached")
+	}
+
+	bc.remove(ids[0])
+	if _, exists := bc.get(ids[0]); exists || bc.size != 60 {
+		t.Fatal("block was not removed")
+	}
+}
+
+// TestBlockCacheLookups checks that blocks served through the block cache
+// match the blocks in the block map.
+func TestBlockCacheLookups(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// Look up every block twice, so that the second lookup is served from
+	// the cache.
+	for i := 0; i < 2; i++ {
+		for h := types.BlockHeight(0); h <= cst.cs.Height(); h++ {
+			b, exists := cst.cs.BlockAtHeight(h)
+			if !exists {
+				t.Fatal("block does not exist at height", h)
+			}
+			id, err := cst.cs.dbGetPath(h)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pb, err := cst.cs.dbGetBlockMap(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b.ID() != pb.Block.ID() || len(b.Transactions) != len(pb.Block.Transactions) {
+				t.Fatal("cached block does not match the block map at height", h)
+			}
+		}
+	}
+	if len(cst.cs.blockCache.entries) == 0 {
+		t.Fatal("blocks were not cached")
+	}
+}
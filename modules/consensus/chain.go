@@ -0,0 +1,129 @@
+package consensus
+
+import (
+	"errors"
+	"io"
+
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/sync"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/NebulousLabs/bolt"
+)
+
+// chain.go implements exporting and importing raw blocks of the current path.
+// Unlike a snapshot, a chain file contains no consensus state; every imported
+// block is fully validated, exactly as though it had been received from a
+// peer. This allows new nodes to be seeded from a local file or a mirror
+// without trusting the source of the file.
+//
+// A chain file is a header followed by the encoded blocks in order of
+// increasing height.
+
+var (
+	errChainBadHeader = errors.New("chain file header is malformed")
+	errChainBadRange  = errors.New("requested range of blocks is not in the current path")
+)
+
+var (
+	// chainFileVersion is the version of the chain file format, written at
+	// the beginning of every chain file.
+	chainFileVersion = types.Specifier{'c', 'h', 'a', 'i', 'n', ' ', 'v', '1'}
+)
+
+// chainFileHeader is the first object in a chain file.
+type chainFileHeader struct {
+	Version    types.Specifier
+	FromHeight types.BlockHeight
+	NumBlocks  uint64
+}
+
+// ExportChain writes the blocks of the current path between fromHeight and
+// toHeight, inclusive, to w.
+func (cs *ConsensusSet) ExportChain(w io.Writer, fromHeight, toHeight types.BlockHeight) error {
+	err := cs.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+
+	enc := encoding.NewEncoder(w)
+	return cs.db.View(func(tx *bolt.Tx) error {
+		if fromHeight > toHeight || toHeight > blockHeight(tx) {
+			return errChainBadRange
+		}
+		err := enc.Encode(chainFileHeader{
+			Version:    chainFileVersion,
+			FromHeight: fromHeight,
+			NumBlocks:  uint64(toHeight-fromHeight) + 1,
+		})
+		if err != nil {
+			return err
+		}
+		for height := fromHeight; height <= toHeight; height++ {
+			id, err := getPath(tx, height)
+			if err != nil {
+				return err
+			}
+			pb, err := getBlockMap(tx, id)
+			if err != nil {
+				return err
+			}
+			err = enc.Encode(pb.Block)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ImportChain reads a chain file from r and adds the blocks to the consensus
+// set. Blocks that are already in the consensus set are skipped, so a chain
+// file can be imported into a partially synced consensus set. The blocks are
+// not broadcast to peers.
+func (cs *ConsensusSet) ImportChain(r io.Reader) error {
+	err := cs.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+
+	dec := encoding.NewDecoder(r)
+	var header chainFileHeader
+	err = dec.Decode(&header)
+	if err != nil {
+		return err
+	}
+	if header.Version != chainFileVersion {
+		return errChainBadHeader
+	}
+
+	// Accept the blocks in batches, which is much faster than accepting them
+	// one at a time.
+	batch := make([]types.Block, 0, MaxCatchUpBlocks)
+	for i := uint64(0); i < header.NumBlocks; i++ {
+		var b types.Block
+		err = dec.Decode(&b)
+		if err != nil {
+			return err
+		}
+		batch = append(batch, b)
+		if len(batch) == cap(batch) || i == header.NumBlocks-1 {
+			_, err = cs.managedAcceptBlocks(batch)
+			if err != nil && err != modules.ErrNonExtendingBlock && err != modules.ErrBlockKnown {
+				return err
+			}
+			batch = batch[:0]
+		}
+
+		// Stop early if the consensus set is shutting down.
+		select {
+		case <-cs.tg.StopChan():
+			return sync.ErrStopped
+		default:
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package consensus
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestExportImportChain exports the blockchain of one consensus set and
+// imports it into a fresh consensus set in two parts.
+func TestExportImportChain(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+	cst2, err := blankConsensusSetTester(t.Name() + "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst2.Close()
+	cst.testSpendSiacoinsBlock()
+
+	// Invalid ranges should be rejected.
+	height := cst.cs.Height()
+	var buf bytes.Buffer
+	err = cst.cs.ExportChain(&buf, 5, 4)
+	if err != errChainBadRange {
+		t.Fatal("expected errChainBadRange, got", err)
+	}
+	err = cst.cs.ExportChain(&buf, 0, height+1)
+	if err != errChainBadRange {
+		t.Fatal("expected errChainBadRange, got", err)
+	}
+
+	// Import the first half of the chain, and then an overlapping second
+	// half.
+	mid := height / 2
+	var first, second bytes.Buffer
+	err = cst.cs.ExportChain(&first, 0, mid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cst.cs.ExportChain(&second, mid-1, height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cst2.cs.ImportChain(&first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cst2.cs.Height() != mid {
+		t.Fatal("wrong height after importing first half:", cst2.cs.Height(), mid)
+	}
+	err = cst2.cs.ImportChain(&second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cst2.cs.CurrentBlock().ID() != cst.cs.CurrentBlock().ID() {
+		t.Fatal("imported chain does not match exported chain")
+	}
+	if cst2.cs.dbConsensusChecksum() != cst.cs.dbConsensusChecksum() {
+		t.Fatal("consensus checksums do not match after importing chain")
+	}
+
+	// A file with a bad header should be rejected.
+	var bad bytes.Buffer
+	err = cst.cs.ExportChain(&bad, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	corrupted := bad.Bytes()
+	corrupted[0] ^= 1
+	err = cst2.cs.ImportChain(bytes.NewReader(corrupted))
+	if err != errChainBadHeader {
+		t.Fatal("expected errChainBadHeader, got", err)
+	}
+
+	// A file containing an invalid block should be rejected.
+	header := chainFileHeader{
+		Version:    chainFileVersion,
+		FromHeight: height + 1,
+		NumBlocks:  1,
+	}
+	invalid := encoding.MarshalAll(header, types.Block{ParentID: cst.cs.CurrentBlock().ID()})
+	err = cst2.cs.ImportChain(bytes.NewReader(invalid))
+	if err == nil {
+		t.Fatal("chain file with an invalid block was imported")
+	}
+	if cst2.cs.Height() != height {
+		t.Fatal("invalid block changed the height of the consensus set")
+	}
+}
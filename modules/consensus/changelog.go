@@ -13,6 +13,14 @@ package consensus
 // changeEntry. The empty hash key leads to the 'changeTail', which contains
 // the id of the most recent changeEntry.
 //
+// The changelog is append-only, and is stored in the same database transaction
+// as the consensus changes it describes, so it survives restarts. A
+// subscriber that persists the ID of the last change it processed can pass
+// that ID to ConsensusSetSubscribe after a restart and will receive exactly
+// the changes that came after it, in order. The IDs are hashes rather than
+// counters, which means that an ID from a different consensus database is
+// rejected instead of silently resuming from the wrong position.
+//
 // Initialization only needs to worry about creating the blank change entry,
 // the genesis block will call 'append' later on during initialization.
 
@@ -1,9 +1,12 @@
 package consensus
 
 import (
+	"path/filepath"
 	"testing"
 
+	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/gateway"
 	"github.com/NebulousLabs/Sia/types"
 )
 
@@ -57,3 +60,57 @@ func TestIntegrationChangeLog(t *testing.T) {
 		t.Error("subscribers have inconsistent update chains")
 	}
 }
+
+// TestChangeLogResumeAfterRestart checks that the changelog is persisted, and
+// that a subscriber can resume from the last change it processed after the
+// consensus set has been restarted.
+func TestChangeLogResumeAfterRestart(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := blankConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// Subscribe from the beginning, then create a copy of the subscriber that
+	// stops processing partway through.
+	ms := newMockSubscriber()
+	cst.cs.ConsensusSetSubscribe(&ms, modules.ConsensusChangeBeginning)
+	cst.addSiafunds()
+	behindSubscriber := ms.copySub()
+	cst.mineSiacoins()
+	cst.cs.Unsubscribe(&ms)
+
+	// Restart the consensus set.
+	err = cst.cs.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := gateway.New("localhost:0", false, build.TempDir(modules.ConsensusDir, t.Name(), modules.GatewayDir+"2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	cst.cs, err = New(g, false, filepath.Join(cst.persistDir, modules.ConsensusDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Resume the behind subscriber from the last change it saw. It should
+	// receive exactly the changes that it missed.
+	err = cst.cs.ConsensusSetSubscribe(&behindSubscriber, behindSubscriber.updates[len(behindSubscriber.updates)-1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(behindSubscriber.updates) != len(ms.updates) {
+		t.Fatal("resumed subscriber received the wrong number of changes:", len(behindSubscriber.updates), len(ms.updates))
+	}
+	for i := range ms.updates {
+		if behindSubscriber.updates[i].ID != ms.updates[i].ID {
+			t.Fatal("resumed subscriber received changes in the wrong order")
+		}
+	}
+}
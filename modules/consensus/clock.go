@@ -0,0 +1,81 @@
+package consensus
+
+import (
+	"time"
+
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/NebulousLabs/bolt"
+)
+
+// clock.go compares the local clock to the timestamps of recent blocks. Blocks
+// with timestamps more than FutureThreshold ahead of the local clock are held
+// back as future blocks, so a local clock that is behind the network silently
+// delays or rejects new blocks. The consensus set warns when it detects this.
+
+// medianTimestamp returns the median timestamp of the most recent
+// MedianTimestampWindow blocks of the current path.
+func (cs *ConsensusSet) medianTimestamp(tx *bolt.Tx) types.Timestamp {
+	return cs.blockRuleHelper.minimumValidChildTimestamp(tx.Bucket(BlockMap), currentProcessedBlock(tx))
+}
+
+// clockSkew returns the local time minus the median timestamp of recent blocks.
+func clockSkew(median types.Timestamp) time.Duration {
+	return time.Duration(types.CurrentTimestamp()-median) * time.Second
+}
+
+// isClockSkewed returns true if the skew shows that the local clock is further
+// behind the network than the future threshold. The median timestamp normally
+// lags behind the network, so a clock that is ahead cannot be detected
+// reliably and is not reported.
+func isClockSkewed(skew time.Duration) bool {
+	return skew < -time.Duration(types.FutureThreshold)*time.Second
+}
+
+// checkClockSkew logs a warning and calls the clock skew callback when the
+// local clock falls behind the network. The warning is only repeated after the
+// clock has recovered. The caller must hold a write lock on the consensus set.
+func (cs *ConsensusSet) checkClockSkew() {
+	if !cs.synced {
+		return
+	}
+	var skew time.Duration
+	_ = cs.db.View(func(tx *bolt.Tx) error {
+		skew = clockSkew(cs.medianTimestamp(tx))
+		return nil
+	})
+	skewed := isClockSkewed(skew)
+	if skewed && !cs.clockSkewed {
+		cs.log.Printf("WARN: local clock is %v behind the median timestamp of recent blocks, new blocks may be rejected", -skew)
+		if cs.onClockSkew != nil {
+			go cs.onClockSkew(skew)
+		}
+	}
+	cs.clockSkewed = skewed
+}
+
+// MedianTimestamp returns the median timestamp of the most recent
+// MedianTimestampWindow blocks of the current path. A child of the current
+// block must have a timestamp of at least the median timestamp.
+func (cs *ConsensusSet) MedianTimestamp() (median types.Timestamp) {
+	// A call to a closed database can cause undefined behavior.
+	err := cs.tg.Add()
+	if err != nil {
+		return 0
+	}
+	defer cs.tg.Done()
+
+	_ = cs.db.View(func(tx *bolt.Tx) error {
+		median = cs.medianTimestamp(tx)
+		return nil
+	})
+	return median
+}
+
+// ClockSkew returns the local time minus the median timestamp of recent
+// blocks. Because the median timestamp lags behind the current block, a small
+// positive skew is normal. A negative skew beyond FutureThreshold means that
+// the local clock is behind the network.
+func (cs *ConsensusSet) ClockSkew() time.Duration {
+	return clockSkew(cs.MedianTimestamp())
+}
@@ -0,0 +1,82 @@
+package consensus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestMedianTimestamp checks that the median timestamp matches the minimum
+// valid child timestamp of the current block, and that a freshly mined chain
+// does not appear to be ahead of the local clock.
+func TestMedianTimestamp(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	expected, _ := cst.cs.MinimumValidChildTimestamp(cst.cs.CurrentBlock().ID())
+	if median := cst.cs.MedianTimestamp(); median != expected {
+		t.Fatal("median timestamp does not match the minimum valid child timestamp:", median, expected)
+	}
+	if skew := cst.cs.ClockSkew(); isClockSkewed(skew) {
+		t.Fatal("unexpected clock skew:", skew)
+	}
+}
+
+// TestClockSkewAlert checks that the clock skew callback is called once when
+// the local clock falls behind the network.
+func TestClockSkewAlert(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	alerts := make(chan time.Duration, 2)
+	cst.cs.mu.Lock()
+	defer cst.cs.mu.Unlock()
+	cst.cs.synced = true
+	cst.cs.onClockSkew = func(skew time.Duration) { alerts <- skew }
+
+	// Pretend that the recent blocks are well ahead of the local clock.
+	cst.cs.blockRuleHelper = mockBlockRuleHelper{
+		minTimestamp: types.CurrentTimestamp() + types.FutureThreshold + 60,
+	}
+	cst.cs.checkClockSkew()
+	select {
+	case skew := <-alerts:
+		if !isClockSkewed(skew) {
+			t.Fatal("alert was sent for a clock that is not skewed:", skew)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no alert was sent for a skewed clock")
+	}
+
+	// The alert should not be repeated while the clock stays skewed.
+	cst.cs.checkClockSkew()
+	select {
+	case <-alerts:
+		t.Fatal("alert was repeated")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// Once the clock recovers, the skew should be cleared.
+	cst.cs.blockRuleHelper = mockBlockRuleHelper{
+		minTimestamp: types.CurrentTimestamp(),
+	}
+	cst.cs.checkClockSkew()
+	if cst.cs.clockSkewed {
+		t.Fatal("clock skew was not cleared")
+	}
+}
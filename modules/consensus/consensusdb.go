@@ -48,6 +48,32 @@ var (
 	// initialized.
 	BucketOak = []byte("Oak")
 
+	// DeploymentStates is a database bucket containing the states of the
+	// deployments following each block that ends a version bits window,
+	// keyed by block id.
+	DeploymentStates = []byte("DeploymentStates")
+
+	// DoSBlocks is a database bucket containing the ids of blocks that failed
+	// expensive validation. The values are empty.
+	DoSBlocks = []byte("DoSBlocks")
+
+	// HeaderHeight is a database bucket storing the height of the header
+	// chain under the key HeaderHeight. It is only used by consensus sets in
+	// headers-only mode.
+	HeaderHeight = []byte("HeaderHeight")
+
+	// HeaderMap is a database bucket containing every validated header of a
+	// consensus set in headers-only mode, keyed by block id.
+	HeaderMap = []byte("HeaderMap")
+
+	// HeaderPath is a database bucket containing a mapping from height to the
+	// id of the header at that height in the heaviest header chain.
+	HeaderPath = []byte("HeaderPath")
+
+	// PrunedHeight is a database bucket storing the height of the most recent
+	// block that has been pruned, under the key PrunedHeight.
+	PrunedHeight = []byte("PrunedHeight")
+
 	// Consistency is a database bucket with a flag indicating whether
 	// inconsistencies within the database have been detected.
 	Consistency = []byte("Consistency")
@@ -67,12 +93,20 @@ var (
 	// SiafundPool is a database bucket storing the current value of the
 	// siafund pool.
 	SiafundPool = []byte("SiafundPool")
+
+	// Stats is a database bucket storing aggregate statistics about the
+	// consensus state, under the key FieldStats.
+	Stats = []byte("Stats")
 )
 
 var (
 	// FieldOakInit is a field in BucketOak that gets set to "true" after the
 	// oak initialiation process has completed.
 	FieldOakInit = []byte("OakInit")
+
+	// FieldStats is a field in the Stats bucket that holds the encoded
+	// consensus statistics.
+	FieldStats = []byte("Stats")
 )
 
 var (
@@ -1,13 +1,17 @@
 package consensus
 
-// All changes to the consenuss set are made via diffs, specifically by calling
-// a commitDiff function. This means that future modifications (such as
-// replacing in-memory versions of the utxo set with on-disk versions of the
-// utxo set) should be relatively easy to verify for correctness. Modifying the
-// commitDiff functions will be sufficient.
+// All changes to the consensus set are made via diffs, specifically by calling
+// a commitDiff function. The state of the consensus set (siacoin outputs, file
+// contracts, siafund outputs, delayed siacoin outputs, and the siafund pool)
+// lives entirely in the bolt database and is only accessed through the helpers
+// in consensusdb.go, which means that nodes can restart without rescanning the
+// blockchain and memory usage does not grow with the size of the utxo set.
+// Changes to the on-disk layout only require modifying the commitDiff
+// functions and the database helpers.
 
 import (
 	"errors"
+	"time"
 
 	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
@@ -56,18 +60,36 @@ type ConsensusSet struct {
 	// the function of adding a subscriber should not be exposed.
 	subscribers []modules.ConsensusSetSubscriber
 
+	// contractSubscribers receive the file contract events of every update
+	// to the consensus set.
+	contractSubscribers []modules.ContractEventSubscriber
+
 	// dosBlocks are blocks that are invalid, but the invalidity is only
 	// discoverable during an expensive step of validation. These blocks are
 	// recorded to eliminate a DoS vector where an expensive-to-validate block
-	// is submitted to the consensus set repeatedly.
+	// is submitted to the consensus set repeatedly. dosBlocks is loaded from
+	// the DoSBlocks bucket at startup, and newDoSBlocks holds the blocks that
+	// have not been persisted yet.
 	//
-	// TODO: dosBlocks needs to be moved into the database, and if there's some
-	// reason it can't be in THE database, it should be in a separate database.
 	// dosBlocks is an unbounded map that an attacker can manipulate, though
 	// iirc manipulations are expensive, to the tune of creating a blockchain
 	// PoW per DoS block (though the attacker could conceivably build off of
 	// the genesis block, meaning the PoW is not very expensive.
-	dosBlocks map[types.BlockID]struct{}
+	dosBlocks    map[types.BlockID]struct{}
+	newDoSBlocks []types.BlockID
+
+	// futureBlocks holds blocks whose timestamps were too far in the future
+	// when they were received. They are retried by
+	// threadedProcessFutureBlocks, which is woken through futureBlocksWake
+	// whenever a block is queued.
+	futureBlocks     map[types.BlockID]types.Block
+	futureBlocksWake chan struct{}
+
+	// orphans holds blocks whose parents are unknown, until the parents are
+	// added to the consensus set. fetchingOrphanParents is set while the
+	// parents of an orphan are being requested from peers.
+	orphans               map[types.BlockID]types.Block
+	fetchingOrphanParents bool
 
 	// checkingConsistency is a bool indicating whether or not a consistency
 	// check is in progress. The consistency check logic call itself, resulting
@@ -81,6 +103,43 @@ type ConsensusSet struct {
 	// whether the consensus set is synced with the network.
 	synced bool
 
+	// headersOnly is true if the consensus set only syncs the header chain.
+	headersOnly bool
+
+	// pruneDepth is the number of recent processed blocks that are kept in
+	// the block map. If zero, pruning is disabled and every block is kept.
+	pruneDepth types.BlockHeight
+
+	// reorgAlertDepth, haltOnDeepReorg and onDeepReorg control how deep
+	// reorgs are handled. See Config for details.
+	reorgAlertDepth types.BlockHeight
+	haltOnDeepReorg bool
+	onDeepReorg     func(DeepReorg)
+
+	// clockSkewed is true if the local clock was behind the network when
+	// the consensus set last changed. onClockSkew is called when the local
+	// clock falls behind.
+	clockSkewed bool
+	onClockSkew func(time.Duration)
+
+	// relayFilter, if set, decides which peers accepted blocks are relayed
+	// to.
+	relayFilter RelayFilter
+
+	// published is the height and current block of the consensus set as last
+	// sent to the subscribers. It has its own lock.
+	published publishedState
+
+	// blockCache holds recently used block bodies. It has its own lock.
+	blockCache *blockCache
+
+	// metrics tracks the block processing performed by the consensus set.
+	metrics Metrics
+
+	// prunedHeight is the height of the most recent block that has been
+	// pruned.
+	prunedHeight types.BlockHeight
+
 	// Interfaces to abstract the dependencies of the ConsensusSet.
 	marshaler       marshaler
 	blockRuleHelper blockRuleHelper
@@ -94,16 +153,73 @@ type ConsensusSet struct {
 	tg         sync.ThreadGroup
 }
 
+// Config contains optional settings for the consensus set. The zero value
+// results in the default behavior.
+type Config struct {
+	// HeadersOnly enables the lightweight sync mode, for wallets on
+	// constrained devices. Only block headers are downloaded and validated,
+	// full blocks are refused and the rest of the consensus state stays at
+	// the genesis block. Transactions can be confirmed against the header
+	// chain with RequestFilteredBlock.
+	HeadersOnly bool
+
+	// PruneDepth, if non-zero, enables pruning. Only the processed blocks of
+	// the most recent PruneDepth blocks are kept, older block bodies and
+	// diffs are deleted. PruneDepth must be at least twice TargetWindow, and
+	// at least VersionBitsWindow.
+	PruneDepth types.BlockHeight
+
+	// ReorgAlertDepth, if non-zero, is the number of reverted blocks at which
+	// a reorg is considered deep. Deep reorgs are logged and reported to
+	// OnDeepReorg.
+	ReorgAlertDepth types.BlockHeight
+
+	// HaltOnDeepReorg causes the consensus set to refuse deep reorgs, staying
+	// on its current chain, instead of performing them.
+	HaltOnDeepReorg bool
+
+	// OnDeepReorg, if set, is called in a separate goroutine after a deep
+	// reorg has been performed. Services can use it to, for example, pause
+	// withdrawals until the reorg has been investigated.
+	OnDeepReorg func(DeepReorg)
+
+	// OnClockSkew, if set, is called in a separate goroutine when the local
+	// clock falls more than FutureThreshold behind the median timestamp of
+	// recent blocks, with the local time minus the median timestamp.
+	OnClockSkew func(time.Duration)
+
+	// RelayFilter, if set, is consulted before an accepted block is relayed
+	// to peers. It can suppress relay, or relay to some peers before others,
+	// for example to mirror blocks selectively or to control propagation in
+	// tests. It is called in a separate goroutine.
+	RelayFilter RelayFilter
+
+	// BlockCacheSize is the maximum number of bytes of blocks that are kept
+	// in memory to answer block queries and serve blocks to peers. If zero,
+	// a default of 64 MiB is used.
+	BlockCacheSize uint64
+
+	// Network, if set, overrides the consensus constants before the
+	// consensus set is created, which allows private test networks to be run
+	// without recompiling. The constants are shared by every module, so the
+	// consensus set must be created before any module that depends on it.
+	Network *types.NetworkConfig
+}
+
 // New returns a new ConsensusSet, containing at least the genesis block. If
 // there is an existing block database present in the persist directory, it
 // will be loaded.
 func New(gateway modules.Gateway, bootstrap bool, persistDir string) (*ConsensusSet, error) {
-	// Check for nil dependencies.
-	if gateway == nil {
-		return nil, errNilGateway
-	}
+	return NewWithConfig(gateway, bootstrap, persistDir, Config{})
+}
 
-	// Create the ConsensusSet object.
+// newConsensusSet creates a ConsensusSet object, including the genesis block,
+// without opening the database.
+func newConsensusSet(gateway modules.Gateway, persistDir string, config Config) *ConsensusSet {
+	blockCacheSize := config.BlockCacheSize
+	if blockCacheSize == 0 {
+		blockCacheSize = defaultBlockCacheSize
+	}
 	cs := &ConsensusSet{
 		gateway: gateway,
 
@@ -117,10 +233,25 @@ func New(gateway modules.Gateway, bootstrap bool, persistDir string) (*Consensus
 
 		dosBlocks: make(map[types.BlockID]struct{}),
 
+		futureBlocks:     make(map[types.BlockID]types.Block),
+		futureBlocksWake: make(chan struct{}, 1),
+
+		orphans: make(map[types.BlockID]types.Block),
+
+		blockCache: newBlockCache(blockCacheSize),
+
 		marshaler:       stdMarshaler{},
 		blockRuleHelper: stdBlockRuleHelper{},
 		blockValidator:  NewBlockValidator(),
 
+		headersOnly:     config.HeadersOnly,
+		pruneDepth:      config.PruneDepth,
+		reorgAlertDepth: config.ReorgAlertDepth,
+		haltOnDeepReorg: config.HaltOnDeepReorg,
+		onDeepReorg:     config.OnDeepReorg,
+		onClockSkew:     config.OnClockSkew,
+		relayFilter:     config.RelayFilter,
+
 		persistDir: persistDir,
 	}
 
@@ -134,6 +265,26 @@ func New(gateway modules.Gateway, bootstrap bool, persistDir string) (*Consensus
 		}
 		cs.blockRoot.SiafundOutputDiffs = append(cs.blockRoot.SiafundOutputDiffs, sfod)
 	}
+	return cs
+}
+
+// NewWithConfig returns a new ConsensusSet that uses the provided config.
+func NewWithConfig(gateway modules.Gateway, bootstrap bool, persistDir string, config Config) (*ConsensusSet, error) {
+	// Check for nil dependencies.
+	if gateway == nil {
+		return nil, errNilGateway
+	}
+	if config.PruneDepth != 0 && config.PruneDepth < minPruneDepth() {
+		return nil, errPruneDepthTooSmall
+	}
+	if config.Network != nil {
+		err := config.Network.Apply()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	cs := newConsensusSet(gateway, persistDir, config)
 
 	// Initialize the consensus persistence structures.
 	err := cs.initPersist()
@@ -141,11 +292,14 @@ func New(gateway modules.Gateway, bootstrap bool, persistDir string) (*Consensus
 		return nil, err
 	}
 
+	// Retry future blocks once they become acceptable.
+	go cs.threadedProcessFutureBlocks()
+
 	go func() {
 		// Sync with the network. Don't sync if we are testing because
 		// typically we don't have any mock peers to synchronize with in
 		// testing.
-		if bootstrap {
+		if bootstrap && !cs.headersOnly {
 			// We are in a virgin goroutine right now, so calling the threaded
 			// function without a goroutine is okay.
 			err = cs.threadedInitialBlockchainDownload()
@@ -167,12 +321,24 @@ func New(gateway modules.Gateway, bootstrap bool, persistDir string) (*Consensus
 		gateway.RegisterRPC("SendBlocks", cs.rpcSendBlocks)
 		gateway.RegisterRPC("RelayHeader", cs.threadedRPCRelayHeader)
 		gateway.RegisterRPC("SendBlk", cs.rpcSendBlk)
-		gateway.RegisterConnectCall("SendBlocks", cs.threadedReceiveBlocks)
+		gateway.RegisterRPC("SendFilteredBlk", cs.rpcSendFilteredBlk)
+		gateway.RegisterRPC("SendHeaders", cs.rpcSendHeaders)
+		// A headers-only consensus set syncs headers instead of blocks from
+		// the peers that it connects to.
+		connectCall := "SendBlocks"
+		if cs.headersOnly {
+			connectCall = "SendHeaders"
+			gateway.RegisterConnectCall(connectCall, cs.threadedReceiveHeaders)
+		} else {
+			gateway.RegisterConnectCall(connectCall, cs.threadedReceiveBlocks)
+		}
 		cs.tg.OnStop(func() {
 			cs.gateway.UnregisterRPC("SendBlocks")
 			cs.gateway.UnregisterRPC("RelayHeader")
 			cs.gateway.UnregisterRPC("SendBlk")
-			cs.gateway.UnregisterConnectCall("SendBlocks")
+			cs.gateway.UnregisterRPC("SendFilteredBlk")
+			cs.gateway.UnregisterRPC("SendHeaders")
+			cs.gateway.UnregisterConnectCall(connectCall)
 		})
 
 		// Mark that we are synced with the network.
@@ -186,22 +352,68 @@ func New(gateway modules.Gateway, bootstrap bool, persistDir string) (*Consensus
 
 // BlockAtHeight returns the block at a given height.
 func (cs *ConsensusSet) BlockAtHeight(height types.BlockHeight) (block types.Block, exists bool) {
+	// A call to a closed database can cause undefined behavior.
+	err := cs.tg.Add()
+	if err != nil {
+		return types.Block{}, false
+	}
+	defer cs.tg.Done()
+
 	_ = cs.db.View(func(tx *bolt.Tx) error {
 		id, err := getPath(tx, height)
 		if err != nil {
 			return err
 		}
-		pb, err := getBlockMap(tx, id)
+		block, err = cs.getBlock(tx, id)
 		if err != nil {
 			return err
 		}
-		block = pb.Block
 		exists = true
 		return nil
 	})
 	return block, exists
 }
 
+// BlocksInRange returns the blocks of the current path between start and end,
+// inclusive, along with their ids. The blocks are looked up through the block
+// path, which is indexed by height, and are read from a single consistent view
+// of the database.
+func (cs *ConsensusSet) BlocksInRange(start, end types.BlockHeight) (blocks []modules.IndexedBlock, err error) {
+	// A call to a closed database can cause undefined behavior.
+	err = cs.tg.Add()
+	if err != nil {
+		return nil, err
+	}
+	defer cs.tg.Done()
+
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		if start > end || end > blockHeight(tx) {
+			return errChainBadRange
+		}
+		blocks = make([]modules.IndexedBlock, 0, end-start+1)
+		for height := start; height <= end; height++ {
+			id, err := getPath(tx, height)
+			if err != nil {
+				return err
+			}
+			b, err := cs.getBlock(tx, id)
+			if err != nil {
+				return err
+			}
+			blocks = append(blocks, modules.IndexedBlock{
+				ID:     id,
+				Height: height,
+				Block:  b,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
 // ChildTarget returns the target for the child of a block.
 func (cs *ConsensusSet) ChildTarget(id types.BlockID) (target types.Target, exists bool) {
 	// A call to a closed database can cause undefined behavior.
@@ -243,25 +455,50 @@ func (cs *ConsensusSet) managedCurrentBlock() (block types.Block) {
 
 // CurrentBlock returns the latest block in the heaviest known blockchain.
 func (cs *ConsensusSet) CurrentBlock() (block types.Block) {
-	// A call to a closed database can cause undefined behavior.
+	// Queries are not answered once the consensus set has shut down.
 	err := cs.tg.Add()
 	if err != nil {
 		return types.Block{}
 	}
 	defer cs.tg.Done()
 
-	// Block until a lock can be grabbed on the consensus set, indicating that
-	// all modules have received the most recent block. The lock is held so that
-	// there are no race conditions when trying to synchronize nodes.
-	cs.mu.Lock()
-	defer cs.mu.Unlock()
+	// The published block has been received by every module, so there are
+	// no race conditions when trying to synchronize nodes.
+	return cs.publishedCurrentBlock()
+}
 
-	_ = cs.db.View(func(tx *bolt.Tx) error {
-		pb := currentProcessedBlock(tx)
-		block = pb.Block
+// DelayedOutputsAtHeight returns the delayed siacoin outputs that will mature
+// at the provided height, sorted by ID. Delayed outputs include miner payouts
+// and the payouts of file contracts with missed storage proofs. The result is
+// empty if no outputs mature at the height, including when the height has
+// already been reached.
+func (cs *ConsensusSet) DelayedOutputsAtHeight(height types.BlockHeight) (dscos []modules.DelayedSiacoinOutput) {
+	// A call to a closed database can cause undefined behavior.
+	err := cs.tg.Add()
+	if err != nil {
 		return nil
+	}
+	defer cs.tg.Done()
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	_ = cs.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(append(prefixDSCO, encoding.Marshal(height)...))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(idBytes, scoBytes []byte) error {
+			dsco := modules.DelayedSiacoinOutput{MaturityHeight: height}
+			copy(dsco.ID[:], idBytes)
+			err := encoding.Unmarshal(scoBytes, &dsco.SiacoinOutput)
+			if err != nil {
+				return err
+			}
+			dscos = append(dscos, dsco)
+			return nil
+		})
 	})
-	return block
+	return dscos
 }
 
 // Flush will block until the consensus set has finished all in-progress
@@ -272,24 +509,16 @@ func (cs *ConsensusSet) Flush() error {
 
 // Height returns the height of the consensus set.
 func (cs *ConsensusSet) Height() (height types.BlockHeight) {
-	// A call to a closed database can cause undefined behavior.
+	// Queries are not answered once the consensus set has shut down.
 	err := cs.tg.Add()
 	if err != nil {
 		return 0
 	}
 	defer cs.tg.Done()
 
-	// Block until a lock can be grabbed on the consensus set, indicating that
-	// all modules have received the most recent block. The lock is held so that
-	// there are no race conditions when trying to synchronize nodes.
-	cs.mu.Lock()
-	defer cs.mu.Unlock()
-
-	_ = cs.db.View(func(tx *bolt.Tx) error {
-		height = blockHeight(tx)
-		return nil
-	})
-	return height
+	// The published height has been received by every module, so there are
+	// no race conditions when trying to synchronize nodes.
+	return cs.publishedHeight()
 }
 
 // InCurrentPath returns true if the block presented is in the current path,
@@ -199,3 +199,97 @@ func TestDatabaseClosing(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+// TestDelayedOutputsAtHeight checks that the miner payouts of the current
+// block are reported as delayed outputs at their maturity height.
+func TestDelayedOutputsAtHeight(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	b := cst.cs.CurrentBlock()
+	maturityHeight := cst.cs.Height() + types.MaturityDelay
+	dscos := cst.cs.DelayedOutputsAtHeight(maturityHeight)
+	if len(dscos) != len(b.MinerPayouts) {
+		t.Fatalf("expected %v delayed outputs, got %v", len(b.MinerPayouts), len(dscos))
+	}
+	for i, mp := range b.MinerPayouts {
+		found := false
+		for _, dsco := range dscos {
+			if dsco.ID == b.MinerPayoutID(uint64(i)) {
+				found = true
+				if dsco.SiacoinOutput.Value.Cmp(mp.Value) != 0 || dsco.MaturityHeight != maturityHeight {
+					t.Error("delayed output does not match the miner payout")
+				}
+			}
+		}
+		if !found {
+			t.Error("miner payout is missing from the delayed outputs")
+		}
+	}
+
+	// Outputs that have already matured are no longer delayed.
+	if dscos := cst.cs.DelayedOutputsAtHeight(cst.cs.Height()); len(dscos) != 0 {
+		t.Error("matured outputs are reported as delayed")
+	}
+}
+
+// TestBlocksInRange checks that BlocksInRange returns the blocks of the
+// current path and rejects ranges outside of it.
+func TestBlocksInRange(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	height := cst.cs.Height()
+	blocks, err := cst.cs.BlocksInRange(0, height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if types.BlockHeight(len(blocks)) != height+1 {
+		t.Fatal("wrong number of blocks returned:", len(blocks), height+1)
+	}
+	for i, b := range blocks {
+		expected, exists := cst.cs.BlockAtHeight(types.BlockHeight(i))
+		if !exists {
+			t.Fatal("block does not exist at height", i)
+		}
+		if b.Height != types.BlockHeight(i) || b.ID != expected.ID() || b.Block.ID() != b.ID {
+			t.Fatal("wrong block returned at height", i)
+		}
+	}
+	if blocks[height].ID != cst.cs.CurrentBlock().ID() {
+		t.Fatal("last block in range is not the current block")
+	}
+
+	// Check a range in the middle of the chain.
+	blocks, err = cst.cs.BlocksInRange(3, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 3 || blocks[0].Height != 3 || blocks[2].Height != 5 {
+		t.Fatal("wrong blocks returned for a partial range")
+	}
+
+	// Invalid ranges should be rejected.
+	_, err = cst.cs.BlocksInRange(5, 3)
+	if err != errChainBadRange {
+		t.Fatal("expected errChainBadRange, got", err)
+	}
+	_, err = cst.cs.BlocksInRange(0, height+1)
+	if err != errChainBadRange {
+		t.Fatal("expected errChainBadRange, got", err)
+	}
+}
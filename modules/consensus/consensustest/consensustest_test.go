@@ -0,0 +1,125 @@
+package consensustest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/consensus"
+	"github.com/NebulousLabs/Sia/modules/gateway"
+)
+
+// newConsensusSet returns a fresh consensus set, along with a function that
+// closes it.
+func newConsensusSet(t *testing.T, name string) (modules.ConsensusSet, func()) {
+	testdir := build.TempDir("consensustest", name)
+	g, err := gateway.New("localhost:0", false, filepath.Join(testdir, modules.GatewayDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs, err := consensus.New(g, false, filepath.Join(testdir, modules.ConsensusDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cs, func() {
+		cs.Close()
+		g.Close()
+	}
+}
+
+// TestRun drives a consensus set with valid and invalid blocks.
+func TestRun(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cs, closeFn := newConsensusSet(t, t.Name())
+	defer closeFn()
+
+	trace, err := NewGenerator(cs, 1).Run(60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var valid, spends int
+	mutations := make(map[Mutation]bool)
+	for _, step := range trace {
+		if step.Mutation == MutationNone {
+			valid++
+			spends += len(step.Block.Transactions)
+		} else {
+			mutations[step.Mutation] = true
+		}
+	}
+	if int(cs.Height()) != valid {
+		t.Fatalf("height is %v after %v valid blocks", cs.Height(), valid)
+	}
+	if spends == 0 {
+		t.Error("generator never spent its outputs")
+	}
+	if len(mutations) < 2 {
+		t.Error("run did not exercise several mutations:", mutations)
+	}
+}
+
+// TestInvalidBlock checks every mutation individually.
+func TestInvalidBlock(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cs, closeFn := newConsensusSet(t, t.Name())
+	defer closeFn()
+
+	g := NewGenerator(cs, 2)
+	_, err := g.InvalidBlock(MutationDoubleSpend)
+	if err != ErrNoSpendableOutputs {
+		t.Fatalf("expected %v, got %v", ErrNoSpendableOutputs, err)
+	}
+	for len(g.spendableOutputs(cs.Height()+1)) == 0 {
+		if _, err := g.Accept(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	before := CaptureState(cs)
+	for _, m := range Mutations {
+		b, err := g.InvalidBlock(m)
+		if err != nil {
+			t.Fatal(m, err)
+		}
+		if cs.AcceptBlock(b) == nil {
+			t.Fatal("invalid block was accepted:", m)
+		}
+		if err := before.Compare(CaptureState(cs)); err != nil {
+			t.Fatal(m, err)
+		}
+	}
+}
+
+// TestDeterminism checks that two runs with the same seed produce the same
+// blocks and states, and that runs with different seeds do not. The genesis
+// block of the testing build depends on the time the process started, so
+// traces can only be compared within a single process.
+func TestDeterminism(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	var traces [][]Step
+	for i, seed := range []int64{3, 3, 4} {
+		cs, closeFn := newConsensusSet(t, filepath.Join(t.Name(), string(rune('a'+i))))
+		trace, err := NewGenerator(cs, seed).Run(30)
+		closeFn()
+		if err != nil {
+			t.Fatal(err)
+		}
+		traces = append(traces, trace)
+	}
+	if err := CompareTraces(traces[0], traces[1]); err != nil {
+		t.Fatal("runs with the same seed differ:", err)
+	}
+	if CompareTraces(traces[0], traces[2]) == nil {
+		t.Fatal("runs with different seeds are the same")
+	}
+}
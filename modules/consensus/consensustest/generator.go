@@ -0,0 +1,276 @@
+// Package consensustest provides a deterministic harness for exercising a
+// consensus set. A Generator builds valid blocks and blocks that break a
+// single consensus rule, feeds them to the consensus set, and records the
+// resulting state so that two runs with the same seed can be compared. The
+// harness only uses the modules.ConsensusSet interface, so it can be pointed at
+// modified consensus implementations to check that they still accept and
+// reject the same blocks.
+//
+// The Generator assumes that it is the only source of blocks for the
+// consensus set, and that the consensus set is fresh: it tracks its own
+// outputs from the blocks it has had accepted, and it timestamps each block
+// BlockFrequency seconds after its parent, which keeps the chain behind the
+// clock only while the chain is young.
+package consensustest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"sort"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+const (
+	// maxTransactions is the maximum number of transactions that the
+	// Generator puts in a block.
+	maxTransactions = 3
+
+	// solveAttempts is the number of nonces that are tried before giving up
+	// on solving a block.
+	solveAttempts = 1 << 24
+)
+
+var (
+	// ErrNoSpendableOutputs is returned when a mutation that changes a
+	// transaction is requested before the Generator has any matured outputs
+	// to spend.
+	ErrNoSpendableOutputs = errors.New("generator has no spendable outputs")
+
+	errSolveFailed     = errors.New("could not find a nonce for the block")
+	errUnknownParent   = errors.New("consensus set does not know the current block")
+	errUnknownMutation = errors.New("unknown mutation")
+)
+
+type (
+	// A Generator builds blocks on top of the current block of a consensus
+	// set. All of its choices are drawn from a seeded source of randomness,
+	// so two Generators with the same seed that drive identical consensus
+	// sets produce identical blocks.
+	Generator struct {
+		cs      modules.ConsensusSet
+		rand    *rand.Rand
+		sk      crypto.SecretKey
+		uc      types.UnlockConditions
+		outputs map[types.SiacoinOutputID]output
+	}
+
+	// output is a siacoin output that the Generator can spend.
+	output struct {
+		value types.Currency
+
+		// spendable is the height of the first block that can spend the
+		// output.
+		spendable types.BlockHeight
+	}
+)
+
+// NewGenerator returns a Generator for the consensus set. The seed determines
+// the key that receives the block rewards and every random choice that the
+// Generator makes.
+func NewGenerator(cs modules.ConsensusSet, seed int64) *Generator {
+	var entropy [crypto.EntropySize]byte
+	binary.LittleEndian.PutUint64(entropy[:], uint64(seed))
+	sk, pk := crypto.GenerateKeyPairDeterministic(entropy)
+	return &Generator{
+		cs:   cs,
+		rand: rand.New(rand.NewSource(seed)),
+		sk:   sk,
+		uc: types.UnlockConditions{
+			PublicKeys:         []types.SiaPublicKey{types.Ed25519PublicKey(pk)},
+			SignaturesRequired: 1,
+		},
+		outputs: make(map[types.SiacoinOutputID]output),
+	}
+}
+
+// UnlockHash returns the address that receives the block rewards of the
+// Generator.
+func (g *Generator) UnlockHash() types.UnlockHash {
+	return g.uc.UnlockHash()
+}
+
+// spendableOutputs returns the ids of the outputs that can be spent in a
+// block at the given height, in a deterministic order.
+func (g *Generator) spendableOutputs(height types.BlockHeight) []types.SiacoinOutputID {
+	var ids []types.SiacoinOutputID
+	for id, o := range g.outputs {
+		if o.spendable <= height {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return bytes.Compare(ids[i][:], ids[j][:]) < 0
+	})
+	return ids
+}
+
+// signTransaction signs the only input of the transaction.
+func (g *Generator) signTransaction(txn *types.Transaction) {
+	txn.TransactionSignatures = []types.TransactionSignature{{
+		ParentID:       crypto.Hash(txn.SiacoinInputs[0].ParentID),
+		CoveredFields:  types.CoveredFields{WholeTransaction: true},
+		PublicKeyIndex: 0,
+	}}
+	sig := crypto.SignHash(txn.SigHash(0), g.sk)
+	txn.TransactionSignatures[0].Signature = sig[:]
+}
+
+// spendTransaction returns a signed transaction that splits the output in two
+// and pays a random miner fee.
+func (g *Generator) spendTransaction(id types.SiacoinOutputID) types.Transaction {
+	value := g.outputs[id].value
+	fee := types.NewCurrency64(uint64(g.rand.Intn(2)))
+	rest := value.Sub(fee)
+	half := rest.Div64(2)
+	txn := types.Transaction{
+		SiacoinInputs: []types.SiacoinInput{{
+			ParentID:         id,
+			UnlockConditions: g.uc,
+		}},
+		SiacoinOutputs: []types.SiacoinOutput{
+			{Value: half, UnlockHash: g.UnlockHash()},
+			{Value: rest.Sub(half), UnlockHash: g.UnlockHash()},
+		},
+	}
+	if !fee.IsZero() {
+		txn.MinerFees = []types.Currency{fee}
+	}
+	g.signTransaction(&txn)
+	return txn
+}
+
+// unsolvedBlock returns a valid block on top of the current block, without a
+// nonce. At least minTxns transactions are included; an error is returned if
+// the Generator does not have enough spendable outputs for them.
+func (g *Generator) unsolvedBlock(minTxns int) (types.Block, error) {
+	parent := g.cs.CurrentBlock()
+	parentID := parent.ID()
+	height := g.cs.Height() + 1
+	minTimestamp, exists := g.cs.MinimumValidChildTimestamp(parentID)
+	if !exists {
+		return types.Block{}, errUnknownParent
+	}
+
+	b := types.Block{
+		ParentID:  parentID,
+		Timestamp: parent.Timestamp + types.Timestamp(types.BlockFrequency),
+	}
+	if b.Timestamp < minTimestamp {
+		b.Timestamp = minTimestamp
+	}
+
+	ids := g.spendableOutputs(height)
+	if len(ids) < minTxns {
+		return types.Block{}, ErrNoSpendableOutputs
+	}
+	g.rand.Shuffle(len(ids), func(i, j int) {
+		ids[i], ids[j] = ids[j], ids[i]
+	})
+	n := minTxns + g.rand.Intn(maxTransactions-minTxns+1)
+	if n > len(ids) {
+		n = len(ids)
+	}
+	for _, id := range ids[:n] {
+		b.Transactions = append(b.Transactions, g.spendTransaction(id))
+	}
+
+	b.MinerPayouts = []types.SiacoinOutput{{
+		Value:      b.CalculateSubsidy(height),
+		UnlockHash: g.UnlockHash(),
+	}}
+	return b, nil
+}
+
+// solve returns the block with the first nonce whose id meets the target of
+// the block's parent, or, if 'valid' is false, the first nonce whose id does
+// not.
+func (g *Generator) solve(b types.Block, valid bool) (types.Block, error) {
+	target, exists := g.cs.ChildTarget(b.ParentID)
+	if !exists {
+		return types.Block{}, errUnknownParent
+	}
+	header := encoding.Marshal(b.Header())
+	for nonce := uint64(0); nonce < solveAttempts; nonce++ {
+		binary.LittleEndian.PutUint64(header[32:40], nonce)
+		id := crypto.HashBytes(header)
+		if (bytes.Compare(target[:], id[:]) >= 0) == valid {
+			copy(b.Nonce[:], header[32:40])
+			return b, nil
+		}
+	}
+	return types.Block{}, errSolveFailed
+}
+
+// track records the outputs that the Generator gains and loses when the block
+// is accepted at the given height.
+func (g *Generator) track(b types.Block, height types.BlockHeight) {
+	for i, mp := range b.MinerPayouts {
+		if mp.UnlockHash == g.UnlockHash() {
+			g.outputs[b.MinerPayoutID(uint64(i))] = output{
+				value:     mp.Value,
+				spendable: height + types.MaturityDelay + 1,
+			}
+		}
+	}
+	for _, txn := range b.Transactions {
+		for _, sci := range txn.SiacoinInputs {
+			delete(g.outputs, sci.ParentID)
+		}
+		for i, sco := range txn.SiacoinOutputs {
+			if sco.UnlockHash == g.UnlockHash() {
+				g.outputs[txn.SiacoinOutputID(uint64(i))] = output{
+					value:     sco.Value,
+					spendable: height + 1,
+				}
+			}
+		}
+	}
+}
+
+// Block returns a valid, solved block on top of the current block of the
+// consensus set. The block is not submitted.
+func (g *Generator) Block() (types.Block, error) {
+	b, err := g.unsolvedBlock(0)
+	if err != nil {
+		return types.Block{}, err
+	}
+	return g.solve(b, true)
+}
+
+// InvalidBlock returns a solved block on top of the current block of the
+// consensus set that breaks exactly the consensus rule described by the
+// mutation. The block is not submitted.
+func (g *Generator) InvalidBlock(m Mutation) (types.Block, error) {
+	minTxns := 0
+	if m.changesTransaction() {
+		minTxns = 1
+	}
+	b, err := g.unsolvedBlock(minTxns)
+	if err != nil {
+		return types.Block{}, err
+	}
+	if err := g.mutate(&b, m); err != nil {
+		return types.Block{}, err
+	}
+	return g.solve(b, m != MutationUnsolved)
+}
+
+// Accept builds a valid block and submits it to the consensus set.
+func (g *Generator) Accept() (types.Block, error) {
+	b, err := g.Block()
+	if err != nil {
+		return types.Block{}, err
+	}
+	height := g.cs.Height() + 1
+	if err := g.cs.AcceptBlock(b); err != nil {
+		return types.Block{}, err
+	}
+	g.track(b, height)
+	return b, nil
+}
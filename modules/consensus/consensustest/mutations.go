@@ -0,0 +1,87 @@
+package consensustest
+
+import (
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// A Mutation describes the single consensus rule that an invalid block
+// breaks.
+type Mutation string
+
+// The mutations that the Generator can apply to a valid block. MutationNone
+// describes a valid block.
+const (
+	MutationNone            = Mutation("")
+	MutationUnsolved        = Mutation("unsolved")
+	MutationEarlyTimestamp  = Mutation("early timestamp")
+	MutationFutureTimestamp = Mutation("extreme future timestamp")
+	MutationBadPayout       = Mutation("bad miner payout")
+	MutationDoubleSpend     = Mutation("double spend")
+	MutationBadSignature    = Mutation("bad signature")
+	MutationOverspend       = Mutation("overspend")
+	MutationMissingOutput   = Mutation("missing output")
+)
+
+// Mutations is the set of mutations that Run draws from.
+var Mutations = []Mutation{
+	MutationUnsolved,
+	MutationEarlyTimestamp,
+	MutationFutureTimestamp,
+	MutationBadPayout,
+	MutationDoubleSpend,
+	MutationBadSignature,
+	MutationOverspend,
+	MutationMissingOutput,
+}
+
+// changesTransaction returns true if the mutation needs a block with at least
+// one transaction.
+func (m Mutation) changesTransaction() bool {
+	switch m {
+	case MutationDoubleSpend, MutationBadSignature, MutationOverspend, MutationMissingOutput:
+		return true
+	}
+	return false
+}
+
+// mutate applies the mutation to an unsolved block. Mutations of the unsolved
+// kind are applied when the block is solved.
+func (g *Generator) mutate(b *types.Block, m Mutation) error {
+	switch m {
+	case MutationUnsolved:
+	case MutationEarlyTimestamp:
+		minTimestamp, exists := g.cs.MinimumValidChildTimestamp(b.ParentID)
+		if !exists {
+			return errUnknownParent
+		}
+		b.Timestamp = minTimestamp - 1
+	case MutationFutureTimestamp:
+		// The block is too far in the future to be kept for later, so it is
+		// rejected outright.
+		b.Timestamp = types.CurrentTimestamp() + 2*types.ExtremeFutureThreshold
+	case MutationBadPayout:
+		b.MinerPayouts[0].Value = b.MinerPayouts[0].Value.Add(types.NewCurrency64(1))
+	case MutationDoubleSpend:
+		// The copy is a different transaction spending the same output.
+		txn := b.Transactions[0]
+		txn.SiacoinOutputs = append([]types.SiacoinOutput(nil), txn.SiacoinOutputs...)
+		txn.SiacoinOutputs[0].Value, txn.SiacoinOutputs[1].Value = txn.SiacoinOutputs[1].Value, txn.SiacoinOutputs[0].Value
+		txn.ArbitraryData = [][]byte{[]byte("double spend")}
+		g.signTransaction(&txn)
+		b.Transactions = append(b.Transactions, txn)
+	case MutationBadSignature:
+		sig := b.Transactions[0].TransactionSignatures[0].Signature
+		sig[g.rand.Intn(len(sig))] ^= 1
+	case MutationOverspend:
+		txn := &b.Transactions[0]
+		txn.SiacoinOutputs[0].Value = txn.SiacoinOutputs[0].Value.Add(types.NewCurrency64(1))
+		g.signTransaction(txn)
+	case MutationMissingOutput:
+		txn := &b.Transactions[0]
+		g.rand.Read(txn.SiacoinInputs[0].ParentID[:])
+		g.signTransaction(txn)
+	default:
+		return errUnknownMutation
+	}
+	return nil
+}
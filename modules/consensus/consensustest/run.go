@@ -0,0 +1,132 @@
+package consensustest
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	errInvalidBlockAccepted = errors.New("consensus set accepted an invalid block")
+	errTraceLength          = errors.New("traces have different lengths")
+)
+
+type (
+	// State is a snapshot of a consensus set.
+	State struct {
+		Height    types.BlockHeight
+		BlockID   types.BlockID
+		StateHash crypto.Hash
+	}
+
+	// A Step is a single block that was submitted by Run.
+	Step struct {
+		Mutation Mutation
+		Block    types.Block
+
+		// Err is the error returned by AcceptBlock.
+		Err error
+
+		// State is the state of the consensus set after the block was
+		// submitted.
+		State State
+	}
+)
+
+// CaptureState returns a snapshot of the consensus set.
+func CaptureState(cs modules.ConsensusSet) State {
+	return State{
+		Height:    cs.Height(),
+		BlockID:   cs.CurrentBlock().ID(),
+		StateHash: cs.StateHash(),
+	}
+}
+
+// Compare returns an error describing the first difference between the two
+// snapshots, or nil if they are the same.
+func (s State) Compare(other State) error {
+	if s.Height != other.Height {
+		return fmt.Errorf("height %v does not match height %v", s.Height, other.Height)
+	} else if s.BlockID != other.BlockID {
+		return fmt.Errorf("current block %v does not match current block %v", s.BlockID, other.BlockID)
+	} else if s.StateHash != other.StateHash {
+		return fmt.Errorf("state hash %v does not match state hash %v", s.StateHash, other.StateHash)
+	}
+	return nil
+}
+
+// Run submits the given number of blocks to the consensus set. About a third
+// of the blocks are invalid; Run checks that each of them is rejected without
+// changing the state of the consensus set, and that each valid block is
+// accepted. An error is returned as soon as either check fails.
+func (g *Generator) Run(steps int) ([]Step, error) {
+	var trace []Step
+	for i := 0; i < steps; i++ {
+		before := CaptureState(g.cs)
+		height := g.cs.Height() + 1
+		mutations := Mutations
+		if len(g.spendableOutputs(height)) == 0 {
+			mutations = nil
+			for _, m := range Mutations {
+				if !m.changesTransaction() {
+					mutations = append(mutations, m)
+				}
+			}
+		}
+
+		step := Step{Mutation: MutationNone}
+		var err error
+		if g.rand.Intn(3) == 0 {
+			step.Mutation = mutations[g.rand.Intn(len(mutations))]
+			step.Block, err = g.InvalidBlock(step.Mutation)
+		} else {
+			step.Block, err = g.Block()
+		}
+		if err != nil {
+			return trace, err
+		}
+		step.Err = g.cs.AcceptBlock(step.Block)
+		step.State = CaptureState(g.cs)
+		trace = append(trace, step)
+
+		if step.Mutation != MutationNone {
+			if step.Err == nil {
+				return trace, fmt.Errorf("%v: %v", errInvalidBlockAccepted, step.Mutation)
+			}
+			if err := before.Compare(step.State); err != nil {
+				return trace, fmt.Errorf("invalid block (%v) changed the consensus set: %v", step.Mutation, err)
+			}
+			continue
+		}
+		if step.Err != nil {
+			return trace, fmt.Errorf("valid block was rejected: %v", step.Err)
+		}
+		g.track(step.Block, height)
+	}
+	return trace, nil
+}
+
+// CompareTraces returns an error describing the first difference between two
+// runs, or nil if they submitted the same kinds of blocks and ended each step
+// in the same state. Invalid blocks are not compared, because blocks with
+// future timestamps depend on the clock.
+func CompareTraces(a, b []Step) error {
+	if len(a) != len(b) {
+		return errTraceLength
+	}
+	for i := range a {
+		if a[i].Mutation != b[i].Mutation {
+			return fmt.Errorf("step %v: mutation %q does not match mutation %q", i, a[i].Mutation, b[i].Mutation)
+		}
+		if a[i].Mutation == MutationNone && a[i].Block.ID() != b[i].Block.ID() {
+			return fmt.Errorf("step %v: block %v does not match block %v", i, a[i].Block.ID(), b[i].Block.ID())
+		}
+		if err := a[i].State.Compare(b[i].State); err != nil {
+			return fmt.Errorf("step %v: %v", i, err)
+		}
+	}
+	return nil
+}
@@ -276,6 +276,60 @@ func checkDSCOs(tx *bolt.Tx) {
 	}
 }
 
+// checkFileContractExpirations checks that the file contract expiration index
+// matches the set of open file contracts. Every open file contract should
+// appear exactly once in the expiration bucket for its WindowEnd, and no
+// expiration bucket at or below the current height should contain any
+// contracts, as those contracts should have been processed by maintenance.
+func checkFileContractExpirations(tx *bolt.Tx) {
+	currentHeight := blockHeight(tx)
+	numExpirations := 0
+	err := tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+		// Skip any buckets that are not file contract expiration buckets.
+		if !bytes.HasPrefix(name, prefixFCEX) {
+			return nil
+		}
+
+		var windowEnd types.BlockHeight
+		err := encoding.Unmarshal(name[len(prefixFCEX):], &windowEnd)
+		if err != nil {
+			manageErr(tx, err)
+		}
+		return b.ForEach(func(idBytes, _ []byte) error {
+			if windowEnd <= currentHeight {
+				return errors.New("file contract expiration was not processed by maintenance")
+			}
+			var id types.FileContractID
+			copy(id[:], idBytes)
+			fc, err := getFileContract(tx, id)
+			if err != nil {
+				return errors.New("file contract expiration index contains a nonexistent file contract")
+			}
+			if fc.WindowEnd != windowEnd {
+				return errors.New("file contract is indexed under the wrong expiration height")
+			}
+			numExpirations++
+			return nil
+		})
+	})
+	if err != nil {
+		manageErr(tx, err)
+	}
+
+	// Every open file contract should have been found in the index.
+	numContracts := 0
+	err = tx.Bucket(FileContracts).ForEach(func(_, _ []byte) error {
+		numContracts++
+		return nil
+	})
+	if err != nil {
+		manageErr(tx, err)
+	}
+	if numContracts != numExpirations {
+		manageErr(tx, errors.New("file contract expiration index does not match the set of file contracts"))
+	}
+}
+
 // checkRevertApply reverts the most recent block, checking to see that the
 // consensus set hash matches the hash obtained for the previous block. Then it
 // applies the block again and checks that the consensus set hash matches the
@@ -319,8 +373,10 @@ func (cs *ConsensusSet) checkConsistency(tx *bolt.Tx) {
 
 	cs.checkingConsistency = true
 	checkDSCOs(tx)
+	checkFileContractExpirations(tx)
 	checkSiacoinCount(tx)
 	checkSiafundCount(tx)
+	checkStats(tx)
 	if build.DEBUG {
 		cs.checkRevertApply(tx)
 	}
@@ -336,6 +392,3 @@ func (cs *ConsensusSet) maybeCheckConsistency(tx *bolt.Tx) {
 		cs.checkConsistency(tx)
 	}
 }
-
-// TODO: Check that every file contract has an expiration too, and that the
-// number of file contracts + the number of expirations is equal.
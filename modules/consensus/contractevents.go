@@ -0,0 +1,104 @@
+package consensus
+
+import (
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/NebulousLabs/bolt"
+)
+
+// contractevents.go derives the lifecycle events of file contracts from the
+// file contract diffs of processed blocks. Transactions are applied before
+// maintenance, so the diffs of a block are walked in order and classified
+// using the contracts, revisions and storage proofs found in the block's
+// transactions. A revert diff that is not explained by a revision or a
+// storage proof was created by applyMissedStorageProof.
+
+// blockContractEvents returns the contract events caused by a processed
+// block, in the order that they were applied.
+func blockContractEvents(pb *processedBlock) []modules.ContractEvent {
+	formed := make(map[types.FileContractID]struct{})
+	revised := make(map[types.FileContractID]struct{})
+	proved := make(map[types.FileContractID]struct{})
+	for _, txn := range pb.Block.Transactions {
+		for i := range txn.FileContracts {
+			formed[txn.FileContractID(uint64(i))] = struct{}{}
+		}
+		for _, fcr := range txn.FileContractRevisions {
+			revised[fcr.ParentID] = struct{}{}
+		}
+		for _, sp := range txn.StorageProofs {
+			proved[sp.ParentID] = struct{}{}
+		}
+	}
+
+	var events []modules.ContractEvent
+	for _, fcd := range pb.FileContractDiffs {
+		event := modules.ContractEvent{
+			ID:           fcd.ID,
+			FileContract: fcd.FileContract,
+			BlockID:      pb.Block.ID(),
+			Height:       pb.Height,
+		}
+		_, isFormed := formed[fcd.ID]
+		_, isRevised := revised[fcd.ID]
+		_, isProved := proved[fcd.ID]
+		if fcd.Direction == modules.DiffApply {
+			// A contract that is formed and revised in the same block is
+			// applied once for the formation and once for each revision.
+			if isFormed {
+				event.Type = modules.ContractEventFormed
+				delete(formed, fcd.ID)
+			} else if isRevised {
+				event.Type = modules.ContractEventRevised
+			} else {
+				// The contract was added without a transaction, which
+				// happens when a snapshot is loaded.
+				event.Type = modules.ContractEventFormed
+			}
+		} else {
+			if isProved {
+				event.Type = modules.ContractEventProofSubmitted
+			} else if isRevised {
+				// The contract is being replaced by its revision, which is
+				// reported by the following apply diff.
+				continue
+			} else {
+				event.Type = modules.ContractEventExpired
+			}
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// computeContractEvents computes the contract events of the change entry.
+func (cs *ConsensusSet) computeContractEvents(tx *bolt.Tx, ce changeEntry) (modules.ContractEventUpdate, error) {
+	ceu := modules.ContractEventUpdate{
+		ID: ce.ID(),
+	}
+	for _, revertedBlockID := range ce.RevertedBlocks {
+		revertedBlock, err := getBlockMap(tx, revertedBlockID)
+		if err != nil && cs.prunedHeight != 0 {
+			return modules.ContractEventUpdate{}, modules.ErrPrunedConsensusChange
+		} else if err != nil {
+			cs.log.Critical("getBlockMap failed in computeContractEvents:", err)
+			return modules.ContractEventUpdate{}, err
+		}
+		events := blockContractEvents(revertedBlock)
+		for i := len(events) - 1; i >= 0; i-- {
+			ceu.RevertedEvents = append(ceu.RevertedEvents, events[i])
+		}
+	}
+	for _, appliedBlockID := range ce.AppliedBlocks {
+		appliedBlock, err := getBlockMap(tx, appliedBlockID)
+		if err != nil && cs.prunedHeight != 0 {
+			return modules.ContractEventUpdate{}, modules.ErrPrunedConsensusChange
+		} else if err != nil {
+			cs.log.Critical("getBlockMap failed in computeContractEvents:", err)
+			return modules.ContractEventUpdate{}, err
+		}
+		ceu.AppliedEvents = append(ceu.AppliedEvents, blockContractEvents(appliedBlock)...)
+	}
+	return ceu, nil
+}
@@ -0,0 +1,112 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// contractEventTracker is a contract event subscriber that counts the events
+// of each type that are currently applied.
+type contractEventTracker struct {
+	counts map[modules.ContractEventType]int
+}
+
+// ProcessContractEvents removes the reverted events from the counts and adds
+// the applied events.
+func (cet *contractEventTracker) ProcessContractEvents(ceu modules.ContractEventUpdate) {
+	for _, event := range ceu.RevertedEvents {
+		cet.counts[event.Type]--
+	}
+	for _, event := range ceu.AppliedEvents {
+		cet.counts[event.Type]++
+	}
+}
+
+// newContractEventTracker returns a contractEventTracker that is ready to
+// subscribe.
+func newContractEventTracker() *contractEventTracker {
+	return &contractEventTracker{
+		counts: make(map[modules.ContractEventType]int),
+	}
+}
+
+// TestContractEvents checks that every stage of the file contract lifecycle
+// is reported to contract event subscribers, and that the events stay
+// consistent across a reorg.
+func TestContractEvents(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	rs := createReorgSets(t.Name())
+	defer rs.Close()
+
+	cet := newContractEventTracker()
+	err := rs.cstMain.cs.ContractEventSubscribe(cet, modules.ConsensusChangeBeginning)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create contracts that are revised, proven and missed. The revised
+	// contract is also proven.
+	rs.cstMain.testValidStorageProofBlocks()
+	rs.cstMain.testMissedStorageProofBlocks()
+	rs.cstMain.testFileContractRevision()
+	if cet.counts[modules.ContractEventFormed] != 3 {
+		t.Fatal("wrong number of formed events:", cet.counts[modules.ContractEventFormed])
+	}
+	if cet.counts[modules.ContractEventRevised] != 1 {
+		t.Fatal("wrong number of revised events:", cet.counts[modules.ContractEventRevised])
+	}
+	if cet.counts[modules.ContractEventProofSubmitted] != 2 {
+		t.Fatal("wrong number of proof events:", cet.counts[modules.ContractEventProofSubmitted])
+	}
+	if cet.counts[modules.ContractEventExpired] != 1 {
+		t.Fatal("wrong number of expired events:", cet.counts[modules.ContractEventExpired])
+	}
+	before := make(map[modules.ContractEventType]int)
+	for typ, count := range cet.counts {
+		before[typ] = count
+	}
+
+	// Reorg the contracts out of the consensus set. Every event should be
+	// reverted.
+	rs.save()
+	rs.extend()
+	for typ, count := range cet.counts {
+		if count != 0 {
+			t.Fatal("events were not reverted by the reorg:", typ, count)
+		}
+	}
+
+	// Reorg the contracts back in and check that the events are restored.
+	rs.restore()
+	for typ, count := range before {
+		if cet.counts[typ] != count {
+			t.Fatal("events were not restored by the reorg:", typ, cet.counts[typ], count)
+		}
+	}
+
+	// A new subscriber should see the same events.
+	cet2 := newContractEventTracker()
+	err = rs.cstMain.cs.ContractEventSubscribe(cet2, modules.ConsensusChangeBeginning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for typ, count := range before {
+		if cet2.counts[typ] != count {
+			t.Fatal("new subscriber received the wrong events:", typ, cet2.counts[typ], count)
+		}
+	}
+
+	// An unsubscribed subscriber should not receive further events.
+	rs.cstMain.cs.ContractEventUnsubscribe(cet)
+	rs.cstMain.testValidStorageProofBlocks()
+	if cet.counts[modules.ContractEventFormed] != before[modules.ContractEventFormed] {
+		t.Fatal("unsubscribed subscriber received events")
+	}
+	if cet2.counts[modules.ContractEventFormed] != before[modules.ContractEventFormed]+1 {
+		t.Fatal("subscriber did not receive events")
+	}
+}
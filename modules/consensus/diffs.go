@@ -2,6 +2,7 @@ package consensus
 
 import (
 	"errors"
+	"time"
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/encoding"
@@ -59,6 +60,7 @@ func commitSiacoinOutputDiff(tx *bolt.Tx, scod modules.SiacoinOutputDiff, dir mo
 	} else {
 		removeSiacoinOutput(tx, scod.ID)
 	}
+	updateSiacoinOutputStats(tx, scod.SiacoinOutput, scod.Direction == dir)
 }
 
 // commitFileContractDiff applies or reverts a FileContractDiff.
@@ -68,6 +70,7 @@ func commitFileContractDiff(tx *bolt.Tx, fcd modules.FileContractDiff, dir modul
 	} else {
 		removeFileContract(tx, fcd.ID)
 	}
+	updateFileContractStats(tx, fcd.FileContract, fcd.Direction == dir)
 }
 
 // commitSiafundOutputDiff applies or reverts a Siafund output diff.
@@ -77,6 +80,7 @@ func commitSiafundOutputDiff(tx *bolt.Tx, sfod modules.SiafundOutputDiff, dir mo
 	} else {
 		removeSiafundOutput(tx, sfod.ID)
 	}
+	updateSiafundOutputStats(tx, sfod.Direction == dir)
 }
 
 // commitDelayedSiacoinOutputDiff applies or reverts a delayedSiacoinOutputDiff.
@@ -125,6 +129,27 @@ func createUpcomingDelayedOutputMaps(tx *bolt.Tx, pb *processedBlock, dir module
 	}
 }
 
+// obsoleteDSCOHeight returns the maturity height of the delayed siacoin output
+// bucket that is deleted after the diffs of a block have been committed in the
+// given direction. When applying, the outputs maturing at the block's height
+// have been moved into the consensus set. When reverting, the outputs created
+// by the block are gone.
+func obsoleteDSCOHeight(pb *processedBlock, dir modules.DiffDirection) (types.BlockHeight, bool) {
+	if dir == modules.DiffApply {
+		return pb.Height, pb.Height >= types.MaturityDelay
+	}
+	return pb.Height + types.MaturityDelay, true
+}
+
+// isObsoleteDSCORemoval returns true if committing the delayed siacoin output
+// diff in the given direction removes an output from the bucket that is
+// deleted at the end of the diff set. Such removals are skipped, as the bucket
+// is dropped as a whole, which keeps applying and reverting symmetric.
+func isObsoleteDSCORemoval(pb *processedBlock, dscod modules.DelayedSiacoinOutputDiff, dir modules.DiffDirection) bool {
+	height, exists := obsoleteDSCOHeight(pb, dir)
+	return exists && dscod.Direction != dir && dscod.MaturityHeight == height
+}
+
 // commitNodeDiffs commits all of the diffs in a block node.
 func commitNodeDiffs(tx *bolt.Tx, pb *processedBlock, dir modules.DiffDirection) {
 	if dir == modules.DiffApply {
@@ -138,7 +163,9 @@ func commitNodeDiffs(tx *bolt.Tx, pb *processedBlock, dir modules.DiffDirection)
 			commitSiafundOutputDiff(tx, sfod, dir)
 		}
 		for _, dscod := range pb.DelayedSiacoinOutputDiffs {
-			commitDelayedSiacoinOutputDiff(tx, dscod, dir)
+			if !isObsoleteDSCORemoval(pb, dscod, dir) {
+				commitDelayedSiacoinOutputDiff(tx, dscod, dir)
+			}
 		}
 		for _, sfpd := range pb.SiafundPoolDiffs {
 			commitSiafundPoolDiff(tx, sfpd, dir)
@@ -154,7 +181,9 @@ func commitNodeDiffs(tx *bolt.Tx, pb *processedBlock, dir modules.DiffDirection)
 			commitSiafundOutputDiff(tx, pb.SiafundOutputDiffs[i], dir)
 		}
 		for i := len(pb.DelayedSiacoinOutputDiffs) - 1; i >= 0; i-- {
-			commitDelayedSiacoinOutputDiff(tx, pb.DelayedSiacoinOutputDiffs[i], dir)
+			if !isObsoleteDSCORemoval(pb, pb.DelayedSiacoinOutputDiffs[i], dir) {
+				commitDelayedSiacoinOutputDiff(tx, pb.DelayedSiacoinOutputDiffs[i], dir)
+			}
 		}
 		for i := len(pb.SiafundPoolDiffs) - 1; i >= 0; i-- {
 			commitSiafundPoolDiff(tx, pb.SiafundPoolDiffs[i], dir)
@@ -166,10 +195,8 @@ func commitNodeDiffs(tx *bolt.Tx, pb *processedBlock, dir modules.DiffDirection)
 // are no longer in use.
 func deleteObsoleteDelayedOutputMaps(tx *bolt.Tx, pb *processedBlock, dir modules.DiffDirection) {
 	// There are no outputs that mature in the first MaturityDelay blocks.
-	if dir == modules.DiffApply && pb.Height >= types.MaturityDelay {
-		deleteDSCOBucket(tx, pb.Height)
-	} else if dir == modules.DiffRevert {
-		deleteDSCOBucket(tx, pb.Height+types.MaturityDelay)
+	if height, exists := obsoleteDSCOHeight(pb, dir); exists {
+		deleteDSCOBucket(tx, height)
 	}
 }
 
@@ -194,6 +221,7 @@ func commitDiffSet(tx *bolt.Tx, pb *processedBlock, dir modules.DiffDirection) {
 	commitNodeDiffs(tx, pb, dir)
 	deleteObsoleteDelayedOutputMaps(tx, pb, dir)
 	updateCurrentPath(tx, pb, dir)
+	updateDeploymentStates(tx, pb, dir)
 }
 
 // generateAndApplyDiff will verify the block and then integrate it into the
@@ -201,7 +229,7 @@ func commitDiffSet(tx *bolt.Tx, pb *processedBlock, dir modules.DiffDirection) {
 // transactions are allowed to depend on each other. We can't be sure that a
 // transaction is valid unless we have applied all of the previous transactions
 // in the block, which means we need to apply while we verify.
-func generateAndApplyDiff(tx *bolt.Tx, pb *processedBlock) error {
+func (cs *ConsensusSet) generateAndApplyDiff(tx *bolt.Tx, pb *processedBlock) error {
 	// Sanity check - the block being applied should have the current block as
 	// a parent.
 	if build.DEBUG && pb.Block.ParentID != currentBlockID(tx) {
@@ -213,22 +241,38 @@ func generateAndApplyDiff(tx *bolt.Tx, pb *processedBlock) error {
 	// applied.
 	createDSCOBucket(tx, pb.Height+types.MaturityDelay)
 
+	// The standalone checks of each transaction, which include signature
+	// verification, do not depend on the consensus state and are performed
+	// for all transactions in parallel.
+	validationStart := time.Now()
+	standaloneErrs := validStandaloneTransactions(pb.Block.Transactions, blockHeight(tx))
+
 	// Validate and apply each transaction in the block. They cannot be
 	// validated all at once because some transactions may not be valid until
-	// previous transactions have been applied.
-	for _, txn := range pb.Block.Transactions {
-		err := validTransaction(tx, txn)
+	// previous transactions have been applied. Errors are reported for the
+	// first invalid transaction in the block, matching the order in which the
+	// transactions would have been checked serially.
+	for i, txn := range pb.Block.Transactions {
+		if standaloneErrs[i] != nil {
+			return standaloneErrs[i]
+		}
+		err := validTransactionState(tx, txn)
 		if err != nil {
 			return err
 		}
 		applyTransaction(tx, pb, txn)
 	}
+	cs.metrics.TransactionsValidated += uint64(len(pb.Block.Transactions))
+	cs.metrics.TransactionValidation.record(time.Since(validationStart))
 
 	// After all of the transactions have been applied, 'maintenance' is
 	// applied on the block. This includes adding any outputs that have reached
 	// maturity, applying any contracts with missed storage proofs, and adding
 	// the miner payouts to the list of delayed outputs.
+	maintenanceStart := time.Now()
 	applyMaintenance(tx, pb)
+	cs.metrics.Maintenance.record(time.Since(maintenanceStart))
+	cs.metrics.recordGeneratedDiffs(pb)
 
 	// DiffsGenerated are only set to true after the block has been fully
 	// validated and integrated. This is required to prevent later blocks from
@@ -243,6 +287,7 @@ func generateAndApplyDiff(tx *bolt.Tx, pb *processedBlock) error {
 	bid := pb.Block.ID()
 	blockMap := tx.Bucket(BlockMap)
 	updateCurrentPath(tx, pb, modules.DiffApply)
+	updateDeploymentStates(tx, pb, modules.DiffApply)
 
 	// Sanity check preparation - set the consensus hash at this height so that
 	// during reverting a check can be performed to assure consistency when
@@ -3,6 +3,7 @@ package consensus
 import (
 	"testing"
 
+	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
 
@@ -46,6 +47,69 @@ func TestCommitDelayedSiacoinOutputDiffBadMaturity(t *testing.T) {
 	})
 }
 
+// TestCommitDiffSetMaturedOutputs reverts and reapplies a block with maturing
+// outputs, checking that the delayed output bucket of the block is restored on
+// revert and dropped on apply.
+func TestCommitDiffSetMaturedOutputs(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	pb := cst.cs.dbCurrentProcessedBlock()
+	if pb.Height < types.MaturityDelay {
+		t.Fatal("tester has no maturing outputs")
+	}
+	var matured int
+	for _, dscod := range pb.DelayedSiacoinOutputDiffs {
+		if dscod.MaturityHeight == pb.Height {
+			matured++
+		}
+	}
+	if matured == 0 {
+		t.Fatal("current block has no matured outputs")
+	}
+	checksum := cst.cs.dbConsensusChecksum()
+	bucketID := append(prefixDSCO, encoding.Marshal(pb.Height)...)
+
+	// Revert the block. The matured outputs should be delayed again.
+	_ = cst.cs.db.Update(func(tx *bolt.Tx) error {
+		commitDiffSet(tx, pb, modules.DiffRevert)
+		var n int
+		err := tx.Bucket(bucketID).ForEach(func(_, _ []byte) error {
+			n++
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != matured {
+			t.Error("wrong number of delayed outputs after revert:", n, matured)
+		}
+		if tx.Bucket(append(prefixDSCO, encoding.Marshal(pb.Height+types.MaturityDelay)...)) != nil {
+			t.Error("delayed outputs created by the block were not dropped")
+		}
+		return nil
+	})
+
+	// Reapply the block. The bucket of matured outputs should be dropped.
+	_ = cst.cs.db.Update(func(tx *bolt.Tx) error {
+		commitDiffSet(tx, pb, modules.DiffApply)
+		if tx.Bucket(bucketID) != nil {
+			t.Error("matured outputs were not dropped")
+		}
+		return nil
+	})
+	if cst.cs.dbConsensusChecksum() != checksum {
+		t.Error("consensus checksum changed after revert and apply")
+	}
+}
+
 // TestCommitNodeDiffs probes the commitNodeDiffs method of the consensus set.
 /*
 func TestCommitNodeDiffs(t *testing.T) {
@@ -0,0 +1,104 @@
+package consensus
+
+import (
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/NebulousLabs/bolt"
+)
+
+// dosblocks.go persists the ids of blocks that failed expensive validation.
+// Such blocks are rejected cheaply when they are relayed again, including
+// after a restart. Blocks are marked invalid inside of the transaction that
+// tried to apply them, which is then rolled back, so new ids are collected in
+// memory and written in a separate transaction.
+
+// initDoSBlocks creates the DoS block bucket if it does not exist and loads
+// the persisted DoS blocks into memory.
+func (cs *ConsensusSet) initDoSBlocks(tx *bolt.Tx) error {
+	bucket, err := tx.CreateBucketIfNotExists(DoSBlocks)
+	if err != nil {
+		return err
+	}
+	return bucket.ForEach(func(k, _ []byte) error {
+		var id types.BlockID
+		copy(id[:], k)
+		cs.dosBlocks[id] = struct{}{}
+		return nil
+	})
+}
+
+// markDoSBlock records that the block with the given id failed expensive
+// validation. The block is persisted by the next call to persistDoSBlocks.
+func (cs *ConsensusSet) markDoSBlock(id types.BlockID) {
+	cs.dosBlocks[id] = struct{}{}
+	cs.newDoSBlocks = append(cs.newDoSBlocks, id)
+}
+
+// persistDoSBlocks writes the DoS blocks that have been marked since the last
+// call to the database.
+func (cs *ConsensusSet) persistDoSBlocks() {
+	if len(cs.newDoSBlocks) == 0 {
+		return
+	}
+	err := cs.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(DoSBlocks)
+		for _, id := range cs.newDoSBlocks {
+			err := bucket.Put(id[:], []byte{})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		cs.log.Println("WARN: unable to persist invalid blocks:", err)
+		return
+	}
+	cs.newDoSBlocks = cs.newDoSBlocks[:0]
+}
+
+// BadBlocks returns the ids of the blocks that are known to be invalid and are
+// rejected without validation.
+func (cs *ConsensusSet) BadBlocks() []types.BlockID {
+	// A call to a closed database can cause undefined behavior.
+	if cs.tg.Add() != nil {
+		return nil
+	}
+	defer cs.tg.Done()
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	ids := make([]types.BlockID, 0, len(cs.dosBlocks))
+	for id := range cs.dosBlocks {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// ClearBadBlocks forgets every block that is known to be invalid, so that the
+// blocks are fully validated if they are seen again.
+func (cs *ConsensusSet) ClearBadBlocks() error {
+	// A call to a closed database can cause undefined behavior.
+	err := cs.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	err = cs.db.Update(func(tx *bolt.Tx) error {
+		err := tx.DeleteBucket(DoSBlocks)
+		if err != nil {
+			return err
+		}
+		_, err = tx.CreateBucket(DoSBlocks)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	cs.dosBlocks = make(map[types.BlockID]struct{})
+	cs.newDoSBlocks = nil
+	return nil
+}
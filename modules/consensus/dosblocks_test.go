@@ -0,0 +1,83 @@
+package consensus
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/gateway"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestDoSBlockPersistence checks that blocks that fail expensive validation
+// are still rejected cheaply after a restart, and that they can be cleared.
+func TestDoSBlockPersistence(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// Mine a block with a transaction that has more siacoin inputs than
+	// outputs, which is only caught when the block is applied.
+	txnBuilder := cst.wallet.StartTransaction()
+	err = txnBuilder.FundSiacoins(types.NewCurrency64(50))
+	if err != nil {
+		t.Fatal(err)
+	}
+	txnSet, err := txnBuilder.Sign(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, target, err := cst.miner.BlockForWork()
+	if err != nil {
+		t.Fatal(err)
+	}
+	block.Transactions = append(block.Transactions, txnSet...)
+	dosBlock, _ := cst.miner.SolveBlock(block, target)
+	err = cst.cs.AcceptBlock(dosBlock)
+	if err != errSiacoinInputOutputMismatch {
+		t.Fatalf("expected %v, got %v", errSiacoinInputOutputMismatch, err)
+	}
+	if bad := cst.cs.BadBlocks(); len(bad) != 1 || bad[0] != dosBlock.ID() {
+		t.Fatal("invalid block was not recorded:", bad)
+	}
+
+	// Restart the consensus set. The block should still be rejected as a
+	// DoS block.
+	err = cst.cs.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := gateway.New("localhost:0", false, build.TempDir(modules.ConsensusDir, t.Name(), "restart", modules.GatewayDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	cst.cs, err = New(g, false, filepath.Join(cst.persistDir, modules.ConsensusDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cst.cs.AcceptBlock(dosBlock)
+	if err != errDoSBlock {
+		t.Fatalf("expected %v, got %v", errDoSBlock, err)
+	}
+
+	// After clearing the bad blocks, the block should be validated again.
+	err = cst.cs.ClearBadBlocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cst.cs.BadBlocks()) != 0 {
+		t.Fatal("bad blocks were not cleared")
+	}
+	err = cst.cs.AcceptBlock(dosBlock)
+	if err != errSiacoinInputOutputMismatch {
+		t.Fatalf("expected %v, got %v", errSiacoinInputOutputMismatch, err)
+	}
+}
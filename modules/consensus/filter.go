@@ -0,0 +1,201 @@
+package consensus
+
+// filter.go serves filtered blocks to lightweight clients. A filtered block
+// is a block header plus Merkle proofs for the transactions that are relevant
+// to a set of addresses, which is enough for a client that only tracks
+// headers to verify that its transactions were confirmed.
+//
+// Clients in the headers-only sync mode (see headers.go) request filtered
+// blocks for the headers of their header chain. A filtered block proves that
+// its transactions are in the block, but not that the peer included every
+// relevant transaction.
+
+import (
+	"errors"
+	"time"
+
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/NebulousLabs/bolt"
+)
+
+const (
+	// maxFilterAddresses is the maximum number of addresses that a peer can
+	// include in a single SendFilteredBlk request.
+	maxFilterAddresses = 1e3
+)
+
+var (
+	errBadFilteredBlock   = errors.New("peer sent a filtered block that does not match the request")
+	errTooManyFilterAddrs = errors.New("filtered block request contains too many addresses")
+)
+
+// filteredBlockRequest is the object sent by the caller of the
+// SendFilteredBlk RPC.
+type filteredBlockRequest struct {
+	ID        types.BlockID
+	Addresses []types.UnlockHash
+}
+
+// relevantTransaction returns true if the transaction spends from or sends to
+// any of the addresses in the provided set.
+func relevantTransaction(txn types.Transaction, addrs map[types.UnlockHash]struct{}) bool {
+	has := func(uh types.UnlockHash) bool {
+		_, exists := addrs[uh]
+		return exists
+	}
+	for _, sci := range txn.SiacoinInputs {
+		if has(sci.UnlockConditions.UnlockHash()) {
+			return true
+		}
+	}
+	for _, sco := range txn.SiacoinOutputs {
+		if has(sco.UnlockHash) {
+			return true
+		}
+	}
+	for _, fc := range txn.FileContracts {
+		for _, sco := range append(fc.ValidProofOutputs, fc.MissedProofOutputs...) {
+			if has(sco.UnlockHash) {
+				return true
+			}
+		}
+	}
+	for _, sfi := range txn.SiafundInputs {
+		if has(sfi.UnlockConditions.UnlockHash()) || has(sfi.ClaimUnlockHash) {
+			return true
+		}
+	}
+	for _, sfo := range txn.SiafundOutputs {
+		if has(sfo.UnlockHash) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterBlock builds a filtered block containing the transactions of b that
+// are relevant to the provided addresses.
+func filterBlock(b types.Block, addrs []types.UnlockHash) modules.FilteredBlock {
+	addrMap := make(map[types.UnlockHash]struct{}, len(addrs))
+	for _, addr := range addrs {
+		addrMap[addr] = struct{}{}
+	}
+	fb := modules.FilteredBlock{
+		Header: b.Header(),
+	}
+	for i, txn := range b.Transactions {
+		if relevantTransaction(txn, addrMap) {
+			fb.Proofs = append(fb.Proofs, b.TransactionProof(i))
+		}
+	}
+	return fb
+}
+
+// FilteredBlock returns the header of the block with the given id along with
+// proofs for every transaction in the block that is relevant to one of the
+// provided addresses.
+func (cs *ConsensusSet) FilteredBlock(id types.BlockID, addrs []types.UnlockHash) (fb modules.FilteredBlock, exists bool) {
+	// A call to a closed database can cause undefined behavior.
+	err := cs.tg.Add()
+	if err != nil {
+		return modules.FilteredBlock{}, false
+	}
+	defer cs.tg.Done()
+
+	_ = cs.db.View(func(tx *bolt.Tx) error {
+		pb, err := getBlockMap(tx, id)
+		if err != nil {
+			return err
+		}
+		fb = filterBlock(pb.Block, addrs)
+		exists = true
+		return nil
+	})
+	return fb, exists
+}
+
+// rpcSendFilteredBlk is an RPC that sends the requested filtered block to
+// the requesting peer.
+func (cs *ConsensusSet) rpcSendFilteredBlk(conn modules.PeerConn) error {
+	err := conn.SetDeadline(time.Now().Add(sendBlkTimeout))
+	if err != nil {
+		return err
+	}
+	finishedChan := make(chan struct{})
+	defer close(finishedChan)
+	go func() {
+		select {
+		case <-cs.tg.StopChan():
+		case <-finishedChan:
+		}
+		conn.Close()
+	}()
+	err = cs.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+
+	// Decode the request from the connection.
+	var req filteredBlockRequest
+	err = encoding.ReadObject(conn, &req, 8+32+8+maxFilterAddresses*32)
+	if err != nil {
+		return err
+	}
+	if len(req.Addresses) > maxFilterAddresses {
+		return errTooManyFilterAddrs
+	}
+
+	// Lookup the corresponding block.
+	var b types.Block
+	cs.mu.RLock()
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		pb, err := getBlockMap(tx, req.ID)
+		if err != nil {
+			return err
+		}
+		b = pb.Block
+		return nil
+	})
+	cs.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	// Encode and send the filtered block to the caller.
+	return encoding.WriteObject(conn, filterBlock(b, req.Addresses))
+}
+
+// ReceiveFilteredBlock returns an RPCFunc that requests a filtered block from
+// a peer using the SendFilteredBlk RPC. The header of the received block is
+// checked against the requested id and every transaction proof is verified
+// before the filtered block is written to fb.
+func ReceiveFilteredBlock(id types.BlockID, addrs []types.UnlockHash, fb *modules.FilteredBlock) modules.RPCFunc {
+	return func(conn modules.PeerConn) error {
+		if len(addrs) > maxFilterAddresses {
+			return errTooManyFilterAddrs
+		}
+		err := encoding.WriteObject(conn, filteredBlockRequest{ID: id, Addresses: addrs})
+		if err != nil {
+			return err
+		}
+		var resp modules.FilteredBlock
+		err = encoding.ReadObject(conn, &resp, types.BlockSizeLimit)
+		if err != nil {
+			return err
+		}
+		if resp.Header.ID() != id {
+			return errBadFilteredBlock
+		}
+		for _, proof := range resp.Proofs {
+			if !proof.Verify(resp.Header) {
+				return errBadFilteredBlock
+			}
+		}
+		*fb = resp
+		return nil
+	}
+}
@@ -0,0 +1,104 @@
+package consensus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestFilteredBlock checks that FilteredBlock returns verifiable proofs for
+// exactly the transactions relevant to the requested addresses.
+func TestFilteredBlock(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// Send coins to a random address and mine the transaction into a block.
+	addr := randAddress()
+	_, err = cst.wallet.SendSiacoins(types.SiacoinPrecision, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := cst.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fb, exists := cst.cs.FilteredBlock(b.ID(), []types.UnlockHash{addr})
+	if !exists {
+		t.Fatal("filtered block does not exist")
+	}
+	if fb.Header.ID() != b.ID() {
+		t.Fatal("filtered block has the wrong header")
+	}
+	if len(fb.Proofs) != 1 {
+		t.Fatal("expected one relevant transaction, got", len(fb.Proofs))
+	}
+	if !fb.Proofs[0].Verify(fb.Header) {
+		t.Fatal("transaction proof does not verify")
+	}
+
+	// An unrelated address should not match any transactions.
+	fb, exists = cst.cs.FilteredBlock(b.ID(), []types.UnlockHash{randAddress()})
+	if !exists || len(fb.Proofs) != 0 {
+		t.Fatal("unrelated address matched transactions")
+	}
+
+	// Unknown blocks should not exist.
+	_, exists = cst.cs.FilteredBlock(types.BlockID{}, []types.UnlockHash{addr})
+	if exists {
+		t.Fatal("filtered block returned for unknown block")
+	}
+}
+
+// TestIntegrationSendFilteredBlkRPC checks that filtered blocks can be
+// requested from peers over the SendFilteredBlk RPC.
+func TestIntegrationSendFilteredBlkRPC(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	cst1, err := createConsensusSetTester(t.Name() + "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst1.Close()
+	cst2, err := blankConsensusSetTester(t.Name() + "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst2.Close()
+
+	err = cst2.gateway.Connect(cst1.gateway.Address())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Give the consensus sets time to register their RPCs.
+	time.Sleep(500 * time.Millisecond)
+
+	addr := randAddress()
+	_, err = cst1.wallet.SendSiacoins(types.SiacoinPrecision, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := cst1.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fb modules.FilteredBlock
+	err = cst2.gateway.RPC(cst1.gateway.Address(), "SendFilteredBlk", ReceiveFilteredBlock(b.ID(), []types.UnlockHash{addr}, &fb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fb.Header.ID() != b.ID() || len(fb.Proofs) != 1 {
+		t.Fatal("received the wrong filtered block")
+	}
+}
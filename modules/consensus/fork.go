@@ -2,6 +2,7 @@ package consensus
 
 import (
 	"errors"
+	"time"
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/modules"
@@ -81,10 +82,10 @@ func (cs *ConsensusSet) applyUntilBlock(tx *bolt.Tx, pb *processedBlock) (applie
 		if block.DiffsGenerated {
 			commitDiffSet(tx, block, modules.DiffApply)
 		} else {
-			err := generateAndApplyDiff(tx, block)
+			err := cs.generateAndApplyDiff(tx, block)
 			if err != nil {
 				// Mark the block as invalid.
-				cs.dosBlocks[block.Block.ID()] = struct{}{}
+				cs.markDoSBlock(block.Block.ID())
 				return nil, err
 			}
 		}
@@ -105,8 +106,28 @@ func (cs *ConsensusSet) applyUntilBlock(tx *bolt.Tx, pb *processedBlock) (applie
 // error will be returned if any of the blocks applied in the transition are
 // found to be invalid. forkBlockchain is atomic; the ConsensusSet is only
 // updated if the function returns nil.
+//
+// Reverting the old fork and applying the new fork happen inside of the
+// caller's bolt transaction, which is rolled back when an error is returned.
+// Bolt commits a transaction by atomically switching to a new meta page, so a
+// crash in the middle of a reorg leaves the database exactly as it was before
+// the reorg, and no journal or recovery step is needed on startup. The only
+// in-memory state changed by a fork is the metrics, which the caller restores
+// when it rolls back the transaction. The block cache and the pruned height
+// are only updated after a prune is committed.
 func (cs *ConsensusSet) forkBlockchain(tx *bolt.Tx, newBlock *processedBlock) (revertedBlocks, appliedBlocks []*processedBlock, err error) {
+	// Consistency checks fork the blockchain as well, but are not included in
+	// the metrics.
+	if !cs.checkingConsistency {
+		start := time.Now()
+		defer func() {
+			cs.metrics.recordFork(len(revertedBlocks), len(appliedBlocks), time.Since(start))
+		}()
+	}
 	commonParent := backtrackToCurrentPath(tx, newBlock)[0]
+	if cs.haltOnDeepReorg && cs.isDeepReorg(blockHeight(tx)-commonParent.Height) {
+		return nil, nil, errDeepReorg
+	}
 	revertedBlocks = cs.revertToBlock(tx, commonParent)
 	appliedBlocks, err = cs.applyUntilBlock(tx, newBlock)
 	if err != nil {
@@ -0,0 +1,134 @@
+package consensus
+
+import (
+	"sort"
+	"time"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// future.go implements the holding queue for future blocks. Blocks with a
+// timestamp that is slightly in the future are valid in every other respect,
+// and will become acceptable once the local clock catches up. Instead of
+// discarding them and waiting for a peer to resend them, they are held in a
+// bounded queue and retried automatically once their timestamps are no longer
+// too far in the future.
+
+const (
+	// maxFutureBlocks is the maximum number of blocks that are held in the
+	// future block queue. When the queue is full, the blocks that are
+	// furthest in the future are dropped first.
+	maxFutureBlocks = 50
+)
+
+// queueFutureBlock adds a block to the future block queue and wakes the
+// future block thread so that it can reschedule. The caller must hold a lock
+// on the consensus set.
+func (cs *ConsensusSet) queueFutureBlock(b types.Block, id types.BlockID) {
+	if _, exists := cs.futureBlocks[id]; exists {
+		return
+	}
+
+	// If the queue is full, evict the block that is furthest in the future,
+	// unless the new block is further in the future still.
+	if len(cs.futureBlocks) >= maxFutureBlocks {
+		var latestID types.BlockID
+		latest := types.Timestamp(0)
+		for fid, fb := range cs.futureBlocks {
+			if fb.Timestamp >= latest {
+				latestID, latest = fid, fb.Timestamp
+			}
+		}
+		if b.Timestamp >= latest {
+			return
+		}
+		delete(cs.futureBlocks, latestID)
+	}
+	cs.futureBlocks[id] = b
+
+	select {
+	case cs.futureBlocksWake <- struct{}{}:
+	default:
+	}
+}
+
+// managedNextFutureBlockTime returns the amount of time until the earliest
+// block in the future block queue becomes acceptable. If the queue is empty,
+// false is returned.
+func (cs *ConsensusSet) managedNextFutureBlockTime() (time.Duration, bool) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	if len(cs.futureBlocks) == 0 {
+		return 0, false
+	}
+	var earliest types.Timestamp
+	for _, b := range cs.futureBlocks {
+		if earliest == 0 || b.Timestamp < earliest {
+			earliest = b.Timestamp
+		}
+	}
+	acceptable := earliest - types.FutureThreshold
+	now := types.CurrentTimestamp()
+	if acceptable <= now {
+		return 0, true
+	}
+	return time.Duration(acceptable-now) * time.Second, true
+}
+
+// managedPopDueFutureBlocks removes every block that has become acceptable
+// from the future block queue, returning them sorted by timestamp so that
+// parents are processed before their children.
+func (cs *ConsensusSet) managedPopDueFutureBlocks() []types.Block {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	var due []types.Block
+	now := types.CurrentTimestamp()
+	for id, b := range cs.futureBlocks {
+		if b.Timestamp <= now+types.FutureThreshold {
+			due = append(due, b)
+			delete(cs.futureBlocks, id)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].Timestamp < due[j].Timestamp
+	})
+	return due
+}
+
+// threadedProcessFutureBlocks sleeps until the earliest block in the future
+// block queue becomes acceptable, and then tries to add every acceptable
+// block to the consensus set. Accepted blocks are sent to subscribers and
+// relayed to peers.
+func (cs *ConsensusSet) threadedProcessFutureBlocks() {
+	err := cs.tg.Add()
+	if err != nil {
+		return
+	}
+	defer cs.tg.Done()
+
+	for {
+		// Sleep until the next block is due, or until a new block is queued.
+		var timer <-chan time.Time
+		if wait, ok := cs.managedNextFutureBlockTime(); ok {
+			timer = time.After(wait)
+		}
+		select {
+		case <-cs.tg.StopChan():
+			return
+		case <-cs.futureBlocksWake:
+			continue
+		case <-timer:
+		}
+
+		for _, b := range cs.managedPopDueFutureBlocks() {
+			_, err := cs.managedAcceptBlocks([]types.Block{b})
+			if err != nil {
+				cs.log.Debugln("WARN: failed to accept a future block:", err)
+				continue
+			}
+			cs.managedBroadcastBlock(b)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package consensus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestFutureBlockQueue checks that the future block queue ignores duplicate
+// blocks and drops the blocks furthest in the future when it is full.
+func TestFutureBlockQueue(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := blankConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+	cs := cst.cs
+
+	// Fill the queue with blocks that are far in the future.
+	base := types.CurrentTimestamp() + 1e6
+	cs.mu.Lock()
+	for i := 0; i < maxFutureBlocks; i++ {
+		b := types.Block{Timestamp: base + types.Timestamp(i)}
+		cs.queueFutureBlock(b, b.ID())
+	}
+	first := types.Block{Timestamp: base}
+	cs.queueFutureBlock(first, first.ID())
+	if len(cs.futureBlocks) != maxFutureBlocks {
+		t.Error("queue has the wrong size after adding a duplicate:", len(cs.futureBlocks))
+	}
+
+	// A block that is further in the future than every queued block should
+	// be dropped.
+	late := types.Block{Timestamp: base + maxFutureBlocks}
+	cs.queueFutureBlock(late, late.ID())
+	if _, exists := cs.futureBlocks[late.ID()]; exists {
+		t.Error("full queue accepted a block that is furthest in the future")
+	}
+
+	// An earlier block should evict the latest queued block.
+	early := types.Block{Timestamp: base - 1}
+	evicted := types.Block{Timestamp: base + maxFutureBlocks - 1}
+	cs.queueFutureBlock(early, early.ID())
+	if _, exists := cs.futureBlocks[early.ID()]; !exists {
+		t.Error("full queue did not accept an earlier block")
+	}
+	if _, exists := cs.futureBlocks[evicted.ID()]; exists {
+		t.Error("full queue did not evict the latest block")
+	}
+	if len(cs.futureBlocks) != maxFutureBlocks {
+		t.Error("queue has the wrong size after an eviction:", len(cs.futureBlocks))
+	}
+	cs.mu.Unlock()
+}
+
+// TestFutureBlockSubscribers checks that a future block is sent to
+// subscribers once it has been accepted from the future block queue.
+func TestFutureBlockSubscribers(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+	ms := newMockSubscriber()
+	err = cst.cs.ConsensusSetSubscribe(&ms, modules.ConsensusChangeRecent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, target, err := cst.miner.BlockForWork()
+	if err != nil {
+		t.Fatal(err)
+	}
+	block.Timestamp = types.CurrentTimestamp() + 2 + types.FutureThreshold
+	solvedBlock, _ := cst.miner.SolveBlock(block, target)
+	err = cst.cs.AcceptBlock(solvedBlock)
+	if err != errFutureTimestamp {
+		t.Fatalf("expected %v, got %v", errFutureTimestamp, err)
+	}
+	// Submitting the block a second time should not queue it twice.
+	err = cst.cs.AcceptBlock(solvedBlock)
+	if err != errFutureTimestamp {
+		t.Fatalf("expected %v, got %v", errFutureTimestamp, err)
+	}
+
+	// Wait for the block to be accepted.
+	for i := 0; i < 50 && cst.cs.CurrentBlock().ID() != solvedBlock.ID(); i++ {
+		time.Sleep(200 * time.Millisecond)
+	}
+	if cst.cs.CurrentBlock().ID() != solvedBlock.ID() {
+		t.Fatal("future block was not accepted")
+	}
+	cst.cs.mu.RLock()
+	defer cst.cs.mu.RUnlock()
+	if len(cst.cs.futureBlocks) != 0 {
+		t.Error("future block queue is not empty after the block was accepted")
+	}
+	last := ms.updates[len(ms.updates)-1]
+	if len(last.AppliedBlocks) != 1 || last.AppliedBlocks[0].ID() != solvedBlock.ID() {
+		t.Error("subscriber did not receive the future block")
+	}
+}
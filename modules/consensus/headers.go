@@ -0,0 +1,603 @@
+package consensus
+
+// headers.go implements the header chain of the headers-only sync mode. A
+// consensus set in headers-only mode does not download or validate full
+// blocks. It downloads block headers from its peers with the SendHeaders RPC
+// and checks that every header has a known parent, meets the target of its
+// parent, and has an acceptable timestamp. The targets are computed from the
+// headers alone, using the same difficulty adjustment as full blocks, and the
+// heaviest chain of valid headers is kept in the header chain store.
+//
+// The transactions of a block cannot be validated without the block, so a
+// headers-only consensus set trusts that the heaviest chain of headers only
+// contains valid blocks. The rest of the consensus state stays at the genesis
+// block. Clients learn about their transactions by requesting filtered blocks
+// for the headers of the header chain, see RequestFilteredBlock.
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/NebulousLabs/bolt"
+)
+
+var (
+	// maxCatchUpHeaders is the maximum number of headers that are sent in a
+	// single batch of the SendHeaders RPC.
+	maxCatchUpHeaders = build.Select(build.Var{
+		Standard: types.BlockHeight(2000),
+		Dev:      types.BlockHeight(500),
+		Testing:  types.BlockHeight(20),
+	}).(types.BlockHeight)
+
+	errHeaderNotFound = errors.New("no header at the requested height")
+	errHeadersOnly    = errors.New("consensus set is in headers-only mode and does not accept blocks")
+)
+
+// processedHeader is the header equivalent of a processedBlock. It holds the
+// values that are needed to validate the children of the header.
+type processedHeader struct {
+	Header      types.BlockHeader
+	Height      types.BlockHeight
+	Depth       types.Target
+	ChildTarget types.Target
+
+	// TotalTime and TotalTarget are the totals of the oak difficulty
+	// adjustment, see storeBlockTotals.
+	TotalTime   int64
+	TotalTarget types.Target
+}
+
+// heavierThan returns true if the header is sufficiently heavier than 'cmp',
+// using the same rule as processedBlock.heavierThan.
+func (ph *processedHeader) heavierThan(cmp *processedHeader) bool {
+	requirement := cmp.Depth.AddDifficulties(cmp.ChildTarget.MulDifficulty(SurpassThreshold))
+	return requirement.Cmp(ph.Depth) > 0 // Inversed, because the smaller target is actually heavier.
+}
+
+// genesisHeader returns the processed header of the genesis block. The totals
+// match the ones that initOak stores for the genesis block.
+func genesisHeader() processedHeader {
+	totalTime, totalTarget := computeBlockTotals(0, 0, types.GenesisTimestamp, types.GenesisTimestamp, types.RootDepth, types.RootTarget)
+	return processedHeader{
+		Header:      types.GenesisBlock.Header(),
+		Depth:       types.RootDepth,
+		ChildTarget: types.RootTarget,
+		TotalTime:   totalTime,
+		TotalTarget: totalTarget,
+	}
+}
+
+// initHeaderChain creates the header chain buckets and adds the genesis
+// header if the header chain is empty.
+func initHeaderChain(tx *bolt.Tx) error {
+	for _, bucket := range [][]byte{HeaderHeight, HeaderMap, HeaderPath} {
+		_, err := tx.CreateBucketIfNotExists(bucket)
+		if err != nil {
+			return err
+		}
+	}
+	if tx.Bucket(HeaderHeight).Get(HeaderHeight) != nil {
+		return nil
+	}
+	genesis := genesisHeader()
+	id := genesis.Header.ID()
+	err := tx.Bucket(HeaderMap).Put(id[:], encoding.Marshal(genesis))
+	if err != nil {
+		return err
+	}
+	err = tx.Bucket(HeaderPath).Put(encoding.Marshal(types.BlockHeight(0)), id[:])
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(HeaderHeight).Put(HeaderHeight, encoding.Marshal(types.BlockHeight(0)))
+}
+
+// headerHeight returns the height of the header chain.
+func headerHeight(tx *bolt.Tx) types.BlockHeight {
+	var height types.BlockHeight
+	err := encoding.Unmarshal(tx.Bucket(HeaderHeight).Get(HeaderHeight), &height)
+	if build.DEBUG && err != nil {
+		panic(err)
+	}
+	return height
+}
+
+// getHeaderPath returns the id of the header at the given height of the
+// header chain.
+func getHeaderPath(tx *bolt.Tx, height types.BlockHeight) (id types.BlockID, err error) {
+	idBytes := tx.Bucket(HeaderPath).Get(encoding.Marshal(height))
+	if idBytes == nil {
+		return types.BlockID{}, errNilItem
+	}
+	copy(id[:], idBytes)
+	return id, nil
+}
+
+// getHeaderMap returns the processed header with the given id.
+func getHeaderMap(tx *bolt.Tx, id types.BlockID) (*processedHeader, error) {
+	phBytes := tx.Bucket(HeaderMap).Get(id[:])
+	if phBytes == nil {
+		return nil, errNilItem
+	}
+	ph := new(processedHeader)
+	err := encoding.Unmarshal(phBytes, ph)
+	if err != nil {
+		return nil, err
+	}
+	return ph, nil
+}
+
+// minimumValidChildHeaderTimestamp returns the earliest timestamp that a child
+// of the header can have. It mirrors minimumValidChildTimestamp.
+func minimumValidChildHeaderTimestamp(tx *bolt.Tx, ph *processedHeader) types.Timestamp {
+	windowTimes := make(types.TimestampSlice, types.MedianTimestampWindow)
+	windowTimes[0] = ph.Header.Timestamp
+	parent := ph.Header.ParentID
+	for i := uint64(1); i < types.MedianTimestampWindow; i++ {
+		// If the genesis block is 'parent', use the genesis block timestamp
+		// for all remaining times.
+		if parent == (types.BlockID{}) {
+			windowTimes[i] = windowTimes[i-1]
+			continue
+		}
+		pph, err := getHeaderMap(tx, parent)
+		if build.DEBUG && err != nil {
+			panic(err)
+		}
+		parent = pph.Header.ParentID
+		windowTimes[i] = pph.Header.Timestamp
+	}
+	sort.Sort(windowTimes)
+	return windowTimes[len(windowTimes)/2]
+}
+
+// preOakHeaderChildTarget computes the child target of a header below the oak
+// hardfork. It mirrors setChildTarget and targetAdjustmentBase.
+func preOakHeaderChildTarget(tx *bolt.Tx, parent, ph *processedHeader) types.Target {
+	if ph.Height%(types.TargetWindow/2) != 0 {
+		return parent.ChildTarget
+	}
+
+	// Grab the header that was generated 'TargetWindow' blocks prior to the
+	// parent. If there are not 'TargetWindow' headers yet, stop at the
+	// genesis header.
+	var windowSize types.BlockHeight
+	ancestor := parent
+	for windowSize = 1; windowSize < types.TargetWindow && ancestor.Header.ParentID != (types.BlockID{}); windowSize++ {
+		var err error
+		ancestor, err = getHeaderMap(tx, ancestor.Header.ParentID)
+		if build.DEBUG && err != nil {
+			panic(err)
+		}
+	}
+	timePassed := ph.Header.Timestamp - ancestor.Header.Timestamp
+	expectedTimePassed := types.BlockFrequency * windowSize
+	adjustment := clampTargetAdjustment(big.NewRat(int64(timePassed), int64(expectedTimePassed)))
+	return types.RatToTarget(new(big.Rat).Mul(parent.ChildTarget.Rat(), adjustment))
+}
+
+// validateChainHeader checks that a header can be added to the header chain,
+// and returns its parent.
+func (cs *ConsensusSet) validateChainHeader(tx *bolt.Tx, h types.BlockHeader) (*processedHeader, error) {
+	id := h.ID()
+	if _, exists := cs.dosBlocks[id]; exists {
+		return nil, errDoSBlock
+	}
+	if tx.Bucket(HeaderMap).Get(id[:]) != nil {
+		return nil, modules.ErrBlockKnown
+	}
+	parent, err := getHeaderMap(tx, h.ParentID)
+	if err != nil {
+		return nil, errOrphan
+	}
+	if !checkHeaderTarget(h, parent.ChildTarget) {
+		return nil, modules.ErrBlockUnsolved
+	}
+	if h.Timestamp < minimumValidChildHeaderTimestamp(tx, parent) {
+		return nil, errEarlyTimestamp
+	}
+	// Full nodes hold blocks that are slightly in the future until they
+	// become acceptable. The header chain has no such queue, so headers
+	// below the extreme future threshold are accepted right away instead of
+	// failing the sync.
+	if h.Timestamp > types.CurrentTimestamp()+types.ExtremeFutureThreshold {
+		return nil, errExtremeFutureTimestamp
+	}
+	return parent, nil
+}
+
+// addHeader adds a validated header to the header map, and makes it the tip
+// of the header chain if it is heavier than the current tip. True is returned
+// if the header chain changed.
+func (cs *ConsensusSet) addHeader(tx *bolt.Tx, parent *processedHeader, h types.BlockHeader) (bool, error) {
+	// Compute the values of the child the same way newChild does.
+	ph := &processedHeader{
+		Header: h,
+		Height: parent.Height + 1,
+		Depth:  parent.Depth.AddDifficulties(parent.ChildTarget),
+	}
+	ph.TotalTime, ph.TotalTarget = computeBlockTotals(ph.Height, parent.TotalTime, parent.Header.Timestamp, h.Timestamp, parent.TotalTarget, parent.ChildTarget)
+	if parent.Height < types.OakHardforkBlock {
+		ph.ChildTarget = preOakHeaderChildTarget(tx, parent, ph)
+	} else {
+		ph.ChildTarget = cs.childTargetOak(parent.TotalTime, parent.TotalTarget, parent.ChildTarget, parent.Height)
+	}
+	id := h.ID()
+	err := tx.Bucket(HeaderMap).Put(id[:], encoding.Marshal(*ph))
+	if err != nil {
+		return false, err
+	}
+
+	tipID, err := getHeaderPath(tx, headerHeight(tx))
+	if err != nil {
+		return false, err
+	}
+	tip, err := getHeaderMap(tx, tipID)
+	if err != nil {
+		return false, err
+	}
+	if !ph.heavierThan(tip) {
+		return false, nil
+	}
+
+	// Make the header the new tip. Headers above it are removed from the
+	// path, and the path is rewritten from the header back to the common
+	// parent with the old path.
+	headerPath := tx.Bucket(HeaderPath)
+	for height := ph.Height + 1; height <= tip.Height; height++ {
+		err = headerPath.Delete(encoding.Marshal(height))
+		if err != nil {
+			return false, err
+		}
+	}
+	err = tx.Bucket(HeaderHeight).Put(HeaderHeight, encoding.Marshal(ph.Height))
+	if err != nil {
+		return false, err
+	}
+	for {
+		id := ph.Header.ID()
+		pathID, err := getHeaderPath(tx, ph.Height)
+		if err == nil && pathID == id {
+			break
+		}
+		err = headerPath.Put(encoding.Marshal(ph.Height), id[:])
+		if err != nil {
+			return false, err
+		}
+		ph, err = getHeaderMap(tx, ph.Header.ParentID)
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// managedAcceptHeaders adds a sequence of headers to the header chain. The
+// headers preceding the first invalid header are kept, and the error of the
+// invalid header is returned. Known headers are skipped.
+func (cs *ConsensusSet) managedAcceptHeaders(headers []types.BlockHeader) (chainExtended bool, err error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	var validationErr error
+	err = cs.db.Update(func(tx *bolt.Tx) error {
+		for _, h := range headers {
+			parent, err := cs.validateChainHeader(tx, h)
+			if err == modules.ErrBlockKnown {
+				continue
+			} else if err != nil {
+				validationErr = err
+				return nil
+			}
+			extended, err := cs.addHeader(tx, parent, h)
+			if err != nil {
+				return err
+			}
+			chainExtended = chainExtended || extended
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return chainExtended, validationErr
+}
+
+// headerAtHeight returns the header at the given height of the header chain.
+// Consensus sets that are not in headers-only mode use the current path.
+func (cs *ConsensusSet) headerAtHeight(tx *bolt.Tx, height types.BlockHeight) (types.BlockHeader, bool) {
+	if !cs.headersOnly {
+		id, err := getPath(tx, height)
+		if err != nil {
+			return types.BlockHeader{}, false
+		}
+		b, err := cs.getBlock(tx, id)
+		if err != nil {
+			return types.BlockHeader{}, false
+		}
+		return b.Header(), true
+	}
+	id, err := getHeaderPath(tx, height)
+	if err != nil {
+		return types.BlockHeader{}, false
+	}
+	ph, err := getHeaderMap(tx, id)
+	if err != nil {
+		return types.BlockHeader{}, false
+	}
+	return ph.Header, true
+}
+
+// headerChainHeight returns the height of the header chain. Consensus sets
+// that are not in headers-only mode use the current path.
+func (cs *ConsensusSet) headerChainHeight(tx *bolt.Tx) types.BlockHeight {
+	if !cs.headersOnly {
+		return blockHeight(tx)
+	}
+	return headerHeight(tx)
+}
+
+// headerPathHeight returns the height of the header with the given id if it
+// is in the header chain.
+func (cs *ConsensusSet) headerPathHeight(tx *bolt.Tx, id types.BlockID) (types.BlockHeight, bool) {
+	var height types.BlockHeight
+	if !cs.headersOnly {
+		pb, err := getBlockMap(tx, id)
+		if err != nil {
+			return 0, false
+		}
+		height = pb.Height
+	} else {
+		ph, err := getHeaderMap(tx, id)
+		if err != nil {
+			return 0, false
+		}
+		height = ph.Height
+	}
+	h, exists := cs.headerAtHeight(tx, height)
+	return height, exists && h.ID() == id
+}
+
+// headerHistory returns up to 32 header ids of the header chain, in the same
+// format as blockHistory.
+func (cs *ConsensusSet) headerHistory(tx *bolt.Tx) (ids [32]types.BlockID) {
+	height := cs.headerChainHeight(tx)
+	step := types.BlockHeight(1)
+	for i := 0; i < 31; i++ {
+		h, _ := cs.headerAtHeight(tx, height)
+		ids[i] = h.ID()
+		if i >= 9 {
+			step *= 2
+		}
+		if height <= step {
+			break
+		}
+		height -= step
+	}
+	ids[31] = types.GenesisID
+	return ids
+}
+
+// HeaderHeight returns the height of the header chain. If the consensus set
+// is not in headers-only mode, the height of the current path is returned.
+func (cs *ConsensusSet) HeaderHeight() (height types.BlockHeight) {
+	// A call to a closed database can cause undefined behavior.
+	err := cs.tg.Add()
+	if err != nil {
+		return 0
+	}
+	defer cs.tg.Done()
+
+	_ = cs.db.View(func(tx *bolt.Tx) error {
+		height = cs.headerChainHeight(tx)
+		return nil
+	})
+	return height
+}
+
+// HeaderAtHeight returns the header at the given height of the header chain.
+// If the consensus set is not in headers-only mode, the header of the block
+// at the given height of the current path is returned.
+func (cs *ConsensusSet) HeaderAtHeight(height types.BlockHeight) (h types.BlockHeader, exists bool) {
+	// A call to a closed database can cause undefined behavior.
+	err := cs.tg.Add()
+	if err != nil {
+		return types.BlockHeader{}, false
+	}
+	defer cs.tg.Done()
+
+	_ = cs.db.View(func(tx *bolt.Tx) error {
+		h, exists = cs.headerAtHeight(tx, height)
+		return nil
+	})
+	return h, exists
+}
+
+// RequestFilteredBlock requests the filtered block for the header at the
+// given height of the header chain from a peer, with proofs for the
+// transactions that are relevant to the provided addresses. The filtered
+// block must match the header, so the proofs are only accepted for blocks in
+// the header chain.
+func (cs *ConsensusSet) RequestFilteredBlock(peer modules.NetAddress, height types.BlockHeight, addrs []types.UnlockHash) (fb modules.FilteredBlock, err error) {
+	err = cs.tg.Add()
+	if err != nil {
+		return modules.FilteredBlock{}, err
+	}
+	defer cs.tg.Done()
+
+	h, exists := cs.HeaderAtHeight(height)
+	if !exists {
+		return modules.FilteredBlock{}, errHeaderNotFound
+	}
+	err = cs.gateway.RPC(peer, "SendFilteredBlk", ReceiveFilteredBlock(h.ID(), addrs, &fb))
+	if err != nil {
+		return modules.FilteredBlock{}, err
+	}
+	return fb, nil
+}
+
+// rpcSendHeaders is the receiving end of the SendHeaders RPC. It works like
+// rpcSendBlocks, sending the headers that follow the most recent known id in
+// batches of up to maxCatchUpHeaders, each followed by a boolean indicating
+// whether more headers are available.
+func (cs *ConsensusSet) rpcSendHeaders(conn modules.PeerConn) error {
+	err := conn.SetDeadline(time.Now().Add(sendBlocksTimeout))
+	if err != nil {
+		return err
+	}
+	finishedChan := make(chan struct{})
+	defer close(finishedChan)
+	go func() {
+		select {
+		case <-cs.tg.StopChan():
+		case <-finishedChan:
+		}
+		conn.Close()
+	}()
+	err = cs.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+
+	// Read a list of blocks known to the requester and find the most recent
+	// one in the header chain.
+	var knownBlocks [32]types.BlockID
+	err = encoding.ReadObject(conn, &knownBlocks, 32*crypto.HashSize)
+	if err != nil {
+		return err
+	}
+	found := false
+	var start types.BlockHeight
+	cs.mu.RLock()
+	_ = cs.db.View(func(tx *bolt.Tx) error {
+		for _, id := range knownBlocks {
+			height, exists := cs.headerPathHeight(tx, id)
+			if exists {
+				found = true
+				start = height + 1
+				break
+			}
+		}
+		return nil
+	})
+	cs.mu.RUnlock()
+
+	moreAvailable := found
+	if !found {
+		// Send 0 headers, and indicate that no more are available.
+		err = encoding.WriteObject(conn, []types.BlockHeader{})
+		if err != nil {
+			return err
+		}
+		return encoding.WriteObject(conn, false)
+	}
+	for moreAvailable {
+		var headers []types.BlockHeader
+		cs.mu.RLock()
+		err = cs.db.View(func(tx *bolt.Tx) error {
+			height := cs.headerChainHeight(tx)
+			for i := start; i <= height && i < start+maxCatchUpHeaders; i++ {
+				if !cs.headersOnly && cs.isPruned(i) {
+					return errPrunedBlocks
+				}
+				h, exists := cs.headerAtHeight(tx, i)
+				if !exists {
+					return errHeaderNotFound
+				}
+				headers = append(headers, h)
+			}
+			moreAvailable = start+maxCatchUpHeaders <= height
+			start += maxCatchUpHeaders
+			return nil
+		})
+		cs.mu.RUnlock()
+		if err != nil {
+			return err
+		}
+		if err = encoding.WriteObject(conn, headers); err != nil {
+			return err
+		}
+		if err = encoding.WriteObject(conn, moreAvailable); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// managedReceiveHeaders is the calling end of the SendHeaders RPC, without the
+// threadgroup wrapping.
+func (cs *ConsensusSet) managedReceiveHeaders(conn modules.PeerConn) error {
+	err := conn.SetDeadline(time.Now().Add(sendBlocksTimeout))
+	if err != nil {
+		return err
+	}
+
+	// Send the ids of the header chain.
+	var history [32]types.BlockID
+	cs.mu.RLock()
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		history = cs.headerHistory(tx)
+		return nil
+	})
+	cs.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	if err := encoding.WriteObject(conn, history); err != nil {
+		return err
+	}
+
+	// Read headers off of the wire and add them to the header chain until
+	// there are no more headers available.
+	moreAvailable := true
+	for moreAvailable {
+		var headers []types.BlockHeader
+		if err := encoding.ReadObject(conn, &headers, uint64(maxCatchUpHeaders)*types.BlockHeaderSize+8); err != nil {
+			return err
+		}
+		if err := encoding.ReadObject(conn, &moreAvailable, 1); err != nil {
+			return err
+		}
+		if len(headers) == 0 {
+			continue
+		}
+		_, err := cs.managedAcceptHeaders(headers)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// threadedReceiveHeaders is the calling end of the SendHeaders RPC.
+func (cs *ConsensusSet) threadedReceiveHeaders(conn modules.PeerConn) error {
+	err := conn.SetDeadline(time.Now().Add(sendBlocksTimeout))
+	if err != nil {
+		return err
+	}
+	finishedChan := make(chan struct{})
+	defer close(finishedChan)
+	go func() {
+		select {
+		case <-cs.tg.StopChan():
+		case <-finishedChan:
+		}
+		conn.Close()
+	}()
+	err = cs.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+	return cs.managedReceiveHeaders(conn)
+}
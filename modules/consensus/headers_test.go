@@ -0,0 +1,218 @@
+package consensus
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/gateway"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/NebulousLabs/bolt"
+)
+
+// newHeadersOnlyTester returns a headers-only consensus set and its gateway.
+func newHeadersOnlyTester(name string) (*ConsensusSet, modules.Gateway, error) {
+	testdir := build.TempDir(modules.ConsensusDir, name)
+	g, err := gateway.New("localhost:0", false, filepath.Join(testdir, modules.GatewayDir))
+	if err != nil {
+		return nil, nil, err
+	}
+	cs, err := NewWithConfig(g, false, filepath.Join(testdir, modules.ConsensusDir), Config{HeadersOnly: true})
+	if err != nil {
+		return nil, nil, err
+	}
+	return cs, g, nil
+}
+
+// TestAcceptHeadersInvalid checks that the header chain refuses orphan and
+// unsolved headers.
+func TestAcceptHeadersInvalid(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cs, g, err := newHeadersOnlyTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	defer cs.Close()
+
+	if cs.HeaderHeight() != 0 {
+		t.Fatal("new header chain should only contain the genesis header")
+	}
+	if h, exists := cs.HeaderAtHeight(0); !exists || h.ID() != types.GenesisID {
+		t.Fatal("header chain does not start with the genesis header")
+	}
+
+	// A header with an unknown parent is an orphan.
+	_, err = cs.managedAcceptHeaders([]types.BlockHeader{{ParentID: types.BlockID{1}}})
+	if err != errOrphan {
+		t.Fatal("expected errOrphan, got", err)
+	}
+
+	// A header that does not meet the target of its parent is refused.
+	h := types.BlockHeader{
+		ParentID:  types.GenesisID,
+		Timestamp: types.CurrentTimestamp(),
+	}
+	for checkHeaderTarget(h, types.RootTarget) {
+		h.Nonce[0]++
+	}
+	_, err = cs.managedAcceptHeaders([]types.BlockHeader{h})
+	if err != modules.ErrBlockUnsolved {
+		t.Fatal("expected ErrBlockUnsolved, got", err)
+	}
+	if cs.HeaderHeight() != 0 {
+		t.Fatal("invalid headers were added to the header chain")
+	}
+
+	// Full blocks are refused.
+	if err := cs.AcceptBlock(types.GenesisBlock); err != errHeadersOnly {
+		t.Fatal("expected errHeadersOnly, got", err)
+	}
+}
+
+// TestAcceptHeadersFuture checks that the header chain accepts headers that
+// are slightly in the future, and refuses headers that are too far in the
+// future.
+func TestAcceptHeadersFuture(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cs, g, err := newHeadersOnlyTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	defer cs.Close()
+
+	solve := func(h types.BlockHeader) types.BlockHeader {
+		for !checkHeaderTarget(h, types.RootTarget) {
+			h.Nonce[0]++
+		}
+		return h
+	}
+	extreme := solve(types.BlockHeader{
+		ParentID:  types.GenesisID,
+		Timestamp: types.CurrentTimestamp() + 2*types.ExtremeFutureThreshold,
+	})
+	_, err = cs.managedAcceptHeaders([]types.BlockHeader{extreme})
+	if err != errExtremeFutureTimestamp {
+		t.Fatal("expected errExtremeFutureTimestamp, got", err)
+	}
+
+	future := solve(types.BlockHeader{
+		ParentID:  types.GenesisID,
+		Timestamp: types.CurrentTimestamp() + (types.FutureThreshold+types.ExtremeFutureThreshold)/2,
+	})
+	_, err = cs.managedAcceptHeaders([]types.BlockHeader{future})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h, _ := cs.HeaderAtHeight(1); h.ID() != future.ID() {
+		t.Fatal("future header was not added to the header chain")
+	}
+}
+
+// TestIntegrationHeadersOnlySync checks that a headers-only consensus set
+// syncs the header chain of a full node, computes the same targets, follows
+// new blocks, and can confirm transactions with filtered blocks.
+func TestIntegrationHeadersOnlySync(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	cst, err := createConsensusSetTester(t.Name() + "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+	cs, g, err := newHeadersOnlyTester(t.Name() + "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	defer cs.Close()
+
+	// Give the consensus set time to register its RPCs, then connect to the
+	// full node, which syncs the header chain.
+	time.Sleep(500 * time.Millisecond)
+	err = g.Connect(cst.gateway.Address())
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForHeaders := func() {
+		err := build.Retry(100, 100*time.Millisecond, func() error {
+			if cs.HeaderHeight() != cst.cs.Height() {
+				return errors.New("header chain is not synced")
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitForHeaders()
+
+	// The header chain should match the current path of the full node,
+	// including the targets computed from the headers.
+	for height := types.BlockHeight(0); height <= cst.cs.Height(); height++ {
+		var pb *processedBlock
+		_ = cst.cs.db.View(func(tx *bolt.Tx) error {
+			id, err := getPath(tx, height)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pb, err = getBlockMap(tx, id)
+			return err
+		})
+		var ph *processedHeader
+		_ = cs.db.View(func(tx *bolt.Tx) error {
+			id, err := getHeaderPath(tx, height)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ph, err = getHeaderMap(tx, id)
+			return err
+		})
+		if ph.Header.ID() != pb.Block.ID() {
+			t.Fatal("header chain does not match the current path at height", height)
+		}
+		if ph.ChildTarget != pb.ChildTarget || ph.Depth != pb.Depth {
+			t.Fatal("header chain computed the wrong target at height", height)
+		}
+	}
+	if cs.Height() != 0 {
+		t.Fatal("headers-only consensus set applied blocks")
+	}
+
+	// New blocks should be added to the header chain when their headers are
+	// relayed. Send coins in the new block, and confirm the transaction with
+	// a filtered block.
+	addr := randAddress()
+	_, err = cst.wallet.SendSiacoins(types.SiacoinPrecision, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := cst.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForHeaders()
+	fb, err := cs.RequestFilteredBlock(cst.gateway.Address(), cs.HeaderHeight(), []types.UnlockHash{addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fb.Header.ID() != b.ID() || len(fb.Proofs) != 1 {
+		t.Fatal("received the wrong filtered block")
+	}
+	_, err = cs.RequestFilteredBlock(cst.gateway.Address(), cs.HeaderHeight()+1, []types.UnlockHash{addr})
+	if err != errHeaderNotFound {
+		t.Fatal("expected errHeaderNotFound, got", err)
+	}
+}
@@ -44,14 +44,12 @@ func applyMaturedSiacoinOutputs(tx *bolt.Tx, pb *processedBlock) {
 		return
 	}
 
-	// Iterate through the list of delayed siacoin outputs. Sometimes boltdb
-	// has trouble if you delete elements in a bucket while iterating through
-	// the bucket (and sometimes not - nondeterministic), so all of the
-	// elements are collected into an array and then deleted after the bucket
-	// scan is complete.
+	// Every output in the bucket matures at this height, so the outputs are
+	// not removed from the bucket one at a time. Instead, the diffs are
+	// recorded and the whole bucket is deleted once the scan is complete. The
+	// matured outputs go into a different bucket, so they can be added while
+	// iterating.
 	bucketID := append(prefixDSCO, encoding.Marshal(pb.Height)...)
-	var scods []modules.SiacoinOutputDiff
-	var dscods []modules.DelayedSiacoinOutputDiff
 	dbErr := tx.Bucket(bucketID).ForEach(func(idBytes, scoBytes []byte) error {
 		// Decode the key-value pair into an id and a siacoin output.
 		var id types.SiacoinOutputID
@@ -74,30 +72,22 @@ func applyMaturedSiacoinOutputs(tx *bolt.Tx, pb *processedBlock) {
 			ID:            id,
 			SiacoinOutput: sco,
 		}
-		scods = append(scods, scod)
+		pb.SiacoinOutputDiffs = append(pb.SiacoinOutputDiffs, scod)
+		commitSiacoinOutputDiff(tx, scod, modules.DiffApply)
 
-		// Create the dscod and add it to the list of dscods that should be
-		// deleted.
-		dscod := modules.DelayedSiacoinOutputDiff{
+		// Record the removal of the delayed output. The removal itself
+		// happens when the bucket is deleted.
+		pb.DelayedSiacoinOutputDiffs = append(pb.DelayedSiacoinOutputDiffs, modules.DelayedSiacoinOutputDiff{
 			Direction:      modules.DiffRevert,
 			ID:             id,
 			SiacoinOutput:  sco,
 			MaturityHeight: pb.Height,
-		}
-		dscods = append(dscods, dscod)
+		})
 		return nil
 	})
 	if build.DEBUG && dbErr != nil {
 		panic(dbErr)
 	}
-	for _, scod := range scods {
-		pb.SiacoinOutputDiffs = append(pb.SiacoinOutputDiffs, scod)
-		commitSiacoinOutputDiff(tx, scod, modules.DiffApply)
-	}
-	for _, dscod := range dscods {
-		pb.DelayedSiacoinOutputDiffs = append(pb.DelayedSiacoinOutputDiffs, dscod)
-		commitDelayedSiacoinOutputDiff(tx, dscod, modules.DiffApply)
-	}
 	deleteDSCOBucket(tx, pb.Height)
 }
 
@@ -0,0 +1,113 @@
+package consensus
+
+import (
+	"time"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// metrics.go tracks where the consensus set spends its time while processing
+// blocks, and how often reorgs happen. Metrics are kept in memory only, and
+// reset when the consensus set is restarted.
+
+// MetricsBucketBounds are the upper bounds of the buckets of a
+// TimingHistogram. A final, unbounded bucket counts the remaining durations.
+var MetricsBucketBounds = [...]time.Duration{
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+	10 * time.Second,
+}
+
+type (
+	// TimingHistogram records the distribution of the duration of an
+	// operation.
+	TimingHistogram struct {
+		Count uint64        `json:"count"`
+		Total time.Duration `json:"total"`
+		Max   time.Duration `json:"max"`
+
+		// Buckets[i] counts the durations that are at most
+		// MetricsBucketBounds[i] and more than MetricsBucketBounds[i-1]. The
+		// last bucket counts the durations that exceed every bound.
+		Buckets [len(MetricsBucketBounds) + 1]uint64 `json:"buckets"`
+	}
+
+	// Metrics contains counters and timings describing the block processing
+	// performed by the consensus set.
+	Metrics struct {
+		// BlocksApplied and BlocksReverted count the blocks added to and
+		// removed from the current path.
+		BlocksApplied  uint64 `json:"blocksapplied"`
+		BlocksReverted uint64 `json:"blocksreverted"`
+
+		// Reorgs counts the forks that reverted at least one block, and
+		// DeepestReorg is the largest number of blocks reverted by one fork.
+		Reorgs       uint64            `json:"reorgs"`
+		DeepestReorg types.BlockHeight `json:"deepestreorg"`
+
+		// TransactionsValidated counts the transactions validated while
+		// generating diffs for new blocks.
+		TransactionsValidated uint64 `json:"transactionsvalidated"`
+
+		// The number of each type of diff generated for new blocks.
+		SiacoinOutputDiffs        uint64 `json:"siacoinoutputdiffs"`
+		FileContractDiffs         uint64 `json:"filecontractdiffs"`
+		SiafundOutputDiffs        uint64 `json:"siafundoutputdiffs"`
+		DelayedSiacoinOutputDiffs uint64 `json:"delayedsiacoinoutputdiffs"`
+		SiafundPoolDiffs          uint64 `json:"siafundpooldiffs"`
+
+		// TransactionValidation times the validation and application of the
+		// transactions of each new block, Maintenance times the maintenance
+		// applied to each new block, and Fork times each change to the
+		// current path, including any reverted blocks.
+		TransactionValidation TimingHistogram `json:"transactionvalidation"`
+		Maintenance           TimingHistogram `json:"maintenance"`
+		Fork                  TimingHistogram `json:"fork"`
+	}
+)
+
+// record adds a duration to the histogram.
+func (th *TimingHistogram) record(d time.Duration) {
+	th.Count++
+	th.Total += d
+	if d > th.Max {
+		th.Max = d
+	}
+	i := 0
+	for i < len(MetricsBucketBounds) && d > MetricsBucketBounds[i] {
+		i++
+	}
+	th.Buckets[i]++
+}
+
+// recordGeneratedDiffs adds the diffs of a newly processed block to the
+// metrics.
+func (m *Metrics) recordGeneratedDiffs(pb *processedBlock) {
+	m.SiacoinOutputDiffs += uint64(len(pb.SiacoinOutputDiffs))
+	m.FileContractDiffs += uint64(len(pb.FileContractDiffs))
+	m.SiafundOutputDiffs += uint64(len(pb.SiafundOutputDiffs))
+	m.DelayedSiacoinOutputDiffs += uint64(len(pb.DelayedSiacoinOutputDiffs))
+	m.SiafundPoolDiffs += uint64(len(pb.SiafundPoolDiffs))
+}
+
+// recordFork adds a change to the current path to the metrics.
+func (m *Metrics) recordFork(reverted, applied int, d time.Duration) {
+	m.BlocksApplied += uint64(applied)
+	m.BlocksReverted += uint64(reverted)
+	if reverted > 0 {
+		m.Reorgs++
+		if types.BlockHeight(reverted) > m.DeepestReorg {
+			m.DeepestReorg = types.BlockHeight(reverted)
+		}
+	}
+	m.Fork.record(d)
+}
+
+// Metrics returns the block processing metrics of the consensus set.
+func (cs *ConsensusSet) Metrics() Metrics {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.metrics
+}
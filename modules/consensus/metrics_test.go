@@ -0,0 +1,59 @@
+package consensus
+
+import (
+	"testing"
+	"time"
+)
+
+// TestTimingHistogram checks that durations are recorded in the right
+// buckets.
+func TestTimingHistogram(t *testing.T) {
+	var th TimingHistogram
+	th.record(0)
+	th.record(MetricsBucketBounds[0])
+	th.record(MetricsBucketBounds[1] + 1)
+	th.record(time.Hour)
+	if th.Count != 4 {
+		t.Fatal("wrong count:", th.Count)
+	}
+	if th.Max != time.Hour {
+		t.Error("wrong max:", th.Max)
+	}
+	if th.Total != time.Hour+MetricsBucketBounds[0]+MetricsBucketBounds[1]+1 {
+		t.Error("wrong total:", th.Total)
+	}
+	if th.Buckets[0] != 2 || th.Buckets[2] != 1 || th.Buckets[len(th.Buckets)-1] != 1 {
+		t.Error("durations recorded in the wrong buckets:", th.Buckets)
+	}
+}
+
+// TestMetrics checks that the consensus set records metrics while processing
+// blocks.
+func TestMetrics(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	m := cst.cs.Metrics()
+	if m.BlocksApplied != uint64(cst.cs.Height()) {
+		t.Error("wrong number of applied blocks:", m.BlocksApplied, cst.cs.Height())
+	}
+	if m.Maintenance.Count != m.BlocksApplied || m.TransactionValidation.Count != m.BlocksApplied {
+		t.Error("maintenance and validation were not timed for every block")
+	}
+	if m.Fork.Count != m.BlocksApplied {
+		t.Error("wrong number of forks timed:", m.Fork.Count)
+	}
+	if m.TransactionsValidated == 0 || m.SiacoinOutputDiffs == 0 || m.DelayedSiacoinOutputDiffs == 0 {
+		t.Error("transactions and diffs were not counted")
+	}
+	if m.Reorgs != 0 || m.BlocksReverted != 0 {
+		t.Error("reorgs recorded without any reorgs")
+	}
+}
@@ -0,0 +1,144 @@
+package consensus
+
+import (
+	"math/big"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/NebulousLabs/bolt"
+)
+
+// orphan.go implements the orphan pool. Blocks whose parents are unknown are
+// kept in a bounded pool instead of being discarded, and the missing
+// ancestors are requested from peers. Once a parent has been added to the
+// consensus set, its orphaned children are removed from the pool and accepted
+// automatically, which in turn releases their own orphaned children.
+//
+// An orphan cannot be validated until its parents are known, so only orphans
+// that meet a minimum target are pooled. Otherwise anyone could fill the pool
+// with blocks that cost nothing to create, pushing out the real orphans.
+
+const (
+	// maxOrphanBlocks is the maximum number of blocks that are held in the
+	// orphan pool. When the pool is full, a random orphan is evicted to make
+	// room for a new one.
+	maxOrphanBlocks = 100
+)
+
+var (
+	// orphanDifficultyFraction is the fraction of the difficulty of the next
+	// block that an orphan must meet to be pooled. Orphans are normally only
+	// a few blocks ahead of the current block, and the difficulty cannot drop
+	// by this much over a few blocks.
+	orphanDifficultyFraction = big.NewRat(1, 4)
+)
+
+// checkOrphanTarget returns true if the orphan with the given id meets the
+// minimum target of the orphan pool.
+func checkOrphanTarget(tx *bolt.Tx, b types.Block, id types.BlockID) bool {
+	minTarget := currentProcessedBlock(tx).ChildTarget.MulDifficulty(orphanDifficultyFraction)
+	return checkTarget(b, id, minTarget)
+}
+
+// addOrphan adds a block to the orphan pool. The caller must hold a lock on
+// the consensus set.
+func (cs *ConsensusSet) addOrphan(b types.Block, id types.BlockID) {
+	if _, exists := cs.orphans[id]; exists {
+		return
+	}
+	if len(cs.orphans) >= maxOrphanBlocks {
+		// Map iteration order is random, so this evicts a random orphan.
+		for orphanID := range cs.orphans {
+			delete(cs.orphans, orphanID)
+			break
+		}
+	}
+	cs.orphans[id] = b
+}
+
+// popOrphanChildren removes and returns every orphan whose parent is one of
+// the provided blocks. The caller must hold a lock on the consensus set.
+func (cs *ConsensusSet) popOrphanChildren(parents []types.Block) (children []types.Block) {
+	if len(cs.orphans) == 0 {
+		return nil
+	}
+	parentIDs := make(map[types.BlockID]struct{}, len(parents))
+	for _, b := range parents {
+		parentIDs[b.ID()] = struct{}{}
+	}
+	for id, orphan := range cs.orphans {
+		if _, exists := parentIDs[orphan.ParentID]; exists {
+			children = append(children, orphan)
+			delete(cs.orphans, id)
+		}
+	}
+	return children
+}
+
+// threadedAcceptOrphans adds orphans whose parents have become known to the
+// consensus set. Any orphans that are children of these blocks are released
+// from the pool in turn by managedAcceptBlocks.
+func (cs *ConsensusSet) threadedAcceptOrphans(orphans []types.Block) {
+	err := cs.tg.Add()
+	if err != nil {
+		return
+	}
+	defer cs.tg.Done()
+
+	for _, b := range orphans {
+		chainExtended, err := cs.managedAcceptBlocks([]types.Block{b})
+		if chainExtended {
+			cs.managedBroadcastBlock(b)
+		}
+		if err != nil && err != modules.ErrNonExtendingBlock && err != modules.ErrBlockKnown {
+			cs.log.Debugln("WARN: failed to accept an orphan block:", err)
+		}
+	}
+}
+
+// threadedFetchOrphanParents requests the blocks that the consensus set is
+// missing from its peers, stopping at the first peer that sends them
+// successfully. If 'peer' is not empty, it is asked first, as the peer that
+// sent an orphan is likely to have its parents. Only one fetch runs at a
+// time.
+func (cs *ConsensusSet) threadedFetchOrphanParents(peer modules.NetAddress) {
+	err := cs.tg.Add()
+	if err != nil {
+		return
+	}
+	defer cs.tg.Done()
+	if cs.gateway == nil {
+		return
+	}
+
+	cs.mu.Lock()
+	if cs.fetchingOrphanParents {
+		cs.mu.Unlock()
+		return
+	}
+	cs.fetchingOrphanParents = true
+	cs.mu.Unlock()
+	defer func() {
+		cs.mu.Lock()
+		cs.fetchingOrphanParents = false
+		cs.mu.Unlock()
+	}()
+
+	var addrs []modules.NetAddress
+	if peer != "" {
+		addrs = append(addrs, peer)
+	}
+	for _, p := range cs.gateway.Peers() {
+		if p.NetAddress != peer {
+			addrs = append(addrs, p.NetAddress)
+		}
+	}
+	for _, addr := range addrs {
+		err := cs.gateway.RPC(addr, "SendBlocks", cs.managedReceiveBlocks)
+		if err == nil {
+			return
+		}
+		cs.log.Debugln("WARN: failed to fetch the parents of an orphan block from", addr, ":", err)
+	}
+}
@@ -0,0 +1,148 @@
+package consensus
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestOrphanPool checks that orphan blocks are held until their parents
+// arrive, and are then added to the consensus set automatically.
+func TestOrphanPool(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+	cst2, err := blankConsensusSetTester(t.Name() + "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst2.Close()
+
+	// Give the second consensus set every block except the last three.
+	var blocks []types.Block
+	for h := types.BlockHeight(1); h <= cst.cs.Height(); h++ {
+		b, _ := cst.cs.BlockAtHeight(h)
+		blocks = append(blocks, b)
+	}
+	err = cst2.cs.AcceptBlocks(blocks[:len(blocks)-3])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Submit the last three blocks in reverse order. The first two are
+	// orphans.
+	tail := blocks[len(blocks)-3:]
+	for _, b := range []types.Block{tail[2], tail[1]} {
+		err = cst2.cs.AcceptBlock(b)
+		if err != errOrphan {
+			t.Fatal("expected errOrphan, got", err)
+		}
+	}
+	cst2.cs.mu.RLock()
+	numOrphans := len(cst2.cs.orphans)
+	cst2.cs.mu.RUnlock()
+	if numOrphans != 2 {
+		t.Fatal("orphan pool has the wrong size:", numOrphans)
+	}
+	err = cst2.cs.AcceptBlock(tail[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The orphans should be added to the consensus set.
+	for i := 0; i < 50 && cst2.cs.CurrentBlock().ID() != cst.cs.CurrentBlock().ID(); i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+	if cst2.cs.CurrentBlock().ID() != cst.cs.CurrentBlock().ID() {
+		t.Fatal("orphans were not added after their parents arrived")
+	}
+	cst2.cs.mu.RLock()
+	numOrphans = len(cst2.cs.orphans)
+	cst2.cs.mu.RUnlock()
+	if numOrphans != 0 {
+		t.Error("orphan pool is not empty after the orphans were added:", numOrphans)
+	}
+}
+
+// TestOrphanPoolBounded checks that the orphan pool does not grow beyond
+// maxOrphanBlocks.
+func TestOrphanPoolBounded(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := blankConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	cst.cs.mu.Lock()
+	defer cst.cs.mu.Unlock()
+	for i := 0; i < maxOrphanBlocks*2; i++ {
+		b := types.Block{Nonce: types.BlockNonce{byte(i), byte(i >> 8)}}
+		cst.cs.addOrphan(b, b.ID())
+		cst.cs.addOrphan(b, b.ID())
+	}
+	if len(cst.cs.orphans) != maxOrphanBlocks {
+		t.Fatal("orphan pool has the wrong size:", len(cst.cs.orphans))
+	}
+}
+
+// TestOrphanPoolMinimumTarget checks that orphans which do not meet the
+// minimum target of the orphan pool are refused.
+func TestOrphanPoolMinimumTarget(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	cst, err := blankConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// The testing root target is so easy that any fraction of its difficulty
+	// accepts every id, so require twice the difficulty of the next block
+	// instead. The next block of a blank consensus set has the root target.
+	oldFraction := orphanDifficultyFraction
+	orphanDifficultyFraction = big.NewRat(2, 1)
+	defer func() {
+		orphanDifficultyFraction = oldFraction
+	}()
+
+	// Find an orphan that misses the minimum target, and one that meets it.
+	var unsolved, solved types.Block
+	target := types.RootTarget.MulDifficulty(orphanDifficultyFraction)
+	for b := (types.Block{ParentID: types.BlockID{1}}); unsolved.ParentID == (types.BlockID{}) || solved.ParentID == (types.BlockID{}); b.Nonce[0]++ {
+		id := b.ID()
+		if checkTarget(b, id, target) {
+			solved = b
+		} else {
+			unsolved = b
+		}
+	}
+	err = cst.cs.AcceptBlock(unsolved)
+	if err != modules.ErrBlockUnsolved {
+		t.Fatal("expected ErrBlockUnsolved, got", err)
+	}
+	err = cst.cs.AcceptBlock(solved)
+	if err != errOrphan {
+		t.Fatal("expected errOrphan, got", err)
+	}
+	cst.cs.mu.RLock()
+	_, unsolvedPooled := cst.cs.orphans[unsolved.ID()]
+	_, solvedPooled := cst.cs.orphans[solved.ID()]
+	cst.cs.mu.RUnlock()
+	if unsolvedPooled || !solvedPooled {
+		t.Fatal("orphan pool admitted the wrong orphans")
+	}
+}
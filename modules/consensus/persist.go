@@ -47,6 +47,26 @@ func (cs *ConsensusSet) loadDB() error {
 			return err
 		}
 
+		// Load the blocks that are known to be invalid. The bucket is created
+		// here because older databases do not have it.
+		err = cs.initDoSBlocks(tx)
+		if err != nil {
+			return err
+		}
+
+		// Compute the consensus statistics if the database predates them.
+		err = initStats(tx)
+		if err != nil {
+			return err
+		}
+
+		// Create the deployment state bucket. Older databases do not have
+		// it, and the missing states are computed when they are needed.
+		err = initDeploymentStates(tx)
+		if err != nil {
+			return err
+		}
+
 		// Check that the genesis block is correct - typically only incorrect
 		// in the event of developer binaries vs. release binaires.
 		genesisID, err := getPath(tx, 0)
@@ -56,6 +76,33 @@ func (cs *ConsensusSet) loadDB() error {
 		if genesisID != cs.blockRoot.Block.ID() {
 			return errors.New("Blockchain has wrong genesis block, exiting.")
 		}
+
+		// Create the header chain of the headers-only sync mode.
+		if cs.headersOnly {
+			err = initHeaderChain(tx)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Load the height up to which blocks have been pruned. The bucket is
+		// created here because older databases do not have it.
+		err = cs.initPrunedHeight(tx)
+		if err != nil {
+			return err
+		}
+
+		// If pruning is enabled, delete the processed blocks that have fallen
+		// out of the prune window. The database may have been created without
+		// pruning. The pruned height can be set before the transaction
+		// commits, because the consensus set is not used if loading fails.
+		if cs.pruneDepth != 0 {
+			cs.prunedHeight, err = cs.pruneBlocks(tx)
+			if err != nil {
+				return err
+			}
+		}
+		cs.publish(tx)
 		return nil
 	})
 }
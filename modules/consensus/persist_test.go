@@ -1,12 +1,16 @@
 package consensus
 
 import (
+	"bytes"
+	"io/ioutil"
 	"path/filepath"
 	"testing"
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/modules/gateway"
+
+	"github.com/NebulousLabs/bolt"
 )
 
 // TestSaveLoad populates a blockchain, saves it, loads it, and checks
@@ -41,3 +45,76 @@ func TestSaveLoad(t *testing.T) {
 		t.Fatal("consensus set hash changed after load")
 	}
 }
+
+// TestOpenReadOnly checks that a consensus database can be opened read-only,
+// that the read-only consensus set reports the same state as the consensus
+// set that wrote the database, and that the database is never modified.
+func TestOpenReadOnly(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	height := cst.cs.Height()
+	currentID := cst.cs.CurrentBlock().ID()
+	checksum := cst.cs.dbConsensusChecksum()
+	block, err := cst.miner.FindBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cst.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dbFilename := filepath.Join(cst.persistDir, modules.ConsensusDir, DatabaseFilename)
+	before, err := ioutil.ReadFile(dbFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cs, err := OpenReadOnly(filepath.Join(cst.persistDir, modules.ConsensusDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cs.Height() != height {
+		t.Fatal("read-only consensus set has the wrong height")
+	}
+	if cs.CurrentBlock().ID() != currentID {
+		t.Fatal("read-only consensus set has the wrong current block")
+	}
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		if consensusChecksum(tx) != checksum {
+			t.Error("read-only consensus set has the wrong consensus checksum")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cs.AcceptBlock(block)
+	if err == nil {
+		t.Fatal("read-only consensus set accepted a block")
+	}
+	err = cs.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := ioutil.ReadFile(dbFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Fatal("read-only consensus set modified the database")
+	}
+
+	// Opening a directory without a consensus database should fail.
+	_, err = OpenReadOnly(build.TempDir(modules.ConsensusDir, t.Name()+"-empty"))
+	if err == nil {
+		t.Fatal("opened a read-only consensus set without a database")
+	}
+}
@@ -0,0 +1,156 @@
+package consensus
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/NebulousLabs/bolt"
+)
+
+// prune.go implements pruning of old blocks. When pruning is enabled, the
+// consensus set only keeps the processed blocks (block bodies and diffs) for
+// the most recent 'pruneDepth' blocks of the current path. Older processed
+// blocks are deleted from the block map, while the block path and the rest of
+// the consensus state are kept. The genesis block is never pruned.
+//
+// A pruned consensus set cannot serve old blocks to peers, cannot send the
+// full history to new subscribers, and cannot reorg to a fork whose common
+// parent with the current path is too deep. Forks are refused as soon as
+// validating or applying them could require a pruned block.
+
+var (
+	errPruneDepthTooSmall = errors.New("prune depth is too small to safely handle reorgs")
+	errPrunedBlocks       = errors.New("the requested blocks have been pruned")
+	errPrunedFork         = errors.New("block forks from a part of the blockchain that has been pruned")
+)
+
+// minPruneDepth returns the smallest allowed prune depth. Validating a block
+// can require the processed blocks up to TargetWindow blocks before it, so
+// keeping twice that many blocks allows reorgs of up to TargetWindow blocks.
+// Counting the deployment signals of a version bits window also requires the
+// blocks of the window.
+func minPruneDepth() types.BlockHeight {
+	if types.VersionBitsWindow > 2*types.TargetWindow {
+		return types.VersionBitsWindow
+	}
+	return 2 * types.TargetWindow
+}
+
+// pruneBlock deletes the processed block at the given height of the current
+// path from the block map and the block cache.
+func (cs *ConsensusSet) pruneBlock(tx *bolt.Tx, height types.BlockHeight) error {
+	id, err := getPath(tx, height)
+	if err != nil {
+		return err
+	}
+	// The block is only dropped from the cache once the deletion is
+	// committed, so that a rolled back prune leaves the cache intact.
+	tx.OnCommit(func() { cs.blockCache.remove(id) })
+	return tx.Bucket(BlockMap).Delete(id[:])
+}
+
+// initPrunedHeight loads the height of the most recent pruned block from the
+// database, creating the bucket if the database predates it. Databases
+// without the bucket have never been pruned.
+func (cs *ConsensusSet) initPrunedHeight(tx *bolt.Tx) error {
+	bucket, err := tx.CreateBucketIfNotExists(PrunedHeight)
+	if err != nil {
+		return err
+	}
+	heightBytes := bucket.Get(PrunedHeight)
+	if heightBytes == nil {
+		return nil
+	}
+	return encoding.Unmarshal(heightBytes, &cs.prunedHeight)
+}
+
+// pruneBlocks deletes every processed block that has fallen out of the prune
+// window since the last call, saves the new pruned height, and returns it. If
+// the database has never been pruned, pruning starts from the genesis block,
+// as the database may have been created without pruning. cs.prunedHeight is
+// not modified; the caller should set it once the transaction has committed.
+func (cs *ConsensusSet) pruneBlocks(tx *bolt.Tx) (types.BlockHeight, error) {
+	height := blockHeight(tx)
+	prunedHeight := cs.prunedHeight
+	for h := cs.prunedHeight + 1; h+cs.pruneDepth <= height; h++ {
+		err := cs.pruneBlock(tx, h)
+		if err != nil {
+			return cs.prunedHeight, err
+		}
+		prunedHeight = h
+	}
+	if prunedHeight == cs.prunedHeight {
+		return prunedHeight, nil
+	}
+	err := tx.Bucket(PrunedHeight).Put(PrunedHeight, encoding.Marshal(prunedHeight))
+	if err != nil {
+		return cs.prunedHeight, err
+	}
+	return prunedHeight, nil
+}
+
+// isPruned returns true if the block at the given height of the current path
+// has been pruned. The genesis block is never pruned.
+func (cs *ConsensusSet) isPruned(height types.BlockHeight) bool {
+	return height != 0 && height <= cs.prunedHeight
+}
+
+// checkPrunedEntry returns modules.ErrPrunedConsensusChange if the consensus
+// changes that follow the change entry include blocks that have been pruned,
+// which is the case if the entry ends below the pruned height.
+func (cs *ConsensusSet) checkPrunedEntry(tx *bolt.Tx, ce changeEntry) error {
+	if cs.prunedHeight == 0 || len(ce.AppliedBlocks) == 0 {
+		return nil
+	}
+	pb, err := getBlockMap(tx, ce.AppliedBlocks[len(ce.AppliedBlocks)-1])
+	if err != nil || pb.Height < cs.prunedHeight {
+		return modules.ErrPrunedConsensusChange
+	}
+	return nil
+}
+
+// checkPrunedParent returns an error if validating a child of 'parent' could
+// require processed blocks that have been pruned.
+func (cs *ConsensusSet) checkPrunedParent(tx dbTx, parent *processedBlock) error {
+	if cs.pruneDepth == 0 {
+		return nil
+	}
+	var height types.BlockHeight
+	err := cs.marshaler.Unmarshal(tx.Bucket(BlockHeight).Get(BlockHeight), &height)
+	if err != nil {
+		return err
+	}
+	if parent.Height+cs.pruneDepth < height+types.TargetWindow {
+		return errPrunedFork
+	}
+	return nil
+}
+
+// checkPrunedFork returns an error if reorganizing to a child of 'parent'
+// could require processed blocks that have been pruned. The common parent of
+// the fork and the current path must be recent enough that every block above
+// it, and every block needed to validate the children of the common parent,
+// is still in the block map.
+func (cs *ConsensusSet) checkPrunedFork(tx *bolt.Tx, parent *processedBlock) error {
+	if cs.pruneDepth == 0 {
+		return nil
+	}
+	pb := parent
+	for {
+		id, err := getPath(tx, pb.Height)
+		if err == nil && id == pb.Block.ID() {
+			break
+		}
+		pb, err = getBlockMap(tx, pb.Block.ParentID)
+		if err != nil {
+			return errPrunedFork
+		}
+	}
+	if pb.Height+cs.pruneDepth < blockHeight(tx)+types.TargetWindow {
+		return errPrunedFork
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package consensus
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/gateway"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestPrunedConsensusSet checks that a pruned consensus set deletes old
+// processed blocks while continuing to track the same state as an unpruned
+// consensus set.
+func TestPrunedConsensusSet(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// Prune depths that are too small should be rejected.
+	g, err := gateway.New("localhost:0", false, build.TempDir(modules.ConsensusDir, t.Name(), "pruned", modules.GatewayDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	prunedDir := build.TempDir(modules.ConsensusDir, t.Name(), "pruned", modules.ConsensusDir)
+	_, err = NewWithConfig(g, false, prunedDir, Config{PruneDepth: minPruneDepth() - 1})
+	if err != errPruneDepthTooSmall {
+		t.Fatal("expected errPruneDepthTooSmall, got", err)
+	}
+	pcs, err := NewWithConfig(g, false, prunedDir, Config{PruneDepth: minPruneDepth()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pcs.Close()
+
+	// Mine enough blocks that some of them fall out of the prune window, and
+	// give them all to the pruned consensus set.
+	for cst.cs.Height() <= minPruneDepth()+5 {
+		_, err = cst.miner.AddBlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	var blocks []types.Block
+	for i := types.BlockHeight(1); i <= cst.cs.Height(); i++ {
+		b, _ := cst.cs.BlockAtHeight(i)
+		blocks = append(blocks, b)
+	}
+	err = pcs.AcceptBlocks(blocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pcs.dbConsensusChecksum() != cst.cs.dbConsensusChecksum() {
+		t.Fatal("pruned consensus set does not match the unpruned consensus set")
+	}
+
+	// Old blocks should be gone, while the genesis block and the blocks in
+	// the prune window should remain.
+	height := pcs.Height()
+	if _, exists := pcs.BlockAtHeight(0); !exists {
+		t.Error("genesis block was pruned")
+	}
+	if _, exists := pcs.BlockAtHeight(height - minPruneDepth()); exists {
+		t.Error("block outside of the prune window was not pruned")
+	}
+	if _, exists := pcs.BlockAtHeight(height - minPruneDepth() + 1); !exists {
+		t.Error("block inside of the prune window was pruned")
+	}
+
+	// Reopening the unpruned database with pruning enabled should prune the
+	// old blocks.
+	err = cst.cs.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cst.cs, err = NewWithConfig(g, false, filepath.Join(cst.persistDir, modules.ConsensusDir), Config{PruneDepth: minPruneDepth()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := cst.cs.BlockAtHeight(1); exists {
+		t.Error("existing blocks were not pruned when pruning was enabled")
+	}
+	if cst.cs.dbConsensusChecksum() != pcs.dbConsensusChecksum() {
+		t.Fatal("consensus checksum changed after pruning existing blocks")
+	}
+}
+
+// TestPrunedSynchronize checks that a pruned consensus set refuses to send
+// the blocks it has pruned without breaking the peers that request them,
+// while still synchronizing peers that only need recent blocks.
+func TestPrunedSynchronize(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// Create a pruned consensus set and give it enough blocks that some of
+	// them are pruned.
+	g, err := gateway.New("localhost:0", false, build.TempDir(modules.ConsensusDir, t.Name(), "pruned", modules.GatewayDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	prunedDir := build.TempDir(modules.ConsensusDir, t.Name(), "pruned", modules.ConsensusDir)
+	pcs, err := NewWithConfig(g, false, prunedDir, Config{PruneDepth: minPruneDepth()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for cst.cs.Height() <= minPruneDepth()+5 {
+		_, err = cst.miner.AddBlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	var blocks []types.Block
+	for i := types.BlockHeight(1); i <= cst.cs.Height(); i++ {
+		b, _ := cst.cs.BlockAtHeight(i)
+		blocks = append(blocks, b)
+	}
+	err = pcs.AcceptBlocks(blocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A fresh peer needs the pruned blocks, so it cannot synchronize from the
+	// pruned consensus set.
+	fg, err := gateway.New("localhost:0", false, build.TempDir(modules.ConsensusDir, t.Name(), "fresh", modules.GatewayDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fg.Close()
+	fcs, err := New(fg, false, build.TempDir(modules.ConsensusDir, t.Name(), "fresh", modules.ConsensusDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fcs.Close()
+	err = fg.Connect(g.Address())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = fg.RPC(g.Address(), "SendBlocks", fcs.managedReceiveBlocks)
+	if err == nil {
+		t.Fatal("fresh peer was able to receive pruned blocks")
+	}
+	if fcs.Height() != 0 {
+		t.Fatal("fresh peer received blocks from the pruned consensus set:", fcs.Height())
+	}
+
+	// A peer that is only missing recent blocks should synchronize.
+	rg, err := gateway.New("localhost:0", false, build.TempDir(modules.ConsensusDir, t.Name(), "recent", modules.GatewayDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rg.Close()
+	rcs, err := New(rg, false, build.TempDir(modules.ConsensusDir, t.Name(), "recent", modules.ConsensusDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rcs.Close()
+	err = rcs.AcceptBlocks(blocks[:len(blocks)-5])
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rg.Connect(g.Address())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = build.Retry(100, 50*time.Millisecond, func() error {
+		if rcs.CurrentBlock().ID() != pcs.CurrentBlock().ID() {
+			return errors.New("recent peer did not synchronize with the pruned consensus set")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Subscribing from the genesis block requires the pruned blocks.
+	ms := newMockSubscriber()
+	err = pcs.ConsensusSetSubscribe(&ms, modules.ConsensusChangeBeginning)
+	if err != modules.ErrPrunedConsensusChange {
+		t.Fatal("expected ErrPrunedConsensusChange, got", err)
+	}
+	if len(ms.updates) != 0 {
+		t.Fatal("subscriber received changes before being rejected")
+	}
+
+	// The pruned height should survive a restart, even with pruning disabled,
+	// as the pruned blocks are still missing.
+	prunedHeight := pcs.prunedHeight
+	if prunedHeight == 0 {
+		t.Fatal("no blocks were pruned")
+	}
+	err = pcs.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pcs, err = New(g, false, prunedDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pcs.Close()
+	if pcs.prunedHeight != prunedHeight {
+		t.Fatalf("pruned height was not persisted: expected %v, got %v", prunedHeight, pcs.prunedHeight)
+	}
+	err = pcs.ConsensusSetSubscribe(&ms, modules.ConsensusChangeBeginning)
+	if err != modules.ErrPrunedConsensusChange {
+		t.Fatal("expected ErrPrunedConsensusChange after restart, got", err)
+	}
+}
@@ -0,0 +1,53 @@
+package consensus
+
+import (
+	"sync"
+
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/NebulousLabs/bolt"
+)
+
+// published.go tracks the most recent state of the consensus set that has been
+// sent to every subscriber. Queries such as Height and CurrentBlock are
+// answered from the published state, which has its own lock, so that they
+// never wait for block validation or for subscribers to finish processing a
+// change. Because the state is only published after the subscribers have been
+// updated, a caller that sees a new height can rely on every module having
+// received the blocks up to that height.
+
+// publishedState is the height and current block of the consensus set as last
+// seen by the subscribers.
+type publishedState struct {
+	height       types.BlockHeight
+	currentBlock types.Block
+	mu           sync.RWMutex
+}
+
+// publish updates the published state to match the database. The caller must
+// hold a lock on the consensus set, so that the published state is never
+// moved backwards by a concurrent call.
+func (cs *ConsensusSet) publish(tx *bolt.Tx) {
+	height := blockHeight(tx)
+	block := currentProcessedBlock(tx).Block
+
+	cs.published.mu.Lock()
+	cs.published.height = height
+	cs.published.currentBlock = block
+	cs.published.mu.Unlock()
+}
+
+// publishedHeight returns the published height of the consensus set.
+func (cs *ConsensusSet) publishedHeight() types.BlockHeight {
+	cs.published.mu.RLock()
+	defer cs.published.mu.RUnlock()
+	return cs.published.height
+}
+
+// publishedCurrentBlock returns the published current block of the consensus
+// set.
+func (cs *ConsensusSet) publishedCurrentBlock() types.Block {
+	cs.published.mu.RLock()
+	defer cs.published.mu.RUnlock()
+	return cs.published.currentBlock
+}
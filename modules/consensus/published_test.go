@@ -0,0 +1,91 @@
+package consensus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// blockingSubscriber is a subscriber that blocks in ProcessConsensusChange
+// until it is released.
+type blockingSubscriber struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+// ProcessConsensusChange signals that a change was received and waits to be
+// released.
+func (bs *blockingSubscriber) ProcessConsensusChange(cc modules.ConsensusChange) {
+	select {
+	case bs.started <- struct{}{}:
+	default:
+		return
+	}
+	<-bs.release
+}
+
+// TestQueriesDuringSubscriberUpdate checks that queries are answered while
+// the subscribers are processing a block, and that the height only changes
+// once every subscriber has received the block.
+func TestQueriesDuringSubscriberUpdate(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	bs := &blockingSubscriber{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	err = cst.cs.ConsensusSetSubscribe(bs, modules.ConsensusChangeRecent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Mine a block in the background; it will stall in the subscriber.
+	height := cst.cs.Height()
+	current := cst.cs.CurrentBlock().ID()
+	errChan := make(chan error)
+	go func() {
+		_, err := cst.miner.AddBlock()
+		errChan <- err
+	}()
+	<-bs.started
+
+	// Queries should be answered with the state that every subscriber has
+	// seen.
+	done := make(chan struct{})
+	go func() {
+		if cst.cs.Height() != height {
+			t.Error("height changed before the subscribers received the block")
+		}
+		if cst.cs.CurrentBlock().ID() != current {
+			t.Error("current block changed before the subscribers received the block")
+		}
+		_, err := cst.cs.TryTransactionSet(nil)
+		if err != nil {
+			t.Error(err)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("queries blocked while a subscriber was processing a block")
+	}
+
+	close(bs.release)
+	err = <-errChan
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cst.cs.Height() != height+1 {
+		t.Fatal("height was not updated after the subscribers received the block")
+	}
+}
@@ -0,0 +1,55 @@
+package consensus
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/NebulousLabs/Sia/persist"
+
+	"github.com/NebulousLabs/bolt"
+)
+
+// OpenReadOnly opens the consensus database in persistDir without ever
+// writing to it, so that external tools such as explorers and analytics jobs
+// can query the consensus set. The returned consensus set is not connected to
+// the network and does not keep a log. Any call that would modify the
+// consensus set, such as AcceptBlock, returns an error.
+//
+// Bolt does not allow a database to be opened for reading while another
+// process has it open for writing, so the database should either belong to a
+// stopped daemon or be a copy of a running daemon's database.
+func OpenReadOnly(persistDir string) (*ConsensusSet, error) {
+	cs := newConsensusSet(nil, persistDir, Config{})
+	cs.log = persist.NewLogger(ioutil.Discard)
+
+	var err error
+	cs.db, err = persist.OpenDatabaseReadOnly(dbMetadata, filepath.Join(persistDir, DatabaseFilename))
+	if err != nil {
+		return nil, errors.New("error opening consensus database: " + err.Error())
+	}
+	cs.tg.AfterStop(func() {
+		err := cs.db.Close()
+		if err != nil {
+			cs.log.Println("ERROR: Unable to close consensus set database at shutdown:", err)
+		}
+	})
+
+	// Check that the genesis block is correct.
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		genesisID, err := getPath(tx, 0)
+		if err != nil {
+			return err
+		}
+		if genesisID != cs.blockRoot.Block.ID() {
+			return errors.New("Blockchain has wrong genesis block, exiting.")
+		}
+		cs.publish(tx)
+		return nil
+	})
+	if err != nil {
+		cs.db.Close()
+		return nil, err
+	}
+	return cs, nil
+}
@@ -0,0 +1,43 @@
+package consensus
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	errDeepReorg = errors.New("refusing to perform a reorg deeper than the reorg alert depth")
+)
+
+// DeepReorg describes a reorg that reverted at least the configured reorg
+// alert depth worth of blocks.
+type DeepReorg struct {
+	// RevertedBlocks are the blocks that were reverted, starting with the
+	// block that was the current block before the reorg.
+	RevertedBlocks []types.BlockID
+
+	// AppliedBlocks are the blocks that were applied, ending with the new
+	// current block.
+	AppliedBlocks []types.BlockID
+}
+
+// isDeepReorg returns true if reverting 'reverted' blocks is a deep reorg.
+func (cs *ConsensusSet) isDeepReorg(reverted types.BlockHeight) bool {
+	return cs.reorgAlertDepth != 0 && reverted >= cs.reorgAlertDepth
+}
+
+// alertDeepReorg logs the change and calls the deep reorg callback if the
+// change is a deep reorg.
+func (cs *ConsensusSet) alertDeepReorg(ce changeEntry) {
+	if !cs.isDeepReorg(types.BlockHeight(len(ce.RevertedBlocks))) {
+		return
+	}
+	cs.log.Printf("WARN: deep reorg reverted %v blocks and applied %v blocks, new current block is %v", len(ce.RevertedBlocks), len(ce.AppliedBlocks), ce.AppliedBlocks[len(ce.AppliedBlocks)-1])
+	if cs.onDeepReorg != nil {
+		go cs.onDeepReorg(DeepReorg{
+			RevertedBlocks: ce.RevertedBlocks,
+			AppliedBlocks:  ce.AppliedBlocks,
+		})
+	}
+}
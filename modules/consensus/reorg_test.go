@@ -0,0 +1,105 @@
+package consensus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/gateway"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestDeepReorgAlert checks that deep reorgs are reported to the deep reorg
+// callback, and refused when HaltOnDeepReorg is set.
+func TestDeepReorgAlert(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst1, err := blankConsensusSetTester(t.Name() + "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst1.Close()
+	cst2, err := blankConsensusSetTester(t.Name() + "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst2.Close()
+
+	// Create two competing chains, the second one heavier than the first.
+	mine := func(cst *consensusSetTester, n int) (blocks []types.Block) {
+		for i := 0; i < n; i++ {
+			b, err := cst.miner.AddBlock()
+			if err != nil {
+				t.Fatal(err)
+			}
+			blocks = append(blocks, b)
+		}
+		return blocks
+	}
+	chain1 := mine(cst1, 3)
+	chain2 := mine(cst2, 5)
+
+	// newCS creates a consensus set on chain1 with the provided config.
+	newCS := func(name string, config Config) *ConsensusSet {
+		g, err := gateway.New("localhost:0", false, build.TempDir(modules.ConsensusDir, t.Name(), name, modules.GatewayDir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cs, err := NewWithConfig(g, false, build.TempDir(modules.ConsensusDir, t.Name(), name, modules.ConsensusDir), config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = cs.AcceptBlocks(chain1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cs
+	}
+
+	// A consensus set that only alerts should perform the reorg and report
+	// it.
+	alerts := make(chan DeepReorg, 1)
+	cs := newCS("alert", Config{
+		ReorgAlertDepth: 3,
+		OnDeepReorg:     func(dr DeepReorg) { alerts <- dr },
+	})
+	defer cs.Close()
+	err = cs.AcceptBlocks(chain2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cs.CurrentBlock().ID() != chain2[len(chain2)-1].ID() {
+		t.Fatal("reorg was not performed")
+	}
+	select {
+	case dr := <-alerts:
+		if len(dr.RevertedBlocks) != len(chain1) || len(dr.AppliedBlocks) != len(chain2) {
+			t.Error("deep reorg alert has the wrong blocks:", len(dr.RevertedBlocks), len(dr.AppliedBlocks))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("deep reorg was not reported")
+	}
+
+	// A consensus set that halts should refuse the reorg. The error is only
+	// returned directly when the block that triggers the reorg is submitted
+	// on its own.
+	haltCS := newCS("halt", Config{
+		ReorgAlertDepth: 3,
+		HaltOnDeepReorg: true,
+	})
+	defer haltCS.Close()
+	err = haltCS.AcceptBlocks(chain2[:3])
+	if err != modules.ErrNonExtendingBlock {
+		t.Fatal(err)
+	}
+	err = haltCS.AcceptBlock(chain2[3])
+	if err != errDeepReorg {
+		t.Fatal("expected errDeepReorg, got", err)
+	}
+	if haltCS.CurrentBlock().ID() != chain1[len(chain1)-1].ID() {
+		t.Fatal("deep reorg was performed despite HaltOnDeepReorg")
+	}
+}
@@ -0,0 +1,104 @@
+package consensus
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/NebulousLabs/bolt"
+)
+
+// siafunds.go exposes the siafund pool and siafund claims, so that wallets can
+// display claimable siacoins without duplicating the consensus math.
+
+var (
+	errSiafundPoolHeight = errors.New("requested height is greater than the current height")
+	errSiafundPoolPruned = errors.New("siafund pool history at the requested height has been pruned")
+)
+
+// siafundClaim returns the siacoins that the siafund output can claim, given
+// the current value of the siafund pool. It is the same computation that is
+// performed when the siafund output is spent.
+func siafundClaim(pool types.Currency, sfo types.SiafundOutput) types.Currency {
+	return pool.Sub(sfo.ClaimStart).Div(types.SiafundCount).Mul(sfo.Value)
+}
+
+// siafundPoolAtHeight returns the value of the siafund pool after the block at
+// the given height of the current path was applied. The pool only changes in
+// blocks that contain file contracts, so the path is searched backwards for
+// the most recent block that changed it.
+func siafundPoolAtHeight(tx *bolt.Tx, height types.BlockHeight) (types.Currency, error) {
+	if height > blockHeight(tx) {
+		return types.Currency{}, errSiafundPoolHeight
+	}
+	for h := height; ; h-- {
+		id, err := getPath(tx, h)
+		if err != nil {
+			return types.Currency{}, err
+		}
+		pb, err := getBlockMap(tx, id)
+		if err != nil {
+			return types.Currency{}, errSiafundPoolPruned
+		}
+		if n := len(pb.SiafundPoolDiffs); n > 0 {
+			return pb.SiafundPoolDiffs[n-1].Adjusted, nil
+		}
+		if h == 0 {
+			return types.ZeroCurrency, nil
+		}
+	}
+}
+
+// SiafundPool returns the current value of the siafund pool.
+func (cs *ConsensusSet) SiafundPool() (pool types.Currency) {
+	// A call to a closed database can cause undefined behavior.
+	err := cs.tg.Add()
+	if err != nil {
+		return types.ZeroCurrency
+	}
+	defer cs.tg.Done()
+
+	_ = cs.db.View(func(tx *bolt.Tx) error {
+		pool = getSiafundPool(tx)
+		return nil
+	})
+	return pool
+}
+
+// SiafundPoolAtHeight returns the value of the siafund pool after the block at
+// the given height of the current path was applied.
+func (cs *ConsensusSet) SiafundPoolAtHeight(height types.BlockHeight) (pool types.Currency, err error) {
+	// A call to a closed database can cause undefined behavior.
+	err = cs.tg.Add()
+	if err != nil {
+		return types.ZeroCurrency, err
+	}
+	defer cs.tg.Done()
+
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		pool, err = siafundPoolAtHeight(tx, height)
+		return err
+	})
+	return pool, err
+}
+
+// SiafundClaim returns the siacoins that the siafund output with the given id
+// would claim if it were spent in the next block.
+func (cs *ConsensusSet) SiafundClaim(id types.SiafundOutputID) (claim types.Currency, err error) {
+	// A call to a closed database can cause undefined behavior.
+	err = cs.tg.Add()
+	if err != nil {
+		return types.ZeroCurrency, err
+	}
+	defer cs.tg.Done()
+
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		sfo, err := getSiafundOutput(tx, id)
+		if err != nil {
+			return err
+		}
+		claim = siafundClaim(getSiafundPool(tx), sfo)
+		return nil
+	})
+	return claim, err
+}
@@ -0,0 +1,91 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestSiafundPoolAtHeight checks that the siafund pool history matches the
+// pool as it was when each height was reached.
+func TestSiafundPoolAtHeight(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// Record the pool at the current height, then add to the pool by
+	// creating file contracts.
+	startHeight := cst.cs.Height()
+	startPool := cst.cs.SiafundPool()
+	cst.testValidStorageProofBlocks()
+	if cst.cs.SiafundPool().Cmp(startPool) <= 0 {
+		t.Fatal("file contracts did not add to the siafund pool")
+	}
+
+	pool, err := cst.cs.SiafundPoolAtHeight(startHeight)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pool.Equals(startPool) {
+		t.Fatal("wrong siafund pool at the start height:", pool, startPool)
+	}
+	pool, err = cst.cs.SiafundPoolAtHeight(cst.cs.Height())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pool.Equals(cst.cs.SiafundPool()) {
+		t.Fatal("wrong siafund pool at the current height")
+	}
+	pool, err = cst.cs.SiafundPoolAtHeight(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pool.IsZero() {
+		t.Fatal("siafund pool at the genesis block should be zero")
+	}
+	_, err = cst.cs.SiafundPoolAtHeight(cst.cs.Height() + 1)
+	if err != errSiafundPoolHeight {
+		t.Fatal("expected errSiafundPoolHeight, got", err)
+	}
+}
+
+// TestSiafundClaim checks that the claim of a siafund output matches the
+// siacoins that are paid out when it is spent.
+func TestSiafundClaim(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+	cst.testValidStorageProofBlocks()
+
+	// The genesis siafund outputs start claiming from an empty pool.
+	id := cst.cs.blockRoot.Block.Transactions[0].SiafundOutputID(0)
+	sfo, err := cst.cs.dbGetSiafundOutput(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claim, err := cst.cs.SiafundClaim(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := cst.cs.SiafundPool().Div(types.SiafundCount).Mul(sfo.Value)
+	if claim.IsZero() || !claim.Equals(expected) {
+		t.Fatal("wrong siafund claim:", claim, expected)
+	}
+
+	_, err = cst.cs.SiafundClaim(types.SiafundOutputID{})
+	if err != errNilItem {
+		t.Fatal("expected errNilItem, got", err)
+	}
+}
@@ -0,0 +1,458 @@
+package consensus
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/NebulousLabs/bolt"
+)
+
+// snapshot.go implements exporting and importing the consensus state at the
+// current block. A snapshot contains the full set of siacoin outputs, file
+// contracts, siafund outputs and delayed siacoin outputs, the siafund pool,
+// the ids of every block in the current path, and the most recent processed
+// blocks, which are needed to validate the children of the snapshot block and
+// to handle shallow reorgs. A new node can import a trusted snapshot and sync
+// forward from the snapshot block instead of processing the whole blockchain.
+//
+// A snapshot is written as a sequence of individually encoded objects, so that
+// no single object exceeds the decoding size limit. The stream is terminated
+// by the hash of every preceding byte.
+
+var (
+	errSnapshotBadHash      = errors.New("snapshot hash does not match snapshot contents")
+	errSnapshotBadHeader    = errors.New("snapshot header is malformed")
+	errSnapshotNotFresh     = errors.New("snapshots can only be imported into a consensus set that is at the genesis block")
+	errSnapshotWrongGenesis = errors.New("snapshot was created from a blockchain with a different genesis block")
+)
+
+var (
+	// snapshotVersion is the version of the snapshot format, written at the
+	// beginning of every snapshot.
+	snapshotVersion = types.Specifier{'s', 'n', 'a', 'p', 's', 'h', 'o', 't', ' ', 'v', '1'}
+)
+
+type (
+	// snapshotHeader is the first object in a snapshot. It describes the
+	// block that the snapshot was taken at, and the number of each type of
+	// object that follows.
+	snapshotHeader struct {
+		Version     types.Specifier
+		BlockID     types.BlockID
+		Height      types.BlockHeight
+		SiafundPool types.Currency
+
+		NumBlocks         uint64
+		NumSiacoinOutputs uint64
+		NumFileContracts  uint64
+		NumSiafundOutputs uint64
+		NumDelayedOutputs uint64
+	}
+
+	// snapshotBlock is a processed block along with the oak difficulty
+	// totals of the block.
+	snapshotBlock struct {
+		Block       processedBlock
+		TotalTime   int64
+		TotalTarget types.Target
+	}
+
+	// snapshotSiacoinOutput is a siacoin output in a snapshot.
+	snapshotSiacoinOutput struct {
+		ID     types.SiacoinOutputID
+		Output types.SiacoinOutput
+	}
+
+	// snapshotFileContract is an open file contract in a snapshot.
+	snapshotFileContract struct {
+		ID           types.FileContractID
+		FileContract types.FileContract
+	}
+
+	// snapshotSiafundOutput is a siafund output in a snapshot.
+	snapshotSiafundOutput struct {
+		ID     types.SiafundOutputID
+		Output types.SiafundOutput
+	}
+
+	// snapshotDelayedOutput is a delayed siacoin output in a snapshot.
+	snapshotDelayedOutput struct {
+		MaturityHeight types.BlockHeight
+		ID             types.SiacoinOutputID
+		Output         types.SiacoinOutput
+	}
+)
+
+// snapshotBlockDepth returns the number of processed blocks that are included
+// in a snapshot taken at the provided height. Enough blocks are included to
+// compute the child target of the snapshot block under either difficulty
+// adjustment algorithm.
+func snapshotBlockDepth(height types.BlockHeight) types.BlockHeight {
+	if height+1 < types.TargetWindow {
+		return height + 1
+	}
+	return types.TargetWindow
+}
+
+// countKeys returns the number of keys in a bucket.
+func countKeys(b *bolt.Bucket) (n uint64) {
+	_ = b.ForEach(func(_, _ []byte) error {
+		n++
+		return nil
+	})
+	return n
+}
+
+// writeSnapshot writes a snapshot of the consensus state to enc.
+func (cs *ConsensusSet) writeSnapshot(tx *bolt.Tx, enc *encoding.Encoder) error {
+	height := blockHeight(tx)
+	depth := snapshotBlockDepth(height)
+
+	// Count the objects in each bucket so that the header can be written
+	// first.
+	header := snapshotHeader{
+		Version:           snapshotVersion,
+		BlockID:           currentBlockID(tx),
+		Height:            height,
+		SiafundPool:       getSiafundPool(tx),
+		NumBlocks:         uint64(depth),
+		NumSiacoinOutputs: countKeys(tx.Bucket(SiacoinOutputs)),
+		NumFileContracts:  countKeys(tx.Bucket(FileContracts)),
+		NumSiafundOutputs: countKeys(tx.Bucket(SiafundOutputs)),
+	}
+	err := tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+		if bytes.HasPrefix(name, prefixDSCO) {
+			header.NumDelayedOutputs += countKeys(b)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	err = enc.Encode(header)
+	if err != nil {
+		return err
+	}
+
+	// Write the block path.
+	for i := types.BlockHeight(0); i <= height; i++ {
+		id, err := getPath(tx, i)
+		if err != nil {
+			return err
+		}
+		err = enc.Encode(id)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Write the most recent processed blocks, oldest first.
+	for i := height + 1 - depth; i <= height; i++ {
+		id, err := getPath(tx, i)
+		if err != nil {
+			return err
+		}
+		pb, err := getBlockMap(tx, id)
+		if err != nil {
+			return err
+		}
+		sb := snapshotBlock{Block: *pb}
+		sb.TotalTime, sb.TotalTarget = cs.getBlockTotals(tx, id)
+		err = enc.Encode(sb)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Write the siacoin outputs, file contracts and siafund outputs.
+	err = tx.Bucket(SiacoinOutputs).ForEach(func(k, v []byte) error {
+		var ssco snapshotSiacoinOutput
+		copy(ssco.ID[:], k)
+		err := encoding.Unmarshal(v, &ssco.Output)
+		if err != nil {
+			return err
+		}
+		return enc.Encode(ssco)
+	})
+	if err != nil {
+		return err
+	}
+	err = tx.Bucket(FileContracts).ForEach(func(k, v []byte) error {
+		var sfc snapshotFileContract
+		copy(sfc.ID[:], k)
+		err := encoding.Unmarshal(v, &sfc.FileContract)
+		if err != nil {
+			return err
+		}
+		return enc.Encode(sfc)
+	})
+	if err != nil {
+		return err
+	}
+	err = tx.Bucket(SiafundOutputs).ForEach(func(k, v []byte) error {
+		var ssfo snapshotSiafundOutput
+		copy(ssfo.ID[:], k)
+		err := encoding.Unmarshal(v, &ssfo.Output)
+		if err != nil {
+			return err
+		}
+		return enc.Encode(ssfo)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Write the delayed siacoin outputs.
+	return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+		if !bytes.HasPrefix(name, prefixDSCO) {
+			return nil
+		}
+		var maturityHeight types.BlockHeight
+		err := encoding.Unmarshal(name[len(prefixDSCO):], &maturityHeight)
+		if err != nil {
+			return err
+		}
+		return b.ForEach(func(k, v []byte) error {
+			sdo := snapshotDelayedOutput{MaturityHeight: maturityHeight}
+			copy(sdo.ID[:], k)
+			err := encoding.Unmarshal(v, &sdo.Output)
+			if err != nil {
+				return err
+			}
+			return enc.Encode(sdo)
+		})
+	})
+}
+
+// readSnapshot reads a snapshot from dec and loads it into the database,
+// replacing the existing consensus state. The database is expected to only
+// contain the genesis block. The change entry that was added to the change log
+// for the snapshot is returned.
+func (cs *ConsensusSet) readSnapshot(tx *bolt.Tx, dec *encoding.Decoder) (ce changeEntry, err error) {
+	var header snapshotHeader
+	err = dec.Decode(&header)
+	if err != nil {
+		return changeEntry{}, err
+	}
+	if header.Version != snapshotVersion || header.NumBlocks == 0 || header.NumBlocks > uint64(header.Height)+1 {
+		return changeEntry{}, errSnapshotBadHeader
+	}
+
+	// Clear out the existing consensus state. The block path and the block
+	// map are left alone, as they only contain the genesis block.
+	for _, bucket := range [][]byte{SiacoinOutputs, FileContracts, SiafundOutputs} {
+		err = tx.DeleteBucket(bucket)
+		if err != nil {
+			return changeEntry{}, err
+		}
+		_, err = tx.CreateBucket(bucket)
+		if err != nil {
+			return changeEntry{}, err
+		}
+	}
+	var prefixed [][]byte
+	err = tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+		if bytes.HasPrefix(name, prefixDSCO) || bytes.HasPrefix(name, prefixFCEX) {
+			prefixed = append(prefixed, append([]byte(nil), name...))
+		}
+		return nil
+	})
+	if err != nil {
+		return changeEntry{}, err
+	}
+	for _, name := range prefixed {
+		err = tx.DeleteBucket(name)
+		if err != nil {
+			return changeEntry{}, err
+		}
+	}
+	setSiafundPool(tx, header.SiafundPool)
+
+	// Read the block path. The genesis block is already in the path.
+	for i := types.BlockHeight(0); i <= header.Height; i++ {
+		var id types.BlockID
+		err = dec.Decode(&id)
+		if err != nil {
+			return changeEntry{}, err
+		}
+		if i == 0 {
+			if id != cs.blockRoot.Block.ID() {
+				return changeEntry{}, errSnapshotWrongGenesis
+			}
+			continue
+		}
+		pushPath(tx, id)
+	}
+	if currentBlockID(tx) != header.BlockID {
+		return changeEntry{}, errSnapshotBadHeader
+	}
+
+	// Read the processed blocks, checking that each one is in the path. The
+	// genesis block is already known, and is skipped.
+	for i := uint64(0); i < header.NumBlocks; i++ {
+		var sb snapshotBlock
+		err = dec.Decode(&sb)
+		if err != nil {
+			return changeEntry{}, err
+		}
+		id := sb.Block.Block.ID()
+		pathID, err := getPath(tx, sb.Block.Height)
+		if err != nil {
+			return changeEntry{}, err
+		}
+		if pathID != id || sb.Block.Height != header.Height+1-types.BlockHeight(header.NumBlocks-i) {
+			return changeEntry{}, errSnapshotBadHeader
+		}
+		if sb.Block.Height == 0 {
+			continue
+		}
+		addBlockMap(tx, &sb.Block)
+		totals := make([]byte, 40)
+		binary.LittleEndian.PutUint64(totals[:8], uint64(sb.TotalTime))
+		copy(totals[8:], sb.TotalTarget[:])
+		err = tx.Bucket(BucketOak).Put(id[:], totals)
+		if err != nil {
+			return changeEntry{}, err
+		}
+		ce.AppliedBlocks = append(ce.AppliedBlocks, id)
+	}
+
+	// Read the siacoin outputs, file contracts, siafund outputs and delayed
+	// siacoin outputs.
+	for i := uint64(0); i < header.NumSiacoinOutputs; i++ {
+		var ssco snapshotSiacoinOutput
+		err = dec.Decode(&ssco)
+		if err != nil {
+			return changeEntry{}, err
+		}
+		addSiacoinOutput(tx, ssco.ID, ssco.Output)
+	}
+	for i := uint64(0); i < header.NumFileContracts; i++ {
+		var sfc snapshotFileContract
+		err = dec.Decode(&sfc)
+		if err != nil {
+			return changeEntry{}, err
+		}
+		addFileContract(tx, sfc.ID, sfc.FileContract)
+	}
+	for i := uint64(0); i < header.NumSiafundOutputs; i++ {
+		var ssfo snapshotSiafundOutput
+		err = dec.Decode(&ssfo)
+		if err != nil {
+			return changeEntry{}, err
+		}
+		addSiafundOutput(tx, ssfo.ID, ssfo.Output)
+	}
+	for i := uint64(0); i < header.NumDelayedOutputs; i++ {
+		var sdo snapshotDelayedOutput
+		err = dec.Decode(&sdo)
+		if err != nil {
+			return changeEntry{}, err
+		}
+		if tx.Bucket(append(prefixDSCO, encoding.Marshal(sdo.MaturityHeight)...)) == nil {
+			createDSCOBucket(tx, sdo.MaturityHeight)
+		}
+		addDSCO(tx, sdo.MaturityHeight, sdo.ID, sdo.Output)
+	}
+
+	// The outputs and contracts were added without diffs, so the statistics
+	// need to be recomputed.
+	err = resetStats(tx)
+	if err != nil {
+		return changeEntry{}, err
+	}
+
+	// Subscribers learn about the snapshot through a single change that
+	// applies the processed blocks included in the snapshot.
+	if len(ce.AppliedBlocks) == 0 {
+		return changeEntry{}, nil
+	}
+	return ce, appendChangeLog(tx, ce)
+}
+
+// ExportSnapshot writes a snapshot of the consensus state at the current block
+// to w. The snapshot ends with a hash of its contents, which is checked when
+// the snapshot is imported.
+func (cs *ConsensusSet) ExportSnapshot(w io.Writer) error {
+	err := cs.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+
+	h := crypto.NewHash()
+	enc := encoding.NewEncoder(io.MultiWriter(w, h))
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		return cs.writeSnapshot(tx, enc)
+	})
+	if err != nil {
+		return err
+	}
+	var checksum crypto.Hash
+	copy(checksum[:], h.Sum(nil))
+	return encoding.NewEncoder(w).Encode(checksum)
+}
+
+// ImportSnapshot replaces the consensus state with the snapshot read from r.
+// The consensus set must not have accepted any blocks beyond the genesis
+// block. After the import, the consensus set can be synced forward from the
+// snapshot block. Reorgs that reach below the oldest block included in the
+// snapshot are not possible, so snapshots should only be imported from a
+// trusted source.
+func (cs *ConsensusSet) ImportSnapshot(r io.Reader) error {
+	err := cs.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	h := crypto.NewHash()
+	tr := io.TeeReader(r, h)
+	var ce changeEntry
+	err = cs.db.Update(func(tx *bolt.Tx) error {
+		if blockHeight(tx) != 0 {
+			return errSnapshotNotFresh
+		}
+		var err error
+		ce, err = cs.readSnapshot(tx, encoding.NewDecoder(tr))
+		if err != nil {
+			return err
+		}
+
+		// Check the hash of the snapshot before committing.
+		var expected, checksum crypto.Hash
+		err = encoding.NewDecoder(r).Decode(&expected)
+		if err != nil {
+			return err
+		}
+		copy(checksum[:], h.Sum(nil))
+		if checksum != expected {
+			return errSnapshotBadHash
+		}
+		if build.DEBUG {
+			cs.checkConsistency(tx)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Send the snapshot to the subscribers.
+	if len(ce.AppliedBlocks) != 0 {
+		cs.updateSubscribers(ce)
+	}
+	return cs.db.View(func(tx *bolt.Tx) error {
+		cs.publish(tx)
+		return nil
+	})
+}
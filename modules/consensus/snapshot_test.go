@@ -0,0 +1,89 @@
+package consensus
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestSnapshotExportImport exports a snapshot from one consensus set, imports
+// it into a fresh consensus set, and checks that the fresh consensus set can
+// continue to sync from the snapshot block.
+func TestSnapshotExportImport(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+	cst2, err := blankConsensusSetTester(t.Name() + "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst2.Close()
+
+	// Create some transactions and file contracts so that every part of the
+	// snapshot is populated. The tester already holds siafunds.
+	cst.testSpendSiacoinsBlock()
+	cst.testFileContractRevision()
+
+	var buf bytes.Buffer
+	err = cst.cs.ExportSnapshot(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot := buf.Bytes()
+
+	// A corrupted snapshot should be rejected without modifying the
+	// consensus set.
+	corrupted := append([]byte(nil), snapshot...)
+	corrupted[len(corrupted)/2] ^= 1
+	err = cst2.cs.ImportSnapshot(bytes.NewReader(corrupted))
+	if err == nil {
+		t.Fatal("corrupted snapshot was imported")
+	}
+	if cst2.cs.Height() != 0 {
+		t.Fatal("failed import modified the consensus set")
+	}
+
+	// Import the real snapshot.
+	err = cst2.cs.ImportSnapshot(bytes.NewReader(snapshot))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cst2.cs.CurrentBlock().ID() != cst.cs.CurrentBlock().ID() {
+		t.Fatal("snapshot import did not move the consensus set to the snapshot block")
+	}
+	if cst2.cs.dbConsensusChecksum() != cst.cs.dbConsensusChecksum() {
+		t.Fatal("consensus checksums do not match after snapshot import")
+	}
+
+	// Importing a second time should fail, as the consensus set is no longer
+	// at the genesis block.
+	err = cst2.cs.ImportSnapshot(bytes.NewReader(snapshot))
+	if err != errSnapshotNotFresh {
+		t.Fatal("expected errSnapshotNotFresh, got", err)
+	}
+
+	// Mine more blocks on the original consensus set and give them to the
+	// imported consensus set.
+	var blocks []types.Block
+	for i := 0; i < 3; i++ {
+		b, err := cst.miner.AddBlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+		blocks = append(blocks, b)
+	}
+	err = cst2.cs.AcceptBlocks(blocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cst2.cs.dbConsensusChecksum() != cst.cs.dbConsensusChecksum() {
+		t.Fatal("consensus checksums do not match after syncing past the snapshot")
+	}
+}
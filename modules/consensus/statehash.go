@@ -0,0 +1,140 @@
+package consensus
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/NebulousLabs/bolt"
+)
+
+// statehash.go implements a deterministic hash of the consensus state. Two
+// nodes that agree on consensus will compute identical state hashes at every
+// height of the current path, so operators can compare state hashes
+// out-of-band to detect consensus divergence early.
+
+var (
+	// maxStateHashDepth is the number of blocks below the current height that
+	// a historic state hash can be computed at. Computing a historic state
+	// hash reverts blocks while holding the consensus lock, so the depth is
+	// capped to keep the lock from being held for long.
+	maxStateHashDepth = build.Select(build.Var{
+		Standard: types.BlockHeight(144),
+		Dev:      types.BlockHeight(144),
+		Testing:  types.BlockHeight(10),
+	}).(types.BlockHeight)
+
+	errStateHashDepth  = errors.New("requested height is too far below the current height")
+	errStateHashHeight = errors.New("requested height is above the current height")
+	errStateHashPruned = errors.New("the blocks needed to compute the requested state hash have been pruned")
+
+	// errStateHashRollback is returned from inside the database transaction
+	// that computes a historic state hash, causing the transaction to be
+	// rolled back.
+	errStateHashRollback = errors.New("rolling back state hash transaction")
+)
+
+// stateHash returns the merkle root of every siacoin output, file contract,
+// siafund output and delayed siacoin output in the consensus set, along with
+// the siafund pool. Each element is pushed into the tree as its key followed
+// by its value. Buckets are iterated in byte order, making the hash
+// independent of the order in which the state was built.
+func stateHash(tx *bolt.Tx) crypto.Hash {
+	tree := crypto.NewTree()
+	for _, bucket := range [][]byte{SiacoinOutputs, FileContracts, SiafundOutputs, SiafundPool} {
+		err := tx.Bucket(bucket).ForEach(func(k, v []byte) error {
+			tree.Push(k)
+			tree.Push(v)
+			return nil
+		})
+		if err != nil {
+			manageErr(tx, err)
+		}
+	}
+	err := tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+		if !bytes.HasPrefix(name, prefixDSCO) {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			tree.Push(k)
+			tree.Push(v)
+			return nil
+		})
+	})
+	if err != nil {
+		manageErr(tx, err)
+	}
+	return tree.Root()
+}
+
+// StateHash returns a deterministic hash of the current consensus state.
+func (cs *ConsensusSet) StateHash() (hash crypto.Hash) {
+	err := cs.tg.Add()
+	if err != nil {
+		return crypto.Hash{}
+	}
+	defer cs.tg.Done()
+
+	_ = cs.db.View(func(tx *bolt.Tx) error {
+		hash = stateHash(tx)
+		return nil
+	})
+	return hash
+}
+
+// StateHashAtHeight returns the id of the block at the given height of the
+// current path, along with the state hash of the consensus set at that block.
+// Historic state hashes are computed by reverting blocks inside a database
+// transaction that is then discarded, so the cost grows with the distance
+// from the current height. Heights more than maxStateHashDepth blocks below
+// the current height are rejected.
+func (cs *ConsensusSet) StateHashAtHeight(height types.BlockHeight) (id types.BlockID, hash crypto.Hash, err error) {
+	err = cs.tg.Add()
+	if err != nil {
+		return types.BlockID{}, crypto.Hash{}, err
+	}
+	defer cs.tg.Done()
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	err = cs.db.Update(func(tx *bolt.Tx) error {
+		current := blockHeight(tx)
+		if height > current {
+			return errStateHashHeight
+		}
+		if current-height > maxStateHashDepth {
+			return errStateHashDepth
+		}
+		if cs.prunedHeight != 0 && height <= cs.prunedHeight {
+			return errStateHashPruned
+		}
+		id, err = getPath(tx, height)
+		if err != nil {
+			return err
+		}
+		if height == current {
+			hash = stateHash(tx)
+			return nil
+		}
+
+		// Revert to the requested block, compute the hash, and discard the
+		// changes.
+		pb, err := getBlockMap(tx, id)
+		if err != nil {
+			return err
+		}
+		cs.revertToBlock(tx, pb)
+		hash = stateHash(tx)
+		return errStateHashRollback
+	})
+	if err == errStateHashRollback {
+		err = nil
+	}
+	if err != nil {
+		return types.BlockID{}, crypto.Hash{}, err
+	}
+	return id, hash, nil
+}
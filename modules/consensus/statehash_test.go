@@ -0,0 +1,101 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestStateHash checks that state hashes are deterministic across consensus
+// sets and that historic state hashes match the state hashes that were
+// computed at the time.
+func TestStateHash(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+	cst2, err := blankConsensusSetTester(t.Name() + "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst2.Close()
+
+	// Record the state hash at the current height.
+	height := cst.cs.Height()
+	hash := cst.cs.StateHash()
+	id, hashAtHeight, err := cst.cs.StateHashAtHeight(height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hashAtHeight != hash || id != cst.cs.CurrentBlock().ID() {
+		t.Fatal("StateHashAtHeight does not match StateHash at the current height")
+	}
+
+	// Change the state and check that the historic state hash is unchanged.
+	cst.testSpendSiacoinsBlock()
+	for i := 0; i < 3; i++ {
+		_, err = cst.miner.AddBlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if cst.cs.StateHash() == hash {
+		t.Fatal("state hash did not change after the state changed")
+	}
+	checksum := cst.cs.dbConsensusChecksum()
+	_, hashAtHeight, err = cst.cs.StateHashAtHeight(height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hashAtHeight != hash {
+		t.Fatal("historic state hash does not match the state hash computed at the time")
+	}
+	if cst.cs.dbConsensusChecksum() != checksum {
+		t.Fatal("computing a historic state hash modified the consensus set")
+	}
+
+	// A second consensus set with the same blocks should compute the same
+	// state hashes.
+	var blocks []types.Block
+	for h := types.BlockHeight(1); h <= cst.cs.Height(); h++ {
+		b, _ := cst.cs.BlockAtHeight(h)
+		blocks = append(blocks, b)
+	}
+	err = cst2.cs.AcceptBlocks(blocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cst2.cs.StateHash() != cst.cs.StateHash() {
+		t.Fatal("consensus sets with the same blocks have different state hashes")
+	}
+	_, hashAtHeight, err = cst2.cs.StateHashAtHeight(height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hashAtHeight != hash {
+		t.Fatal("consensus sets with the same blocks have different historic state hashes")
+	}
+
+	// Heights above the current height should be rejected.
+	_, _, err = cst.cs.StateHashAtHeight(cst.cs.Height() + 1)
+	if err != errStateHashHeight {
+		t.Fatal("expected errStateHashHeight, got", err)
+	}
+
+	// Heights too far below the current height should be rejected.
+	for cst.cs.Height() <= maxStateHashDepth {
+		_, err = cst.miner.AddBlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, _, err = cst.cs.StateHashAtHeight(cst.cs.Height() - maxStateHashDepth - 1)
+	if err != errStateHashDepth {
+		t.Fatal("expected errStateHashDepth, got", err)
+	}
+}
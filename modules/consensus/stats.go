@@ -0,0 +1,201 @@
+package consensus
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/NebulousLabs/bolt"
+)
+
+// stats.go maintains aggregate statistics about the consensus state. The
+// statistics are updated as siacoin output, file contract and siafund output
+// diffs are committed, inside of the same transaction, so they follow reorgs
+// and are never out of sync with the state they describe.
+
+var (
+	errStatsMismatch = errors.New("consensus statistics do not match the consensus state")
+
+	// statsDustThreshold is the value below which a siacoin output is counted
+	// as dust. It matches the dust value of the wallet.
+	statsDustThreshold = types.SiacoinPrecision
+)
+
+// getStats returns the statistics stored in the database.
+func getStats(tx *bolt.Tx) (stats modules.ConsensusStats) {
+	err := encoding.Unmarshal(tx.Bucket(Stats).Get(FieldStats), &stats)
+	if build.DEBUG && err != nil {
+		panic(err)
+	}
+	return stats
+}
+
+// setStats stores the statistics in the database.
+func setStats(tx *bolt.Tx, stats modules.ConsensusStats) {
+	err := tx.Bucket(Stats).Put(FieldStats, encoding.Marshal(stats))
+	if build.DEBUG && err != nil {
+		panic(err)
+	}
+}
+
+// updateStats applies 'update' to the stored statistics. Nothing is done if
+// the Stats bucket does not exist yet, as the statistics are computed from
+// scratch when the bucket is created.
+func updateStats(tx *bolt.Tx, update func(*modules.ConsensusStats)) {
+	if tx.Bucket(Stats) == nil {
+		return
+	}
+	stats := getStats(tx)
+	update(&stats)
+	setStats(tx, stats)
+}
+
+// updateSiacoinOutputStats counts a siacoin output that is being added or
+// removed.
+func updateSiacoinOutputStats(tx *bolt.Tx, sco types.SiacoinOutput, add bool) {
+	updateStats(tx, func(stats *modules.ConsensusStats) {
+		dust := sco.Value.Cmp(statsDustThreshold) < 0
+		if add {
+			stats.SiacoinOutputs++
+			if dust {
+				stats.DustOutputs++
+			}
+		} else {
+			stats.SiacoinOutputs--
+			if dust {
+				stats.DustOutputs--
+			}
+		}
+	})
+}
+
+// updateFileContractStats counts a file contract that is being added or
+// removed.
+func updateFileContractStats(tx *bolt.Tx, fc types.FileContract, add bool) {
+	updateStats(tx, func(stats *modules.ConsensusStats) {
+		if add {
+			stats.FileContracts++
+			stats.LockedCoins = stats.LockedCoins.Add(fc.Payout)
+		} else {
+			stats.FileContracts--
+			stats.LockedCoins = stats.LockedCoins.Sub(fc.Payout)
+		}
+	})
+}
+
+// updateSiafundOutputStats counts a siafund output that is being added or
+// removed.
+func updateSiafundOutputStats(tx *bolt.Tx, add bool) {
+	updateStats(tx, func(stats *modules.ConsensusStats) {
+		if add {
+			stats.SiafundOutputs++
+		} else {
+			stats.SiafundOutputs--
+		}
+	})
+}
+
+// computeStats computes the statistics by scanning the consensus state.
+func computeStats(tx *bolt.Tx) (stats modules.ConsensusStats, err error) {
+	err = tx.Bucket(SiacoinOutputs).ForEach(func(_, v []byte) error {
+		var sco types.SiacoinOutput
+		err := encoding.Unmarshal(v, &sco)
+		if err != nil {
+			return err
+		}
+		stats.SiacoinOutputs++
+		if sco.Value.Cmp(statsDustThreshold) < 0 {
+			stats.DustOutputs++
+		}
+		return nil
+	})
+	if err != nil {
+		return modules.ConsensusStats{}, err
+	}
+	err = tx.Bucket(FileContracts).ForEach(func(_, v []byte) error {
+		var fc types.FileContract
+		err := encoding.Unmarshal(v, &fc)
+		if err != nil {
+			return err
+		}
+		stats.FileContracts++
+		stats.LockedCoins = stats.LockedCoins.Add(fc.Payout)
+		return nil
+	})
+	if err != nil {
+		return modules.ConsensusStats{}, err
+	}
+	err = tx.Bucket(SiafundOutputs).ForEach(func(_, _ []byte) error {
+		stats.SiafundOutputs++
+		return nil
+	})
+	if err != nil {
+		return modules.ConsensusStats{}, err
+	}
+	return stats, nil
+}
+
+// initStats creates the Stats bucket and computes the statistics if the
+// bucket does not exist. Older databases do not have the bucket.
+func initStats(tx *bolt.Tx) error {
+	if tx.Bucket(Stats) != nil {
+		return nil
+	}
+	_, err := tx.CreateBucket(Stats)
+	if err != nil {
+		return err
+	}
+	return resetStats(tx)
+}
+
+// resetStats recomputes the stored statistics from the consensus state. It is
+// used after the consensus state has been replaced without committing diffs.
+func resetStats(tx *bolt.Tx) error {
+	stats, err := computeStats(tx)
+	if err != nil {
+		return err
+	}
+	setStats(tx, stats)
+	return nil
+}
+
+// checkStats checks that the stored statistics match the consensus state.
+func checkStats(tx *bolt.Tx) {
+	if tx.Bucket(Stats) == nil {
+		return
+	}
+	expected, err := computeStats(tx)
+	if err != nil {
+		manageErr(tx, err)
+	}
+	stats := getStats(tx)
+	if stats.SiacoinOutputs != expected.SiacoinOutputs || stats.DustOutputs != expected.DustOutputs ||
+		stats.SiafundOutputs != expected.SiafundOutputs || stats.FileContracts != expected.FileContracts ||
+		!stats.LockedCoins.Equals(expected.LockedCoins) {
+		manageErr(tx, errStatsMismatch)
+	}
+}
+
+// Stats returns aggregate statistics about the current consensus state.
+func (cs *ConsensusSet) Stats() (stats modules.ConsensusStats) {
+	// A call to a closed database can cause undefined behavior.
+	err := cs.tg.Add()
+	if err != nil {
+		return modules.ConsensusStats{}
+	}
+	defer cs.tg.Done()
+
+	_ = cs.db.View(func(tx *bolt.Tx) error {
+		// A database opened read-only may not have the Stats bucket.
+		if tx.Bucket(Stats) == nil {
+			stats, err = computeStats(tx)
+			return err
+		}
+		stats = getStats(tx)
+		return nil
+	})
+	return stats
+}
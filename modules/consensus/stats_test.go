@@ -0,0 +1,95 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+
+	"github.com/NebulousLabs/bolt"
+)
+
+// dbComputeStats is a convenience function allowing computeStats to be called
+// without a bolt.Tx.
+func (cs *ConsensusSet) dbComputeStats() (stats modules.ConsensusStats) {
+	err := cs.db.View(func(tx *bolt.Tx) error {
+		var err error
+		stats, err = computeStats(tx)
+		return err
+	})
+	if err != nil {
+		panic(err)
+	}
+	return stats
+}
+
+// checkStatsMatch fails the test if the stats of the consensus set do not
+// match the stats computed by scanning the consensus state.
+func checkStatsMatch(t *testing.T, cs *ConsensusSet) {
+	stats, expected := cs.Stats(), cs.dbComputeStats()
+	if stats.SiacoinOutputs != expected.SiacoinOutputs || stats.DustOutputs != expected.DustOutputs ||
+		stats.SiafundOutputs != expected.SiafundOutputs || stats.FileContracts != expected.FileContracts ||
+		!stats.LockedCoins.Equals(expected.LockedCoins) {
+		t.Fatalf("stats do not match the consensus state: %v, expected %v", stats, expected)
+	}
+}
+
+// TestStats checks that the consensus stats are kept up to date as blocks are
+// applied and reverted.
+func TestStats(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	rs := createReorgSets(t.Name())
+	defer rs.Close()
+
+	checkStatsMatch(t, rs.cstMain.cs)
+	stats := rs.cstMain.cs.Stats()
+	if stats.SiacoinOutputs == 0 || stats.SiafundOutputs == 0 {
+		t.Fatal("stats are missing the outputs of the tester:", stats)
+	}
+
+	// Spend siacoins and create and resolve file contracts.
+	rs.cstMain.testSpendSiacoinsBlock()
+	rs.cstMain.testValidStorageProofBlocks()
+	rs.cstMain.testMissedStorageProofBlocks()
+	checkStatsMatch(t, rs.cstMain.cs)
+
+	// Reorg the blocks out of the consensus set and back in.
+	rs.save()
+	rs.extend()
+	checkStatsMatch(t, rs.cstMain.cs)
+	rs.restore()
+	checkStatsMatch(t, rs.cstMain.cs)
+}
+
+// TestInitStats checks that the stats are computed for a database that does
+// not have them yet.
+func TestInitStats(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	expected := cst.cs.Stats()
+	err = cst.cs.db.Update(func(tx *bolt.Tx) error {
+		err := tx.DeleteBucket(Stats)
+		if err != nil {
+			return err
+		}
+		return initStats(tx)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := cst.cs.Stats()
+	if stats.SiacoinOutputs != expected.SiacoinOutputs || stats.SiafundOutputs != expected.SiafundOutputs {
+		t.Fatal("stats were not recomputed:", stats, expected)
+	}
+	checkStatsMatch(t, cst.cs)
+}
@@ -15,7 +15,9 @@ func (cs *ConsensusSet) computeConsensusChange(tx *bolt.Tx, ce changeEntry) (mod
 	}
 	for _, revertedBlockID := range ce.RevertedBlocks {
 		revertedBlock, err := getBlockMap(tx, revertedBlockID)
-		if err != nil {
+		if err != nil && cs.prunedHeight != 0 {
+			return modules.ConsensusChange{}, modules.ErrPrunedConsensusChange
+		} else if err != nil {
 			cs.log.Critical("getBlockMap failed in computeConsensusChange:", err)
 			return modules.ConsensusChange{}, err
 		}
@@ -51,7 +53,9 @@ func (cs *ConsensusSet) computeConsensusChange(tx *bolt.Tx, ce changeEntry) (mod
 	}
 	for _, appliedBlockID := range ce.AppliedBlocks {
 		appliedBlock, err := getBlockMap(tx, appliedBlockID)
-		if err != nil {
+		if err != nil && cs.prunedHeight != 0 {
+			return modules.ConsensusChange{}, modules.ErrPrunedConsensusChange
+		} else if err != nil {
 			cs.log.Critical("getBlockMap failed in computeConsensusChange:", err)
 			return modules.ConsensusChange{}, err
 		}
@@ -100,10 +104,15 @@ func (cs *ConsensusSet) computeConsensusChange(tx *bolt.Tx, ce changeEntry) (mod
 func (cs *ConsensusSet) updateSubscribers(ce changeEntry) {
 	// Get the consensus change and send it to all subscribers.
 	var cc modules.ConsensusChange
+	var ceu modules.ContractEventUpdate
 	err := cs.db.View(func(tx *bolt.Tx) error {
 		// Compute the consensus change so it can be sent to subscribers.
 		var err error
 		cc, err = cs.computeConsensusChange(tx, ce)
+		if err != nil || len(cs.contractSubscribers) == 0 {
+			return err
+		}
+		ceu, err = cs.computeContractEvents(tx, ce)
 		return err
 	})
 	if err != nil {
@@ -113,14 +122,18 @@ func (cs *ConsensusSet) updateSubscribers(ce changeEntry) {
 	for _, subscriber := range cs.subscribers {
 		subscriber.ProcessConsensusChange(cc)
 	}
+	for _, subscriber := range cs.contractSubscribers {
+		subscriber.ProcessContractEvents(ceu)
+	}
 }
 
-// managedInitializeSubscribe will take a subscriber and feed them all of the
-// consensus changes that have occurred since the change provided.
+// managedInitializeSubscribe will call 'process' on every change entry that
+// has occurred since the change provided, so that a subscriber can be fed all
+// of the changes it is missing.
 //
 // As a special case, using an empty id as the start will have all the changes
 // sent to the modules starting with the genesis block.
-func (cs *ConsensusSet) managedInitializeSubscribe(subscriber modules.ConsensusSetSubscriber, start modules.ConsensusChangeID) error {
+func (cs *ConsensusSet) managedInitializeSubscribe(start modules.ConsensusChangeID, process func(*bolt.Tx, changeEntry) error) error {
 	if start == modules.ConsensusChangeRecent {
 		return nil
 	}
@@ -139,6 +152,11 @@ func (cs *ConsensusSet) managedInitializeSubscribe(subscriber modules.ConsensusS
 			// the genesis block.
 			entry = cs.genesisEntry()
 			exists = true
+			// A pruned consensus set no longer has the blocks that follow
+			// the genesis block.
+			if err := cs.checkPrunedEntry(tx, entry); err != nil {
+				return err
+			}
 		} else {
 			// The subscriber has provided an existing consensus change.
 			// Because the subscriber already has this consensus change,
@@ -153,6 +171,11 @@ func (cs *ConsensusSet) managedInitializeSubscribe(subscriber modules.ConsensusS
 				// perform a rescan of the consensus set.
 				return modules.ErrInvalidConsensusChangeID
 			}
+			// The changes that follow the entry cannot be computed if their
+			// blocks have been pruned.
+			if err := cs.checkPrunedEntry(tx, entry); err != nil {
+				return err
+			}
 			entry, exists = entry.NextEntry(tx)
 		}
 		return nil
@@ -169,11 +192,10 @@ func (cs *ConsensusSet) managedInitializeSubscribe(subscriber modules.ConsensusS
 		cs.mu.RLock()
 		err = cs.db.View(func(tx *bolt.Tx) error {
 			for i := 0; i < 100 && exists; i++ {
-				cc, err := cs.computeConsensusChange(tx, entry)
+				err := process(tx, entry)
 				if err != nil {
 					return err
 				}
-				subscriber.ProcessConsensusChange(cc)
 				entry, exists = entry.NextEntry(tx)
 			}
 			return nil
@@ -200,7 +222,14 @@ func (cs *ConsensusSet) ConsensusSetSubscribe(subscriber modules.ConsensusSetSub
 	defer cs.tg.Done()
 
 	// Get the input module caught up to the current consensus set.
-	err = cs.managedInitializeSubscribe(subscriber, start)
+	err = cs.managedInitializeSubscribe(start, func(tx *bolt.Tx, ce changeEntry) error {
+		cc, err := cs.computeConsensusChange(tx, ce)
+		if err != nil {
+			return err
+		}
+		subscriber.ProcessConsensusChange(cc)
+		return nil
+	})
 	if err != nil {
 		return err
 	}
@@ -238,3 +267,59 @@ func (cs *ConsensusSet) Unsubscribe(subscriber modules.ConsensusSetSubscriber) {
 		}
 	}
 }
+
+// ContractEventSubscribe adds a subscriber to the list of contract event
+// subscribers, and gives them the contract events of every consensus change
+// that has occurred since the change with the provided id.
+//
+// As a special case, using an empty id as the start will have all the events
+// sent to the modules starting with the genesis block.
+func (cs *ConsensusSet) ContractEventSubscribe(subscriber modules.ContractEventSubscriber, start modules.ConsensusChangeID) error {
+	err := cs.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+
+	// Get the input module caught up to the current consensus set.
+	err = cs.managedInitializeSubscribe(start, func(tx *bolt.Tx, ce changeEntry) error {
+		ceu, err := cs.computeContractEvents(tx, ce)
+		if err != nil {
+			return err
+		}
+		subscriber.ProcessContractEvents(ceu)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Add the module to the list of contract event subscribers.
+	cs.mu.Lock()
+	for _, s := range cs.contractSubscribers {
+		if s == subscriber {
+			build.Critical("refusing to double-subscribe contract event subscriber")
+		}
+	}
+	cs.contractSubscribers = append(cs.contractSubscribers, subscriber)
+	cs.mu.Unlock()
+	return nil
+}
+
+// ContractEventUnsubscribe removes a subscriber from the list of contract
+// event subscribers. If the subscriber is not found, no action is taken.
+func (cs *ConsensusSet) ContractEventUnsubscribe(subscriber modules.ContractEventSubscriber) {
+	if cs.tg.Add() != nil {
+		return
+	}
+	defer cs.tg.Done()
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	for i := range cs.contractSubscribers {
+		if cs.contractSubscribers[i] == subscriber {
+			cs.contractSubscribers = append(cs.contractSubscribers[0:i], cs.contractSubscribers[i+1:]...)
+			break
+		}
+	}
+}
@@ -72,7 +72,6 @@ var (
 	}).(time.Duration)
 
 	errEarlyStop         = errors.New("initial blockchain download did not complete by the time shutdown was issued")
-	errNilProcBlock      = errors.New("nil processed block was fetched from the database")
 	errSendBlocksStalled = errors.New("SendBlocks RPC timed and never received any blocks")
 )
 
@@ -190,7 +189,7 @@ func (cs *ConsensusSet) managedReceiveBlocks(conn modules.PeerConn) (returnErr e
 				panic("blockchain extension reporting is incorrect")
 			}
 			fullBlock := cs.managedCurrentBlock() // TODO: Add cacheing, replace this line by looking at the cache.
-			go cs.gateway.Broadcast("RelayHeader", fullBlock.Header(), cs.gateway.Peers())
+			cs.managedBroadcastBlock(fullBlock)
 		}
 	}()
 
@@ -310,6 +309,12 @@ func (cs *ConsensusSet) rpcSendBlocks(conn modules.PeerConn) error {
 			start = pb.Height + 1
 			break
 		}
+		// A pruned consensus set cannot send the blocks that it has
+		// deleted. The caller needs to synchronize from a peer that has kept
+		// them.
+		if found && cs.isPruned(start) {
+			return errPrunedBlocks
+		}
 		return nil
 	})
 	cs.mu.RUnlock()
@@ -338,21 +343,20 @@ func (cs *ConsensusSet) rpcSendBlocks(conn modules.PeerConn) error {
 		err = cs.db.View(func(tx *bolt.Tx) error {
 			height := blockHeight(tx)
 			for i := start; i <= height && i < start+MaxCatchUpBlocks; i++ {
+				if cs.isPruned(i) {
+					return errPrunedBlocks
+				}
 				id, err := getPath(tx, i)
 				if err != nil {
 					cs.log.Critical("Unable to get path: height", height, ":: request", i)
 					return err
 				}
-				pb, err := getBlockMap(tx, id)
+				b, err := cs.getBlock(tx, id)
 				if err != nil {
 					cs.log.Critical("Unable to get block from block map: height", height, ":: request", i, ":: id", id)
 					return err
 				}
-				if pb == nil {
-					cs.log.Critical("getBlockMap yielded 'nil' block:", height, ":: request", i, ":: id", id)
-					return errNilProcBlock
-				}
-				blocks = append(blocks, pb.Block)
+				blocks = append(blocks, b)
 			}
 			moreAvailable = start+MaxCatchUpBlocks <= height
 			start += MaxCatchUpBlocks
@@ -410,6 +414,24 @@ func (cs *ConsensusSet) threadedRPCRelayHeader(conn modules.PeerConn) error {
 		return err
 	}
 
+	// A headers-only consensus set adds the header to its header chain, and
+	// fetches the missing headers if the header is an orphan.
+	if cs.headersOnly {
+		_, err = cs.managedAcceptHeaders([]types.BlockHeader{h})
+		if err == errOrphan {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := cs.gateway.RPC(conn.RPCAddr(), "SendHeaders", cs.managedReceiveHeaders)
+				if err != nil {
+					cs.log.Debugln("WARN: failed to get parents of orphan header:", err)
+				}
+			}()
+			return nil
+		}
+		return err
+	}
+
 	// Start verification inside of a bolt View tx.
 	cs.mu.RLock()
 	err = cs.db.View(func(tx *bolt.Tx) error {
@@ -493,12 +515,8 @@ func (cs *ConsensusSet) rpcSendBlk(conn modules.PeerConn) error {
 	var b types.Block
 	cs.mu.RLock()
 	err = cs.db.View(func(tx *bolt.Tx) error {
-		pb, err := getBlockMap(tx, id)
-		if err != nil {
-			return err
-		}
-		b = pb.Block
-		return nil
+		b, err = cs.getBlock(tx, id)
+		return err
 	})
 	cs.mu.RUnlock()
 	if err != nil {
@@ -528,6 +546,12 @@ func (cs *ConsensusSet) managedReceiveBlock(id types.BlockID) modules.RPCFunc {
 		if chainExtended {
 			cs.managedBroadcastBlock(block)
 		}
+		if err == errOrphan {
+			// The block has been added to the orphan pool. Fetch its parents,
+			// starting with the peer that sent it, in a separate goroutine
+			// because this function is called from within a gateway RPC.
+			go cs.threadedFetchOrphanParents(conn.RPCAddr())
+		}
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,116 @@
+package consensus
+
+import (
+	"math/big"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/NebulousLabs/bolt"
+)
+
+// target.go exposes the difficulty history of the current path and a forecast
+// of the next difficulty adjustment, so that miners and dashboards do not need
+// to re-implement the adjustment algorithm.
+
+// TargetAtHeight returns the child target of the block at the given height of
+// the current path, which is the target that the block at the next height must
+// meet. False is returned if there is no block at the height.
+func (cs *ConsensusSet) TargetAtHeight(height types.BlockHeight) (target types.Target, exists bool) {
+	// A call to a closed database can cause undefined behavior.
+	err := cs.tg.Add()
+	if err != nil {
+		return types.Target{}, false
+	}
+	defer cs.tg.Done()
+
+	_ = cs.db.View(func(tx *bolt.Tx) error {
+		id, err := getPath(tx, height)
+		if err != nil {
+			return err
+		}
+		pb, err := getBlockMap(tx, id)
+		if err != nil {
+			return err
+		}
+		target = pb.ChildTarget
+		exists = true
+		return nil
+	})
+	return target, exists
+}
+
+// EstimateNextTarget returns the projected child target of the next block,
+// which is the target after the next difficulty adjustment, along with the
+// height of the first block that must meet it.
+//
+// Since the oak hardfork, the difficulty is adjusted every block and the child
+// target of the next block depends only on the current block, so the estimate
+// is exact. Before the hardfork, the difficulty is adjusted every
+// TargetWindow/2 blocks, and the estimate assumes that blocks keep arriving at
+// the average rate of the most recent TargetWindow blocks until the
+// adjustment.
+func (cs *ConsensusSet) EstimateNextTarget() (target types.Target, height types.BlockHeight) {
+	// A call to a closed database can cause undefined behavior.
+	err := cs.tg.Add()
+	if err != nil {
+		return types.Target{}, 0
+	}
+	defer cs.tg.Done()
+
+	_ = cs.db.View(func(tx *bolt.Tx) error {
+		pb := currentProcessedBlock(tx)
+		if pb.Height >= types.OakHardforkBlock {
+			totalTime, totalTarget := cs.getBlockTotals(tx, pb.Block.ID())
+			target = cs.childTargetOak(totalTime, totalTarget, pb.ChildTarget, pb.Height)
+			height = pb.Height + 2
+			return nil
+		}
+		target, height = estimatePreOakTarget(tx, pb)
+		return nil
+	})
+	return target, height
+}
+
+// estimatePreOakTarget projects the child target of the next block at which
+// the pre-oak difficulty adjustment is applied, assuming that blocks keep
+// arriving at the average rate of the most recent TargetWindow blocks.
+func estimatePreOakTarget(tx *bolt.Tx, pb *processedBlock) (types.Target, types.BlockHeight) {
+	timestampAt := func(height types.BlockHeight) types.Timestamp {
+		id, err := getPath(tx, height)
+		if build.DEBUG && err != nil {
+			panic(err)
+		}
+		b, err := getBlockMap(tx, id)
+		if build.DEBUG && err != nil {
+			panic(err)
+		}
+		return b.Block.Timestamp
+	}
+
+	// Measure the average block time over the most recent blocks.
+	blockTime := int64(types.BlockFrequency)
+	window := types.TargetWindow
+	if pb.Height < window {
+		window = pb.Height
+	}
+	if window > 0 {
+		elapsed := int64(pb.Block.Timestamp) - int64(timestampAt(pb.Height-window))
+		blockTime = elapsed / int64(window)
+	}
+
+	// Project the timestamp of the block at the next adjustment height, and
+	// compute the adjustment that it would cause.
+	interval := types.TargetWindow / 2
+	adjustHeight := (pb.Height/interval + 1) * interval
+	projected := int64(pb.Block.Timestamp) + blockTime*int64(adjustHeight-pb.Height)
+	windowSize := types.TargetWindow
+	if adjustHeight < windowSize {
+		windowSize = adjustHeight
+	}
+	timePassed := projected - int64(timestampAt(adjustHeight-windowSize))
+	expectedTimePassed := int64(types.BlockFrequency * windowSize)
+	adjustment := clampTargetAdjustment(big.NewRat(timePassed, expectedTimePassed))
+	target := types.RatToTarget(new(big.Rat).Mul(pb.ChildTarget.Rat(), adjustment))
+	return target, adjustHeight + 1
+}
@@ -0,0 +1,82 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestTargetAtHeight checks that TargetAtHeight matches the child targets of
+// the blocks in the current path.
+func TestTargetAtHeight(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	height := cst.cs.Height()
+	for h := types.BlockHeight(0); h <= height; h++ {
+		b, _ := cst.cs.BlockAtHeight(h)
+		expected, _ := cst.cs.ChildTarget(b.ID())
+		target, exists := cst.cs.TargetAtHeight(h)
+		if !exists || target != expected {
+			t.Fatal("wrong target at height", h)
+		}
+	}
+	if _, exists := cst.cs.TargetAtHeight(height + 1); exists {
+		t.Fatal("target returned for a height above the current height")
+	}
+}
+
+// TestEstimateNextTarget checks that the estimate of the next target is exact
+// after the oak hardfork, and is a valid adjustment of the current target
+// before it.
+func TestEstimateNextTarget(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := blankConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// Before the hardfork, the estimate applies after the next adjustment
+	// height and is clamped relative to the current target.
+	target, height := cst.cs.EstimateNextTarget()
+	if height != types.TargetWindow/2+1 {
+		t.Fatal("wrong height for the pre-oak estimate:", height)
+	}
+	current, _ := cst.cs.TargetAtHeight(0)
+	if target.Cmp(current.MulDifficulty(types.MaxAdjustmentUp)) < 0 && target.Cmp(current.MulDifficulty(types.MaxAdjustmentDown)) > 0 {
+		t.Fatal("pre-oak estimate is outside of the adjustment bounds")
+	}
+
+	// Mine past the hardfork and check that the estimate is exact.
+	for cst.cs.Height() <= types.OakHardforkBlock {
+		_, err = cst.miner.AddBlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		target, height = cst.cs.EstimateNextTarget()
+		if height != cst.cs.Height()+2 {
+			t.Fatal("wrong height for the oak estimate:", height)
+		}
+		_, err = cst.miner.AddBlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual, _ := cst.cs.TargetAtHeight(height - 1)
+		if actual != target {
+			t.Fatal("oak estimate does not match the actual target")
+		}
+	}
+}
@@ -3,6 +3,8 @@ package consensus
 import (
 	"errors"
 	"math/big"
+	"runtime"
+	"sync"
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/crypto"
@@ -291,10 +293,49 @@ func validTransaction(tx *bolt.Tx, t types.Transaction) error {
 	if err != nil {
 		return err
 	}
+	return validTransactionState(tx, t)
+}
+
+// validStandaloneTransactions runs the standalone checks of every transaction
+// in txns, spreading the work across one goroutine per CPU. The checks are
+// independent of the consensus state, which means they can be performed in
+// any order, and are dominated by signature verification. The returned slice
+// contains the result for each transaction at the same index as the
+// transaction, so callers can report errors in a deterministic order
+// regardless of the order in which the workers finish.
+func validStandaloneTransactions(txns []types.Transaction, currentHeight types.BlockHeight) []error {
+	errs := make([]error, len(txns))
+	numWorkers := runtime.NumCPU()
+	if numWorkers > len(txns) {
+		numWorkers = len(txns)
+	}
+	if numWorkers <= 1 {
+		for i := range txns {
+			errs[i] = txns[i].StandaloneValid(currentHeight)
+		}
+		return errs
+	}
+
+	// Each worker checks every numWorkers'th transaction. Each index of errs
+	// is written by exactly one worker, so no locking is needed.
+	var wg sync.WaitGroup
+	for w := 0; w < numWorkers; w++ {
+		wg.Add(1)
+		go func(start int) {
+			defer wg.Done()
+			for i := start; i < len(txns); i += numWorkers {
+				errs[i] = txns[i].StandaloneValid(currentHeight)
+			}
+		}(w)
+	}
+	wg.Wait()
+	return errs
+}
 
-	// Check that each portion of the transaction is legal given the current
-	// consensus set.
-	err = validSiacoins(tx, t)
+// validTransactionState checks that each portion of the transaction is legal
+// given the current consensus set.
+func validTransactionState(tx *bolt.Tx, t types.Transaction) error {
+	err := validSiacoins(tx, t)
 	if err != nil {
 		return err
 	}
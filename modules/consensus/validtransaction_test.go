@@ -838,3 +838,33 @@ func TestValidTransaction(t *testing.T) {
 	}
 }
 */
+
+// TestValidStandaloneTransactions checks that the parallel standalone checks
+// return the same errors, in the same order, as checking each transaction
+// serially.
+func TestValidStandaloneTransactions(t *testing.T) {
+	// Create a mix of valid transactions and transactions with frivolous
+	// signatures.
+	txns := make([]types.Transaction, 50)
+	for i := range txns {
+		if fastrand.Intn(2) == 0 {
+			txns[i].TransactionSignatures = []types.TransactionSignature{{}}
+		}
+	}
+
+	errs := validStandaloneTransactions(txns, 0)
+	if len(errs) != len(txns) {
+		t.Fatal("wrong number of results:", len(errs))
+	}
+	for i, txn := range txns {
+		expected := txn.StandaloneValid(0)
+		if errs[i] != expected {
+			t.Errorf("transaction %v: expected %v, got %v", i, expected, errs[i])
+		}
+	}
+
+	// An empty set of transactions should produce an empty set of results.
+	if errs := validStandaloneTransactions(nil, 0); len(errs) != 0 {
+		t.Error("expected no results for no transactions")
+	}
+}
@@ -0,0 +1,156 @@
+package consensus
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/persist"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/NebulousLabs/bolt"
+)
+
+// verify.go implements a full integrity check of the consensus database. Every
+// block in the current path is replayed into a temporary consensus database,
+// which regenerates the diffs of every block from scratch. The regenerated
+// processed blocks and the final consensus state are then compared against
+// the live database. A divergence indicates that the live database has been
+// corrupted, for example by a crash or a failing disk.
+
+var (
+	errVerifyPruned = errors.New("cannot verify a consensus set that has pruned old blocks")
+	errVerifyReorg  = errors.New("current path changed during verification, try again")
+)
+
+// verifyBatchSize is the number of blocks that are replayed at a time while
+// verifying the blockchain.
+const verifyBatchSize = 100
+
+// processedBlocksMatch returns true if two processed blocks have the same
+// height, depth, target and diffs. The consensus checksums are ignored, as
+// they are only computed in debug builds.
+func processedBlocksMatch(a, b *processedBlock) bool {
+	ac, bc := *a, *b
+	ac.ConsensusChecksum = crypto.Hash{}
+	bc.ConsensusChecksum = crypto.Hash{}
+	return bytes.Equal(encoding.Marshal(ac), encoding.Marshal(bc))
+}
+
+// VerifyChain replays every block in the current path from the genesis block
+// and compares the result to the live consensus database, returning an error
+// that describes the first divergence found. On success, the height that was
+// verified is returned. The blocks are read in batches, each in its own short
+// transaction, so verification does not hold the database while it runs.
+// Blocks that are accepted in the meantime are verified as well, but if the
+// replayed blocks are reorganized out of the current path, verification fails
+// with errVerifyReorg and can be retried.
+func (cs *ConsensusSet) VerifyChain() (types.BlockHeight, error) {
+	err := cs.tg.Add()
+	if err != nil {
+		return 0, err
+	}
+	defer cs.tg.Done()
+	if cs.pruneDepth != 0 {
+		return 0, errVerifyPruned
+	}
+
+	// Create a temporary consensus set to replay the blocks into.
+	dir, err := ioutil.TempDir(cs.persistDir, "verify")
+	if err != nil {
+		return 0, err
+	}
+	defer os.RemoveAll(dir)
+	replay := newConsensusSet(nil, dir, Config{})
+	replay.log = persist.NewLogger(ioutil.Discard)
+	err = replay.loadDB()
+	if err != nil {
+		return 0, err
+	}
+	replay.tg.AfterStop(func() {
+		replay.db.Close()
+	})
+	defer replay.Close()
+
+	// Each batch is anchored to the last replayed block, which must still be
+	// in the current path for the batch to extend the replayed chain.
+	var anchorHeight types.BlockHeight
+	anchorID := replay.blockRoot.Block.ID()
+	for {
+		var live []*processedBlock
+		var blocks []types.Block
+		var done bool
+		err = cs.db.View(func(tx *bolt.Tx) error {
+			id, err := getPath(tx, anchorHeight)
+			if err != nil || id != anchorID {
+				return errVerifyReorg
+			}
+			height := blockHeight(tx)
+			if height == anchorHeight {
+				// Every block has been replayed, compare the final consensus
+				// state.
+				done = true
+				return replay.db.View(func(rtx *bolt.Tx) error {
+					if currentBlockID(rtx) != currentBlockID(tx) {
+						return errors.New("replayed blockchain does not end at the current block")
+					}
+					if consensusChecksum(rtx) != consensusChecksum(tx) {
+						return errors.New("consensus state does not match the replayed consensus state")
+					}
+					return nil
+				})
+			}
+
+			// Read the next batch of blocks.
+			for h := anchorHeight + 1; h <= anchorHeight+verifyBatchSize && h <= height; h++ {
+				id, err := getPath(tx, h)
+				if err != nil {
+					return fmt.Errorf("block path is missing height %v: %v", h, err)
+				}
+				pb, err := getBlockMap(tx, id)
+				if err != nil {
+					return fmt.Errorf("block at height %v is missing: %v", h, err)
+				}
+				live = append(live, pb)
+				blocks = append(blocks, pb.Block)
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+		if done {
+			return anchorHeight, nil
+		}
+
+		// Replay the batch.
+		_, err = replay.managedAcceptBlocks(blocks)
+		if err != nil {
+			return 0, fmt.Errorf("blocks starting at height %v failed validation: %v", anchorHeight+1, err)
+		}
+
+		// Compare the regenerated processed blocks to the live processed
+		// blocks.
+		err = replay.db.View(func(rtx *bolt.Tx) error {
+			for _, pb := range live {
+				rpb, err := getBlockMap(rtx, pb.Block.ID())
+				if err != nil {
+					return fmt.Errorf("block at height %v was not replayed: %v", pb.Height, err)
+				}
+				if !processedBlocksMatch(pb, rpb) {
+					return fmt.Errorf("diffs of block at height %v do not match the replayed diffs", pb.Height)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+		last := live[len(live)-1]
+		anchorHeight, anchorID = last.Height, last.Block.ID()
+	}
+}
@@ -0,0 +1,81 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/encoding"
+
+	"github.com/NebulousLabs/bolt"
+)
+
+// TestVerifyChain checks that VerifyChain accepts an intact consensus
+// database and reports a corrupted one.
+func TestVerifyChain(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+	cst.testSpendSiacoinsBlock()
+	cst.testFileContractRevision()
+
+	height, err := cst.cs.VerifyChain()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if height != cst.cs.Height() {
+		t.Fatal("wrong height verified:", height, cst.cs.Height())
+	}
+
+	// Corrupt the diffs of a stored block.
+	err = cst.cs.db.Update(func(tx *bolt.Tx) error {
+		id, err := getPath(tx, 3)
+		if err != nil {
+			return err
+		}
+		pb, err := getBlockMap(tx, id)
+		if err != nil {
+			return err
+		}
+		pb.SiacoinOutputDiffs = pb.SiacoinOutputDiffs[1:]
+		return tx.Bucket(BlockMap).Put(id[:], encoding.Marshal(*pb))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = cst.cs.VerifyChain()
+	if err == nil {
+		t.Fatal("corrupted block diffs were not detected")
+	}
+}
+
+// TestVerifyChainCorruptState checks that VerifyChain reports a corrupted
+// consensus state.
+func TestVerifyChainCorruptState(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// Remove a siacoin output from the consensus state.
+	err = cst.cs.db.Update(func(tx *bolt.Tx) error {
+		k, _ := tx.Bucket(SiacoinOutputs).Cursor().First()
+		return tx.Bucket(SiacoinOutputs).Delete(k)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = cst.cs.VerifyChain()
+	if err == nil {
+		t.Fatal("corrupted consensus state was not detected")
+	}
+}
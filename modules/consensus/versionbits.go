@@ -0,0 +1,247 @@
+package consensus
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/NebulousLabs/bolt"
+)
+
+// versionbits.go tracks the activation state of deployments. The state of
+// every deployment is computed at the end of each window of VersionBitsWindow
+// blocks, from its state during the window and the number of blocks in the
+// window that signaled for it. The states are stored under the id of the last
+// block of the window, so states that were computed for blocks that are later
+// reverted are simply never looked up again.
+
+var (
+	errDeploymentBlocksPruned = errors.New("blocks needed to count deployment signals have been pruned")
+)
+
+type (
+	// deploymentStateEntry is the stored state of a single deployment.
+	deploymentStateEntry struct {
+		Name  string
+		State modules.DeploymentState
+	}
+)
+
+// nextDeploymentState returns the state of a deployment for the window that
+// starts at the given height, given its state during the previous window and
+// the number of blocks in the previous window that signaled for it.
+func nextDeploymentState(d types.Deployment, state modules.DeploymentState, height types.BlockHeight, signals uint64) modules.DeploymentState {
+	switch state {
+	case modules.DeploymentDefined:
+		if height >= d.TimeoutHeight {
+			return modules.DeploymentFailed
+		} else if height >= d.StartHeight {
+			return modules.DeploymentStarted
+		}
+	case modules.DeploymentStarted:
+		if height >= d.TimeoutHeight {
+			return modules.DeploymentFailed
+		} else if signals >= types.VersionBitsThreshold {
+			return modules.DeploymentLockedIn
+		}
+	case modules.DeploymentLockedIn:
+		return modules.DeploymentActive
+	}
+	return state
+}
+
+// pathID returns the id of the block at the given height of the current path.
+// 'tip' is a block that has been added to the path but not yet to the block
+// map, and may be nil.
+func pathID(tx *bolt.Tx, height types.BlockHeight, tip *processedBlock) (types.BlockID, error) {
+	if tip != nil && tip.Height == height {
+		return tip.Block.ID(), nil
+	}
+	return getPath(tx, height)
+}
+
+// pathBlock returns the block at the given height of the current path. 'tip'
+// is handled as in pathID.
+func pathBlock(tx *bolt.Tx, height types.BlockHeight, tip *processedBlock) (types.Block, error) {
+	if tip != nil && tip.Height == height {
+		return tip.Block, nil
+	}
+	id, err := getPath(tx, height)
+	if err != nil {
+		return types.Block{}, err
+	}
+	pb, err := getBlockMap(tx, id)
+	if err != nil {
+		return types.Block{}, errDeploymentBlocksPruned
+	}
+	return pb.Block, nil
+}
+
+// countSignals returns the number of blocks between the start and end heights
+// of the current path, inclusive, that signal for each deployment, indexed by
+// deployment bit.
+func countSignals(tx *bolt.Tx, start, end types.BlockHeight, tip *processedBlock) (counts [64]uint64, err error) {
+	for height := start; height <= end; height++ {
+		b, err := pathBlock(tx, height, tip)
+		if err != nil {
+			return counts, err
+		}
+		bits := b.VersionBits()
+		for _, d := range types.Deployments {
+			if bits&(1<<d.Bit) != 0 {
+				counts[d.Bit]++
+			}
+		}
+	}
+	return counts, nil
+}
+
+// getDeploymentStates returns the stored deployment states for the window
+// following the block with the given id. False is returned if no states are
+// stored, or if the stored states do not include every known deployment.
+func getDeploymentStates(tx *bolt.Tx, id types.BlockID) (map[string]modules.DeploymentState, bool) {
+	bucket := tx.Bucket(DeploymentStates)
+	if bucket == nil {
+		return nil, false
+	}
+	statesBytes := bucket.Get(id[:])
+	if statesBytes == nil {
+		return nil, false
+	}
+	var entries []deploymentStateEntry
+	err := encoding.Unmarshal(statesBytes, &entries)
+	if build.DEBUG && err != nil {
+		panic(err)
+	}
+	states := make(map[string]modules.DeploymentState)
+	for _, entry := range entries {
+		states[entry.Name] = entry.State
+	}
+	for _, d := range types.Deployments {
+		if _, exists := states[d.Name]; !exists {
+			return nil, false
+		}
+	}
+	return states, true
+}
+
+// deploymentStates returns the state of every known deployment for the blocks
+// that follow the block at the given height of the current path. Windows
+// without stored states are computed from the most recent window with stored
+// states.
+func deploymentStates(tx *bolt.Tx, height types.BlockHeight, tip *processedBlock) (map[string]modules.DeploymentState, error) {
+	states := make(map[string]modules.DeploymentState)
+	for _, d := range types.Deployments {
+		states[d.Name] = modules.DeploymentDefined
+	}
+	if len(types.Deployments) == 0 || height+1 < types.VersionBitsWindow {
+		return states, nil
+	}
+
+	// Walk backwards to the most recent window with stored states. Every
+	// deployment is defined during the first window.
+	var pending []types.BlockHeight
+	for end := (height+1)/types.VersionBitsWindow*types.VersionBitsWindow - 1; ; end -= types.VersionBitsWindow {
+		id, err := pathID(tx, end, tip)
+		if err != nil {
+			return nil, err
+		}
+		if stored, exists := getDeploymentStates(tx, id); exists {
+			states = stored
+			break
+		}
+		pending = append(pending, end)
+		if end+1 == types.VersionBitsWindow {
+			break
+		}
+	}
+
+	// Walk forwards, computing the states at the end of each window.
+	for i := len(pending) - 1; i >= 0; i-- {
+		end := pending[i]
+		counts, err := countSignals(tx, end+1-types.VersionBitsWindow, end, tip)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range types.Deployments {
+			states[d.Name] = nextDeploymentState(d, states[d.Name], end+1, counts[d.Bit])
+		}
+	}
+	return states, nil
+}
+
+// initDeploymentStates creates the deployment state bucket if it does not
+// exist.
+func initDeploymentStates(tx *bolt.Tx) error {
+	_, err := tx.CreateBucketIfNotExists(DeploymentStates)
+	return err
+}
+
+// updateDeploymentStates stores the deployment states that follow a block
+// that ends a window. Nothing needs to be done when reverting, because the
+// states are stored by block id.
+func updateDeploymentStates(tx *bolt.Tx, pb *processedBlock, dir modules.DiffDirection) {
+	if dir == modules.DiffRevert || len(types.Deployments) == 0 || (pb.Height+1)%types.VersionBitsWindow != 0 {
+		return
+	}
+	bucket := tx.Bucket(DeploymentStates)
+	if bucket == nil {
+		return
+	}
+	states, err := deploymentStates(tx, pb.Height, pb)
+	if err != nil {
+		// The blocks of the window have been pruned, which can only happen
+		// if the states were never stored. There is nothing to be done.
+		return
+	}
+	entries := make([]deploymentStateEntry, 0, len(types.Deployments))
+	for _, d := range types.Deployments {
+		entries = append(entries, deploymentStateEntry{Name: d.Name, State: states[d.Name]})
+	}
+	id := pb.Block.ID()
+	err = bucket.Put(id[:], encoding.Marshal(entries))
+	if build.DEBUG && err != nil {
+		panic(err)
+	}
+}
+
+// Deployments returns the state of every known deployment for the next block,
+// along with the number of blocks in the current window that signal for it.
+func (cs *ConsensusSet) Deployments() (statuses []modules.DeploymentStatus, err error) {
+	// A call to a closed database can cause undefined behavior.
+	err = cs.tg.Add()
+	if err != nil {
+		return nil, err
+	}
+	defer cs.tg.Done()
+
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		height := blockHeight(tx)
+		states, err := deploymentStates(tx, height, nil)
+		if err != nil {
+			return err
+		}
+		var counts [64]uint64
+		if start := (height + 1) / types.VersionBitsWindow * types.VersionBitsWindow; start <= height {
+			counts, err = countSignals(tx, start, height, nil)
+			if err != nil {
+				return err
+			}
+		}
+		for _, d := range types.Deployments {
+			statuses = append(statuses, modules.DeploymentStatus{
+				Name:          d.Name,
+				Bit:           d.Bit,
+				StartHeight:   d.StartHeight,
+				TimeoutHeight: d.TimeoutHeight,
+				State:         states[d.Name],
+				Signals:       counts[d.Bit],
+			})
+		}
+		return nil
+	})
+	return statuses, err
+}
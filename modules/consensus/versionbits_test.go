@@ -0,0 +1,120 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/NebulousLabs/bolt"
+)
+
+// TestNextDeploymentState probes the deployment state transitions.
+func TestNextDeploymentState(t *testing.T) {
+	d := types.Deployment{Name: "test", StartHeight: 100, TimeoutHeight: 200}
+	tests := []struct {
+		state   modules.DeploymentState
+		height  types.BlockHeight
+		signals uint64
+		next    modules.DeploymentState
+	}{
+		{modules.DeploymentDefined, 50, 0, modules.DeploymentDefined},
+		{modules.DeploymentDefined, 100, 0, modules.DeploymentStarted},
+		{modules.DeploymentDefined, 200, 0, modules.DeploymentFailed},
+		{modules.DeploymentStarted, 150, types.VersionBitsThreshold - 1, modules.DeploymentStarted},
+		{modules.DeploymentStarted, 150, types.VersionBitsThreshold, modules.DeploymentLockedIn},
+		{modules.DeploymentStarted, 200, types.VersionBitsThreshold, modules.DeploymentFailed},
+		{modules.DeploymentLockedIn, 300, 0, modules.DeploymentActive},
+		{modules.DeploymentActive, 300, 0, modules.DeploymentActive},
+		{modules.DeploymentFailed, 300, types.VersionBitsThreshold, modules.DeploymentFailed},
+	}
+	for _, test := range tests {
+		if next := nextDeploymentState(d, test.state, test.height, test.signals); next != test.next {
+			t.Errorf("%v at height %v with %v signals: got %v, expected %v", test.state, test.height, test.signals, next, test.next)
+		}
+	}
+}
+
+// deploymentState returns the state of the named deployment for the next
+// block.
+func (cst *consensusSetTester) deploymentState(name string) modules.DeploymentState {
+	statuses, err := cst.cs.Deployments()
+	if err != nil {
+		panic(err)
+	}
+	for _, status := range statuses {
+		if status.Name == name {
+			return status.State
+		}
+	}
+	panic("deployment not found: " + name)
+}
+
+// TestIntegrationDeploymentActivation mines blocks that signal for a
+// deployment and checks that the deployment activates. The test changes the
+// set of known deployments, so it cannot run in parallel.
+func TestIntegrationDeploymentActivation(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	defer func(deployments []types.Deployment) {
+		types.Deployments = deployments
+	}(types.Deployments)
+	height := cst.cs.Height()
+	types.Deployments = []types.Deployment{
+		{Name: "signaled", Bit: 1, StartHeight: height + 1, TimeoutHeight: height + 100*types.VersionBitsWindow},
+		{Name: "expired", Bit: 2, StartHeight: height + 1, TimeoutHeight: height + 2},
+		{Name: "future", Bit: 3, StartHeight: height + 100*types.VersionBitsWindow, TimeoutHeight: height + 200*types.VersionBitsWindow},
+	}
+
+	// The miner signals for every deployment between its start and timeout
+	// heights. Mine until the signaled deployment is active, which should
+	// take at most four windows.
+	for i := types.BlockHeight(0); i < 4*types.VersionBitsWindow && cst.deploymentState("signaled") != modules.DeploymentActive; i++ {
+		_, err := cst.miner.AddBlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if state := cst.deploymentState("signaled"); state != modules.DeploymentActive {
+		t.Fatal("signaled deployment is not active:", state)
+	}
+	if state := cst.deploymentState("expired"); state != modules.DeploymentFailed {
+		t.Fatal("expired deployment has not failed:", state)
+	}
+	if state := cst.deploymentState("future"); state != modules.DeploymentDefined {
+		t.Fatal("future deployment has left the defined state:", state)
+	}
+
+	// The states should be the same when they are computed without the
+	// stored states, as for a database that predates version bits.
+	expected, err := cst.cs.Deployments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cst.cs.db.Update(func(tx *bolt.Tx) error {
+		err := tx.DeleteBucket(DeploymentStates)
+		if err != nil {
+			return err
+		}
+		return initDeploymentStates(tx)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses, err := cst.cs.Deployments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range statuses {
+		if statuses[i] != expected[i] {
+			t.Error("recomputed deployment status does not match:", statuses[i], expected[i])
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package modules
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestConsensusChangeAppend checks that Append combines every diff type and
+// takes the metadata of the later change.
+func TestConsensusChangeAppend(t *testing.T) {
+	t.Parallel()
+
+	cc1 := ConsensusChange{
+		ID:                 ConsensusChangeID{1},
+		AppliedBlocks:      []types.Block{{Nonce: types.BlockNonce{1}}},
+		SiacoinOutputDiffs: []SiacoinOutputDiff{{ID: types.SiacoinOutputID{1}}},
+		SiafundPoolDiffs:   []SiafundPoolDiff{{Adjusted: types.NewCurrency64(1)}},
+		ChildTarget:        types.Target{1},
+	}
+	cc2 := ConsensusChange{
+		ID:                        ConsensusChangeID{2},
+		AppliedBlocks:             []types.Block{{Nonce: types.BlockNonce{2}}},
+		FileContractDiffs:         []FileContractDiff{{ID: types.FileContractID{2}}},
+		SiafundOutputDiffs:        []SiafundOutputDiff{{ID: types.SiafundOutputID{2}}},
+		DelayedSiacoinOutputDiffs: []DelayedSiacoinOutputDiff{{ID: types.SiacoinOutputID{2}}},
+		SiafundPoolDiffs:          []SiafundPoolDiff{{Adjusted: types.NewCurrency64(2)}},
+		ChildTarget:               types.Target{2},
+		Synced:                    true,
+	}
+
+	cc := cc1.Append(cc2)
+	if cc.ID != cc2.ID {
+		t.Error("combined change should have the id of the later change")
+	}
+	if len(cc.AppliedBlocks) != 2 || cc.AppliedBlocks[1].Nonce != cc2.AppliedBlocks[0].Nonce {
+		t.Error("applied blocks were not combined in order")
+	}
+	if len(cc.SiacoinOutputDiffs) != 1 || len(cc.FileContractDiffs) != 1 || len(cc.SiafundOutputDiffs) != 1 || len(cc.DelayedSiacoinOutputDiffs) != 1 {
+		t.Error("diffs were not combined")
+	}
+	if len(cc.SiafundPoolDiffs) != 2 {
+		t.Error("siafund pool diffs were not combined")
+	}
+	if cc.ChildTarget != cc2.ChildTarget || !cc.Synced {
+		t.Error("combined change should have the metadata of the later change")
+	}
+}
@@ -0,0 +1,28 @@
+package modules
+
+const (
+	// DAVServerDir is the name of the directory that is used to store the
+	// WebDAV server's persistent data.
+	DAVServerDir = "davserver"
+)
+
+type (
+	// DAVCredentials are the credentials that WebDAV clients authenticate
+	// with, using HTTP basic authentication. A server without a username
+	// accepts unauthenticated requests.
+	DAVCredentials struct {
+		Username string
+		Password string
+	}
+
+	// A DAVServer serves the files of the renter over WebDAV, so that
+	// operating systems can mount them as a network drive. The directories of
+	// the renter are collections, and its files are resources within them.
+	DAVServer interface {
+		// Address returns the address that the server is listening on.
+		Address() NetAddress
+
+		// Close shuts down the server.
+		Close() error
+	}
+)
@@ -0,0 +1,196 @@
+// Package davserver serves the files of the renter over WebDAV, so that
+// operating systems can mount them natively as a network drive. The
+// directories of the renter are collections, and its files are resources
+// within them. Files are read by streaming downloads from the renter, and
+// written by streaming uploads that replace the previous file once they have
+// completed.
+package davserver
+
+import (
+	"crypto/subtle"
+	"errors"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/persist"
+
+	"golang.org/x/net/webdav"
+)
+
+const (
+	logFile      = modules.DAVServerDir + ".log"
+	modTimesFile = "modtimes.json"
+)
+
+var (
+	modTimesMetadata = persist.Metadata{
+		Header:  "WebDAV Modification Times",
+		Version: "1.0",
+	}
+
+	errNilRenter = errors.New("WebDAV server cannot use a nil renter")
+)
+
+// A DAVServer serves WebDAV on its own listener, storing files with the
+// renter.
+type DAVServer struct {
+	creds    modules.DAVCredentials
+	renter   modules.Renter
+	handler  *webdav.Handler
+	listener net.Listener
+
+	// modTimes holds the modification times of the files written through
+	// the server, keyed by siapath. The renter does not keep track of
+	// modification times, so other files have none.
+	modTimes map[string]time.Time
+
+	log        *persist.Logger
+	mu         sync.Mutex
+	persistDir string
+}
+
+// New creates a WebDAV server that stores files with r and listens on addr.
+// If creds has a username, every request must authenticate with creds.
+func New(r modules.Renter, addr string, creds modules.DAVCredentials, persistDir string) (*DAVServer, error) {
+	if r == nil {
+		return nil, errNilRenter
+	}
+	d := &DAVServer{
+		creds:      creds,
+		renter:     r,
+		modTimes:   make(map[string]time.Time),
+		persistDir: persistDir,
+	}
+	d.handler = &webdav.Handler{
+		FileSystem: renterFS{d},
+		LockSystem: webdav.NewMemLS(),
+		Logger:     d.logRequest,
+	}
+
+	if err := os.MkdirAll(persistDir, 0700); err != nil {
+		return nil, err
+	}
+	var err error
+	d.log, err = persist.NewFileLogger(filepath.Join(persistDir, logFile))
+	if err != nil {
+		return nil, err
+	}
+	err = persist.LoadJSON(modTimesMetadata, &d.modTimes, filepath.Join(persistDir, modTimesFile))
+	if err != nil && !os.IsNotExist(err) {
+		d.log.Close()
+		return nil, err
+	}
+
+	d.listener, err = net.Listen("tcp", addr)
+	if err != nil {
+		d.log.Close()
+		return nil, err
+	}
+	go d.threadedServe()
+	d.log.Println("INFO: WebDAV server listening on", d.listener.Addr())
+	return d, nil
+}
+
+// threadedServe serves WebDAV until the listener is closed.
+func (d *DAVServer) threadedServe() {
+	err := http.Serve(d.listener, d)
+	if err != nil && !strings.HasSuffix(err.Error(), "use of closed network connection") {
+		d.log.Println("ERROR: WebDAV server stopped serving:", err)
+	}
+}
+
+// Address returns the address that the server is listening on.
+func (d *DAVServer) Address() modules.NetAddress {
+	return modules.NetAddress(d.listener.Addr().String())
+}
+
+// Close stops the server from accepting requests.
+func (d *DAVServer) Close() error {
+	err := d.listener.Close()
+	d.log.Close()
+	return err
+}
+
+// logRequest logs the requests that failed for reasons other than a missing
+// resource. Clients routinely probe for resources that do not exist.
+func (d *DAVServer) logRequest(req *http.Request, err error) {
+	if err != nil && !os.IsNotExist(err) {
+		d.log.Printf("WARN: %v %v failed: %v", req.Method, req.URL.Path, err)
+	}
+}
+
+// authenticate reports whether req carries the credentials of the server, if
+// the server has any.
+func (d *DAVServer) authenticate(req *http.Request) bool {
+	if d.creds.Username == "" {
+		return true
+	}
+	user, pass, ok := req.BasicAuth()
+	userOK := subtle.ConstantTimeCompare([]byte(user), []byte(d.creds.Username)) == 1
+	passOK := subtle.ConstantTimeCompare([]byte(pass), []byte(d.creds.Password)) == 1
+	return ok && userOK && passOK
+}
+
+// ServeHTTP implements http.Handler, authenticating requests before passing
+// them to the WebDAV handler.
+func (d *DAVServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !d.authenticate(req) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="Sia"`)
+		http.Error(w, "WebDAV authentication failed.", http.StatusUnauthorized)
+		return
+	}
+	// Without a Content-Type, files with an unknown extension would be
+	// sniffed, which starts a download only to restart it from the
+	// beginning.
+	if (req.Method == "GET" || req.Method == "HEAD") && mime.TypeByExtension(path.Ext(req.URL.Path)) == "" {
+		w.Header().Set("Content-Type", "application/octet-stream")
+	}
+	d.handler.ServeHTTP(w, req)
+}
+
+// modTime returns the modification time of the file at siapath, or the zero
+// time if the file was not written through the server.
+func (d *DAVServer) modTime(siapath string) time.Time {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.modTimes[siapath]
+}
+
+// moveModTimes moves the modification times of the file or directory at
+// siapath, and of the files within it, to newPath. If newPath is empty, the
+// modification times are deleted.
+func (d *DAVServer) moveModTimes(siapath, newPath string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	changed := false
+	for name, t := range d.modTimes {
+		if name != siapath && !strings.HasPrefix(name, siapath+"/") {
+			continue
+		}
+		delete(d.modTimes, name)
+		if newPath != "" {
+			d.modTimes[newPath+strings.TrimPrefix(name, siapath)] = t
+		}
+		changed = true
+	}
+	if changed {
+		d.saveModTimes()
+	}
+}
+
+// saveModTimes saves the modification times of the files. The caller must
+// hold the lock.
+func (d *DAVServer) saveModTimes() {
+	err := persist.SaveJSON(modTimesMetadata, d.modTimes, filepath.Join(d.persistDir, modTimesFile))
+	if err != nil {
+		d.log.Println("WARN: could not save the modification times of the files:", err)
+	}
+}
@@ -0,0 +1,262 @@
+package davserver
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/renter/rentertest"
+)
+
+// davTester sends requests to a server backed by a rentertest.MemRenter.
+type davTester struct {
+	t      *testing.T
+	server *DAVServer
+	renter *rentertest.MemRenter
+	creds  modules.DAVCredentials
+}
+
+// do sends a request to the server, returning the status and the body of the
+// response.
+func (dt *davTester) do(method, path string, body []byte, header map[string]string) (int, []byte, http.Header) {
+	req, err := http.NewRequest(method, "http://"+string(dt.server.Address())+path, bytes.NewReader(body))
+	if err != nil {
+		dt.t.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	if dt.creds.Username != "" {
+		req.SetBasicAuth(dt.creds.Username, dt.creds.Password)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		dt.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		dt.t.Fatal(err)
+	}
+	return resp.StatusCode, respBody, resp.Header
+}
+
+// expect sends a request and checks the status of the response.
+func (dt *davTester) expect(method, path string, body []byte, header map[string]string, status int) []byte {
+	got, respBody, _ := dt.do(method, path, body, header)
+	if got != status {
+		dt.t.Fatalf("%v %v: expected %v, got %v %s", method, path, status, got, respBody)
+	}
+	return respBody
+}
+
+func newDAVTester(t *testing.T, creds modules.DAVCredentials) *davTester {
+	r := rentertest.NewMemRenter()
+	d, err := New(r, "localhost:0", creds, build.TempDir("davserver", t.Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &davTester{t: t, server: d, renter: r, creds: creds}
+}
+
+// TestCollections checks that collections can be created, listed, moved and
+// deleted.
+func TestCollections(t *testing.T) {
+	dt := newDAVTester(t, modules.DAVCredentials{})
+	defer dt.server.Close()
+
+	dt.expect("MKCOL", "/photos", nil, nil, http.StatusCreated)
+	dt.expect("MKCOL", "/photos", nil, nil, http.StatusMethodNotAllowed)
+	dt.expect("MKCOL", "/missing/photos", nil, nil, http.StatusConflict)
+	dt.expect("MKCOL", "/photos/2017", nil, nil, http.StatusCreated)
+	dt.expect("PUT", "/photos/cat.jpg", []byte("meow"), nil, http.StatusCreated)
+
+	// The listing contains the collection and the resources directly within
+	// it.
+	depth1 := map[string]string{"Depth": "1"}
+	body := string(dt.expect("PROPFIND", "/photos/", nil, depth1, http.StatusMultiStatus))
+	for _, s := range []string{"<D:href>/photos/</D:href>", "<D:href>/photos/2017/</D:href>", "<D:href>/photos/cat.jpg</D:href>", "<D:getcontentlength>4</D:getcontentlength>", "image/jpeg"} {
+		if !strings.Contains(body, s) {
+			t.Fatalf("listing does not contain %v: %v", s, body)
+		}
+	}
+
+	// Moving a collection moves everything within it.
+	dt.expect("MOVE", "/photos", nil, map[string]string{"Destination": "/pictures"}, http.StatusCreated)
+	dt.expect("PROPFIND", "/photos", nil, depth1, http.StatusNotFound)
+	if _, ok := dt.renter.Files()["pictures/cat.jpg"]; !ok {
+		t.Fatal("file was not moved with its collection")
+	} else if _, ok := dt.renter.Dirs()["pictures/2017"]; !ok {
+		t.Fatal("collection was not moved with its parent")
+	}
+
+	dt.expect("DELETE", "/pictures", nil, nil, http.StatusNoContent)
+	if len(dt.renter.Files()) != 0 || len(dt.renter.Dirs()) != 0 {
+		t.Fatal("collection was not deleted:", dt.renter.Files(), dt.renter.Dirs())
+	}
+	dt.expect("DELETE", "/", nil, nil, http.StatusMethodNotAllowed)
+}
+
+// TestFiles checks that files can be uploaded, downloaded, replaced, moved,
+// copied and deleted.
+func TestFiles(t *testing.T) {
+	dt := newDAVTester(t, modules.DAVCredentials{})
+	defer dt.server.Close()
+
+	data := []byte("hello, world")
+	dt.expect("PUT", "/hello.txt", data, nil, http.StatusCreated)
+	dt.expect("PUT", "/missing/hello.txt", data, nil, http.StatusConflict)
+	status, body, header := dt.do("GET", "/hello.txt", nil, nil)
+	if status != http.StatusOK || !bytes.Equal(body, data) {
+		t.Fatal("wrong file was downloaded:", status, string(body))
+	} else if header.Get("ETag") == "" || header.Get("Last-Modified") == "" {
+		t.Fatal("file metadata is missing:", header)
+	} else if !strings.HasPrefix(header.Get("Content-Type"), "text/plain") {
+		t.Fatal("wrong Content-Type:", header.Get("Content-Type"))
+	}
+	status, body, _ = dt.do("GET", "/hello.txt", nil, map[string]string{"Range": "bytes=7-"})
+	if status != http.StatusPartialContent || string(body) != "world" {
+		t.Fatal("range was not downloaded:", status, string(body))
+	}
+
+	// The file is replaced by a new upload, and the upload is not left
+	// behind.
+	dt.expect("PUT", "/hello.txt", []byte("bye"), nil, http.StatusCreated)
+	if body = dt.expect("GET", "/hello.txt", nil, nil, http.StatusOK); string(body) != "bye" {
+		t.Fatal("file was not replaced:", string(body))
+	} else if len(dt.renter.Files()) != 1 {
+		t.Fatal("expected 1 file, got", len(dt.renter.Files()))
+	}
+
+	dt.expect("MKCOL", "/docs", nil, nil, http.StatusCreated)
+	dt.expect("COPY", "/hello.txt", nil, map[string]string{"Destination": "/docs/copy.txt"}, http.StatusCreated)
+	dt.expect("MOVE", "/hello.txt", nil, map[string]string{"Destination": "/docs/moved.txt"}, http.StatusCreated)
+	dt.expect("GET", "/hello.txt", nil, nil, http.StatusNotFound)
+	if string(dt.renter.Files()["docs/copy.txt"]) != "bye" || string(dt.renter.Files()["docs/moved.txt"]) != "bye" {
+		t.Fatal("file was not copied and moved:", dt.renter.Files())
+	}
+	dt.expect("DELETE", "/docs/moved.txt", nil, nil, http.StatusNoContent)
+	dt.expect("DELETE", "/docs/moved.txt", nil, nil, http.StatusNotFound)
+}
+
+// TestInterruptedUpload checks that an upload whose data cannot be read in
+// full does not replace the file.
+func TestInterruptedUpload(t *testing.T) {
+	dt := newDAVTester(t, modules.DAVCredentials{})
+	defer dt.server.Close()
+	dt.renter.SetFile("foo", []byte("foo"))
+
+	fs := renterFS{dt.server}
+	f, err := fs.OpenFile(context.Background(), "/foo", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errInterrupted := errors.New("connection reset")
+	r := io.MultiReader(strings.NewReader("bar"), errReader{errInterrupted})
+	if _, err := io.Copy(f, r); err != errInterrupted {
+		t.Fatal("expected the error of the reader, got", err)
+	} else if err := f.Close(); err != errInterrupted {
+		t.Fatal("expected the upload to be discarded, got", err)
+	}
+	if len(dt.renter.Files()) != 1 || string(dt.renter.Files()["foo"]) != "foo" {
+		t.Fatal("interrupted upload replaced the file:", dt.renter.Files())
+	}
+}
+
+// errReader is a reader that always fails with err.
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
+
+// renameFailRenter is a MemRenter that fails the first rename of a hidden
+// file to a visible one, which is the rename of an upload into place.
+type renameFailRenter struct {
+	*rentertest.MemRenter
+	failed bool
+}
+
+func (r *renameFailRenter) RenameFile(siapath, newPath string) error {
+	if !r.failed && isUploadPath(siapath) && !isUploadPath(newPath) {
+		r.failed = true
+		return errors.New("rename failed")
+	}
+	return r.MemRenter.RenameFile(siapath, newPath)
+}
+
+// TestFailedReplace checks that an upload that cannot be renamed into place
+// keeps both the previous file and the uploaded data.
+func TestFailedReplace(t *testing.T) {
+	r := rentertest.NewMemRenter()
+	r.SetFile("foo", []byte("foo"))
+	d, err := New(&renameFailRenter{MemRenter: r}, "localhost:0", modules.DAVCredentials{}, build.TempDir("davserver", t.Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	dt := &davTester{t: t, server: d, renter: r}
+
+	if status, _, _ := dt.do("PUT", "/foo", []byte("bar"), nil); status == http.StatusCreated {
+		t.Fatal("expected the replacement to fail")
+	}
+	if body := dt.expect("GET", "/foo", nil, nil, http.StatusOK); string(body) != "foo" {
+		t.Fatal("previous file was not restored:", string(body))
+	}
+	var kept bool
+	for name, data := range r.Files() {
+		kept = kept || (isUploadPath(name) && string(data) == "bar")
+	}
+	if len(r.Files()) != 2 || !kept {
+		t.Fatal("upload was not kept:", r.Files())
+	}
+}
+
+// TestModTimesPersist checks that the modification times of files survive a
+// restart of the server, and follow the files when they are moved.
+func TestModTimesPersist(t *testing.T) {
+	dt := newDAVTester(t, modules.DAVCredentials{})
+	dt.expect("MKCOL", "/dir", nil, nil, http.StatusCreated)
+	dt.expect("PUT", "/dir/foo", []byte("foo"), nil, http.StatusCreated)
+	dt.expect("MOVE", "/dir", nil, map[string]string{"Destination": "/moved"}, http.StatusCreated)
+	_, _, header := dt.do("HEAD", "/moved/foo", nil, nil)
+	if err := dt.server.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := New(dt.renter, "localhost:0", modules.DAVCredentials{}, dt.server.persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	dt.server = d
+	_, _, header2 := dt.do("HEAD", "/moved/foo", nil, nil)
+	if header.Get("Last-Modified") == "" || header2.Get("Last-Modified") != header.Get("Last-Modified") || header2.Get("ETag") != header.Get("ETag") {
+		t.Fatal("modification time was not persisted:", header, header2)
+	}
+}
+
+// TestAuthentication checks that a server with credentials rejects requests
+// that do not carry them.
+func TestAuthentication(t *testing.T) {
+	creds := modules.DAVCredentials{Username: "sia", Password: "hunter2"}
+	dt := newDAVTester(t, creds)
+	defer dt.server.Close()
+	dt.expect("PROPFIND", "/", nil, map[string]string{"Depth": "0"}, http.StatusMultiStatus)
+
+	dt.creds = modules.DAVCredentials{}
+	status, _, header := dt.do("PROPFIND", "/", nil, map[string]string{"Depth": "0"})
+	if status != http.StatusUnauthorized || header.Get("WWW-Authenticate") == "" {
+		t.Fatal("expected an unauthenticated request to be rejected, got", status)
+	}
+	dt.creds = modules.DAVCredentials{Username: "sia", Password: "wrong"}
+	dt.expect("PROPFIND", "/", nil, map[string]string{"Depth": "0"}, http.StatusUnauthorized)
+}
@@ -0,0 +1,529 @@
+package davserver
+
+// fs.go implements the file system that the WebDAV handler serves. Files are
+// read by streaming a download from the offset of the reader, which is
+// restarted whenever the reader seeks elsewhere. Files are written by
+// streaming an upload to a temporary siapath, which replaces the file once the
+// upload has completed. Files cannot be modified in place.
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/renter"
+	"github.com/NebulousLabs/Sia/persist"
+
+	"golang.org/x/net/webdav"
+)
+
+const (
+	// uploadMarker separates the siapath of a file from the random suffix of
+	// the siapath that the file is uploaded to. Files being uploaded are
+	// hidden from clients.
+	uploadMarker = ".davupload-"
+)
+
+var (
+	errFileClosed   = errors.New("file is closed")
+	errIsDir        = errors.New("a collection exists at the path")
+	errModifyFile   = errors.New("files can only be replaced, not modified")
+	errNotDir       = errors.New("resource is not a collection")
+	errReadOnly     = errors.New("file is open for reading")
+	errRootDir      = errors.New("the root collection cannot be changed")
+	errUploadMarker = errors.New("paths cannot contain " + uploadMarker)
+	errWriteOnly    = errors.New("file is open for writing")
+)
+
+type (
+	// renterFS is a webdav.FileSystem backed by the renter of a server.
+	renterFS struct {
+		d *DAVServer
+	}
+
+	// fileInfo describes a file or directory of the renter.
+	fileInfo struct {
+		name    string
+		size    int64
+		dir     bool
+		modTime time.Time
+	}
+
+	// dirFile is a directory of the renter that is open for listing.
+	dirFile struct {
+		fs      renterFS
+		siapath string
+		entries []os.FileInfo
+		listed  bool
+	}
+
+	// readFile is a file of the renter that is open for reading. The data of
+	// the file is streamed from a download that starts at the offset of the
+	// first read.
+	readFile struct {
+		fs      renterFS
+		siapath string
+		info    fileInfo
+		offset  int64
+		stream  *io.PipeReader
+		closed  bool
+	}
+
+	// writeFile is a file that is open for writing. The data written is
+	// streamed to an upload, which replaces the file when it is closed.
+	writeFile struct {
+		fs         renterFS
+		siapath    string
+		uploadPath string
+		pw         *io.PipeWriter
+		done       chan error
+		modTime    time.Time
+		n          int64
+		err        error
+		closed     bool
+	}
+)
+
+// toSiaPath converts the name of a resource to a siapath. The root
+// collection has the empty siapath.
+func toSiaPath(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+// parentDir returns the siapath of the directory containing siapath.
+func parentDir(siapath string) string {
+	dir := path.Dir(siapath)
+	if dir == "." {
+		return ""
+	}
+	return dir
+}
+
+// isUploadPath reports whether siapath is the siapath of a file that is being
+// uploaded.
+func isUploadPath(siapath string) bool {
+	return strings.Contains(path.Base(siapath), uploadMarker)
+}
+
+// newFileInfo returns the information of a file of the renter.
+func (fs renterFS) newFileInfo(fi modules.FileInfo) fileInfo {
+	return fileInfo{
+		name:    path.Base(fi.SiaPath),
+		size:    int64(fi.Filesize),
+		modTime: fs.d.modTime(fi.SiaPath),
+	}
+}
+
+// newDirInfo returns the information of a directory of the renter.
+func newDirInfo(siapath string) fileInfo {
+	return fileInfo{
+		name: path.Base("/" + siapath),
+		dir:  true,
+	}
+}
+
+// file returns the information of the file at siapath.
+func (fs renterFS) file(siapath string) (modules.FileInfo, bool) {
+	if isUploadPath(siapath) {
+		return modules.FileInfo{}, false
+	}
+	for _, fi := range fs.d.renter.FileList() {
+		if fi.SiaPath == siapath {
+			return fi, true
+		}
+	}
+	return modules.FileInfo{}, false
+}
+
+// dirExists reports whether the directory at siapath exists.
+func (fs renterFS) dirExists(siapath string) bool {
+	_, _, _, err := fs.d.renter.DirList(siapath)
+	return err == nil
+}
+
+// Mkdir implements webdav.FileSystem, creating a directory. The parent of the
+// directory must exist.
+func (fs renterFS) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	siapath := toSiaPath(name)
+	if siapath == "" {
+		return os.ErrExist
+	} else if isUploadPath(siapath) {
+		return errUploadMarker
+	} else if !fs.dirExists(parentDir(siapath)) {
+		return os.ErrNotExist
+	}
+	switch err := fs.d.renter.CreateDir(siapath); err {
+	case renter.ErrDirOverload, renter.ErrPathOverload:
+		return os.ErrExist
+	default:
+		return err
+	}
+}
+
+// OpenFile implements webdav.FileSystem. Files opened for writing are always
+// truncated, as the renter cannot modify files in place.
+func (fs renterFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	siapath := toSiaPath(name)
+	if flag&(os.O_WRONLY|os.O_RDWR) != 0 {
+		return fs.create(siapath, flag)
+	}
+	if fi, ok := fs.file(siapath); ok {
+		return &readFile{
+			fs:      fs,
+			siapath: siapath,
+			info:    fs.newFileInfo(fi),
+		}, nil
+	} else if fs.dirExists(siapath) {
+		return &dirFile{fs: fs, siapath: siapath}, nil
+	}
+	return nil, os.ErrNotExist
+}
+
+// create opens the file at siapath for writing, starting its upload.
+func (fs renterFS) create(siapath string, flag int) (*writeFile, error) {
+	if siapath == "" || fs.dirExists(siapath) {
+		return nil, errIsDir
+	} else if isUploadPath(siapath) {
+		return nil, errUploadMarker
+	} else if !fs.dirExists(parentDir(siapath)) {
+		return nil, os.ErrNotExist
+	}
+	if _, exists := fs.file(siapath); !exists && flag&os.O_CREATE == 0 {
+		return nil, os.ErrNotExist
+	} else if exists && flag&os.O_TRUNC == 0 {
+		return nil, errModifyFile
+	}
+
+	pr, pw := io.Pipe()
+	wf := &writeFile{
+		fs:         fs,
+		siapath:    siapath,
+		uploadPath: siapath + uploadMarker + persist.RandomSuffix(),
+		pw:         pw,
+		done:       make(chan error, 1),
+		modTime:    time.Now().UTC(),
+	}
+	go func() {
+		err := fs.d.renter.UploadStreamFromReader(modules.FileUploadParams{SiaPath: wf.uploadPath}, pr)
+		// Writes to a failed upload return its error.
+		pr.CloseWithError(err)
+		wf.done <- err
+	}()
+	return wf, nil
+}
+
+// RemoveAll implements webdav.FileSystem, deleting a file, or a directory
+// along with everything within it.
+func (fs renterFS) RemoveAll(ctx context.Context, name string) error {
+	siapath := toSiaPath(name)
+	if siapath == "" {
+		return errRootDir
+	}
+	var err error
+	if _, ok := fs.file(siapath); ok {
+		err = fs.d.renter.DeleteFile(siapath)
+	} else {
+		err = fs.d.renter.DeleteDir(siapath)
+	}
+	if err == renter.ErrUnknownPath || err == renter.ErrUnknownDir {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	fs.d.moveModTimes(siapath, "")
+	return nil
+}
+
+// Rename implements webdav.FileSystem, moving a file or directory. The
+// parent of the new path must exist.
+func (fs renterFS) Rename(ctx context.Context, oldName, newName string) error {
+	siapath, newPath := toSiaPath(oldName), toSiaPath(newName)
+	if siapath == "" || newPath == "" {
+		return errRootDir
+	} else if isUploadPath(newPath) {
+		return errUploadMarker
+	} else if !fs.dirExists(parentDir(newPath)) {
+		return os.ErrNotExist
+	}
+	var err error
+	if _, ok := fs.file(siapath); ok {
+		err = fs.d.renter.RenameFile(siapath, newPath)
+	} else {
+		err = fs.d.renter.RenameDir(siapath, newPath)
+	}
+	switch err {
+	case nil:
+	case renter.ErrPathOverload, renter.ErrDirOverload:
+		return os.ErrExist
+	case renter.ErrUnknownPath, renter.ErrUnknownDir:
+		return os.ErrNotExist
+	default:
+		return err
+	}
+	fs.d.moveModTimes(siapath, newPath)
+	return nil
+}
+
+// Stat implements webdav.FileSystem.
+func (fs renterFS) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	siapath := toSiaPath(name)
+	if fi, ok := fs.file(siapath); ok {
+		return fs.newFileInfo(fi), nil
+	} else if fs.dirExists(siapath) {
+		return newDirInfo(siapath), nil
+	}
+	return nil, os.ErrNotExist
+}
+
+// Name implements os.FileInfo.
+func (fi fileInfo) Name() string { return fi.name }
+
+// Size implements os.FileInfo.
+func (fi fileInfo) Size() int64 { return fi.size }
+
+// Mode implements os.FileInfo.
+func (fi fileInfo) Mode() os.FileMode {
+	if fi.dir {
+		return os.ModeDir | 0755
+	}
+	return 0644
+}
+
+// ModTime implements os.FileInfo.
+func (fi fileInfo) ModTime() time.Time { return fi.modTime }
+
+// IsDir implements os.FileInfo.
+func (fi fileInfo) IsDir() bool { return fi.dir }
+
+// Sys implements os.FileInfo.
+func (fi fileInfo) Sys() interface{} { return nil }
+
+// ContentType implements webdav.ContentTyper. The type of a file is derived
+// from its extension only, as sniffing its contents would download it.
+func (fi fileInfo) ContentType(ctx context.Context) (string, error) {
+	if fi.dir {
+		return "", webdav.ErrNotImplemented
+	}
+	if ctype := mime.TypeByExtension(path.Ext(fi.name)); ctype != "" {
+		return ctype, nil
+	}
+	return "application/octet-stream", nil
+}
+
+// Close implements webdav.File.
+func (df *dirFile) Close() error { return nil }
+
+// Read implements webdav.File.
+func (df *dirFile) Read([]byte) (int, error) { return 0, errIsDir }
+
+// Write implements webdav.File.
+func (df *dirFile) Write([]byte) (int, error) { return 0, errIsDir }
+
+// Seek implements webdav.File.
+func (df *dirFile) Seek(int64, int) (int64, error) { return 0, errIsDir }
+
+// Stat implements webdav.File.
+func (df *dirFile) Stat() (os.FileInfo, error) { return newDirInfo(df.siapath), nil }
+
+// Readdir implements webdav.File, listing the directories and files within
+// the directory. Files that are being uploaded are not listed.
+func (df *dirFile) Readdir(count int) ([]os.FileInfo, error) {
+	if !df.listed {
+		_, dirs, files, err := df.fs.d.renter.DirList(df.siapath)
+		if err != nil {
+			return nil, os.ErrNotExist
+		}
+		for _, dir := range dirs {
+			df.entries = append(df.entries, newDirInfo(dir.SiaPath))
+		}
+		for _, fi := range files {
+			if !isUploadPath(fi.SiaPath) {
+				df.entries = append(df.entries, df.fs.newFileInfo(fi))
+			}
+		}
+		df.listed = true
+	}
+
+	if count <= 0 {
+		entries := df.entries
+		df.entries = nil
+		return entries, nil
+	} else if len(df.entries) == 0 {
+		return nil, io.EOF
+	} else if count > len(df.entries) {
+		count = len(df.entries)
+	}
+	entries := df.entries[:count]
+	df.entries = df.entries[count:]
+	return entries, nil
+}
+
+// Close implements webdav.File, stopping the download of the file.
+func (rf *readFile) Close() error {
+	if rf.stream != nil {
+		rf.stream.Close()
+		rf.stream = nil
+	}
+	rf.closed = true
+	return nil
+}
+
+// Read implements webdav.File, reading from the download of the file. The
+// download is started at the offset of the file if it is not running.
+func (rf *readFile) Read(b []byte) (int, error) {
+	if rf.closed {
+		return 0, errFileClosed
+	} else if rf.offset >= rf.info.size {
+		return 0, io.EOF
+	}
+	if rf.stream == nil {
+		pr, pw := io.Pipe()
+		siapath, offset, length := rf.siapath, uint64(rf.offset), uint64(rf.info.size-rf.offset)
+		go func() {
+			pw.CloseWithError(rf.fs.d.renter.DownloadSection(siapath, offset, length, pw))
+		}()
+		rf.stream = pr
+	}
+	n, err := rf.stream.Read(b)
+	rf.offset += int64(n)
+	if err == io.EOF && rf.offset < rf.info.size {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// Seek implements webdav.File. Seeking away from the offset of the download
+// stops it, so that the next read starts a download at the new offset.
+func (rf *readFile) Seek(offset int64, whence int) (int64, error) {
+	if rf.closed {
+		return 0, errFileClosed
+	}
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += rf.offset
+	case io.SeekEnd:
+		offset += rf.info.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative offset")
+	}
+	if offset != rf.offset && rf.stream != nil {
+		rf.stream.Close()
+		rf.stream = nil
+	}
+	rf.offset = offset
+	return offset, nil
+}
+
+// Readdir implements webdav.File.
+func (rf *readFile) Readdir(int) ([]os.FileInfo, error) { return nil, errNotDir }
+
+// Stat implements webdav.File.
+func (rf *readFile) Stat() (os.FileInfo, error) { return rf.info, nil }
+
+// Write implements webdav.File.
+func (rf *readFile) Write([]byte) (int, error) { return 0, errReadOnly }
+
+// Close implements webdav.File, finishing the upload of the file and
+// replacing the previous file with it. If the data could not be read in full,
+// the upload is discarded and the previous file is kept.
+func (wf *writeFile) Close() error {
+	if wf.closed {
+		return errFileClosed
+	}
+	wf.closed = true
+	wf.pw.CloseWithError(wf.err)
+	err := <-wf.done
+	if err == nil {
+		err = wf.err
+	}
+	if err != nil {
+		wf.fs.d.renter.DeleteFile(wf.uploadPath)
+		wf.fs.d.log.Printf("WARN: upload of %v failed: %v", wf.siapath, err)
+		return err
+	}
+
+	// Replace the previous file, moving it aside until the upload has been
+	// renamed into its place. The lock serializes concurrent uploads of the
+	// same file. If the upload cannot be renamed, it is kept, so that a failed
+	// replacement loses neither the previous data nor the new data.
+	d := wf.fs.d
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var backupPath string
+	if _, exists := wf.fs.file(wf.siapath); exists {
+		backupPath = wf.siapath + uploadMarker + persist.RandomSuffix()
+		if err := d.renter.RenameFile(wf.siapath, backupPath); err != nil {
+			d.log.Printf("WARN: could not replace %v, the upload was kept at %v: %v", wf.siapath, wf.uploadPath, err)
+			return errors.New("could not move the previous file aside: " + err.Error())
+		}
+	}
+	if err := d.renter.RenameFile(wf.uploadPath, wf.siapath); err != nil {
+		if backupPath != "" {
+			if err := d.renter.RenameFile(backupPath, wf.siapath); err != nil {
+				d.log.Printf("WARN: could not restore %v from %v: %v", wf.siapath, backupPath, err)
+			}
+		}
+		d.log.Printf("WARN: could not replace %v, the upload was kept at %v: %v", wf.siapath, wf.uploadPath, err)
+		return errors.New("could not rename the uploaded file: " + err.Error())
+	}
+	if backupPath != "" {
+		if err := d.renter.DeleteFile(backupPath); err != nil {
+			d.log.Printf("WARN: could not delete the previous version of %v: %v", wf.siapath, err)
+		}
+	}
+	d.modTimes[wf.siapath] = wf.modTime
+	d.saveModTimes()
+	return nil
+}
+
+// Read implements webdav.File.
+func (wf *writeFile) Read([]byte) (int, error) { return 0, errWriteOnly }
+
+// Write implements webdav.File, writing to the upload of the file.
+func (wf *writeFile) Write(b []byte) (int, error) {
+	if wf.closed {
+		return 0, errFileClosed
+	}
+	n, err := wf.pw.Write(b)
+	wf.n += int64(n)
+	if err != nil && wf.err == nil {
+		wf.err = err
+	}
+	return n, err
+}
+
+// ReadFrom implements io.ReaderFrom, so that the upload is discarded if the
+// data being copied to the file cannot be read in full. Otherwise a request
+// that is cut short would replace the file with part of its data.
+func (wf *writeFile) ReadFrom(r io.Reader) (int64, error) {
+	n, err := io.Copy(struct{ io.Writer }{wf}, r)
+	if err != nil && wf.err == nil {
+		wf.err = err
+	}
+	return n, err
+}
+
+// Seek implements webdav.File.
+func (wf *writeFile) Seek(int64, int) (int64, error) { return 0, errWriteOnly }
+
+// Readdir implements webdav.File.
+func (wf *writeFile) Readdir(int) ([]os.FileInfo, error) { return nil, errNotDir }
+
+// Stat implements webdav.File, describing the data written so far.
+func (wf *writeFile) Stat() (os.FileInfo, error) {
+	return fileInfo{
+		name:    path.Base(wf.siapath),
+		size:    wf.n,
+		modTime: wf.modTime,
+	}, nil
+}
@@ -479,19 +479,18 @@ func dbCalculateBlockFacts(tx *bolt.Tx, cs modules.ConsensusSet, block types.Blo
 	var estimatedHashrate types.Currency
 	if bf.Height > hashrateEstimationBlocks {
 		var totalDifficulty = bf.Target
-		var oldestTimestamp types.Timestamp
-		for i := types.BlockHeight(1); i < hashrateEstimationBlocks; i++ {
-			b, exists := cs.BlockAtHeight(bf.Height - i)
-			if !exists {
-				panic(fmt.Sprint("ConsensusSet is missing block at height", bf.Height-hashrateEstimationBlocks))
-			}
-			target, exists := cs.ChildTarget(b.ParentID)
+		blocks, err := cs.BlocksInRange(bf.Height-hashrateEstimationBlocks+1, bf.Height-1)
+		if err != nil {
+			panic(fmt.Sprint("ConsensusSet is missing blocks below height", bf.Height, ":", err))
+		}
+		for _, b := range blocks {
+			target, exists := cs.ChildTarget(b.Block.ParentID)
 			if !exists {
-				panic(fmt.Sprint("ConsensusSet is missing target of known block", b.ParentID))
+				panic(fmt.Sprint("ConsensusSet is missing target of known block", b.Block.ParentID))
 			}
 			totalDifficulty = totalDifficulty.AddDifficulties(target)
-			oldestTimestamp = b.Timestamp
 		}
+		oldestTimestamp := blocks[0].Block.Timestamp
 		secondsPassed := bf.Timestamp - oldestTimestamp
 		estimatedHashrate = totalDifficulty.Difficulty().Div64(uint64(secondsPassed))
 	}
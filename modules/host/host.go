@@ -124,6 +124,7 @@ type Host struct {
 	atomicRecentRevisionCalls uint64
 	atomicSettingsCalls       uint64
 	atomicUnrecognizedCalls   uint64
+	atomicVerifySegmentsCalls uint64
 
 	// Error management. There are a few different types of errors returned by
 	// the host. These errors intentionally not persistent, so that the logging
@@ -0,0 +1,70 @@
+package host
+
+import (
+	"net"
+	"time"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// errBadSegmentIndex is returned if the renter challenges the host with a
+// segment that lies outside of a sector.
+var errBadSegmentIndex = ErrorCommunication("segment challenge has an invalid segment index")
+
+// managedRPCVerifySegments answers a batch of segment challenges from the
+// renter, proving that the host stores the challenged segments of the sectors
+// of a file contract. The renter proves that it owns the contract by the
+// same challenge that RPCRecentRevision uses. Sectors that are not part of
+// the contract, or that cannot be read, are answered with an empty proof.
+func (h *Host) managedRPCVerifySegments(conn net.Conn) error {
+	fcid, so, err := h.managedRPCRecentRevision(conn)
+	if err != nil {
+		return extendErr("failed RPCRecentRevision during RPCVerifySegments: ", err)
+	}
+	// The storage obligation is not modified, so it only needs to be locked
+	// while its sector roots are collected.
+	roots := make(map[crypto.Hash]struct{})
+	for _, root := range so.SectorRoots {
+		roots[root] = struct{}{}
+	}
+	h.managedUnlockStorageObligation(fcid)
+
+	// Read the challenges.
+	conn.SetDeadline(time.Now().Add(modules.NegotiateVerifyTime))
+	var challenges []modules.SegmentChallenge
+	err = encoding.ReadObject(conn, &challenges, modules.NegotiateMaxSegmentChallengesSize)
+	if err != nil {
+		return extendErr("could not read segment challenges: ", ErrorConnection(err.Error()))
+	}
+	numSegments := modules.SectorSize / crypto.SegmentSize
+	for _, challenge := range challenges {
+		if challenge.SegmentIndex >= numSegments {
+			modules.WriteNegotiationRejection(conn, errBadSegmentIndex) // Error not reported to preserve type in extendErr
+			return extendErr("segment challenge rejected: ", errBadSegmentIndex)
+		}
+	}
+
+	// Build the proofs.
+	proofs := make([]modules.SegmentProof, len(challenges))
+	for i, challenge := range challenges {
+		if _, ok := roots[challenge.MerkleRoot]; !ok {
+			continue
+		}
+		sector, err := h.ReadSector(challenge.MerkleRoot)
+		if err != nil {
+			h.log.Printf("WARN: could not read challenged sector %v: %v", challenge.MerkleRoot, err)
+			continue
+		}
+		proofs[i].Base, proofs[i].HashSet = crypto.MerkleProof(sector, challenge.SegmentIndex)
+	}
+
+	if err := modules.WriteNegotiationAcceptance(conn); err != nil {
+		return extendErr("failed to write acceptance of segment challenges: ", ErrorConnection(err.Error()))
+	}
+	if err := encoding.WriteObject(conn, proofs); err != nil {
+		return extendErr("failed to write segment proofs: ", ErrorConnection(err.Error()))
+	}
+	return nil
+}
@@ -300,6 +300,9 @@ func (h *Host) threadedHandleConn(conn net.Conn) {
 			// the storage obligation that gets returned.
 			h.managedUnlockStorageObligation(so.id())
 		}
+	case modules.RPCVerifySegments:
+		atomic.AddUint64(&h.atomicVerifySegmentsCalls, 1)
+		err = extendErr("incoming RPCVerifySegments failed: ", h.managedRPCVerifySegments(conn))
 	case modules.RPCSettings:
 		atomic.AddUint64(&h.atomicSettingsCalls, 1)
 		err = extendErr("incoming RPCSettings failed: ", h.managedRPCSettings(conn))
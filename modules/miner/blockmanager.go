@@ -36,11 +36,15 @@ func (m *Miner) blockForWork() types.Block {
 		UnlockHash: m.persist.Address,
 	}}
 
-	// Add an arb-data txn to the block to create a unique merkle root.
+	// Add an arb-data txn to the block to create a unique merkle root. The
+	// txn also signals for the deployments that this miner supports.
 	randBytes := fastrand.Bytes(types.SpecifierLen)
 	randTxn := types.Transaction{
 		ArbitraryData: [][]byte{append(modules.PrefixNonSia[:], randBytes...)},
 	}
+	if bits := types.SignalingBits(m.persist.Height + 1); bits != 0 {
+		randTxn.ArbitraryData = append(randTxn.ArbitraryData, types.VersionBitsArbitraryData(bits))
+	}
 	b.Transactions = append([]types.Transaction{randTxn}, b.Transactions...)
 
 	return b
@@ -191,7 +195,10 @@ func (m *Miner) SubmitHeader(bh types.BlockHeader) error {
 		txns := make([]types.Transaction, len(b.Transactions))
 		copy(txns, b.Transactions)
 		b.Transactions = txns
-		b.Transactions[0].ArbitraryData = [][]byte{arbData[:]}
+		arbDatas := make([][]byte, len(b.Transactions[0].ArbitraryData))
+		copy(arbDatas, b.Transactions[0].ArbitraryData)
+		arbDatas[0] = arbData[:]
+		b.Transactions[0].ArbitraryData = arbDatas
 		b.Nonce = nonce
 
 		// Sanity check - block should have same id as header.
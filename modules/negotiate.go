@@ -60,12 +60,27 @@ const (
 	// should be successful even if both parties are on Tor.
 	NegotiateSettingsTime = 120 * time.Second
 
+	// NegotiateVerifyTime defines the amount of time that the renter and host
+	// have to complete a batch of segment challenges, after the recent
+	// revision of the contract has been exchanged. The host reads a full
+	// sector from disk for each challenge.
+	NegotiateVerifyTime = 300 * time.Second
+
 	// NegotiateMaxDownloadActionRequestSize defines the maximum size that a
 	// download request can be. Note, this is not a max size for the data that
 	// can be requested, but instead is a max size for the definition of the
 	// data being requested.
 	NegotiateMaxDownloadActionRequestSize = 50e3
 
+	// NegotiateMaxSegmentChallengesSize defines the maximum size of a batch
+	// of segment challenges, which bounds the number of sectors that a host
+	// reads for a single RPCVerifySegments call.
+	NegotiateMaxSegmentChallengesSize = 2e3
+
+	// NegotiateMaxSegmentProofSize defines the maximum size of the proof that
+	// a host sends in response to a single segment challenge.
+	NegotiateMaxSegmentProofSize = 1e3
+
 	// NegotiateMaxErrorSize indicates the maximum number of bytes that can be
 	// used to encode an error being sent during negotiation.
 	NegotiateMaxErrorSize = 256
@@ -160,6 +175,10 @@ var (
 	// contract revision for a given file contract.
 	RPCRecentRevision = types.Specifier{'R', 'e', 'c', 'e', 'n', 't', 'R', 'e', 'v', 'i', 's', 'i', 'o', 'n', 2}
 
+	// RPCVerifySegments is the specifier for challenging a host to prove that
+	// it stores segments of the sectors of a file contract.
+	RPCVerifySegments = types.Specifier{'V', 'e', 'r', 'i', 'f', 'y', 'S', 'e', 'g', 'm', 'e', 'n', 't', 's'}
+
 	// RPCSettings is the specifier for requesting settings from the host.
 	RPCSettings = types.Specifier{'S', 'e', 't', 't', 'i', 'n', 'g', 's', 2}
 
@@ -187,6 +206,21 @@ type (
 		Length     uint64
 	}
 
+	// A SegmentChallenge asks a host to prove that it stores the segment at
+	// SegmentIndex of the sector with the given MerkleRoot.
+	SegmentChallenge struct {
+		MerkleRoot   crypto.Hash
+		SegmentIndex uint64
+	}
+
+	// A SegmentProof is the response of a host to a SegmentChallenge: the
+	// challenged segment, and the hashes needed to prove that it belongs to
+	// the sector. A host that cannot find the sector sends an empty proof.
+	SegmentProof struct {
+		Base    []byte
+		HashSet []crypto.Hash
+	}
+
 	// HostAnnouncement is an announcement by the host that appears in the
 	// blockchain. 'Specifier' is always 'PrefixHostAnnouncement'. The
 	// announcement is always followed by a signature from the public key of
@@ -45,6 +45,17 @@ func (na NetAddress) Port() string {
 	return port
 }
 
+// Subnet returns the subnet that contains ip in CIDR notation: the /24 of an
+// IPv4 address, or the /64 of an IPv6 address. Hosts in the same subnet are
+// likely to be run by the same operator or hosting provider, and to fail
+// together.
+func Subnet(ip net.IP) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		return (&net.IPNet{IP: ip4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+	}
+	return (&net.IPNet{IP: ip.Mask(net.CIDRMask(64, 128)), Mask: net.CIDRMask(64, 128)}).String()
+}
+
 // IsLoopback returns true for IP addresses that are on the same machine.
 func (na NetAddress) IsLoopback() bool {
 	host, _, err := net.SplitHostPort(string(na))
@@ -317,3 +317,24 @@ func TestIsLocal(t *testing.T) {
 		}
 	}
 }
+
+// TestSubnet checks that Subnet returns the /24 of IPv4 addresses and the /64
+// of IPv6 addresses.
+func TestSubnet(t *testing.T) {
+	t.Parallel()
+
+	testSet := []struct {
+		ip     string
+		subnet string
+	}{
+		{"1.2.3.4", "1.2.3.0/24"},
+		{"1.2.3.255", "1.2.3.0/24"},
+		{"::ffff:1.2.3.4", "1.2.3.0/24"},
+		{"2001:db8:1:2:3:4:5:6", "2001:db8:1:2::/64"},
+	}
+	for _, test := range testSet {
+		if subnet := Subnet(net.ParseIP(test.ip)); subnet != test.subnet {
+			t.Errorf("Subnet(%v) returned %v, expected %v", test.ip, subnet, test.subnet)
+		}
+	}
+}
@@ -16,6 +16,18 @@ const (
 	RenterDir = "renter"
 )
 
+// The modes of a HostFilter.
+const (
+	// HostFilterModeDisable disables the host filter.
+	HostFilterModeDisable = "disable"
+
+	// HostFilterModeBlacklist excludes the hosts matched by the filter.
+	HostFilterModeBlacklist = "blacklist"
+
+	// HostFilterModeWhitelist excludes the hosts not matched by the filter.
+	HostFilterModeWhitelist = "whitelist"
+)
+
 // An ErasureCoder is an error-correcting encoder and decoder.
 type ErasureCoder interface {
 	// NumPieces is the number of pieces returned by Encode.
@@ -47,15 +59,92 @@ type Allowance struct {
 	RenewWindow types.BlockHeight `json:"renewwindow"`
 }
 
+// DirectoryInfo provides information about a directory of the renter. The
+// size and health of a directory aggregate the files within it and within its
+// subdirectories. A directory without files has a MinRedundancy of -1.
+type DirectoryInfo struct {
+	SiaPath        string  `json:"siapath"`
+	NumFiles       uint64  `json:"numfiles"`
+	Size           uint64  `json:"size"`
+	Available      bool    `json:"available"`
+	MinRedundancy  float64 `json:"minredundancy"`
+	UploadProgress float64 `json:"uploadprogress"`
+}
+
+// DownloadCacheMetrics reports the usage of the cache of the chunks that the
+// renter has downloaded, and how many chunks were and were not found in it
+// since the renter started. Size and MaxSize are in bytes.
+type DownloadCacheMetrics struct {
+	Chunks  uint64 `json:"chunks"`
+	Size    uint64 `json:"size"`
+	MaxSize uint64 `json:"maxsize"`
+	Hits    uint64 `json:"hits"`
+	Misses  uint64 `json:"misses"`
+}
+
 // DownloadInfo provides information about a file that has been requested for
-// download.
+// download. Speed is the number of bytes received per second over the last
+// few seconds, and ETA the number of seconds until the download completes at
+// that speed, or 0 if it is not known.
 type DownloadInfo struct {
-	SiaPath     string         `json:"siapath"`
-	Destination DownloadWriter `json:"destination"`
-	Filesize    uint64         `json:"filesize"`
-	Received    uint64         `json:"received"`
-	StartTime   time.Time      `json:"starttime"`
-	Error       string         `json:"error"`
+	ID          uint64             `json:"id"`
+	SiaPath     string             `json:"siapath"`
+	Destination DownloadWriter     `json:"destination"`
+	Filesize    uint64             `json:"filesize"`
+	Received    uint64             `json:"received"`
+	StartTime   time.Time          `json:"starttime"`
+	Complete    bool               `json:"complete"`
+	Speed       uint64             `json:"speed"`
+	ETA         uint64             `json:"eta"`
+	Hosts       []TransferHostInfo `json:"hosts"`
+	Error       string             `json:"error"`
+}
+
+// UploadInfo provides information about a file that has been uploaded since
+// the renter started. Uploaded and Total count the bytes of the
+// erasure-coded pieces of the file, so Total is larger than Filesize by the
+// redundancy of the file. Speed and ETA are measured like those of a
+// DownloadInfo. Priority and Paused are the scheduling of the file's uploads
+// and repairs.
+type UploadInfo struct {
+	ID        uint64             `json:"id"`
+	SiaPath   string             `json:"siapath"`
+	Source    string             `json:"source"`
+	Filesize  uint64             `json:"filesize"`
+	Uploaded  uint64             `json:"uploaded"`
+	Total     uint64             `json:"total"`
+	StartTime time.Time          `json:"starttime"`
+	Complete  bool               `json:"complete"`
+	Speed     uint64             `json:"speed"`
+	ETA       uint64             `json:"eta"`
+	Hosts     []TransferHostInfo `json:"hosts"`
+	Priority  int                `json:"priority"`
+	Paused    bool               `json:"paused"`
+	Error     string             `json:"error"`
+}
+
+// TransferHostInfo counts the pieces of a transfer that a host has
+// transferred, and the attempts to transfer a piece to or from the host that
+// failed.
+type TransferHostInfo struct {
+	NetAddress NetAddress `json:"netaddress"`
+	Pieces     uint64     `json:"pieces"`
+	Failures   uint64     `json:"failures"`
+}
+
+// A TransferEvent reports the progress of an upload or a download. Exactly one
+// of Download and Upload is set, to the state of the transfer at the time of
+// the event.
+type TransferEvent struct {
+	Download *DownloadInfo `json:"download,omitempty"`
+	Upload   *UploadInfo   `json:"upload,omitempty"`
+}
+
+// A TransferSubscriber receives an event each time a transfer of the renter
+// starts, makes progress, completes or fails. ProcessTransferEvent is called
+// by the threads that perform the transfers, and must not block.
+type TransferSubscriber interface {
+	ProcessTransferEvent(TransferEvent)
 }
 
 // DownloadWriter provides an interface which all output writers have to implement.
@@ -65,22 +154,57 @@ type DownloadWriter interface {
 }
 
 // FileUploadParams contains the information used by the Renter to upload a
-// file.
+// file. A nil ErasureCode and a zero PieceSize are replaced by the defaults
+// of the renter. The pieces of files with a higher Priority are uploaded and
+// repaired first.
 type FileUploadParams struct {
 	Source      string
 	SiaPath     string
 	ErasureCode ErasureCoder
+	PieceSize   uint64
+	Priority    int
+
+	// Class is the name of the redundancy class of the file. The erasure
+	// code of the class is used, so ErasureCode must not be set.
+	Class string
+
+	// Include and Exclude filter the files of a directory upload by glob
+	// patterns. They are ignored when a single file is uploaded.
+	Include []string
+	Exclude []string
 }
 
-// FileInfo provides information about a file.
+// FileInfo provides information about a file. Redundancy and Health only count
+// the pieces stored on online hosts. Health is the percentage of the pieces of
+// the least healthy chunk that are stored. A file is AtRisk if it is available,
+// but would not be if the hosts that failed their most recent scan went
+// offline. A file is Converting while it is uploaded again with the layout of
+// its redundancy class.
 type FileInfo struct {
 	SiaPath        string            `json:"siapath"`
 	Filesize       uint64            `json:"filesize"`
 	Available      bool              `json:"available"`
 	Renewing       bool              `json:"renewing"`
 	Redundancy     float64           `json:"redundancy"`
+	Health         float64           `json:"health"`
+	AtRisk         bool              `json:"atrisk"`
 	UploadProgress float64           `json:"uploadprogress"`
 	Expiration     types.BlockHeight `json:"expiration"`
+	DataPieces     int               `json:"datapieces"`
+	ParityPieces   int               `json:"paritypieces"`
+	PieceSize      uint64            `json:"piecesize"`
+	Repairing      bool              `json:"repairing"`
+	RepairProgress float64           `json:"repairprogress"`
+
+	Class              string  `json:"class"`
+	Converting         bool    `json:"converting"`
+	ConversionProgress float64 `json:"conversionprogress"`
+}
+
+// ShareLinkInfo describes the file that a share link points to.
+type ShareLinkInfo struct {
+	SiaPath  string `json:"siapath"`
+	Filesize uint64 `json:"filesize"`
 }
 
 // A HostDBEntry represents one host entry in the Renter's host DB. It
@@ -101,12 +225,41 @@ type HostDBEntry struct {
 	// The public key of the host, stored separately to minimize risk of certain
 	// MitM based vulnerabilities.
 	PublicKey types.SiaPublicKey `json:"publickey"`
+
+	// Subnet is the subnet of the IP address that the host was last reached
+	// at, as returned by Subnet. It is empty if the host was never reached.
+	Subnet string `json:"subnet"`
+}
+
+// AverageRTT returns the average round-trip time of the successful scans of
+// the host, or zero if no round-trip time has been measured.
+func (he HostDBEntry) AverageRTT() time.Duration {
+	var total time.Duration
+	var measurements time.Duration
+	for _, scan := range he.ScanHistory {
+		if scan.Success && scan.RTT > 0 {
+			total += scan.RTT
+			measurements++
+		}
+	}
+	if measurements == 0 {
+		return 0
+	}
+	return total / measurements
 }
 
 // HostDBScan represents a single scan event.
 type HostDBScan struct {
 	Timestamp time.Time `json:"timestamp"`
 	Success   bool      `json:"success"`
+
+	// Latency is the time it took a successful scan to connect to the host
+	// and receive its settings. It is zero for failed scans.
+	Latency time.Duration `json:"latency"`
+
+	// RTT is the round-trip time to the host, measured as the time it took
+	// the scan to open a connection to the host. It is zero for failed scans.
+	RTT time.Duration `json:"rtt"`
 }
 
 // HostScoreBreakdown provides a piece-by-piece explanation of why a host has
@@ -123,12 +276,33 @@ type HostScoreBreakdown struct {
 	AgeAdjustment              float64 `json:"ageadjustment"`
 	BurnAdjustment             float64 `json:"burnadjustment"`
 	CollateralAdjustment       float64 `json:"collateraladjustment"`
+	LatencyAdjustment          float64 `json:"latencyadjustment"`
 	PriceAdjustment            float64 `json:"pricesmultiplier"`
 	StorageRemainingAdjustment float64 `json:"storageremainingadjustment"`
 	UptimeAdjustment           float64 `json:"uptimeadjustment"`
 	VersionAdjustment          float64 `json:"versionadjustment"`
 }
 
+// RenterCostEstimate estimates the cost of uploading and storing data, before
+// any funds are committed to contracts.
+type RenterCostEstimate struct {
+	// The number of hosts that contracts would be formed with.
+	Hosts int `json:"hosts"`
+
+	// The cost of storing the data, including redundancy, for the duration.
+	Storage types.Currency `json:"storage"`
+
+	// The cost of uploading the data, including redundancy.
+	Upload types.Currency `json:"upload"`
+
+	// The contract prices of the hosts, the transaction fees of forming the
+	// contracts, and the siafund fee.
+	ContractFees types.Currency `json:"contractfees"`
+
+	// The sum of the costs.
+	Total types.Currency `json:"total"`
+}
+
 // RenterPriceEstimation contains a bunch of files estimating the costs of
 // various operations on the network.
 type RenterPriceEstimation struct {
@@ -146,9 +320,159 @@ type RenterPriceEstimation struct {
 	UploadTerabyte types.Currency `json:"uploadterabyte"`
 }
 
+// A RedundancyClass is a named erasure coding layout, such as 10-of-30 for
+// archives or 1-of-3 replication for frequently used files. The files that
+// are assigned to a class are converted to its layout by the repair loop.
+type RedundancyClass struct {
+	Name         string `json:"name"`
+	DataPieces   int    `json:"datapieces"`
+	ParityPieces int    `json:"paritypieces"`
+}
+
 // RenterSettings control the behavior of the Renter.
 type RenterSettings struct {
-	Allowance Allowance `json:"allowance"`
+	Allowance  Allowance  `json:"allowance"`
+	RateLimits RateLimits `json:"ratelimits"`
+}
+
+// RateLimits limits the bandwidth that the renter uses to transfer data with
+// hosts, in bytes per second. The Max*Speed limits apply to all connections
+// together, and the MaxHost*Speed limits to each connection to a host. A limit
+// of 0 is unlimited.
+type RateLimits struct {
+	MaxDownloadSpeed     int64 `json:"maxdownloadspeed"`
+	MaxUploadSpeed       int64 `json:"maxuploadspeed"`
+	MaxHostDownloadSpeed int64 `json:"maxhostdownloadspeed"`
+	MaxHostUploadSpeed   int64 `json:"maxhostuploadspeed"`
+}
+
+// A HostFilter restricts the hosts that the renter forms contracts with and
+// uploads to. A host matches the filter if its public key is in Hosts, or if
+// the IP address it announced is in one of NetRanges, which are in CIDR
+// notation. Hosts announced with a domain name are only matched by their
+// public key. Mode decides whether matching hosts are excluded (blacklist) or
+// are the only hosts used (whitelist).
+type HostFilter struct {
+	Mode      string               `json:"mode"`
+	Hosts     []types.SiaPublicKey `json:"hosts"`
+	NetRanges []string             `json:"netranges"`
+}
+
+// RenterKeys contains the keys that the renter encrypts files with. The key of
+// every uploaded file is derived from MasterKey, which is itself derived from
+// the wallet seed. FileKeys maps the siapath of every file to its key.
+type RenterKeys struct {
+	MasterKey crypto.TwofishKey
+	FileKeys  map[string]crypto.TwofishKey
+}
+
+// RenterSnapshot describes a snapshot of the renter's metadata that is stored
+// on its hosts. TransactionID is the transaction that records the location of
+// the snapshot, and Size is the size of the encrypted snapshot in bytes.
+type RenterSnapshot struct {
+	TransactionID types.TransactionID  `json:"transactionid"`
+	Timestamp     types.Timestamp      `json:"timestamp"`
+	Size          uint64               `json:"size"`
+	NumFiles      uint64               `json:"numfiles"`
+	Hosts         []types.SiaPublicKey `json:"hosts"`
+}
+
+// SpendingBreakdown is the amount of money spent on storing, uploading and
+// downloading data.
+type SpendingBreakdown struct {
+	StorageSpending  types.Currency `json:"storagespending"`
+	UploadSpending   types.Currency `json:"uploadspending"`
+	DownloadSpending types.Currency `json:"downloadspending"`
+}
+
+// ContractSpending is the spending of a contract of the renter. Sectors is
+// the number of sectors stored in the contract, Fees are the fees paid to form
+// the contract, and RemainingFunds are the funds of the contract that have not
+// been spent.
+type ContractSpending struct {
+	ID             types.FileContractID `json:"id"`
+	NetAddress     NetAddress           `json:"netaddress"`
+	HostPublicKey  types.SiaPublicKey   `json:"hostpublickey"`
+	StartHeight    types.BlockHeight    `json:"startheight"`
+	EndHeight      types.BlockHeight    `json:"endheight"`
+	Sectors        uint64               `json:"sectors"`
+	Fees           types.Currency       `json:"fees"`
+	RemainingFunds types.Currency       `json:"remainingfunds"`
+	TotalCost      types.Currency       `json:"totalcost"`
+	SpendingBreakdown
+}
+
+// FileSpending is the share of the spending of the renter's contracts that is
+// attributed to a file. Sectors is the number of sectors of the file that are
+// stored in the contracts.
+type FileSpending struct {
+	SiaPath string `json:"siapath"`
+	Sectors uint64 `json:"sectors"`
+	SpendingBreakdown
+}
+
+// RenterSpendingReport shows where the money of the renter's contracts went.
+// The storage and upload spending of a contract is split among the files by
+// the number of sectors they store in the contract, and its download spending
+// by the number of sectors that were downloaded for them. Unattributed is the
+// spending that belongs to no file, such as the spending on the sectors of
+// deleted files and of snapshots.
+type RenterSpendingReport struct {
+	Contracts    []ContractSpending `json:"contracts"`
+	Files        []FileSpending     `json:"files"`
+	Unattributed SpendingBreakdown  `json:"unattributed"`
+}
+
+// ContractForecast describes the utilization of a contract of the renter, and
+// forecasts when its funds run out. SpendingRate is the average amount that
+// the contract has spent per block since it was formed. DepletionHeight is
+// the height at which the remaining funds run out at that rate, or zero if
+// the contract has not spent anything. The contract is renewed at
+// RenewHeight; if its funds run out before then, uploads to its host fail
+// until the contract is refreshed from the allowance.
+type ContractForecast struct {
+	ID              types.FileContractID `json:"id"`
+	NetAddress      NetAddress           `json:"netaddress"`
+	HostPublicKey   types.SiaPublicKey   `json:"hostpublickey"`
+	StartHeight     types.BlockHeight    `json:"startheight"`
+	EndHeight       types.BlockHeight    `json:"endheight"`
+	RenewHeight     types.BlockHeight    `json:"renewheight"`
+	RemainingBlocks types.BlockHeight    `json:"remainingblocks"`
+	Size            uint64               `json:"size"`
+	TotalCost       types.Currency       `json:"totalcost"`
+	RemainingFunds  types.Currency       `json:"remainingfunds"`
+	SpendingRate    types.Currency       `json:"spendingrate"`
+	DepletionHeight types.BlockHeight    `json:"depletionheight"`
+
+	DepletesBeforeRenewal bool `json:"depletesbeforerenewal"`
+}
+
+// RenterForecast forecasts when the funds of the renter's contracts run out,
+// as of the block height Height.
+type RenterForecast struct {
+	Height    types.BlockHeight  `json:"height"`
+	Contracts []ContractForecast `json:"contracts"`
+}
+
+// HostVerification is the result of challenging a host to prove that it
+// stores segments of the pieces of a file. Failures counts the challenges
+// that the host did not answer with a valid proof. Error is set if the host
+// could not be challenged at all, in which case nothing is known about the
+// pieces that it stores.
+type HostVerification struct {
+	NetAddress    NetAddress         `json:"netaddress"`
+	HostPublicKey types.SiaPublicKey `json:"hostpublickey"`
+	Pieces        int                `json:"pieces"`
+	Challenges    int                `json:"challenges"`
+	Failures      int                `json:"failures"`
+	Error         string             `json:"error,omitempty"`
+}
+
+// FileVerification reports the results of verifying the storage of a file on
+// each of the hosts that store its pieces.
+type FileVerification struct {
+	SiaPath string             `json:"siapath"`
+	Hosts   []HostVerification `json:"hosts"`
 }
 
 // HostDBScans represents a sortable slice of scans.
@@ -245,6 +569,9 @@ type Renter interface {
 	// AllHosts returns the full list of hosts known to the renter.
 	AllHosts() []HostDBEntry
 
+	// CancelUpload stops the upload with the given ID and deletes its file.
+	CancelUpload(id uint64) error
+
 	// Close closes the Renter.
 	Close() error
 
@@ -255,19 +582,74 @@ type Renter interface {
 	// began.
 	CurrentPeriod() types.BlockHeight
 
+	// CreateBackup writes an encrypted backup of the files, contracts and
+	// allowance of the renter to dst.
+	CreateBackup(dst string) error
+
+	// CreateSnapshot stores an encrypted backup of the renter on its hosts,
+	// and records its location in a transaction of the wallet, so that it
+	// can be recovered from the wallet seed.
+	CreateSnapshot() (RenterSnapshot, error)
+
+	// CreateDir creates an empty directory.
+	CreateDir(path string) error
+
+	// DeleteDir deletes a directory along with the files and directories
+	// within it.
+	DeleteDir(path string) error
+
 	// DeleteFile deletes a file entry from the renter.
 	DeleteFile(path string) error
 
+	// DeleteRedundancyClass deletes a redundancy class that is not assigned
+	// to any file.
+	DeleteRedundancyClass(name string) error
+
+	// DirList returns information on a directory, along with the
+	// directories and files directly within it. The root directory has the
+	// empty path.
+	DirList(path string) (DirectoryInfo, []DirectoryInfo, []FileInfo, error)
+
 	// Download performs a download according to the parameters passed, including
 	// downloads of `offset` and `length` type.
 	Download(params RenterDownloadParameters) error
 
+	// DownloadCacheMetrics returns the usage of the cache of downloaded
+	// chunks, and the number of chunks that were and were not found in it.
+	DownloadCacheMetrics() DownloadCacheMetrics
+
 	// DownloadQueue lists all the files that have been scheduled for download.
 	DownloadQueue() []DownloadInfo
 
+	// DownloadSection downloads length bytes of a file, starting at offset,
+	// and writes them to w in order. A length of 0 downloads the rest of the
+	// file.
+	DownloadSection(siapath string, offset, length uint64, w io.Writer) error
+
+	// DownloadShareLink downloads length bytes of the file that a share link
+	// points to, starting at offset, and writes them to w. The file is not
+	// added to the renter.
+	DownloadShareLink(link string, offset, length uint64, w io.Writer) error
+
+	// EstimateCost estimates the cost of uploading size bytes with the given
+	// redundancy and storing them for duration blocks.
+	EstimateCost(size uint64, redundancy float64, duration types.BlockHeight) (RenterCostEstimate, error)
+
+	// ExportKeys returns the keys that the Renter encrypts files with, so
+	// that they can be backed up.
+	ExportKeys() (RenterKeys, error)
+
 	// FileList returns information on all of the files stored by the renter.
 	FileList() []FileInfo
 
+	// Forecast reports the utilization of each contract of the renter, and
+	// forecasts when its funds run out at the rate they have been spent.
+	Forecast() RenterForecast
+
+	// HostFilter returns the filter that restricts the hosts used by the
+	// renter.
+	HostFilter() HostFilter
+
 	// Host provides the DB entry and score breakdown for the requested host.
 	Host(pk types.SiaPublicKey) (HostDBEntry, bool)
 
@@ -279,10 +661,36 @@ type Renter interface {
 	// renter.
 	LoadSharedFilesAscii(asciiSia string) ([]string, error)
 
+	// LoadSharedFilesReader loads '.sia' data into the renter. The data
+	// contains everything needed to download the files, such as their
+	// erasure coding, keys and the contracts holding their pieces.
+	LoadSharedFilesReader(r io.Reader) ([]string, error)
+
+	// Pause stops all background activity of the renter: uploads, repairs,
+	// snapshots and the formation and renewal of contracts. Downloads are
+	// not affected. The renter stays paused across restarts until Resume is
+	// called.
+	Pause() error
+
+	// Paused reports whether the renter is paused.
+	Paused() bool
+
+	// PauseUpload stops the renter from uploading and repairing the file of
+	// the upload with the given ID until ResumeUpload is called.
+	PauseUpload(id uint64) error
+
 	// PriceEstimation estimates the cost in siacoins of performing various
 	// storage and data operations.
 	PriceEstimation() RenterPriceEstimation
 
+	// RedundancyClasses returns the redundancy classes of the renter, sorted
+	// by name.
+	RedundancyClasses() []RedundancyClass
+
+	// RenameDir changes the path of a directory and of everything within
+	// it.
+	RenameDir(path, newPath string) error
+
 	// RenameFile changes the path of a file.
 	RenameFile(path, newPath string) error
 
@@ -290,6 +698,22 @@ type Renter interface {
 	// settings, assuming perfect age and uptime adjustments
 	EstimateHostScore(entry HostDBEntry) HostScoreBreakdown
 
+	// RecoverSnapshot adds the files, contracts and allowance of the most
+	// recent snapshot found in the wallet's transactions to the renter. The
+	// paths of the restored files are returned.
+	RecoverSnapshot() ([]string, error)
+
+	// RestoreBackup adds the files, contracts and allowance of a backup
+	// created by CreateBackup to the renter. The paths of the restored files
+	// are returned.
+	RestoreBackup(src string) ([]string, error)
+
+	// Resume resumes the background activity of a renter paused by Pause.
+	Resume() error
+
+	// ResumeUpload resumes an upload paused by PauseUpload.
+	ResumeUpload(id uint64) error
+
 	// ScoreBreakdown will return the score for a host db entry using the
 	// hostdb's weighting algorithm.
 	ScoreBreakdown(entry HostDBEntry) HostScoreBreakdown
@@ -297,17 +721,84 @@ type Renter interface {
 	// Settings returns the Renter's current settings.
 	Settings() RenterSettings
 
+	// SetRateLimits sets the bandwidth limits of the Renter, without
+	// changing its allowance.
+	SetRateLimits(RateLimits) error
+
+	// SetFileClass assigns a file to a redundancy class, after which the file
+	// is converted to the layout of the class. The empty class leaves the
+	// file with its current layout.
+	SetFileClass(path, class string) error
+
+	// SetHostFilter sets the filter that restricts the hosts used by the
+	// renter. Existing contracts with excluded hosts are not renewed.
+	SetHostFilter(HostFilter) error
+
+	// SetRedundancyClass defines a redundancy class, or changes the layout of
+	// an existing one.
+	SetRedundancyClass(RedundancyClass) error
+
 	// SetSettings sets the Renter's settings.
 	SetSettings(RenterSettings) error
 
+	// SetUploadPriority sets the priority of the file of the upload with the
+	// given ID. The pieces of files with a higher priority are uploaded and
+	// repaired first.
+	SetUploadPriority(id uint64, priority int) error
+
 	// ShareFiles creates a '.sia' file that can be shared with others.
 	ShareFiles(paths []string, shareDest string) error
 
 	// ShareFilesAscii creates an ASCII-encoded '.sia' file.
 	ShareFilesAscii(paths []string) (asciiSia string, err error)
 
+	// ShareFilesWriter writes a '.sia' file to w, allowing another renter
+	// to download the files.
+	ShareFilesWriter(paths []string, w io.Writer) error
+
+	// ShareLink returns a link from which other renters can download the
+	// file at siapath.
+	ShareLink(siapath string) (string, error)
+
+	// ShareLinkInfo returns the siapath and size of the file that a share
+	// link points to.
+	ShareLinkInfo(link string) (ShareLinkInfo, error)
+
+	// Snapshots returns the snapshots of the renter that are stored on its
+	// hosts, most recent first.
+	Snapshots() []RenterSnapshot
+
+	// SpendingReport returns the spending of each contract of the renter, and
+	// the share of it that is attributed to each file.
+	SpendingReport() RenterSpendingReport
+
+	// TransferSubscribe adds a subscriber that is notified of the progress
+	// of uploads and downloads.
+	TransferSubscribe(TransferSubscriber)
+
+	// TransferUnsubscribe removes a subscriber added by TransferSubscribe.
+	TransferUnsubscribe(TransferSubscriber)
+
 	// Upload uploads a file using the input parameters.
 	Upload(FileUploadParams) error
+
+	// UploadDirectory uploads the files of a local directory, and of its
+	// subdirectories, into a directory of the renter.
+	UploadDirectory(FileUploadParams) error
+
+	// UploadQueue lists the files that have been uploaded since the renter
+	// started, most recent first.
+	UploadQueue() []UploadInfo
+
+	// UploadStreamFromReader uploads the data read from r as a new file,
+	// without writing the data to disk. The Source of the params is
+	// ignored.
+	UploadStreamFromReader(up FileUploadParams, r io.Reader) error
+
+	// Verify challenges the hosts that store the pieces of a file to prove
+	// that they still store random segments of the pieces, and reports
+	// which hosts fail.
+	Verify(siapath string) (FileVerification, error)
 }
 
 // RenterDownloadParameters defines the parameters passed to the Renter's
@@ -0,0 +1,264 @@
+package renter
+
+// backup.go creates and restores backups of the renter's metadata. A backup
+// holds everything needed to download the renter's files on another node: the
+// .sia data of the files, the contracts that their pieces are stored in, and
+// the allowance that the contracts are renewed with. The backup is encrypted
+// with a key derived from the renter's master key, so it can be restored by any
+// node whose wallet has the same seed.
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/persist"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+const (
+	// backupManifestFile and backupDataFile are the names of the files of a
+	// backup archive.
+	backupManifestFile = "manifest.json"
+	backupDataFile     = "renter.dat"
+)
+
+var (
+	backupMetadata = persist.Metadata{
+		Header:  "Renter Backup",
+		Version: "1.0.0",
+	}
+
+	// backupKeySpecifier separates the key of backups from the other keys
+	// derived from the master key.
+	backupKeySpecifier = types.Specifier{'b', 'a', 'c', 'k', 'u', 'p', ' ', 'k', 'e', 'y'}
+
+	errBadBackup    = errors.New("file is not a renter backup")
+	errBackupKey    = errors.New("backup was not created with the master key of this renter")
+	errBackupNoData = errors.New("backup archive is missing its data")
+)
+
+// backupManifest is the first file of a backup archive, identifying the
+// archive. It is not encrypted.
+type backupManifest struct {
+	persist.Metadata
+	NumFiles  int       `json:"numfiles"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// renterBackup is the encrypted content of a backup archive.
+type renterBackup struct {
+	Allowance   modules.Allowance        `json:"allowance"`
+	Contracts   []modules.RenterContract `json:"contracts"`
+	Directories []string                 `json:"directories"`
+	// Files holds the .sia data of the files, whose siapaths are listed in
+	// the same order in Paths.
+	Files    []byte                 `json:"files"`
+	Paths    []string               `json:"paths"`
+	Tracking map[string]trackedFile `json:"tracking"`
+}
+
+// deriveBackupKey derives the key that backups are encrypted with from the
+// renter's master key.
+func deriveBackupKey(masterKey crypto.TwofishKey) crypto.TwofishKey {
+	return crypto.TwofishKey(crypto.HashAll(backupKeySpecifier, masterKey))
+}
+
+// managedBackup collects the metadata of the renter into a renterBackup.
+func (r *Renter) managedBackup() (renterBackup, error) {
+	// Point the files at the latest renewals of their contracts, so that the
+	// backup does not depend on the renewal history of the contractor.
+	r.managedMigrateRenewedContracts()
+
+	b := renterBackup{
+		Allowance: r.hostContractor.Allowance(),
+		Contracts: r.AllContracts(),
+		Tracking:  make(map[string]trackedFile),
+	}
+
+	lockID := r.mu.RLock()
+	defer r.mu.RUnlock(lockID)
+	for siapath := range r.files {
+		b.Paths = append(b.Paths, siapath)
+	}
+	sort.Strings(b.Paths)
+	for siapath, tf := range r.tracking {
+		b.Tracking[siapath] = tf
+	}
+	for dir := range r.dirs {
+		b.Directories = append(b.Directories, dir)
+	}
+	sort.Strings(b.Directories)
+	files, err := r.sharedFiles(b.Paths)
+	if err != nil {
+		return renterBackup{}, err
+	}
+	buf := new(bytes.Buffer)
+	if err := shareFiles(files, buf); err != nil {
+		return renterBackup{}, err
+	}
+	b.Files = buf.Bytes()
+	return b, nil
+}
+
+// CreateBackup writes an encrypted backup of the renter's files, contracts
+// and allowance to dst. The backup can be restored with RestoreBackup by any
+// renter whose wallet has the same seed.
+func (r *Renter) CreateBackup(dst string) error {
+	if err := r.tg.Add(); err != nil {
+		return err
+	}
+	defer r.tg.Done()
+	masterKey, err := r.managedMasterKey()
+	if err != nil {
+		return err
+	}
+	b, err := r.managedBackup()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	ciphertext := deriveBackupKey(masterKey).EncryptBytes(data)
+	manifest, err := json.Marshal(backupManifest{
+		Metadata:  backupMetadata,
+		NumFiles:  len(b.Paths),
+		Timestamp: time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	tw := tar.NewWriter(f)
+	for _, entry := range []struct {
+		name string
+		data []byte
+	}{
+		{backupManifestFile, manifest},
+		{backupDataFile, ciphertext},
+	} {
+		err = tw.WriteHeader(&tar.Header{
+			Name:    entry.name,
+			Mode:    0600,
+			Size:    int64(len(entry.data)),
+			ModTime: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := tw.Write(entry.data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// readBackup checks the manifest of the backup archive at src and decrypts
+// its content with the key derived from masterKey.
+func readBackup(src string, masterKey crypto.TwofishKey) (renterBackup, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return renterBackup{}, err
+	}
+	defer f.Close()
+	tr := tar.NewReader(f)
+
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != backupManifestFile {
+		return renterBackup{}, errBadBackup
+	}
+	var manifest backupManifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return renterBackup{}, errBadBackup
+	}
+	if manifest.Header != backupMetadata.Header {
+		return renterBackup{}, errBadBackup
+	} else if manifest.Version != backupMetadata.Version {
+		return renterBackup{}, persist.ErrBadVersion
+	}
+
+	hdr, err = tr.Next()
+	if err != nil || hdr.Name != backupDataFile {
+		return renterBackup{}, errBackupNoData
+	}
+	ciphertext, err := ioutil.ReadAll(tr)
+	if err != nil {
+		return renterBackup{}, err
+	}
+	data, err := deriveBackupKey(masterKey).DecryptBytes(ciphertext)
+	if err != nil {
+		return renterBackup{}, errBackupKey
+	}
+	var b renterBackup
+	if err := json.Unmarshal(data, &b); err != nil {
+		return renterBackup{}, err
+	}
+	return b, nil
+}
+
+// RestoreBackup adds the files, contracts and allowance of the backup at src
+// to the renter. The backup must have been created by a renter whose wallet
+// has the same seed. Files whose siapath is already in use are restored under
+// a new siapath. The paths of the restored files are returned.
+func (r *Renter) RestoreBackup(src string) ([]string, error) {
+	if err := r.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer r.tg.Done()
+	masterKey, err := r.managedMasterKey()
+	if err != nil {
+		return nil, err
+	}
+	b, err := readBackup(src, masterKey)
+	if err != nil {
+		return nil, err
+	}
+	return r.managedRestore(b)
+}
+
+// managedRestore adds the files, contracts and allowance of a backup to the
+// renter, returning the paths of the restored files.
+func (r *Renter) managedRestore(b renterBackup) ([]string, error) {
+	// Restore the contracts first, so that the files can be downloaded as
+	// soon as they are added.
+	if err := r.hostContractor.RestoreContracts(b.Allowance, b.Contracts); err != nil {
+		return nil, err
+	}
+
+	lockID := r.mu.Lock()
+	defer r.mu.Unlock(lockID)
+	names, err := r.loadSharedFiles(bytes.NewReader(b.Files))
+	if err != nil {
+		return nil, err
+	}
+	if len(names) != len(b.Paths) {
+		return nil, errBadBackup
+	}
+	for i, name := range names {
+		if tf, ok := b.Tracking[b.Paths[i]]; ok {
+			r.tracking[name] = tf
+		}
+	}
+	for _, dir := range b.Directories {
+		r.dirs[dir] = struct{}{}
+	}
+	r.log.Printf("INFO: restored %v files and %v contracts from a backup", len(names), len(b.Contracts))
+	return names, r.saveSync()
+}
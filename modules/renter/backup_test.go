@@ -0,0 +1,94 @@
+package renter
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/NebulousLabs/fastrand"
+)
+
+// TestBackupRestore checks that the files of a renter can be restored from a
+// backup by another renter with the same master key, and only by such a
+// renter.
+func TestBackupRestore(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+
+	f := newTestingFile()
+	f.name = "foo/bar"
+	id := rt.renter.mu.Lock()
+	rt.renter.files[f.name] = f
+	rt.renter.tracking[f.name] = trackedFile{RepairPath: "/tmp/bar"}
+	rt.renter.dirs["foo"] = struct{}{}
+	rt.renter.mu.Unlock(id)
+
+	backup := filepath.Join(rt.renter.persistDir, "renter.backup")
+	if err := rt.renter.CreateBackup(backup); err != nil {
+		t.Fatal(err)
+	}
+	masterKey, err := rt.renter.managedMasterKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A renter with a different master key cannot read the backup.
+	rt2, err := newRenterTester(t.Name() + "-restore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt2.Close()
+	if _, err := rt2.renter.RestoreBackup(backup); err != errBackupKey {
+		t.Fatal("expected errBackupKey, got", err)
+	}
+
+	// With the same master key, the files are restored.
+	id = rt2.renter.mu.Lock()
+	rt2.renter.masterKey = masterKey
+	rt2.renter.mu.Unlock(id)
+	names, err := rt2.renter.RestoreBackup(backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names[0] != f.name {
+		t.Fatal("wrong files were restored:", names)
+	}
+	id = rt2.renter.mu.RLock()
+	restored := rt2.renter.files[f.name]
+	tf, tracked := rt2.renter.tracking[f.name]
+	_, hasDir := rt2.renter.dirs["foo"]
+	rt2.renter.mu.RUnlock(id)
+	if err := equalFiles(f, restored); err != nil {
+		t.Fatal(err)
+	}
+	if !tracked || tf.RepairPath != "/tmp/bar" {
+		t.Fatal("tracking was not restored:", tf)
+	}
+	if !hasDir {
+		t.Fatal("directories were not restored")
+	}
+
+	// Restoring again does not overwrite the restored file.
+	names, err = rt2.renter.RestoreBackup(backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names[0] != f.name+"_1" {
+		t.Fatal("restored file did not get a new siapath:", names)
+	}
+
+	// Other files are rejected.
+	other := filepath.Join(rt2.renter.persistDir, "other")
+	if err := ioutil.WriteFile(other, fastrand.Bytes(64), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rt2.renter.RestoreBackup(other); err != errBadBackup {
+		t.Fatal("expected errBadBackup, got", err)
+	}
+}
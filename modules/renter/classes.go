@@ -0,0 +1,229 @@
+package renter
+
+// classes.go implements redundancy classes: named erasure coding layouts that
+// files are assigned to. A file whose layout differs from the layout of its
+// class is converted by uploading it again, chunk by chunk, as a separate
+// file with the layout of the class. The data of the chunks is read from the
+// source of the file on disk, or downloaded from the pieces of the file.
+// Until the conversion is complete, the file keeps being downloaded and
+// repaired with its old layout; then the conversion replaces it.
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+var (
+	errBadClassName   = errors.New("redundancy class names must be nonempty and cannot contain '/'")
+	errBadClassLayout = errors.New("redundancy classes need at least one data piece and one parity piece")
+	errClassInUse     = errors.New("redundancy class is assigned to files")
+	errClassWithCode  = errors.New("an upload cannot have both a redundancy class and an erasure code")
+	errUnknownClass   = errors.New("no redundancy class with that name exists")
+	errUntrackedClass = errors.New("only files that are repaired by the renter can be assigned a redundancy class")
+)
+
+// hasLayout reports whether an erasure code has the layout of a redundancy
+// class.
+func hasLayout(ec modules.ErasureCoder, rc modules.RedundancyClass) bool {
+	return ec.MinPieces() == rc.DataPieces && ec.NumPieces() == rc.DataPieces+rc.ParityPieces
+}
+
+// complete reports whether every piece of every chunk of the file has been
+// uploaded.
+func (f *file) complete() bool {
+	for i := uint64(0); i < f.numChunks(); i++ {
+		if f.chunkPieces(i) < f.erasureCode.NumPieces() {
+			return false
+		}
+	}
+	return true
+}
+
+// conversionPath returns the path of the .sia file of the conversion of the
+// file with the given name.
+func (r *Renter) conversionPath(name string) string {
+	return filepath.Join(r.persistDir, conversionsDir, name+ShareExtension)
+}
+
+// discardConversion stops the conversion of the file with the given name,
+// if any. The caller must hold the renter lock.
+func (r *Renter) discardConversion(name string) {
+	if _, exists := r.conversions[name]; !exists {
+		return
+	}
+	delete(r.conversions, name)
+	os.Remove(r.conversionPath(name))
+}
+
+// redundancyClasses returns the redundancy classes sorted by name. The caller
+// must hold the renter lock.
+func (r *Renter) redundancyClasses() []modules.RedundancyClass {
+	classes := make([]modules.RedundancyClass, 0, len(r.classes))
+	for _, rc := range r.classes {
+		classes = append(classes, rc)
+	}
+	sort.Slice(classes, func(i, j int) bool {
+		return classes[i].Name < classes[j].Name
+	})
+	return classes
+}
+
+// RedundancyClasses returns the redundancy classes of the renter, sorted by
+// name.
+func (r *Renter) RedundancyClasses() []modules.RedundancyClass {
+	lockID := r.mu.RLock()
+	defer r.mu.RUnlock(lockID)
+	return r.redundancyClasses()
+}
+
+// SetRedundancyClass defines a redundancy class, or changes the layout of an
+// existing one. The files of a changed class are converted to its new layout.
+func (r *Renter) SetRedundancyClass(rc modules.RedundancyClass) error {
+	if rc.Name == "" || strings.Contains(rc.Name, "/") {
+		return errBadClassName
+	} else if rc.DataPieces < 1 || rc.ParityPieces < 1 {
+		return errBadClassLayout
+	} else if _, err := NewRSCode(rc.DataPieces, rc.ParityPieces); err != nil {
+		return err
+	}
+
+	lockID := r.mu.Lock()
+	r.classes[rc.Name] = rc
+	err := r.saveSync()
+	r.mu.Unlock(lockID)
+	if err != nil {
+		return err
+	}
+	go r.threadedQueueRepairsNow()
+	return nil
+}
+
+// DeleteRedundancyClass deletes a redundancy class that is not assigned to
+// any file.
+func (r *Renter) DeleteRedundancyClass(name string) error {
+	lockID := r.mu.Lock()
+	defer r.mu.Unlock(lockID)
+	if _, exists := r.classes[name]; !exists {
+		return errUnknownClass
+	}
+	for _, tf := range r.tracking {
+		if tf.Class == name {
+			return errClassInUse
+		}
+	}
+	delete(r.classes, name)
+	return r.saveSync()
+}
+
+// SetFileClass assigns a file to a redundancy class. The file is converted to
+// the layout of the class once it has been uploaded. Assigning the empty
+// class stops any conversion and leaves the file with its current layout.
+func (r *Renter) SetFileClass(siapath, class string) error {
+	lockID := r.mu.Lock()
+	if _, exists := r.files[siapath]; !exists {
+		r.mu.Unlock(lockID)
+		return ErrUnknownPath
+	}
+	tf, tracked := r.tracking[siapath]
+	if !tracked {
+		r.mu.Unlock(lockID)
+		return errUntrackedClass
+	} else if _, exists := r.classes[class]; !exists && class != "" {
+		r.mu.Unlock(lockID)
+		return errUnknownClass
+	}
+	tf.Class = class
+	r.tracking[siapath] = tf
+	if class == "" {
+		r.discardConversion(siapath)
+	}
+	err := r.saveSync()
+	r.mu.Unlock(lockID)
+	if err != nil {
+		return err
+	}
+	go r.threadedQueueRepairsNow()
+	return nil
+}
+
+// managedConversion returns the conversion of a file to the layout of its
+// redundancy class, starting the conversion if needed. It returns nil if the
+// file already has the layout of its class, or if the file is still being
+// uploaded.
+func (r *Renter) managedConversion(f *file) *file {
+	lockID := r.mu.Lock()
+	defer r.mu.Unlock(lockID)
+	rc, exists := r.classes[r.tracking[f.name].Class]
+	if !exists || r.files[f.name] != f {
+		return nil
+	}
+	if _, uploading := r.activeUpload(f); uploading {
+		return nil
+	}
+
+	f.mu.RLock()
+	converted := hasLayout(f.erasureCode, rc)
+	f.mu.RUnlock()
+	conv, converting := r.conversions[f.name]
+	if converting && !converted && hasLayout(conv.erasureCode, rc) {
+		return conv
+	}
+	// The class is new, or its layout has changed since the conversion
+	// started.
+	r.discardConversion(f.name)
+	if converted {
+		return nil
+	}
+
+	ec, err := NewRSCode(rc.DataPieces, rc.ParityPieces)
+	if err != nil {
+		r.log.Println("WARN: could not create the erasure code of a redundancy class:", err)
+		return nil
+	}
+	f.mu.RLock()
+	conv = newFile(f.name, ec, f.pieceSize, f.size)
+	conv.masterKey = f.masterKey
+	conv.mode = f.mode
+	f.mu.RUnlock()
+	r.conversions[f.name] = conv
+	if err := r.saveFile(conv); err != nil {
+		r.log.Println("WARN: could not save the conversion of a file:", err)
+	}
+	return conv
+}
+
+// managedFinishConversion replaces a file by its conversion once every piece
+// of the conversion has been uploaded. The pieces of the old layout are
+// abandoned.
+func (r *Renter) managedFinishConversion(name string) {
+	lockID := r.mu.Lock()
+	defer r.mu.Unlock(lockID)
+	conv, converting := r.conversions[name]
+	f, exists := r.files[name]
+	if !converting || !exists {
+		return
+	}
+	conv.mu.RLock()
+	complete := conv.complete()
+	conv.mu.RUnlock()
+	if !complete {
+		return
+	}
+
+	delete(r.conversions, name)
+	if err := r.saveFile(conv); err != nil {
+		r.log.Println("WARN: could not save a converted file:", err)
+		r.conversions[name] = conv
+		return
+	}
+	os.Remove(r.conversionPath(name))
+	r.files[name] = conv
+	delete(r.repairs, name)
+	r.downloadCache.removeFile(f.masterKey)
+	r.log.Printf("INFO: converted %v to %v-of-%v", name, conv.erasureCode.MinPieces(), conv.erasureCode.NumPieces())
+}
@@ -0,0 +1,205 @@
+package renter
+
+import (
+	"os"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestRedundancyClasses tests defining, assigning and deleting redundancy
+// classes.
+func TestRedundancyClasses(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+	r := rt.renter
+
+	bad := []modules.RedundancyClass{
+		{Name: "", DataPieces: 1, ParityPieces: 2},
+		{Name: "a/b", DataPieces: 1, ParityPieces: 2},
+		{Name: "none", DataPieces: 0, ParityPieces: 2},
+		{Name: "noparity", DataPieces: 1, ParityPieces: 0},
+	}
+	for _, rc := range bad {
+		if err := r.SetRedundancyClass(rc); err == nil {
+			t.Fatal("invalid class was accepted:", rc)
+		}
+	}
+	hot := modules.RedundancyClass{Name: "hot", DataPieces: 1, ParityPieces: 2}
+	archive := modules.RedundancyClass{Name: "archive", DataPieces: 10, ParityPieces: 20}
+	for _, rc := range []modules.RedundancyClass{hot, archive} {
+		if err := r.SetRedundancyClass(rc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if classes := r.RedundancyClasses(); len(classes) != 2 || classes[0] != archive || classes[1] != hot {
+		t.Fatal("wrong classes:", classes)
+	}
+
+	// Uploads use the erasure code of their class.
+	r.hostDB = activeHostsDB{hostDB: r.hostDB, n: 3}
+	up, err := r.managedUploadParams(modules.FileUploadParams{SiaPath: "foo", Class: "hot"})
+	if err != nil {
+		t.Fatal(err)
+	} else if !hasLayout(up.ErasureCode, hot) {
+		t.Fatal("upload does not have the layout of its class")
+	}
+	rsc, _ := NewRSCode(1, 1)
+	if _, err := r.managedUploadParams(modules.FileUploadParams{SiaPath: "foo", Class: "hot", ErasureCode: rsc}); err != errClassWithCode {
+		t.Fatal("expected errClassWithCode, got", err)
+	}
+	if _, err := r.managedUploadParams(modules.FileUploadParams{SiaPath: "foo", Class: "cold"}); err != errUnknownClass {
+		t.Fatal("expected errUnknownClass, got", err)
+	}
+
+	// Assign a file to a class.
+	lockID := r.mu.Lock()
+	r.files["foo"] = newFile("foo", rsc, 100, 100)
+	r.files["shared"] = newFile("shared", rsc, 100, 100)
+	r.tracking["foo"] = trackedFile{}
+	r.mu.Unlock(lockID)
+	if err := r.SetFileClass("bar", "hot"); err != ErrUnknownPath {
+		t.Fatal("expected ErrUnknownPath, got", err)
+	} else if err := r.SetFileClass("shared", "hot"); err != errUntrackedClass {
+		t.Fatal("expected errUntrackedClass, got", err)
+	} else if err := r.SetFileClass("foo", "cold"); err != errUnknownClass {
+		t.Fatal("expected errUnknownClass, got", err)
+	}
+	if err := r.SetFileClass("foo", "hot"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.DeleteRedundancyClass("hot"); err != errClassInUse {
+		t.Fatal("expected errClassInUse, got", err)
+	}
+	if err := r.DeleteRedundancyClass("archive"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.DeleteRedundancyClass("archive"); err != errUnknownClass {
+		t.Fatal("expected errUnknownClass, got", err)
+	}
+
+	// The classes and the class of the file should survive a reload.
+	lockID = r.mu.Lock()
+	r.classes = make(map[string]modules.RedundancyClass)
+	err = r.load()
+	r.mu.Unlock(lockID)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	if classes := r.RedundancyClasses(); len(classes) != 1 || classes[0] != hot {
+		t.Fatal("classes were not persisted:", classes)
+	}
+	if r.tracking["foo"].Class != "hot" {
+		t.Fatal("class of the file was not persisted")
+	}
+}
+
+// TestConversion checks that a file is converted to the layout of its
+// redundancy class, and that the conversion replaces the file once all of its
+// pieces have been uploaded.
+func TestConversion(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+	r := rt.renter
+
+	rsc, _ := NewRSCode(1, 1)
+	f := newFile("foo", rsc, 100, 150)
+	lockID := r.mu.Lock()
+	r.files["foo"] = f
+	r.tracking["foo"] = trackedFile{}
+	r.mu.Unlock(lockID)
+	if r.managedConversion(f) != nil {
+		t.Fatal("file without a class is converted")
+	}
+	hot := modules.RedundancyClass{Name: "hot", DataPieces: 1, ParityPieces: 2}
+	if err := r.SetRedundancyClass(hot); err != nil {
+		t.Fatal(err)
+	} else if err := r.SetFileClass("foo", "hot"); err != nil {
+		t.Fatal(err)
+	}
+
+	conv := r.managedConversion(f)
+	if conv == nil || !hasLayout(conv.erasureCode, hot) || conv.size != f.size || conv.masterKey != f.masterKey {
+		t.Fatal("conversion does not have the layout of the class:", conv)
+	}
+	if r.managedConversion(f) != conv {
+		t.Fatal("conversion was started twice")
+	}
+
+	// The chunks of the conversion are repaired separately from the chunks
+	// of the file.
+	rs := &repairState{
+		activeWorkers:     make(map[types.FileContractID]*worker),
+		availableWorkers:  make(map[types.FileContractID]*worker),
+		gapCounts:         make(map[int]int),
+		incompleteChunks:  make(map[chunkID]*chunkStatus),
+		cachedChunks:      make(map[chunkID][]byte),
+		downloadingChunks: make(map[chunkID]struct{}),
+	}
+	lockID = r.mu.Lock()
+	r.addFileToRepairState(rs, f)
+	r.addFileToRepairState(rs, conv)
+	r.mu.Unlock(lockID)
+	if len(rs.incompleteChunks) != 4 {
+		t.Fatal("wrong number of incomplete chunks:", len(rs.incompleteChunks))
+	} else if _, ok := rs.incompleteChunks[chunkID{index: 1, filename: "foo", conversion: true}]; !ok {
+		t.Fatal("chunks of the conversion were not added")
+	}
+
+	// The conversion should survive a reload.
+	lockID = r.mu.Lock()
+	r.conversions = make(map[string]*file)
+	err = r.load()
+	r.mu.Unlock(lockID)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	f = r.files["foo"]
+	conv = r.conversions["foo"]
+	if conv == nil || !hasLayout(conv.erasureCode, hot) {
+		t.Fatal("conversion was not persisted")
+	}
+	if fi := r.FileList()[0]; fi.Class != "hot" || !fi.Converting || fi.ConversionProgress != 0 {
+		t.Fatal("wrong file info:", fi)
+	}
+
+	// An incomplete conversion does not replace the file.
+	for i := uint64(0); i < 3; i++ {
+		conv.contracts[types.FileContractID{byte(i)}] = fileContract{
+			ID:     types.FileContractID{byte(i)},
+			Pieces: []pieceData{{Chunk: 0, Piece: i}},
+		}
+	}
+	r.managedFinishConversion("foo")
+	if r.files["foo"] != f {
+		t.Fatal("incomplete conversion replaced the file")
+	}
+	for i := uint64(0); i < 3; i++ {
+		fc := conv.contracts[types.FileContractID{byte(i)}]
+		fc.Pieces = append(fc.Pieces, pieceData{Chunk: 1, Piece: i})
+		conv.contracts[fc.ID] = fc
+	}
+	r.managedFinishConversion("foo")
+	if r.files["foo"] != conv || len(r.conversions) != 0 {
+		t.Fatal("complete conversion did not replace the file")
+	}
+	if _, err := os.Stat(r.conversionPath("foo")); !os.IsNotExist(err) {
+		t.Fatal("conversion was not deleted:", err)
+	}
+	if r.managedConversion(conv) != nil {
+		t.Fatal("converted file is converted again")
+	}
+}
@@ -29,12 +29,37 @@ var (
 		Testing:  3,
 	}).(int)
 
+	// repairThreshold is the fraction of its full redundancy below which a
+	// file that has finished uploading is queued for repair.
+	repairThreshold = build.Select(build.Var{
+		Dev:      0.75,
+		Standard: 0.75,
+		Testing:  0.75,
+	}).(float64)
+
 	repairQueueInterval = build.Select(build.Var{
 		Dev:      30 * time.Second,
 		Standard: time.Minute * 15,
 		Testing:  10 * time.Second,
 	}).(time.Duration)
 
+	// maxActiveUploads is the maximum number of pieces that are uploaded at
+	// once, across all hosts. Every piece in flight holds a sector in memory.
+	maxActiveUploads = build.Select(build.Var{
+		Dev:      16,
+		Standard: 64,
+		Testing:  8,
+	}).(int)
+
+	// editorIdleTimeout is how long a worker keeps its connection to a host
+	// open after an upload, so that the next upload to the host does not have
+	// to open a new connection.
+	editorIdleTimeout = build.Select(build.Var{
+		Dev:      10 * time.Second,
+		Standard: 30 * time.Second,
+		Testing:  time.Second,
+	}).(time.Duration)
+
 	// maxChunkCacheSize determines the maximum number of chunks that will be
 	// cached in memory.
 	maxChunkCacheSize = build.Select(build.Var{
@@ -43,6 +68,14 @@ var (
 		Testing:  60,
 	}).(int)
 
+	// maxDownloadCacheSize is the maximum number of bytes of downloaded
+	// chunks that are kept in the download cache on disk.
+	maxDownloadCacheSize = build.Select(build.Var{
+		Dev:      uint64(256 << 20),
+		Standard: uint64(1 << 30),
+		Testing:  uint64(1 << 20),
+	}).(uint64)
+
 	// chunkDownloadTimeout defines the maximum amount of time to wait for a
 	// chunk download to finish before returning in the download-to-upload repair
 	// loop
@@ -51,4 +84,20 @@ var (
 		Standard: 15 * time.Minute,
 		Testing:  40 * time.Second,
 	}).(time.Duration)
+
+	// chunkUploadTimeout defines the maximum amount of time to wait for a
+	// chunk of a streamed upload to become recoverable from the hosts.
+	chunkUploadTimeout = build.Select(build.Var{
+		Dev:      15 * time.Minute,
+		Standard: 15 * time.Minute,
+		Testing:  40 * time.Second,
+	}).(time.Duration)
+
+	// streamPollInterval is how often a streamed upload checks whether its
+	// current chunk has become recoverable.
+	streamPollInterval = build.Select(build.Var{
+		Dev:      time.Second,
+		Standard: time.Second,
+		Testing:  100 * time.Millisecond,
+	}).(time.Duration)
 )
@@ -46,6 +46,8 @@ func (c *Contractor) contractEndHeight() types.BlockHeight {
 // set. The contracts cannot be used to create Editors or Downloads, and will
 // not be renewed.
 //
+// If the contractor is paused, SetAllowance only records the allowance.
+//
 // TODO: can an Editor or Downloader be used across renewals?
 // TODO: will hosts allow renewing the same contract twice?
 //
@@ -88,9 +90,19 @@ func (c *Contractor) SetAllowance(a modules.Allowance) error {
 	shouldRenew := a.Period != c.allowance.Period || !a.Funds.Equals(c.allowance.Funds)
 	shouldWait := c.blockHeight+a.Period < c.contractEndHeight()
 	remaining := int(a.Hosts) - len(c.contracts)
+	paused := c.paused
 	c.mu.RUnlock()
 
-	if !shouldRenew {
+	if paused {
+		// Contracts are not formed or renewed while the contractor is
+		// paused. They will be formed with the new allowance once it is
+		// resumed.
+		c.mu.Lock()
+		c.allowance = a
+		err = c.saveSync()
+		c.mu.Unlock()
+		return err
+	} else if !shouldRenew {
 		// If no contracts need renewing, just form new contracts.
 		return c.managedFormAllowanceContracts(remaining, numSectors, a)
 	} else if shouldWait {
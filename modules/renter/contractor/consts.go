@@ -48,6 +48,14 @@ var (
 	}).(time.Duration)
 )
 
+// Constants related to contract renewal.
+const (
+	// minContractFundRenewalThreshold is the fraction of a contract's cost
+	// below which its remaining renter funds must fall for the contract to
+	// be refreshed before it enters the renew window.
+	minContractFundRenewalThreshold = 0.03
+)
+
 // Constants related to the safety values for when the contractor is forming
 // contracts.
 var (
@@ -8,6 +8,7 @@ import (
 	"sync"
 
 	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/renter/proto"
 	"github.com/NebulousLabs/Sia/persist"
 	siasync "github.com/NebulousLabs/Sia/sync"
 	"github.com/NebulousLabs/Sia/types"
@@ -18,6 +19,8 @@ var (
 	errNilWallet = errors.New("cannot create contractor with nil wallet")
 	errNilTpool  = errors.New("cannot create contractor with nil transaction pool")
 
+	errNegativeRateLimit = errors.New("rate limits cannot be negative")
+
 	// COMPATv1.0.4-lts
 	// metricsContractID identifies a special contract that contains aggregate
 	// financial metrics from older contractors
@@ -54,6 +57,10 @@ type Contractor struct {
 	blockHeight   types.BlockHeight
 	currentPeriod types.BlockHeight
 	lastChange    modules.ConsensusChangeID
+	rateLimiter   *proto.RateLimiter
+
+	// paused is set while the contractor must not form or renew contracts.
+	paused bool
 
 	downloaders map[types.FileContractID]*hostDownloader
 	editors     map[types.FileContractID]*hostEditor
@@ -73,6 +80,40 @@ func (c *Contractor) Allowance() modules.Allowance {
 	return c.allowance
 }
 
+// RateLimits returns the bandwidth limits of the connections to hosts.
+func (c *Contractor) RateLimits() modules.RateLimits {
+	return c.rateLimiter.Limits()
+}
+
+// SetRateLimits sets the bandwidth limits of the connections to hosts. The
+// limits also apply to the editors and downloaders that are already open.
+func (c *Contractor) SetRateLimits(limits modules.RateLimits) error {
+	if limits.MaxDownloadSpeed < 0 || limits.MaxUploadSpeed < 0 || limits.MaxHostDownloadSpeed < 0 || limits.MaxHostUploadSpeed < 0 {
+		return errNegativeRateLimit
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rateLimiter.SetLimits(limits)
+	return c.saveSync()
+}
+
+// Paused reports whether the formation and renewal of contracts is paused.
+func (c *Contractor) Paused() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.paused
+}
+
+// SetPaused pauses or resumes the formation and renewal of contracts. While
+// paused, SetAllowance only records the allowance. Contracts are formed and
+// renewed again after the next block once the contractor is resumed.
+func (c *Contractor) SetPaused(paused bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paused = paused
+	return c.saveSync()
+}
+
 // Contract returns the latest contract formed with the specified host.
 func (c *Contractor) Contract(hostAddr modules.NetAddress) (modules.RenterContract, bool) {
 	c.mu.RLock()
@@ -110,6 +151,71 @@ func (c *Contractor) AllContracts() (cs []modules.RenterContract) {
 	return
 }
 
+// RestoreContracts adds contracts taken from a renter backup to the
+// Contractor. Contracts that the Contractor already knows of, and contracts
+// that have expired, are skipped. If no allowance is set, the allowance of the
+// backup is adopted without forming new contracts, so that the restored
+// contracts are renewed.
+func (c *Contractor) RestoreContracts(a modules.Allowance, contracts []modules.RenterContract) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	periodStart := c.blockHeight
+	for _, contract := range contracts {
+		if _, ok := c.contracts[contract.ID]; ok {
+			continue
+		} else if _, ok := c.oldContracts[contract.ID]; ok {
+			continue
+		} else if c.blockHeight > contract.EndHeight() {
+			continue
+		}
+		c.contracts[contract.ID] = contract
+		if contract.StartHeight < periodStart {
+			periodStart = contract.StartHeight
+		}
+	}
+	if c.allowance.Hosts == 0 && a.Hosts != 0 {
+		c.allowance = a
+		c.currentPeriod = periodStart
+	}
+	return c.saveSync()
+}
+
+// ReleaseContract archives the contract with the specified ID, so that it is
+// neither revised nor renewed again. Its funds are released when it expires.
+// The contractor forms a replacement contract if the allowance calls for one.
+func (c *Contractor) ReleaseContract(id types.FileContractID) error {
+	// Block new editors and downloaders for the contract, like a renewal
+	// would, and invalidate the existing ones.
+	c.mu.Lock()
+	id = c.resolveID(id)
+	if _, ok := c.contracts[id]; !ok {
+		c.mu.Unlock()
+		return errors.New("no record of that contract")
+	} else if c.renewing[id] {
+		c.mu.Unlock()
+		return errors.New("currently renewing that contract")
+	}
+	c.renewing[id] = true
+	e, eok := c.editors[id]
+	d, dok := c.downloaders[id]
+	c.mu.Unlock()
+	if eok {
+		e.invalidate()
+	}
+	if dok {
+		d.invalidate()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.renewing, id)
+	c.oldContracts[id] = c.contracts[id]
+	delete(c.contracts, id)
+	delete(c.cachedRevisions, id)
+	c.log.Printf("released contract %v", id)
+	return c.saveSync()
+}
+
 // CurrentPeriod returns the height at which the current allowance period
 // began.
 func (c *Contractor) CurrentPeriod() types.BlockHeight {
@@ -120,8 +226,16 @@ func (c *Contractor) CurrentPeriod() types.BlockHeight {
 
 // ResolveID returns the ID of the most recent renewal of id.
 func (c *Contractor) ResolveID(id types.FileContractID) types.FileContractID {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.resolveID(id)
+}
+
+// resolveID returns the ID of the most recent renewal of id. The caller must
+// hold the lock.
+func (c *Contractor) resolveID(id types.FileContractID) types.FileContractID {
 	if newID, ok := c.renewedIDs[id]; ok && newID != id {
-		return c.ResolveID(newID)
+		return c.resolveID(newID)
 	}
 	return id
 }
@@ -175,6 +289,7 @@ func newContractor(cs consensusSet, w wallet, tp transactionPool, hdb hostDB, p
 		downloaders:     make(map[types.FileContractID]*hostDownloader),
 		editors:         make(map[types.FileContractID]*hostEditor),
 		oldContracts:    make(map[types.FileContractID]modules.RenterContract),
+		rateLimiter:     proto.NewRateLimiter(modules.RateLimits{}),
 		renewedIDs:      make(map[types.FileContractID]types.FileContractID),
 		renewing:        make(map[types.FileContractID]bool),
 		revising:        make(map[types.FileContractID]bool),
@@ -1,12 +1,15 @@
 package contractor
 
 import (
+	"io/ioutil"
 	"os"
 	"testing"
 	"time"
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/renter/proto"
+	"github.com/NebulousLabs/Sia/persist"
 	"github.com/NebulousLabs/Sia/types"
 )
 
@@ -30,10 +33,12 @@ func (newStub) AcceptTransactionSet([]types.Transaction) error      { return nil
 func (newStub) FeeEstimation() (a types.Currency, b types.Currency) { return }
 
 // hdb stubs
-func (newStub) AllHosts() []modules.HostDBEntry                                 { return nil }
-func (newStub) ActiveHosts() []modules.HostDBEntry                              { return nil }
-func (newStub) Host(types.SiaPublicKey) (settings modules.HostDBEntry, ok bool) { return }
-func (newStub) RandomHosts(int, []types.SiaPublicKey) []modules.HostDBEntry     { return nil }
+func (newStub) AllHosts() []modules.HostDBEntry                                    { return nil }
+func (newStub) ActiveHosts() []modules.HostDBEntry                                 { return nil }
+func (newStub) Host(types.SiaPublicKey) (settings modules.HostDBEntry, ok bool)    { return }
+func (newStub) IsFiltered(types.SiaPublicKey) bool                                 { return false }
+func (newStub) RandomHosts(int, []types.SiaPublicKey) []modules.HostDBEntry        { return nil }
+func (newStub) ScoreBreakdown(modules.HostDBEntry) (sb modules.HostScoreBreakdown) { return }
 
 // TestNew tests the New function.
 func TestNew(t *testing.T) {
@@ -177,15 +182,167 @@ func TestAllowance(t *testing.T) {
 	}
 }
 
+// TestRateLimits tests the RateLimits and SetRateLimits methods.
+func TestRateLimits(t *testing.T) {
+	c := &Contractor{
+		persist:     new(memPersist),
+		rateLimiter: proto.NewRateLimiter(modules.RateLimits{}),
+	}
+	limits := modules.RateLimits{
+		MaxDownloadSpeed:     1,
+		MaxUploadSpeed:       2,
+		MaxHostDownloadSpeed: 3,
+		MaxHostUploadSpeed:   4,
+	}
+	if err := c.SetRateLimits(limits); err != nil {
+		t.Fatal(err)
+	}
+	if c.RateLimits() != limits {
+		t.Fatal("RateLimits did not return the limits that were set:", c.RateLimits())
+	}
+	if err := c.SetRateLimits(modules.RateLimits{MaxUploadSpeed: -1}); err != errNegativeRateLimit {
+		t.Fatal("expected errNegativeRateLimit, got", err)
+	}
+
+	// The limits should be persisted.
+	c2 := &Contractor{
+		persist:     c.persist,
+		rateLimiter: proto.NewRateLimiter(modules.RateLimits{}),
+	}
+	if err := c2.load(); err != nil {
+		t.Fatal(err)
+	}
+	if c2.RateLimits() != limits {
+		t.Fatal("limits were not persisted:", c2.RateLimits())
+	}
+}
+
+// TestPaused tests the Paused and SetPaused methods.
+func TestPaused(t *testing.T) {
+	c := &Contractor{
+		persist:     new(memPersist),
+		rateLimiter: proto.NewRateLimiter(modules.RateLimits{}),
+	}
+	if c.Paused() {
+		t.Fatal("new contractor is paused")
+	}
+	if err := c.SetPaused(true); err != nil {
+		t.Fatal(err)
+	}
+	if !c.Paused() {
+		t.Fatal("contractor was not paused")
+	}
+
+	// The paused state should be persisted.
+	c2 := &Contractor{
+		persist:     c.persist,
+		rateLimiter: proto.NewRateLimiter(modules.RateLimits{}),
+	}
+	if err := c2.load(); err != nil {
+		t.Fatal(err)
+	}
+	if !c2.Paused() {
+		t.Fatal("paused state was not persisted")
+	}
+	if err := c2.SetPaused(false); err != nil {
+		t.Fatal(err)
+	}
+	if c2.Paused() {
+		t.Fatal("contractor was not resumed")
+	}
+}
+
+// TestRestoreContracts tests the RestoreContracts method.
+func TestRestoreContracts(t *testing.T) {
+	newContract := func(id byte, start, end types.BlockHeight) modules.RenterContract {
+		var rc modules.RenterContract
+		rc.ID = types.FileContractID{id}
+		rc.StartHeight = start
+		rc.LastRevision.NewWindowStart = end
+		return rc
+	}
+	c := &Contractor{
+		blockHeight: 50,
+		contracts: map[types.FileContractID]modules.RenterContract{
+			{1}: newContract(1, 10, 100),
+		},
+		oldContracts: map[types.FileContractID]modules.RenterContract{
+			{2}: newContract(2, 10, 100),
+		},
+		persist:     new(memPersist),
+		rateLimiter: proto.NewRateLimiter(modules.RateLimits{}),
+	}
+	a := modules.Allowance{Hosts: 3, Period: 100, RenewWindow: 10}
+	err := c.RestoreContracts(a, []modules.RenterContract{
+		newContract(1, 5, 100), // known
+		newContract(2, 5, 100), // archived
+		newContract(3, 20, 40), // expired
+		newContract(4, 30, 120),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.contracts) != 2 || c.contracts[types.FileContractID{1}].StartHeight != 10 {
+		t.Fatal("wrong contracts were restored:", c.contracts)
+	}
+	if _, ok := c.contracts[types.FileContractID{4}]; !ok {
+		t.Fatal("contract was not restored")
+	}
+	if c.allowance.Hosts != a.Hosts || c.currentPeriod != 30 {
+		t.Fatal("allowance of the backup was not adopted:", c.allowance, c.currentPeriod)
+	}
+
+	// An existing allowance is kept.
+	if err := c.RestoreContracts(modules.Allowance{Hosts: 5}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if c.allowance.Hosts != a.Hosts {
+		t.Fatal("existing allowance was replaced")
+	}
+}
+
+// TestReleaseContract tests the ReleaseContract method.
+func TestReleaseContract(t *testing.T) {
+	c := &Contractor{
+		contracts: map[types.FileContractID]modules.RenterContract{
+			{2}: {ID: types.FileContractID{2}},
+		},
+		oldContracts:    make(map[types.FileContractID]modules.RenterContract),
+		renewedIDs:      map[types.FileContractID]types.FileContractID{{1}: {2}},
+		cachedRevisions: make(map[types.FileContractID]cachedRevision),
+		editors:         make(map[types.FileContractID]*hostEditor),
+		downloaders:     make(map[types.FileContractID]*hostDownloader),
+		renewing:        make(map[types.FileContractID]bool),
+		persist:         new(memPersist),
+		log:             persist.NewLogger(ioutil.Discard),
+	}
+
+	// Contracts are released by their most recent renewal.
+	if err := c.ReleaseContract(types.FileContractID{1}); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.contracts) != 0 {
+		t.Fatal("released contract is still in use")
+	}
+	if _, ok := c.oldContracts[types.FileContractID{2}]; !ok {
+		t.Fatal("released contract was not archived")
+	}
+	if err := c.ReleaseContract(types.FileContractID{2}); err == nil {
+		t.Fatal("released a contract twice")
+	}
+}
+
 // stubHostDB mocks the hostDB dependency using zero-valued implementations of
 // its methods.
 type stubHostDB struct{}
 
-func (stubHostDB) AllHosts() (hs []modules.HostDBEntry)                             { return }
-func (stubHostDB) ActiveHosts() (hs []modules.HostDBEntry)                          { return }
-func (stubHostDB) Host(types.SiaPublicKey) (h modules.HostDBEntry, ok bool)         { return }
-func (stubHostDB) PublicKey() (spk types.SiaPublicKey)                              { return }
-func (stubHostDB) RandomHosts(int, []types.SiaPublicKey) (hs []modules.HostDBEntry) { return }
+func (stubHostDB) AllHosts() (hs []modules.HostDBEntry)                               { return }
+func (stubHostDB) ActiveHosts() (hs []modules.HostDBEntry)                            { return }
+func (stubHostDB) Host(types.SiaPublicKey) (h modules.HostDBEntry, ok bool)           { return }
+func (stubHostDB) IsFiltered(types.SiaPublicKey) bool                                 { return false }
+func (stubHostDB) PublicKey() (spk types.SiaPublicKey)                                { return }
+func (stubHostDB) RandomHosts(int, []types.SiaPublicKey) (hs []modules.HostDBEntry)   { return }
+func (stubHostDB) ScoreBreakdown(modules.HostDBEntry) (sb modules.HostScoreBreakdown) { return }
 
 // TestIntegrationSetAllowance tests the SetAllowance method.
 func TestIntegrationSetAllowance(t *testing.T) {
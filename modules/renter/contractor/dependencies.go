@@ -49,7 +49,9 @@ type (
 		AllHosts() []modules.HostDBEntry
 		ActiveHosts() []modules.HostDBEntry
 		Host(types.SiaPublicKey) (modules.HostDBEntry, bool)
+		IsFiltered(types.SiaPublicKey) bool
 		RandomHosts(n int, exclude []types.SiaPublicKey) []modules.HostDBEntry
+		ScoreBreakdown(modules.HostDBEntry) modules.HostScoreBreakdown
 	}
 
 	persister interface {
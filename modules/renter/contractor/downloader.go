@@ -113,7 +113,7 @@ func (hd *hostDownloader) Close() error {
 // from a host.
 func (c *Contractor) Downloader(id types.FileContractID, cancel <-chan struct{}) (_ Downloader, err error) {
 	c.mu.RLock()
-	id = c.ResolveID(id)
+	id = c.resolveID(id)
 	cachedDownloader, haveDownloader := c.downloaders[id]
 	height := c.blockHeight
 	contract, haveContract := c.contracts[id]
@@ -176,7 +176,7 @@ func (c *Contractor) Downloader(id types.FileContractID, cancel <-chan struct{})
 	}
 
 	// create downloader
-	d, err := proto.NewDownloader(host, contract, cancel)
+	d, err := proto.NewDownloader(host, contract, c.rateLimiter, cancel)
 	if proto.IsRevisionMismatch(err) {
 		// try again with the cached revision
 		c.mu.RLock()
@@ -189,7 +189,7 @@ func (c *Contractor) Downloader(id types.FileContractID, cancel <-chan struct{})
 		}
 		c.log.Printf("host %v has different revision for %v; retrying with cached revision", contract.NetAddress, contract.ID)
 		contract.LastRevision = cached.Revision
-		d, err = proto.NewDownloader(host, contract, cancel)
+		d, err = proto.NewDownloader(host, contract, c.rateLimiter, cancel)
 	}
 	if err != nil {
 		return nil, err
@@ -212,3 +212,79 @@ func (c *Contractor) Downloader(id types.FileContractID, cancel <-chan struct{})
 
 	return hd, nil
 }
+
+// RecoverContract brings a contract that the Contractor does not track up to
+// date with the most recent revision signed by its host, and downloads the
+// sectors with the specified Merkle roots from the host. Only the ID, the host
+// public key and the secret key of the contract are required; the other terms
+// of the contract are taken from the revision. This is used to recover
+// contracts that were lost along with the renter's metadata. The contract is
+// not added to the Contractor.
+func (c *Contractor) RecoverContract(contract modules.RenterContract, roots []crypto.Hash, cancel <-chan struct{}) (_ modules.RenterContract, sectors [][]byte, err error) {
+	c.mu.RLock()
+	_, tracked := c.contracts[c.resolveID(contract.ID)]
+	c.mu.RUnlock()
+	if tracked {
+		return modules.RenterContract{}, nil, errors.New("contract is already tracked by the contractor")
+	}
+	host, haveHost := c.hdb.Host(contract.HostPublicKey)
+	if !haveHost {
+		return modules.RenterContract{}, nil, errors.New("no record of that host")
+	}
+
+	txn, err := proto.LatestRevision(host, contract.ID, contract.SecretKey, c.rateLimiter, cancel)
+	if err != nil {
+		return modules.RenterContract{}, nil, err
+	}
+	rev := txn.FileContractRevisions[0]
+	contract.NetAddress = host.NetAddress
+	contract.LastRevision = rev
+	contract.LastRevisionTxn = txn
+	if contract.FileContract.WindowStart == 0 {
+		contract.FileContract = types.FileContract{
+			FileSize:           rev.NewFileSize,
+			FileMerkleRoot:     rev.NewFileMerkleRoot,
+			WindowStart:        rev.NewWindowStart,
+			WindowEnd:          rev.NewWindowEnd,
+			ValidProofOutputs:  rev.NewValidProofOutputs,
+			MissedProofOutputs: rev.NewMissedProofOutputs,
+			UnlockHash:         rev.NewUnlockHash,
+			RevisionNumber:     rev.NewRevisionNumber,
+		}
+	}
+	if len(roots) == 0 {
+		return contract, nil, nil
+	}
+
+	d, err := proto.NewDownloader(host, contract, c.rateLimiter, cancel)
+	if err != nil {
+		return modules.RenterContract{}, nil, err
+	}
+	defer d.Close()
+	for _, root := range roots {
+		var sector []byte
+		contract, sector, err = d.Sector(root)
+		if err != nil {
+			return modules.RenterContract{}, nil, err
+		}
+		sectors = append(sectors, sector)
+	}
+	return contract, sectors, nil
+}
+
+// VerifySegments challenges the host of a contract to prove that it stores
+// segments of the contract's sectors, returning the number of challenges
+// that the host failed.
+func (c *Contractor) VerifySegments(id types.FileContractID, challenges []modules.SegmentChallenge, cancel <-chan struct{}) (int, error) {
+	c.mu.RLock()
+	contract, haveContract := c.contracts[c.resolveID(id)]
+	c.mu.RUnlock()
+	if !haveContract {
+		return 0, errors.New("no record of that contract")
+	}
+	host, haveHost := c.hdb.Host(contract.HostPublicKey)
+	if !haveHost {
+		return 0, errors.New("no record of that host")
+	}
+	return proto.VerifySegments(host, contract, challenges, c.rateLimiter, cancel)
+}
@@ -175,7 +175,7 @@ func (he *hostEditor) Modify(oldRoot, newRoot crypto.Hash, offset uint64, newDat
 // delete sectors on a host.
 func (c *Contractor) Editor(id types.FileContractID, cancel <-chan struct{}) (_ Editor, err error) {
 	c.mu.RLock()
-	id = c.ResolveID(id)
+	id = c.resolveID(id)
 	cachedEditor, haveEditor := c.editors[id]
 	height := c.blockHeight
 	contract, haveContract := c.contracts[id]
@@ -244,7 +244,7 @@ func (c *Contractor) Editor(id types.FileContractID, cancel <-chan struct{}) (_
 	}
 
 	// create editor
-	e, err := proto.NewEditor(host, contract, height, cancel)
+	e, err := proto.NewEditor(host, contract, height, c.rateLimiter, cancel)
 	if proto.IsRevisionMismatch(err) {
 		// try again with the cached revision
 		c.mu.RLock()
@@ -258,7 +258,7 @@ func (c *Contractor) Editor(id types.FileContractID, cancel <-chan struct{}) (_
 		c.log.Printf("host %v has different revision for %v; retrying with cached revision", contract.NetAddress, contract.ID)
 		contract.LastRevision = cached.Revision
 		contract.MerkleRoots = cached.MerkleRoots
-		e, err = proto.NewEditor(host, contract, height, cancel)
+		e, err = proto.NewEditor(host, contract, height, c.rateLimiter, cancel)
 	}
 	if err != nil {
 		return nil, err
@@ -306,6 +306,78 @@ func TestIntegrationUploadDownload(t *testing.T) {
 	}
 }
 
+// TestIntegrationRecoverContract tests that a contract that is not tracked by
+// the contractor can be recovered from its host, along with its data.
+func TestIntegrationRecoverContract(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	// create testing trio
+	h, c, _, err := newTestingTrio(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	defer c.Close()
+
+	// get the host's entry from the db
+	hostEntry, ok := c.hdb.Host(h.PublicKey())
+	if !ok {
+		t.Fatal("no entry for host in db")
+	}
+
+	// form a contract with the host and upload a sector
+	contract, err := c.managedNewContract(hostEntry, 10, c.blockHeight+100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.mu.Lock()
+	c.contracts[contract.ID] = contract
+	c.mu.Unlock()
+	editor, err := c.Editor(contract.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := fastrand.Bytes(int(modules.SectorSize))
+	root, err := editor.Upload(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := editor.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// tracked contracts cannot be recovered
+	if _, _, err := c.RecoverContract(contract, nil, nil); err == nil {
+		t.Fatal("expected a tracked contract to be rejected")
+	}
+
+	// forget the contract, keeping only what is needed to recover it
+	c.mu.Lock()
+	revised := c.contracts[contract.ID]
+	delete(c.contracts, contract.ID)
+	c.mu.Unlock()
+	recovered, sectors, err := c.RecoverContract(modules.RenterContract{
+		ID:            contract.ID,
+		HostPublicKey: contract.HostPublicKey,
+		SecretKey:     contract.SecretKey,
+	}, []crypto.Hash{root}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sectors) != 1 || !bytes.Equal(sectors[0], data) {
+		t.Fatal("recovered data does not match original")
+	}
+	// the download of the sector revises the contract once more
+	if recovered.LastRevision.NewRevisionNumber != revised.LastRevision.NewRevisionNumber+1 {
+		t.Fatal("contract was not recovered at its latest revision:", recovered.LastRevision.NewRevisionNumber, revised.LastRevision.NewRevisionNumber)
+	}
+	if recovered.NetAddress != hostEntry.NetAddress || recovered.FileContract.WindowStart != revised.LastRevision.NewWindowStart {
+		t.Fatal("recovered contract is missing its host or file contract:", recovered)
+	}
+}
+
 // TestIntegrationDelete tests that the contractor can delete a sector from a
 // contract previously formed with a host.
 func TestIntegrationDelete(t *testing.T) {
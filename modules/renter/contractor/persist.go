@@ -18,6 +18,8 @@ type contractorPersist struct {
 	CurrentPeriod   types.BlockHeight                 `json:"currentperiod"`
 	LastChange      modules.ConsensusChangeID         `json:"lastchange"`
 	OldContracts    []modules.RenterContract          `json:"oldcontracts"`
+	Paused          bool                              `json:"paused"`
+	RateLimits      modules.RateLimits                `json:"ratelimits"`
 	RenewedIDs      map[string]string                 `json:"renewedids"`
 }
 
@@ -30,6 +32,8 @@ func (c *Contractor) persistData() contractorPersist {
 		Contracts:       make(map[string]modules.RenterContract),
 		CurrentPeriod:   c.currentPeriod,
 		LastChange:      c.lastChange,
+		Paused:          c.paused,
+		RateLimits:      c.rateLimiter.Limits(),
 		RenewedIDs:      make(map[string]string),
 	}
 	for _, rev := range c.cachedRevisions {
@@ -57,6 +61,8 @@ func (c *Contractor) load() error {
 	}
 	c.allowance = data.Allowance
 	c.blockHeight = data.BlockHeight
+	c.paused = data.Paused
+	c.rateLimiter.SetLimits(data.RateLimits)
 	for _, rev := range data.CachedRevisions {
 		c.cachedRevisions[rev.Revision.ParentID] = rev
 	}
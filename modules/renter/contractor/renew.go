@@ -73,20 +73,58 @@ func (c *Contractor) managedRenew(contract modules.RenterContract, numSectors ui
 	return newContract, nil
 }
 
-// managedRenewContracts renews any contracts that are up for renewal, using
-// the current allowance.
-func (c *Contractor) managedRenewContracts() error {
-	c.mu.RLock()
-	// Renew contracts when they enter the renew window.
-	// NOTE: offline contracts are not considered here, since we may have
-	// replaced them (and we probably won't be able to connect to their host
-	// anyway)
+// renewSet returns the IDs of the contracts that should be renewed. Contracts
+// are renewed when they enter the renew window, and refreshed when their
+// remaining funds fall below minContractFundRenewalThreshold of their cost, as
+// long as the unspent allowance can pay for the refresh. Contracts with hosts
+// excluded by the host filter are not renewed.
+//
+// NOTE: failing contracts are not considered here, since they are being
+// replaced (and we probably won't be able to connect to their host anyway)
+func (c *Contractor) renewSet() []types.FileContractID {
+	// Refreshing a contract costs about as much as forming one.
+	var unspent, refreshCost types.Currency
+	if c.allowance.Hosts > 0 {
+		refreshCost = c.allowance.Funds.Div64(c.allowance.Hosts)
+		unspent = c.allowance.Funds
+		for _, contract := range c.contracts {
+			if unspent.Cmp(contract.TotalCost) < 0 {
+				unspent = types.ZeroCurrency
+				break
+			}
+			unspent = unspent.Sub(contract.TotalCost)
+		}
+	}
+
 	var renewSet []types.FileContractID
-	for _, contract := range c.onlineContracts() {
+	for _, contract := range c.healthyContracts() {
+		// Contracts with hosts excluded by the host filter are left to
+		// expire.
+		if c.hdb.IsFiltered(contract.HostPublicKey) {
+			continue
+		}
 		if c.blockHeight+c.allowance.RenewWindow >= contract.EndHeight() {
 			renewSet = append(renewSet, contract.ID)
+			continue
 		}
+		if contract.RenterFunds().Cmp(contract.TotalCost.MulFloat(minContractFundRenewalThreshold)) >= 0 {
+			continue
+		}
+		if c.allowance.Hosts == 0 || unspent.Cmp(refreshCost) < 0 {
+			c.log.Printf("WARN: contract with %v is running out of funds, but the allowance cannot pay to refresh it", contract.NetAddress)
+			continue
+		}
+		unspent = unspent.Sub(refreshCost)
+		renewSet = append(renewSet, contract.ID)
 	}
+	return renewSet
+}
+
+// managedRenewContracts renews any contracts that are up for renewal or are
+// running out of funds, using the current allowance.
+func (c *Contractor) managedRenewContracts() error {
+	c.mu.RLock()
+	renewSet := c.renewSet()
 	c.mu.RUnlock()
 	if len(renewSet) == 0 {
 		// nothing to do
@@ -0,0 +1,113 @@
+package contractor
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/persist"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestRenewSet tests that contracts are selected for renewal when they enter
+// the renew window, or when they run out of funds and the allowance can pay
+// to refresh them.
+func TestRenewSet(t *testing.T) {
+	// newContract returns a contract costing 100 SC, ending at endHeight with
+	// funds SC remaining.
+	newContract := func(id byte, endHeight types.BlockHeight, funds uint64) modules.RenterContract {
+		var rc modules.RenterContract
+		rc.ID = types.FileContractID{id}
+		rc.HostPublicKey = types.SiaPublicKey{Key: []byte{id}}
+		rc.TotalCost = types.SiacoinPrecision.Mul64(100)
+		rc.LastRevision.NewWindowStart = endHeight
+		rc.LastRevision.NewValidProofOutputs = []types.SiacoinOutput{
+			{Value: types.SiacoinPrecision.Mul64(funds)},
+			{},
+		}
+		return rc
+	}
+	hosts := make(map[string]modules.HostDBEntry)
+	for id := byte(1); id <= 4; id++ {
+		hosts[string([]byte{id})] = modules.HostDBEntry{}
+	}
+	c := &Contractor{
+		blockHeight: 100,
+		allowance: modules.Allowance{
+			Funds:       types.SiacoinPrecision.Mul64(600),
+			Hosts:       3,
+			RenewWindow: 10,
+		},
+		contracts: map[types.FileContractID]modules.RenterContract{
+			{1}: newContract(1, 105, 50), // in the renew window
+			{2}: newContract(2, 200, 50), // healthy
+			{3}: newContract(3, 200, 1),  // out of funds
+		},
+		hdb: mapHostDB{hosts: hosts},
+		log: persist.NewLogger(ioutil.Discard),
+	}
+
+	// With 300 SC of the allowance unspent, both the contract in the renew
+	// window and the contract out of funds are renewed.
+	renewSet := c.renewSet()
+	if len(renewSet) != 2 {
+		t.Fatal("expected 2 contracts to be renewed, got", renewSet)
+	}
+	for _, id := range renewSet {
+		if id == (types.FileContractID{2}) {
+			t.Fatal("healthy contract was renewed")
+		}
+	}
+
+	// Once the allowance is spent, contracts out of funds are no longer
+	// refreshed, but contracts in the renew window still are.
+	c.contracts[types.FileContractID{4}] = newContract(4, 200, 50)
+	c.allowance.Funds = types.SiacoinPrecision.Mul64(450)
+	renewSet = c.renewSet()
+	if len(renewSet) != 1 || renewSet[0] != (types.FileContractID{1}) {
+		t.Fatal("expected only the contract in the renew window to be renewed, got", renewSet)
+	}
+
+	// Contracts with filtered hosts are not renewed.
+	c.hdb = filterHostDB{mapHostDB{hosts: hosts}, types.SiaPublicKey{Key: []byte{1}}}
+	if renewSet = c.renewSet(); len(renewSet) != 0 {
+		t.Fatal("contract with a filtered host was renewed:", renewSet)
+	}
+}
+
+// filterHostDB is a hostDB whose filter excludes a single host.
+type filterHostDB struct {
+	mapHostDB
+	filtered types.SiaPublicKey
+}
+
+func (hdb filterHostDB) IsFiltered(spk types.SiaPublicKey) bool {
+	return spk.String() == hdb.filtered.String()
+}
+
+// TestIsOfflineRenewed tests that the renewals of contracts are checked by
+// IsOffline and IsUnreliable.
+func TestIsOfflineRenewed(t *testing.T) {
+	c := &Contractor{
+		contracts: map[types.FileContractID]modules.RenterContract{
+			{2}: {ID: types.FileContractID{2}, HostPublicKey: types.SiaPublicKey{Key: []byte("foo")}},
+		},
+		renewedIDs: map[types.FileContractID]types.FileContractID{
+			{1}: {2},
+		},
+		hdb: mapHostDB{
+			hosts: map[string]modules.HostDBEntry{
+				"foo": {},
+			},
+		},
+	}
+	if c.IsOffline(types.FileContractID{1}) {
+		t.Error("renewed contract is reported offline")
+	}
+	if c.IsUnreliable(types.FileContractID{1}) {
+		t.Error("renewed contract is reported unreliable")
+	}
+	if !c.IsOffline(types.FileContractID{3}) {
+		t.Error("unknown contract is reported online")
+	}
+}
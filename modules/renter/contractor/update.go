@@ -76,16 +76,22 @@ func (c *Contractor) ProcessConsensusChange(cc modules.ConsensusChange) {
 			}
 			defer c.editLock.Unlock()
 
+			// Contracts are not formed or renewed while the contractor is
+			// paused.
+			if c.Paused() {
+				return
+			}
+
 			// Renew any (online) contracts that have entered the renew window.
 			err = c.managedRenewContracts()
 			if err != nil {
 				c.log.Debugln("WARN: failed to renew contracts after processing a consensus chage:", err)
 			}
 
-			// If we don't have enough (online) contracts, form new ones.
+			// If we don't have enough healthy contracts, form new ones.
 			c.mu.RLock()
 			a := c.allowance
-			remaining := int(a.Hosts) - len(c.onlineContracts())
+			remaining := int(a.Hosts) - len(c.healthyContracts())
 			c.mu.RUnlock()
 			if remaining <= 0 {
 				return
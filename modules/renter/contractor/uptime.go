@@ -13,6 +13,11 @@ import (
 // host is offline or not.
 const uptimeMinScans = 3
 
+// failingScoreDivisor is the factor by which the score of a contract's host
+// must fall below the median score of the Contractor's hosts for the host to
+// be considered failing.
+const failingScoreDivisor = 100
+
 // uptimeWindow specifies the duration in which host uptime is checked.
 var uptimeWindow = func() time.Duration {
 	switch build.Release {
@@ -27,11 +32,12 @@ var uptimeWindow = func() time.Duration {
 }()
 
 // IsOffline indicates whether a contract's host should be considered offline,
-// based on its scan metrics.
+// based on its scan metrics. Renewed contracts are checked by their most recent
+// renewal.
 func (c *Contractor) IsOffline(id types.FileContractID) bool {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	return c.isOffline(id)
+	return c.isOffline(c.resolveID(id))
 }
 
 // isOffline indicates whether a contract's host should be considered offline,
@@ -82,6 +88,25 @@ func (c *Contractor) isOffline(id types.FileContractID) bool {
 	return windowEnd.Sub(windowStart) >= uptimeWindow
 }
 
+// IsUnreliable indicates whether a contract's host is offline, or is down
+// without having been down long enough to be considered offline. A host is down
+// if its most recent scan failed. Data stored only on unreliable hosts is at
+// risk of being lost.
+func (c *Contractor) IsUnreliable(id types.FileContractID) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	id = c.resolveID(id)
+	if c.isOffline(id) {
+		return true
+	}
+	host, ok := c.hdb.Host(c.contracts[id].HostPublicKey)
+	if !ok {
+		return true
+	}
+	numScans := len(host.ScanHistory)
+	return numScans > 0 && !host.ScanHistory[numScans-1].Success
+}
+
 // onlineContracts returns the subset of the Contractor's contracts whose
 // hosts are considered online.
 func (c *Contractor) onlineContracts() []modules.RenterContract {
@@ -93,3 +118,81 @@ func (c *Contractor) onlineContracts() []modules.RenterContract {
 	}
 	return cs
 }
+
+// unresponsive indicates whether the uptimeMinScans most recent scans of a
+// host have all failed. Unlike an offline host, an unresponsive host may have
+// been down for less than uptimeWindow.
+func unresponsive(host modules.HostDBEntry) bool {
+	numScans := len(host.ScanHistory)
+	if numScans < uptimeMinScans {
+		return false
+	}
+	for _, scan := range host.ScanHistory[numScans-uptimeMinScans:] {
+		if scan.Success {
+			return false
+		}
+	}
+	return true
+}
+
+// FailingContracts returns the IDs of the contracts whose hosts are failing.
+func (c *Contractor) FailingContracts() []types.FileContractID {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var ids []types.FileContractID
+	for id := range c.failingContracts() {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// failingContracts returns the set of the Contractor's contracts whose hosts
+// are failing. A host is failing if it is offline, if it is unresponsive, or
+// if its score has collapsed below 1/failingScoreDivisor of the median score
+// of the Contractor's hosts. The data stored on failing hosts should be moved
+// to other hosts before it is lost.
+func (c *Contractor) failingContracts() map[types.FileContractID]struct{} {
+	failing := make(map[types.FileContractID]struct{})
+	scores := make(map[types.FileContractID]types.Currency)
+	for id, contract := range c.contracts {
+		host, ok := c.hdb.Host(contract.HostPublicKey)
+		if !ok || c.isOffline(id) || unresponsive(host) {
+			failing[id] = struct{}{}
+			continue
+		}
+		scores[id] = c.hdb.ScoreBreakdown(host).Score
+	}
+	if len(scores) == 0 {
+		return failing
+	}
+
+	sorted := make([]types.Currency, 0, len(scores))
+	for _, score := range scores {
+		sorted = append(sorted, score)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Cmp(sorted[j]) < 0
+	})
+	median := sorted[len(sorted)/2]
+	for id, score := range scores {
+		if score.Mul64(failingScoreDivisor).Cmp(median) < 0 {
+			failing[id] = struct{}{}
+		}
+	}
+	return failing
+}
+
+// healthyContracts returns the subset of the Contractor's contracts whose
+// hosts are not failing. Failing contracts are neither renewed nor counted
+// towards the hosts of the allowance, so that they are replaced while the
+// renter still has a chance to move their data.
+func (c *Contractor) healthyContracts() []modules.RenterContract {
+	failing := c.failingContracts()
+	var cs []modules.RenterContract
+	for id, contract := range c.contracts {
+		if _, ok := failing[id]; !ok {
+			cs = append(cs, contract)
+		}
+	}
+	return cs
+}
@@ -170,3 +170,107 @@ func TestIsOffline(t *testing.T) {
 		t.Fatal("IsOffline returned false for a nonexistent contract id")
 	}
 }
+
+// TestIsUnreliable tests the IsUnreliable method.
+func TestIsUnreliable(t *testing.T) {
+	now := time.Now()
+	oldBadScan := modules.HostDBScan{Timestamp: now.Add(-uptimeWindow * 2), Success: false}
+	newBadScan := modules.HostDBScan{Timestamp: now.Add(-uptimeWindow / 2), Success: false}
+	newGoodScan := modules.HostDBScan{Timestamp: now.Add(-uptimeWindow / 2), Success: true}
+	currentBadScan := modules.HostDBScan{Timestamp: now, Success: false}
+	currentGoodScan := modules.HostDBScan{Timestamp: now, Success: true}
+
+	tests := []struct {
+		scans      []modules.HostDBScan
+		unreliable bool
+	}{
+		// no data
+		{nil, false},
+		// most recent scan succeeded
+		{[]modules.HostDBScan{oldBadScan, newBadScan, currentGoodScan}, false},
+		// most recent scan failed, but the host is not offline yet
+		{[]modules.HostDBScan{newGoodScan, currentBadScan}, true},
+		// host is offline
+		{[]modules.HostDBScan{oldBadScan, newBadScan, currentBadScan}, true},
+	}
+	for i, test := range tests {
+		c := &Contractor{
+			contracts: map[types.FileContractID]modules.RenterContract{
+				{1}: {HostPublicKey: types.SiaPublicKey{Key: []byte("foo")}},
+			},
+			hdb: mapHostDB{
+				hosts: map[string]modules.HostDBEntry{
+					"foo": {ScanHistory: test.scans},
+				},
+			},
+		}
+		if unreliable := c.IsUnreliable(types.FileContractID{1}); unreliable != test.unreliable {
+			t.Errorf("IsUnreliable(%v) = %v, expected %v", i, unreliable, test.unreliable)
+		}
+	}
+}
+
+// scoreHostDB is a mapHostDB that reports fixed scores for its hosts.
+type scoreHostDB struct {
+	mapHostDB
+	scores map[string]uint64
+}
+
+func (hdb scoreHostDB) ScoreBreakdown(entry modules.HostDBEntry) modules.HostScoreBreakdown {
+	return modules.HostScoreBreakdown{Score: types.NewCurrency64(hdb.scores[string(entry.PublicKey.Key)])}
+}
+
+// TestFailingContracts tests that contracts are failing when their hosts are
+// offline, unresponsive or have a collapsed score, and that failing contracts
+// are neither renewed nor counted as healthy.
+func TestFailingContracts(t *testing.T) {
+	now := time.Now()
+	newBadScan := modules.HostDBScan{Timestamp: now.Add(-uptimeWindow / 2), Success: false}
+	currentBadScan := modules.HostDBScan{Timestamp: now, Success: false}
+	currentGoodScan := modules.HostDBScan{Timestamp: now, Success: true}
+
+	hosts := make(map[string]modules.HostDBEntry)
+	contracts := make(map[types.FileContractID]modules.RenterContract)
+	for id := byte(1); id <= 5; id++ {
+		spk := types.SiaPublicKey{Key: []byte{id}}
+		hosts[string(spk.Key)] = modules.HostDBEntry{
+			PublicKey:   spk,
+			ScanHistory: []modules.HostDBScan{currentGoodScan},
+		}
+		contracts[types.FileContractID{id}] = modules.RenterContract{
+			ID:            types.FileContractID{id},
+			HostPublicKey: spk,
+		}
+	}
+	// Host 4 has been down for less than uptimeWindow, and host 5 is missing
+	// from the hostdb.
+	hosts[string([]byte{4})] = modules.HostDBEntry{
+		PublicKey:   types.SiaPublicKey{Key: []byte{4}},
+		ScanHistory: []modules.HostDBScan{newBadScan, currentBadScan, currentBadScan},
+	}
+	delete(hosts, string([]byte{5}))
+
+	// The score of host 3 collapsed.
+	c := &Contractor{
+		contracts: contracts,
+		hdb: scoreHostDB{
+			mapHostDB: mapHostDB{hosts: hosts},
+			scores:    map[string]uint64{"\x01": 1000, "\x02": 2000, "\x03": 5},
+		},
+	}
+	failing := c.FailingContracts()
+	if len(failing) != 3 {
+		t.Fatal("expected 3 failing contracts, got", failing)
+	}
+	for _, id := range failing {
+		if id[0] < 3 {
+			t.Fatal("healthy contract is failing:", id)
+		}
+	}
+	if c.IsOffline(types.FileContractID{4}) {
+		t.Fatal("unresponsive host is offline before uptimeWindow has passed")
+	}
+	if healthy := c.healthyContracts(); len(healthy) != 2 {
+		t.Fatal("expected 2 healthy contracts, got", len(healthy))
+	}
+}
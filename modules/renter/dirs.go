@@ -0,0 +1,351 @@
+package renter
+
+import (
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+var (
+	ErrUnknownDir  = errors.New("no directory known with that path")
+	ErrDirOverload = errors.New("a directory already exists at that location")
+
+	errDirIntoSelf = errors.New("cannot move a directory into itself")
+	errRootDir     = errors.New("the root directory cannot be modified")
+	errUploadFile  = errors.New("source is not a directory")
+)
+
+// isWithin reports whether siapath is within the directory dir, at any depth.
+// Every path is within the root directory, whose path is empty.
+func isWithin(siapath, dir string) bool {
+	return dir == "" || strings.HasPrefix(siapath, dir+"/")
+}
+
+// childDir returns the directory directly within dir that contains siapath,
+// or the empty string if siapath is directly within dir.
+func childDir(dir, siapath string) string {
+	prefix := ""
+	if dir != "" {
+		prefix = dir + "/"
+	}
+	i := strings.Index(strings.TrimPrefix(siapath, prefix), "/")
+	if i == -1 {
+		return ""
+	}
+	return siapath[:len(prefix)+i]
+}
+
+// dirInfo aggregates the information of the files within a directory.
+func dirInfo(siapath string, files []modules.FileInfo) modules.DirectoryInfo {
+	di := modules.DirectoryInfo{
+		SiaPath:        siapath,
+		NumFiles:       uint64(len(files)),
+		Available:      true,
+		MinRedundancy:  -1,
+		UploadProgress: 100,
+	}
+	var uploaded float64
+	for i, fi := range files {
+		di.Size += fi.Filesize
+		di.Available = di.Available && fi.Available
+		if i == 0 || fi.Redundancy < di.MinRedundancy {
+			di.MinRedundancy = fi.Redundancy
+		}
+		uploaded += fi.UploadProgress * float64(fi.Filesize)
+	}
+	if di.Size > 0 {
+		di.UploadProgress = uploaded / float64(di.Size)
+	}
+	return di
+}
+
+// dirExists reports whether the directory dir exists, either because it was
+// created or because there are files or directories within it. The caller
+// must hold the renter lock.
+func (r *Renter) dirExists(dir string) bool {
+	if dir == "" {
+		return true
+	}
+	if _, exists := r.dirs[dir]; exists {
+		return true
+	}
+	for name := range r.files {
+		if isWithin(name, dir) {
+			return true
+		}
+	}
+	for d := range r.dirs {
+		if isWithin(d, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateDir creates an empty directory. The parents of the directory are
+// created implicitly.
+func (r *Renter) CreateDir(siapath string) error {
+	if err := validateSiapath(siapath); err != nil {
+		return err
+	}
+	lockID := r.mu.Lock()
+	defer r.mu.Unlock(lockID)
+	if _, exists := r.files[siapath]; exists {
+		return ErrPathOverload
+	} else if r.dirExists(siapath) {
+		return ErrDirOverload
+	}
+	r.dirs[siapath] = struct{}{}
+	return r.saveSync()
+}
+
+// DeleteDir deletes a directory, along with the files and directories within
+// it. Like DeleteFile, the data of the files is not deleted from the hosts.
+func (r *Renter) DeleteDir(siapath string) error {
+	if siapath == "" {
+		return errRootDir
+	}
+	lockID := r.mu.Lock()
+	defer r.mu.Unlock(lockID)
+	if !r.dirExists(siapath) {
+		return ErrUnknownDir
+	}
+	for name := range r.files {
+		if isWithin(name, siapath) {
+			r.removeFile(name)
+		}
+	}
+	for dir := range r.dirs {
+		if dir == siapath || isWithin(dir, siapath) {
+			delete(r.dirs, dir)
+		}
+	}
+	return r.saveSync()
+}
+
+// DirList returns the information of a directory, along with the information
+// of the directories and files directly within it, sorted by path. The root
+// directory has the empty path.
+func (r *Renter) DirList(siapath string) (modules.DirectoryInfo, []modules.DirectoryInfo, []modules.FileInfo, error) {
+	lockID := r.mu.RLock()
+	defer r.mu.RUnlock(lockID)
+	if !r.dirExists(siapath) {
+		return modules.DirectoryInfo{}, nil, nil, ErrUnknownDir
+	}
+
+	var all, files []modules.FileInfo
+	subdirs := make(map[string][]modules.FileInfo)
+	for name, f := range r.files {
+		if !isWithin(name, siapath) {
+			continue
+		}
+		fi := r.fileInfo(f)
+		all = append(all, fi)
+		if child := childDir(siapath, name); child == "" {
+			files = append(files, fi)
+		} else {
+			subdirs[child] = append(subdirs[child], fi)
+		}
+	}
+	// Include the subdirectories that do not contain files.
+	for dir := range r.dirs {
+		if dir == siapath || !isWithin(dir, siapath) {
+			continue
+		}
+		child := childDir(siapath, dir)
+		if child == "" {
+			child = dir
+		}
+		if _, exists := subdirs[child]; !exists {
+			subdirs[child] = nil
+		}
+	}
+
+	dirs := make([]modules.DirectoryInfo, 0, len(subdirs))
+	for child, childFiles := range subdirs {
+		dirs = append(dirs, dirInfo(child, childFiles))
+	}
+	sort.Slice(dirs, func(i, j int) bool { return dirs[i].SiaPath < dirs[j].SiaPath })
+	sort.Slice(files, func(i, j int) bool { return files[i].SiaPath < files[j].SiaPath })
+	return dirInfo(siapath, all), dirs, files, nil
+}
+
+// RenameDir changes the path of a directory, moving the files and directories
+// within it. There must not be any file or directory at the new path.
+func (r *Renter) RenameDir(siapath, newSiaPath string) error {
+	if siapath == "" {
+		return errRootDir
+	}
+	if err := validateSiapath(newSiaPath); err != nil {
+		return err
+	}
+	if newSiaPath == siapath || isWithin(newSiaPath, siapath) {
+		return errDirIntoSelf
+	}
+	lockID := r.mu.Lock()
+	defer r.mu.Unlock(lockID)
+	if !r.dirExists(siapath) {
+		return ErrUnknownDir
+	}
+	if _, exists := r.files[newSiaPath]; exists {
+		return ErrPathOverload
+	} else if r.dirExists(newSiaPath) {
+		return ErrDirOverload
+	}
+
+	// Collect the files and directories to move before modifying the maps
+	// of the renter.
+	var moved []*file
+	for name, f := range r.files {
+		if isWithin(name, siapath) {
+			moved = append(moved, f)
+		}
+	}
+	var dirs []string
+	for dir := range r.dirs {
+		if dir == siapath || isWithin(dir, siapath) {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	var oldPaths []string
+	for _, f := range moved {
+		oldPaths = append(oldPaths, filepath.Join(r.persistDir, f.name+ShareExtension))
+		if err := r.renameFile(f, newSiaPath+strings.TrimPrefix(f.name, siapath)); err != nil {
+			return err
+		}
+	}
+	for _, dir := range dirs {
+		delete(r.dirs, dir)
+		r.dirs[newSiaPath+strings.TrimPrefix(dir, siapath)] = struct{}{}
+	}
+	if err := r.saveSync(); err != nil {
+		return err
+	}
+
+	// Delete the old .sia files.
+	for _, path := range oldPaths {
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchesAny reports whether the path rel, relative to the source of a
+// directory upload and separated by slashes, matches any of the glob patterns.
+// Patterns without a slash are also matched against the last element of rel,
+// so that "*.tmp" matches temporary files in every subdirectory.
+func matchesAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, rel); ok {
+			return true
+		}
+		if !strings.Contains(pattern, "/") {
+			if ok, _ := path.Match(pattern, path.Base(rel)); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// UploadDirectory uploads the files of the local directory up.Source, and of
+// its subdirectories, into the directory up.SiaPath, preserving their paths
+// relative to the source. Every file is uploaded with the erasure coding and
+// piece size of up, and the subdirectories of the source are created even if
+// they are empty. Files and directories that match a pattern of up.Exclude
+// are skipped. If up.Include is not empty, only the files that match one of
+// its patterns are uploaded, and only their directories are created. Nothing
+// is uploaded if a file would replace an existing file, but an upload that
+// fails leaves the files that were already uploaded in place.
+func (r *Renter) UploadDirectory(up modules.FileUploadParams) error {
+	if err := validateSiapath(up.SiaPath); err != nil {
+		return err
+	}
+	for _, patterns := range [][]string{up.Include, up.Exclude} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return errors.New("invalid pattern " + pattern + ": " + err.Error())
+			}
+		}
+	}
+	finfo, err := os.Stat(up.Source)
+	if err != nil {
+		return err
+	}
+	if !finfo.IsDir() {
+		return errUploadFile
+	}
+
+	var dirs []string
+	var uploads []modules.FileUploadParams
+	err = filepath.Walk(up.Source, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(up.Source, file)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		siapath := up.SiaPath
+		if rel != "." {
+			siapath += "/" + rel
+			if matchesAny(up.Exclude, rel) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+		if info.IsDir() {
+			if len(up.Include) == 0 || rel == "." {
+				dirs = append(dirs, siapath)
+			}
+		} else if info.Mode().IsRegular() && (len(up.Include) == 0 || matchesAny(up.Include, rel)) {
+			fup := up
+			fup.Source = file
+			fup.SiaPath = siapath
+			uploads = append(uploads, fup)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	lockID := r.mu.Lock()
+	for _, fup := range uploads {
+		if _, exists := r.files[fup.SiaPath]; exists {
+			r.mu.Unlock(lockID)
+			return ErrPathOverload
+		}
+	}
+	for _, dir := range dirs {
+		if _, exists := r.files[dir]; exists {
+			r.mu.Unlock(lockID)
+			return ErrPathOverload
+		}
+	}
+	for _, dir := range dirs {
+		r.dirs[dir] = struct{}{}
+	}
+	err = r.saveSync()
+	r.mu.Unlock(lockID)
+	if err != nil {
+		return err
+	}
+
+	for _, fup := range uploads {
+		if err := r.Upload(fup); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package renter
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// addTestingFile adds a file with the given path and size to the renter.
+func (rt *renterTester) addTestingFile(siapath string, size uint64) *file {
+	f := newTestingFile()
+	f.name = siapath
+	f.size = size
+	lockID := rt.renter.mu.Lock()
+	rt.renter.files[siapath] = f
+	rt.renter.mu.Unlock(lockID)
+	return f
+}
+
+// TestRenterDirs probes the creation, listing, renaming and deletion of
+// directories.
+func TestRenterDirs(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+	r := rt.renter
+
+	rt.addTestingFile("top", 10)
+	rt.addTestingFile("a/one", 20)
+	rt.addTestingFile("a/b/two", 30)
+	if err := r.CreateDir("a/empty"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.CreateDir("a/b"); err != ErrDirOverload {
+		t.Fatal("expected ErrDirOverload, got", err)
+	}
+	if err := r.CreateDir("top"); err != ErrPathOverload {
+		t.Fatal("expected ErrPathOverload, got", err)
+	}
+	if err := r.RenameFile("top", "a"); err != ErrDirOverload {
+		t.Fatal("expected ErrDirOverload, got", err)
+	}
+
+	// The root directory aggregates every file.
+	root, dirs, files, err := r.DirList("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root.NumFiles != 3 || root.Size != 60 {
+		t.Fatal("wrong root directory:", root)
+	}
+	if len(dirs) != 1 || dirs[0].SiaPath != "a" || dirs[0].NumFiles != 2 || dirs[0].Size != 50 {
+		t.Fatal("wrong directories in root:", dirs)
+	}
+	if len(files) != 1 || files[0].SiaPath != "top" {
+		t.Fatal("wrong files in root:", files)
+	}
+	_, dirs, files, err = r.DirList("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirs) != 2 || dirs[0].SiaPath != "a/b" || dirs[1].SiaPath != "a/empty" {
+		t.Fatal("wrong directories in a:", dirs)
+	}
+	if dirs[1].NumFiles != 0 || dirs[1].MinRedundancy != -1 {
+		t.Fatal("wrong empty directory:", dirs[1])
+	}
+	if len(files) != 1 || files[0].SiaPath != "a/one" {
+		t.Fatal("wrong files in a:", files)
+	}
+	if _, _, _, err := r.DirList("missing"); err != ErrUnknownDir {
+		t.Fatal("expected ErrUnknownDir, got", err)
+	}
+
+	// Rename the directory.
+	if err := r.RenameDir("a", "a/c"); err != errDirIntoSelf {
+		t.Fatal("expected errDirIntoSelf, got", err)
+	}
+	if err := r.RenameDir("a", "top"); err != ErrPathOverload {
+		t.Fatal("expected ErrPathOverload, got", err)
+	}
+	if err := r.RenameDir("a", "c"); err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := r.files["c/b/two"]; !exists {
+		t.Fatal("file was not moved")
+	}
+	if _, err := os.Stat(filepath.Join(r.persistDir, "a", "b", "two"+ShareExtension)); !os.IsNotExist(err) {
+		t.Fatal("old .sia file was not deleted:", err)
+	}
+	if _, dirs, _, err := r.DirList("c"); err != nil || len(dirs) != 2 || dirs[1].SiaPath != "c/empty" {
+		t.Fatal("directories were not moved:", dirs, err)
+	}
+	if r.dirExists("a") {
+		t.Fatal("old directory still exists")
+	}
+
+	// The empty directory should persist.
+	lockID := r.mu.Lock()
+	r.dirs = make(map[string]struct{})
+	err = r.load()
+	r.mu.Unlock(lockID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.dirExists("c/empty") {
+		t.Fatal("empty directory was not persisted")
+	}
+
+	// Delete the directory.
+	if err := r.DeleteDir(""); err != errRootDir {
+		t.Fatal("expected errRootDir, got", err)
+	}
+	if err := r.DeleteDir("c"); err != nil {
+		t.Fatal(err)
+	}
+	if files := r.FileList(); len(files) != 1 || files[0].SiaPath != "top" {
+		t.Fatal("files of the directory were not deleted:", files)
+	}
+	if r.dirExists("c/empty") {
+		t.Fatal("subdirectory was not deleted")
+	}
+}
+
+// TestRenterUploadDirectory checks that uploading a directory uploads its
+// files and creates its subdirectories.
+func TestRenterUploadDirectory(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+	r := rt.renter
+
+	source := build.TempDir("renter", t.Name()+"-source")
+	if err := os.MkdirAll(filepath.Join(source, "sub", "empty"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"one", filepath.Join("sub", "two")} {
+		if err := ioutil.WriteFile(filepath.Join(source, name), []byte(name), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := r.UploadDirectory(modules.FileUploadParams{Source: filepath.Join(source, "one"), SiaPath: "up"}); err != errUploadFile {
+		t.Fatal("expected errUploadFile, got", err)
+	}
+	rt.addTestingFile("up/one", 1)
+	if err := r.UploadDirectory(modules.FileUploadParams{Source: source, SiaPath: "up"}); err != ErrPathOverload {
+		t.Fatal("expected ErrPathOverload, got", err)
+	}
+	if err := r.DeleteFile("up/one"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.UploadDirectory(modules.FileUploadParams{Source: source, SiaPath: "up"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"up/one", "up/sub/two"} {
+		if _, exists := r.files[name]; !exists {
+			t.Fatal("file was not uploaded:", name)
+		}
+	}
+	if !r.dirExists("up/sub/empty") {
+		t.Fatal("empty subdirectory was not created")
+	}
+}
+
+// TestRenterUploadDirectoryFilter checks that the include and exclude
+// patterns of a directory upload select the files that are uploaded.
+func TestRenterUploadDirectoryFilter(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+	r := rt.renter
+
+	source := build.TempDir("renter", t.Name()+"-source")
+	for _, dir := range []string{"docs", "build", "empty"} {
+		if err := os.MkdirAll(filepath.Join(source, dir), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"a.txt", "a.tmp", filepath.Join("docs", "b.txt"), filepath.Join("docs", "c.md"), filepath.Join("build", "d.txt")} {
+		if err := ioutil.WriteFile(filepath.Join(source, name), []byte(name), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	up := modules.FileUploadParams{Source: source, SiaPath: "up", Exclude: []string{"["}}
+	if err := r.UploadDirectory(up); err == nil {
+		t.Fatal("expected an error for an invalid pattern")
+	}
+
+	// Excluded files and directories are skipped at any depth.
+	up.Exclude = []string{"*.tmp", "build"}
+	if err := r.UploadDirectory(up); err != nil {
+		t.Fatal(err)
+	}
+	for name, uploaded := range map[string]bool{"up/a.txt": true, "up/a.tmp": false, "up/docs/b.txt": true, "up/docs/c.md": true, "up/build/d.txt": false} {
+		if _, exists := r.files[name]; exists != uploaded {
+			t.Fatalf("expected %v to be uploaded: %v", name, uploaded)
+		}
+	}
+	if !r.dirExists("up/empty") || r.dirExists("up/build") {
+		t.Fatal("wrong directories were created")
+	}
+
+	// Only included files are uploaded, and only their directories are
+	// created.
+	up = modules.FileUploadParams{Source: source, SiaPath: "inc", Include: []string{"*.txt"}, Exclude: []string{"docs/*"}}
+	if err := r.UploadDirectory(up); err != nil {
+		t.Fatal(err)
+	}
+	for name, uploaded := range map[string]bool{"inc/a.txt": true, "inc/a.tmp": false, "inc/docs/b.txt": false, "inc/build/d.txt": true} {
+		if _, exists := r.files[name]; exists != uploaded {
+			t.Fatalf("expected %v to be uploaded: %v", name, uploaded)
+		}
+	}
+	if r.dirExists("inc/empty") || r.dirExists("inc/docs") {
+		t.Fatal("directories without included files were created")
+	}
+}
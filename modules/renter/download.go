@@ -9,6 +9,7 @@ import (
 	"errors"
 	"io"
 	"os"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -28,6 +29,7 @@ var (
 	errPrevErr            = errors.New("download could not be completed due to a previous error")
 	errInsufficientHosts  = errors.New("insufficient hosts to recover file")
 	errInsufficientPieces = errors.New("couldn't fetch enough pieces to recover data")
+	errChunkRecovered     = errors.New("chunk was recovered from other hosts")
 
 	// maxActiveDownloadPieces determines the maximum number of pieces that are
 	// allowed to be concurrently downloading. More pieces means more
@@ -39,6 +41,16 @@ var (
 		Dev:      int(10),
 		Testing:  int(5),
 	}).(int)
+
+	// downloadOverdrive is the number of pieces of each chunk that are
+	// requested in addition to the pieces needed to recover it. The chunk is
+	// recovered from the first pieces that arrive, so that a slow host does
+	// not hold up the download.
+	downloadOverdrive = build.Select(build.Var{
+		Standard: int(2),
+		Dev:      int(1),
+		Testing:  int(1),
+	}).(int)
 )
 
 type (
@@ -56,6 +68,15 @@ type (
 		// have tried to fetch a piece of the chunk.
 		completedPieces map[uint64][]byte
 		workerAttempts  map[types.FileContractID]bool
+
+		// inFlight is the number of pieces of the chunk that workers are
+		// downloading.
+		//
+		// done is closed once the chunk has been recovered, after which the
+		// remaining pieces of the chunk are discarded. Workers that have not
+		// started to download a piece of the chunk skip it.
+		inFlight int
+		done     chan struct{}
 	}
 
 	// A download is a file download that has been queued by the renter.
@@ -67,6 +88,10 @@ type (
 		finishedChunks     map[uint64]bool
 		offset             uint64
 		length             uint64
+		progress           transferProgress
+
+		// id identifies the download in the download queue.
+		id uint64
 
 		// Timestamp information.
 		completeTime time.Time
@@ -79,6 +104,7 @@ type (
 		fileSize    uint64
 		masterKey   crypto.TwofishKey
 		numChunks   uint64
+		pieceSize   uint64
 
 		// pieceSet contains a sparse map of the chunk indices to be downloaded to
 		// their piece data.
@@ -140,6 +166,7 @@ func (r *Renter) newSectionDownload(f *file, destination modules.DownloadWriter,
 	}
 
 	d.initPieceSet(f, currentContracts, r)
+	d.progress = newTransferProgress(d.startTime, d.atomicDataReceived)
 	return d
 }
 
@@ -153,6 +180,7 @@ func newDownload(f *file, destination modules.DownloadWriter) *download {
 		fileSize:         f.size,
 		masterKey:        f.masterKey,
 		numChunks:        f.numChunks(),
+		pieceSize:        f.pieceSize,
 		siapath:          f.name,
 		downloadFinished: make(chan struct{}),
 		finishedChunks:   make(map[uint64]bool),
@@ -223,9 +251,18 @@ func (d *download) fail(err error) {
 	close(d.downloadFinished)
 }
 
+// recoverSize returns the number of bytes of the file held by a chunk.
+func (d *download) recoverSize(index uint64) uint64 {
+	if index == d.numChunks-1 && d.fileSize%d.chunkSize != 0 {
+		return d.fileSize % d.chunkSize
+	}
+	return d.chunkSize
+}
+
 // recoverChunk takes a chunk that has had a sufficient number of pieces
-// downloaded and verifies, decrypts and decodes them into the file.
-func (cd *chunkDownload) recoverChunk() error {
+// downloaded and verifies, decrypts and decodes them, returning the data of
+// the chunk.
+func (cd *chunkDownload) recoverChunk() ([]byte, error) {
 	// Assemble the chunk from the download.
 	cd.download.mu.Lock()
 	chunk := make([][]byte, cd.download.erasureCode.NumPieces())
@@ -238,7 +275,7 @@ func (cd *chunkDownload) recoverChunk() error {
 
 	// Return early if the download has previously suffered an error.
 	if complete {
-		return build.ComposeErrors(errPrevErr, prevErr)
+		return nil, build.ComposeErrors(errPrevErr, prevErr)
 	}
 
 	// Decrypt the chunk pieces.
@@ -248,28 +285,41 @@ func (cd *chunkDownload) recoverChunk() error {
 			continue
 		}
 
-		// Decrypt the piece.
+		// Strip the padding of pieces that are smaller than a sector, and
+		// decrypt the piece.
+		if n := cd.download.pieceSize + crypto.TwofishOverhead; uint64(len(chunk[i])) > n {
+			chunk[i] = chunk[i][:n]
+		}
 		key := deriveKey(cd.download.masterKey, cd.index, uint64(i))
 		decryptedPiece, err := key.DecryptBytes(chunk[i])
 		if err != nil {
-			return build.ExtendErr("unable to decrypt piece", err)
+			return nil, build.ExtendErr("unable to decrypt piece", err)
 		}
 		chunk[i] = decryptedPiece
 	}
 
 	// Recover the chunk into a byte slice.
 	recoverWriter := new(bytes.Buffer)
-	recoverSize := cd.download.chunkSize
-	if cd.index == cd.download.numChunks-1 && cd.download.fileSize%cd.download.chunkSize != 0 {
-		recoverSize = cd.download.fileSize % cd.download.chunkSize
-	}
-	err := cd.download.erasureCode.Recover(chunk, recoverSize, recoverWriter)
+	err := cd.download.erasureCode.Recover(chunk, cd.download.recoverSize(cd.index), recoverWriter)
 	if err != nil {
-		return build.ExtendErr("unable to recover chunk", err)
+		return nil, build.ExtendErr("unable to recover chunk", err)
 	}
+	return recoverWriter.Bytes(), nil
+}
 
-	result := recoverWriter.Bytes()
+// recovered reports whether the chunk has been recovered.
+func (cd *chunkDownload) recovered() bool {
+	select {
+	case <-cd.done:
+		return true
+	default:
+		return false
+	}
+}
 
+// writeChunk writes the part of the data of a recovered chunk that was
+// requested to the destination of the download.
+func (cd *chunkDownload) writeChunk(result []byte) error {
 	// Calculate the offset. If the offset is within the chunk, the
 	// requested offset is passed, otherwise the offset of the chunk
 	// within the overall file is passed.
@@ -293,7 +343,7 @@ func (cd *chunkDownload) recoverChunk() error {
 	result = result[lowerBound:upperBound]
 
 	// Write the bytes to the requested output.
-	_, err = cd.download.destination.WriteAt(result, int64(off))
+	_, err := cd.download.destination.WriteAt(result, int64(off))
 	if err != nil {
 		return build.ExtendErr("unable to write to download destination", err)
 	}
@@ -325,32 +375,57 @@ func (cd *chunkDownload) recoverChunk() error {
 }
 
 // addDownloadToChunkQueue takes a file and adds all incomplete work from the file
-// to the renter's chunk queue.
+// to the renter's chunk queue. Chunks that are in the download cache are
+// written to the destination right away instead.
 func (r *Renter) addDownloadToChunkQueue(d *download) {
 	d.mu.Lock()
-	defer d.mu.Unlock()
-
 	// Skip this file if it has already errored out or has already finished
 	// downloading.
 	if d.downloadComplete {
+		d.mu.Unlock()
 		return
 	}
-
-	// Add the unfinished chunks one at a time.
+	// Collect the unfinished chunks, in order, so that a streamed download
+	// can be written as soon as its first chunks are ready.
+	var unfinished []uint64
 	for i, isChunkFinished := range d.finishedChunks {
-		// Skip chunks that have already finished downloading.
-		if isChunkFinished {
-			continue
+		if !isChunkFinished {
+			unfinished = append(unfinished, i)
 		}
+	}
+	d.mu.Unlock()
+	sort.Slice(unfinished, func(i, j int) bool { return unfinished[i] < unfinished[j] })
 
-		// Add this chunk to the chunk queue.
+	// Add the unfinished chunks one at a time.
+	for _, i := range unfinished {
 		cd := &chunkDownload{
 			download: d,
-			index:    uint64(i),
+			index:    i,
 
 			completedPieces: make(map[uint64][]byte),
 			workerAttempts:  make(map[types.FileContractID]bool),
+			done:            make(chan struct{}),
+		}
+
+		// Write the chunk directly if it is cached.
+		if data, cached := r.downloadCache.get(d.masterKey, i, d.recoverSize(i)); cached {
+			if err := cd.writeChunk(data); err != nil {
+				r.log.Println("Download failed - could not write a cached chunk:", err)
+				d.mu.Lock()
+				d.fail(err)
+				d.mu.Unlock()
+				r.managedNotifyDownload(d)
+				return
+			}
+			received := atomic.AddUint64(&d.atomicDataReceived, d.reportedPieceSize*uint64(d.erasureCode.MinPieces()))
+			d.mu.Lock()
+			d.progress.addSample(time.Now(), received)
+			d.mu.Unlock()
+			r.managedNotifyDownload(d)
+			continue
 		}
+
+		// Add this chunk to the chunk queue.
 		for fcid := range d.pieceSet[i] {
 			cd.workerAttempts[fcid] = false
 		}
@@ -403,6 +478,10 @@ func (r *Renter) managedDownloadIteration(ds *downloadState) {
 	}
 	r.mu.Unlock(id)
 
+	// Pieces are downloaded from the first available worker that has them,
+	// so the hosts with the lowest round-trip time are tried first.
+	sortWorkersByRTT(ds.availableWorkers)
+
 	// Add new chunks to the extent that resources allow.
 	r.managedScheduleNewChunks(ds)
 
@@ -437,6 +516,13 @@ loop:
 			continue
 		}
 
+		// Drop the surplus requests of a chunk that has already been
+		// recovered.
+		if incompleteChunk.recovered() {
+			ds.activePieces--
+			continue
+		}
+
 		// Try to find a worker that is able to pick up the slack on the
 		// incomplete download from the set of available workers.
 		for i, worker := range ds.availableWorkers {
@@ -460,6 +546,7 @@ loop:
 				resultChan:    ds.resultChan,
 			}
 			incompleteChunk.workerAttempts[worker.contractID] = true
+			incompleteChunk.inFlight++
 			ds.availableWorkers = append(ds.availableWorkers[:i], ds.availableWorkers[i+1:]...)
 			ds.activeWorkers[worker.contractID] = struct{}{}
 			select {
@@ -490,10 +577,18 @@ loop:
 		// or the active set is able to pick up the slack. Verify that they are
 		// safe to be scheduled, and then schedule them if so.
 
+		// A surplus request that no worker can serve is dropped, as long as
+		// the pieces that are being downloaded may still recover the chunk.
+		if len(incompleteChunk.completedPieces)+incompleteChunk.inFlight >= incompleteChunk.download.erasureCode.MinPieces() {
+			ds.activePieces--
+			continue
+		}
+
 		// Cannot find workers to complete this download, fail the download
 		// connected to this chunk.
 		r.log.Println("Not enough workers to finish download:", errInsufficientHosts)
 		incompleteChunk.download.fail(errInsufficientHosts)
+		r.managedNotifyDownload(incompleteChunk.download)
 
 		// Clear out the piece burden for this chunk.
 		ds.activePieces--                                       // for the current incomplete chunk
@@ -518,9 +613,10 @@ func (r *Renter) managedScheduleNewChunks(ds *downloadState) {
 
 		// View the next chunk.
 		nextChunk := r.chunkQueue[0]
+		minPieces := nextChunk.download.erasureCode.MinPieces()
 
 		// Check whether there are enough resources to perform the download.
-		if ds.activePieces+nextChunk.download.erasureCode.MinPieces() > maxActiveDownloadPieces {
+		if ds.activePieces+minPieces > maxActiveDownloadPieces {
 			// There is a limited amount of RAM available, and scheduling the
 			// next piece would consume too much RAM.
 			return
@@ -539,11 +635,23 @@ func (r *Renter) managedScheduleNewChunks(ds *downloadState) {
 			continue
 		}
 
-		// Add an incomplete chunk entry for every piece of the download.
-		for i := 0; i < nextChunk.download.erasureCode.MinPieces(); i++ {
+		// Add an incomplete chunk entry for every piece needed to recover
+		// the chunk, and for up to downloadOverdrive extra pieces if there
+		// are enough hosts and resources for them.
+		overdrive := len(nextChunk.download.pieceSet[nextChunk.index]) - minPieces
+		if overdrive > downloadOverdrive {
+			overdrive = downloadOverdrive
+		}
+		if spare := maxActiveDownloadPieces - ds.activePieces - minPieces; overdrive > spare {
+			overdrive = spare
+		}
+		if overdrive < 0 {
+			overdrive = 0
+		}
+		for i := 0; i < minPieces+overdrive; i++ {
 			ds.incompleteChunks = append(ds.incompleteChunks, nextChunk)
 		}
-		ds.activePieces += nextChunk.download.erasureCode.MinPieces()
+		ds.activePieces += minPieces + overdrive
 	}
 }
 
@@ -570,6 +678,8 @@ func (r *Renter) managedWaitOnDownloadWork(ds *downloadState) {
 	// Prepare the piece.
 	workerID := finishedDownload.workerID
 	delete(ds.activeWorkers, workerID)
+	cd := finishedDownload.chunkDownload
+	cd.inFlight--
 
 	// Fetch the corresponding worker.
 	id := r.mu.RLock()
@@ -579,24 +689,48 @@ func (r *Renter) managedWaitOnDownloadWork(ds *downloadState) {
 		return
 	}
 
+	// Discard the piece if the chunk has already been recovered from the
+	// pieces of faster hosts. The piece was still paid for if it arrived.
+	if cd.recovered() {
+		ds.activePieces--
+		if finishedDownload.err == nil {
+			r.managedRecordDownload(worker.contractID, cd.download.siapath)
+		}
+		return
+	}
+
 	// Check for an error.
-	cd := finishedDownload.chunkDownload
 	if finishedDownload.err != nil {
 		r.log.Debugln("Error when downloading a piece:", finishedDownload.err)
 		worker.recentDownloadFailure = time.Now()
 		ds.incompleteChunks = append(ds.incompleteChunks, cd)
+		cd.download.mu.Lock()
+		cd.download.progress.addFailure(worker.netAddress)
+		cd.download.mu.Unlock()
 		return
 	}
 
 	// Add this returned piece to the appropriate chunk.
+	r.managedRecordDownload(worker.contractID, cd.download.siapath)
 	cd.completedPieces[finishedDownload.pieceIndex] = finishedDownload.data
-	atomic.AddUint64(&cd.download.atomicDataReceived, cd.download.reportedPieceSize)
+	received := atomic.AddUint64(&cd.download.atomicDataReceived, cd.download.reportedPieceSize)
+	cd.download.mu.Lock()
+	cd.download.progress.addPiece(worker.netAddress, time.Now(), received)
+	cd.download.mu.Unlock()
+	defer r.managedNotifyDownload(cd.download)
 
 	// If the chunk has completed, perform chunk recovery.
 	if len(cd.completedPieces) == cd.download.erasureCode.MinPieces() {
-		err := cd.recoverChunk()
+		data, err := cd.recoverChunk()
+		if err == nil {
+			if cacheErr := r.downloadCache.put(cd.download.masterKey, cd.index, data); cacheErr != nil {
+				r.log.Debugln("Unable to add a chunk to the download cache:", cacheErr)
+			}
+			err = cd.writeChunk(data)
+		}
 		ds.activePieces -= len(cd.completedPieces)
 		cd.completedPieces = make(map[uint64][]byte)
+		close(cd.done)
 		if err != nil {
 			r.log.Println("Download failed - could not recover a chunk:", err)
 			cd.download.mu.Lock()
@@ -0,0 +1,117 @@
+package renter
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+	"github.com/NebulousLabs/fastrand"
+)
+
+// TestDownloadOverdrive checks that the pieces of a chunk are requested from
+// more hosts than needed to recover it, that the chunk is recovered from the
+// first pieces that arrive, and that the remaining requests are dropped.
+func TestDownloadOverdrive(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+	r := rt.renter
+
+	// Store a 1-of-3 file of two chunks on three hosts.
+	rsc, _ := NewRSCode(1, 2)
+	data := fastrand.Bytes(100)
+	f := newFile("foo", rsc, 64, uint64(len(data)))
+	pieces := make(map[uint64][][]byte)
+	currentContracts := make(map[modules.NetAddress]types.FileContractID)
+	workers := make([]*worker, 3)
+	for chunk := uint64(0); chunk < 2; chunk++ {
+		end := (chunk + 1) * 64
+		if end > uint64(len(data)) {
+			end = uint64(len(data))
+		}
+		pieces[chunk], _ = rsc.Encode(data[chunk*64 : end])
+	}
+	for i := range workers {
+		id := types.FileContractID{byte(i)}
+		addr := modules.NetAddress(string('a'+byte(i)) + ":1")
+		f.contracts[id] = fileContract{ID: id, IP: addr, Pieces: []pieceData{
+			{Chunk: 0, Piece: uint64(i)},
+			{Chunk: 1, Piece: uint64(i)},
+		}}
+		currentContracts[addr] = id
+		workers[i] = &worker{
+			contractID:           id,
+			netAddress:           addr,
+			rtt:                  time.Duration(i+1) * time.Millisecond,
+			priorityDownloadChan: make(chan downloadWork, 1),
+			renter:               r,
+		}
+		r.workerPool[id] = workers[i]
+	}
+	buf := NewDownloadBufferWriter(f.size)
+	d := r.newSectionDownload(f, buf, currentContracts, 0, f.size)
+
+	ds := &downloadState{
+		activeWorkers:    make(map[types.FileContractID]struct{}),
+		availableWorkers: append([]*worker(nil), workers...),
+		resultChan:       make(chan finishedDownload),
+	}
+	// respond returns the piece requested from a worker, or fails it.
+	respond := func(w *worker, fail bool) {
+		dw := <-w.priorityDownloadChan
+		fd := finishedDownload{dw.chunkDownload, nil, nil, dw.pieceIndex, w.contractID}
+		if fail {
+			fd.err = errors.New("host is offline")
+		} else {
+			key := deriveKey(f.masterKey, dw.chunkDownload.index, dw.pieceIndex)
+			fd.data = key.EncryptBytes(pieces[dw.chunkDownload.index][dw.pieceIndex])
+		}
+		go func() { ds.resultChan <- fd }()
+		r.managedWaitOnDownloadWork(ds)
+	}
+
+	// Each chunk is requested from two hosts. The third host can only take
+	// one request, so the second request for the second chunk waits.
+	r.addDownloadToChunkQueue(d)
+	r.managedScheduleNewChunks(ds)
+	r.managedScheduleIncompleteChunks(ds)
+	if ds.activePieces != 4 || len(ds.activeWorkers) != 3 || len(ds.incompleteChunks) != 1 {
+		t.Fatal("wrong number of requests:", ds.activePieces, len(ds.activeWorkers), len(ds.incompleteChunks))
+	}
+
+	// The first chunk is recovered from the second host, and the first host
+	// skips its request for the chunk.
+	respond(workers[1], false)
+	if !d.finishedChunks[0] {
+		t.Fatal("chunk was not recovered from the first piece to arrive")
+	}
+	go workers[0].download(<-workers[0].priorityDownloadChan)
+	r.managedWaitOnDownloadWork(ds)
+	if !workers[0].recentDownloadFailure.IsZero() {
+		t.Fatal("skipped request counted as a failure")
+	}
+
+	// The waiting request for the second chunk goes to the first host. The
+	// third host fails, and the chunk is recovered from the first host.
+	ds.availableWorkers = []*worker{workers[0], workers[1]}
+	r.managedScheduleIncompleteChunks(ds)
+	respond(workers[2], true)
+	respond(workers[0], false)
+	r.managedScheduleIncompleteChunks(ds)
+	if ds.activePieces != 0 || len(ds.incompleteChunks) != 0 {
+		t.Fatal("requests were not dropped:", ds.activePieces, len(ds.incompleteChunks))
+	}
+	if err := d.Err(); err != nil {
+		t.Fatal(err)
+	} else if !d.downloadComplete || !bytes.Equal(buf.Bytes(), data) {
+		t.Fatal("download did not complete with the data of the file")
+	}
+}
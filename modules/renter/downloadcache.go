@@ -0,0 +1,248 @@
+package renter
+
+// downloadcache.go keeps the chunks that the renter has recently downloaded
+// on disk, so that a chunk which is downloaded repeatedly, such as a chunk of
+// a file that is streamed several times, only has to be paid for once. The
+// cache is bounded in size, and evicts the least recently used chunks first.
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+var errChunkTooLarge = errors.New("chunk is larger than the download cache")
+
+type (
+	// downloadCacheKey identifies a chunk in the download cache. The file is
+	// identified by a hash of its master key, so that the key itself is not
+	// revealed by the names of the cache files.
+	downloadCacheKey struct {
+		fileID crypto.Hash
+		index  uint64
+	}
+
+	// downloadCacheEntry is a chunk that is held by the download cache.
+	downloadCacheEntry struct {
+		key  downloadCacheKey
+		size uint64
+	}
+
+	// downloadCache is a cache on disk of the chunks that have been
+	// downloaded by the renter. The chunks are stored encrypted, and the
+	// entries are ordered from most to least recently used.
+	downloadCache struct {
+		dir     string
+		maxSize uint64
+		size    uint64
+		entries map[downloadCacheKey]*list.Element
+		lru     *list.List
+
+		hits   uint64
+		misses uint64
+		mu     sync.Mutex
+	}
+)
+
+// newDownloadCacheKey returns the key of a chunk of the file with the given
+// master key.
+func newDownloadCacheKey(masterKey crypto.TwofishKey, index uint64) downloadCacheKey {
+	return downloadCacheKey{
+		fileID: crypto.HashAll("downloadcache", masterKey),
+		index:  index,
+	}
+}
+
+// filename returns the name of the file that holds the chunk.
+func (key downloadCacheKey) filename() string {
+	return fmt.Sprintf("%v-%v", key.fileID, key.index)
+}
+
+// parseDownloadCacheFilename returns the key of the chunk held by the file
+// with the given name.
+func parseDownloadCacheFilename(name string) (key downloadCacheKey, err error) {
+	i := strings.IndexByte(name, '-')
+	if i == -1 {
+		return downloadCacheKey{}, errors.New("invalid download cache filename")
+	}
+	if err := key.fileID.LoadString(name[:i]); err != nil {
+		return downloadCacheKey{}, err
+	}
+	key.index, err = strconv.ParseUint(name[i+1:], 10, 64)
+	if err != nil {
+		return downloadCacheKey{}, err
+	}
+	return key, nil
+}
+
+// chunkKey returns the key that a chunk is encrypted with in the download
+// cache.
+func chunkKey(masterKey crypto.TwofishKey, index uint64) crypto.TwofishKey {
+	return crypto.TwofishKey(crypto.HashAll(masterKey, index, "downloadcache"))
+}
+
+// newDownloadCache returns a download cache that keeps up to maxSize bytes of
+// chunks in dir. The chunks that are already in dir are added to the cache,
+// ordered by the time of their last use.
+func newDownloadCache(dir string, maxSize uint64) (*downloadCache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ModTime().Before(infos[j].ModTime()) })
+
+	dc := &downloadCache{
+		dir:     dir,
+		maxSize: maxSize,
+		entries: make(map[downloadCacheKey]*list.Element),
+		lru:     list.New(),
+	}
+	for _, info := range infos {
+		key, err := parseDownloadCacheFilename(info.Name())
+		if info.IsDir() || err != nil {
+			// Remove the files that do not belong to the cache, such as
+			// partially written chunks.
+			os.RemoveAll(filepath.Join(dir, info.Name()))
+			continue
+		}
+		dc.entries[key] = dc.lru.PushFront(&downloadCacheEntry{key: key, size: uint64(info.Size())})
+		dc.size += uint64(info.Size())
+	}
+	dc.evict(0)
+	return dc, nil
+}
+
+// remove removes an entry from the cache. The caller must hold the cache
+// lock.
+func (dc *downloadCache) remove(e *list.Element) {
+	entry := dc.lru.Remove(e).(*downloadCacheEntry)
+	delete(dc.entries, entry.key)
+	dc.size -= entry.size
+	os.Remove(filepath.Join(dc.dir, entry.key.filename()))
+}
+
+// evict removes the least recently used entries until n more bytes fit in the
+// cache. The caller must hold the cache lock.
+func (dc *downloadCache) evict(n uint64) {
+	for dc.size+n > dc.maxSize && dc.lru.Len() > 0 {
+		dc.remove(dc.lru.Back())
+	}
+}
+
+// get returns the data of a chunk of the file with the given master key, if
+// the chunk is in the cache. size is the expected size of the chunk; a chunk
+// of a different size belongs to an earlier version of the file and is
+// removed.
+func (dc *downloadCache) get(masterKey crypto.TwofishKey, index, size uint64) ([]byte, bool) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	key := newDownloadCacheKey(masterKey, index)
+	e, exists := dc.entries[key]
+	if !exists {
+		dc.misses++
+		return nil, false
+	}
+	path := filepath.Join(dc.dir, key.filename())
+	ciphertext, err := ioutil.ReadFile(path)
+	if err != nil {
+		dc.remove(e)
+		dc.misses++
+		return nil, false
+	}
+	data, err := chunkKey(masterKey, index).DecryptBytes(ciphertext)
+	if err != nil || uint64(len(data)) != size {
+		dc.remove(e)
+		dc.misses++
+		return nil, false
+	}
+
+	// Record the use on disk as well, so that the order of the entries is
+	// kept when the renter restarts.
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	dc.lru.MoveToFront(e)
+	dc.hits++
+	return data, true
+}
+
+// put adds a chunk of the file with the given master key to the cache,
+// evicting the least recently used chunks to make room for it.
+func (dc *downloadCache) put(masterKey crypto.TwofishKey, index uint64, data []byte) error {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	key := newDownloadCacheKey(masterKey, index)
+	if e, exists := dc.entries[key]; exists {
+		dc.remove(e)
+	}
+	ciphertext := chunkKey(masterKey, index).EncryptBytes(data)
+	size := uint64(len(ciphertext))
+	if size > dc.maxSize {
+		return errChunkTooLarge
+	}
+	dc.evict(size)
+
+	// Write the chunk to a temporary file first, so that a partially written
+	// chunk is never mistaken for a cached one.
+	path := filepath.Join(dc.dir, key.filename())
+	if err := ioutil.WriteFile(path+"_temp", ciphertext, 0600); err != nil {
+		os.Remove(path + "_temp")
+		return err
+	}
+	if err := os.Rename(path+"_temp", path); err != nil {
+		os.Remove(path + "_temp")
+		return err
+	}
+	dc.entries[key] = dc.lru.PushFront(&downloadCacheEntry{key: key, size: size})
+	dc.size += size
+	return nil
+}
+
+// removeFile removes the chunks of the file with the given master key from
+// the cache. It is called when a file is deleted, as a new file uploaded to
+// the same path has the same master key.
+func (dc *downloadCache) removeFile(masterKey crypto.TwofishKey) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	fileID := newDownloadCacheKey(masterKey, 0).fileID
+	for key, e := range dc.entries {
+		if key.fileID == fileID {
+			dc.remove(e)
+		}
+	}
+}
+
+// metrics returns the usage and effectiveness of the cache.
+func (dc *downloadCache) metrics() modules.DownloadCacheMetrics {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	return modules.DownloadCacheMetrics{
+		Chunks:  uint64(dc.lru.Len()),
+		Size:    dc.size,
+		MaxSize: dc.maxSize,
+		Hits:    dc.hits,
+		Misses:  dc.misses,
+	}
+}
+
+// DownloadCacheMetrics returns the usage of the download cache, and the
+// number of chunks that were and were not found in it.
+func (r *Renter) DownloadCacheMetrics() modules.DownloadCacheMetrics {
+	return r.downloadCache.metrics()
+}
@@ -0,0 +1,85 @@
+package renter
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/fastrand"
+)
+
+// TestDownloadCache probes the eviction and the metrics of the download
+// cache.
+func TestDownloadCache(t *testing.T) {
+	dir := build.TempDir("renter", t.Name())
+	// Leave room for 3 chunks of 100 bytes.
+	chunkSize := uint64(100 + crypto.TwofishOverhead)
+	dc, err := newDownloadCache(dir, 3*chunkSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var key1, key2 crypto.TwofishKey
+	fastrand.Read(key1[:])
+	fastrand.Read(key2[:])
+	chunks := make([][]byte, 4)
+	for i := range chunks {
+		chunks[i] = fastrand.Bytes(100)
+		if err := dc.put(key1, uint64(i), chunks[i]); err != nil {
+			t.Fatal(err)
+		}
+		// Use the first chunk, so that the second is the least recently
+		// used when the fourth is added.
+		if i == 2 {
+			if data, exists := dc.get(key1, 0, 100); !exists || !bytes.Equal(data, chunks[0]) {
+				t.Fatal("chunk 0 was not cached")
+			}
+		}
+	}
+	if _, exists := dc.get(key1, 1, 100); exists {
+		t.Fatal("least recently used chunk was not evicted")
+	}
+	for _, i := range []uint64{0, 2, 3} {
+		if data, exists := dc.get(key1, i, 100); !exists || !bytes.Equal(data, chunks[i]) {
+			t.Fatal("chunk", i, "was not cached")
+		}
+	}
+	// Chunks of other files, and chunks of a different size, are not found.
+	if _, exists := dc.get(key2, 0, 100); exists {
+		t.Fatal("chunk of another file was found")
+	}
+	if _, exists := dc.get(key1, 0, 50); exists {
+		t.Fatal("chunk of the wrong size was found")
+	}
+	if m := dc.metrics(); m.Chunks != 2 || m.Size != 2*chunkSize || m.MaxSize != 3*chunkSize || m.Hits != 4 || m.Misses != 3 {
+		t.Fatal("wrong metrics:", m)
+	}
+	if err := dc.put(key1, 0, make([]byte, 4*chunkSize)); err != errChunkTooLarge {
+		t.Fatal("expected errChunkTooLarge, got", err)
+	}
+
+	// The cache is loaded from disk.
+	if err := dc.put(key2, 0, chunks[0]); err != nil {
+		t.Fatal(err)
+	}
+	dc, err = newDownloadCache(dir, 3*chunkSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := dc.metrics(); m.Chunks != 3 || m.Size != 3*chunkSize || m.Hits != 0 {
+		t.Fatal("wrong metrics after reload:", m)
+	}
+	if data, exists := dc.get(key2, 0, 100); !exists || !bytes.Equal(data, chunks[0]) {
+		t.Fatal("chunk was not loaded")
+	}
+
+	// Removing a file removes its chunks only.
+	dc.removeFile(key1)
+	if _, exists := dc.get(key1, 3, 100); exists {
+		t.Fatal("chunk of removed file was found")
+	}
+	if _, exists := dc.get(key2, 0, 100); !exists {
+		t.Fatal("chunk of another file was removed")
+	}
+}
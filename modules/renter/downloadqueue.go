@@ -3,8 +3,9 @@ package renter
 import (
 	"errors"
 	"fmt"
+	"io"
 	"path/filepath"
-	"sync/atomic"
+	"time"
 
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
@@ -19,7 +20,12 @@ func (r *Renter) Download(p modules.RenterDownloadParameters) error {
 	if !exists {
 		return errors.New(fmt.Sprintf("no file with that path: %s", p.Siapath))
 	}
+	return r.managedDownload(file, p)
+}
 
+// managedDownload downloads file using the passed parameters. The file does
+// not need to be one of the renter's files.
+func (r *Renter) managedDownload(file *file, p modules.RenterDownloadParameters) error {
 	isHttpResp := p.Httpwriter != nil
 
 	// validate download parameters
@@ -71,9 +77,12 @@ func (r *Renter) Download(p modules.RenterDownloadParameters) error {
 	// Create the download object and add it to the queue.
 	d := r.newSectionDownload(file, dw, currentContracts, p.Offset, p.Length)
 
-	lockID = r.mu.Lock()
+	lockID := r.mu.Lock()
+	r.lastTransferID++
+	d.id = r.lastTransferID
 	r.downloadQueue = append(r.downloadQueue, d)
 	r.mu.Unlock(lockID)
+	r.managedNotifyDownload(d)
 	r.newDownloads <- d
 
 	// Block until the download has completed.
@@ -88,27 +97,28 @@ func (r *Renter) Download(p modules.RenterDownloadParameters) error {
 	}
 }
 
+// DownloadSection downloads a section of a file to w. The bytes of the
+// section are written in order as soon as they are recovered, so that the
+// section can be streamed while it is downloaded.
+func (r *Renter) DownloadSection(siapath string, offset, length uint64, w io.Writer) error {
+	return r.Download(modules.RenterDownloadParameters{
+		Httpwriter: w,
+		Length:     length,
+		Offset:     offset,
+		Siapath:    siapath,
+	})
+}
+
 // DownloadQueue returns the list of downloads in the queue.
 func (r *Renter) DownloadQueue() []modules.DownloadInfo {
 	lockID := r.mu.RLock()
 	defer r.mu.RUnlock(lockID)
 
 	// Order from most recent to least recent.
+	now := time.Now()
 	downloads := make([]modules.DownloadInfo, len(r.downloadQueue))
 	for i := range r.downloadQueue {
-		d := r.downloadQueue[len(r.downloadQueue)-i-1]
-
-		downloads[i] = modules.DownloadInfo{
-			SiaPath:     d.siapath,
-			Destination: d.destination,
-			Filesize:    d.length,
-			StartTime:   d.startTime,
-		}
-		downloads[i].Received = atomic.LoadUint64(&d.atomicDataReceived)
-
-		if err := d.Err(); err != nil {
-			downloads[i].Error = err.Error()
-		}
+		downloads[i] = r.downloadQueue[len(r.downloadQueue)-i-1].info(now)
 	}
 	return downloads
 }
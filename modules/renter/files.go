@@ -80,6 +80,20 @@ func (f *file) numChunks() uint64 {
 	return n
 }
 
+// chunkPieces returns the number of distinct pieces of a chunk that have been
+// uploaded.
+func (f *file) chunkPieces(chunk uint64) int {
+	pieces := make(map[uint64]struct{})
+	for _, fc := range f.contracts {
+		for _, p := range fc.Pieces {
+			if p.Chunk == chunk {
+				pieces[p.Piece] = struct{}{}
+			}
+		}
+	}
+	return len(pieces)
+}
+
 // available indicates whether the file is ready to be downloaded.
 func (f *file) available(isOffline func(types.FileContractID) bool) bool {
 	chunkPieces := make([]int, f.numChunks())
@@ -99,6 +113,38 @@ func (f *file) available(isOffline func(types.FileContractID) bool) bool {
 	return true
 }
 
+// health returns the percentage of the pieces of the least healthy chunk that
+// are stored on online hosts. A file with every piece on an online host has a
+// health of 100. A file stops being available when its health falls below
+// 100 * MinPieces / NumPieces.
+func (f *file) health(isOffline func(types.FileContractID) bool) float64 {
+	chunkPieces := make([]map[uint64]struct{}, f.numChunks())
+	for i := range chunkPieces {
+		chunkPieces[i] = make(map[uint64]struct{})
+	}
+	for _, fc := range f.contracts {
+		if isOffline(fc.ID) {
+			continue
+		}
+		for _, p := range fc.Pieces {
+			chunkPieces[p.Chunk][p.Piece] = struct{}{}
+		}
+	}
+	minPieces := f.erasureCode.NumPieces()
+	for _, pieces := range chunkPieces {
+		if len(pieces) < minPieces {
+			minPieces = len(pieces)
+		}
+	}
+	return 100 * float64(minPieces) / float64(f.erasureCode.NumPieces())
+}
+
+// atRisk indicates whether the file is available, but would stop being
+// available if its unreliable hosts went offline.
+func (f *file) atRisk(isOffline, isUnreliable func(types.FileContractID) bool) bool {
+	return f.available(isOffline) && !f.available(isUnreliable)
+}
+
 // uploadProgress indicates what percentage of the file (plus redundancy) has
 // been uploaded. Note that a file may be Available long before UploadProgress
 // reaches 100%, and UploadProgress may report a value greater than 100%.
@@ -161,6 +207,36 @@ func (f *file) expiration() types.BlockHeight {
 	return lowest
 }
 
+// migrateRenewedContracts moves the pieces stored in renewed contracts to the
+// contracts that renewed them, updating their address and expiration from the
+// provided contracts. It returns whether the file was changed.
+func (f *file) migrateRenewedContracts(resolveID func(types.FileContractID) types.FileContractID, contracts map[types.FileContractID]modules.RenterContract) bool {
+	changed := false
+	for oldID, fc := range f.contracts {
+		newID := resolveID(oldID)
+		if newID == oldID {
+			continue
+		}
+		renewed, exists := f.contracts[newID]
+		if !exists {
+			renewed = fileContract{
+				ID:          newID,
+				IP:          fc.IP,
+				WindowStart: fc.WindowStart,
+			}
+		}
+		if contract, ok := contracts[newID]; ok {
+			renewed.IP = contract.NetAddress
+			renewed.WindowStart = contract.EndHeight()
+		}
+		renewed.Pieces = append(renewed.Pieces, fc.Pieces...)
+		f.contracts[newID] = renewed
+		delete(f.contracts, oldID)
+		changed = true
+	}
+	return changed
+}
+
 // newFile creates a new file object.
 func newFile(name string, code modules.ErasureCoder, pieceSize, fileSize uint64) *file {
 	return &file{
@@ -185,8 +261,7 @@ func (r *Renter) DeleteFile(nickname string) error {
 		r.mu.Unlock(lockID)
 		return ErrUnknownPath
 	}
-	delete(r.files, nickname)
-	os.RemoveAll(filepath.Join(r.persistDir, f.name+ShareExtension))
+	r.removeFile(nickname)
 	r.saveSync()
 	r.mu.Unlock(lockID)
 
@@ -199,6 +274,64 @@ func (r *Renter) DeleteFile(nickname string) error {
 	return nil
 }
 
+// removeFile removes a file from the renter and deletes its .sia file,
+// canceling its upload. The caller must hold the renter lock.
+func (r *Renter) removeFile(nickname string) {
+	f := r.files[nickname]
+	delete(r.files, nickname)
+	delete(r.repairs, nickname)
+	r.discardConversion(nickname)
+	os.RemoveAll(filepath.Join(r.persistDir, f.name+ShareExtension))
+	r.downloadCache.removeFile(f.masterKey)
+	r.moveDownloadRecords(nickname, "")
+
+	// The subscribers are notified in a separate thread, as they cannot be
+	// notified while the renter lock is held.
+	if event, uploading := r.finishUpload(f, errUploadFileDeleted); uploading {
+		go r.managedNotifyTransfer(event)
+	}
+}
+
+// fileInfo returns the information of a file. The caller must hold the
+// renter lock.
+func (r *Renter) fileInfo(f *file) modules.FileInfo {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	renewing := true
+	var repairProgress float64
+	fr, repairing := r.repairs[f.name]
+	if repairing {
+		repairProgress = 100 * float64(fr.completed) / float64(fr.chunks)
+	}
+	var conversionProgress float64
+	conv, converting := r.conversions[f.name]
+	if converting {
+		conv.mu.RLock()
+		conversionProgress = conv.uploadProgress()
+		conv.mu.RUnlock()
+	}
+	return modules.FileInfo{
+		SiaPath:        f.name,
+		Filesize:       f.size,
+		Available:      f.available(r.hostContractor.IsOffline),
+		Redundancy:     f.redundancy(r.hostContractor.IsOffline),
+		Health:         f.health(r.hostContractor.IsOffline),
+		AtRisk:         f.atRisk(r.hostContractor.IsOffline, r.hostContractor.IsUnreliable),
+		Renewing:       renewing,
+		UploadProgress: f.uploadProgress(),
+		Expiration:     f.expiration(),
+		DataPieces:     f.erasureCode.MinPieces(),
+		ParityPieces:   f.erasureCode.NumPieces() - f.erasureCode.MinPieces(),
+		PieceSize:      f.pieceSize,
+		Repairing:      repairing,
+		RepairProgress: repairProgress,
+
+		Class:              r.tracking[f.name].Class,
+		Converting:         converting,
+		ConversionProgress: conversionProgress,
+	}
+}
+
 // FileList returns all of the files that the renter has.
 func (r *Renter) FileList() []modules.FileInfo {
 	lockID := r.mu.RLock()
@@ -206,18 +339,7 @@ func (r *Renter) FileList() []modules.FileInfo {
 
 	files := make([]modules.FileInfo, 0, len(r.files))
 	for _, f := range r.files {
-		f.mu.RLock()
-		renewing := true
-		files = append(files, modules.FileInfo{
-			SiaPath:        f.name,
-			Filesize:       f.size,
-			Available:      f.available(r.hostContractor.IsOffline),
-			Redundancy:     f.redundancy(r.hostContractor.IsOffline),
-			Renewing:       renewing,
-			UploadProgress: f.uploadProgress(),
-			Expiration:     f.expiration(),
-		})
-		f.mu.RUnlock()
+		files = append(files, r.fileInfo(f))
 	}
 	return files
 }
@@ -243,6 +365,27 @@ func (r *Renter) RenameFile(currentName, newName string) error {
 	if exists {
 		return ErrPathOverload
 	}
+	if r.dirExists(newName) {
+		return ErrDirOverload
+	}
+
+	if err := r.renameFile(file, newName); err != nil {
+		return err
+	}
+	if err := r.saveSync(); err != nil {
+		return err
+	}
+
+	// Delete the old .sia file.
+	oldPath := filepath.Join(r.persistDir, currentName+ShareExtension)
+	return os.RemoveAll(oldPath)
+}
+
+// renameFile changes the name of a file, saving it to disk under its new name
+// and updating the entries of the renter. The .sia file of the old name is
+// not deleted. The caller must hold the renter lock.
+func (r *Renter) renameFile(file *file, newName string) error {
+	currentName := file.name
 
 	// Modify the file and save it to disk.
 	file.mu.Lock()
@@ -253,19 +396,15 @@ func (r *Renter) RenameFile(currentName, newName string) error {
 		return err
 	}
 
-	// Update the entries in the renter.
+	// Update the entries in the renter. A conversion of the file is started
+	// again under its new name.
 	delete(r.files, currentName)
 	r.files[newName] = file
+	r.discardConversion(currentName)
 	if t, ok := r.tracking[currentName]; ok {
 		delete(r.tracking, currentName)
 		r.tracking[newName] = t
 	}
-	err = r.saveSync()
-	if err != nil {
-		return err
-	}
-
-	// Delete the old .sia file.
-	oldPath := filepath.Join(r.persistDir, currentName+ShareExtension)
-	return os.RemoveAll(oldPath)
+	r.moveDownloadRecords(currentName, newName)
+	return nil
 }
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
 )
 
@@ -212,6 +213,47 @@ func TestFileExpiration(t *testing.T) {
 	}
 }
 
+// TestFileMigrateRenewedContracts checks that the pieces of renewed contracts
+// are moved to the contracts that renewed them.
+func TestFileMigrateRenewedContracts(t *testing.T) {
+	f := &file{
+		contracts: map[types.FileContractID]fileContract{
+			{1}: {ID: types.FileContractID{1}, IP: "old:1", WindowStart: 10, Pieces: []pieceData{{Chunk: 0}}},
+			{2}: {ID: types.FileContractID{2}, IP: "foo:2", WindowStart: 10, Pieces: []pieceData{{Chunk: 1}}},
+			{3}: {ID: types.FileContractID{3}, IP: "new:1", WindowStart: 20, Pieces: []pieceData{{Chunk: 2}}},
+		},
+	}
+	// {1} was renewed by {3}, which already holds a piece.
+	resolveID := func(id types.FileContractID) types.FileContractID {
+		if id == (types.FileContractID{1}) {
+			return types.FileContractID{3}
+		}
+		return id
+	}
+	var renewed modules.RenterContract
+	renewed.ID = types.FileContractID{3}
+	renewed.NetAddress = "new:2"
+	renewed.LastRevision.NewWindowStart = 30
+	contracts := map[types.FileContractID]modules.RenterContract{renewed.ID: renewed}
+
+	if !f.migrateRenewedContracts(resolveID, contracts) {
+		t.Fatal("file was not changed")
+	}
+	if len(f.contracts) != 2 {
+		t.Fatal("expected 2 contracts, got", len(f.contracts))
+	}
+	fc := f.contracts[types.FileContractID{3}]
+	if len(fc.Pieces) != 2 {
+		t.Fatal("pieces were not moved to the renewed contract:", fc.Pieces)
+	}
+	if fc.IP != "new:2" || fc.WindowStart != 30 {
+		t.Fatal("renewed contract was not updated:", fc.IP, fc.WindowStart)
+	}
+	if f.migrateRenewedContracts(resolveID, contracts) {
+		t.Fatal("file was changed twice")
+	}
+}
+
 // TestRenterDeleteFile probes the DeleteFile method of the renter type.
 func TestRenterDeleteFile(t *testing.T) {
 	if testing.Short() {
@@ -375,7 +417,7 @@ func TestRenterRenameFile(t *testing.T) {
 	}
 
 	// Renaming should also update the tracking set
-	rt.renter.tracking["1"] = trackedFile{"foo"}
+	rt.renter.tracking["1"] = trackedFile{RepairPath: "foo"}
 	err = rt.renter.RenameFile("1", "1b")
 	if err != nil {
 		t.Fatal(err)
@@ -386,3 +428,65 @@ func TestRenterRenameFile(t *testing.T) {
 		t.Error("renaming should have updated the entry in the tracking set")
 	}
 }
+
+// TestFileHealth probes the health and atRisk methods of the file type.
+func TestFileHealth(t *testing.T) {
+	rsc, _ := NewRSCode(2, 2)
+	f := &file{
+		size:        1000,
+		pieceSize:   100,
+		contracts:   make(map[types.FileContractID]fileContract),
+		erasureCode: rsc,
+	}
+	neverOffline := func(types.FileContractID) bool {
+		return false
+	}
+	if h := f.health(neverOffline); h != 0 {
+		t.Error("expected 0 health, got", h)
+	}
+
+	// Store each piece of every chunk on its own host.
+	for piece := uint64(0); piece < uint64(rsc.NumPieces()); piece++ {
+		fc := fileContract{ID: types.FileContractID{byte(piece)}}
+		for chunk := uint64(0); chunk < f.numChunks(); chunk++ {
+			fc.Pieces = append(fc.Pieces, pieceData{Chunk: chunk, Piece: piece})
+		}
+		f.contracts[fc.ID] = fc
+	}
+	if h := f.health(neverOffline); h != 100 {
+		t.Error("expected 100 health, got", h)
+	}
+	if f.atRisk(neverOffline, neverOffline) {
+		t.Error("healthy file should not be at risk")
+	}
+
+	// A duplicate piece does not improve the health.
+	dup := f.contracts[types.FileContractID{0}]
+	dup.ID = types.FileContractID{10}
+	f.contracts[dup.ID] = dup
+	firstOffline := func(id types.FileContractID) bool {
+		return id == types.FileContractID{0} || id == types.FileContractID{10}
+	}
+	if h := f.health(firstOffline); h != 75 {
+		t.Error("expected 75 health, got", h)
+	}
+
+	// The file is at risk if it would be unavailable without its unreliable
+	// hosts.
+	oneUnreliable := func(id types.FileContractID) bool {
+		return id == types.FileContractID{1}
+	}
+	threeUnreliable := func(id types.FileContractID) bool {
+		return id != types.FileContractID{3}
+	}
+	if f.atRisk(neverOffline, oneUnreliable) {
+		t.Error("file with one unreliable host should not be at risk")
+	}
+	if !f.atRisk(neverOffline, threeUnreliable) {
+		t.Error("file with only one reliable host should be at risk")
+	}
+	// An unavailable file is not at risk; it is already unavailable.
+	if f.atRisk(threeUnreliable, threeUnreliable) {
+		t.Error("unavailable file should not be at risk")
+	}
+}
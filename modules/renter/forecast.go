@@ -0,0 +1,69 @@
+package renter
+
+// forecast.go forecasts when the funds of the renter's contracts run out, so
+// that the allowance can be increased before uploads start to fail. The
+// contracts do not record when their money was spent, so the forecast assumes
+// that each contract keeps spending at the average rate since it was formed.
+
+import (
+	"sort"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// forecastContract forecasts when the funds of a contract run out, given the
+// current block height and the renew window of the allowance.
+func forecastContract(c modules.RenterContract, height, renewWindow types.BlockHeight) modules.ContractForecast {
+	cf := modules.ContractForecast{
+		ID:             c.ID,
+		NetAddress:     c.NetAddress,
+		HostPublicKey:  c.HostPublicKey,
+		StartHeight:    c.StartHeight,
+		EndHeight:      c.EndHeight(),
+		Size:           uint64(len(c.MerkleRoots)) * modules.SectorSize,
+		TotalCost:      c.TotalCost,
+		RemainingFunds: c.RenterFunds(),
+	}
+	if cf.EndHeight > renewWindow {
+		cf.RenewHeight = cf.EndHeight - renewWindow
+	}
+	if cf.EndHeight > height {
+		cf.RemainingBlocks = cf.EndHeight - height
+	}
+
+	// A contract that was formed in the current block is counted as one
+	// block old.
+	elapsed := types.BlockHeight(1)
+	if height > c.StartHeight {
+		elapsed = height - c.StartHeight
+	}
+	spent := c.StorageSpending.Add(c.UploadSpending).Add(c.DownloadSpending)
+	cf.SpendingRate = spent.Div64(uint64(elapsed))
+	if cf.SpendingRate.IsZero() {
+		return cf
+	}
+	blocks, err := cf.RemainingFunds.Div(cf.SpendingRate).Uint64()
+	if err != nil || blocks > uint64(^types.BlockHeight(0)-height) {
+		// The funds will not run out in any meaningful time.
+		return cf
+	}
+	cf.DepletionHeight = height + types.BlockHeight(blocks)
+	cf.DepletesBeforeRenewal = cf.DepletionHeight < cf.RenewHeight
+	return cf
+}
+
+// Forecast reports the utilization of each contract of the renter, and
+// forecasts when its funds run out at the rate they have been spent.
+func (r *Renter) Forecast() modules.RenterForecast {
+	height := r.cs.Height()
+	renewWindow := r.hostContractor.Allowance().RenewWindow
+	forecast := modules.RenterForecast{Height: height}
+	for _, c := range r.hostContractor.Contracts() {
+		forecast.Contracts = append(forecast.Contracts, forecastContract(c, height, renewWindow))
+	}
+	sort.Slice(forecast.Contracts, func(i, j int) bool {
+		return forecast.Contracts[i].NetAddress < forecast.Contracts[j].NetAddress
+	})
+	return forecast
+}
@@ -0,0 +1,91 @@
+package renter
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// forecastContractor is a hostContractor that reports a fixed set of
+// contracts and allowance.
+type forecastContractor struct {
+	hostContractor
+	contracts []modules.RenterContract
+	allowance modules.Allowance
+}
+
+func (c forecastContractor) Contracts() []modules.RenterContract { return c.contracts }
+func (c forecastContractor) Allowance() modules.Allowance        { return c.allowance }
+
+// forecastTestContract returns a contract that has spent spent out of funds
+// since startHeight, and that ends at endHeight.
+func forecastTestContract(addr modules.NetAddress, startHeight, endHeight types.BlockHeight, spent, funds uint64) modules.RenterContract {
+	return modules.RenterContract{
+		NetAddress:      addr,
+		StartHeight:     startHeight,
+		MerkleRoots:     make(modules.MerkleRootSet, 3),
+		StorageSpending: types.NewCurrency64(spent),
+		TotalCost:       types.NewCurrency64(spent + funds),
+		LastRevision: types.FileContractRevision{
+			NewWindowStart:       endHeight,
+			NewValidProofOutputs: []types.SiacoinOutput{{Value: types.NewCurrency64(funds)}, {}},
+		},
+	}
+}
+
+// TestForecast checks that the funds of a contract are forecast to run out at
+// the rate that they have been spent since the contract was formed.
+func TestForecast(t *testing.T) {
+	// 100 was spent in the 10 blocks since the contract was formed, so the
+	// remaining 300 last 30 more blocks, until before the renewal.
+	cf := forecastContract(forecastTestContract("a:1", 90, 200, 100, 300), 100, 50)
+	if cf.Size != 3*modules.SectorSize || cf.RemainingBlocks != 100 || cf.RenewHeight != 150 {
+		t.Fatal("wrong utilization:", cf)
+	}
+	if cf.SpendingRate.Cmp64(10) != 0 || cf.DepletionHeight != 130 || !cf.DepletesBeforeRenewal {
+		t.Fatal("wrong forecast:", cf)
+	}
+
+	// The funds of a contract that spends slowly last until the renewal.
+	cf = forecastContract(forecastTestContract("a:1", 90, 200, 10, 300), 100, 50)
+	if cf.DepletionHeight != 400 || cf.DepletesBeforeRenewal {
+		t.Fatal("wrong forecast:", cf)
+	}
+
+	// The funds of a contract that has not spent anything never run out.
+	cf = forecastContract(forecastTestContract("a:1", 100, 200, 0, 300), 100, 50)
+	if !cf.SpendingRate.IsZero() || cf.DepletionHeight != 0 || cf.DepletesBeforeRenewal {
+		t.Fatal("wrong forecast:", cf)
+	}
+
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+	r := rt.renter
+	height := r.cs.Height()
+	r.hostContractor = forecastContractor{
+		hostContractor: r.hostContractor,
+		contracts: []modules.RenterContract{
+			forecastTestContract("b:1", height, height+100, 10, 100),
+			forecastTestContract("a:1", height, height+100, 0, 100),
+		},
+		allowance: modules.Allowance{RenewWindow: 20},
+	}
+	forecast := r.Forecast()
+	if forecast.Height != height || len(forecast.Contracts) != 2 {
+		t.Fatal("wrong forecast:", forecast)
+	}
+	a, b := forecast.Contracts[0], forecast.Contracts[1]
+	if a.NetAddress != "a:1" || a.DepletionHeight != 0 || a.RenewHeight != height+80 {
+		t.Fatal("wrong forecast of contract a:", a)
+	}
+	if b.NetAddress != "b:1" || b.DepletionHeight != height+10 || !b.DepletesBeforeRenewal {
+		t.Fatal("wrong forecast of contract b:", b)
+	}
+}
@@ -0,0 +1,114 @@
+package hostdb
+
+import (
+	"errors"
+	"net"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	errEmptyWhitelist = errors.New("a whitelist must contain at least one host or net range")
+	errFilterMode     = errors.New("filter mode must be disable, blacklist or whitelist")
+)
+
+// hostFilter is the parsed form of a modules.HostFilter.
+type hostFilter struct {
+	mode string
+	keys map[string]struct{}
+	nets []*net.IPNet
+}
+
+// newHostFilter checks and parses f.
+func newHostFilter(f modules.HostFilter) (hostFilter, error) {
+	hf := hostFilter{
+		mode: f.Mode,
+		keys: make(map[string]struct{}),
+	}
+	switch f.Mode {
+	case modules.HostFilterModeDisable, modules.HostFilterModeBlacklist:
+	case modules.HostFilterModeWhitelist:
+		if len(f.Hosts) == 0 && len(f.NetRanges) == 0 {
+			return hostFilter{}, errEmptyWhitelist
+		}
+	default:
+		return hostFilter{}, errFilterMode
+	}
+	for _, spk := range f.Hosts {
+		hf.keys[spk.String()] = struct{}{}
+	}
+	for _, cidr := range f.NetRanges {
+		_, ipnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return hostFilter{}, err
+		}
+		hf.nets = append(hf.nets, ipnet)
+	}
+	return hf, nil
+}
+
+// matches returns whether the host is listed in the filter, by its public key
+// or by its IP address.
+func (hf hostFilter) matches(host modules.HostDBEntry) bool {
+	if _, ok := hf.keys[host.PublicKey.String()]; ok {
+		return true
+	}
+	ip := net.ParseIP(host.NetAddress.Host())
+	if ip == nil {
+		return false
+	}
+	for _, ipnet := range hf.nets {
+		if ipnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// excludes returns whether the filter excludes the host.
+func (hf hostFilter) excludes(host modules.HostDBEntry) bool {
+	switch hf.mode {
+	case modules.HostFilterModeBlacklist:
+		return hf.matches(host)
+	case modules.HostFilterModeWhitelist:
+		return !hf.matches(host)
+	}
+	return false
+}
+
+// Filter returns the filter that restricts the hosts used by the renter.
+func (hdb *HostDB) Filter() modules.HostFilter {
+	hdb.mu.RLock()
+	defer hdb.mu.RUnlock()
+	return hdb.filter
+}
+
+// SetFilter sets the filter that restricts the hosts used by the renter. Hosts
+// excluded by the filter are no longer returned by RandomHosts.
+func (hdb *HostDB) SetFilter(f modules.HostFilter) error {
+	if f.Mode == "" {
+		f.Mode = modules.HostFilterModeDisable
+	}
+	hf, err := newHostFilter(f)
+	if err != nil {
+		return err
+	}
+	hdb.mu.Lock()
+	defer hdb.mu.Unlock()
+	hdb.filter = f
+	hdb.hostFilter = hf
+	return hdb.saveSync()
+}
+
+// IsFiltered returns whether the filter excludes the host with the given
+// public key. Hosts unknown to the hostdb are matched by their key only.
+func (hdb *HostDB) IsFiltered(spk types.SiaPublicKey) bool {
+	host, ok := hdb.hostTree.Select(spk)
+	if !ok {
+		host = modules.HostDBEntry{PublicKey: spk}
+	}
+	hdb.mu.RLock()
+	defer hdb.mu.RUnlock()
+	return hdb.hostFilter.excludes(host)
+}
@@ -0,0 +1,126 @@
+package hostdb
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestHostFilter checks that hosts are matched by their public key and by the
+// IP address they announced.
+func TestHostFilter(t *testing.T) {
+	byKey := makeHostDBEntry()
+	byKey.NetAddress = "example.com:9982"
+	byIP := makeHostDBEntry()
+	byIP.NetAddress = "10.1.2.3:9982"
+	other := makeHostDBEntry()
+	other.NetAddress = "192.168.1.1:9982"
+
+	f := modules.HostFilter{
+		Mode:      modules.HostFilterModeBlacklist,
+		Hosts:     []types.SiaPublicKey{byKey.PublicKey},
+		NetRanges: []string{"10.0.0.0/8"},
+	}
+	hf, err := newHostFilter(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hf.excludes(byKey) || !hf.excludes(byIP) || hf.excludes(other) {
+		t.Fatal("blacklist excluded the wrong hosts")
+	}
+	f.Mode = modules.HostFilterModeWhitelist
+	if hf, err = newHostFilter(f); err != nil {
+		t.Fatal(err)
+	}
+	if hf.excludes(byKey) || hf.excludes(byIP) || !hf.excludes(other) {
+		t.Fatal("whitelist excluded the wrong hosts")
+	}
+	f.Mode = modules.HostFilterModeDisable
+	if hf, err = newHostFilter(f); err != nil {
+		t.Fatal(err)
+	}
+	if hf.excludes(byKey) || hf.excludes(other) {
+		t.Fatal("disabled filter excluded a host")
+	}
+
+	// Invalid filters are rejected.
+	if _, err := newHostFilter(modules.HostFilter{Mode: "foo"}); err != errFilterMode {
+		t.Fatal("expected errFilterMode, got", err)
+	}
+	if _, err := newHostFilter(modules.HostFilter{Mode: modules.HostFilterModeWhitelist}); err != errEmptyWhitelist {
+		t.Fatal("expected errEmptyWhitelist, got", err)
+	}
+	if _, err := newHostFilter(modules.HostFilter{Mode: modules.HostFilterModeBlacklist, NetRanges: []string{"10.0.0.0"}}); err == nil {
+		t.Fatal("invalid net range was accepted")
+	}
+}
+
+// TestRandomHostsFilter checks that RandomHosts does not return hosts excluded
+// by the filter, and that the filter is persisted.
+func TestRandomHostsFilter(t *testing.T) {
+	hdb := bareHostDB()
+	hdb.deps = prodDependencies{}
+	hdb.persistDir = build.TempDir("HostDB", t.Name())
+	if err := os.MkdirAll(hdb.persistDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	var hosts []modules.HostDBEntry
+	for i := 0; i < 4; i++ {
+		host := makeHostDBEntry()
+		host.NetAddress = modules.NetAddress("10.0.0." + string('1'+byte(i)) + ":9982")
+		if err := hdb.hostTree.Insert(host); err != nil {
+			t.Fatal(err)
+		}
+		hosts = append(hosts, host)
+	}
+
+	exclude := []types.SiaPublicKey{hosts[3].PublicKey}
+	err := hdb.SetFilter(modules.HostFilter{
+		Mode:  modules.HostFilterModeBlacklist,
+		Hosts: []types.SiaPublicKey{hosts[0].PublicKey},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	random := hdb.RandomHosts(4, exclude)
+	if len(random) != 2 {
+		t.Fatal("expected 2 hosts, got", len(random))
+	}
+	for _, host := range random {
+		if host.PublicKey.String() == hosts[0].PublicKey.String() {
+			t.Fatal("blacklisted host was returned")
+		}
+	}
+	if len(exclude) != 1 {
+		t.Fatal("RandomHosts modified the excluded keys")
+	}
+	if !hdb.IsFiltered(hosts[0].PublicKey) || hdb.IsFiltered(hosts[1].PublicKey) {
+		t.Fatal("IsFiltered does not agree with the filter")
+	}
+
+	// Only the whitelisted hosts are returned.
+	err = hdb.SetFilter(modules.HostFilter{
+		Mode:      modules.HostFilterModeWhitelist,
+		NetRanges: []string{"10.0.0.2/32"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	random = hdb.RandomHosts(4, nil)
+	if len(random) != 1 || random[0].PublicKey.String() != hosts[1].PublicKey.String() {
+		t.Fatal("expected only the whitelisted host, got", random)
+	}
+
+	// The filter is persisted.
+	var data hdbPersist
+	if err := hdb.deps.loadFile(persistMetadata, &data, filepath.Join(hdb.persistDir, persistFilename)); err != nil {
+		t.Fatal(err)
+	}
+	if data.Filter.Mode != modules.HostFilterModeWhitelist || len(data.Filter.NetRanges) != 1 {
+		t.Fatal("filter was not persisted:", data.Filter)
+	}
+}
@@ -7,6 +7,7 @@ package hostdb
 import (
 	"errors"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 	"sync"
@@ -52,6 +53,11 @@ type HostDB struct {
 	scanWait bool
 	online   bool
 
+	// filter restricts the hosts returned by RandomHosts. hostFilter is its
+	// parsed form.
+	filter     modules.HostFilter
+	hostFilter hostFilter
+
 	blockHeight types.BlockHeight
 	lastChange  modules.ConsensusChangeID
 }
@@ -80,6 +86,9 @@ func newHostDB(g modules.Gateway, cs modules.ConsensusSet, persistDir string, de
 		gateway:    g,
 		persistDir: persistDir,
 
+		filter:     modules.HostFilter{Mode: modules.HostFilterModeDisable},
+		hostFilter: hostFilter{mode: modules.HostFilterModeDisable},
+
 		scanMap:  make(map[string]struct{}),
 		scanPool: make(chan modules.HostDBEntry),
 	}
@@ -234,7 +243,58 @@ func (hdb *HostDB) Host(spk types.SiaPublicKey) (modules.HostDBEntry, bool) {
 
 // RandomHosts implements the HostDB interface's RandomHosts() method. It takes
 // a number of hosts to return, and a slice of netaddresses to ignore, and
-// returns a slice of entries.
+// returns a slice of entries. Hosts excluded by the host filter are never
+// returned. Hosts in subnets that are not used by the ignored hosts or by the
+// hosts returned before them are preferred, so that the hosts that are
+// returned are unlikely to fail together.
 func (hdb *HostDB) RandomHosts(n int, excludeKeys []types.SiaPublicKey) []modules.HostDBEntry {
-	return hdb.hostTree.SelectRandom(n, excludeKeys)
+	hdb.mu.RLock()
+	hf := hdb.hostFilter
+	hdb.mu.RUnlock()
+	if hf.mode != modules.HostFilterModeDisable {
+		// Copy the keys, so that the caller's slice is not modified.
+		excludeKeys = append([]types.SiaPublicKey(nil), excludeKeys...)
+		for _, host := range hdb.hostTree.All() {
+			if hf.excludes(host) {
+				excludeKeys = append(excludeKeys, host.PublicKey)
+			}
+		}
+	}
+
+	// Draw every candidate in random order, weighted by their score, and
+	// take the first host of every unused subnet before the others.
+	usedSubnets := make(map[string]struct{})
+	for _, spk := range excludeKeys {
+		if host, ok := hdb.hostTree.Select(spk); ok && host.Subnet != "" {
+			usedSubnets[host.Subnet] = struct{}{}
+		}
+	}
+	candidates := hdb.hostTree.SelectRandom(math.MaxInt32, excludeKeys)
+	return selectDiverseHosts(n, candidates, usedSubnets)
+}
+
+// selectDiverseHosts returns up to n of the candidates, preferring hosts in
+// subnets that are not in usedSubnets and that are not used by the hosts
+// selected before them. Hosts of unknown subnets are treated as being in a
+// subnet of their own. The order of the candidates is otherwise kept.
+func selectDiverseHosts(n int, candidates []modules.HostDBEntry, usedSubnets map[string]struct{}) []modules.HostDBEntry {
+	var hosts, duplicates []modules.HostDBEntry
+	for _, host := range candidates {
+		if len(hosts) == n {
+			return hosts
+		}
+		if _, used := usedSubnets[host.Subnet]; used && host.Subnet != "" {
+			duplicates = append(duplicates, host)
+			continue
+		}
+		usedSubnets[host.Subnet] = struct{}{}
+		hosts = append(hosts, host)
+	}
+	for _, host := range duplicates {
+		if len(hosts) == n {
+			break
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts
 }
@@ -394,6 +394,62 @@ func TestRandomHosts(t *testing.T) {
 	}
 }
 
+// TestRandomHostsSubnets checks that RandomHosts prefers hosts in subnets that
+// are not used by the excluded hosts or by the hosts returned before them, and
+// falls back to hosts in used subnets when there are not enough subnets.
+func TestRandomHostsSubnets(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	hdbt, err := newHDBTesterDeps(t.Name(), disableScanLoopDeps{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Three hosts in 1.2.3.0/24, two in 4.5.6.0/24, and one in 7.8.9.0/24.
+	var hosts []modules.HostDBEntry
+	for _, subnet := range []string{"1.2.3.0/24", "1.2.3.0/24", "1.2.3.0/24", "4.5.6.0/24", "4.5.6.0/24", "7.8.9.0/24"} {
+		entry := makeHostDBEntry()
+		entry.Subnet = subnet
+		if err := hdbt.hdb.hostTree.Insert(entry); err != nil {
+			t.Fatal(err)
+		}
+		hosts = append(hosts, entry)
+	}
+	subnets := func(hosts []modules.HostDBEntry) map[string]int {
+		counts := make(map[string]int)
+		for _, host := range hosts {
+			counts[host.Subnet]++
+		}
+		return counts
+	}
+
+	for i := 0; i < 10; i++ {
+		if counts := subnets(hdbt.hdb.RandomHosts(3, nil)); len(counts) != 3 {
+			t.Fatal("hosts were not selected from every subnet:", counts)
+		}
+
+		// The subnet of an excluded host is only used once the other
+		// subnets are exhausted.
+		selected := hdbt.hdb.RandomHosts(3, []types.SiaPublicKey{hosts[0].PublicKey})
+		if len(selected) != 3 {
+			t.Fatal("expected 3 hosts, got", len(selected))
+		}
+		if counts := subnets(selected[:2]); counts["4.5.6.0/24"] != 1 || counts["7.8.9.0/24"] != 1 {
+			t.Fatal("hosts in unused subnets were not preferred:", counts)
+		}
+		for _, host := range selected {
+			if host.PublicKey.String() == hosts[0].PublicKey.String() {
+				t.Fatal("excluded host was selected")
+			}
+		}
+
+		if selected := hdbt.hdb.RandomHosts(6, nil); len(selected) != 6 {
+			t.Fatal("expected every host to be selected, got", len(selected))
+		}
+	}
+}
+
 // TestRemoveNonexistingHostFromHostTree checks that the host tree interface
 // correctly responds to having a nonexisting host removed from the host tree.
 func TestRemoveNonexistingHostFromHostTree(t *testing.T) {
@@ -3,6 +3,7 @@ package hostdb
 import (
 	"math"
 	"math/big"
+	"time"
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/modules"
@@ -92,6 +93,39 @@ func (hdb *HostDB) collateralAdjustments(entry modules.HostDBEntry) float64 {
 	return weight
 }
 
+// latencyAdjustments will adjust the weight of the entry according to the
+// average latency of its successful scans. Hosts without any latency
+// measurements are not penalized.
+func latencyAdjustments(entry modules.HostDBEntry) float64 {
+	var total time.Duration
+	var measurements int64
+	for _, scan := range entry.ScanHistory {
+		if scan.Success && scan.Latency > 0 {
+			total += scan.Latency
+			measurements++
+		}
+	}
+	if measurements == 0 {
+		return 1
+	}
+	latency := total / time.Duration(measurements)
+
+	base := float64(1)
+	if latency > 500*time.Millisecond {
+		base = base / 2 // 2x total penalty
+	}
+	if latency > time.Second {
+		base = base / 2 // 4x total penalty
+	}
+	if latency > 2*time.Second {
+		base = base / 2 // 8x total penalty
+	}
+	if latency > 5*time.Second {
+		base = base / 2 // 16x total penalty
+	}
+	return base
+}
+
 // priceAdjustments will adjust the weight of the entry according to the prices
 // that it has set.
 func (hdb *HostDB) priceAdjustments(entry modules.HostDBEntry) float64 {
@@ -345,6 +379,7 @@ func (hdb *HostDB) uptimeAdjustments(entry modules.HostDBEntry) float64 {
 // the host database entry.
 func (hdb *HostDB) calculateHostWeight(entry modules.HostDBEntry) types.Currency {
 	collateralReward := hdb.collateralAdjustments(entry)
+	latencyPenalty := latencyAdjustments(entry)
 	pricePenalty := hdb.priceAdjustments(entry)
 	storageRemainingPenalty := storageRemainingAdjustments(entry)
 	versionPenalty := versionAdjustments(entry)
@@ -352,7 +387,7 @@ func (hdb *HostDB) calculateHostWeight(entry modules.HostDBEntry) types.Currency
 	uptimePenalty := hdb.uptimeAdjustments(entry)
 
 	// Combine the adjustments.
-	fullPenalty := collateralReward * pricePenalty * storageRemainingPenalty * versionPenalty * lifetimePenalty * uptimePenalty
+	fullPenalty := collateralReward * latencyPenalty * pricePenalty * storageRemainingPenalty * versionPenalty * lifetimePenalty * uptimePenalty
 
 	// Return a types.Currency.
 	weight := baseWeight.MulFloat(fullPenalty)
@@ -382,7 +417,8 @@ func (hdb *HostDB) calculateConversionRate(score types.Currency) float64 {
 }
 
 // EstimateHostScore takes a HostExternalSettings and returns the estimated
-// score of that host in the hostdb, assuming no penalties for age or uptime.
+// score of that host in the hostdb, assuming no penalties for age, latency or
+// uptime.
 func (hdb *HostDB) EstimateHostScore(entry modules.HostDBEntry) modules.HostScoreBreakdown {
 	collateralReward := hdb.collateralAdjustments(entry)
 	pricePenalty := hdb.priceAdjustments(entry)
@@ -402,6 +438,7 @@ func (hdb *HostDB) EstimateHostScore(entry modules.HostDBEntry) modules.HostScor
 		AgeAdjustment:              1,
 		BurnAdjustment:             1,
 		CollateralAdjustment:       collateralReward,
+		LatencyAdjustment:          1,
 		PriceAdjustment:            pricePenalty,
 		StorageRemainingAdjustment: storageRemainingPenalty,
 		UptimeAdjustment:           1,
@@ -420,6 +457,7 @@ func (hdb *HostDB) ScoreBreakdown(entry modules.HostDBEntry) modules.HostScoreBr
 		AgeAdjustment:              hdb.lifetimeAdjustments(entry),
 		BurnAdjustment:             1,
 		CollateralAdjustment:       hdb.collateralAdjustments(entry),
+		LatencyAdjustment:          latencyAdjustments(entry),
 		PriceAdjustment:            hdb.priceAdjustments(entry),
 		StorageRemainingAdjustment: storageRemainingAdjustments(entry),
 		UptimeAdjustment:           hdb.uptimeAdjustments(entry),
@@ -284,3 +284,41 @@ func TestHostWeightUptimeDifferences4(t *testing.T) {
 		t.Error("Been around longer should have more weight")
 	}
 }
+
+func TestHostWeightLatencyDifferences(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	hdb := bareHostDB()
+	hdb.blockHeight = 10000
+	var entry modules.HostDBEntry
+	entry.RemainingStorage = 250e3
+	entry.StoragePrice = types.NewCurrency64(1000).Mul(types.SiacoinPrecision)
+	entry.Collateral = types.NewCurrency64(1000).Mul(types.SiacoinPrecision)
+	entry.Version = "v1.0.4"
+	entry.ScanHistory = modules.HostDBScans{
+		{Timestamp: time.Now().Add(time.Hour * -100), Success: true},
+		{Timestamp: time.Now().Add(time.Hour * -80), Success: true, Latency: 100 * time.Millisecond},
+		{Timestamp: time.Now().Add(time.Hour * -60), Success: true, Latency: 200 * time.Millisecond},
+		{Timestamp: time.Now().Add(time.Hour * -40), Success: false},
+	}
+
+	// Failed scans and scans without a measurement should not count towards
+	// the latency.
+	if adj := latencyAdjustments(entry); adj != 1 {
+		t.Error("fast host should not be penalized:", adj)
+	}
+	entry2 := entry
+	entry2.ScanHistory = append(modules.HostDBScans(nil), entry.ScanHistory...)
+	entry2.ScanHistory[1].Latency = 3 * time.Second
+	entry2.ScanHistory[2].Latency = 2 * time.Second
+	if adj := latencyAdjustments(entry2); adj != 0.125 {
+		t.Error("slow host has the wrong adjustment:", adj)
+	}
+
+	w1 := hdb.calculateHostWeight(entry)
+	w2 := hdb.calculateHostWeight(entry2)
+	if w1.Cmp(w2) <= 0 {
+		t.Error("Host with lower latency should have more weight")
+	}
+}
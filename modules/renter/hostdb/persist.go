@@ -26,6 +26,7 @@ var (
 type hdbPersist struct {
 	AllHosts    []modules.HostDBEntry
 	BlockHeight types.BlockHeight
+	Filter      modules.HostFilter
 	LastChange  modules.ConsensusChangeID
 }
 
@@ -33,6 +34,7 @@ type hdbPersist struct {
 func (hdb *HostDB) persistData() (data hdbPersist) {
 	data.AllHosts = hdb.hostTree.All()
 	data.BlockHeight = hdb.blockHeight
+	data.Filter = hdb.filter
 	data.LastChange = hdb.lastChange
 	return data
 }
@@ -54,6 +56,15 @@ func (hdb *HostDB) load() error {
 	// Set the hostdb internal values.
 	hdb.blockHeight = data.BlockHeight
 	hdb.lastChange = data.LastChange
+	if data.Filter.Mode != "" {
+		hf, err := newHostFilter(data.Filter)
+		if err != nil {
+			hdb.log.Println("WARN: could not load the host filter:", err)
+		} else {
+			hdb.filter = data.Filter
+			hdb.hostFilter = hf
+		}
+	}
 
 	// Load each of the hosts into the host tree.
 	for _, host := range data.AllHosts {
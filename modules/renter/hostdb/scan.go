@@ -90,7 +90,11 @@ func (hdb *HostDB) queueScan(entry modules.HostDBEntry) {
 // to give that host some base uptime. This makes this function co-dependent
 // with the host weight functions. Adjustment of the host weight functions need
 // to keep this function in mind, and vice-versa.
-func (hdb *HostDB) updateEntry(entry modules.HostDBEntry, netErr error) {
+//
+// measured holds the latency and round-trip time measured by the scan, which
+// are ignored if the scan failed. The subnet of entry, if set, is the subnet
+// that the host was reached at.
+func (hdb *HostDB) updateEntry(entry modules.HostDBEntry, measured modules.HostDBScan, netErr error) {
 	// If the scan failed because we don't have Internet access, toss out this update.
 	if netErr != nil && !hdb.online {
 		return
@@ -104,6 +108,14 @@ func (hdb *HostDB) updateEntry(entry modules.HostDBEntry, netErr error) {
 		newEntry = entry
 	}
 
+	if entry.Subnet != "" {
+		newEntry.Subnet = entry.Subnet
+	}
+	latency, rtt := measured.Latency, measured.RTT
+	if netErr != nil {
+		latency, rtt = 0, 0
+	}
+
 	// Add the datapoints for the scan.
 	if len(newEntry.ScanHistory) < 2 {
 		// Add two scans to the scan history. Two are needed because the scans
@@ -116,7 +128,7 @@ func (hdb *HostDB) updateEntry(entry modules.HostDBEntry, netErr error) {
 		}
 		newEntry.ScanHistory = modules.HostDBScans{
 			{Timestamp: suggestedStartTime, Success: netErr == nil},
-			{Timestamp: time.Now(), Success: netErr == nil},
+			{Timestamp: time.Now(), Success: netErr == nil, Latency: latency, RTT: rtt},
 		}
 	} else {
 		if newEntry.ScanHistory[len(newEntry.ScanHistory)-1].Success && netErr != nil {
@@ -135,7 +147,7 @@ func (hdb *HostDB) updateEntry(entry modules.HostDBEntry, netErr error) {
 		// Before appending, make sure that the scan we just performed is
 		// timestamped after the previous scan performed. It may not be if the
 		// system clock has changed.
-		newEntry.ScanHistory = append(newEntry.ScanHistory, modules.HostDBScan{Timestamp: newTimestamp, Success: netErr == nil})
+		newEntry.ScanHistory = append(newEntry.ScanHistory, modules.HostDBScan{Timestamp: newTimestamp, Success: netErr == nil, Latency: latency, RTT: rtt})
 	}
 
 	// Check whether any of the recent scans demonstrate uptime. The pruning and
@@ -200,7 +212,9 @@ func (hdb *HostDB) managedScanHost(entry modules.HostDBEntry) {
 	hdb.log.Debugf("Scanning host %v at %v", pubKey, netAddr)
 
 	var settings modules.HostExternalSettings
+	var measured modules.HostDBScan
 	err := func() error {
+		start := time.Now()
 		dialer := &net.Dialer{
 			Cancel:  hdb.tg.StopChan(),
 			Timeout: hostRequestTimeout,
@@ -209,6 +223,13 @@ func (hdb *HostDB) managedScanHost(entry modules.HostDBEntry) {
 		if err != nil {
 			return err
 		}
+		// Opening the connection takes one round trip to the host. The
+		// subnet is taken from the address that was dialed, so that hosts
+		// announced with a domain name are placed in a subnet as well.
+		measured.RTT = time.Since(start)
+		if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+			entry.Subnet = modules.Subnet(addr.IP)
+		}
 		connCloseChan := make(chan struct{})
 		go func() {
 			select {
@@ -226,7 +247,12 @@ func (hdb *HostDB) managedScanHost(entry modules.HostDBEntry) {
 		}
 		var pubkey crypto.PublicKey
 		copy(pubkey[:], pubKey.Key)
-		return crypto.ReadSignedObject(conn, &settings, maxSettingsLen, pubkey)
+		err = crypto.ReadSignedObject(conn, &settings, maxSettingsLen, pubkey)
+		if err != nil {
+			return err
+		}
+		measured.Latency = time.Since(start)
+		return nil
 	}()
 	if err != nil {
 		hdb.log.Debugf("Scan of host at %v failed: %v", netAddr, err)
@@ -238,7 +264,7 @@ func (hdb *HostDB) managedScanHost(entry modules.HostDBEntry) {
 	// Update the host tree to have a new entry, including the new error. Then
 	// delete the entry from the scan map as the scan has been successful.
 	hdb.mu.Lock()
-	hdb.updateEntry(entry, err)
+	hdb.updateEntry(entry, measured, err)
 	hdb.mu.Unlock()
 }
 
@@ -36,7 +36,7 @@ func TestUpdateEntry(t *testing.T) {
 
 	// Try inserting the first entry. Result in the host tree should be a host
 	// with a scan history length of two.
-	hdbt.hdb.updateEntry(entry1, nil)
+	hdbt.hdb.updateEntry(entry1, modules.HostDBScan{}, nil)
 	updatedEntry, exists := hdbt.hdb.hostTree.Select(entry1.PublicKey)
 	if !exists {
 		t.Fatal("Entry did not get inserted into the host tree")
@@ -53,7 +53,7 @@ func TestUpdateEntry(t *testing.T) {
 
 	// Try inserting the second entry, but with an error. Results should largely
 	// be the same.
-	hdbt.hdb.updateEntry(entry2, someErr)
+	hdbt.hdb.updateEntry(entry2, modules.HostDBScan{}, someErr)
 	updatedEntry, exists = hdbt.hdb.hostTree.Select(entry2.PublicKey)
 	if !exists {
 		t.Fatal("Entry did not get inserted into the host tree")
@@ -70,8 +70,8 @@ func TestUpdateEntry(t *testing.T) {
 
 	// Insert the first entry twice more, with no error. There should be 4
 	// entries, and the timestamps should be strictly increasing.
-	hdbt.hdb.updateEntry(entry1, nil)
-	hdbt.hdb.updateEntry(entry1, nil)
+	hdbt.hdb.updateEntry(entry1, modules.HostDBScan{}, nil)
+	hdbt.hdb.updateEntry(entry1, modules.HostDBScan{}, nil)
 	updatedEntry, exists = hdbt.hdb.hostTree.Select(entry1.PublicKey)
 	if !exists {
 		t.Fatal("Entry did not get inserted into the host tree")
@@ -90,7 +90,7 @@ func TestUpdateEntry(t *testing.T) {
 	}
 
 	// Add a non-successful scan and verify that it is registered properly.
-	hdbt.hdb.updateEntry(entry1, someErr)
+	hdbt.hdb.updateEntry(entry1, modules.HostDBScan{}, someErr)
 	updatedEntry, exists = hdbt.hdb.hostTree.Select(entry1.PublicKey)
 	if !exists {
 		t.Fatal("Entry did not get inserted into the host tree")
@@ -122,7 +122,7 @@ func TestUpdateEntry(t *testing.T) {
 	// Add enough entries to get to minScans total length. When that length is
 	// reached, the entry should be deleted.
 	for i := len(updatedEntry.ScanHistory); i < minScans; i++ {
-		hdbt.hdb.updateEntry(entry2, someErr)
+		hdbt.hdb.updateEntry(entry2, modules.HostDBScan{}, someErr)
 	}
 	// The entry should no longer exist in the hostdb, wiped for being offline.
 	updatedEntry, exists = hdbt.hdb.hostTree.Select(entry2.PublicKey)
@@ -142,7 +142,7 @@ func TestUpdateEntry(t *testing.T) {
 		t.Fatal(err)
 	}
 	for i := len(updatedEntry.ScanHistory); i <= minScans; i++ {
-		hdbt.hdb.updateEntry(entry1, someErr)
+		hdbt.hdb.updateEntry(entry1, modules.HostDBScan{}, someErr)
 	}
 	// The result should be compression, and not the entry getting deleted.
 	updatedEntry, exists = hdbt.hdb.hostTree.Select(entry1.PublicKey)
@@ -169,7 +169,7 @@ func TestUpdateEntry(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	hdbt.hdb.updateEntry(entry1, someErr)
+	hdbt.hdb.updateEntry(entry1, modules.HostDBScan{}, someErr)
 	// The result should be compression, and not the entry getting deleted.
 	updatedEntry, exists = hdbt.hdb.hostTree.Select(entry1.PublicKey)
 	if !exists {
@@ -182,3 +182,36 @@ func TestUpdateEntry(t *testing.T) {
 		t.Error("host not reporting historic uptime?")
 	}
 }
+
+// TestUpdateEntryMeasurements checks that updateEntry records the round-trip
+// time and subnet measured by successful scans only.
+func TestUpdateEntryMeasurements(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	hdbt, err := newHDBTesterDeps(t.Name(), disableScanLoopDeps{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry := modules.HostDBEntry{
+		PublicKey: types.SiaPublicKey{Key: []byte{1}},
+		Subnet:    "1.2.3.0/24",
+	}
+	hdbt.hdb.updateEntry(entry, modules.HostDBScan{Latency: 30 * time.Millisecond, RTT: 10 * time.Millisecond}, nil)
+	hdbt.hdb.updateEntry(entry, modules.HostDBScan{Latency: 60 * time.Millisecond, RTT: 20 * time.Millisecond}, nil)
+	hdbt.hdb.updateEntry(entry, modules.HostDBScan{RTT: 90 * time.Millisecond}, errors.New("testing err"))
+	updatedEntry, exists := hdbt.hdb.hostTree.Select(entry.PublicKey)
+	if !exists {
+		t.Fatal("Entry did not get inserted into the host tree")
+	}
+	if updatedEntry.Subnet != entry.Subnet {
+		t.Error("subnet was not recorded:", updatedEntry.Subnet)
+	}
+	if rtt := updatedEntry.ScanHistory[len(updatedEntry.ScanHistory)-1].RTT; rtt != 0 {
+		t.Error("failed scan recorded a round-trip time:", rtt)
+	}
+	if rtt := updatedEntry.AverageRTT(); rtt != 15*time.Millisecond {
+		t.Error("wrong average round-trip time:", rtt)
+	}
+}
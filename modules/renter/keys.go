@@ -0,0 +1,81 @@
+package renter
+
+// keys.go derives the keys that uploaded files are encrypted with. Every file
+// has its own key, derived from the renter's master key and the siapath that
+// the file was uploaded to. The master key is in turn derived from the
+// primary seed of the wallet, which means that the keys of all uploaded files
+// can be recovered from the seed. Hosts only ever receive encrypted pieces.
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	// fileKeySpecifier and masterKeySpecifier separate the derived keys from
+	// any other hashes of the same data.
+	fileKeySpecifier   = types.Specifier{'f', 'i', 'l', 'e', ' ', 'k', 'e', 'y'}
+	masterKeySpecifier = types.Specifier{'m', 'a', 's', 't', 'e', 'r', ' ', 'k', 'e', 'y'}
+)
+
+// deriveMasterKey derives the renter's master key from the primary seed of
+// the wallet. The seed cannot be recovered from the master key.
+func deriveMasterKey(seed modules.Seed) crypto.TwofishKey {
+	return crypto.TwofishKey(crypto.HashAll(masterKeySpecifier, seed))
+}
+
+// deriveFileKey derives the key of the file uploaded to siapath. The key is
+// stored with the file, so renaming the file does not change it. Uploading
+// new data to the same siapath reuses the key, which is safe because every
+// piece is encrypted with a random nonce.
+func deriveFileKey(masterKey crypto.TwofishKey, siapath string) crypto.TwofishKey {
+	return crypto.TwofishKey(crypto.HashAll(fileKeySpecifier, masterKey, siapath))
+}
+
+// managedMasterKey returns the renter's master key. The first time it is
+// called, the key is derived from the wallet seed, which requires the wallet
+// to be unlocked.
+func (r *Renter) managedMasterKey() (crypto.TwofishKey, error) {
+	lockID := r.mu.RLock()
+	masterKey := r.masterKey
+	r.mu.RUnlock(lockID)
+	if masterKey != (crypto.TwofishKey{}) {
+		return masterKey, nil
+	}
+
+	seed, _, err := r.wallet.PrimarySeed()
+	if err != nil {
+		return crypto.TwofishKey{}, errors.New("could not derive the renter's master key from the wallet seed: " + err.Error())
+	}
+	masterKey = deriveMasterKey(seed)
+
+	lockID = r.mu.Lock()
+	defer r.mu.Unlock(lockID)
+	r.masterKey = masterKey
+	return masterKey, r.saveSync()
+}
+
+// ExportKeys returns the renter's master key and the key of every file, so
+// that they can be stored somewhere safe. Files that were uploaded before
+// the master key existed, or that were loaded from a .sia file, have random
+// keys that cannot be derived from the master key.
+func (r *Renter) ExportKeys() (modules.RenterKeys, error) {
+	masterKey, err := r.managedMasterKey()
+	if err != nil {
+		return modules.RenterKeys{}, err
+	}
+
+	lockID := r.mu.RLock()
+	defer r.mu.RUnlock(lockID)
+	fileKeys := make(map[string]crypto.TwofishKey, len(r.files))
+	for siapath, f := range r.files {
+		fileKeys[siapath] = f.masterKey
+	}
+	return modules.RenterKeys{
+		MasterKey: masterKey,
+		FileKeys:  fileKeys,
+	}, nil
+}
@@ -0,0 +1,88 @@
+package renter
+
+import (
+	"os"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/crypto"
+)
+
+// TestDeriveFileKey checks that file keys depend on both the master key and
+// the siapath.
+func TestDeriveFileKey(t *testing.T) {
+	mk1 := crypto.GenerateTwofishKey()
+	mk2 := crypto.GenerateTwofishKey()
+	if deriveFileKey(mk1, "foo") != deriveFileKey(mk1, "foo") {
+		t.Fatal("file key derivation is not deterministic")
+	}
+	if deriveFileKey(mk1, "foo") == deriveFileKey(mk1, "bar") {
+		t.Fatal("different siapaths have the same key")
+	}
+	if deriveFileKey(mk1, "foo") == deriveFileKey(mk2, "foo") {
+		t.Fatal("different master keys give the same file key")
+	}
+	if deriveFileKey(mk1, "foo") == mk1 {
+		t.Fatal("file key is the master key")
+	}
+}
+
+// TestExportKeys checks that the master key is derived from the wallet seed,
+// persisted, and exported along with the keys of the files.
+func TestExportKeys(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+
+	f := newTestingFile()
+	id := rt.renter.mu.Lock()
+	rt.renter.files[f.name] = f
+	rt.renter.mu.Unlock(id)
+
+	keys, err := rt.renter.ExportKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	seed, _, err := rt.wallet.PrimarySeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys.MasterKey != deriveMasterKey(seed) {
+		t.Fatal("master key was not derived from the wallet seed")
+	}
+	if len(keys.FileKeys) != 1 || keys.FileKeys[f.name] != f.masterKey {
+		t.Fatal("file keys were not exported:", keys.FileKeys)
+	}
+
+	// The master key should survive a reload, and be available while the
+	// wallet is locked.
+	id = rt.renter.mu.Lock()
+	rt.renter.masterKey = crypto.TwofishKey{}
+	err = rt.renter.load()
+	rt.renter.mu.Unlock(id)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	if err := rt.wallet.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	keys2, err := rt.renter.ExportKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys2.MasterKey != keys.MasterKey {
+		t.Fatal("master key was not persisted")
+	}
+
+	// Without a master key, a locked wallet cannot provide one.
+	id = rt.renter.mu.Lock()
+	rt.renter.masterKey = crypto.TwofishKey{}
+	rt.renter.mu.Unlock(id)
+	if _, err := rt.renter.ExportKeys(); err == nil {
+		t.Fatal("master key was derived while the wallet was locked")
+	}
+}
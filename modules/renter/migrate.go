@@ -0,0 +1,106 @@
+package renter
+
+// migrate.go moves data away from failing hosts. The contractor considers a
+// host failing when it is offline, when its recent scans have all failed, or
+// when its score has collapsed. The pieces stored on a failing host are
+// treated as lost by the repair loop, which uploads them again to other hosts,
+// and the contract with the host is released once all of the pieces of the
+// tracked files have been moved. The contractor replaces failing contracts as
+// soon as they start failing, so that the pieces have somewhere to go.
+
+import (
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// unavailabilityCheck returns a function that reports whether the pieces
+// stored in a contract should be treated as lost, either because the host of
+// the contract is offline or because the contract is in the failing set.
+func (r *Renter) unavailabilityCheck(failing map[types.FileContractID]struct{}) func(types.FileContractID) bool {
+	return func(id types.FileContractID) bool {
+		if _, ok := failing[r.hostContractor.ResolveID(id)]; ok {
+			return true
+		}
+		return r.hostContractor.IsOffline(id)
+	}
+}
+
+// failingContracts returns the set of contracts whose hosts are failing.
+func (r *Renter) failingContracts() map[types.FileContractID]struct{} {
+	failing := make(map[types.FileContractID]struct{})
+	for _, id := range r.hostContractor.FailingContracts() {
+		failing[id] = struct{}{}
+	}
+	return failing
+}
+
+// storedOn indicates whether the file has pieces in any of the specified
+// contracts.
+func (f *file) storedOn(contracts map[types.FileContractID]struct{}) bool {
+	for id, fc := range f.contracts {
+		if _, ok := contracts[id]; ok && len(fc.Pieces) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// migrated indicates whether every piece that the file stores in the contract
+// with the specified ID is also stored in a contract that is available.
+func (f *file) migrated(id types.FileContractID, isUnavailable func(types.FileContractID) bool) bool {
+	type pieceID struct{ chunk, piece uint64 }
+	stored := make(map[pieceID]struct{})
+	for otherID, fc := range f.contracts {
+		if otherID == id || isUnavailable(otherID) {
+			continue
+		}
+		for _, p := range fc.Pieces {
+			stored[pieceID{p.Chunk, p.Piece}] = struct{}{}
+		}
+	}
+	for _, p := range f.contracts[id].Pieces {
+		if _, ok := stored[pieceID{p.Chunk, p.Piece}]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// managedMigrateFailingContracts releases the failing contracts whose pieces
+// have all been uploaded to other hosts. Untracked files are never repaired,
+// so their pieces do not hold up the release of a contract.
+func (r *Renter) managedMigrateFailingContracts() {
+	failing := r.failingContracts()
+	if len(failing) == 0 {
+		return
+	}
+	isUnavailable := r.unavailabilityCheck(failing)
+
+	var released []types.FileContractID
+	id := r.mu.RLock()
+	for fcid := range failing {
+		migrated := true
+		for name, f := range r.files {
+			if _, tracked := r.tracking[name]; !tracked {
+				continue
+			}
+			f.mu.RLock()
+			migrated = f.migrated(fcid, isUnavailable)
+			f.mu.RUnlock()
+			if !migrated {
+				break
+			}
+		}
+		if migrated {
+			released = append(released, fcid)
+		}
+	}
+	r.mu.RUnlock(id)
+
+	for _, fcid := range released {
+		if err := r.hostContractor.ReleaseContract(fcid); err != nil {
+			r.log.Println("WARN: could not release failing contract:", err)
+			continue
+		}
+		r.log.Println("Released failing contract", fcid)
+	}
+}
@@ -0,0 +1,75 @@
+package renter
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// failingContractor is a hostContractor that reports a fixed set of failing
+// contracts and records the contracts that are released.
+type failingContractor struct {
+	hostContractor
+	failing  []types.FileContractID
+	released []types.FileContractID
+}
+
+func (c *failingContractor) FailingContracts() []types.FileContractID { return c.failing }
+func (c *failingContractor) IsOffline(types.FileContractID) bool      { return false }
+func (c *failingContractor) ResolveID(id types.FileContractID) types.FileContractID {
+	return id
+}
+func (c *failingContractor) ReleaseContract(id types.FileContractID) error {
+	c.released = append(c.released, id)
+	return nil
+}
+
+// TestMigrateFailingContracts checks that the pieces on failing contracts are
+// treated as lost, and that failing contracts are only released once their
+// pieces are stored on other hosts.
+func TestMigrateFailingContracts(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+	r := rt.renter
+
+	// Contract 1 is healthy. Contract 2 is failing, and its piece is also
+	// stored in contract 1. Contract 3 is failing, and its piece is stored
+	// nowhere else.
+	hc := &failingContractor{
+		hostContractor: r.hostContractor,
+		failing:        []types.FileContractID{{2}, {3}},
+	}
+	r.hostContractor = hc
+	rsc, _ := NewRSCode(1, 2)
+	f := newFile("foo", rsc, 100, 100)
+	f.contracts[types.FileContractID{1}] = fileContract{ID: types.FileContractID{1}, Pieces: []pieceData{{Chunk: 0, Piece: 0}}}
+	f.contracts[types.FileContractID{2}] = fileContract{ID: types.FileContractID{2}, Pieces: []pieceData{{Chunk: 0, Piece: 0}}}
+	f.contracts[types.FileContractID{3}] = fileContract{ID: types.FileContractID{3}, Pieces: []pieceData{{Chunk: 0, Piece: 1}}}
+	lockID := r.mu.Lock()
+	r.files["foo"] = f
+	r.tracking["foo"] = trackedFile{RepairPath: "foo"}
+	r.mu.Unlock(lockID)
+
+	failing := r.failingContracts()
+	if !f.storedOn(failing) {
+		t.Fatal("file is not stored on its failing contracts")
+	}
+	isUnavailable := r.unavailabilityCheck(failing)
+	if f.redundancy(isUnavailable) != 1 {
+		t.Fatal("pieces on failing contracts were not treated as lost:", f.redundancy(isUnavailable))
+	}
+	if !f.migrated(types.FileContractID{2}, isUnavailable) || f.migrated(types.FileContractID{3}, isUnavailable) {
+		t.Fatal("wrong pieces were considered migrated")
+	}
+
+	r.managedMigrateFailingContracts()
+	if len(hc.released) != 1 || hc.released[0] != (types.FileContractID{2}) {
+		t.Fatal("expected only contract 2 to be released, got", hc.released)
+	}
+}
@@ -0,0 +1,61 @@
+package renter
+
+// pause.go lets the user suspend all background activity of the renter, for
+// example while on a metered connection. While paused, the renter does not
+// upload or repair chunks, create snapshots or release failing contracts,
+// and the contractor does not form or renew contracts. Files keep being
+// scanned for repairs, so that the renter can pick up where it left off once
+// it is resumed. Downloads are not affected.
+
+import (
+	"errors"
+)
+
+var (
+	errRenterPaused    = errors.New("renter is already paused")
+	errRenterNotPaused = errors.New("renter is not paused")
+	errStreamPaused    = errors.New("streaming uploads cannot be started while the renter is paused")
+)
+
+// Paused reports whether the background activity of the renter is paused.
+func (r *Renter) Paused() bool {
+	lockID := r.mu.RLock()
+	defer r.mu.RUnlock(lockID)
+	return r.paused
+}
+
+// Pause stops all background activity of the renter until Resume is called.
+// Pieces that are being uploaded when the renter is paused are still
+// completed. The paused state is persisted.
+func (r *Renter) Pause() error {
+	return r.managedSetPaused(true)
+}
+
+// Resume resumes the background activity of a renter paused by Pause.
+// Contracts are formed and renewed again after the next block.
+func (r *Renter) Resume() error {
+	if err := r.managedSetPaused(false); err != nil {
+		return err
+	}
+
+	// Wake up the repair loop, which waits for new work while the renter is
+	// paused.
+	go r.threadedQueueRepairsNow()
+	return nil
+}
+
+// managedSetPaused pauses or resumes the renter and its contractor.
+func (r *Renter) managedSetPaused(paused bool) error {
+	lockID := r.mu.Lock()
+	defer r.mu.Unlock(lockID)
+	if paused && r.paused {
+		return errRenterPaused
+	} else if !paused && !r.paused {
+		return errRenterNotPaused
+	}
+	if err := r.hostContractor.SetPaused(paused); err != nil {
+		return err
+	}
+	r.paused = paused
+	return r.saveSync()
+}
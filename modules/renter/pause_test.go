@@ -0,0 +1,87 @@
+package renter
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/renter/contractor"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestPause checks that no chunks are worked on while the renter is paused,
+// that its contractor is paused with it, and that the paused state is
+// persisted.
+func TestPause(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+	r := rt.renter
+
+	rs := &repairState{
+		activeWorkers:     make(map[types.FileContractID]*worker),
+		availableWorkers:  make(map[types.FileContractID]*worker),
+		gapCounts:         make(map[int]int),
+		incompleteChunks:  make(map[chunkID]*chunkStatus),
+		cachedChunks:      make(map[chunkID][]byte),
+		downloadingChunks: make(map[chunkID]struct{}),
+	}
+	rsc, _ := NewRSCode(1, 1)
+	f := newFile("foo", rsc, 100, 100)
+	lockID := r.mu.Lock()
+	r.files["foo"] = f
+	r.tracking["foo"] = trackedFile{}
+	r.addFileToRepairState(rs, f)
+	r.mu.Unlock(lockID)
+	if len(r.managedPrioritizedChunks(rs)) != 1 {
+		t.Fatal("chunk of the file is not being repaired")
+	}
+
+	if err := r.Pause(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Pause(); err != errRenterPaused {
+		t.Fatal("expected errRenterPaused, got", err)
+	}
+	if !r.Paused() || !r.hostContractor.(*contractor.Contractor).Paused() {
+		t.Fatal("renter or contractor was not paused")
+	}
+	if chunks := r.managedPrioritizedChunks(rs); len(chunks) != 0 {
+		t.Fatal("chunks are repaired while the renter is paused:", chunks)
+	}
+	err = r.UploadStreamFromReader(modules.FileUploadParams{SiaPath: "bar"}, bytes.NewReader(nil))
+	if err != errStreamPaused {
+		t.Fatal("expected errStreamPaused, got", err)
+	}
+
+	// The paused state should survive a reload.
+	lockID = r.mu.Lock()
+	r.paused = false
+	err = r.load()
+	r.mu.Unlock(lockID)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	if !r.Paused() {
+		t.Fatal("paused state was not persisted")
+	}
+
+	if err := r.Resume(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Resume(); err != errRenterNotPaused {
+		t.Fatal("expected errRenterNotPaused, got", err)
+	}
+	if r.Paused() || r.hostContractor.(*contractor.Contractor).Paused() {
+		t.Fatal("renter or contractor was not resumed")
+	}
+	if len(r.managedPrioritizedChunks(rs)) != 1 {
+		t.Fatal("chunk of the file is not repaired after resuming")
+	}
+}
@@ -8,9 +8,11 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 
 	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/persist"
@@ -21,6 +23,15 @@ const (
 	PersistFilename = "renter.json"
 	ShareExtension  = ".sia"
 	logFile         = modules.RenterDir + ".log"
+
+	// downloadCacheDir is the directory within the persist directory that
+	// holds the download cache.
+	downloadCacheDir = "downloadcache"
+
+	// conversionsDir is the directory within the persist directory that
+	// holds the .sia files of the conversions of files to the layouts of
+	// their redundancy classes.
+	conversionsDir = "conversions"
 )
 
 var (
@@ -152,17 +163,21 @@ func (f *file) UnmarshalSia(r io.Reader) error {
 	return nil
 }
 
-// saveFile saves a file to the renter directory.
+// saveFile saves a file to the renter directory. The conversion of a file is
+// saved to the conversions directory. The caller must hold the renter lock.
 func (r *Renter) saveFile(f *file) error {
 	// Create directory structure specified in nickname.
 	fullPath := filepath.Join(r.persistDir, f.name+ShareExtension)
+	if r.conversions[f.name] == f {
+		fullPath = r.conversionPath(f.name)
+	}
 	err := os.MkdirAll(filepath.Dir(fullPath), 0700)
 	if err != nil {
 		return err
 	}
 
 	// Open SafeFile handle.
-	handle, err := persist.NewSafeFile(filepath.Join(r.persistDir, f.name+ShareExtension))
+	handle, err := persist.NewSafeFile(fullPath)
 	if err != nil {
 		return err
 	}
@@ -180,11 +195,26 @@ func (r *Renter) saveFile(f *file) error {
 
 // saveSync stores the current renter data to disk and then syncs to disk.
 func (r *Renter) saveSync() error {
+	dirs := make([]string, 0, len(r.dirs))
+	for dir := range r.dirs {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
 	data := struct {
-		Tracking map[string]trackedFile
-	}{r.tracking}
-
-	return persist.SaveJSON(saveMetadata, data, filepath.Join(r.persistDir, PersistFilename))
+		Tracking    map[string]trackedFile
+		Directories []string
+		MasterKey   crypto.TwofishKey
+		Snapshots   []snapshot
+		Downloads   []downloadRecord
+		Paused      bool
+		Classes     []modules.RedundancyClass
+	}{r.tracking, dirs, r.masterKey, r.snapshots, r.downloadRecords(), r.paused, r.redundancyClasses()}
+
+	err := persist.SaveJSON(saveMetadata, data, filepath.Join(r.persistDir, PersistFilename))
+	if err == nil {
+		r.unsavedDownloads = false
+	}
+	return err
 }
 
 // load fetches the saved renter data from disk.
@@ -199,8 +229,11 @@ func (r *Renter) load() error {
 			return nil
 		}
 
-		// Skip folders and non-sia files.
-		if info.IsDir() || filepath.Ext(path) != ShareExtension {
+		// Skip the conversions, which are loaded separately, along with
+		// folders and non-sia files.
+		if info.IsDir() && path == filepath.Join(r.persistDir, conversionsDir) {
+			return filepath.SkipDir
+		} else if info.IsDir() || filepath.Ext(path) != ShareExtension {
 			return nil
 		}
 
@@ -223,11 +256,20 @@ func (r *Renter) load() error {
 	if err != nil {
 		return err
 	}
+	if err := r.loadConversions(); err != nil {
+		return err
+	}
 
 	// Load contracts, repair set, and entropy.
 	data := struct {
-		Tracking  map[string]trackedFile
-		Repairing map[string]string // COMPATv0.4.8
+		Tracking    map[string]trackedFile
+		Directories []string
+		MasterKey   crypto.TwofishKey
+		Snapshots   []snapshot
+		Downloads   []downloadRecord
+		Paused      bool
+		Classes     []modules.RedundancyClass
+		Repairing   map[string]string // COMPATv0.4.8
 	}{}
 	err = persist.LoadJSON(saveMetadata, &data, filepath.Join(r.persistDir, PersistFilename))
 	if err != nil {
@@ -236,10 +278,59 @@ func (r *Renter) load() error {
 	if data.Tracking != nil {
 		r.tracking = data.Tracking
 	}
+	for _, dir := range data.Directories {
+		r.dirs[dir] = struct{}{}
+	}
+	r.masterKey = data.MasterKey
+	r.snapshots = data.Snapshots
+	r.paused = data.Paused
+	for _, rc := range data.Classes {
+		r.classes[rc.Name] = rc
+	}
+	for _, rec := range data.Downloads {
+		if r.downloadedSectors[rec.ContractID] == nil {
+			r.downloadedSectors[rec.ContractID] = make(map[string]uint64)
+		}
+		r.downloadedSectors[rec.ContractID][rec.SiaPath] += rec.Sectors
+	}
 
 	return nil
 }
 
+// loadConversions loads the conversions of files from the conversions
+// directory. Conversions of files that no longer exist are deleted.
+func (r *Renter) loadConversions() error {
+	dir := filepath.Join(r.persistDir, conversionsDir)
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if !os.IsNotExist(err) {
+				r.log.Println("WARN: could not stat file or folder during walk:", err)
+			}
+			return nil
+		} else if info.IsDir() || filepath.Ext(path) != ShareExtension {
+			return nil
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			r.log.Println("ERROR: could not open conversion .sia file:", err)
+			return nil
+		}
+		defer file.Close()
+		files, err := readSharedFiles(file)
+		if err != nil || len(files) != 1 {
+			r.log.Println("ERROR: could not load conversion .sia file:", err)
+			return nil
+		}
+		if _, exists := r.files[files[0].name]; !exists {
+			os.Remove(path)
+			return nil
+		}
+		r.conversions[files[0].name] = files[0]
+		return nil
+	})
+}
+
 // shareFiles writes the specified files to w. First a header is written,
 // followed by the gzipped concatenation of each file.
 func shareFiles(files []*file, w io.Writer) error {
@@ -268,11 +359,22 @@ func shareFiles(files []*file, w io.Writer) error {
 	return zip.Close()
 }
 
+// sharedFiles returns the files with the specified nicknames. The caller must
+// hold the renter lock.
+func (r *Renter) sharedFiles(nicknames []string) ([]*file, error) {
+	files := make([]*file, len(nicknames))
+	for i, name := range nicknames {
+		f, exists := r.files[name]
+		if !exists {
+			return nil, ErrUnknownPath
+		}
+		files[i] = f
+	}
+	return files, nil
+}
+
 // ShareFile saves the specified files to shareDest.
 func (r *Renter) ShareFiles(nicknames []string, shareDest string) error {
-	lockID := r.mu.RLock()
-	defer r.mu.RUnlock(lockID)
-
 	// TODO: consider just appending the proper extension.
 	if filepath.Ext(shareDest) != ShareExtension {
 		return ErrNonShareSuffix
@@ -284,17 +386,7 @@ func (r *Renter) ShareFiles(nicknames []string, shareDest string) error {
 	}
 	defer handle.Close()
 
-	// Load files from renter.
-	files := make([]*file, len(nicknames))
-	for i, name := range nicknames {
-		f, exists := r.files[name]
-		if !exists {
-			return ErrUnknownPath
-		}
-		files[i] = f
-	}
-
-	err = shareFiles(files, handle)
+	err = r.ShareFilesWriter(nicknames, handle)
 	if err != nil {
 		os.Remove(shareDest)
 		return err
@@ -305,31 +397,67 @@ func (r *Renter) ShareFiles(nicknames []string, shareDest string) error {
 
 // ShareFilesAscii returns the specified files in ASCII format.
 func (r *Renter) ShareFilesAscii(nicknames []string) (string, error) {
-	lockID := r.mu.RLock()
-	defer r.mu.RUnlock(lockID)
-
-	// Load files from renter.
-	files := make([]*file, len(nicknames))
-	for i, name := range nicknames {
-		f, exists := r.files[name]
-		if !exists {
-			return "", ErrUnknownPath
-		}
-		files[i] = f
-	}
-
 	buf := new(bytes.Buffer)
-	err := shareFiles(files, base64.NewEncoder(base64.URLEncoding, buf))
+	enc := base64.NewEncoder(base64.URLEncoding, buf)
+	err := r.ShareFilesWriter(nicknames, enc)
 	if err != nil {
 		return "", err
 	}
+	enc.Close()
 
 	return buf.String(), nil
 }
 
+// ShareFilesWriter writes the specified files to w in the .sia format.
+func (r *Renter) ShareFilesWriter(nicknames []string, w io.Writer) error {
+	lockID := r.mu.RLock()
+	defer r.mu.RUnlock(lockID)
+
+	files, err := r.sharedFiles(nicknames)
+	if err != nil {
+		return err
+	}
+	return shareFiles(files, w)
+}
+
 // loadSharedFiles reads .sia data from reader and registers the contained
 // files in the renter. It returns the nicknames of the loaded files.
 func (r *Renter) loadSharedFiles(reader io.Reader) ([]string, error) {
+	files, err := readSharedFiles(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure the names of the files do not conflict with existing files.
+	for _, f := range files {
+		dupCount := 0
+		origName := f.name
+		for {
+			_, exists := r.files[f.name]
+			if !exists {
+				break
+			}
+			dupCount++
+			f.name = origName + "_" + strconv.Itoa(dupCount)
+		}
+	}
+
+	// Add files to renter.
+	names := make([]string, len(files))
+	for i, f := range files {
+		r.files[f.name] = f
+		names[i] = f.name
+	}
+	// Save the files.
+	for _, f := range files {
+		r.saveFile(f)
+	}
+
+	return names, nil
+}
+
+// readSharedFiles reads the files written by shareFiles from reader.
+func readSharedFiles(reader io.Reader) ([]*file, error) {
 	// read header
 	var header [15]byte
 	var version string
@@ -362,32 +490,8 @@ func (r *Renter) loadSharedFiles(reader io.Reader) ([]string, error) {
 		if err != nil {
 			return nil, err
 		}
-
-		// Make sure the file's name does not conflict with existing files.
-		dupCount := 0
-		origName := files[i].name
-		for {
-			_, exists := r.files[files[i].name]
-			if !exists {
-				break
-			}
-			dupCount++
-			files[i].name = origName + "_" + strconv.Itoa(dupCount)
-		}
-	}
-
-	// Add files to renter.
-	names := make([]string, numFiles)
-	for i, f := range files {
-		r.files[f.name] = f
-		names[i] = f.name
-	}
-	// Save the files.
-	for _, f := range files {
-		r.saveFile(f)
 	}
-
-	return names, nil
+	return files, nil
 }
 
 // initPersist handles all of the persistence initialization, such as creating
@@ -405,6 +509,12 @@ func (r *Renter) initPersist() error {
 		return err
 	}
 
+	// Open the download cache.
+	r.downloadCache, err = newDownloadCache(filepath.Join(r.persistDir, downloadCacheDir), maxDownloadCacheSize)
+	if err != nil {
+		return err
+	}
+
 	// Load the prior persistence structures.
 	err = r.load()
 	if err != nil && !os.IsNotExist(err) {
@@ -416,23 +526,25 @@ func (r *Renter) initPersist() error {
 // LoadSharedFiles loads a .sia file into the renter. It returns the nicknames
 // of the loaded files.
 func (r *Renter) LoadSharedFiles(filename string) ([]string, error) {
-	lockID := r.mu.Lock()
-	defer r.mu.Unlock(lockID)
-
 	file, err := os.Open(filename)
 	if err != nil {
 		return nil, err
 	}
 	defer file.Close()
-	return r.loadSharedFiles(file)
+	return r.LoadSharedFilesReader(file)
 }
 
 // LoadSharedFilesAscii loads an ASCII-encoded .sia file into the renter. It
 // returns the nicknames of the loaded files.
 func (r *Renter) LoadSharedFilesAscii(asciiSia string) ([]string, error) {
+	dec := base64.NewDecoder(base64.URLEncoding, bytes.NewBufferString(asciiSia))
+	return r.LoadSharedFilesReader(dec)
+}
+
+// LoadSharedFilesReader loads .sia data from reader into the renter. It
+// returns the nicknames of the loaded files.
+func (r *Renter) LoadSharedFilesReader(reader io.Reader) ([]string, error) {
 	lockID := r.mu.Lock()
 	defer r.mu.Unlock(lockID)
-
-	dec := base64.NewDecoder(base64.URLEncoding, bytes.NewBufferString(asciiSia))
-	return r.loadSharedFiles(dec)
+	return r.loadSharedFiles(reader)
 }
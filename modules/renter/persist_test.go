@@ -178,6 +178,57 @@ func TestFileShareLoadASCII(t *testing.T) {
 	}
 }
 
+// TestFileShareLoadReader tests the io.Writer/io.Reader sharing/loading
+// functions.
+func TestFileShareLoadReader(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+
+	// Create a file and add it to the renter.
+	savedFile := newTestingFile()
+	id := rt.renter.mu.Lock()
+	rt.renter.files[savedFile.name] = savedFile
+	rt.renter.mu.Unlock(id)
+
+	buf := new(bytes.Buffer)
+	err = rt.renter.ShareFilesWriter([]string{savedFile.name}, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rt.renter.ShareFilesWriter([]string{"missing"}, new(bytes.Buffer)); err != ErrUnknownPath {
+		t.Fatal("expected ErrUnknownPath, got", err)
+	}
+
+	// Loading the file while it still exists should rename it.
+	names, err := rt.renter.LoadSharedFilesReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names[0] != savedFile.name+"_1" {
+		t.Fatal("nickname not loaded properly:", names)
+	}
+
+	// Remove the file from the renter and load it again.
+	delete(rt.renter.files, savedFile.name)
+	names, err = rt.renter.LoadSharedFilesReader(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names[0] != savedFile.name {
+		t.Fatal("nickname not loaded properly:", names)
+	}
+	err = equalFiles(rt.renter.files[savedFile.name], savedFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
 // TestRenterSaveLoad probes the save and load methods of the renter type.
 func TestRenterSaveLoad(t *testing.T) {
 	if testing.Short() {
@@ -0,0 +1,66 @@
+package renter
+
+// placement.go decides which hosts the pieces of a chunk are uploaded to and
+// downloaded from. Pieces are downloaded from the hosts with the lowest
+// round-trip time, and the pieces of a chunk are spread across subnets, so
+// that a single operator or hosting provider going offline does not take
+// several pieces of a chunk with it.
+
+import (
+	"sort"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// fasterWorker returns whether the host of a has a lower round-trip time than
+// the host of b. Hosts whose round-trip time has not been measured are slower
+// than the others.
+func fasterWorker(a, b *worker) bool {
+	if a.rtt == 0 || b.rtt == 0 {
+		return a.rtt != 0
+	}
+	return a.rtt < b.rtt
+}
+
+// sortWorkersByRTT sorts workers from the lowest to the highest round-trip
+// time to their hosts.
+func sortWorkersByRTT(workers []*worker) {
+	sort.SliceStable(workers, func(i, j int) bool {
+		return fasterWorker(workers[i], workers[j])
+	})
+}
+
+// orderUploadWorkers orders the workers that can upload the missing pieces of
+// a chunk. Workers whose hosts are in a subnet that holds no piece of the
+// chunk come first, each subnet once, followed by the remaining workers, so
+// that the pieces of the chunk are only placed in the same subnet when there
+// is no other choice. The workers are otherwise ordered by round-trip time.
+func orderUploadWorkers(rs *repairState, cs *chunkStatus, workerIDs []types.FileContractID) []types.FileContractID {
+	usedSubnets := make(map[string]struct{})
+	for id := range cs.contracts {
+		w, exists := rs.activeWorkers[id]
+		if !exists {
+			w, exists = rs.availableWorkers[id]
+		}
+		if exists && w.subnet != "" {
+			usedSubnets[w.subnet] = struct{}{}
+		}
+	}
+
+	workers := make([]*worker, 0, len(workerIDs))
+	for _, id := range workerIDs {
+		workers = append(workers, rs.availableWorkers[id])
+	}
+	sortWorkersByRTT(workers)
+
+	var diverse, duplicates []types.FileContractID
+	for _, w := range workers {
+		if _, used := usedSubnets[w.subnet]; used && w.subnet != "" {
+			duplicates = append(duplicates, w.contractID)
+			continue
+		}
+		usedSubnets[w.subnet] = struct{}{}
+		diverse = append(diverse, w.contractID)
+	}
+	return append(diverse, duplicates...)
+}
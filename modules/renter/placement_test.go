@@ -0,0 +1,48 @@
+package renter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestOrderUploadWorkers checks that the pieces of a chunk are uploaded to
+// hosts in subnets that hold no piece of the chunk first, starting with the
+// hosts with the lowest round-trip time.
+func TestOrderUploadWorkers(t *testing.T) {
+	newWorker := func(id byte, subnet string, rtt time.Duration) *worker {
+		return &worker{contractID: types.FileContractID{id}, subnet: subnet, rtt: rtt}
+	}
+	rs := &repairState{
+		activeWorkers: map[types.FileContractID]*worker{
+			{1}: newWorker(1, "1.0.0.0/24", time.Millisecond),
+		},
+		availableWorkers: make(map[types.FileContractID]*worker),
+	}
+	var ids []types.FileContractID
+	for _, w := range []*worker{
+		newWorker(2, "1.0.0.0/24", time.Millisecond),
+		newWorker(3, "2.0.0.0/24", 30*time.Millisecond),
+		newWorker(4, "2.0.0.0/24", 20*time.Millisecond),
+		newWorker(5, "3.0.0.0/24", 0),
+		newWorker(6, "", 40*time.Millisecond),
+		newWorker(7, "", 50*time.Millisecond),
+	} {
+		rs.availableWorkers[w.contractID] = w
+		ids = append(ids, w.contractID)
+	}
+
+	// The chunk has a piece on the host of worker 1.
+	cs := &chunkStatus{contracts: map[types.FileContractID]struct{}{{1}: {}}}
+	expected := []byte{4, 6, 7, 5, 2, 3}
+	ordered := orderUploadWorkers(rs, cs, ids)
+	if len(ordered) != len(expected) {
+		t.Fatal("wrong number of workers:", len(ordered))
+	}
+	for i, id := range ordered {
+		if id != (types.FileContractID{expected[i]}) {
+			t.Fatalf("wrong order of workers: expected %v at %v, got %v", expected[i], i, id[0])
+		}
+	}
+}
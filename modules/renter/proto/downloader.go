@@ -1,6 +1,7 @@
 package proto
 
 import (
+	"bytes"
 	"errors"
 	"net"
 	"sync"
@@ -10,6 +11,7 @@ import (
 	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
 )
 
 // A Downloader retrieves sectors by calling the download RPC on a host.
@@ -124,8 +126,9 @@ func (hd *Downloader) Close() error {
 }
 
 // NewDownloader initiates the download request loop with a host, and returns a
-// Downloader.
-func NewDownloader(host modules.HostDBEntry, contract modules.RenterContract, cancel <-chan struct{}) (*Downloader, error) {
+// Downloader. The bandwidth of the connection to the host is limited by rl,
+// which may be nil.
+func NewDownloader(host modules.HostDBEntry, contract modules.RenterContract, rl *RateLimiter, cancel <-chan struct{}) (*Downloader, error) {
 	// check that contract has enough value to support a download
 	if len(contract.LastRevision.NewValidProofOutputs) != 2 {
 		return nil, errors.New("invalid contract")
@@ -143,6 +146,7 @@ func NewDownloader(host modules.HostDBEntry, contract modules.RenterContract, ca
 	if err != nil {
 		return nil, err
 	}
+	conn = rl.Conn(conn)
 
 	closeChan := make(chan struct{})
 	go func() {
@@ -173,3 +177,63 @@ func NewDownloader(host modules.HostDBEntry, contract modules.RenterContract, ca
 		closeChan: closeChan,
 	}, nil
 }
+
+// LatestRevision returns the most recent revision of a contract that was
+// signed by both the renter and the host, in a transaction along with the
+// signatures. Only the ID of the contract and the renter's secret key are
+// needed, so that a renter which lost its contracts can recover them from
+// the hosts. The bandwidth of the connection to the host is limited by rl,
+// which may be nil.
+func LatestRevision(host modules.HostDBEntry, id types.FileContractID, sk crypto.SecretKey, rl *RateLimiter, cancel <-chan struct{}) (types.Transaction, error) {
+	conn, err := (&net.Dialer{
+		Cancel:  cancel,
+		Timeout: 15 * time.Second,
+	}).Dial("tcp", string(host.NetAddress))
+	if err != nil {
+		return types.Transaction{}, err
+	}
+	conn = rl.Conn(conn)
+	defer conn.Close()
+	closeChan := make(chan struct{})
+	defer close(closeChan)
+	go func() {
+		select {
+		case <-cancel:
+			conn.Close()
+		case <-closeChan:
+		}
+	}()
+
+	// allot 2 minutes for RPC request + revision exchange
+	extendDeadline(conn, modules.NegotiateRecentRevisionTime)
+	if err := encoding.WriteObject(conn, modules.RPCDownload); err != nil {
+		return types.Transaction{}, errors.New("couldn't initiate RPC: " + err.Error())
+	}
+	rev, sigs, err := readRecentRevision(conn, id, sk)
+	if err != nil {
+		return types.Transaction{}, err
+	}
+	if rev.ParentID != id {
+		return types.Transaction{}, errors.New("host sent the revision of another contract")
+	}
+	// The renter's public key is the first key of the unlock conditions.
+	pk := sk.PublicKey()
+	if len(rev.UnlockConditions.PublicKeys) != 2 || !bytes.Equal(rev.UnlockConditions.PublicKeys[0].Key, pk[:]) {
+		return types.Transaction{}, errors.New("revision does not belong to the renter")
+	}
+	// NOTE: as in verifyRecentRevision, the blockheight only needs to be
+	// above the fork height and below the contract expiration.
+	if err := modules.VerifyFileContractRevisionTransactionSignatures(rev, sigs, rev.NewWindowStart-1); err != nil {
+		return types.Transaction{}, err
+	}
+
+	// End the download loop before it begins; errors don't matter, as the
+	// revision has already been received.
+	extendDeadline(conn, modules.NegotiateSettingsTime)
+	_, _ = verifySettings(conn, host)
+	_ = modules.WriteNegotiationStop(conn)
+	return types.Transaction{
+		FileContractRevisions: []types.FileContractRevision{rev},
+		TransactionSignatures: sigs,
+	}, nil
+}
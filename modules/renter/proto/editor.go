@@ -254,8 +254,9 @@ func (he *Editor) Modify(oldRoot, newRoot crypto.Hash, offset uint64, newData []
 }
 
 // NewEditor initiates the contract revision process with a host, and returns
-// an Editor.
-func NewEditor(host modules.HostDBEntry, contract modules.RenterContract, currentHeight types.BlockHeight, cancel <-chan struct{}) (*Editor, error) {
+// an Editor. The bandwidth of the connection to the host is limited by rl,
+// which may be nil.
+func NewEditor(host modules.HostDBEntry, contract modules.RenterContract, currentHeight types.BlockHeight, rl *RateLimiter, cancel <-chan struct{}) (*Editor, error) {
 	// check that contract has enough value to support an upload
 	if len(contract.LastRevision.NewValidProofOutputs) != 2 {
 		return nil, errors.New("invalid contract")
@@ -269,6 +270,7 @@ func NewEditor(host modules.HostDBEntry, contract modules.RenterContract, curren
 	if err != nil {
 		return nil, err
 	}
+	conn = rl.Conn(conn)
 
 	closeChan := make(chan struct{})
 	go func() {
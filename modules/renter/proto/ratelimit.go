@@ -0,0 +1,141 @@
+package proto
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// rateLimitPacketSize is the largest number of bytes that a rate limited
+// connection transfers at once, so that the bandwidth is spread evenly over
+// time.
+const rateLimitPacketSize = 16 * 1024
+
+type (
+	// A RateLimiter limits the bandwidth of the connections to hosts, both
+	// across all connections and for each connection. The limits can be
+	// changed while connections are open. A nil RateLimiter does not limit
+	// anything.
+	RateLimiter struct {
+		limits   modules.RateLimits
+		download time.Time // when all connections may next download
+		upload   time.Time // when all connections may next upload
+		mu       sync.Mutex
+	}
+
+	// rateLimitedConn is a connection whose transfers are delayed to respect
+	// the limits of a RateLimiter.
+	rateLimitedConn struct {
+		net.Conn
+		rl       *RateLimiter
+		download time.Time // when the connection may next download
+		upload   time.Time // when the connection may next upload
+	}
+)
+
+// schedule reserves the transfer of n bytes at a rate of bps bytes per
+// second, starting at next, and returns how long the transfer must wait. A
+// rate of 0 is unlimited.
+func schedule(next *time.Time, bps int64, n int, now time.Time) time.Duration {
+	if bps <= 0 {
+		return 0
+	}
+	if next.Before(now) {
+		*next = now
+	}
+	wait := next.Sub(now)
+	*next = next.Add(time.Duration(int64(n) * int64(time.Second) / bps))
+	return wait
+}
+
+// NewRateLimiter returns a RateLimiter with the given limits.
+func NewRateLimiter(limits modules.RateLimits) *RateLimiter {
+	return &RateLimiter{limits: limits}
+}
+
+// Limits returns the current limits of the RateLimiter.
+func (rl *RateLimiter) Limits() modules.RateLimits {
+	if rl == nil {
+		return modules.RateLimits{}
+	}
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return rl.limits
+}
+
+// SetLimits changes the limits of the RateLimiter, including for the
+// connections that are already open. Setting the limits of a nil RateLimiter
+// has no effect.
+func (rl *RateLimiter) SetLimits(limits modules.RateLimits) {
+	if rl == nil {
+		return
+	}
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.limits = limits
+}
+
+// Conn returns conn, limited by the RateLimiter.
+func (rl *RateLimiter) Conn(conn net.Conn) net.Conn {
+	if rl == nil {
+		return conn
+	}
+	return &rateLimitedConn{Conn: conn, rl: rl}
+}
+
+// reserveDownload returns how long the download of n bytes by c must wait.
+func (rl *RateLimiter) reserveDownload(c *rateLimitedConn, n int) time.Duration {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	now := time.Now()
+	wait := schedule(&rl.download, rl.limits.MaxDownloadSpeed, n, now)
+	if connWait := schedule(&c.download, rl.limits.MaxHostDownloadSpeed, n, now); connWait > wait {
+		wait = connWait
+	}
+	return wait
+}
+
+// reserveUpload returns how long the upload of n bytes by c must wait.
+func (rl *RateLimiter) reserveUpload(c *rateLimitedConn, n int) time.Duration {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	now := time.Now()
+	wait := schedule(&rl.upload, rl.limits.MaxUploadSpeed, n, now)
+	if connWait := schedule(&c.upload, rl.limits.MaxHostUploadSpeed, n, now); connWait > wait {
+		wait = connWait
+	}
+	return wait
+}
+
+// Read reads from the connection, then waits until the bytes that were read
+// fit within the download limits.
+func (c *rateLimitedConn) Read(b []byte) (int, error) {
+	if len(b) > rateLimitPacketSize {
+		b = b[:rateLimitPacketSize]
+	}
+	n, err := c.Conn.Read(b)
+	time.Sleep(c.rl.reserveDownload(c, n))
+	return n, err
+}
+
+// Write writes to the connection in packets, waiting before each packet until
+// it fits within the upload limits.
+func (c *rateLimitedConn) Write(b []byte) (int, error) {
+	var written int
+	for len(b) > 0 {
+		packet := b
+		if len(packet) > rateLimitPacketSize {
+			packet = packet[:rateLimitPacketSize]
+		}
+		time.Sleep(c.rl.reserveUpload(c, len(packet)))
+		n, err := c.Conn.Write(packet)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		b = b[n:]
+	}
+	return written, nil
+}
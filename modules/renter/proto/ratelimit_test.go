@@ -0,0 +1,92 @@
+package proto
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/fastrand"
+)
+
+// TestSchedule tests that schedule spaces out transfers according to the
+// rate.
+func TestSchedule(t *testing.T) {
+	now := time.Now()
+	var next time.Time
+	if wait := schedule(&next, 0, 1e6, now); wait != 0 || !next.IsZero() {
+		t.Fatal("unlimited transfer was scheduled:", wait, next)
+	}
+	if wait := schedule(&next, 1000, 500, now); wait != 0 {
+		t.Fatal("first transfer should not wait:", wait)
+	}
+	if wait := schedule(&next, 1000, 500, now); wait != 500*time.Millisecond {
+		t.Fatal("second transfer should wait for the first:", wait)
+	}
+	// A transfer after the schedule has caught up should not wait.
+	if wait := schedule(&next, 1000, 500, now.Add(2*time.Second)); wait != 0 {
+		t.Fatal("transfer after an idle period should not wait:", wait)
+	}
+}
+
+// TestRateLimitedConn tests that the writes and reads of a rate limited
+// connection respect the limits of the RateLimiter.
+func TestRateLimitedConn(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	data := fastrand.Bytes(4 * rateLimitPacketSize)
+
+	// transfer sends data from one end of a limited pipe to the other, and
+	// returns how long it took.
+	transfer := func(rl *RateLimiter, limitReads bool) time.Duration {
+		c1, c2 := net.Pipe()
+		defer c1.Close()
+		defer c2.Close()
+		var w io.Writer = c1
+		var r io.Reader = c2
+		if limitReads {
+			r = rl.Conn(c2)
+		} else {
+			w = rl.Conn(c1)
+		}
+		start := time.Now()
+		go w.Write(data)
+		received, err := ioutil.ReadAll(io.LimitReader(r, int64(len(data))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(received) != len(data) {
+			t.Fatal("wrong amount of data received:", len(received))
+		}
+		return time.Since(start)
+	}
+
+	// A nil RateLimiter does not limit the connection.
+	if d := transfer(nil, false); d > 100*time.Millisecond {
+		t.Fatal("nil RateLimiter limited the connection:", d)
+	}
+
+	// 4 packets at 4 packets per second take at least 750ms, as the first
+	// packet is sent immediately.
+	rl := NewRateLimiter(modules.RateLimits{MaxUploadSpeed: 4 * rateLimitPacketSize})
+	if d := transfer(rl, false); d < 700*time.Millisecond {
+		t.Fatal("upload was not limited:", d)
+	}
+	// Downloads are not affected by the upload limit.
+	if d := transfer(rl, true); d > 100*time.Millisecond {
+		t.Fatal("download was limited by the upload limit:", d)
+	}
+
+	// The limit of each connection applies as well, and can be changed while
+	// connections are open.
+	rl.SetLimits(modules.RateLimits{MaxHostDownloadSpeed: 4 * rateLimitPacketSize})
+	if d := transfer(rl, true); d < 700*time.Millisecond {
+		t.Fatal("download was not limited:", d)
+	}
+	if d := transfer(rl, false); d > 100*time.Millisecond {
+		t.Fatal("upload was limited after the limit was removed:", d)
+	}
+}
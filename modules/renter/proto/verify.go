@@ -0,0 +1,71 @@
+package proto
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// VerifySegments challenges a host to prove that it stores segments of the
+// sectors of a contract, and returns the number of challenges that the host
+// failed to answer with a valid Merkle proof. Unlike a download, the host is
+// not paid for answering the challenges. The bandwidth of the connection to
+// the host is limited by rl, which may be nil.
+func VerifySegments(host modules.HostDBEntry, contract modules.RenterContract, challenges []modules.SegmentChallenge, rl *RateLimiter, cancel <-chan struct{}) (int, error) {
+	conn, err := (&net.Dialer{
+		Cancel:  cancel,
+		Timeout: 15 * time.Second,
+	}).Dial("tcp", string(host.NetAddress))
+	if err != nil {
+		return 0, err
+	}
+	conn = rl.Conn(conn)
+	defer conn.Close()
+	closeChan := make(chan struct{})
+	defer close(closeChan)
+	go func() {
+		select {
+		case <-cancel:
+			conn.Close()
+		case <-closeChan:
+		}
+	}()
+
+	// allot 2 minutes for RPC request + revision exchange
+	extendDeadline(conn, modules.NegotiateRecentRevisionTime)
+	if err := encoding.WriteObject(conn, modules.RPCVerifySegments); err != nil {
+		return 0, errors.New("couldn't initiate RPC: " + err.Error())
+	}
+	if _, _, err := readRecentRevision(conn, contract.ID, contract.SecretKey); err != nil {
+		return 0, err
+	}
+
+	// send the challenges and read the proofs
+	extendDeadline(conn, modules.NegotiateVerifyTime)
+	if err := encoding.WriteObject(conn, challenges); err != nil {
+		return 0, errors.New("couldn't send segment challenges: " + err.Error())
+	}
+	if err := modules.ReadNegotiationAcceptance(conn); err != nil {
+		return 0, errors.New("host did not accept segment challenges: " + err.Error())
+	}
+	var proofs []modules.SegmentProof
+	maxLen := uint64(len(challenges)+1) * modules.NegotiateMaxSegmentProofSize
+	if err := encoding.ReadObject(conn, &proofs, maxLen); err != nil {
+		return 0, errors.New("couldn't read segment proofs: " + err.Error())
+	} else if len(proofs) != len(challenges) {
+		return 0, errors.New("host did not send a proof for every challenge")
+	}
+
+	numSegments := modules.SectorSize / crypto.SegmentSize
+	var failed int
+	for i, proof := range proofs {
+		if !crypto.VerifySegment(proof.Base, proof.HashSet, numSegments, challenges[i].SegmentIndex, challenges[i].MerkleRoot) {
+			failed++
+		}
+	}
+	return failed, nil
+}
@@ -9,8 +9,10 @@ package renter
 
 import (
 	"errors"
+	"math"
 
 	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/modules/renter/contractor"
 	"github.com/NebulousLabs/Sia/modules/renter/hostdb"
@@ -23,7 +25,12 @@ var (
 	errNilContractor = errors.New("cannot create renter with nil contractor")
 	errNilCS         = errors.New("cannot create renter with nil consensus set")
 	errNilTpool      = errors.New("cannot create renter with nil transaction pool")
+	errNilWallet     = errors.New("cannot create renter with nil wallet")
 	errNilHdb        = errors.New("cannot create renter with nil hostdb")
+
+	errEstimateNoHosts    = errors.New("no hosts are available to estimate the cost")
+	errEstimateDuration   = errors.New("duration of the estimate must be at least one block")
+	errEstimateRedundancy = errors.New("redundancy of the estimate must be positive")
 )
 
 var (
@@ -58,11 +65,22 @@ type hostDB interface {
 	// Host returns the HostDBEntry for a given host.
 	Host(types.SiaPublicKey) (modules.HostDBEntry, bool)
 
+	// Filter returns the filter that restricts the hosts used by the
+	// renter.
+	Filter() modules.HostFilter
+
+	// IsFiltered returns whether the filter excludes the host with the given
+	// public key.
+	IsFiltered(types.SiaPublicKey) bool
+
 	// RandomHosts returns a set of random hosts, weighted by their estimated
 	// usefulness / attractiveness to the renter. RandomHosts will not return
-	// any offline or inactive hosts.
+	// any offline or inactive hosts, or hosts excluded by the filter.
 	RandomHosts(int, []types.SiaPublicKey) []modules.HostDBEntry
 
+	// SetFilter sets the filter that restricts the hosts used by the renter.
+	SetFilter(modules.HostFilter) error
+
 	// ScoreBreakdown returns a detailed explanation of the various properties
 	// of the host.
 	ScoreBreakdown(modules.HostDBEntry) modules.HostScoreBreakdown
@@ -101,15 +119,49 @@ type hostContractor interface {
 	// insertion, deletion, and modification of sectors.
 	Editor(types.FileContractID, <-chan struct{}) (contractor.Editor, error)
 
+	// FailingContracts returns the IDs of the contracts whose hosts are
+	// failing. The data stored on failing hosts should be moved elsewhere.
+	FailingContracts() []types.FileContractID
+
 	// IsOffline reports whether the specified host is considered offline.
 	IsOffline(types.FileContractID) bool
 
+	// IsUnreliable reports whether the specified host is offline, or failed
+	// its most recent scan.
+	IsUnreliable(types.FileContractID) bool
+
+	// RateLimits returns the bandwidth limits of the connections to hosts.
+	RateLimits() modules.RateLimits
+
+	// RecoverContract updates a contract that is not tracked by the
+	// hostContractor to the most recent revision of its host, and downloads
+	// the sectors with the specified roots from the host.
+	RecoverContract(modules.RenterContract, []crypto.Hash, <-chan struct{}) (modules.RenterContract, [][]byte, error)
+
+	// ReleaseContract stops the use and renewal of the specified contract.
+	ReleaseContract(types.FileContractID) error
+
+	// RestoreContracts adds the contracts of a backup, adopting the
+	// allowance of the backup if no allowance is set.
+	RestoreContracts(modules.Allowance, []modules.RenterContract) error
+
+	// SetRateLimits sets the bandwidth limits of the connections to hosts.
+	SetRateLimits(modules.RateLimits) error
+
+	// SetPaused pauses or resumes the formation and renewal of contracts.
+	SetPaused(bool) error
+
 	// Downloader creates a Downloader from the specified contract ID,
 	// allowing the retrieval of sectors.
 	Downloader(types.FileContractID, <-chan struct{}) (contractor.Downloader, error)
 
 	// ResolveID returns the most recent renewal of the specified ID.
 	ResolveID(types.FileContractID) types.FileContractID
+
+	// VerifySegments challenges the host of the specified contract to prove
+	// that it stores segments of the contract's sectors, and returns the
+	// number of challenges that the host failed.
+	VerifySegments(types.FileContractID, []modules.SegmentChallenge, <-chan struct{}) (int, error)
 }
 
 // A trackedFile contains metadata about files being tracked by the Renter.
@@ -119,6 +171,14 @@ type hostContractor interface {
 type trackedFile struct {
 	// location of original file on disk
 	RepairPath string
+
+	// Priority orders the uploads and repairs of the file against those of
+	// other files. The pieces of paused files are not uploaded.
+	Priority int
+	Paused   bool
+
+	// Class is the name of the redundancy class of the file, if any.
+	Class string
 }
 
 // A Renter is responsible for tracking all of the files that a user has
@@ -128,8 +188,18 @@ type Renter struct {
 	//
 	// tracking contains a list of files that the user intends to maintain. By
 	// default, files loaded through sharing are not maintained by the user.
-	files    map[string]*file
-	tracking map[string]trackedFile // map from nickname to metadata
+	//
+	// dirs contains the directories that were created explicitly. Other
+	// directories exist as long as there are files within them.
+	//
+	// classes contains the redundancy classes that files can be assigned to,
+	// and conversions the files that are being uploaded again with the layout
+	// of their class. A conversion replaces its file once it is complete.
+	classes     map[string]modules.RedundancyClass
+	conversions map[string]*file
+	dirs        map[string]struct{}
+	files       map[string]*file
+	tracking    map[string]trackedFile // map from nickname to metadata
 
 	// Work management.
 	//
@@ -139,11 +209,52 @@ type Renter struct {
 	//
 	// downloadQueue contains a complete history of work that has been
 	// submitted to the download loop.
-	chunkQueue    []*chunkDownload // Accessed without locks.
-	downloadQueue []*download
-	newDownloads  chan *download
-	newRepairs    chan *file
-	workerPool    map[types.FileContractID]*worker
+	//
+	// downloadCache holds the chunks that were recently downloaded, so that
+	// they do not have to be downloaded from the hosts again.
+	//
+	// repairs tracks the progress of the files that are being repaired by the
+	// repair loop.
+	//
+	// streamChunks holds the data of the chunks of streamed uploads that
+	// cannot yet be recovered from the hosts.
+	//
+	// uploadQueue contains the history of the uploads that have been started
+	// since the renter started. lastTransferID is the ID of the most recent
+	// upload or download.
+	chunkQueue     []*chunkDownload // Accessed without locks.
+	downloadCache  *downloadCache
+	downloadQueue  []*download
+	lastTransferID uint64
+	newDownloads   chan *download
+	newRepairs     chan *file
+	repairs        map[string]*fileRepair
+	streamChunks   map[chunkID][]byte
+	uploadQueue    []*upload
+	workerPool     map[types.FileContractID]*worker
+
+	// transferSubscribers are notified of the progress of uploads and
+	// downloads.
+	transferSubscribers []modules.TransferSubscriber
+
+	// masterKey is the key that the keys of uploaded files are derived from.
+	// It is derived from the wallet seed when it is first needed.
+	masterKey crypto.TwofishKey
+
+	// snapshots are the snapshots of the renter that are stored on its hosts,
+	// oldest first. snapshotting is set while a snapshot is being created.
+	snapshots    []snapshot
+	snapshotting bool
+
+	// paused is set while the background activity of the renter is paused.
+	paused bool
+
+	// downloadedSectors counts the sectors that have been downloaded from
+	// each contract for each file, so that the download spending of the
+	// contracts can be attributed to files. unsavedDownloads is set when the
+	// counts have changed since the renter was last saved.
+	downloadedSectors map[types.FileContractID]map[string]uint64
+	unsavedDownloads  bool
 
 	// Utilities.
 	cs             modules.ConsensusSet
@@ -154,6 +265,7 @@ type Renter struct {
 	mu             *sync.RWMutex
 	tg             *sync.ThreadGroup
 	tpool          modules.TransactionPool
+	wallet         modules.Wallet
 }
 
 // New returns an initialized renter.
@@ -167,14 +279,17 @@ func New(g modules.Gateway, cs modules.ConsensusSet, wallet modules.Wallet, tpoo
 		return nil, err
 	}
 
-	return newRenter(cs, tpool, hdb, hc, persistDir)
+	return newRenter(cs, wallet, tpool, hdb, hc, persistDir)
 }
 
 // newRenter initializes a renter and returns it.
-func newRenter(cs modules.ConsensusSet, tpool modules.TransactionPool, hdb hostDB, hc hostContractor, persistDir string) (*Renter, error) {
+func newRenter(cs modules.ConsensusSet, wallet modules.Wallet, tpool modules.TransactionPool, hdb hostDB, hc hostContractor, persistDir string) (*Renter, error) {
 	if cs == nil {
 		return nil, errNilCS
 	}
+	if wallet == nil {
+		return nil, errNilWallet
+	}
 	if tpool == nil {
 		return nil, errNilTpool
 	}
@@ -187,11 +302,18 @@ func newRenter(cs modules.ConsensusSet, tpool modules.TransactionPool, hdb hostD
 	}
 
 	r := &Renter{
-		newRepairs: make(chan *file),
-		files:      make(map[string]*file),
-		tracking:   make(map[string]trackedFile),
+		newRepairs:  make(chan *file),
+		repairs:     make(map[string]*fileRepair),
+		classes:     make(map[string]modules.RedundancyClass),
+		conversions: make(map[string]*file),
+		dirs:        make(map[string]struct{}),
+		files:       make(map[string]*file),
+		tracking:    make(map[string]trackedFile),
+
+		downloadedSectors: make(map[types.FileContractID]map[string]uint64),
 
 		newDownloads: make(chan *download),
+		streamChunks: make(map[chunkID][]byte),
 		workerPool:   make(map[types.FileContractID]*worker),
 
 		cs:             cs,
@@ -201,6 +323,7 @@ func newRenter(cs modules.ConsensusSet, tpool modules.TransactionPool, hdb hostD
 		mu:             sync.New(modules.SafeMutexDelay, 1),
 		tg:             new(sync.ThreadGroup),
 		tpool:          tpool,
+		wallet:         wallet,
 	}
 	if err := r.initPersist(); err != nil {
 		return nil, err
@@ -211,6 +334,7 @@ func newRenter(cs modules.ConsensusSet, tpool modules.TransactionPool, hdb hostD
 	go r.threadedRepairLoop()
 	go r.threadedDownloadLoop()
 	go r.threadedQueueRepairs()
+	go r.threadedSnapshotLoop()
 
 	// Kill workers on shutdown.
 	r.tg.OnStop(func() {
@@ -288,9 +412,64 @@ func (r *Renter) PriceEstimation() modules.RenterPriceEstimation {
 	}
 }
 
+// EstimateCost estimates the cost of uploading size bytes with the given
+// redundancy and storing them for duration blocks. The estimate averages the
+// prices of the hosts that the renter would choose, and assumes that new
+// contracts are formed with as many hosts as the default erasure coding
+// requires for the redundancy.
+func (r *Renter) EstimateCost(size uint64, redundancy float64, duration types.BlockHeight) (modules.RenterCostEstimate, error) {
+	if redundancy <= 0 {
+		return modules.RenterCostEstimate{}, errEstimateRedundancy
+	}
+	if duration == 0 {
+		return modules.RenterCostEstimate{}, errEstimateDuration
+	}
+
+	// Each host stores one piece of every chunk, so the redundancy
+	// determines the number of hosts.
+	numHosts := int(math.Ceil(redundancy * float64(defaultDataPieces)))
+	hosts := r.hostDB.RandomHosts(numHosts, nil)
+	if len(hosts) == 0 {
+		return modules.RenterCostEstimate{}, errEstimateNoHosts
+	}
+
+	// Average the prices of the hosts.
+	var contractPrice, storagePrice, uploadPrice types.Currency
+	for _, host := range hosts {
+		contractPrice = contractPrice.Add(host.ContractPrice)
+		storagePrice = storagePrice.Add(host.StoragePrice)
+		uploadPrice = uploadPrice.Add(host.UploadBandwidthPrice)
+	}
+	contractPrice = contractPrice.Div64(uint64(len(hosts)))
+	storagePrice = storagePrice.Div64(uint64(len(hosts)))
+	uploadPrice = uploadPrice.Div64(uint64(len(hosts)))
+
+	// The data, including redundancy, is spread over the hosts.
+	storage := storagePrice.Mul64(size).Mul64(uint64(duration)).MulFloat(redundancy)
+	upload := uploadPrice.Mul64(size).MulFloat(redundancy)
+
+	// Forming a contract costs the host's contract price, a transaction fee,
+	// and the siafund fee on the money put into the contract.
+	_, feePerByte := r.tpool.FeeEstimation()
+	contractFees := contractPrice.Add(feePerByte.Mul64(1000)).Mul64(uint64(numHosts))
+	contractFees = contractFees.Add(types.Tax(r.cs.Height(), storage.Add(upload).Add(contractFees)))
+
+	return modules.RenterCostEstimate{
+		Hosts:        numHosts,
+		Storage:      storage,
+		Upload:       upload,
+		ContractFees: contractFees,
+		Total:        storage.Add(upload).Add(contractFees),
+	}, nil
+}
+
 // SetSettings will update the settings for the renter.
 func (r *Renter) SetSettings(s modules.RenterSettings) error {
-	err := r.hostContractor.SetAllowance(s.Allowance)
+	err := r.hostContractor.SetRateLimits(s.RateLimits)
+	if err != nil {
+		return err
+	}
+	err = r.hostContractor.SetAllowance(s.Allowance)
 	if err != nil {
 		return err
 	}
@@ -301,10 +480,18 @@ func (r *Renter) SetSettings(s modules.RenterSettings) error {
 	return nil
 }
 
+// SetRateLimits sets the bandwidth limits of the renter's connections to
+// hosts. Unlike SetSettings, the allowance is left untouched.
+func (r *Renter) SetRateLimits(limits modules.RateLimits) error {
+	return r.hostContractor.SetRateLimits(limits)
+}
+
 // hostdb passthroughs
 func (r *Renter) ActiveHosts() []modules.HostDBEntry                      { return r.hostDB.ActiveHosts() }
 func (r *Renter) AllHosts() []modules.HostDBEntry                         { return r.hostDB.AllHosts() }
 func (r *Renter) Host(spk types.SiaPublicKey) (modules.HostDBEntry, bool) { return r.hostDB.Host(spk) }
+func (r *Renter) HostFilter() modules.HostFilter                          { return r.hostDB.Filter() }
+func (r *Renter) SetHostFilter(f modules.HostFilter) error                { return r.hostDB.SetFilter(f) }
 func (r *Renter) ScoreBreakdown(e modules.HostDBEntry) modules.HostScoreBreakdown {
 	return r.hostDB.ScoreBreakdown(e)
 }
@@ -317,7 +504,8 @@ func (r *Renter) Contracts() []modules.RenterContract { return r.hostContractor.
 func (r *Renter) CurrentPeriod() types.BlockHeight    { return r.hostContractor.CurrentPeriod() }
 func (r *Renter) Settings() modules.RenterSettings {
 	return modules.RenterSettings{
-		Allowance: r.hostContractor.Allowance(),
+		Allowance:  r.hostContractor.Allowance(),
+		RateLimits: r.hostContractor.RateLimits(),
 	}
 }
 func (r *Renter) AllContracts() []modules.RenterContract {
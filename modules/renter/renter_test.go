@@ -2,6 +2,7 @@ package renter
 
 import (
 	"path/filepath"
+	"testing"
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/crypto"
@@ -125,7 +126,7 @@ func newContractorTester(name string, hdb hostDB, hc hostContractor) (*renterTes
 	if err != nil {
 		return nil, err
 	}
-	r, err := newRenter(cs, tp, hdb, hc, filepath.Join(testdir, modules.RenterDir))
+	r, err := newRenter(cs, w, tp, hdb, hc, filepath.Join(testdir, modules.RenterDir))
 	if err != nil {
 		return nil, err
 	}
@@ -170,8 +171,10 @@ func (stubHostDB) IsOffline(modules.NetAddress) bool    { return true }
 // interface.
 type stubContractor struct{}
 
-func (stubContractor) SetAllowance(modules.Allowance) error { return nil }
-func (stubContractor) Allowance() modules.Allowance         { return modules.Allowance{} }
+func (stubContractor) SetAllowance(modules.Allowance) error   { return nil }
+func (stubContractor) Allowance() modules.Allowance           { return modules.Allowance{} }
+func (stubContractor) RateLimits() modules.RateLimits         { return modules.RateLimits{} }
+func (stubContractor) SetRateLimits(modules.RateLimits) error { return nil }
 func (stubContractor) Contract(modules.NetAddress) (modules.RenterContract, bool) {
 	return modules.RenterContract{}, false
 }
@@ -182,3 +185,73 @@ func (stubContractor) Editor(types.FileContractID) (contractor.Editor, error) {
 func (stubContractor) Downloader(types.FileContractID) (contractor.Downloader, error) {
 	return nil, nil
 }
+
+// pricedHostDB is a hostDB that always selects the same hosts.
+type pricedHostDB struct {
+	hostDB
+	hosts []modules.HostDBEntry
+}
+
+func (hdb pricedHostDB) RandomHosts(n int, _ []types.SiaPublicKey) []modules.HostDBEntry {
+	if n > len(hdb.hosts) {
+		n = len(hdb.hosts)
+	}
+	return hdb.hosts[:n]
+}
+
+// TestEstimateCost checks that EstimateCost averages the prices of the hosts
+// and scales them by the size, redundancy, and duration.
+func TestEstimateCost(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+
+	// Without hosts, no estimate can be made.
+	rt.renter.hostDB = pricedHostDB{hostDB: rt.renter.hostDB}
+	if _, err := rt.renter.EstimateCost(1e6, 1, 10); err != errEstimateNoHosts {
+		t.Fatal("expected errEstimateNoHosts, got", err)
+	}
+	if _, err := rt.renter.EstimateCost(1e6, 0, 10); err != errEstimateRedundancy {
+		t.Fatal("expected errEstimateRedundancy, got", err)
+	}
+	if _, err := rt.renter.EstimateCost(1e6, 1, 0); err != errEstimateDuration {
+		t.Fatal("expected errEstimateDuration, got", err)
+	}
+
+	// Two hosts whose prices average to 20 hastings.
+	var hosts []modules.HostDBEntry
+	for _, price := range []uint64{10, 30} {
+		var host modules.HostDBEntry
+		host.ContractPrice = types.NewCurrency64(price)
+		host.StoragePrice = types.NewCurrency64(price)
+		host.UploadBandwidthPrice = types.NewCurrency64(price)
+		hosts = append(hosts, host)
+	}
+	rt.renter.hostDB = pricedHostDB{hostDB: rt.renter.hostDB, hosts: hosts}
+
+	size, duration := uint64(1e6), types.BlockHeight(10)
+	est, err := rt.renter.EstimateCost(size, 2, duration)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if est.Hosts != 2*defaultDataPieces {
+		t.Error("expected", 2*defaultDataPieces, "hosts, got", est.Hosts)
+	}
+	if !est.Storage.Equals64(20 * size * uint64(duration) * 2) {
+		t.Error("wrong storage cost:", est.Storage)
+	}
+	if !est.Upload.Equals64(20 * size * 2) {
+		t.Error("wrong upload cost:", est.Upload)
+	}
+	if est.ContractFees.Cmp64(20*uint64(est.Hosts)) <= 0 {
+		t.Error("contract fees should include the contract prices and the transaction fees:", est.ContractFees)
+	}
+	if !est.Total.Equals(est.Storage.Add(est.Upload).Add(est.ContractFees)) {
+		t.Error("total is not the sum of the costs")
+	}
+}
@@ -0,0 +1,245 @@
+// Package rentertest provides an in-memory renter for testing the modules
+// that store files with the renter, such as the WebDAV server and the S3
+// gateway.
+package rentertest
+
+import (
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/renter"
+)
+
+// MemRenter is a renter that keeps the data of its files in memory. Only the
+// methods used to store and retrieve files are implemented; calling any other
+// method of modules.Renter panics.
+type MemRenter struct {
+	modules.Renter
+	dirs  map[string]struct{}
+	files map[string][]byte
+	mu    sync.Mutex
+}
+
+// NewMemRenter returns an empty MemRenter.
+func NewMemRenter() *MemRenter {
+	return &MemRenter{
+		dirs:  make(map[string]struct{}),
+		files: make(map[string][]byte),
+	}
+}
+
+// isWithin reports whether name is within the directory dir.
+func isWithin(name, dir string) bool {
+	return dir == "" || strings.HasPrefix(name, dir+"/")
+}
+
+// dirExists mirrors the renter: a directory exists if it was created, or if
+// there are files or directories within it.
+func (r *MemRenter) dirExists(dir string) bool {
+	if _, ok := r.dirs[dir]; ok || dir == "" {
+		return true
+	}
+	for name := range r.files {
+		if isWithin(name, dir) {
+			return true
+		}
+	}
+	for d := range r.dirs {
+		if isWithin(d, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// Dirs returns a copy of the directories that were created explicitly.
+func (r *MemRenter) Dirs() map[string]struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	dirs := make(map[string]struct{}, len(r.dirs))
+	for d := range r.dirs {
+		dirs[d] = struct{}{}
+	}
+	return dirs
+}
+
+// Files returns a copy of the files of the renter, keyed by siapath.
+func (r *MemRenter) Files() map[string][]byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	files := make(map[string][]byte, len(r.files))
+	for name, data := range r.files {
+		files[name] = data
+	}
+	return files
+}
+
+// SetFile stores a file with the given data, replacing any previous file at
+// siapath.
+func (r *MemRenter) SetFile(siapath string, data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.files[siapath] = data
+}
+
+// CreateDir implements modules.Renter.
+func (r *MemRenter) CreateDir(dir string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.files[dir]; ok {
+		return renter.ErrPathOverload
+	} else if r.dirExists(dir) {
+		return renter.ErrDirOverload
+	}
+	r.dirs[dir] = struct{}{}
+	return nil
+}
+
+// DeleteDir implements modules.Renter.
+func (r *MemRenter) DeleteDir(dir string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.dirExists(dir) {
+		return renter.ErrUnknownDir
+	}
+	for name := range r.files {
+		if isWithin(name, dir) {
+			delete(r.files, name)
+		}
+	}
+	for d := range r.dirs {
+		if d == dir || isWithin(d, dir) {
+			delete(r.dirs, d)
+		}
+	}
+	return nil
+}
+
+// DirList implements modules.Renter. The NumFiles of the directory counts
+// the files anywhere within it.
+func (r *MemRenter) DirList(dir string) (modules.DirectoryInfo, []modules.DirectoryInfo, []modules.FileInfo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, isFile := r.files[dir]; isFile || !r.dirExists(dir) {
+		return modules.DirectoryInfo{}, nil, nil, renter.ErrUnknownDir
+	}
+	di := modules.DirectoryInfo{SiaPath: dir}
+	subdirs := make(map[string]struct{})
+	var files []modules.FileInfo
+	for name, data := range r.files {
+		if !isWithin(name, dir) {
+			continue
+		}
+		di.NumFiles++
+		rest := strings.TrimPrefix(strings.TrimPrefix(name, dir), "/")
+		if i := strings.Index(rest, "/"); i != -1 {
+			subdirs[rest[:i]] = struct{}{}
+		} else {
+			files = append(files, modules.FileInfo{SiaPath: name, Filesize: uint64(len(data))})
+		}
+	}
+	for d := range r.dirs {
+		if d != dir && isWithin(d, dir) {
+			rest := strings.TrimPrefix(strings.TrimPrefix(d, dir), "/")
+			subdirs[strings.SplitN(rest, "/", 2)[0]] = struct{}{}
+		}
+	}
+	var dirs []modules.DirectoryInfo
+	for name := range subdirs {
+		dirs = append(dirs, modules.DirectoryInfo{SiaPath: strings.TrimPrefix(dir+"/"+name, "/")})
+	}
+	sort.Slice(dirs, func(i, j int) bool { return dirs[i].SiaPath < dirs[j].SiaPath })
+	sort.Slice(files, func(i, j int) bool { return files[i].SiaPath < files[j].SiaPath })
+	return di, dirs, files, nil
+}
+
+// DeleteFile implements modules.Renter.
+func (r *MemRenter) DeleteFile(siapath string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.files[siapath]; !ok {
+		return renter.ErrUnknownPath
+	}
+	delete(r.files, siapath)
+	return nil
+}
+
+// DownloadSection implements modules.Renter.
+func (r *MemRenter) DownloadSection(siapath string, offset, length uint64, w io.Writer) error {
+	r.mu.Lock()
+	data, ok := r.files[siapath]
+	r.mu.Unlock()
+	if !ok {
+		return renter.ErrUnknownPath
+	}
+	_, err := w.Write(data[offset : offset+length])
+	return err
+}
+
+// FileList implements modules.Renter.
+func (r *MemRenter) FileList() []modules.FileInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var fis []modules.FileInfo
+	for name, data := range r.files {
+		fis = append(fis, modules.FileInfo{SiaPath: name, Filesize: uint64(len(data))})
+	}
+	return fis
+}
+
+// RenameDir implements modules.Renter.
+func (r *MemRenter) RenameDir(dir, newDir string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.dirExists(dir) {
+		return renter.ErrUnknownDir
+	} else if r.dirExists(newDir) {
+		return renter.ErrDirOverload
+	}
+	for name, data := range r.files {
+		if isWithin(name, dir) {
+			delete(r.files, name)
+			r.files[newDir+strings.TrimPrefix(name, dir)] = data
+		}
+	}
+	for d := range r.dirs {
+		if d == dir || isWithin(d, dir) {
+			delete(r.dirs, d)
+			r.dirs[newDir+strings.TrimPrefix(d, dir)] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// RenameFile implements modules.Renter.
+func (r *MemRenter) RenameFile(siapath, newPath string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.files[siapath]; !ok {
+		return renter.ErrUnknownPath
+	} else if _, ok := r.files[newPath]; ok {
+		return renter.ErrPathOverload
+	}
+	r.files[newPath] = r.files[siapath]
+	delete(r.files, siapath)
+	return nil
+}
+
+// UploadStreamFromReader implements modules.Renter.
+func (r *MemRenter) UploadStreamFromReader(up modules.FileUploadParams, reader io.Reader) error {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.files[up.SiaPath]; ok {
+		return renter.ErrPathOverload
+	}
+	r.files[up.SiaPath] = data
+	return nil
+}
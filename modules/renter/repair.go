@@ -41,11 +41,22 @@ type (
 		totalPieces  int
 	}
 
+	// fileRepair tracks the repair of a file. chunks is the number of
+	// incomplete chunks of the file that were added to the repair state, and
+	// completed the number that have since been repaired or given up on.
+	fileRepair struct {
+		chunks    int
+		completed int
+	}
+
 	// chunkID can be used to uniquely identify a chunk within the repair
-	// matrix.
+	// matrix. The chunks of the conversion of a file to the layout of its
+	// redundancy class are identified separately from the chunks of the
+	// file.
 	chunkID struct {
-		index    uint64 // the index of the chunk within its file.
-		filename string
+		index      uint64 // the index of the chunk within its file.
+		filename   string
+		conversion bool
 	}
 
 	// repairState tracks a bunch of chunks that are being actively repaired.
@@ -99,6 +110,30 @@ func (cs *chunkStatus) numGaps(rs *repairState) int {
 	return pieceGaps
 }
 
+// needsRepair indicates whether the file should be queued for repair: either
+// it has not finished uploading, or the redundancy of its online pieces has
+// dropped below repairThreshold of its full redundancy.
+func (f *file) needsRepair(isOffline func(types.FileContractID) bool) bool {
+	if f.uploadProgress() < 100 {
+		return true
+	}
+	fullRedundancy := float64(f.erasureCode.NumPieces()) / float64(f.erasureCode.MinPieces())
+	return f.redundancy(isOffline) < repairThreshold*fullRedundancy
+}
+
+// finishChunkRepair records that a chunk has left the repair state, removing
+// the repair of its file once all of its chunks have left.
+func (r *Renter) finishChunkRepair(cid chunkID) {
+	fr, exists := r.repairs[cid.filename]
+	if !exists || cid.conversion {
+		return
+	}
+	fr.completed++
+	if fr.completed >= fr.chunks {
+		delete(r.repairs, cid.filename)
+	}
+}
+
 // addFileToRepairState will take a file and add each of the incomplete chunks
 // to the repair state, along with data about which pieces need attention.
 func (r *Renter) addFileToRepairState(rs *repairState, file *file) {
@@ -131,11 +166,14 @@ func (r *Renter) addFileToRepairState(rs *repairState, file *file) {
 	}
 
 	// Iterate through each contract and figure out which pieces are available.
+	conversion := r.conversions[file.name] == file
+	isUnavailable := r.unavailabilityCheck(r.failingContracts())
 	for _, contract := range file.contracts {
-		// Check whether this contract is offline. Even if the contract is
-		// offline, we want to record that the chunk has attempted to use this
-		// contract.
-		offline := r.hostContractor.IsOffline(contract.ID)
+		// Check whether this contract is offline or failing. Even if the
+		// contract is offline, we want to record that the chunk has attempted
+		// to use this contract. The pieces on failing contracts are uploaded
+		// again to other hosts, so that the contracts can be released.
+		offline := isUnavailable(contract.ID)
 
 		// Scan all of the pieces of the contract.
 		for _, piece := range contract.Pieces {
@@ -161,7 +199,7 @@ func (r *Renter) addFileToRepairState(rs *repairState, file *file) {
 		}
 
 		// Skip this chunk if it's already in the set of incomplete chunks.
-		cid := chunkID{i, file.name}
+		cid := chunkID{index: i, filename: file.name, conversion: conversion}
 		_, exists := rs.incompleteChunks[cid]
 		if exists {
 			continue
@@ -177,6 +215,18 @@ func (r *Renter) addFileToRepairState(rs *repairState, file *file) {
 		cs.recordedGaps = cs.numGaps(rs)
 		rs.incompleteChunks[cid] = cs
 		rs.gapCounts[cs.recordedGaps]++
+
+		// Track the progress of the repair of the file. The progress of
+		// conversions is tracked by their files.
+		if conversion {
+			continue
+		}
+		fr, exists := r.repairs[file.name]
+		if !exists {
+			fr = new(fileRepair)
+			r.repairs[file.name] = fr
+		}
+		fr.chunks++
 	}
 }
 
@@ -184,8 +234,11 @@ func (r *Renter) addFileToRepairState(rs *repairState, file *file) {
 // scanning all of the files for missing pieces and attempting repair them by
 // uploading to chunks.
 func (r *Renter) managedRepairIteration(rs *repairState) {
-	// Wait for work if there is nothing to do.
-	if len(rs.activeWorkers) == 0 && len(rs.incompleteChunks) == 0 {
+	// Wait for work if there is nothing to do. The chunks of paused files
+	// are not worked on, and neither are any chunks while the renter is
+	// paused.
+	chunks := r.managedPrioritizedChunks(rs)
+	if len(rs.activeWorkers) == 0 && len(chunks) == 0 {
 		select {
 		case <-r.tg.StopChan():
 			return
@@ -199,6 +252,7 @@ func (r *Renter) managedRepairIteration(rs *repairState) {
 	}
 
 	// Reset the available workers.
+	failing := r.failingContracts()
 	id := r.mu.Lock()
 	r.updateWorkerPool()
 	rs.availableWorkers = make(map[types.FileContractID]*worker)
@@ -209,6 +263,17 @@ func (r *Renter) managedRepairIteration(rs *repairState) {
 			continue
 		}
 
+		// Ignore workers whose hosts are excluded by the host filter.
+		if r.hostDB.IsFiltered(worker.hostPubKey) {
+			continue
+		}
+
+		// Ignore workers whose hosts are failing. Their data is being moved
+		// to other hosts.
+		if _, ok := failing[id]; ok {
+			continue
+		}
+
 		// Ignore workers that have had an upload failure recently. The cooldown
 		// time scales exponentially as the number of consecutive failures grow,
 		// stopping at 10 doublings, or about 17 hours total cooldown.
@@ -231,11 +296,12 @@ func (r *Renter) managedRepairIteration(rs *repairState) {
 	}
 	r.mu.Unlock(id)
 
-	// Determine the maximum number of gaps of any chunk in the repair matrix.
+	// Determine the maximum number of gaps of any chunk that can be worked
+	// on.
 	maxGaps := 0
-	for i, gaps := range rs.gapCounts {
-		if i > maxGaps && gaps > 0 {
-			maxGaps = i
+	for _, cid := range chunks {
+		if gaps := rs.incompleteChunks[cid].recordedGaps; gaps > maxGaps {
+			maxGaps = gaps
 		}
 	}
 
@@ -247,9 +313,11 @@ func (r *Renter) managedRepairIteration(rs *repairState) {
 		delete(rs.cachedChunks, cid)
 	}
 
-	// Scan through the chunks until a candidate for uploads is found.
+	// Scan through the chunks, from the highest priority to the lowest, until
+	// a candidate for uploads is found.
 	var chunksToDelete []chunkID
-	for chunkID, chunkStatus := range rs.incompleteChunks {
+	for _, chunkID := range chunks {
+		chunkStatus := rs.incompleteChunks[chunkID]
 		// check if the chunk is currently being downloaded for recovery
 		if _, downloading := rs.downloadingChunks[chunkID]; downloading {
 			continue
@@ -294,6 +362,12 @@ func (r *Renter) managedRepairIteration(rs *repairState) {
 			continue
 		}
 
+		// Skip this chunk if as many pieces as allowed are being uploaded
+		// already.
+		if len(rs.activeWorkers) >= maxActiveUploads {
+			continue
+		}
+
 		// Send off the work.
 		err := r.managedScheduleChunkRepair(rs, chunkID, chunkStatus, usefulWorkers)
 		if err != nil {
@@ -302,8 +376,20 @@ func (r *Renter) managedRepairIteration(rs *repairState) {
 			continue
 		}
 	}
+	lockID := r.mu.Lock()
+	var conversions []string
 	for _, cid := range chunksToDelete {
 		delete(rs.incompleteChunks, cid)
+		r.finishChunkRepair(cid)
+		if cid.conversion {
+			conversions = append(conversions, cid.filename)
+		}
+	}
+	r.mu.Unlock(lockID)
+
+	// Replace the files whose conversions are complete.
+	for _, name := range conversions {
+		r.managedFinishConversion(name)
 	}
 
 	// Block until some of the workers return.
@@ -313,8 +399,9 @@ func (r *Renter) managedRepairIteration(rs *repairState) {
 // managedGetChunkData grabs the requested `chunkID` from the file, in order to
 // repair the file. If the `trackedFile` can be found on disk, grab the chunk
 // from the file, otherwise attempt to queue a new download for only that chunk
-// and return the downloaded chunk.
-func (r *Renter) managedGetChunkData(rs *repairState, file *file, trackedFile trackedFile, chunkID chunkID) ([]byte, error) {
+// from the pieces of src and return the downloaded chunk. src differs from
+// file when file is the conversion of src.
+func (r *Renter) managedGetChunkData(rs *repairState, file, src *file, trackedFile trackedFile, chunkID chunkID) ([]byte, error) {
 	chunkIndex := chunkID.index
 	offset := chunkIndex * file.chunkSize()
 
@@ -341,7 +428,7 @@ func (r *Renter) managedGetChunkData(rs *repairState, file *file, trackedFile tr
 		buf := NewDownloadBufferWriter(file.chunkSize())
 
 		// create the download object and push it on to the download queue
-		d := r.newSectionDownload(file, buf, currentContracts, offset, downloadSize)
+		d := r.newSectionDownload(src, buf, currentContracts, offset, downloadSize)
 		go func() {
 			r.newDownloads <- d
 		}()
@@ -378,20 +465,36 @@ func (r *Renter) managedScheduleChunkRepair(rs *repairState, chunkID chunkID, ch
 	// Check that the file is still in the renter.
 	filename := chunkID.filename
 	id := r.mu.RLock()
-	file, exists1 := r.files[filename]
+	src, exists1 := r.files[filename]
+	file := src
+	if chunkID.conversion {
+		file, exists1 = r.conversions[filename]
+	}
 	meta, exists2 := r.tracking[filename]
+	streamData, streaming := r.streamChunks[chunkID]
 	r.mu.RUnlock(id)
 	if !exists1 || !exists2 {
 		return errFileDeleted
 	}
+	// The chunk may belong to a file that has since been replaced by its
+	// conversion, which has fewer chunks.
+	file.mu.RLock()
+	numChunks := file.numChunks()
+	file.mu.RUnlock()
+	if chunkID.index >= numChunks {
+		return errFileDeleted
+	}
 
 	// read the chunk into memory
-	// check the cache first
+	// check the streamed chunks and the cache first
 	var chunkData []byte
-	if cachedData, exists := rs.cachedChunks[chunkID]; exists {
+	if streaming {
+		chunkData = streamData
+		rs.cachedChunks[chunkID] = streamData
+	} else if cachedData, exists := rs.cachedChunks[chunkID]; exists {
 		chunkData = cachedData
 	} else {
-		data, err := r.managedGetChunkData(rs, file, meta, chunkID)
+		data, err := r.managedGetChunkData(rs, file, src, meta, chunkID)
 		if err != nil {
 			return build.ExtendErr("unable to get repair chunk:", err)
 		}
@@ -414,6 +517,15 @@ func (r *Renter) managedScheduleChunkRepair(rs *repairState, chunkID chunkID, ch
 		}
 	}
 
+	// Prefer workers in subnets that hold no piece of the chunk, so that the
+	// workers that are left out by the limits below are the least useful.
+	usefulWorkers = orderUploadWorkers(rs, chunkStatus, usefulWorkers)
+
+	// Only use as many workers as maxActiveUploads allows.
+	if free := maxActiveUploads - len(rs.activeWorkers); len(usefulWorkers) > free {
+		usefulWorkers = usefulWorkers[:free]
+	}
+
 	// Truncate the pieces so that they match the size of the useful workers.
 	if len(usefulWorkers) < len(missingPieces) {
 		missingPieces = missingPieces[:len(usefulWorkers)]
@@ -423,6 +535,10 @@ func (r *Renter) managedScheduleChunkRepair(rs *repairState, chunkID chunkID, ch
 	for _, missingPiece := range missingPieces {
 		key := deriveKey(file.masterKey, chunkID.index, uint64(missingPiece))
 		pieces[missingPiece] = key.EncryptBytes(pieces[missingPiece])
+		// Hosts only store full sectors, so smaller pieces are padded.
+		if n := uint64(len(pieces[missingPiece])); n < modules.SectorSize {
+			pieces[missingPiece] = append(pieces[missingPiece], make([]byte, modules.SectorSize-n)...)
+		}
 	}
 
 	// Give each piece to a worker in the set of useful workers.
@@ -507,6 +623,11 @@ func (r *Renter) managedWaitOnRepairWork(rs *repairState) {
 
 	// Log the error and retire the worker.
 	r.log.Debugln("Error while performing upload to", finishedUpload.workerID, "::", finishedUpload.err)
+	id := r.mu.Lock()
+	if file, exists := r.files[finishedUpload.chunkID.filename]; exists {
+		r.failedPiece(file, rs.activeWorkers[finishedUpload.workerID].netAddress)
+	}
+	r.mu.Unlock(id)
 	delete(rs.activeWorkers, finishedUpload.workerID)
 
 	// Indicate in the set of incomplete chunks that this piece was not
@@ -514,41 +635,131 @@ func (r *Renter) managedWaitOnRepairWork(rs *repairState) {
 	rs.incompleteChunks[finishedUpload.chunkID].pieces[finishedUpload.pieceIndex] = struct{}{}
 }
 
+// managedMigrateRenewedContracts moves the pieces of every file from the
+// contracts that have been renewed to the contracts that renewed them.
+func (r *Renter) managedMigrateRenewedContracts() {
+	contracts := make(map[types.FileContractID]modules.RenterContract)
+	for _, contract := range r.hostContractor.Contracts() {
+		contracts[contract.ID] = contract
+	}
+
+	id := r.mu.Lock()
+	defer r.mu.Unlock(id)
+	files := make([]*file, 0, len(r.files)+len(r.conversions))
+	for _, f := range r.files {
+		files = append(files, f)
+	}
+	for _, f := range r.conversions {
+		files = append(files, f)
+	}
+	for _, f := range files {
+		f.mu.Lock()
+		if f.migrateRenewedContracts(r.hostContractor.ResolveID, contracts) {
+			if err := r.saveFile(f); err != nil {
+				r.log.Println("WARN: could not save file after migrating its renewed contracts:", err)
+			}
+		}
+		f.mu.Unlock()
+	}
+}
+
 // threadedQueueRepairs is a goroutine that runs in the background and
-// continuously adds files to the repair loop, slow enough that it's not a
-// resource burden but fast enough that no file is ever at risk.
+// continuously adds files that need repair to the repair loop, slow enough
+// that it's not a resource burden but fast enough that no file is ever at
+// risk.
 //
 // NOTE: This loop is pretty naive in terms of work management. As the number
 // of files goes up, and as the number of chunks per file goes up, this will
 // become a performance bottleneck, and even inhibit repair progress.
 func (r *Renter) threadedQueueRepairs() {
 	for {
-		// Compress the set of files into a slice.
-		id := r.mu.RLock()
-		var files []*file
-		for _, file := range r.files {
+		// Point the files at the renewals of their contracts, so that
+		// renewed contracts are not repaired or uploaded to twice.
+		r.managedMigrateRenewedContracts()
+		r.managedPruneDownloadRecords()
+
+		// Release the failing contracts whose data has been moved to other
+		// hosts. Contracts are not released while the renter is paused.
+		if !r.Paused() {
+			r.managedMigrateFailingContracts()
+		}
+
+		if !r.managedQueueRepairs() {
+			return
+		}
+
+		// Chill out for an extra 15 minutes before going through the files
+		// again.
+		select {
+		case <-time.After(repairQueueInterval):
+		case <-r.tg.StopChan():
+			return
+		}
+	}
+}
+
+// threadedQueueRepairsNow sends the tracked files that need to be repaired to
+// the repair loop without waiting for the next pass of threadedQueueRepairs.
+func (r *Renter) threadedQueueRepairsNow() {
+	if r.tg.Add() != nil {
+		return
+	}
+	defer r.tg.Done()
+	r.managedQueueRepairs()
+}
+
+// managedQueueRepairs sends the tracked files that need to be repaired, and
+// the incomplete conversions of files to the layouts of their redundancy
+// classes, to the repair loop. It returns false if the renter was stopped.
+func (r *Renter) managedQueueRepairs() bool {
+	// Compress the set of tracked files into a slice. Untracked files are
+	// never repaired.
+	id := r.mu.RLock()
+	var files []*file
+	for name, file := range r.files {
+		if _, tracked := r.tracking[name]; tracked {
 			files = append(files, file)
 		}
-		r.mu.RUnlock(id)
+	}
+	r.mu.RUnlock(id)
 
-		// Add files.
-		for _, file := range files {
+	// Add files.
+	failing := r.failingContracts()
+	isUnavailable := r.unavailabilityCheck(failing)
+	for _, file := range files {
+		// Skip files whose online redundancy is still high enough, unless
+		// they have pieces on failing hosts that need to be moved.
+		file.mu.RLock()
+		needsRepair := file.needsRepair(isUnavailable) || file.storedOn(failing)
+		file.mu.RUnlock()
+		if needsRepair {
 			// Send the file down the repair channel.
 			select {
 			case r.newRepairs <- file:
 			case <-r.tg.StopChan():
-				return
+				return false
 			}
 		}
 
-		// Chill out for an extra 15 minutes before going through the files
-		// again.
+		// Convert the file to the layout of its redundancy class.
+		conv := r.managedConversion(file)
+		if conv == nil {
+			continue
+		}
+		conv.mu.RLock()
+		complete := conv.complete()
+		conv.mu.RUnlock()
+		if complete {
+			r.managedFinishConversion(file.name)
+			continue
+		}
 		select {
-		case <-time.After(repairQueueInterval):
+		case r.newRepairs <- conv:
 		case <-r.tg.StopChan():
-			return
+			return false
 		}
 	}
+	return true
 }
 
 // threadedRepairLoop improves the health of files tracked by the renter by
@@ -0,0 +1,96 @@
+package renter
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestFileNeedsRepair checks that files are queued for repair until they are
+// uploaded, and again once their online redundancy drops below the repair
+// threshold.
+func TestFileNeedsRepair(t *testing.T) {
+	rsc, _ := NewRSCode(1, 1)
+	f := newFile("foo", rsc, pieceSize, 1000)
+	allOnline := func(types.FileContractID) bool { return false }
+	if !f.needsRepair(allOnline) {
+		t.Fatal("a file that was not uploaded should need repair")
+	}
+
+	// Upload both pieces of the only chunk.
+	for i := uint64(0); i < 2; i++ {
+		id := types.FileContractID{byte(i)}
+		f.contracts[id] = fileContract{
+			ID:     id,
+			Pieces: []pieceData{{Chunk: 0, Piece: i}},
+		}
+	}
+	if f.needsRepair(allOnline) {
+		t.Fatal("a fully redundant file should not need repair")
+	}
+
+	// Losing a host halves the redundancy of the file, which is below the
+	// threshold.
+	oneOffline := func(id types.FileContractID) bool { return id == types.FileContractID{0} }
+	if f.redundancy(oneOffline) >= repairThreshold*2 {
+		t.Fatal("test assumes that losing one piece drops below the threshold")
+	}
+	if !f.needsRepair(oneOffline) {
+		t.Fatal("a file that lost a piece should need repair")
+	}
+}
+
+// TestRepairProgress checks that the repair progress of a file is reported
+// as its incomplete chunks leave the repair state.
+func TestRepairProgress(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+	r := rt.renter
+
+	rsc, _ := NewRSCode(1, 1)
+	f := newFile("foo", rsc, 100, 250)
+	rs := &repairState{
+		activeWorkers:     make(map[types.FileContractID]*worker),
+		availableWorkers:  make(map[types.FileContractID]*worker),
+		gapCounts:         make(map[int]int),
+		incompleteChunks:  make(map[chunkID]*chunkStatus),
+		cachedChunks:      make(map[chunkID][]byte),
+		downloadingChunks: make(map[chunkID]struct{}),
+	}
+	lockID := r.mu.Lock()
+	r.files[f.name] = f
+	r.tracking[f.name] = trackedFile{}
+	r.addFileToRepairState(rs, f)
+	r.mu.Unlock(lockID)
+
+	progress := func() (bool, float64) {
+		for _, fi := range r.FileList() {
+			if fi.SiaPath == f.name {
+				return fi.Repairing, fi.RepairProgress
+			}
+		}
+		t.Fatal("file is missing from the file list")
+		return false, 0
+	}
+	if repairing, p := progress(); !repairing || p != 0 {
+		t.Fatal("file should be repairing from 0%:", repairing, p)
+	}
+	for i := uint64(0); i < f.numChunks(); i++ {
+		lockID := r.mu.Lock()
+		r.finishChunkRepair(chunkID{index: i, filename: f.name})
+		r.mu.Unlock(lockID)
+		repairing, p := progress()
+		if i+1 < f.numChunks() && (!repairing || p <= 0 || p >= 100) {
+			t.Fatal("wrong repair progress after", i+1, "chunks:", repairing, p)
+		}
+	}
+	if repairing, _ := progress(); repairing {
+		t.Fatal("file should no longer be repairing")
+	}
+}
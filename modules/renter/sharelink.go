@@ -0,0 +1,198 @@
+package renter
+
+// sharelink.go mints share links for uploaded files. A share link is a compact,
+// URL-safe string holding everything needed to fetch and decrypt a file: its
+// size and master key, its erasure code, and the hosts that store each of its
+// pieces. Any renter with contracts with those hosts can download the file
+// from the link without adding the file to its own set of files.
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sort"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+const (
+	// shareLinkVersion is the first byte of the data of a share link.
+	shareLinkVersion = 1
+
+	// maxShareLinkSize is the largest decompressed size of the data of a
+	// share link that will be decoded.
+	maxShareLinkSize = 1 << 22
+)
+
+var (
+	errBadShareLink     = errors.New("not a valid share link")
+	errShareLinkVersion = errors.New("share link was created by an incompatible version")
+)
+
+type (
+	// shareLink is the data encoded in a share link. Unlike a .sia file, it
+	// does not hold the IDs of the contracts that the pieces are stored in,
+	// as they are of no use to other renters.
+	shareLink struct {
+		Name         string
+		Size         uint64
+		MasterKey    crypto.TwofishKey
+		PieceSize    uint64
+		DataPieces   uint64
+		ParityPieces uint64
+		Hosts        []shareLinkHost
+	}
+
+	// shareLinkHost lists the pieces of a file stored on a host.
+	shareLinkHost struct {
+		NetAddress modules.NetAddress
+		Pieces     []pieceData
+	}
+)
+
+// encodeShareLink returns the share link of f.
+func encodeShareLink(f *file) (string, error) {
+	code, ok := f.erasureCode.(*rsCode)
+	if !ok {
+		return "", errors.New("unknown erasure code")
+	}
+	sl := shareLink{
+		Name:         f.name,
+		Size:         f.size,
+		MasterKey:    f.masterKey,
+		PieceSize:    f.pieceSize,
+		DataPieces:   uint64(code.dataPieces),
+		ParityPieces: uint64(code.numPieces - code.dataPieces),
+	}
+
+	// Merge the pieces of contracts with the same host, so that renewed
+	// contracts do not list their host twice.
+	hosts := make(map[modules.NetAddress][]pieceData)
+	f.mu.RLock()
+	for _, fc := range f.contracts {
+		hosts[fc.IP] = append(hosts[fc.IP], fc.Pieces...)
+	}
+	f.mu.RUnlock()
+	for addr, pieces := range hosts {
+		sl.Hosts = append(sl.Hosts, shareLinkHost{
+			NetAddress: addr,
+			Pieces:     pieces,
+		})
+	}
+	sort.Slice(sl.Hosts, func(i, j int) bool {
+		return sl.Hosts[i].NetAddress < sl.Hosts[j].NetAddress
+	})
+
+	buf := new(bytes.Buffer)
+	zip, _ := gzip.NewWriterLevel(buf, gzip.BestCompression)
+	if _, err := zip.Write(append([]byte{shareLinkVersion}, encoding.Marshal(sl)...)); err != nil {
+		return "", err
+	}
+	if err := zip.Close(); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// decodeShareLink returns the file described by a share link. The contracts
+// of the file are keyed by the hash of their host's address, and cannot be
+// resolved by the contractor.
+func decodeShareLink(link string) (*file, error) {
+	compressed, err := base64.RawURLEncoding.DecodeString(link)
+	if err != nil {
+		return nil, errBadShareLink
+	}
+	unzip, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, errBadShareLink
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(unzip, maxShareLinkSize))
+	if err != nil || len(data) == 0 {
+		return nil, errBadShareLink
+	} else if data[0] != shareLinkVersion {
+		return nil, errShareLinkVersion
+	}
+	var sl shareLink
+	if err := encoding.Unmarshal(data[1:], &sl); err != nil {
+		return nil, errBadShareLink
+	}
+
+	if sl.Name == "" || sl.PieceSize == 0 || sl.PieceSize > modules.SectorSize {
+		return nil, errBadShareLink
+	}
+	rsc, err := NewRSCode(int(sl.DataPieces), int(sl.ParityPieces))
+	if err != nil {
+		return nil, errBadShareLink
+	}
+	f := &file{
+		name:        sl.Name,
+		size:        sl.Size,
+		contracts:   make(map[types.FileContractID]fileContract),
+		masterKey:   sl.MasterKey,
+		erasureCode: rsc,
+		pieceSize:   sl.PieceSize,
+		mode:        defaultFilePerm,
+	}
+	numChunks := f.numChunks()
+	for _, host := range sl.Hosts {
+		for _, p := range host.Pieces {
+			if p.Chunk >= numChunks || p.Piece >= uint64(rsc.NumPieces()) {
+				return nil, errBadShareLink
+			}
+		}
+		id := types.FileContractID(crypto.HashObject(host.NetAddress))
+		f.contracts[id] = fileContract{
+			ID:     id,
+			IP:     host.NetAddress,
+			Pieces: host.Pieces,
+		}
+	}
+	return f, nil
+}
+
+// ShareLink returns a share link for the file at siapath, from which other
+// renters can download the file.
+func (r *Renter) ShareLink(siapath string) (string, error) {
+	lockID := r.mu.RLock()
+	f, exists := r.files[siapath]
+	r.mu.RUnlock(lockID)
+	if !exists {
+		return "", ErrUnknownPath
+	}
+	return encodeShareLink(f)
+}
+
+// ShareLinkInfo returns the siapath and size of the file that a share link
+// points to.
+func (r *Renter) ShareLinkInfo(link string) (modules.ShareLinkInfo, error) {
+	f, err := decodeShareLink(link)
+	if err != nil {
+		return modules.ShareLinkInfo{}, err
+	}
+	return modules.ShareLinkInfo{
+		SiaPath:  f.name,
+		Filesize: f.size,
+	}, nil
+}
+
+// DownloadShareLink downloads a section of the file that a share link points
+// to, writing it to w as it is recovered. The file is not added to the
+// renter's files.
+func (r *Renter) DownloadShareLink(link string, offset, length uint64, w io.Writer) error {
+	f, err := decodeShareLink(link)
+	if err != nil {
+		return err
+	}
+	return r.managedDownload(f, modules.RenterDownloadParameters{
+		Httpwriter: w,
+		Length:     length,
+		Offset:     offset,
+		Siapath:    f.name,
+	})
+}
@@ -0,0 +1,63 @@
+package renter
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestShareLink checks that a file can be recovered from its share link, and
+// that invalid links are rejected.
+func TestShareLink(t *testing.T) {
+	rsc, _ := NewRSCode(1, 2)
+	f := &file{
+		name:        "foo/bar.txt",
+		size:        1000,
+		masterKey:   crypto.GenerateTwofishKey(),
+		erasureCode: rsc,
+		pieceSize:   100,
+		contracts: map[types.FileContractID]fileContract{
+			{1}: {ID: types.FileContractID{1}, IP: "foo:1234", Pieces: []pieceData{{Chunk: 0, Piece: 0}}},
+			{2}: {ID: types.FileContractID{2}, IP: "foo:1234", Pieces: []pieceData{{Chunk: 1, Piece: 1}}},
+			{3}: {ID: types.FileContractID{3}, IP: "bar:1234", Pieces: []pieceData{{Chunk: 9, Piece: 2}}},
+		},
+	}
+	link, err := encodeShareLink(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lf, err := decodeShareLink(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lf.name != f.name || lf.size != f.size || lf.masterKey != f.masterKey || lf.pieceSize != f.pieceSize {
+		t.Fatal("file was not recovered from its share link")
+	}
+	if lf.erasureCode.MinPieces() != 1 || lf.erasureCode.NumPieces() != 3 {
+		t.Fatal("erasure code was not recovered from its share link")
+	}
+	// The contracts with the same host are merged.
+	pieces := make(map[modules.NetAddress]int)
+	for _, fc := range lf.contracts {
+		pieces[fc.IP] += len(fc.Pieces)
+	}
+	if len(lf.contracts) != 2 || pieces["foo:1234"] != 2 || pieces["bar:1234"] != 1 {
+		t.Fatal("contracts were not recovered from the share link:", lf.contracts)
+	}
+
+	// A piece outside of the file is rejected.
+	f.contracts[types.FileContractID{4}] = fileContract{IP: "baz:1234", Pieces: []pieceData{{Chunk: 10}}}
+	if link, err = encodeShareLink(f); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decodeShareLink(link); err != errBadShareLink {
+		t.Fatal("expected errBadShareLink, got", err)
+	}
+	for _, link := range []string{"", "foo", "sia://" + link} {
+		if _, err := decodeShareLink(link); err != errBadShareLink {
+			t.Fatalf("expected errBadShareLink for %q, got %v", link, err)
+		}
+	}
+}
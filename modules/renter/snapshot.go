@@ -0,0 +1,521 @@
+package renter
+
+// snapshot.go stores snapshots of the renter's metadata on its own hosts, so
+// that the renter can be recovered from the wallet seed alone. A snapshot is
+// an encrypted backup (see backup.go) that is uploaded to a few of the hosts
+// that the renter has contracts with. The location of each snapshot, along
+// with the keys of the contracts that it is stored in, is recorded in the
+// arbitrary data of a transaction funded by the wallet, encrypted with a key
+// derived from the master key. A node whose wallet was restored from the seed
+// finds the records among the transactions of its wallet, recovers the
+// contracts from their hosts, and restores the latest snapshot.
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	// snapshotHosts is the number of hosts that each snapshot is stored on.
+	snapshotHosts = build.Select(build.Var{
+		Dev:      2,
+		Standard: 3,
+		Testing:  1,
+	}).(int)
+
+	// snapshotInterval is how often a snapshot of the renter is created, and
+	// snapshotCheckInterval how often the renter checks whether a snapshot
+	// is due.
+	snapshotInterval = build.Select(build.Var{
+		Dev:      time.Hour,
+		Standard: 24 * time.Hour,
+		Testing:  24 * time.Hour,
+	}).(time.Duration)
+	snapshotCheckInterval = build.Select(build.Var{
+		Dev:      time.Minute,
+		Standard: 30 * time.Minute,
+		Testing:  time.Hour,
+	}).(time.Duration)
+
+	// snapshotsKept is the number of snapshots whose sectors are kept on the
+	// hosts. The sectors of older snapshots are deleted.
+	snapshotsKept = 2
+
+	// snapshotKeySpecifier separates the key of snapshot records from the
+	// other keys derived from the master key.
+	snapshotKeySpecifier = types.Specifier{'s', 'n', 'a', 'p', 's', 'h', 'o', 't', ' ', 'k', 'e', 'y'}
+
+	errNoSnapshot           = errors.New("no snapshot of the renter was found in the wallet's transactions")
+	errNoSnapshotHosts      = errors.New("snapshot could not be stored on any host")
+	errSnapshotInProgress   = errors.New("a snapshot is already being created")
+	errSnapshotWalletLocked = errors.New("wallet must be unlocked to create a snapshot")
+)
+
+type (
+	// snapshotLocation is a host that stores a snapshot, along with the
+	// contract that the snapshot is stored in. The secret key of the
+	// contract is needed to recover the contract from the host.
+	snapshotLocation struct {
+		HostPublicKey types.SiaPublicKey
+		ContractID    types.FileContractID
+		SecretKey     crypto.SecretKey
+		Roots         []crypto.Hash
+	}
+
+	// snapshotRecord locates a snapshot. It is stored, encrypted, in the
+	// arbitrary data of a transaction. Size is the size of the encrypted
+	// snapshot, which is padded to a whole number of sectors.
+	snapshotRecord struct {
+		Timestamp types.Timestamp
+		Size      uint64
+		NumFiles  uint64
+		Locations []snapshotLocation
+	}
+
+	// snapshot is a snapshot whose record was stored in a transaction.
+	snapshot struct {
+		TransactionID types.TransactionID
+		Record        snapshotRecord
+	}
+)
+
+// deriveSnapshotKey derives the key that snapshot records are encrypted with
+// from the renter's master key.
+func deriveSnapshotKey(masterKey crypto.TwofishKey) crypto.TwofishKey {
+	return crypto.TwofishKey(crypto.HashAll(snapshotKeySpecifier, masterKey))
+}
+
+// encodeSnapshotRecord returns the arbitrary data that holds rec. The data is
+// prefixed as non-Sia data, so that the transaction is standard, and is
+// otherwise indistinguishable from random data.
+func encodeSnapshotRecord(key crypto.TwofishKey, rec snapshotRecord) []byte {
+	return append(modules.PrefixNonSia[:], key.EncryptBytes(encoding.Marshal(rec))...)
+}
+
+// findSnapshots returns the snapshots whose records are in txns, most recent
+// first. Arbitrary data that cannot be decrypted with key is ignored.
+func findSnapshots(key crypto.TwofishKey, txns []modules.ProcessedTransaction) []snapshot {
+	var snapshots []snapshot
+	for i := len(txns) - 1; i >= 0; i-- {
+		for _, arb := range txns[i].Transaction.ArbitraryData {
+			if !bytes.HasPrefix(arb, modules.PrefixNonSia[:]) {
+				continue
+			}
+			plaintext, err := key.DecryptBytes(arb[types.SpecifierLen:])
+			if err != nil {
+				continue
+			}
+			var rec snapshotRecord
+			if err := encoding.Unmarshal(plaintext, &rec); err != nil {
+				continue
+			}
+			snapshots = append(snapshots, snapshot{
+				TransactionID: txns[i].TransactionID,
+				Record:        rec,
+			})
+		}
+	}
+	return snapshots
+}
+
+// info returns the information of a snapshot.
+func (s snapshot) info() modules.RenterSnapshot {
+	rs := modules.RenterSnapshot{
+		TransactionID: s.TransactionID,
+		Timestamp:     s.Record.Timestamp,
+		Size:          s.Record.Size,
+		NumFiles:      s.Record.NumFiles,
+	}
+	for _, loc := range s.Record.Locations {
+		rs.Hosts = append(rs.Hosts, loc.HostPublicKey)
+	}
+	return rs
+}
+
+// Snapshots returns the snapshots that the renter has created or recovered,
+// most recent first.
+func (r *Renter) Snapshots() []modules.RenterSnapshot {
+	lockID := r.mu.RLock()
+	defer r.mu.RUnlock(lockID)
+	snapshots := make([]modules.RenterSnapshot, len(r.snapshots))
+	for i := range r.snapshots {
+		snapshots[i] = r.snapshots[len(r.snapshots)-i-1].info()
+	}
+	return snapshots
+}
+
+// managedUploadSnapshot uploads the sectors of a snapshot to the hosts of up
+// to snapshotHosts contracts, returning the locations of the snapshot.
+func (r *Renter) managedUploadSnapshot(sectors [][]byte) []snapshotLocation {
+	var locations []snapshotLocation
+	for _, contract := range r.hostContractor.Contracts() {
+		if len(locations) == snapshotHosts {
+			break
+		} else if r.hostContractor.IsOffline(contract.ID) {
+			continue
+		}
+		editor, err := r.hostContractor.Editor(contract.ID, r.tg.StopChan())
+		if err != nil {
+			r.log.Debugln("Unable to open an editor to store a snapshot:", err)
+			continue
+		}
+		loc := snapshotLocation{
+			HostPublicKey: contract.HostPublicKey,
+			ContractID:    contract.ID,
+			SecretKey:     contract.SecretKey,
+		}
+		for _, sector := range sectors {
+			root, err := editor.Upload(sector)
+			if err != nil {
+				r.log.Debugln("Unable to store a snapshot on", contract.NetAddress, err)
+				break
+			}
+			loc.Roots = append(loc.Roots, root)
+		}
+		// Remove the sectors of an incomplete snapshot from the host.
+		if len(loc.Roots) != len(sectors) {
+			for _, root := range loc.Roots {
+				editor.Delete(root)
+			}
+			editor.Close()
+			continue
+		}
+		editor.Close()
+		locations = append(locations, loc)
+	}
+	return locations
+}
+
+// managedDeleteSnapshot deletes the sectors of a snapshot from its hosts.
+// Errors are logged, as the sectors only cost storage until the contracts
+// expire.
+func (r *Renter) managedDeleteSnapshot(s snapshot) {
+	for _, loc := range s.Record.Locations {
+		editor, err := r.hostContractor.Editor(loc.ContractID, r.tg.StopChan())
+		if err != nil {
+			r.log.Debugln("Unable to open an editor to delete a snapshot:", err)
+			continue
+		}
+		for _, root := range loc.Roots {
+			if err := editor.Delete(root); err != nil {
+				r.log.Debugln("Unable to delete a snapshot sector:", err)
+				break
+			}
+		}
+		editor.Close()
+	}
+}
+
+// managedPostSnapshotRecord adds rec to the arbitrary data of a transaction
+// funded by the wallet, returning the ID of the transaction.
+func (r *Renter) managedPostSnapshotRecord(masterKey crypto.TwofishKey, rec snapshotRecord) (types.TransactionID, error) {
+	arb := encodeSnapshotRecord(deriveSnapshotKey(masterKey), rec)
+	txnBuilder := r.wallet.StartTransaction()
+	_, fee := r.tpool.FeeEstimation()
+	fee = fee.Mul64(uint64(len(arb)) + 400) // Estimated size (in bytes) of the rest of the transaction.
+	if err := txnBuilder.FundSiacoins(fee); err != nil {
+		txnBuilder.Drop()
+		return types.TransactionID{}, err
+	}
+	txnBuilder.AddMinerFee(fee)
+	txnBuilder.AddArbitraryData(arb)
+	txnSet, err := txnBuilder.Sign(true)
+	if err != nil {
+		txnBuilder.Drop()
+		return types.TransactionID{}, err
+	}
+	if err := r.tpool.AcceptTransactionSet(txnSet); err != nil {
+		txnBuilder.Drop()
+		return types.TransactionID{}, err
+	}
+	txid := txnSet[len(txnSet)-1].ID()
+	if err := r.wallet.SetTransactionMemo(txid, "renter snapshot"); err != nil {
+		r.log.Debugln("Unable to label the transaction of a snapshot:", err)
+	}
+	return txid, nil
+}
+
+// CreateSnapshot stores a snapshot of the renter's files, contracts and
+// allowance on its hosts, and records its location in a transaction, so that
+// the renter can be recovered from the wallet seed with RecoverSnapshot. The
+// wallet must be unlocked to pay the fee of the transaction.
+func (r *Renter) CreateSnapshot() (modules.RenterSnapshot, error) {
+	if err := r.tg.Add(); err != nil {
+		return modules.RenterSnapshot{}, err
+	}
+	defer r.tg.Done()
+	if !r.wallet.Unlocked() {
+		return modules.RenterSnapshot{}, errSnapshotWalletLocked
+	}
+	lockID := r.mu.Lock()
+	if r.snapshotting {
+		r.mu.Unlock(lockID)
+		return modules.RenterSnapshot{}, errSnapshotInProgress
+	}
+	r.snapshotting = true
+	r.mu.Unlock(lockID)
+	defer func() {
+		lockID := r.mu.Lock()
+		r.snapshotting = false
+		r.mu.Unlock(lockID)
+	}()
+
+	masterKey, err := r.managedMasterKey()
+	if err != nil {
+		return modules.RenterSnapshot{}, err
+	}
+	b, err := r.managedBackup()
+	if err != nil {
+		return modules.RenterSnapshot{}, err
+	}
+	data, err := json.Marshal(b)
+	if err != nil {
+		return modules.RenterSnapshot{}, err
+	}
+	ciphertext := deriveBackupKey(masterKey).EncryptBytes(data)
+
+	// Split the snapshot into sectors, padding the last one.
+	var sectors [][]byte
+	for i := uint64(0); i < uint64(len(ciphertext)); i += modules.SectorSize {
+		sector := make([]byte, modules.SectorSize)
+		copy(sector, ciphertext[i:])
+		sectors = append(sectors, sector)
+	}
+	locations := r.managedUploadSnapshot(sectors)
+	if len(locations) == 0 {
+		return modules.RenterSnapshot{}, errNoSnapshotHosts
+	}
+
+	rec := snapshotRecord{
+		Timestamp: types.CurrentTimestamp(),
+		Size:      uint64(len(ciphertext)),
+		NumFiles:  uint64(len(b.Paths)),
+		Locations: locations,
+	}
+	txid, err := r.managedPostSnapshotRecord(masterKey, rec)
+	if err != nil {
+		s := snapshot{Record: rec}
+		r.managedDeleteSnapshot(s)
+		return modules.RenterSnapshot{}, errors.New("could not record the location of the snapshot: " + err.Error())
+	}
+	s := snapshot{TransactionID: txid, Record: rec}
+
+	lockID = r.mu.Lock()
+	r.snapshots = append(r.snapshots, s)
+	var expired []snapshot
+	if len(r.snapshots) > snapshotsKept {
+		expired = append(expired, r.snapshots[:len(r.snapshots)-snapshotsKept]...)
+		r.snapshots = append([]snapshot(nil), r.snapshots[len(r.snapshots)-snapshotsKept:]...)
+	}
+	err = r.saveSync()
+	r.mu.Unlock(lockID)
+	for _, old := range expired {
+		r.managedDeleteSnapshot(old)
+	}
+	r.log.Printf("INFO: stored a snapshot of %v files on %v hosts", rec.NumFiles, len(locations))
+	return s.info(), err
+}
+
+// managedDownloadSnapshot downloads the encrypted data of a snapshot from one
+// of its hosts. Contracts that the renter does not track are recovered from
+// their hosts, and returned so that they can be restored along with the
+// snapshot.
+func (r *Renter) managedDownloadSnapshot(rec snapshotRecord) ([]byte, map[types.FileContractID]modules.RenterContract, error) {
+	recovered := make(map[types.FileContractID]modules.RenterContract)
+	var err error
+	for _, loc := range rec.Locations {
+		var sectors [][]byte
+		sectors, err = r.managedDownloadSnapshotSectors(loc, recovered)
+		if err != nil {
+			r.log.Debugln("Unable to download a snapshot:", err)
+			continue
+		}
+		ciphertext := bytes.Join(sectors, nil)
+		if uint64(len(ciphertext)) < rec.Size {
+			err = errors.New("snapshot is shorter than its record")
+			continue
+		}
+		return ciphertext[:rec.Size], recovered, nil
+	}
+	return nil, nil, err
+}
+
+// managedDownloadSnapshotSectors downloads the sectors of a snapshot from the
+// host of loc. If the contract of loc is not tracked, it is recovered from the
+// host and added to recovered.
+func (r *Renter) managedDownloadSnapshotSectors(loc snapshotLocation, recovered map[types.FileContractID]modules.RenterContract) ([][]byte, error) {
+	id := r.hostContractor.ResolveID(loc.ContractID)
+	for _, contract := range r.hostContractor.Contracts() {
+		if contract.ID != id {
+			continue
+		}
+		downloader, err := r.hostContractor.Downloader(id, r.tg.StopChan())
+		if err != nil {
+			return nil, err
+		}
+		defer downloader.Close()
+		var sectors [][]byte
+		for _, root := range loc.Roots {
+			sector, err := downloader.Sector(root)
+			if err != nil {
+				return nil, err
+			}
+			r.managedRecordDownload(id, "")
+			sectors = append(sectors, sector)
+		}
+		return sectors, nil
+	}
+
+	contract, sectors, err := r.hostContractor.RecoverContract(modules.RenterContract{
+		ID:            loc.ContractID,
+		HostPublicKey: loc.HostPublicKey,
+		SecretKey:     loc.SecretKey,
+	}, loc.Roots, r.tg.StopChan())
+	if err != nil {
+		return nil, err
+	}
+	recovered[loc.ContractID] = contract
+	return sectors, nil
+}
+
+// managedUpdateSnapshotContracts brings the contracts of a snapshot that
+// store the snapshot up to date. The contracts in a snapshot predate the
+// upload of the snapshot itself, so their most recent revisions are
+// recovered from their hosts, and the roots of the snapshot are added to
+// them.
+func (r *Renter) managedUpdateSnapshotContracts(b *renterBackup, rec snapshotRecord, recovered map[types.FileContractID]modules.RenterContract) {
+	// Contracts that are still tracked are kept up to date by the
+	// contractor, and are not restored.
+	tracked := make(map[types.FileContractID]struct{})
+	for _, contract := range r.hostContractor.Contracts() {
+		tracked[contract.ID] = struct{}{}
+	}
+	for i, contract := range b.Contracts {
+		if _, ok := tracked[r.hostContractor.ResolveID(contract.ID)]; ok {
+			continue
+		}
+		for _, loc := range rec.Locations {
+			if loc.ContractID != contract.ID {
+				continue
+			}
+			rc, ok := recovered[contract.ID]
+			if !ok {
+				var err error
+				rc, _, err = r.hostContractor.RecoverContract(contract, nil, r.tg.StopChan())
+				if err != nil {
+					r.log.Println("WARN: could not recover the most recent revision of a contract:", err)
+					break
+				}
+			}
+			contract.MerkleRoots = append(contract.MerkleRoots, loc.Roots...)
+			if uint64(len(contract.MerkleRoots))*modules.SectorSize != rc.LastRevision.NewFileSize {
+				r.log.Println("WARN: contract", contract.ID, "was revised after the snapshot was created; sectors uploaded since then cannot be recovered")
+			}
+			contract.NetAddress = rc.NetAddress
+			contract.LastRevision = rc.LastRevision
+			contract.LastRevisionTxn = rc.LastRevisionTxn
+			contract.DownloadSpending = contract.DownloadSpending.Add(rc.DownloadSpending)
+			b.Contracts[i] = contract
+			break
+		}
+	}
+}
+
+// RecoverSnapshot finds the most recent snapshot of the renter among the
+// transactions of the wallet, and adds its files, contracts and allowance to
+// the renter. Only the wallet seed is needed: the wallet must have been
+// restored from the seed of the renter that created the snapshot, and the
+// blockchain must be synced so that the hosts of the snapshot are known. The
+// paths of the restored files are returned.
+func (r *Renter) RecoverSnapshot() ([]string, error) {
+	if err := r.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer r.tg.Done()
+	masterKey, err := r.managedMasterKey()
+	if err != nil {
+		return nil, err
+	}
+	txns, err := r.wallet.Transactions(0, r.cs.Height())
+	if err != nil {
+		return nil, err
+	}
+	snapshots := findSnapshots(deriveSnapshotKey(masterKey), txns)
+	if len(snapshots) == 0 {
+		return nil, errNoSnapshot
+	}
+
+	// Restore the most recent snapshot that can be downloaded.
+	for _, s := range snapshots {
+		ciphertext, recovered, err := r.managedDownloadSnapshot(s.Record)
+		if err != nil {
+			r.log.Println("WARN: could not download the snapshot recorded in", s.TransactionID, err)
+			continue
+		}
+		data, err := deriveBackupKey(masterKey).DecryptBytes(ciphertext)
+		if err != nil {
+			return nil, errBackupKey
+		}
+		var b renterBackup
+		if err := json.Unmarshal(data, &b); err != nil {
+			return nil, err
+		}
+		r.managedUpdateSnapshotContracts(&b, s.Record, recovered)
+		names, err := r.managedRestore(b)
+		if err != nil {
+			return nil, err
+		}
+
+		// Keep track of the snapshot, so that its sectors are deleted once
+		// newer snapshots have been created.
+		lockID := r.mu.Lock()
+		known := false
+		for _, existing := range r.snapshots {
+			known = known || existing.TransactionID == s.TransactionID
+		}
+		if !known {
+			r.snapshots = append(r.snapshots, s)
+		}
+		err = r.saveSync()
+		r.mu.Unlock(lockID)
+		return names, err
+	}
+	return nil, errors.New("none of the snapshots could be downloaded")
+}
+
+// threadedSnapshotLoop creates a snapshot of the renter whenever the most
+// recent snapshot is older than snapshotInterval. Snapshots are only created
+// while the renter has contracts, the wallet is unlocked and the renter is not
+// paused.
+func (r *Renter) threadedSnapshotLoop() {
+	for {
+		select {
+		case <-r.tg.StopChan():
+			return
+		case <-time.After(snapshotCheckInterval):
+		}
+
+		lockID := r.mu.RLock()
+		var last types.Timestamp
+		if len(r.snapshots) > 0 {
+			last = r.snapshots[len(r.snapshots)-1].Record.Timestamp
+		}
+		r.mu.RUnlock(lockID)
+		if time.Since(time.Unix(int64(last), 0)) < snapshotInterval {
+			continue
+		} else if len(r.hostContractor.Contracts()) == 0 || !r.wallet.Unlocked() || r.Paused() {
+			continue
+		}
+		if _, err := r.CreateSnapshot(); err != nil {
+			r.log.Println("WARN: could not create a snapshot:", err)
+		}
+	}
+}
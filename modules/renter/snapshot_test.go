@@ -0,0 +1,62 @@
+package renter
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+	"github.com/NebulousLabs/fastrand"
+)
+
+// TestFindSnapshots checks that snapshot records are found among the
+// transactions of a wallet, most recent first, and that the arbitrary data of
+// other transactions is ignored.
+func TestFindSnapshots(t *testing.T) {
+	var masterKey crypto.TwofishKey
+	fastrand.Read(masterKey[:])
+	key := deriveSnapshotKey(masterKey)
+
+	sk, pk := crypto.GenerateKeyPair()
+	rec := func(ts types.Timestamp) snapshotRecord {
+		return snapshotRecord{
+			Timestamp: ts,
+			Size:      1000,
+			NumFiles:  3,
+			Locations: []snapshotLocation{{
+				HostPublicKey: types.SiaPublicKey{Algorithm: types.SignatureEd25519, Key: pk[:]},
+				ContractID:    types.FileContractID{1},
+				SecretKey:     sk,
+				Roots:         []crypto.Hash{{2}, {3}},
+			}},
+		}
+	}
+	var otherKey crypto.TwofishKey
+	fastrand.Read(otherKey[:])
+	txns := []modules.ProcessedTransaction{
+		{TransactionID: types.TransactionID{1}, Transaction: types.Transaction{ArbitraryData: [][]byte{encodeSnapshotRecord(key, rec(10))}}},
+		{TransactionID: types.TransactionID{2}, Transaction: types.Transaction{ArbitraryData: [][]byte{encodeSnapshotRecord(otherKey, rec(20))}}},
+		{TransactionID: types.TransactionID{3}, Transaction: types.Transaction{ArbitraryData: [][]byte{append(modules.PrefixNonSia[:], fastrand.Bytes(100)...), modules.PrefixNonSia[:]}}},
+		{TransactionID: types.TransactionID{4}},
+		{TransactionID: types.TransactionID{5}, Transaction: types.Transaction{ArbitraryData: [][]byte{encodeSnapshotRecord(key, rec(30))}}},
+	}
+
+	snapshots := findSnapshots(key, txns)
+	if len(snapshots) != 2 {
+		t.Fatal("expected 2 snapshots, got", len(snapshots))
+	}
+	if snapshots[0].TransactionID != txns[4].TransactionID || snapshots[0].Record.Timestamp != 30 {
+		t.Fatal("most recent snapshot is not first:", snapshots[0])
+	}
+	s := snapshots[1]
+	if s.TransactionID != txns[0].TransactionID || s.Record.Timestamp != 10 || s.Record.Size != 1000 || s.Record.NumFiles != 3 {
+		t.Fatal("wrong snapshot:", s)
+	}
+	loc := s.Record.Locations[0]
+	if len(s.Record.Locations) != 1 || loc.SecretKey != sk || loc.ContractID != (types.FileContractID{1}) || len(loc.Roots) != 2 || loc.Roots[1] != (crypto.Hash{3}) {
+		t.Fatal("wrong snapshot location:", loc)
+	}
+	if info := s.info(); len(info.Hosts) != 1 || info.Hosts[0].String() != loc.HostPublicKey.String() {
+		t.Fatal("wrong snapshot info:", info)
+	}
+}
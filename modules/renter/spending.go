@@ -0,0 +1,203 @@
+package renter
+
+// spending.go reports where the money of the renter's contracts went. The
+// contracts only record how much they have spent on storage, uploads and
+// downloads, so the spending of a contract is attributed to files by the
+// number of sectors that each file stores in the contract, and by the number
+// of sectors that were downloaded from the contract for each file.
+
+import (
+	"sort"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// downloadRecord is the number of sectors that have been downloaded from a
+// contract for a file. Sectors that were not downloaded for a file, such as
+// those of snapshots and of files that have since been deleted, are recorded
+// with an empty SiaPath.
+type downloadRecord struct {
+	ContractID types.FileContractID
+	SiaPath    string
+	Sectors    uint64
+}
+
+// recordDownload records that a sector has been downloaded from the contract
+// with the given ID for the file at siapath. The caller must hold the renter
+// lock.
+func (r *Renter) recordDownload(id types.FileContractID, siapath string) {
+	sectors, exists := r.downloadedSectors[id]
+	if !exists {
+		sectors = make(map[string]uint64)
+		r.downloadedSectors[id] = sectors
+	}
+	sectors[siapath]++
+	r.unsavedDownloads = true
+}
+
+// managedRecordDownload records that a sector has been downloaded from the
+// contract with the given ID for the file at siapath.
+func (r *Renter) managedRecordDownload(id types.FileContractID, siapath string) {
+	lockID := r.mu.Lock()
+	r.recordDownload(id, siapath)
+	r.mu.Unlock(lockID)
+}
+
+// moveDownloadRecords moves the downloads recorded for the file at oldPath to
+// newPath. The caller must hold the renter lock.
+func (r *Renter) moveDownloadRecords(oldPath, newPath string) {
+	for _, sectors := range r.downloadedSectors {
+		if n, exists := sectors[oldPath]; exists {
+			delete(sectors, oldPath)
+			sectors[newPath] += n
+			r.unsavedDownloads = true
+		}
+	}
+}
+
+// downloadRecords returns the recorded downloads in the form that they are
+// persisted. The caller must hold the renter lock.
+func (r *Renter) downloadRecords() []downloadRecord {
+	var records []downloadRecord
+	for id, sectors := range r.downloadedSectors {
+		for siapath, n := range sectors {
+			records = append(records, downloadRecord{ContractID: id, SiaPath: siapath, Sectors: n})
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].ContractID != records[j].ContractID {
+			return records[i].ContractID.String() < records[j].ContractID.String()
+		}
+		return records[i].SiaPath < records[j].SiaPath
+	})
+	return records
+}
+
+// managedPruneDownloadRecords removes the downloads recorded for contracts
+// that have been renewed, as the spending of a renewed contract is no longer
+// reported, and saves the recorded downloads if they have changed.
+func (r *Renter) managedPruneDownloadRecords() {
+	lockID := r.mu.Lock()
+	defer r.mu.Unlock(lockID)
+	for id := range r.downloadedSectors {
+		if r.hostContractor.ResolveID(id) != id {
+			delete(r.downloadedSectors, id)
+			r.unsavedDownloads = true
+		}
+	}
+	if !r.unsavedDownloads {
+		return
+	}
+	if err := r.saveSync(); err != nil {
+		r.log.Println("WARN: could not save the recorded downloads:", err)
+	}
+}
+
+// shareOf returns the share of c that n out of total sectors account for.
+func shareOf(c types.Currency, n, total uint64) types.Currency {
+	if total == 0 {
+		return types.ZeroCurrency
+	}
+	return c.Mul64(n).Div64(total)
+}
+
+// SpendingReport returns the spending of each contract of the renter, and
+// the share of it that is attributed to each file.
+func (r *Renter) SpendingReport() modules.RenterSpendingReport {
+	contracts := r.AllContracts()
+
+	lockID := r.mu.RLock()
+	defer r.mu.RUnlock(lockID)
+
+	// Count the sectors that each file stores in each contract. Pieces that
+	// are still recorded under the ID of a renewed contract are counted for
+	// its renewal.
+	storedSectors := make(map[types.FileContractID]map[string]uint64)
+	files := make(map[string]*modules.FileSpending)
+	for name, f := range r.files {
+		files[name] = &modules.FileSpending{SiaPath: name}
+		f.mu.RLock()
+		for id, fc := range f.contracts {
+			id = r.hostContractor.ResolveID(id)
+			if storedSectors[id] == nil {
+				storedSectors[id] = make(map[string]uint64)
+			}
+			storedSectors[id][name] += uint64(len(fc.Pieces))
+		}
+		f.mu.RUnlock()
+	}
+
+	var report modules.RenterSpendingReport
+	for _, c := range contracts {
+		cs := modules.ContractSpending{
+			ID:             c.ID,
+			NetAddress:     c.NetAddress,
+			HostPublicKey:  c.HostPublicKey,
+			StartHeight:    c.StartHeight,
+			EndHeight:      c.EndHeight(),
+			Sectors:        uint64(len(c.MerkleRoots)),
+			Fees:           c.TxnFee.Add(c.SiafundFee).Add(c.ContractFee),
+			RemainingFunds: c.RenterFunds(),
+			TotalCost:      c.TotalCost,
+			SpendingBreakdown: modules.SpendingBreakdown{
+				StorageSpending:  c.StorageSpending,
+				UploadSpending:   c.UploadSpending,
+				DownloadSpending: c.DownloadSpending,
+			},
+		}
+		report.Contracts = append(report.Contracts, cs)
+		unattributed := cs.SpendingBreakdown
+
+		// The sectors of a file may be counted more than once if a piece was
+		// uploaded again, so the sectors of the files may add up to more
+		// than the sectors of the contract.
+		total := cs.Sectors
+		var stored uint64
+		for _, n := range storedSectors[c.ID] {
+			stored += n
+		}
+		if stored > total {
+			total = stored
+		}
+		for name, n := range storedSectors[c.ID] {
+			storage := shareOf(c.StorageSpending, n, total)
+			upload := shareOf(c.UploadSpending, n, total)
+			fs := files[name]
+			fs.Sectors += n
+			fs.StorageSpending = fs.StorageSpending.Add(storage)
+			fs.UploadSpending = fs.UploadSpending.Add(upload)
+			unattributed.StorageSpending = unattributed.StorageSpending.Sub(storage)
+			unattributed.UploadSpending = unattributed.UploadSpending.Sub(upload)
+		}
+
+		var downloaded uint64
+		for _, n := range r.downloadedSectors[c.ID] {
+			downloaded += n
+		}
+		for name, n := range r.downloadedSectors[c.ID] {
+			fs, exists := files[name]
+			if !exists {
+				continue
+			}
+			download := shareOf(c.DownloadSpending, n, downloaded)
+			fs.DownloadSpending = fs.DownloadSpending.Add(download)
+			unattributed.DownloadSpending = unattributed.DownloadSpending.Sub(download)
+		}
+
+		report.Unattributed.StorageSpending = report.Unattributed.StorageSpending.Add(unattributed.StorageSpending)
+		report.Unattributed.UploadSpending = report.Unattributed.UploadSpending.Add(unattributed.UploadSpending)
+		report.Unattributed.DownloadSpending = report.Unattributed.DownloadSpending.Add(unattributed.DownloadSpending)
+	}
+	sort.Slice(report.Contracts, func(i, j int) bool {
+		return report.Contracts[i].NetAddress < report.Contracts[j].NetAddress
+	})
+
+	for _, fs := range files {
+		report.Files = append(report.Files, *fs)
+	}
+	sort.Slice(report.Files, func(i, j int) bool {
+		return report.Files[i].SiaPath < report.Files[j].SiaPath
+	})
+	return report
+}
@@ -0,0 +1,137 @@
+package renter
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// spendingContractor is a hostContractor that reports a fixed set of
+// contracts.
+type spendingContractor struct {
+	hostContractor
+	contracts []modules.RenterContract
+	renewals  map[types.FileContractID]types.FileContractID
+}
+
+func (c spendingContractor) AllContracts() []modules.RenterContract { return c.contracts }
+func (c spendingContractor) ResolveID(id types.FileContractID) types.FileContractID {
+	if newID, ok := c.renewals[id]; ok {
+		return newID
+	}
+	return id
+}
+
+// TestSpendingReport checks that the spending of a contract is attributed to
+// files by the sectors that they store in the contract and the sectors that
+// were downloaded for them, and that the attribution follows renamed and
+// deleted files.
+func TestSpendingReport(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+	r := rt.renter
+
+	id := types.FileContractID{1}
+	contract := modules.RenterContract{
+		ID:               id,
+		NetAddress:       "foo:1234",
+		MerkleRoots:      make(modules.MerkleRootSet, 4),
+		StorageSpending:  types.NewCurrency64(400),
+		UploadSpending:   types.NewCurrency64(80),
+		DownloadSpending: types.NewCurrency64(60),
+		ContractFee:      types.NewCurrency64(7),
+		LastRevision: types.FileContractRevision{
+			NewValidProofOutputs: []types.SiacoinOutput{{Value: types.NewCurrency64(500)}, {}},
+		},
+	}
+	hc := spendingContractor{
+		hostContractor: r.hostContractor,
+		contracts:      []modules.RenterContract{contract},
+		renewals:       make(map[types.FileContractID]types.FileContractID),
+	}
+	r.hostContractor = hc
+
+	// "a" stores 2 of the 4 sectors of the contract and "b" stores 1. One
+	// sector was downloaded for each file, and one for a snapshot.
+	rsc, _ := NewRSCode(1, 1)
+	lockID := r.mu.Lock()
+	for name, pieces := range map[string]int{"a": 2, "b": 1} {
+		f := newFile(name, rsc, 100, 150)
+		f.contracts[id] = fileContract{ID: id, Pieces: make([]pieceData, pieces)}
+		r.files[name] = f
+		r.recordDownload(id, name)
+	}
+	r.recordDownload(id, "")
+	r.mu.Unlock(lockID)
+
+	breakdown := func(storage, upload, download uint64) modules.SpendingBreakdown {
+		return modules.SpendingBreakdown{
+			StorageSpending:  types.NewCurrency64(storage),
+			UploadSpending:   types.NewCurrency64(upload),
+			DownloadSpending: types.NewCurrency64(download),
+		}
+	}
+	equal := func(a, b modules.SpendingBreakdown) bool {
+		return a.StorageSpending.Equals(b.StorageSpending) && a.UploadSpending.Equals(b.UploadSpending) && a.DownloadSpending.Equals(b.DownloadSpending)
+	}
+	checkFile := func(report modules.RenterSpendingReport, siapath string, sectors uint64, sb modules.SpendingBreakdown) {
+		t.Helper()
+		for _, fs := range report.Files {
+			if fs.SiaPath == siapath {
+				if fs.Sectors != sectors || !equal(fs.SpendingBreakdown, sb) {
+					t.Fatal("wrong spending of", siapath, fs)
+				}
+				return
+			}
+		}
+		t.Fatal("no spending reported for", siapath)
+	}
+
+	report := r.SpendingReport()
+	if len(report.Contracts) != 1 || len(report.Files) != 2 {
+		t.Fatal("wrong report:", report)
+	}
+	cs := report.Contracts[0]
+	if cs.ID != id || cs.Sectors != 4 || !equal(cs.SpendingBreakdown, breakdown(400, 80, 60)) || !cs.Fees.Equals64(7) || !cs.RemainingFunds.Equals64(500) {
+		t.Fatal("wrong contract spending:", cs)
+	}
+	checkFile(report, "a", 2, breakdown(200, 40, 20))
+	checkFile(report, "b", 1, breakdown(100, 20, 20))
+	if !equal(report.Unattributed, breakdown(100, 20, 20)) {
+		t.Fatal("wrong unattributed spending:", report.Unattributed)
+	}
+
+	// The spending of a renamed file follows it, and the spending of a
+	// deleted file is no longer attributed.
+	if err := r.RenameFile("a", "c"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.DeleteFile("b"); err != nil {
+		t.Fatal(err)
+	}
+	report = r.SpendingReport()
+	if len(report.Files) != 1 {
+		t.Fatal("wrong files:", report.Files)
+	}
+	checkFile(report, "c", 2, breakdown(200, 40, 20))
+	if !equal(report.Unattributed, breakdown(200, 40, 40)) {
+		t.Fatal("wrong unattributed spending:", report.Unattributed)
+	}
+
+	// The downloads of a renewed contract are forgotten.
+	hc.renewals[id] = types.FileContractID{2}
+	r.managedPruneDownloadRecords()
+	lockID = r.mu.RLock()
+	records := r.downloadRecords()
+	r.mu.RUnlock(lockID)
+	if len(records) != 0 {
+		t.Fatal("downloads of a renewed contract were kept:", records)
+	}
+}
@@ -0,0 +1,308 @@
+package renter
+
+// transfers.go tracks the progress of uploads and downloads, and reports it
+// to the transfer subscribers of the renter.
+
+import (
+	"errors"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// speedWindow is the period over which the current speed of a transfer is
+// measured.
+const speedWindow = 30 * time.Second
+
+var errUploadFileDeleted = errors.New("upload canceled because the file was deleted")
+
+type (
+	// progressSample is the number of bytes that a transfer had completed at
+	// a point in time.
+	progressSample struct {
+		time  time.Time
+		bytes uint64
+	}
+
+	// transferProgress tracks the speed of a transfer, and the pieces that
+	// each host has transferred for it. It is protected by the lock of the
+	// transfer that holds it.
+	transferProgress struct {
+		hosts   map[modules.NetAddress]*modules.TransferHostInfo
+		samples []progressSample
+	}
+
+	// An upload is a file upload that has been started by the renter. The
+	// upload of a file on disk completes once every piece of the file has
+	// been uploaded, and the upload of a stream once the last chunk of the
+	// stream can be recovered. uploads are protected by the renter lock.
+	upload struct {
+		id        uint64
+		file      *file
+		source    string
+		startTime time.Time
+		streaming bool
+
+		complete bool
+		err      error
+		pieces   uint64
+		progress transferProgress
+	}
+)
+
+// newTransferProgress returns the progress of a transfer that has completed
+// the given number of bytes.
+func newTransferProgress(start time.Time, bytes uint64) transferProgress {
+	return transferProgress{
+		hosts:   make(map[modules.NetAddress]*modules.TransferHostInfo),
+		samples: []progressSample{{start, bytes}},
+	}
+}
+
+// host returns the status of the pieces that addr has transferred.
+func (tp *transferProgress) host(addr modules.NetAddress) *modules.TransferHostInfo {
+	h, exists := tp.hosts[addr]
+	if !exists {
+		h = &modules.TransferHostInfo{NetAddress: addr}
+		tp.hosts[addr] = h
+	}
+	return h
+}
+
+// addPiece records that addr has transferred a piece, after which the
+// transfer has completed the given number of bytes.
+func (tp *transferProgress) addPiece(addr modules.NetAddress, now time.Time, bytes uint64) {
+	tp.host(addr).Pieces++
+	tp.addSample(now, bytes)
+}
+
+// addSample records that the transfer has completed the given number of
+// bytes, without the help of a host.
+func (tp *transferProgress) addSample(now time.Time, bytes uint64) {
+	tp.samples = append(tp.samples, progressSample{now, bytes})
+	// Keep one sample from before the window, so that the speed is measured
+	// over the whole window.
+	for len(tp.samples) > 2 && now.Sub(tp.samples[1].time) > speedWindow {
+		tp.samples = tp.samples[1:]
+	}
+}
+
+// addFailure records that addr has failed to transfer a piece.
+func (tp *transferProgress) addFailure(addr modules.NetAddress) {
+	tp.host(addr).Failures++
+}
+
+// speed returns the number of bytes transferred per second since the oldest
+// sample.
+func (tp *transferProgress) speed(now time.Time) uint64 {
+	first, last := tp.samples[0], tp.samples[len(tp.samples)-1]
+	elapsed := now.Sub(first.time).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return uint64(float64(last.bytes-first.bytes) / elapsed)
+}
+
+// eta returns the number of seconds needed to transfer the remaining bytes at
+// speed, or 0 if speed is 0.
+func eta(remaining, speed uint64) uint64 {
+	if speed == 0 {
+		return 0
+	}
+	return (remaining + speed - 1) / speed
+}
+
+// hostInfos returns the status of the pieces of each host, sorted by address.
+func (tp *transferProgress) hostInfos() []modules.TransferHostInfo {
+	infos := make([]modules.TransferHostInfo, 0, len(tp.hosts))
+	for _, h := range tp.hosts {
+		infos = append(infos, *h)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].NetAddress < infos[j].NetAddress })
+	return infos
+}
+
+// info returns the information of a download.
+func (d *download) info(now time.Time) modules.DownloadInfo {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	di := modules.DownloadInfo{
+		ID:          d.id,
+		SiaPath:     d.siapath,
+		Destination: d.destination,
+		Filesize:    d.length,
+		Received:    atomic.LoadUint64(&d.atomicDataReceived),
+		StartTime:   d.startTime,
+		Complete:    d.downloadComplete && d.downloadErr == nil,
+		Hosts:       d.progress.hostInfos(),
+	}
+	if d.downloadErr != nil {
+		di.Error = d.downloadErr.Error()
+	}
+	if !d.downloadComplete {
+		di.Speed = d.progress.speed(now)
+		di.ETA = eta(di.Filesize-di.Received, di.Speed)
+	}
+	return di
+}
+
+// info returns the information of an upload, whose file is tracked as tf. The
+// caller must hold the renter lock.
+func (u *upload) info(now time.Time, tf trackedFile) modules.UploadInfo {
+	u.file.mu.RLock()
+	ui := modules.UploadInfo{
+		ID:        u.id,
+		SiaPath:   u.file.name,
+		Source:    u.source,
+		Filesize:  u.file.size,
+		Uploaded:  u.pieces * u.file.pieceSize,
+		Total:     u.file.numChunks() * uint64(u.file.erasureCode.NumPieces()) * u.file.pieceSize,
+		StartTime: u.startTime,
+		Complete:  u.complete,
+		Hosts:     u.progress.hostInfos(),
+		Priority:  tf.Priority,
+		Paused:    tf.Paused,
+	}
+	u.file.mu.RUnlock()
+	if u.err != nil {
+		ui.Error = u.err.Error()
+	}
+	if !u.complete && u.err == nil {
+		ui.Speed = u.progress.speed(now)
+		if ui.Total > ui.Uploaded {
+			ui.ETA = eta(ui.Total-ui.Uploaded, ui.Speed)
+		}
+	}
+	return ui
+}
+
+// newUpload adds an upload of f to the upload queue, returning the event of
+// its start. The caller must hold the renter lock.
+func (r *Renter) newUpload(f *file, source string, streaming bool) modules.TransferEvent {
+	r.lastTransferID++
+	now := time.Now()
+	u := &upload{
+		id:        r.lastTransferID,
+		file:      f,
+		source:    source,
+		startTime: now,
+		streaming: streaming,
+		progress:  newTransferProgress(now, 0),
+	}
+	r.uploadQueue = append(r.uploadQueue, u)
+	ui := u.info(now, r.tracking[f.name])
+	return modules.TransferEvent{Upload: &ui}
+}
+
+// activeUpload returns the upload of f that is in progress, if there is one.
+// The caller must hold the renter lock.
+func (r *Renter) activeUpload(f *file) (*upload, bool) {
+	for i := len(r.uploadQueue) - 1; i >= 0; i-- {
+		u := r.uploadQueue[i]
+		if u.file == f && !u.complete && u.err == nil {
+			return u, true
+		}
+	}
+	return nil, false
+}
+
+// finishUpload marks the active upload of f as completed if err is nil, and
+// as failed otherwise, returning the event of its end. The caller must hold
+// the renter lock.
+func (r *Renter) finishUpload(f *file, err error) (modules.TransferEvent, bool) {
+	u, exists := r.activeUpload(f)
+	if !exists {
+		return modules.TransferEvent{}, false
+	}
+	u.complete = err == nil
+	u.err = err
+	ui := u.info(time.Now(), r.tracking[f.name])
+	return modules.TransferEvent{Upload: &ui}, true
+}
+
+// uploadedPiece records that addr has uploaded a piece of f, returning the
+// event of the progress of the active upload of f. The caller must hold the
+// renter lock.
+func (r *Renter) uploadedPiece(f *file, addr modules.NetAddress) (modules.TransferEvent, bool) {
+	u, exists := r.activeUpload(f)
+	if !exists {
+		return modules.TransferEvent{}, false
+	}
+	u.pieces++
+	now := time.Now()
+	u.progress.addPiece(addr, now, u.pieces*f.pieceSize)
+	// The upload of a stream completes when the stream ends, as the file
+	// grows while it is uploaded.
+	f.mu.RLock()
+	u.complete = !u.streaming && f.uploadProgress() >= 100
+	f.mu.RUnlock()
+	ui := u.info(now, r.tracking[f.name])
+	return modules.TransferEvent{Upload: &ui}, true
+}
+
+// failedPiece records that addr has failed to upload a piece of f. The caller
+// must hold the renter lock.
+func (r *Renter) failedPiece(f *file, addr modules.NetAddress) {
+	if u, exists := r.activeUpload(f); exists {
+		u.progress.addFailure(addr)
+	}
+}
+
+// UploadQueue returns the uploads that have been started since the renter
+// started, most recent first.
+func (r *Renter) UploadQueue() []modules.UploadInfo {
+	lockID := r.mu.RLock()
+	defer r.mu.RUnlock(lockID)
+
+	now := time.Now()
+	uploads := make([]modules.UploadInfo, len(r.uploadQueue))
+	for i := range r.uploadQueue {
+		u := r.uploadQueue[len(r.uploadQueue)-i-1]
+		uploads[i] = u.info(now, r.tracking[u.file.name])
+	}
+	return uploads
+}
+
+// TransferSubscribe adds a subscriber to the list of subscribers that are
+// notified of the progress of uploads and downloads.
+func (r *Renter) TransferSubscribe(subscriber modules.TransferSubscriber) {
+	lockID := r.mu.Lock()
+	r.transferSubscribers = append(r.transferSubscribers, subscriber)
+	r.mu.Unlock(lockID)
+}
+
+// TransferUnsubscribe removes a subscriber from the list of transfer
+// subscribers.
+func (r *Renter) TransferUnsubscribe(subscriber modules.TransferSubscriber) {
+	lockID := r.mu.Lock()
+	defer r.mu.Unlock(lockID)
+	for i := range r.transferSubscribers {
+		if r.transferSubscribers[i] == subscriber {
+			r.transferSubscribers = append(r.transferSubscribers[:i], r.transferSubscribers[i+1:]...)
+			return
+		}
+	}
+}
+
+// managedNotifyTransfer sends an event to the transfer subscribers.
+func (r *Renter) managedNotifyTransfer(event modules.TransferEvent) {
+	lockID := r.mu.RLock()
+	subscribers := append([]modules.TransferSubscriber(nil), r.transferSubscribers...)
+	r.mu.RUnlock(lockID)
+	for _, subscriber := range subscribers {
+		subscriber.ProcessTransferEvent(event)
+	}
+}
+
+// managedNotifyDownload sends the state of d to the transfer subscribers.
+// Only the downloads in the download queue are reported; the downloads of the
+// repair loop have no ID.
+func (r *Renter) managedNotifyDownload(d *download) {
+	if d.id == 0 {
+		return
+	}
+	di := d.info(time.Now())
+	r.managedNotifyTransfer(modules.TransferEvent{Download: &di})
+}
@@ -0,0 +1,150 @@
+package renter
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+	"github.com/NebulousLabs/fastrand"
+)
+
+// TestTransferProgress probes the speed and host tracking of transfers.
+func TestTransferProgress(t *testing.T) {
+	start := time.Now()
+	tp := newTransferProgress(start, 100)
+	if s := tp.speed(start); s != 0 {
+		t.Fatal("expected no speed at the start, got", s)
+	}
+
+	tp.addPiece("foo:1", start.Add(time.Second), 200)
+	tp.addPiece("bar:1", start.Add(2*time.Second), 300)
+	tp.addFailure("bar:1")
+	if s := tp.speed(start.Add(2 * time.Second)); s != 100 {
+		t.Fatal("expected a speed of 100, got", s)
+	}
+	// The speed drops while no pieces are transferred.
+	if s := tp.speed(start.Add(4 * time.Second)); s != 50 {
+		t.Fatal("expected a speed of 50, got", s)
+	}
+	hosts := tp.hostInfos()
+	if len(hosts) != 2 || hosts[0] != (modules.TransferHostInfo{NetAddress: "bar:1", Pieces: 1, Failures: 1}) || hosts[1] != (modules.TransferHostInfo{NetAddress: "foo:1", Pieces: 1}) {
+		t.Fatal("wrong hosts:", hosts)
+	}
+
+	// Samples from before the window are dropped, except for the last one.
+	later := start.Add(speedWindow + 12*time.Second)
+	tp.addPiece("foo:1", later, 400)
+	tp.addPiece("foo:1", later.Add(time.Second), 600)
+	if len(tp.samples) != 3 || tp.samples[0].bytes != 300 {
+		t.Fatal("wrong samples were kept:", tp.samples)
+	}
+	if s := tp.speed(later.Add(time.Second)); s != uint64(300/(speedWindow.Seconds()+11)) {
+		t.Fatal("wrong speed:", s)
+	}
+
+	if eta(1000, 0) != 0 || eta(1000, 300) != 4 || eta(900, 300) != 3 {
+		t.Fatal("wrong ETA")
+	}
+}
+
+// transferRecorder is a transfer subscriber that records the events it
+// receives.
+type transferRecorder chan modules.TransferEvent
+
+func (tr transferRecorder) ProcessTransferEvent(event modules.TransferEvent) {
+	tr <- event
+}
+
+// TestUploadQueue checks that uploads are added to the upload queue, and that
+// subscribers are notified of their progress.
+func TestUploadQueue(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+
+	events := make(transferRecorder, 10)
+	rt.renter.TransferSubscribe(events)
+	nextEvent := func() modules.UploadInfo {
+		select {
+		case event := <-events:
+			if event.Upload == nil {
+				t.Fatal("expected an upload event:", event)
+			}
+			return *event.Upload
+		case <-time.After(10 * time.Second):
+			t.Fatal("no event was received")
+		}
+		return modules.UploadInfo{}
+	}
+
+	source := build.TempDir("renter", t.Name(), "test.dat")
+	if err := ioutil.WriteFile(source, fastrand.Bytes(1000), 0600); err != nil {
+		t.Fatal(err)
+	}
+	ec, err := NewRSCode(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt.renter.hostDB = activeHostsDB{hostDB: rt.renter.hostDB, n: 2}
+	err = rt.renter.Upload(modules.FileUploadParams{Source: source, SiaPath: "foo", ErasureCode: ec, PieceSize: 1 << 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ui := nextEvent()
+	if ui.ID == 0 || ui.SiaPath != "foo" || ui.Source != source || ui.Filesize != 1000 || ui.Total != 2<<10 || ui.Complete {
+		t.Fatal("wrong upload info:", ui)
+	}
+
+	// Upload the pieces of the file by hand.
+	id := rt.renter.mu.Lock()
+	f := rt.renter.files["foo"]
+	for i := uint64(0); i < 2; i++ {
+		f.mu.Lock()
+		f.contracts[types.FileContractID{byte(i)}] = fileContract{Pieces: []pieceData{{Chunk: 0, Piece: i}}}
+		f.mu.Unlock()
+		rt.renter.uploadedPiece(f, "host:1")
+	}
+	rt.renter.mu.Unlock(id)
+	uploads := rt.renter.UploadQueue()
+	if len(uploads) != 1 {
+		t.Fatal("expected 1 upload, got", len(uploads))
+	} else if ui = uploads[0]; !ui.Complete || ui.Uploaded != ui.Total || len(ui.Hosts) != 1 || ui.Hosts[0].Pieces != 2 {
+		t.Fatal("upload did not complete:", ui)
+	}
+
+	// Deleting a file cancels its upload.
+	err = rt.renter.Upload(modules.FileUploadParams{Source: source, SiaPath: "bar", ErasureCode: ec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nextEvent()
+	if err := rt.renter.DeleteFile("bar"); err != nil {
+		t.Fatal(err)
+	}
+	if ui = nextEvent(); ui.SiaPath != "bar" || ui.Error != errUploadFileDeleted.Error() {
+		t.Fatal("upload was not canceled:", ui)
+	}
+	uploads = rt.renter.UploadQueue()
+	if len(uploads) != 2 || uploads[0].SiaPath != "bar" || uploads[1].SiaPath != "foo" {
+		t.Fatal("wrong upload queue:", uploads)
+	}
+
+	// Unsubscribed subscribers are not notified.
+	rt.renter.TransferUnsubscribe(events)
+	if err := rt.renter.Upload(modules.FileUploadParams{Source: source, SiaPath: "baz", ErasureCode: ec}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case event := <-events:
+		t.Fatal("unsubscribed subscriber was notified:", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -3,8 +3,10 @@ package renter
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/crypto"
@@ -12,12 +14,16 @@ import (
 )
 
 var (
+	errChunkUploadTimeout    = errors.New("timed out waiting for a chunk to be uploaded")
 	errInsufficientContracts = errors.New("not enough contracts to upload file")
 	errUploadDirectory       = errors.New("cannot upload directory")
 
-	// Erasure-coded piece size
+	// Erasure-coded piece size. This is the default and the largest piece
+	// size, as an encrypted piece must fit in a sector.
 	pieceSize = modules.SectorSize - crypto.TwofishOverhead
 
+	errBadPieceSize = fmt.Errorf("piece size cannot be larger than %v bytes", pieceSize)
+
 	// defaultDataPieces is the number of data pieces per erasure-coded chunk
 	defaultDataPieces = func() int {
 		switch build.Release {
@@ -83,6 +89,60 @@ func validateSource(sourcePath string) error {
 	return nil
 }
 
+// managedUploadParams checks that a file can be uploaded to up.SiaPath, and
+// fills in any upload params that were not supplied with sensible defaults.
+func (r *Renter) managedUploadParams(up modules.FileUploadParams) (modules.FileUploadParams, error) {
+	// Check for a nickname conflict.
+	lockID := r.mu.RLock()
+	_, exists := r.files[up.SiaPath]
+	isDir := r.dirExists(up.SiaPath)
+	r.mu.RUnlock(lockID)
+	if exists {
+		return up, ErrPathOverload
+	} else if isDir {
+		return up, ErrDirOverload
+	}
+
+	// Use the erasure code of the redundancy class of the file.
+	if up.Class != "" {
+		if up.ErasureCode != nil {
+			return up, errClassWithCode
+		}
+		lockID := r.mu.RLock()
+		rc, exists := r.classes[up.Class]
+		r.mu.RUnlock(lockID)
+		if !exists {
+			return up, errUnknownClass
+		}
+		up.ErasureCode, _ = NewRSCode(rc.DataPieces, rc.ParityPieces)
+	}
+
+	// Fill in any missing upload params with sensible defaults.
+	customParams := up.ErasureCode != nil || up.PieceSize != 0
+	if up.ErasureCode == nil {
+		up.ErasureCode, _ = NewRSCode(defaultDataPieces, defaultParityPieces)
+	}
+	if up.PieceSize == 0 {
+		up.PieceSize = pieceSize
+	} else if up.PieceSize > pieceSize {
+		return up, errBadPieceSize
+	}
+
+	// Check that the file can be recovered from the hosts that are
+	// available, which requires a host for each data piece.
+	if nHosts := len(r.hostDB.ActiveHosts()); customParams && nHosts < up.ErasureCode.MinPieces() {
+		return up, fmt.Errorf("not enough hosts to upload file: got %v, needed %v", nHosts, up.ErasureCode.MinPieces())
+	}
+
+	// Check that we have contracts to upload to. We need at least (data +
+	// parity/2) contracts; since NumPieces = data + parity, we arrive at the
+	// expression below.
+	if nContracts := len(r.hostContractor.Contracts()); nContracts < (up.ErasureCode.NumPieces()+up.ErasureCode.MinPieces())/2 && build.Release != "testing" {
+		return up, fmt.Errorf("not enough contracts to upload file: got %v, needed %v", nContracts, (up.ErasureCode.NumPieces()+up.ErasureCode.MinPieces())/2)
+	}
+	return up, nil
+}
+
 // Upload instructs the renter to start tracking a file. The renter will
 // automatically upload and repair tracked files using a background loop.
 func (r *Renter) Upload(up modules.FileUploadParams) error {
@@ -96,48 +156,180 @@ func (r *Renter) Upload(up modules.FileUploadParams) error {
 		return err
 	}
 
-	// Check for a nickname conflict.
-	lockID := r.mu.RLock()
-	_, exists := r.files[up.SiaPath]
-	r.mu.RUnlock(lockID)
-	if exists {
-		return ErrPathOverload
+	up, err := r.managedUploadParams(up)
+	if err != nil {
+		return err
 	}
-
-	// Fill in any missing upload params with sensible defaults.
 	fileInfo, err := os.Stat(up.Source)
 	if err != nil {
 		return err
 	}
-	if up.ErasureCode == nil {
-		up.ErasureCode, _ = NewRSCode(defaultDataPieces, defaultParityPieces)
-	}
-
-	// Check that we have contracts to upload to. We need at least (data +
-	// parity/2) contracts; since NumPieces = data + parity, we arrive at the
-	// expression below.
-	if nContracts := len(r.hostContractor.Contracts()); nContracts < (up.ErasureCode.NumPieces()+up.ErasureCode.MinPieces())/2 && build.Release != "testing" {
-		return fmt.Errorf("not enough contracts to upload file: got %v, needed %v", nContracts, (up.ErasureCode.NumPieces()+up.ErasureCode.MinPieces())/2)
+	masterKey, err := r.managedMasterKey()
+	if err != nil {
+		return err
 	}
 
 	// Create file object.
-	f := newFile(up.SiaPath, up.ErasureCode, pieceSize, uint64(fileInfo.Size()))
+	f := newFile(up.SiaPath, up.ErasureCode, up.PieceSize, uint64(fileInfo.Size()))
+	f.masterKey = deriveFileKey(masterKey, up.SiaPath)
 	f.mode = uint32(fileInfo.Mode())
 
 	// Add file to renter.
-	lockID = r.mu.Lock()
+	lockID := r.mu.Lock()
 	r.files[up.SiaPath] = f
 	r.tracking[up.SiaPath] = trackedFile{
 		RepairPath: up.Source,
+		Priority:   up.Priority,
+		Class:      up.Class,
 	}
 	r.saveSync()
 	err = r.saveFile(f)
+	event := r.newUpload(f, up.Source, false)
 	r.mu.Unlock(lockID)
 	if err != nil {
 		return err
 	}
+	r.managedNotifyTransfer(event)
 
 	// Send the upload to the repair loop.
 	r.newRepairs <- f
 	return nil
 }
+
+// UploadStreamFromReader uploads the data read from reader as a new file.
+// up.Source is ignored. The data is not written to disk: each chunk is kept in
+// memory until enough of its pieces have been uploaded to recover it, and the
+// next chunk is only read afterwards. Later repairs of the file download the
+// chunks from the hosts. If the upload fails, the file is deleted. Streaming
+// uploads cannot be paused, so they cannot be started while the renter is
+// paused.
+func (r *Renter) UploadStreamFromReader(up modules.FileUploadParams, reader io.Reader) error {
+	// Enforce nickname rules.
+	if err := validateSiapath(up.SiaPath); err != nil {
+		return err
+	}
+	if r.Paused() {
+		return errStreamPaused
+	}
+	up, err := r.managedUploadParams(up)
+	if err != nil {
+		return err
+	}
+	masterKey, err := r.managedMasterKey()
+	if err != nil {
+		return err
+	}
+
+	// Add the empty file to the renter. The file has no repair path, as its
+	// data is never on disk.
+	f := newFile(up.SiaPath, up.ErasureCode, up.PieceSize, 0)
+	f.masterKey = deriveFileKey(masterKey, up.SiaPath)
+	f.mode = defaultFilePerm
+	lockID := r.mu.Lock()
+	if _, exists := r.files[up.SiaPath]; exists {
+		r.mu.Unlock(lockID)
+		return ErrPathOverload
+	}
+	r.files[up.SiaPath] = f
+	r.tracking[up.SiaPath] = trackedFile{Priority: up.Priority, Class: up.Class}
+	event := r.newUpload(f, "", true)
+	r.mu.Unlock(lockID)
+	r.managedNotifyTransfer(event)
+
+	err = r.managedUploadStream(f, reader)
+	if err != nil {
+		lockID = r.mu.Lock()
+		event, _ = r.finishUpload(f, err)
+		r.mu.Unlock(lockID)
+		r.managedNotifyTransfer(event)
+		r.DeleteFile(up.SiaPath)
+		lockID = r.mu.Lock()
+		delete(r.tracking, up.SiaPath)
+		r.saveSync()
+		r.mu.Unlock(lockID)
+		return err
+	}
+
+	lockID = r.mu.Lock()
+	r.saveSync()
+	err = r.saveFile(f)
+	event, _ = r.finishUpload(f, err)
+	r.mu.Unlock(lockID)
+	r.managedNotifyTransfer(event)
+	return err
+}
+
+// managedUploadStream reads the data of f from reader one chunk at a time,
+// growing the file and waiting for each chunk to become recoverable before
+// reading the next one.
+func (r *Renter) managedUploadStream(f *file, reader io.Reader) error {
+	for index := uint64(0); ; index++ {
+		// The chunk is padded with zeroes, like the chunks read from disk.
+		chunk := make([]byte, f.chunkSize())
+		n, err := io.ReadFull(reader, chunk)
+		if err == io.EOF && index > 0 {
+			return nil
+		} else if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+
+		cid := chunkID{index: index, filename: f.name}
+		lockID := r.mu.Lock()
+		r.streamChunks[cid] = chunk
+		f.mu.Lock()
+		f.size += uint64(n)
+		f.mu.Unlock()
+		r.mu.Unlock(lockID)
+
+		err = r.managedWaitForStreamChunk(f, index)
+		lockID = r.mu.Lock()
+		delete(r.streamChunks, cid)
+		r.mu.Unlock(lockID)
+		if err != nil {
+			return err
+		}
+
+		// A partial chunk is the last chunk of the file.
+		if uint64(n) < f.chunkSize() {
+			return nil
+		}
+	}
+}
+
+// managedWaitForStreamChunk sends f to the repair loop and blocks until enough
+// pieces of the chunk have been uploaded to recover it.
+func (r *Renter) managedWaitForStreamChunk(f *file, index uint64) error {
+	timeout := time.After(chunkUploadTimeout)
+	for {
+		select {
+		case r.newRepairs <- f:
+		case <-r.tg.StopChan():
+			return errors.New("upload interrupted by shutdown")
+		case <-timeout:
+			return errChunkUploadTimeout
+		}
+
+		f.mu.RLock()
+		recoverable := f.chunkPieces(index) >= f.erasureCode.MinPieces()
+		f.mu.RUnlock()
+		if recoverable {
+			return nil
+		}
+
+		// Stop if the upload was canceled.
+		lockID := r.mu.RLock()
+		_, uploading := r.activeUpload(f)
+		r.mu.RUnlock(lockID)
+		if !uploading {
+			return errUploadCanceled
+		}
+
+		select {
+		case <-time.After(streamPollInterval):
+		case <-r.tg.StopChan():
+			return errors.New("upload interrupted by shutdown")
+		case <-timeout:
+			return errChunkUploadTimeout
+		}
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"os"
 	"testing"
 
+	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/fastrand"
 )
 
 // TestRenterSiapathValidate verifies that the validateSiapath function correctly validates SiaPaths.
@@ -72,3 +74,64 @@ func TestRenterUploadInode(t *testing.T) {
 		t.Fatal("expected errUploadDirectory, got", err)
 	}
 }
+
+// activeHostsDB is a hostDB that reports a fixed number of active hosts.
+type activeHostsDB struct {
+	hostDB
+	n int
+}
+
+func (hdb activeHostsDB) ActiveHosts() []modules.HostDBEntry {
+	return make([]modules.HostDBEntry, hdb.n)
+}
+
+// TestRenterUploadParams checks that the erasure coding parameters and piece
+// size of an upload are validated and applied to the file.
+func TestRenterUploadParams(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+
+	source := build.TempDir("renter", t.Name(), "test.dat")
+	if err := ioutil.WriteFile(source, fastrand.Bytes(1000), 0600); err != nil {
+		t.Fatal(err)
+	}
+	ec, err := NewRSCode(2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = rt.renter.Upload(modules.FileUploadParams{Source: source, SiaPath: "big", PieceSize: pieceSize + 1})
+	if err != errBadPieceSize {
+		t.Fatal("expected errBadPieceSize, got", err)
+	}
+	err = rt.renter.Upload(modules.FileUploadParams{Source: source, SiaPath: "nohosts", ErasureCode: ec})
+	if err == nil {
+		t.Fatal("expected an error when there are fewer hosts than data pieces")
+	}
+
+	rt.renter.hostDB = activeHostsDB{hostDB: rt.renter.hostDB, n: 2}
+	err = rt.renter.Upload(modules.FileUploadParams{Source: source, SiaPath: "custom", ErasureCode: ec, PieceSize: 256})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rt.renter.Upload(modules.FileUploadParams{Source: source, SiaPath: "default"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]modules.FileInfo)
+	for _, fi := range rt.renter.FileList() {
+		files[fi.SiaPath] = fi
+	}
+	if fi := files["custom"]; fi.DataPieces != 2 || fi.ParityPieces != 1 || fi.PieceSize != 256 {
+		t.Fatal("custom parameters were not applied:", fi)
+	}
+	if fi := files["default"]; fi.DataPieces != defaultDataPieces || fi.ParityPieces != defaultParityPieces || fi.PieceSize != pieceSize {
+		t.Fatal("default parameters were not applied:", fi)
+	}
+}
@@ -0,0 +1,183 @@
+package renter
+
+// uploadqueue.go lets the user schedule the uploads in the upload queue. The
+// priority and paused state of an upload belong to its file, so that they also
+// apply to the repairs of the file and are kept when the renter restarts.
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+var (
+	errUploadCanceled    = errors.New("upload was canceled")
+	errUploadNotActive   = errors.New("no upload with that ID is in progress")
+	errUploadNotPaused   = errors.New("upload is not paused")
+	errUploadPaused      = errors.New("upload is already paused")
+	errPauseStreamUpload = errors.New("streaming uploads cannot be paused")
+)
+
+// repairOrder is the position of the chunks of a file in the repair loop.
+// Chunks of files with a higher priority are repaired first. Among files of
+// equal priority, the chunks of files that are being repaired come before
+// those of uploads, which are repaired in the order that they were started.
+type repairOrder struct {
+	priority int
+	uploadID uint64
+}
+
+// uploadByID returns the upload with the given ID if it is in progress. The
+// caller must hold the renter lock.
+func (r *Renter) uploadByID(id uint64) (*upload, error) {
+	for _, u := range r.uploadQueue {
+		if u.id == id {
+			if u.complete || u.err != nil {
+				break
+			}
+			return u, nil
+		}
+	}
+	return nil, errUploadNotActive
+}
+
+// managedUpdateUpload applies fn to the tracking metadata of the file of the
+// upload with the given ID, persists it, and notifies the transfer
+// subscribers of the new state of the upload. The file is returned.
+func (r *Renter) managedUpdateUpload(id uint64, fn func(u *upload, tf *trackedFile) error) (*file, error) {
+	lockID := r.mu.Lock()
+	u, err := r.uploadByID(id)
+	if err != nil {
+		r.mu.Unlock(lockID)
+		return nil, err
+	}
+	tf := r.tracking[u.file.name]
+	if err := fn(u, &tf); err != nil {
+		r.mu.Unlock(lockID)
+		return nil, err
+	}
+	r.tracking[u.file.name] = tf
+	err = r.saveSync()
+	ui := u.info(time.Now(), tf)
+	r.mu.Unlock(lockID)
+	r.managedNotifyTransfer(modules.TransferEvent{Upload: &ui})
+	return u.file, err
+}
+
+// PauseUpload stops the renter from uploading and repairing the file of the
+// upload with the given ID. Pieces that are being uploaded when the upload is
+// paused are still completed.
+func (r *Renter) PauseUpload(id uint64) error {
+	_, err := r.managedUpdateUpload(id, func(u *upload, tf *trackedFile) error {
+		if u.streaming {
+			return errPauseStreamUpload
+		} else if tf.Paused {
+			return errUploadPaused
+		}
+		tf.Paused = true
+		return nil
+	})
+	return err
+}
+
+// ResumeUpload resumes an upload paused by PauseUpload.
+func (r *Renter) ResumeUpload(id uint64) error {
+	f, err := r.managedUpdateUpload(id, func(u *upload, tf *trackedFile) error {
+		if !tf.Paused {
+			return errUploadNotPaused
+		}
+		tf.Paused = false
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Wake up the repair loop, which waits for new work while only paused
+	// files have chunks to upload.
+	select {
+	case r.newRepairs <- f:
+	case <-r.tg.StopChan():
+	}
+	return nil
+}
+
+// SetUploadPriority sets the priority of the file of the upload with the
+// given ID. Uploads are reordered by changing their priorities: the pieces of
+// files with a higher priority are uploaded and repaired first.
+func (r *Renter) SetUploadPriority(id uint64, priority int) error {
+	_, err := r.managedUpdateUpload(id, func(u *upload, tf *trackedFile) error {
+		tf.Priority = priority
+		return nil
+	})
+	return err
+}
+
+// CancelUpload stops the upload with the given ID and deletes its file. A
+// streaming upload stops once the chunk that is being uploaded is abandoned.
+func (r *Renter) CancelUpload(id uint64) error {
+	lockID := r.mu.Lock()
+	u, err := r.uploadByID(id)
+	if err != nil {
+		r.mu.Unlock(lockID)
+		return err
+	}
+	f := u.file
+	event, _ := r.finishUpload(f, errUploadCanceled)
+	name := f.name
+	r.mu.Unlock(lockID)
+	r.managedNotifyTransfer(event)
+	return r.DeleteFile(name)
+}
+
+// managedPrioritizedChunks returns the incomplete chunks of the repair state
+// in the order that they should be repaired. The chunks of paused files are
+// left out, and no chunks are returned while the renter is paused.
+func (r *Renter) managedPrioritizedChunks(rs *repairState) []chunkID {
+	orders := make(map[string]repairOrder)
+	paused := make(map[string]bool)
+	chunks := make([]chunkID, 0, len(rs.incompleteChunks))
+	lockID := r.mu.RLock()
+	if r.paused {
+		r.mu.RUnlock(lockID)
+		return nil
+	}
+	for cid := range rs.incompleteChunks {
+		if _, seen := orders[cid.filename]; !seen && !paused[cid.filename] {
+			// The chunks of deleted files are not paused, so that they are
+			// removed from the repair state.
+			f, exists := r.files[cid.filename]
+			tf := r.tracking[cid.filename]
+			if exists && tf.Paused {
+				paused[cid.filename] = true
+				continue
+			}
+			order := repairOrder{priority: tf.Priority}
+			if exists {
+				if u, uploading := r.activeUpload(f); uploading {
+					order.uploadID = u.id
+				}
+			}
+			orders[cid.filename] = order
+		}
+		if !paused[cid.filename] {
+			chunks = append(chunks, cid)
+		}
+	}
+	r.mu.RUnlock(lockID)
+
+	sort.Slice(chunks, func(i, j int) bool {
+		a, b := orders[chunks[i].filename], orders[chunks[j].filename]
+		if a.priority != b.priority {
+			return a.priority > b.priority
+		} else if a.uploadID != b.uploadID {
+			return a.uploadID < b.uploadID
+		} else if chunks[i].filename != chunks[j].filename {
+			return chunks[i].filename < chunks[j].filename
+		}
+		return chunks[i].index < chunks[j].index
+	})
+	return chunks
+}
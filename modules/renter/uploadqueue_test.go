@@ -0,0 +1,127 @@
+package renter
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestUploadScheduling checks that the chunks of the repair state are ordered
+// by the priorities of their files, that the chunks of paused files are left
+// out, and that uploads can be canceled.
+func TestUploadScheduling(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+	r := rt.renter
+
+	rs := &repairState{
+		activeWorkers:     make(map[types.FileContractID]*worker),
+		availableWorkers:  make(map[types.FileContractID]*worker),
+		gapCounts:         make(map[int]int),
+		incompleteChunks:  make(map[chunkID]*chunkStatus),
+		cachedChunks:      make(map[chunkID][]byte),
+		downloadingChunks: make(map[chunkID]struct{}),
+	}
+	// "repair" is only being repaired; the other files are being uploaded,
+	// in alphabetical order.
+	rsc, _ := NewRSCode(1, 1)
+	ids := make(map[string]uint64)
+	lockID := r.mu.Lock()
+	for _, name := range []string{"repair", "a", "b", "c", "stream"} {
+		f := newFile(name, rsc, 100, 150)
+		r.files[name] = f
+		r.tracking[name] = trackedFile{}
+		if name != "repair" {
+			r.newUpload(f, "", name == "stream")
+			ids[name] = r.lastTransferID
+		}
+		if name != "stream" {
+			r.addFileToRepairState(rs, f)
+		}
+	}
+	r.mu.Unlock(lockID)
+
+	order := func() (names []string) {
+		for _, cid := range r.managedPrioritizedChunks(rs) {
+			names = append(names, cid.filename)
+		}
+		return names
+	}
+	checkOrder := func(expected ...string) {
+		t.Helper()
+		var chunks []string
+		for _, name := range expected {
+			chunks = append(chunks, name, name)
+		}
+		names := order()
+		if len(names) != len(chunks) {
+			t.Fatal("wrong chunks:", names)
+		}
+		for i := range names {
+			if names[i] != chunks[i] {
+				t.Fatal("wrong order of chunks:", names)
+			}
+		}
+	}
+	checkOrder("repair", "a", "b", "c")
+
+	if err := r.SetUploadPriority(ids["c"], 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SetUploadPriority(ids["a"], -1); err != nil {
+		t.Fatal(err)
+	}
+	checkOrder("c", "repair", "b", "a")
+
+	// Paused files are left out until they are resumed.
+	if err := r.PauseUpload(ids["b"]); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.PauseUpload(ids["b"]); err != errUploadPaused {
+		t.Fatal("expected errUploadPaused, got", err)
+	}
+	if err := r.PauseUpload(ids["stream"]); err != errPauseStreamUpload {
+		t.Fatal("expected errPauseStreamUpload, got", err)
+	}
+	checkOrder("c", "repair", "a")
+	for _, ui := range r.UploadQueue() {
+		if ui.SiaPath == "b" && !ui.Paused || ui.SiaPath == "c" && ui.Priority != 5 {
+			t.Fatal("wrong upload info:", ui)
+		}
+	}
+	if err := r.ResumeUpload(ids["b"]); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.ResumeUpload(ids["b"]); err != errUploadNotPaused {
+		t.Fatal("expected errUploadNotPaused, got", err)
+	}
+	checkOrder("c", "repair", "b", "a")
+
+	// Canceling an upload deletes its file, and ends the upload.
+	if err := r.CancelUpload(ids["c"]); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.CancelUpload(ids["c"]); err != errUploadNotActive {
+		t.Fatal("expected errUploadNotActive, got", err)
+	}
+	if err := r.SetUploadPriority(1000, 1); err != errUploadNotActive {
+		t.Fatal("expected errUploadNotActive, got", err)
+	}
+	lockID = r.mu.RLock()
+	_, exists := r.files["c"]
+	r.mu.RUnlock(lockID)
+	if exists {
+		t.Fatal("file of a canceled upload was not deleted")
+	}
+	for _, ui := range r.UploadQueue() {
+		if ui.SiaPath == "c" && ui.Error != errUploadCanceled.Error() {
+			t.Fatal("upload was not canceled:", ui)
+		}
+	}
+}
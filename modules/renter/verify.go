@@ -0,0 +1,104 @@
+package renter
+
+// verify.go audits the storage of a file without downloading it. Each host
+// that stores pieces of the file is challenged to prove, with a Merkle proof
+// against the root recorded in the file's metadata, that it still stores a
+// random segment of a few random pieces.
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+	"github.com/NebulousLabs/fastrand"
+)
+
+// verifyChallenges is the maximum number of pieces that a host is challenged
+// to prove in a single verification of a file.
+var verifyChallenges = build.Select(build.Var{
+	Dev:      8,
+	Standard: 16,
+	Testing:  4,
+}).(int)
+
+// segmentChallenges returns challenges for random segments of up to n of the
+// specified pieces.
+func segmentChallenges(pieces []pieceData, n int) []modules.SegmentChallenge {
+	if n > len(pieces) {
+		n = len(pieces)
+	}
+	numSegments := modules.SectorSize / crypto.SegmentSize
+	challenges := make([]modules.SegmentChallenge, n)
+	for i, j := range fastrand.Perm(len(pieces))[:n] {
+		challenges[i] = modules.SegmentChallenge{
+			MerkleRoot:   pieces[j].MerkleRoot,
+			SegmentIndex: fastrand.Uint64n(numSegments),
+		}
+	}
+	return challenges
+}
+
+// Verify challenges the hosts that store the pieces of a file to prove that
+// they still store random segments of the pieces. Hosts whose contracts are
+// no longer in use are reported with an error.
+func (r *Renter) Verify(siapath string) (modules.FileVerification, error) {
+	lockID := r.mu.RLock()
+	f, exists := r.files[siapath]
+	r.mu.RUnlock(lockID)
+	if !exists {
+		return modules.FileVerification{}, ErrUnknownPath
+	}
+	f.mu.RLock()
+	var fcs []fileContract
+	for _, fc := range f.contracts {
+		if len(fc.Pieces) > 0 {
+			fcs = append(fcs, fc)
+		}
+	}
+	f.mu.RUnlock()
+
+	contracts := make(map[types.FileContractID]modules.RenterContract)
+	for _, contract := range r.hostContractor.Contracts() {
+		contracts[contract.ID] = contract
+	}
+
+	hosts := make([]modules.HostVerification, len(fcs))
+	var wg sync.WaitGroup
+	for i, fc := range fcs {
+		hv := &hosts[i]
+		hv.NetAddress = fc.IP
+		hv.Pieces = len(fc.Pieces)
+		contract, ok := contracts[r.hostContractor.ResolveID(fc.ID)]
+		if !ok {
+			hv.Error = "contract with host is no longer in use"
+			continue
+		}
+		hv.NetAddress = contract.NetAddress
+		hv.HostPublicKey = contract.HostPublicKey
+
+		challenges := segmentChallenges(fc.Pieces, verifyChallenges)
+		hv.Challenges = len(challenges)
+		wg.Add(1)
+		go func(id types.FileContractID) {
+			defer wg.Done()
+			failures, err := r.hostContractor.VerifySegments(id, challenges, r.tg.StopChan())
+			if err != nil {
+				hv.Error = err.Error()
+				return
+			}
+			hv.Failures = failures
+		}(contract.ID)
+	}
+	wg.Wait()
+
+	sort.Slice(hosts, func(i, j int) bool {
+		return hosts[i].NetAddress < hosts[j].NetAddress
+	})
+	return modules.FileVerification{
+		SiaPath: siapath,
+		Hosts:   hosts,
+	}, nil
+}
@@ -0,0 +1,86 @@
+package renter
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// verifyContractor is a hostContractor whose hosts answer segment challenges
+// with a fixed number of failures.
+type verifyContractor struct {
+	hostContractor
+	contracts []modules.RenterContract
+	failures  map[types.FileContractID]int
+}
+
+func (c verifyContractor) Contracts() []modules.RenterContract { return c.contracts }
+func (c verifyContractor) ResolveID(id types.FileContractID) types.FileContractID {
+	return id
+}
+func (c verifyContractor) VerifySegments(id types.FileContractID, challenges []modules.SegmentChallenge, _ <-chan struct{}) (int, error) {
+	for _, challenge := range challenges {
+		if challenge.SegmentIndex >= modules.SectorSize/crypto.SegmentSize {
+			return 0, errors.New("segment index out of bounds")
+		}
+	}
+	return c.failures[id], nil
+}
+
+// TestVerify checks that the hosts of a file are challenged for up to
+// verifyChallenges of their pieces, and that their failures are reported.
+func TestVerify(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+	r := rt.renter
+
+	// Host "a" stores many pieces and fails one challenge, host "b" stores
+	// one piece, and the contract with host "c" is no longer in use.
+	r.hostContractor = verifyContractor{
+		hostContractor: r.hostContractor,
+		contracts: []modules.RenterContract{
+			{ID: types.FileContractID{1}, NetAddress: "a:1"},
+			{ID: types.FileContractID{2}, NetAddress: "b:1"},
+		},
+		failures: map[types.FileContractID]int{{1}: 1},
+	}
+	rsc, _ := NewRSCode(1, 2)
+	f := newFile("foo", rsc, 100, 100)
+	f.contracts[types.FileContractID{1}] = fileContract{ID: types.FileContractID{1}, Pieces: make([]pieceData, verifyChallenges+2)}
+	f.contracts[types.FileContractID{2}] = fileContract{ID: types.FileContractID{2}, Pieces: make([]pieceData, 1)}
+	f.contracts[types.FileContractID{3}] = fileContract{ID: types.FileContractID{3}, IP: "c:1", Pieces: make([]pieceData, 1)}
+	lockID := r.mu.Lock()
+	r.files["foo"] = f
+	r.mu.Unlock(lockID)
+
+	fv, err := r.Verify("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fv.Hosts) != 3 {
+		t.Fatal("wrong number of hosts:", fv.Hosts)
+	}
+	a, b, c := fv.Hosts[0], fv.Hosts[1], fv.Hosts[2]
+	if a.NetAddress != "a:1" || a.Pieces != verifyChallenges+2 || a.Challenges != verifyChallenges || a.Failures != 1 || a.Error != "" {
+		t.Fatal("wrong verification of host a:", a)
+	}
+	if b.NetAddress != "b:1" || b.Challenges != 1 || b.Failures != 0 || b.Error != "" {
+		t.Fatal("wrong verification of host b:", b)
+	}
+	if c.NetAddress != "c:1" || c.Challenges != 0 || c.Error == "" {
+		t.Fatal("unused contract was verified:", c)
+	}
+
+	if _, err := r.Verify("bar"); err != ErrUnknownPath {
+		t.Fatal("expected ErrUnknownPath, got", err)
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/renter/contractor"
 	"github.com/NebulousLabs/Sia/types"
 )
 
@@ -66,6 +68,18 @@ type (
 		// with.
 		contractID types.FileContractID
 
+		// hostPubKey is the public key of the host of the contract.
+		hostPubKey types.SiaPublicKey
+
+		// netAddress is the address of the host when the worker was created.
+		netAddress modules.NetAddress
+
+		// subnet and rtt are the subnet of the host and the average
+		// round-trip time to the host when the worker was created. They are
+		// empty if the host has not been reached by the hostdb.
+		subnet string
+		rtt    time.Duration
+
 		// If there is work on all three channels, the worker will first do all
 		// of the work in the priority download chan, then all of the work in the
 		// download chan, and finally all of the work in the upload chan.
@@ -77,6 +91,12 @@ type (
 		priorityDownloadChan chan downloadWork // higher priority than downloads (used for user-initiated downloads)
 		uploadChan           chan uploadWork   // lowest priority
 
+		// editor is the open connection that the worker uploads to its host
+		// with. It is kept open between consecutive uploads, and closed when
+		// the worker downloads or has been idle for editorIdleTimeout. Only
+		// accessed by the worker thread.
+		editor contractor.Editor
+
 		// recentUploadFailure documents the most recent time that an upload
 		// has failed.
 		consecutiveUploadFailures time.Duration
@@ -91,8 +111,31 @@ type (
 	}
 )
 
+// closeEditor closes the worker's editor, if it has one.
+func (w *worker) closeEditor() {
+	if w.editor != nil {
+		w.editor.Close()
+		w.editor = nil
+	}
+}
+
 // download will perform some download work.
 func (w *worker) download(dw downloadWork) {
+	// Skip the piece if its chunk has been recovered from other hosts since
+	// the work was scheduled.
+	select {
+	case <-dw.chunkDownload.done:
+		select {
+		case dw.resultChan <- finishedDownload{dw.chunkDownload, nil, errChunkRecovered, dw.pieceIndex, w.contractID}:
+		case <-w.renter.tg.StopChan():
+		}
+		return
+	default:
+	}
+
+	// The contract cannot be revised by a downloader while the editor is open.
+	w.closeEditor()
+
 	d, err := w.renter.hostContractor.Downloader(w.contractID, w.renter.tg.StopChan())
 	if err != nil {
 		select {
@@ -110,21 +153,34 @@ func (w *worker) download(dw downloadWork) {
 	}
 }
 
-// upload will perform some upload work.
-func (w *worker) upload(uw uploadWork) {
-	e, err := w.renter.hostContractor.Editor(w.contractID, w.renter.tg.StopChan())
-	if err != nil {
-		w.recentUploadFailure = time.Now()
-		w.consecutiveUploadFailures++
-		select {
-		case uw.resultChan <- finishedUpload{uw.chunkID, crypto.Hash{}, err, uw.pieceIndex, w.contractID}:
-		case <-w.renter.tg.StopChan():
+// uploadPiece uploads data to the host, opening an editor if the worker does
+// not have one. An editor that was kept open may have been closed by the host
+// or invalidated by a renewal in the meantime, so a failed upload through it
+// is retried once with a new editor.
+func (w *worker) uploadPiece(data []byte) (root crypto.Hash, err error) {
+	for {
+		reused := w.editor != nil
+		if !reused {
+			e, err := w.renter.hostContractor.Editor(w.contractID, w.renter.tg.StopChan())
+			if err != nil {
+				return crypto.Hash{}, err
+			}
+			w.editor = e
+		}
+		root, err = w.editor.Upload(data)
+		if err == nil {
+			return root, nil
+		}
+		w.closeEditor()
+		if !reused {
+			return root, err
 		}
-		return
 	}
-	defer e.Close()
+}
 
-	root, err := e.Upload(uw.data)
+// upload will perform some upload work.
+func (w *worker) upload(uw uploadWork) {
+	root, err := w.uploadPiece(uw.data)
 	if err != nil {
 		w.recentUploadFailure = time.Now()
 		w.consecutiveUploadFailures++
@@ -145,8 +201,8 @@ func (w *worker) upload(uw uploadWork) {
 	if !exists {
 		contract = fileContract{
 			ID:          w.contractID,
-			IP:          e.Address(),
-			WindowStart: e.EndHeight(),
+			IP:          w.editor.Address(),
+			WindowStart: w.editor.EndHeight(),
 		}
 	}
 	contract.Pieces = append(contract.Pieces, pieceData{
@@ -157,7 +213,11 @@ func (w *worker) upload(uw uploadWork) {
 	uw.file.contracts[w.contractID] = contract
 	w.renter.saveFile(uw.file)
 	uw.file.mu.Unlock()
+	event, uploading := w.renter.uploadedPiece(uw.file, w.netAddress)
 	w.renter.mu.Unlock(id)
+	if uploading {
+		w.renter.managedNotifyTransfer(event)
+	}
 
 	select {
 	case uw.resultChan <- finishedUpload{uw.chunkID, root, err, uw.pieceIndex, w.contractID}:
@@ -186,6 +246,13 @@ func (w *worker) work() {
 		// do nothing
 	}
 
+	// Close the editor if no upload arrives before it has been idle for
+	// editorIdleTimeout.
+	var idle <-chan time.Time
+	if w.editor != nil {
+		idle = time.After(editorIdleTimeout)
+	}
+
 	// None of the priority channels have work, listen on all channels.
 	select {
 	case d := <-w.downloadChan:
@@ -199,6 +266,9 @@ func (w *worker) work() {
 	case u := <-w.uploadChan:
 		w.upload(u)
 		return
+	case <-idle:
+		w.closeEditor()
+		return
 	case <-w.renter.tg.StopChan():
 		return
 	}
@@ -207,6 +277,7 @@ func (w *worker) work() {
 // threadedWorkLoop repeatedly issues work to a worker, stopping when the
 // thread group is closed.
 func (w *worker) threadedWorkLoop() {
+	defer w.closeEditor()
 	for {
 		// Check if the worker has been killed individually.
 		select {
@@ -228,17 +299,22 @@ func (w *worker) threadedWorkLoop() {
 // update the worker pool to match.
 func (r *Renter) updateWorkerPool() {
 	// Get a map of all the contracts in the contractor.
-	newContracts := make(map[types.FileContractID]struct{})
+	newContracts := make(map[types.FileContractID]modules.RenterContract)
 	for _, nc := range r.hostContractor.Contracts() {
-		newContracts[nc.ID] = struct{}{}
+		newContracts[nc.ID] = nc
 	}
 
 	// Add a worker for any contract that does not already have a worker.
-	for id := range newContracts {
+	for id, nc := range newContracts {
 		_, exists := r.workerPool[id]
 		if !exists {
+			host, _ := r.hostDB.Host(nc.HostPublicKey)
 			worker := &worker{
 				contractID: id,
+				hostPubKey: nc.HostPublicKey,
+				netAddress: nc.NetAddress,
+				subnet:     host.Subnet,
+				rtt:        host.AverageRTT(),
 
 				downloadChan:         make(chan downloadWork, 1),
 				killChan:             make(chan struct{}),
@@ -0,0 +1,120 @@
+package renter
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/renter/contractor"
+	"github.com/NebulousLabs/Sia/sync"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// countingEditor is an Editor that records whether it has been closed, and
+// that fails its uploads once it is stale.
+type countingEditor struct {
+	contractor.Editor
+	closed bool
+	stale  bool
+}
+
+func (e *countingEditor) Upload(data []byte) (crypto.Hash, error) {
+	if e.closed || e.stale {
+		return crypto.Hash{}, errors.New("stale editor")
+	}
+	return crypto.MerkleRoot(data), nil
+}
+func (e *countingEditor) Address() modules.NetAddress  { return "foo:1234" }
+func (e *countingEditor) EndHeight() types.BlockHeight { return 100 }
+func (e *countingEditor) Close() error {
+	e.closed = true
+	return nil
+}
+
+// editorContractor is a hostContractor that hands out countingEditors.
+type editorContractor struct {
+	hostContractor
+	editors []*countingEditor
+}
+
+func (c *editorContractor) Editor(types.FileContractID, <-chan struct{}) (contractor.Editor, error) {
+	e := new(countingEditor)
+	c.editors = append(c.editors, e)
+	return e, nil
+}
+
+// TestWorkerEditorReuse checks that a worker uploads consecutive pieces
+// through one editor, replaces an editor that has gone stale, and closes its
+// editor once it has been idle.
+func TestWorkerEditorReuse(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	dir := build.TempDir("renter", t.Name())
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	hc := new(editorContractor)
+	r := &Renter{
+		hostContractor: hc,
+		persistDir:     dir,
+		mu:             sync.New(modules.SafeMutexDelay, 1),
+		tg:             new(sync.ThreadGroup),
+	}
+	defer r.tg.Stop()
+	w := &worker{
+		contractID: types.FileContractID{1},
+		uploadChan: make(chan uploadWork, 1),
+		renter:     r,
+	}
+
+	f := newTestingFile()
+	f.contracts = make(map[types.FileContractID]fileContract)
+	resultChan := make(chan finishedUpload, 1)
+	upload := func(piece uint64) {
+		w.upload(uploadWork{
+			chunkID:    chunkID{index: 0, filename: f.name},
+			data:       make([]byte, 64),
+			file:       f,
+			pieceIndex: piece,
+			resultChan: resultChan,
+		})
+		if fu := <-resultChan; fu.err != nil {
+			t.Fatal(fu.err)
+		}
+	}
+
+	// Consecutive uploads share an editor.
+	upload(0)
+	upload(1)
+	if len(hc.editors) != 1 {
+		t.Fatal("expected 1 editor, got", len(hc.editors))
+	}
+	if len(f.contracts[w.contractID].Pieces) != 2 {
+		t.Fatal("pieces were not recorded")
+	}
+
+	// A stale editor is replaced without counting as a failure.
+	hc.editors[0].stale = true
+	upload(2)
+	if len(hc.editors) != 2 || !hc.editors[0].closed {
+		t.Fatal("stale editor was not replaced")
+	}
+	if w.consecutiveUploadFailures != 0 {
+		t.Fatal("replacing a stale editor counted as a failure")
+	}
+
+	// An idle worker closes its editor.
+	start := time.Now()
+	w.work()
+	if w.editor != nil || !hc.editors[1].closed {
+		t.Fatal("idle editor was not closed")
+	}
+	if time.Since(start) < editorIdleTimeout {
+		t.Fatal("editor was closed before it was idle for editorIdleTimeout")
+	}
+}
@@ -0,0 +1,27 @@
+package modules
+
+const (
+	// S3GatewayDir is the name of the directory that is used to store the S3
+	// gateway's persistent data.
+	S3GatewayDir = "s3gateway"
+)
+
+type (
+	// S3Credentials are the credentials that S3 clients sign their requests
+	// with. A gateway without an access key ID accepts unsigned requests.
+	S3Credentials struct {
+		AccessKeyID     string
+		SecretAccessKey string
+	}
+
+	// An S3Gateway serves a subset of the Amazon S3 API, so that existing S3
+	// tools can store objects with the renter. Buckets are the top-level
+	// directories of the renter, and objects are the files within them.
+	S3Gateway interface {
+		// Address returns the address that the gateway is listening on.
+		Address() NetAddress
+
+		// Close shuts down the gateway.
+		Close() error
+	}
+)
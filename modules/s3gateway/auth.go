@@ -0,0 +1,199 @@
+package s3gateway
+
+// auth.go verifies the AWS Signature Version 4 of requests. Only signatures
+// passed in the Authorization header are supported; presigned URLs and chunked
+// payload signatures are not.
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+const (
+	signV4Algorithm = "AWS4-HMAC-SHA256"
+	amzDateFormat   = "20060102T150405Z"
+	scopeDateFormat = "20060102"
+
+	// maxClockSkew is the largest difference between the time of a request
+	// and the time of the gateway that is accepted.
+	maxClockSkew = 15 * time.Minute
+
+	// unsignedPayload and streamingPayload are the values of the
+	// x-amz-content-sha256 header of requests whose payload is not signed,
+	// and of requests whose payload is signed in chunks.
+	unsignedPayload  = "UNSIGNED-PAYLOAD"
+	streamingPayload = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+)
+
+// authenticate checks the signature of req, if the gateway has credentials.
+// It returns the SHA-256 hash of the payload that was signed, or the empty
+// string if the payload is not signed.
+func (g *S3Gateway) authenticate(req *http.Request) (string, error) {
+	if g.creds.AccessKeyID == "" {
+		return "", nil
+	}
+	payloadHash, err := verifySignature(req, g.creds, time.Now())
+	if err != nil {
+		return "", err
+	}
+	if payloadHash == streamingPayload {
+		return "", errNotImplemented("chunked payload signatures are not supported")
+	} else if payloadHash == unsignedPayload {
+		return "", nil
+	}
+	return payloadHash, nil
+}
+
+// verifySignature checks that req is signed with creds at a time close to
+// now, returning the value of its x-amz-content-sha256 header.
+func verifySignature(req *http.Request, creds modules.S3Credentials, now time.Time) (string, error) {
+	auth := req.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, signV4Algorithm+" ") {
+		return "", errAccessDenied
+	}
+	fields := make(map[string]string)
+	for _, field := range strings.Split(strings.TrimPrefix(auth, signV4Algorithm+" "), ",") {
+		kv := strings.SplitN(strings.TrimSpace(field), "=", 2)
+		if len(kv) == 2 {
+			fields[kv[0]] = kv[1]
+		}
+	}
+	// The credential is of the form accesskey/date/region/service/aws4_request.
+	credential := strings.Split(fields["Credential"], "/")
+	if len(credential) != 5 || credential[3] != "s3" || credential[4] != "aws4_request" {
+		return "", errAccessDenied
+	}
+	if credential[0] != creds.AccessKeyID {
+		return "", errInvalidAccessKeyID
+	}
+	signedHeaders := strings.Split(fields["SignedHeaders"], ";")
+	signature, err := hex.DecodeString(fields["Signature"])
+	if err != nil {
+		return "", errSignatureDoesNotMatch
+	}
+
+	amzDate := req.Header.Get("X-Amz-Date")
+	t, err := time.Parse(amzDateFormat, amzDate)
+	if err != nil {
+		return "", errAccessDenied
+	} else if d := now.Sub(t); d > maxClockSkew || d < -maxClockSkew {
+		return "", errRequestTimeSkewed
+	} else if t.Format(scopeDateFormat) != credential[1] {
+		return "", errSignatureDoesNotMatch
+	}
+	payloadHash := req.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash == "" {
+		return "", errAccessDenied
+	}
+
+	// Build the canonical request, and the string to sign from it.
+	canonicalHeaders := ""
+	hostSigned := false
+	for _, h := range signedHeaders {
+		hostSigned = hostSigned || h == "host"
+		canonicalHeaders += h + ":" + canonicalHeaderValue(req, h) + "\n"
+	}
+	if !hostSigned {
+		return "", errAccessDenied
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		uriEncode(req.URL.Path, false),
+		canonicalQuery(req),
+		canonicalHeaders,
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+	scope := strings.Join(credential[1:], "/")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		signV4Algorithm,
+		amzDate,
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	// Derive the signing key from the secret, and sign.
+	key := []byte("AWS4" + creds.SecretAccessKey)
+	for _, s := range credential[1:] {
+		key = hmacSHA256(key, s)
+	}
+	if !hmac.Equal(hmacSHA256(key, stringToSign), signature) {
+		return "", errSignatureDoesNotMatch
+	}
+	return payloadHash, nil
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data with key.
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalHeaderValue returns the value of the header h of req as it is
+// signed: the values of the header joined by commas, with sequences of
+// spaces collapsed.
+func canonicalHeaderValue(req *http.Request, h string) string {
+	var values []string
+	switch h {
+	case "host":
+		values = []string{req.Host}
+	case "content-length":
+		values = []string{strconv.FormatInt(req.ContentLength, 10)}
+	default:
+		values = req.Header[http.CanonicalHeaderKey(h)]
+	}
+	for i, v := range values {
+		values[i] = strings.Join(strings.Fields(v), " ")
+	}
+	return strings.Join(values, ",")
+}
+
+// canonicalQuery returns the query string of req as it is signed: the
+// parameters sorted by name and then by value, and encoded.
+func canonicalQuery(req *http.Request) string {
+	var params [][2]string
+	for k, vs := range req.URL.Query() {
+		for _, v := range vs {
+			params = append(params, [2]string{uriEncode(k, true), uriEncode(v, true)})
+		}
+	}
+	sort.Slice(params, func(i, j int) bool {
+		if params[i][0] != params[j][0] {
+			return params[i][0] < params[j][0]
+		}
+		return params[i][1] < params[j][1]
+	})
+	encoded := make([]string, len(params))
+	for i, p := range params {
+		encoded[i] = p[0] + "=" + p[1]
+	}
+	return strings.Join(encoded, "&")
+}
+
+// uriEncode percent-encodes every byte of s except the unreserved characters
+// of RFC 3986, and slashes if encodeSlash is false.
+func uriEncode(s string, encodeSlash bool) string {
+	const hexDigits = "0123456789ABCDEF"
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !encodeSlash:
+			b = append(b, c)
+		default:
+			b = append(b, '%', hexDigits[c>>4], hexDigits[c&15])
+		}
+	}
+	return string(b)
+}
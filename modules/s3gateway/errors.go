@@ -0,0 +1,76 @@
+package s3gateway
+
+import (
+	"encoding/xml"
+	"net/http"
+)
+
+// An s3Error is an error in the format of the S3 API, which identifies the
+// error by a code and the HTTP status it is returned with.
+type s3Error struct {
+	Code    string
+	Message string
+	Status  int
+}
+
+// Error implements the error interface.
+func (e s3Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+var (
+	errAccessDenied          = s3Error{"AccessDenied", "Access Denied", http.StatusForbidden}
+	errBadDigest             = s3Error{"BadDigest", "The Content-MD5 you specified did not match what was received.", http.StatusBadRequest}
+	errBucketAlreadyOwned    = s3Error{"BucketAlreadyOwnedByYou", "The bucket that you tried to create already exists, and you own it.", http.StatusConflict}
+	errBucketNotEmpty        = s3Error{"BucketNotEmpty", "The bucket that you tried to delete is not empty.", http.StatusConflict}
+	errContentSHA256         = s3Error{"XAmzContentSHA256Mismatch", "The provided x-amz-content-sha256 header does not match what was computed.", http.StatusBadRequest}
+	errInvalidAccessKeyID    = s3Error{"InvalidAccessKeyId", "The access key ID that you provided does not exist in our records.", http.StatusForbidden}
+	errInvalidBucketName     = s3Error{"InvalidBucketName", "The specified bucket is not valid.", http.StatusBadRequest}
+	errInvalidDigest         = s3Error{"InvalidDigest", "The Content-MD5 you specified is not valid.", http.StatusBadRequest}
+	errInvalidRange          = s3Error{"InvalidRange", "The requested range is not satisfiable.", http.StatusRequestedRangeNotSatisfiable}
+	errMethodNotAllowed      = s3Error{"MethodNotAllowed", "The specified method is not allowed against this resource.", http.StatusMethodNotAllowed}
+	errNoSuchBucket          = s3Error{"NoSuchBucket", "The specified bucket does not exist.", http.StatusNotFound}
+	errNoSuchKey             = s3Error{"NoSuchKey", "The specified key does not exist.", http.StatusNotFound}
+	errRequestTimeSkewed     = s3Error{"RequestTimeTooSkewed", "The difference between the request time and the server's time is too large.", http.StatusForbidden}
+	errSignatureDoesNotMatch = s3Error{"SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided.", http.StatusForbidden}
+)
+
+// errInvalidArgument returns an InvalidArgument error with the given message.
+func errInvalidArgument(msg string) s3Error {
+	return s3Error{"InvalidArgument", msg, http.StatusBadRequest}
+}
+
+// errInternal returns an InternalError wrapping err.
+func errInternal(err error) s3Error {
+	return s3Error{"InternalError", err.Error(), http.StatusInternalServerError}
+}
+
+// errNotImplemented returns a NotImplemented error with the given message.
+func errNotImplemented(msg string) s3Error {
+	return s3Error{"NotImplemented", msg, http.StatusNotImplemented}
+}
+
+// writeError writes err to w as an S3 error response. Errors that are not
+// s3Errors are reported as internal errors.
+func writeError(w http.ResponseWriter, req *http.Request, err error) {
+	e, ok := err.(s3Error)
+	if !ok {
+		e = errInternal(err)
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(e.Status)
+	if req.Method == "HEAD" {
+		return
+	}
+	w.Write([]byte(xml.Header))
+	xml.NewEncoder(w).Encode(struct {
+		XMLName  xml.Name `xml:"Error"`
+		Code     string
+		Message  string
+		Resource string
+	}{
+		Code:     e.Code,
+		Message:  e.Message,
+		Resource: req.URL.Path,
+	})
+}
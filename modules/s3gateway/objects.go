@@ -0,0 +1,520 @@
+package s3gateway
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/internal/httpstream"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/renter"
+	"github.com/NebulousLabs/Sia/persist"
+)
+
+const (
+	// s3Namespace is the XML namespace of the responses of the S3 API.
+	s3Namespace = "http://s3.amazonaws.com/doc/2006-03-01/"
+
+	// s3TimeFormat is the format of the times in the responses of the S3
+	// API.
+	s3TimeFormat = "2006-01-02T15:04:05.000Z"
+
+	// maxListKeys is the largest number of objects returned by a listing.
+	maxListKeys = 1000
+
+	// uploadMarker separates the siapath of an object from the random suffix
+	// of the siapath that the object is uploaded to. Once the upload has
+	// completed, the file is renamed to the siapath of the object, replacing
+	// the previous version of the object.
+	uploadMarker = ".s3upload-"
+
+	// emptyETag is the ETag of an empty object.
+	emptyETag = `"d41d8cd98f00b204e9800998ecf8427e"`
+)
+
+type (
+	// bucketInfo describes a bucket in a listing of buckets.
+	bucketInfo struct {
+		Name         string
+		CreationDate string
+	}
+
+	// listAllMyBucketsResult is the response to a listing of buckets.
+	listAllMyBucketsResult struct {
+		XMLName xml.Name `xml:"ListAllMyBucketsResult"`
+		Xmlns   string   `xml:"xmlns,attr"`
+		Owner   struct {
+			ID          string
+			DisplayName string
+		}
+		Buckets []bucketInfo `xml:"Buckets>Bucket"`
+	}
+
+	// objectInfo describes an object in a listing of objects.
+	objectInfo struct {
+		Key          string
+		LastModified string
+		ETag         string
+		Size         uint64
+		StorageClass string
+	}
+
+	// commonPrefix is a group of objects in a listing of objects, whose keys
+	// share a prefix up to the delimiter.
+	commonPrefix struct {
+		Prefix string
+	}
+
+	// listBucketResult is the response to both versions of listing the
+	// objects of a bucket. Version 2 listings are paginated with a
+	// continuation token instead of a marker.
+	listBucketResult struct {
+		XMLName               xml.Name `xml:"ListBucketResult"`
+		Xmlns                 string   `xml:"xmlns,attr"`
+		Name                  string
+		Prefix                string
+		Delimiter             string `xml:",omitempty"`
+		Marker                string `xml:",omitempty"`
+		NextMarker            string `xml:",omitempty"`
+		StartAfter            string `xml:",omitempty"`
+		ContinuationToken     string `xml:",omitempty"`
+		NextContinuationToken string `xml:",omitempty"`
+		KeyCount              int    `xml:",omitempty"`
+		MaxKeys               int
+		IsTruncated           bool
+		Contents              []objectInfo
+		CommonPrefixes        []commonPrefix
+	}
+
+	// listEntry is an object or a common prefix in a listing of objects.
+	listEntry struct {
+		key      string
+		isPrefix bool
+		file     modules.FileInfo
+	}
+)
+
+// writeXML writes v to w as an XML response.
+func writeXML(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write([]byte(xml.Header))
+	xml.NewEncoder(w).Encode(v)
+}
+
+// validBucketName reports whether name follows the S3 rules for bucket names:
+// between 3 and 63 lowercase letters, digits, dots and hyphens, starting and
+// ending with a letter or digit.
+func validBucketName(name string) bool {
+	if len(name) < 3 || len(name) > 63 {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		alnum := 'a' <= c && c <= 'z' || '0' <= c && c <= '9'
+		if !alnum && (i == 0 || i == len(name)-1 || (c != '.' && c != '-')) {
+			return false
+		}
+	}
+	return true
+}
+
+// isUploadPath reports whether siapath is the siapath of an object that is
+// being uploaded.
+func isUploadPath(siapath string) bool {
+	return strings.Contains(path.Base(siapath), uploadMarker)
+}
+
+// bucketExists reports whether the bucket exists.
+func (g *S3Gateway) bucketExists(bucket string) bool {
+	_, _, _, err := g.renter.DirList(bucket)
+	return err == nil
+}
+
+// file returns the information of the file at siapath.
+func (g *S3Gateway) file(siapath string) (modules.FileInfo, bool) {
+	for _, fi := range g.renter.FileList() {
+		if fi.SiaPath == siapath {
+			return fi, true
+		}
+	}
+	return modules.FileInfo{}, false
+}
+
+// metadata returns the metadata of the object stored in fi. Objects that were
+// not uploaded through the gateway have an ETag derived from their siapath
+// and size, and no modification time.
+func (g *S3Gateway) metadata(fi modules.FileInfo) objectMetadata {
+	g.mu.Lock()
+	md, ok := g.objects[fi.SiaPath]
+	g.mu.Unlock()
+	if !ok {
+		h := crypto.HashAll(fi.SiaPath, fi.Filesize)
+		md.ETag = `"` + hex.EncodeToString(h[:16]) + `"`
+	}
+	return md
+}
+
+// listEntries returns the objects of bucket whose keys start with prefix,
+// sorted by key. If delimiter is not empty, the objects whose keys contain
+// the delimiter after the prefix are grouped into common prefixes.
+func (g *S3Gateway) listEntries(bucket, prefix, delimiter string) []listEntry {
+	var entries []listEntry
+	prefixes := make(map[string]struct{})
+	for _, fi := range g.renter.FileList() {
+		if !strings.HasPrefix(fi.SiaPath, bucket+"/") || isUploadPath(fi.SiaPath) {
+			continue
+		}
+		key := strings.TrimPrefix(fi.SiaPath, bucket+"/")
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if i := strings.Index(key[len(prefix):], delimiter); delimiter != "" && i != -1 {
+			cp := key[:len(prefix)+i+len(delimiter)]
+			if _, ok := prefixes[cp]; !ok {
+				prefixes[cp] = struct{}{}
+				entries = append(entries, listEntry{key: cp, isPrefix: true})
+			}
+			continue
+		}
+		entries = append(entries, listEntry{key: key, file: fi})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].key < entries[j].key
+	})
+	return entries
+}
+
+// listBuckets handles GET requests for the service, listing the buckets.
+func (g *S3Gateway) listBuckets(w http.ResponseWriter, req *http.Request) {
+	_, dirs, _, err := g.renter.DirList("")
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	res := listAllMyBucketsResult{Xmlns: s3Namespace}
+	res.Owner.ID = "sia"
+	res.Owner.DisplayName = "sia"
+	for _, dir := range dirs {
+		res.Buckets = append(res.Buckets, bucketInfo{
+			Name:         dir.SiaPath,
+			CreationDate: time.Time{}.Format(s3TimeFormat),
+		})
+	}
+	writeXML(w, res)
+}
+
+// listObjects handles GET requests for a bucket, listing its objects. Both
+// versions of the listing are supported.
+func (g *S3Gateway) listObjects(w http.ResponseWriter, req *http.Request, bucket string) {
+	if !g.bucketExists(bucket) {
+		writeError(w, req, errNoSuchBucket)
+		return
+	}
+	q := req.URL.Query()
+	res := listBucketResult{
+		Xmlns:     s3Namespace,
+		Name:      bucket,
+		Prefix:    q.Get("prefix"),
+		Delimiter: q.Get("delimiter"),
+		MaxKeys:   maxListKeys,
+	}
+	if s := q.Get("max-keys"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			writeError(w, req, errInvalidArgument("max-keys must be a non-negative integer"))
+			return
+		}
+		if n < res.MaxKeys {
+			res.MaxKeys = n
+		}
+	}
+	v2 := q.Get("list-type") == "2"
+	marker := q.Get("marker")
+	if v2 {
+		res.StartAfter = q.Get("start-after")
+		res.ContinuationToken = q.Get("continuation-token")
+		marker = res.StartAfter
+		if res.ContinuationToken != "" {
+			b, err := base64.StdEncoding.DecodeString(res.ContinuationToken)
+			if err != nil {
+				writeError(w, req, errInvalidArgument("the continuation token is not valid"))
+				return
+			}
+			marker = string(b)
+		}
+	} else {
+		res.Marker = marker
+	}
+
+	// Skip the entries up to and including the marker.
+	entries := g.listEntries(bucket, res.Prefix, res.Delimiter)
+	entries = entries[sort.Search(len(entries), func(i int) bool {
+		return entries[i].key > marker
+	}):]
+	if len(entries) > res.MaxKeys {
+		entries = entries[:res.MaxKeys]
+		res.IsTruncated = true
+	}
+	for _, e := range entries {
+		if e.isPrefix {
+			res.CommonPrefixes = append(res.CommonPrefixes, commonPrefix{e.key})
+			continue
+		}
+		md := g.metadata(e.file)
+		res.Contents = append(res.Contents, objectInfo{
+			Key:          e.key,
+			LastModified: md.LastModified.Format(s3TimeFormat),
+			ETag:         md.ETag,
+			Size:         e.file.Filesize,
+			StorageClass: "STANDARD",
+		})
+	}
+	if res.IsTruncated {
+		last := entries[len(entries)-1].key
+		if v2 {
+			res.NextContinuationToken = base64.StdEncoding.EncodeToString([]byte(last))
+		} else {
+			res.NextMarker = last
+		}
+	}
+	if v2 {
+		res.KeyCount = len(entries)
+	}
+	writeXML(w, res)
+}
+
+// headBucket handles HEAD requests for a bucket, reporting whether it exists.
+func (g *S3Gateway) headBucket(w http.ResponseWriter, req *http.Request, bucket string) {
+	if !g.bucketExists(bucket) {
+		writeError(w, req, errNoSuchBucket)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// createBucket handles PUT requests for a bucket, creating its directory.
+func (g *S3Gateway) createBucket(w http.ResponseWriter, req *http.Request, bucket string) {
+	if !validBucketName(bucket) {
+		writeError(w, req, errInvalidBucketName)
+		return
+	}
+	switch err := g.renter.CreateDir(bucket); err {
+	case nil:
+	case renter.ErrDirOverload:
+		writeError(w, req, errBucketAlreadyOwned)
+		return
+	case renter.ErrPathOverload:
+		writeError(w, req, errInvalidArgument("a file already exists at the path of the bucket"))
+		return
+	default:
+		writeError(w, req, err)
+		return
+	}
+	w.Header().Set("Location", "/"+bucket)
+	w.WriteHeader(http.StatusOK)
+}
+
+// deleteBucket handles DELETE requests for a bucket. Only empty buckets can
+// be deleted.
+func (g *S3Gateway) deleteBucket(w http.ResponseWriter, req *http.Request, bucket string) {
+	di, _, _, err := g.renter.DirList(bucket)
+	if err != nil {
+		writeError(w, req, errNoSuchBucket)
+		return
+	} else if di.NumFiles > 0 {
+		writeError(w, req, errBucketNotEmpty)
+		return
+	}
+	if err := g.renter.DeleteDir(bucket); err != nil {
+		writeError(w, req, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getObject handles GET and HEAD requests for an object. A single byte range
+// may be requested with the Range header.
+func (g *S3Gateway) getObject(w http.ResponseWriter, req *http.Request, bucket, key string) {
+	siapath := bucket + "/" + key
+	fi, exists := g.file(siapath)
+	if !exists || isUploadPath(siapath) {
+		if !g.bucketExists(bucket) {
+			writeError(w, req, errNoSuchBucket)
+		} else {
+			writeError(w, req, errNoSuchKey)
+		}
+		return
+	}
+	offset, length, partial, err := httpstream.ParseRange(req.Header.Get("Range"), fi.Filesize)
+	if err != nil {
+		err = errInvalidRange
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", fi.Filesize))
+		writeError(w, req, err)
+		return
+	}
+
+	md := g.metadata(fi)
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	sw := httpstream.NewWriter(w)
+	sw.Header().Set("Accept-Ranges", "bytes")
+	sw.Header().Set("Content-Length", strconv.FormatUint(length, 10))
+	sw.Header().Set("Content-Type", contentType)
+	sw.Header().Set("ETag", md.ETag)
+	if !md.LastModified.IsZero() {
+		sw.Header().Set("Last-Modified", md.LastModified.UTC().Format(http.TimeFormat))
+	}
+	if partial {
+		sw.SetStatus(http.StatusPartialContent)
+		sw.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, offset+length-1, fi.Filesize))
+	}
+	if req.Method == "HEAD" || length == 0 {
+		sw.Write(nil)
+		return
+	}
+
+	err = g.renter.DownloadSection(siapath, offset, length, sw)
+	if err != nil && !sw.Started() {
+		writeError(w, req, err)
+	} else if err != nil {
+		// A download that fails after the response has started can only be
+		// reported by ending the response early.
+		g.log.Printf("WARN: download of %v failed: %v", siapath, err)
+	}
+}
+
+// putObject handles PUT requests for an object, uploading the body of the
+// request to the renter. The object replaces any previous object with the
+// same key once the upload has completed. Keys ending with a slash are
+// directory markers, and create a directory instead.
+func (g *S3Gateway) putObject(w http.ResponseWriter, req *http.Request, bucket, key, payloadHash string) {
+	if req.Header.Get("X-Amz-Copy-Source") != "" {
+		writeError(w, req, errNotImplemented("copying objects is not supported"))
+		return
+	} else if !g.bucketExists(bucket) {
+		writeError(w, req, errNoSuchBucket)
+		return
+	}
+	siapath := bucket + "/" + key
+	if strings.Contains(siapath, "./") || isUploadPath(siapath) {
+		writeError(w, req, errInvalidArgument("object keys cannot contain ./ or "+uploadMarker))
+		return
+	}
+	if strings.HasSuffix(key, "/") {
+		err := g.renter.CreateDir(strings.TrimSuffix(siapath, "/"))
+		if err != nil && err != renter.ErrDirOverload {
+			writeError(w, req, errInvalidArgument(err.Error()))
+			return
+		}
+		w.Header().Set("ETag", emptyETag)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if _, _, _, err := g.renter.DirList(siapath); err == nil {
+		writeError(w, req, errInvalidArgument("a directory already exists at the key of the object"))
+		return
+	}
+	var contentMD5 []byte
+	if s := req.Header.Get("Content-MD5"); s != "" {
+		var err error
+		contentMD5, err = base64.StdEncoding.DecodeString(s)
+		if err != nil || len(contentMD5) != md5.Size {
+			writeError(w, req, errInvalidDigest)
+			return
+		}
+	}
+
+	md5Hash, sha256Hash := md5.New(), sha256.New()
+	body := io.TeeReader(req.Body, io.MultiWriter(md5Hash, sha256Hash))
+	uploadPath := siapath + uploadMarker + persist.RandomSuffix()
+	err := g.renter.UploadStreamFromReader(modules.FileUploadParams{SiaPath: uploadPath}, body)
+	if err != nil {
+		g.log.Printf("WARN: upload of %v failed: %v", siapath, err)
+		writeError(w, req, err)
+		return
+	}
+	sum := md5Hash.Sum(nil)
+	if contentMD5 != nil && !bytes.Equal(sum, contentMD5) {
+		err = errBadDigest
+	} else if payloadHash != "" && payloadHash != hex.EncodeToString(sha256Hash.Sum(nil)) {
+		err = errContentSHA256
+	}
+	if err != nil {
+		g.renter.DeleteFile(uploadPath)
+		writeError(w, req, err)
+		return
+	}
+	etag := `"` + hex.EncodeToString(sum) + `"`
+
+	// Replace the previous object. The lock serializes concurrent uploads
+	// of the same key.
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.renter.DeleteFile(siapath); err != nil && err != renter.ErrUnknownPath {
+		g.renter.DeleteFile(uploadPath)
+		writeError(w, req, err)
+		return
+	}
+	if err := g.renter.RenameFile(uploadPath, siapath); err != nil {
+		g.renter.DeleteFile(uploadPath)
+		writeError(w, req, errors.New("could not rename the uploaded file: "+err.Error()))
+		return
+	}
+	g.objects[siapath] = objectMetadata{
+		ETag:         etag,
+		LastModified: time.Now().UTC(),
+	}
+	if err := g.saveObjects(); err != nil {
+		g.log.Println("WARN: could not save the metadata of the objects:", err)
+	}
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(http.StatusOK)
+}
+
+// deleteObject handles DELETE requests for an object. Deleting an object that
+// does not exist succeeds, as in S3.
+func (g *S3Gateway) deleteObject(w http.ResponseWriter, req *http.Request, bucket, key string) {
+	if !g.bucketExists(bucket) {
+		writeError(w, req, errNoSuchBucket)
+		return
+	}
+	siapath := bucket + "/" + key
+	if strings.HasSuffix(key, "/") {
+		// Directory markers delete their directory if it is empty.
+		dir := strings.TrimSuffix(siapath, "/")
+		if di, _, _, err := g.renter.DirList(dir); err == nil && di.NumFiles == 0 {
+			g.renter.DeleteDir(dir)
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err := g.renter.DeleteFile(siapath); err != nil && err != renter.ErrUnknownPath {
+		writeError(w, req, err)
+		return
+	}
+	g.mu.Lock()
+	if _, ok := g.objects[siapath]; ok {
+		delete(g.objects, siapath)
+		if err := g.saveObjects(); err != nil {
+			g.log.Println("WARN: could not save the metadata of the objects:", err)
+		}
+	}
+	g.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
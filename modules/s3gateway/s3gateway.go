@@ -0,0 +1,174 @@
+// Package s3gateway serves a subset of the Amazon S3 API, translating the
+// operations on objects into uploads and downloads of the renter. Buckets are
+// the top-level directories of the renter, and the key of an object is its
+// siapath within the bucket. Requests must address buckets in path style, e.g.
+// PUT /bucket/photos/cat.jpg.
+package s3gateway
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/persist"
+)
+
+const (
+	logFile     = modules.S3GatewayDir + ".log"
+	objectsFile = "objects.json"
+)
+
+var (
+	objectsMetadata = persist.Metadata{
+		Header:  "S3 Gateway Objects",
+		Version: "1.0",
+	}
+
+	errNilRenter = errors.New("s3 gateway cannot use a nil renter")
+)
+
+type (
+	// objectMetadata holds the S3 metadata of an object that the renter does
+	// not keep track of.
+	objectMetadata struct {
+		ETag         string    `json:"etag"`
+		LastModified time.Time `json:"lastmodified"`
+	}
+
+	// An S3Gateway serves the S3 API on its own listener, storing objects with
+	// the renter.
+	S3Gateway struct {
+		creds    modules.S3Credentials
+		renter   modules.Renter
+		listener net.Listener
+
+		// objects holds the metadata of the objects uploaded through the
+		// gateway, keyed by siapath. Objects uploaded by other means have no
+		// metadata.
+		objects map[string]objectMetadata
+
+		log        *persist.Logger
+		mu         sync.Mutex
+		persistDir string
+	}
+)
+
+// New creates an S3 gateway that stores objects with r and listens on addr.
+// If creds has an access key ID, every request must be signed with creds.
+func New(r modules.Renter, addr string, creds modules.S3Credentials, persistDir string) (*S3Gateway, error) {
+	if r == nil {
+		return nil, errNilRenter
+	}
+	g := &S3Gateway{
+		creds:      creds,
+		renter:     r,
+		objects:    make(map[string]objectMetadata),
+		persistDir: persistDir,
+	}
+
+	if err := os.MkdirAll(persistDir, 0700); err != nil {
+		return nil, err
+	}
+	var err error
+	g.log, err = persist.NewFileLogger(filepath.Join(persistDir, logFile))
+	if err != nil {
+		return nil, err
+	}
+	err = persist.LoadJSON(objectsMetadata, &g.objects, filepath.Join(persistDir, objectsFile))
+	if err != nil && !os.IsNotExist(err) {
+		g.log.Close()
+		return nil, err
+	}
+
+	g.listener, err = net.Listen("tcp", addr)
+	if err != nil {
+		g.log.Close()
+		return nil, err
+	}
+	go g.threadedServe()
+	g.log.Println("INFO: S3 gateway listening on", g.listener.Addr())
+	return g, nil
+}
+
+// threadedServe serves the S3 API until the listener is closed.
+func (g *S3Gateway) threadedServe() {
+	err := http.Serve(g.listener, g)
+	if err != nil && !strings.HasSuffix(err.Error(), "use of closed network connection") {
+		g.log.Println("ERROR: S3 gateway stopped serving:", err)
+	}
+}
+
+// Address returns the address that the gateway is listening on.
+func (g *S3Gateway) Address() modules.NetAddress {
+	return modules.NetAddress(g.listener.Addr().String())
+}
+
+// Close stops the gateway from accepting requests.
+func (g *S3Gateway) Close() error {
+	err := g.listener.Close()
+	g.log.Close()
+	return err
+}
+
+// saveObjects saves the metadata of the objects. The caller must hold the
+// lock.
+func (g *S3Gateway) saveObjects() error {
+	return persist.SaveJSON(objectsMetadata, g.objects, filepath.Join(g.persistDir, objectsFile))
+}
+
+// ServeHTTP implements http.Handler, dispatching S3 requests by the path and
+// method of the request.
+func (g *S3Gateway) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	payloadHash, err := g.authenticate(req)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	bucket, key := splitPath(req.URL.Path)
+	query := req.URL.Query()
+	if _, ok := query["uploads"]; ok || query.Get("uploadId") != "" {
+		writeError(w, req, errNotImplemented("multipart uploads are not supported"))
+		return
+	}
+	switch {
+	case bucket == "" && req.Method == "GET":
+		g.listBuckets(w, req)
+	case bucket == "":
+		writeError(w, req, errMethodNotAllowed)
+	case key == "" && req.Method == "GET":
+		g.listObjects(w, req, bucket)
+	case key == "" && req.Method == "HEAD":
+		g.headBucket(w, req, bucket)
+	case key == "" && req.Method == "PUT":
+		g.createBucket(w, req, bucket)
+	case key == "" && req.Method == "DELETE":
+		g.deleteBucket(w, req, bucket)
+	case key == "":
+		writeError(w, req, errMethodNotAllowed)
+	case req.Method == "GET" || req.Method == "HEAD":
+		g.getObject(w, req, bucket, key)
+	case req.Method == "PUT":
+		g.putObject(w, req, bucket, key, payloadHash)
+	case req.Method == "DELETE":
+		g.deleteObject(w, req, bucket, key)
+	default:
+		writeError(w, req, errMethodNotAllowed)
+	}
+}
+
+// splitPath splits the path of a request into a bucket and the key of an
+// object within it.
+func splitPath(path string) (bucket, key string) {
+	path = strings.TrimPrefix(path, "/")
+	if i := strings.Index(path, "/"); i != -1 {
+		return path[:i], path[i+1:]
+	}
+	return path, ""
+}
@@ -0,0 +1,257 @@
+package s3gateway
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/renter/rentertest"
+)
+
+// s3Tester sends requests to a gateway backed by an in-memory renter.
+type s3Tester struct {
+	t       *testing.T
+	gateway *S3Gateway
+	renter  *rentertest.MemRenter
+}
+
+// do sends a request to the gateway, returning the status and the body of
+// the response.
+func (st *s3Tester) do(method, path string, body []byte, header map[string]string) (int, []byte, http.Header) {
+	req, err := http.NewRequest(method, "http://"+string(st.gateway.Address())+path, bytes.NewReader(body))
+	if err != nil {
+		st.t.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		st.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		st.t.Fatal(err)
+	}
+	return resp.StatusCode, respBody, resp.Header
+}
+
+// expectError sends a request and checks that it fails with the S3 error
+// code.
+func (st *s3Tester) expectError(method, path, code string) {
+	status, body, _ := st.do(method, path, nil, nil)
+	var e struct {
+		Code string
+	}
+	if err := xml.Unmarshal(body, &e); err != nil || e.Code != code {
+		st.t.Fatalf("%v %v: expected %v, got %v %s", method, path, code, status, body)
+	}
+}
+
+func newS3Tester(t *testing.T) *s3Tester {
+	r := rentertest.NewMemRenter()
+	g, err := New(r, "localhost:0", modules.S3Credentials{}, build.TempDir("s3gateway", t.Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &s3Tester{t: t, gateway: g, renter: r}
+}
+
+// TestBuckets checks that buckets can be created, listed and deleted.
+func TestBuckets(t *testing.T) {
+	st := newS3Tester(t)
+	defer st.gateway.Close()
+
+	if status, _, _ := st.do("PUT", "/foo-bar", nil, nil); status != http.StatusOK {
+		t.Fatal("could not create bucket:", status)
+	}
+	st.expectError("PUT", "/foo-bar", "BucketAlreadyOwnedByYou")
+	st.expectError("PUT", "/Foo", "InvalidBucketName")
+	if status, _, _ := st.do("HEAD", "/foo-bar", nil, nil); status != http.StatusOK {
+		t.Fatal("bucket does not exist:", status)
+	}
+	if status, _, _ := st.do("HEAD", "/baz", nil, nil); status != http.StatusNotFound {
+		t.Fatal("expected a nonexistent bucket, got", status)
+	}
+
+	_, body, _ := st.do("GET", "/", nil, nil)
+	var buckets listAllMyBucketsResult
+	if err := xml.Unmarshal(body, &buckets); err != nil {
+		t.Fatal(err)
+	}
+	if len(buckets.Buckets) != 1 || buckets.Buckets[0].Name != "foo-bar" {
+		t.Fatal("wrong buckets were listed:", buckets.Buckets)
+	}
+
+	// Only empty buckets can be deleted.
+	st.do("PUT", "/foo-bar/obj", []byte("data"), nil)
+	st.expectError("DELETE", "/foo-bar", "BucketNotEmpty")
+	st.do("DELETE", "/foo-bar/obj", nil, nil)
+	if status, _, _ := st.do("DELETE", "/foo-bar", nil, nil); status != http.StatusNoContent {
+		t.Fatal("could not delete bucket:", status)
+	}
+	st.expectError("DELETE", "/foo-bar", "NoSuchBucket")
+}
+
+// TestObjects checks that objects can be uploaded, downloaded, replaced and
+// deleted.
+func TestObjects(t *testing.T) {
+	st := newS3Tester(t)
+	defer st.gateway.Close()
+	st.do("PUT", "/bucket", nil, nil)
+
+	data := []byte("hello, world")
+	sum := md5.Sum(data)
+	md5Header := map[string]string{"Content-MD5": base64.StdEncoding.EncodeToString(sum[:])}
+	status, _, header := st.do("PUT", "/bucket/dir/hello.txt", data, md5Header)
+	if status != http.StatusOK || header.Get("ETag") != `"e4d7f1b4ed2e42d15898f4b27b019da4"` {
+		t.Fatal("could not upload object:", status, header.Get("ETag"))
+	}
+	status, body, header := st.do("GET", "/bucket/dir/hello.txt", nil, nil)
+	if status != http.StatusOK || !bytes.Equal(body, data) {
+		t.Fatal("wrong object was downloaded:", status, string(body))
+	} else if header.Get("ETag") != `"e4d7f1b4ed2e42d15898f4b27b019da4"` || header.Get("Last-Modified") == "" {
+		t.Fatal("object metadata is missing:", header)
+	} else if !strings.HasPrefix(header.Get("Content-Type"), "text/plain") {
+		t.Fatal("wrong Content-Type:", header.Get("Content-Type"))
+	}
+	status, body, header = st.do("GET", "/bucket/dir/hello.txt", nil, map[string]string{"Range": "bytes=7-"})
+	if status != http.StatusPartialContent || string(body) != "world" || header.Get("Content-Range") != "bytes 7-11/12" {
+		t.Fatal("range was not downloaded:", status, string(body))
+	}
+	status, body, header = st.do("HEAD", "/bucket/dir/hello.txt", nil, nil)
+	if status != http.StatusOK || len(body) != 0 || header.Get("Content-Length") != "12" {
+		t.Fatal("wrong HEAD response:", status, header)
+	}
+
+	// The object is replaced by a new upload, and the upload is not left
+	// behind.
+	st.do("PUT", "/bucket/dir/hello.txt", []byte("bye"), nil)
+	if _, body, _ = st.do("GET", "/bucket/dir/hello.txt", nil, nil); string(body) != "bye" {
+		t.Fatal("object was not replaced:", string(body))
+	}
+	if len(st.renter.Files()) != 1 {
+		t.Fatal("expected 1 file, got", len(st.renter.Files()))
+	}
+
+	// An upload with the wrong digest is rejected, and does not replace
+	// the object.
+	md5Header["Content-MD5"] = base64.StdEncoding.EncodeToString(make([]byte, md5.Size))
+	if status, _, _ = st.do("PUT", "/bucket/dir/hello.txt", data, md5Header); status != http.StatusBadRequest {
+		t.Fatal("expected an upload with a bad digest to fail, got", status)
+	}
+	if _, body, _ = st.do("GET", "/bucket/dir/hello.txt", nil, nil); string(body) != "bye" || len(st.renter.Files()) != 1 {
+		t.Fatal("upload with a bad digest replaced the object")
+	}
+
+	st.expectError("GET", "/bucket/nonexistent", "NoSuchKey")
+	st.expectError("GET", "/nonexistent/foo", "NoSuchBucket")
+	st.expectError("PUT", "/nonexistent/foo", "NoSuchBucket")
+	st.expectError("POST", "/bucket/foo?uploads", "NotImplemented")
+
+	if status, _, _ = st.do("DELETE", "/bucket/dir/hello.txt", nil, nil); status != http.StatusNoContent {
+		t.Fatal("could not delete object:", status)
+	}
+	st.expectError("GET", "/bucket/dir/hello.txt", "NoSuchKey")
+	// Deleting a nonexistent object succeeds.
+	if status, _, _ = st.do("DELETE", "/bucket/dir/hello.txt", nil, nil); status != http.StatusNoContent {
+		t.Fatal("could not delete nonexistent object:", status)
+	}
+}
+
+// TestListObjects checks the listings of objects, with prefixes, delimiters
+// and pagination.
+func TestListObjects(t *testing.T) {
+	st := newS3Tester(t)
+	defer st.gateway.Close()
+	st.do("PUT", "/bucket", nil, nil)
+	for _, key := range []string{"a.txt", "b/1.txt", "b/2.txt", "c/d/3.txt"} {
+		st.do("PUT", "/bucket/"+key, []byte(key), nil)
+	}
+	list := func(query string) listBucketResult {
+		status, body, _ := st.do("GET", "/bucket?"+query, nil, nil)
+		if status != http.StatusOK {
+			t.Fatalf("could not list %v: %v %s", query, status, body)
+		}
+		var res listBucketResult
+		if err := xml.Unmarshal(body, &res); err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+	keys := func(res listBucketResult) string {
+		var s []string
+		for _, o := range res.Contents {
+			s = append(s, o.Key)
+		}
+		for _, cp := range res.CommonPrefixes {
+			s = append(s, cp.Prefix)
+		}
+		return strings.Join(s, ",")
+	}
+
+	if res := list(""); keys(res) != "a.txt,b/1.txt,b/2.txt,c/d/3.txt" || res.Contents[0].Size != 5 {
+		t.Fatal("wrong listing:", keys(res))
+	}
+	if res := list("delimiter=/"); keys(res) != "a.txt,b/,c/" {
+		t.Fatal("wrong listing with delimiter:", keys(res))
+	}
+	if res := list("prefix=b/&delimiter=/"); keys(res) != "b/1.txt,b/2.txt" {
+		t.Fatal("wrong listing with prefix:", keys(res))
+	}
+
+	// Page through the objects one at a time with both versions of the
+	// listing.
+	var v1, v2 []string
+	for marker := ""; ; {
+		res := list("delimiter=/&max-keys=1&marker=" + marker)
+		v1 = append(v1, keys(res))
+		if !res.IsTruncated {
+			break
+		}
+		marker = res.NextMarker
+	}
+	for token := ""; ; {
+		res := list("list-type=2&delimiter=/&max-keys=1&continuation-token=" + token)
+		v2 = append(v2, keys(res))
+		if !res.IsTruncated {
+			break
+		}
+		token = url.QueryEscape(res.NextContinuationToken)
+	}
+	if strings.Join(v1, ",") != "a.txt,b/,c/" || strings.Join(v2, ",") != "a.txt,b/,c/" {
+		t.Fatal("wrong pages:", v1, v2)
+	}
+}
+
+// TestObjectMetadataPersist checks that the metadata of objects survives a
+// restart of the gateway.
+func TestObjectMetadataPersist(t *testing.T) {
+	st := newS3Tester(t)
+	st.do("PUT", "/bucket", nil, nil)
+	st.do("PUT", "/bucket/foo", []byte("foo"), nil)
+	_, _, header := st.do("HEAD", "/bucket/foo", nil, nil)
+	if err := st.gateway.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := New(st.renter, "localhost:0", modules.S3Credentials{}, st.gateway.persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	st.gateway = g
+	_, _, header2 := st.do("HEAD", "/bucket/foo", nil, nil)
+	if header2.Get("ETag") != header.Get("ETag") || header2.Get("Last-Modified") != header.Get("Last-Modified") {
+		t.Fatal("metadata was not persisted:", header, header2)
+	}
+}
@@ -2,7 +2,13 @@ package modules
 
 import (
 	"bytes"
+	"encoding/csv"
 	"errors"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/NebulousLabs/entropy-mnemonics"
 
@@ -14,6 +20,10 @@ const (
 	// WalletDir is the directory that contains the wallet persistence.
 	WalletDir = "wallet"
 
+	// NamedWalletsDir is the directory that contains a subdirectory with the
+	// persistence of each named wallet.
+	NamedWalletsDir = "wallets"
+
 	// SeedChecksumSize is the number of bytes that are used to checksum
 	// addresses to prevent accidental spending.
 	SeedChecksumSize = 6
@@ -41,6 +51,34 @@ var (
 	// ErrLockedWallet is returned when an action cannot be performed due to
 	// the wallet being locked.
 	ErrLockedWallet = errors.New("wallet must be unlocked before it can be used")
+
+	// ErrMismatchedTransactions is returned when signatures are merged from
+	// transactions that are not copies of the same transaction.
+	ErrMismatchedTransactions = errors.New("cannot merge the signatures of different transactions")
+
+	// ErrNoTransactions is returned when signatures are merged from an empty
+	// set of transactions.
+	ErrNoTransactions = errors.New("no transactions to merge")
+
+	// ErrUnknownSeedDictionary is returned when a seed phrase does not decode
+	// to a valid seed in any of the SeedDictionaries.
+	ErrUnknownSeedDictionary = errors.New("seed is not a valid seed phrase in any of the known dictionaries")
+
+	// SeedDictionaries are the dictionaries that seed phrases can be written
+	// in.
+	SeedDictionaries = []mnemonics.DictionaryID{mnemonics.English, mnemonics.German, mnemonics.Japanese}
+)
+
+// The statuses of an AtomicSwap. A pending swap has not been confirmed yet,
+// and expires if it is not confirmed before its redemption window opens. An
+// active swap can be redeemed until its timelock. A swap is refunded once its
+// timelock is reached without a redemption.
+const (
+	AtomicSwapPending  = "pending"
+	AtomicSwapExpired  = "expired"
+	AtomicSwapActive   = "active"
+	AtomicSwapRedeemed = "redeemed"
+	AtomicSwapRefunded = "refunded"
 )
 
 type (
@@ -100,6 +138,210 @@ type (
 		Outputs []ProcessedOutput `json:"outputs"`
 	}
 
+	// A TransactionSummary totals the effect of a ProcessedTransaction on the
+	// wallet. Incoming funds are outputs sent to wallet addresses, outgoing
+	// funds are inputs spent from wallet addresses, and RelatedAddresses are
+	// the addresses outside of the wallet that the transaction sends to or
+	// spends from.
+	TransactionSummary struct {
+		IncomingSiacoins types.Currency     `json:"incomingsiacoins"`
+		OutgoingSiacoins types.Currency     `json:"outgoingsiacoins"`
+		IncomingSiafunds types.Currency     `json:"incomingsiafunds"`
+		OutgoingSiafunds types.Currency     `json:"outgoingsiafunds"`
+		MinerFees        types.Currency     `json:"minerfees"`
+		RelatedAddresses []types.UnlockHash `json:"relatedaddresses"`
+	}
+
+	// A HistoryEntry is a row of the exported wallet history. It summarizes a
+	// confirmed transaction and records the confirmed siacoin and siafund
+	// balances of the wallet after the transaction, along with the memo that
+	// the user attached to the transaction, if any.
+	HistoryEntry struct {
+		TransactionID         types.TransactionID `json:"transactionid"`
+		ConfirmationHeight    types.BlockHeight   `json:"confirmationheight"`
+		ConfirmationTimestamp types.Timestamp     `json:"confirmationtimestamp"`
+		TransactionSummary
+		SiacoinBalance types.Currency `json:"siacoinbalance"`
+		SiafundBalance types.Currency `json:"siafundbalance"`
+		Memo           string         `json:"memo"`
+	}
+
+	// An UnspentOutput is a siacoin or siafund output of the wallet that has
+	// not been spent. The fund types are 'SiacoinOutput' and 'SiafundOutput'.
+	// WatchOnly outputs belong to watch-only addresses and cannot be spent by
+	// the wallet itself.
+	UnspentOutput struct {
+		ID                 types.OutputID    `json:"id"`
+		FundType           types.Specifier   `json:"fundtype"`
+		UnlockHash         types.UnlockHash  `json:"unlockhash"`
+		Value              types.Currency    `json:"value"`
+		ConfirmationHeight types.BlockHeight `json:"confirmationheight"`
+		Confirmations      types.BlockHeight `json:"confirmations"`
+		WatchOnly          bool              `json:"watchonly"`
+	}
+
+	// An AddressLabel is a label that the user gave to one of the wallet's
+	// addresses.
+	AddressLabel struct {
+		Address types.UnlockHash `json:"address"`
+		Label   string           `json:"label"`
+	}
+
+	// A TransactionMemo is a note that the user attached to one of the
+	// wallet's transactions.
+	TransactionMemo struct {
+		TransactionID types.TransactionID `json:"transactionid"`
+		Memo          string              `json:"memo"`
+	}
+
+	// An AddressBookEntry is a named address of a payee outside of the
+	// wallet.
+	AddressBookEntry struct {
+		Name    string           `json:"name"`
+		Address types.UnlockHash `json:"address"`
+	}
+
+	// An UnlockConditionsTemplate is a named set of multisig parameters from
+	// which the wallet generates addresses. Each address requires
+	// SignaturesRequired signatures from a new wallet key and the public keys
+	// of the cosigners, in the same way as NewMultisigAddress.
+	UnlockConditionsTemplate struct {
+		Name               string               `json:"name"`
+		SignaturesRequired uint64               `json:"signaturesrequired"`
+		Cosigners          []types.SiaPublicKey `json:"cosigners"`
+	}
+
+	// A TransactionPreview describes a transaction set that the wallet built
+	// without broadcasting it. Size is the encoded size of the set in bytes,
+	// Fee the sum of its miner fees, Inputs the wallet outputs that it spends,
+	// and Change the value that it returns to the wallet.
+	TransactionPreview struct {
+		Size   uint64                  `json:"size"`
+		Fee    types.Currency          `json:"fee"`
+		Inputs []types.SiacoinOutputID `json:"inputs"`
+		Change types.Currency          `json:"change"`
+	}
+
+	// A BalanceBreakdown separates the siacoin balance of the wallet into its
+	// confirmed outputs, the unconfirmed transactions that add to or take
+	// from it, and the delayed outputs, such as miner payouts, that have not
+	// matured yet. Maturing outputs are not part of the confirmed balance.
+	BalanceBreakdown struct {
+		Confirmed           types.Currency         `json:"confirmed"`
+		UnconfirmedIncoming types.Currency         `json:"unconfirmedincoming"`
+		UnconfirmedOutgoing types.Currency         `json:"unconfirmedoutgoing"`
+		Maturing            types.Currency         `json:"maturing"`
+		MaturingOutputs     []DelayedSiacoinOutput `json:"maturingoutputs"`
+	}
+
+	// A TimelockedBalance is the value of the wallet's outputs whose unlock
+	// conditions cannot be satisfied before UnlockHeight.
+	TimelockedBalance struct {
+		UnlockHeight types.BlockHeight `json:"unlockheight"`
+		Siacoins     types.Currency    `json:"siacoins"`
+		Siafunds     types.Currency    `json:"siafunds"`
+	}
+
+	// A ScheduledTransaction is a signed transaction set that the wallet
+	// broadcasts at the first block whose height is at least Height and whose
+	// timestamp is at least Timestamp. The ID is that of the last transaction
+	// of the set. LastError holds the reason that the most recent attempt to
+	// broadcast the set failed, if any.
+	ScheduledTransaction struct {
+		ID           types.TransactionID `json:"id"`
+		Transactions []types.Transaction `json:"transactions"`
+		Height       types.BlockHeight   `json:"height"`
+		Timestamp    types.Timestamp     `json:"timestamp"`
+		LastError    string              `json:"lasterror"`
+	}
+
+	// A PaymentEvent reports an output that pays one of the wallet's
+	// addresses in a transaction that was not funded by the wallet. An event
+	// is sent when the payment appears in the transaction pool, with zero
+	// Confirmations, and whenever the number of confirmations of the payment
+	// changes. A payment whose block is reverted is reported again with zero
+	// Confirmations. The fund types are 'SiacoinOutput' and 'SiafundOutput'.
+	PaymentEvent struct {
+		ID                 types.OutputID      `json:"id"`
+		TransactionID      types.TransactionID `json:"transactionid"`
+		FundType           types.Specifier     `json:"fundtype"`
+		Address            types.UnlockHash    `json:"address"`
+		Value              types.Currency      `json:"value"`
+		ConfirmationHeight types.BlockHeight   `json:"confirmationheight"`
+		Confirmations      types.BlockHeight   `json:"confirmations"`
+	}
+
+	// An AutoLockStatus reports the auto-lock timer of the wallet. Timeout is
+	// the time after which the wallet locks itself once it is unlocked, or
+	// zero if it stays unlocked until it is locked explicitly. TimeUntilLock
+	// is the time left before the wallet locks itself, or zero if no timer is
+	// running. UnlockedSince is the time the wallet was last unlocked, and
+	// LastAutoLock the last time it locked itself; both are zero if it never
+	// was.
+	AutoLockStatus struct {
+		Timeout       time.Duration
+		TimeUntilLock time.Duration
+		UnlockedSince types.Timestamp
+		LastAutoLock  types.Timestamp
+	}
+
+	// A LockEvent reports that the wallet locked itself because its auto-lock
+	// timeout elapsed.
+	LockEvent struct {
+		UnlockedSince types.Timestamp `json:"unlockedsince"`
+		Timestamp     types.Timestamp `json:"timestamp"`
+	}
+
+	// An AtomicSwap is a hashlocked and timelocked file contract, which
+	// allows siacoins to be exchanged with coins of another blockchain. The
+	// contract pays Value to RedeemAddress if the 32 byte Secret whose Merkle
+	// root is SecretHash is revealed between WindowStart and Timelock, and
+	// pays it to RefundAddress otherwise. Value is the payout of the contract
+	// minus the siafund fee. Secret is empty until it is known to the wallet,
+	// either because the wallet created the swap or because the secret was
+	// revealed by a redemption.
+	AtomicSwap struct {
+		ID                 types.FileContractID `json:"id"`
+		Value              types.Currency       `json:"value"`
+		SecretHash         crypto.Hash          `json:"secrethash"`
+		Secret             crypto.Hash          `json:"secret"`
+		RedeemAddress      types.UnlockHash     `json:"redeemaddress"`
+		RefundAddress      types.UnlockHash     `json:"refundaddress"`
+		WindowStart        types.BlockHeight    `json:"windowstart"`
+		Timelock           types.BlockHeight    `json:"timelock"`
+		Confirmed          bool                 `json:"confirmed"`
+		ConfirmationHeight types.BlockHeight    `json:"confirmationheight"`
+		Redeemed           bool                 `json:"redeemed"`
+		Status             string               `json:"status"`
+	}
+
+	// DefragSettings control how the wallet consolidates its siacoin outputs.
+	// When Enabled, the wallet defragments itself after a block if it has
+	// more than Threshold spendable outputs and the estimated fee per byte is
+	// at most MaxFeePerByte. Each defragmentation spends BatchSize outputs,
+	// skipping the StartIndex largest ones.
+	DefragSettings struct {
+		Enabled       bool           `json:"enabled"`
+		Threshold     uint64         `json:"threshold"`
+		BatchSize     uint64         `json:"batchsize"`
+		StartIndex    uint64         `json:"startindex"`
+		MaxFeePerByte types.Currency `json:"maxfeeperbyte"`
+	}
+
+	// A Signer produces signatures for public keys whose secret keys are kept
+	// outside of the wallet, such as on a hardware wallet. The signer is given
+	// the transaction along with the hash to sign, so that a device can
+	// display the transaction and ask for confirmation before signing.
+	Signer interface {
+		// PublicKeys returns the public keys that the signer can sign for.
+		PublicKeys() ([]types.SiaPublicKey, error)
+
+		// SignHash returns the signature of hash made with the secret key of
+		// spk. hash is the SigHash of the transaction signature at index
+		// sigIndex of txn.
+		SignHash(spk types.SiaPublicKey, hash crypto.Hash, txn types.Transaction, sigIndex int) ([]byte, error)
+	}
+
 	// TransactionBuilder is used to construct custom transactions. A transaction
 	// builder is initialized via 'RegisterTransaction' and then can be modified by
 	// adding funds or other fields. The transaction is completed by calling
@@ -120,6 +362,16 @@ type (
 		// transaction failed.
 		FundSiacoins(amount types.Currency) error
 
+		// FundSiacoinsFromOutputs works like FundSiacoins, but only spends
+		// the wallet's siacoin outputs whose ids are in ids. An error is
+		// returned if any of them is not a spendable output of the wallet.
+		FundSiacoinsFromOutputs(amount types.Currency, ids []types.SiacoinOutputID) error
+
+		// FundSiacoinsWithConfirmations works like FundSiacoins, but only
+		// spends siacoin outputs that have been confirmed by at least
+		// minConfirmations blocks. Unconfirmed outputs are never spent.
+		FundSiacoinsWithConfirmations(amount types.Currency, minConfirmations types.BlockHeight) error
+
 		// FundSiafunds will add a siafund input of exactly 'amount' to the
 		// transaction. A parent transaction may be needed to achieve an input
 		// with the correct value. The siafund input will not be signed until
@@ -243,6 +495,12 @@ type (
 		// until the blockchain is fully synced.
 		InitFromSeed(masterKey crypto.TwofishKey, seed Seed) error
 
+		// Recover initializes the wallet from a seed and unlocks it, which
+		// rescans the blockchain and rebuilds the balances of the seed's
+		// addresses. Like InitFromSeed, Recover should not be called until the
+		// blockchain is fully synced.
+		Recover(masterKey crypto.TwofishKey, seed Seed) error
+
 		// Lock deletes all keys in memory and prevents the wallet from being
 		// used to spend coins or extract keys until 'Unlock' is called.
 		Lock() error
@@ -256,6 +514,22 @@ type (
 		// derived from the master key.
 		Unlock(masterKey crypto.TwofishKey) error
 
+		// UnlockWithTimeout unlocks the wallet like Unlock, and locks it
+		// again once the timeout has elapsed.
+		UnlockWithTimeout(masterKey crypto.TwofishKey, timeout time.Duration) error
+
+		// AutoLock returns the state of the auto-lock timer of the wallet.
+		AutoLock() AutoLockStatus
+
+		// SetAutoLockTimeout sets the time after which the wallet locks
+		// itself once it is unlocked. A zero timeout disables the auto-lock.
+		SetAutoLockTimeout(timeout time.Duration) error
+
+		// SubscribeLockEvents returns a channel that receives an event
+		// whenever the wallet locks itself. The channel is closed when
+		// unsubscribe is called or the wallet is closed.
+		SubscribeLockEvents() (events <-chan LockEvent, unsubscribe func(), err error)
+
 		// ChangeKey changes the wallet's materKey from masterKey to newKey,
 		// re-encrypting the wallet with the provided key.
 		ChangeKey(masterKey crypto.TwofishKey, newKey crypto.TwofishKey) error
@@ -281,9 +555,16 @@ type (
 		AllSeeds() ([]Seed, error)
 
 		// CreateBackup will create a backup of the wallet at the provided
-		// filepath. The backup will have all seeds and keys.
+		// filepath. The backup is a versioned archive holding a snapshot of
+		// the wallet's database, with all seeds, keys and metadata. It can
+		// be taken while the wallet is in use.
 		CreateBackup(string) error
 
+		// RestoreBackup replaces the wallet with a backup created by
+		// CreateBackup, which must have been encrypted with the provided
+		// key. The wallet is then unlocked, rescanning the blockchain.
+		RestoreBackup(crypto.TwofishKey, string) error
+
 		// LoadBackup will load a backup of the wallet from the provided
 		// address. The backup wallet will be added as an auxiliary seed, not
 		// as a primary seed.
@@ -343,6 +624,11 @@ type (
 		// not considered in the unconfirmed balance.
 		UnconfirmedBalance() (outgoingSiacoins types.Currency, incomingSiacoins types.Currency)
 
+		// BalanceBreakdown returns the confirmed siacoin balance of the
+		// wallet, its unconfirmed inflows and outflows, and the delayed
+		// outputs of the wallet that have not matured yet.
+		BalanceBreakdown() (BalanceBreakdown, error)
+
 		// AddressTransactions returns all of the transactions that are related
 		// to a given address.
 		AddressTransactions(types.UnlockHash) []ProcessedTransaction
@@ -365,41 +651,431 @@ type (
 		// relative to the wallet.
 		UnconfirmedTransactions() []ProcessedTransaction
 
+		// TransactionMemos returns the memos attached to the transactions of
+		// the wallet.
+		TransactionMemos() []TransactionMemo
+
+		// SetTransactionMemo attaches a memo to a transaction of the wallet.
+		// An empty memo removes the memo of the transaction.
+		SetTransactionMemo(txid types.TransactionID, memo string) error
+
 		// RegisterTransaction takes a transaction and its parents and returns
 		// a TransactionBuilder which can be used to expand the transaction.
 		RegisterTransaction(t types.Transaction, parents []types.Transaction) TransactionBuilder
 
+		// UnspentOutputs returns the confirmed siacoin and siafund outputs of
+		// the wallet that have not been spent.
+		UnspentOutputs() ([]UnspentOutput, error)
+
 		// Rescanning reports whether the wallet is currently rescanning the
 		// blockchain.
 		Rescanning() bool
 
+		// Rescan rebuilds the outputs of the wallet and its transaction
+		// history from the given height onwards, keeping the history below
+		// it.
+		Rescan(fromHeight types.BlockHeight) error
+
 		// StartTransaction is a convenience method that calls
 		// RegisterTransaction(types.Transaction{}, nil)
 		StartTransaction() TransactionBuilder
 
+		// EstimateFee returns the recommended fee per byte for a transaction
+		// that should be confirmed within targetBlocks blocks. The wallet
+		// uses it to set the fees of the transactions it sends.
+		EstimateFee(targetBlocks int) (types.Currency, error)
+
 		// SendSiacoins is a tool for sending siacoins from the wallet to an
 		// address. Sending money usually results in multiple transactions. The
 		// transactions are automatically given to the transaction pool, and
 		// are also returned to the caller.
 		SendSiacoins(amount types.Currency, dest types.UnlockHash) ([]types.Transaction, error)
 
+		// SendSiacoinsFromOutputs works like SendSiacoins, but only spends
+		// the wallet's siacoin outputs whose ids are in ids.
+		SendSiacoinsFromOutputs(amount types.Currency, dest types.UnlockHash, ids []types.SiacoinOutputID) ([]types.Transaction, error)
+
+		// SendSiacoinsWithConfirmations works like SendSiacoins, but only
+		// spends siacoin outputs that have been confirmed by at least
+		// minConfirmations blocks.
+		SendSiacoinsWithConfirmations(amount types.Currency, dest types.UnlockHash, minConfirmations types.BlockHeight) ([]types.Transaction, error)
+
+		// PreviewSiacoins describes the transaction set that SendSiacoins,
+		// SendSiacoinsFromOutputs or SendSiacoinsWithConfirmations would
+		// send, without broadcasting it. ids and minConfirmations are ignored
+		// if empty.
+		PreviewSiacoins(amount types.Currency, dest types.UnlockHash, ids []types.SiacoinOutputID, minConfirmations types.BlockHeight) (TransactionPreview, error)
+
 		// SendSiacoinsMulti sends coins to multiple addresses.
 		SendSiacoinsMulti(outputs []types.SiacoinOutput) ([]types.Transaction, error)
 
+		// PreviewSiacoinsMulti describes the transaction set that
+		// SendSiacoinsMulti would send, without broadcasting it.
+		PreviewSiacoinsMulti(outputs []types.SiacoinOutput) (TransactionPreview, error)
+
 		// SendSiafunds is a tool for sending siafunds from the wallet to an
 		// address. Sending money usually results in multiple transactions. The
 		// transactions are automatically given to the transaction pool, and
 		// are also returned to the caller.
 		SendSiafunds(amount types.Currency, dest types.UnlockHash) ([]types.Transaction, error)
+
+		// BumpFee raises the fee of an unconfirmed transaction of the wallet
+		// by spending its change in a child transaction that pays for both.
+		// The parent and child are given to the transaction pool and
+		// returned.
+		BumpFee(txid types.TransactionID) ([]types.Transaction, error)
+
+		// BumpableTransactions returns the IDs of the unconfirmed
+		// transactions of the wallet that are stuck with a low fee and whose
+		// fee can be bumped.
+		BumpableTransactions() []types.TransactionID
+
+		// Defragment consolidates a batch of the wallet's small siacoin
+		// outputs into a single output, regardless of the threshold and fee
+		// limit of the defrag settings. The transactions are given to the
+		// transaction pool and returned.
+		Defragment() ([]types.Transaction, error)
+
+		// DefragSettings returns the settings that control how the wallet
+		// consolidates its outputs.
+		DefragSettings() DefragSettings
+
+		// SetDefragSettings changes the settings that control how the
+		// wallet consolidates its outputs.
+		SetDefragSettings(DefragSettings) error
+
+		// ScanGapLimit returns the number of unused keys that are generated
+		// beyond the largest key index found when scanning the blockchain
+		// for the addresses of a seed.
+		ScanGapLimit() uint64
+
+		// SetScanGapLimit sets the scan gap limit used by InitFromSeed,
+		// Recover, LoadSeed and SweepSeed.
+		SetScanGapLimit(limit uint64) error
+
+		// NewMultisigAddress returns unlock conditions that require
+		// signaturesRequired signatures from a new wallet key and the public
+		// keys of the cosigners.
+		NewMultisigAddress(signaturesRequired uint64, cosigners []types.SiaPublicKey) (types.UnlockConditions, error)
+
+		// NewTimelockedAddress returns unlock conditions for a new wallet
+		// key that cannot be spent before the timelock height. Outputs sent
+		// to the address are tracked by the wallet.
+		NewTimelockedAddress(timelock types.BlockHeight) (types.UnlockConditions, error)
+
+		// TimelockedBalances returns the balances of the wallet that are
+		// still timelocked, grouped by the height at which they unlock.
+		TimelockedBalances() ([]TimelockedBalance, error)
+
+		// ScheduleTransaction stores a signed transaction set to be
+		// broadcast once the blockchain reaches the height and timestamp.
+		ScheduleTransaction(txns []types.Transaction, height types.BlockHeight, timestamp types.Timestamp) (ScheduledTransaction, error)
+
+		// ScheduledTransactions returns the transaction sets that are waiting
+		// to be broadcast.
+		ScheduledTransactions() []ScheduledTransaction
+
+		// CancelScheduledTransaction removes a transaction set that has not
+		// been broadcast yet.
+		CancelScheduledTransaction(id types.TransactionID) error
+
+		// SignTransaction adds the wallet's signatures to the inputs of the
+		// transaction whose parent ids are in toSign, or to every input if
+		// toSign is empty. The transaction may already hold signatures from
+		// cosigners.
+		SignTransaction(txn *types.Transaction, toSign []crypto.Hash) error
+
+		// SignMessage signs an arbitrary message with a key of a wallet
+		// address, proving that the wallet controls the address without
+		// moving any coins. The signature is checked with
+		// types.VerifyMessage.
+		SignMessage(addr types.UnlockHash, msg []byte) (types.MessageSignature, error)
+
+		// AddSigner registers an external signer with the wallet.
+		// SignTransaction asks the signer for the signatures of its public
+		// keys that the wallet cannot produce itself.
+		AddSigner(s Signer) error
+
+		// SignerPublicKeys returns the public keys of the external signers
+		// of the wallet.
+		SignerPublicKeys() []types.SiaPublicKey
+
+		// AddWatchAddresses instructs the wallet to track the outputs and
+		// history of the addresses without being able to spend them. If
+		// unused is true, the wallet skips the rescan of the blockchain.
+		AddWatchAddresses(addrs []types.UnlockHash, unused bool) error
+
+		// AddWatchPublicKeys instructs the wallet to track the standard
+		// addresses of the public keys, which can then be spent by
+		// BuildUnsignedTransaction. If unused is true, the wallet skips the
+		// rescan of the blockchain.
+		AddWatchPublicKeys(spks []types.SiaPublicKey, unused bool) ([]types.UnlockHash, error)
+
+		// RemoveWatchAddresses instructs the wallet to stop tracking the
+		// watch-only addresses. If unused is true, the wallet skips the
+		// rescan of the blockchain.
+		RemoveWatchAddresses(addrs []types.UnlockHash, unused bool) error
+
+		// WatchAddresses returns the watch-only addresses of the wallet.
+		WatchAddresses() []types.UnlockHash
+
+		// AddressLabels returns the labels of the wallet's addresses.
+		AddressLabels() []AddressLabel
+
+		// SetAddressLabel labels an address of the wallet. An empty label
+		// removes the label of the address.
+		SetAddressLabel(addr types.UnlockHash, label string) error
+
+		// AddressBook returns the named payee addresses of the wallet.
+		AddressBook() []AddressBookEntry
+
+		// SetAddressBookEntry adds a named payee address to the address
+		// book, replacing any address of the same name.
+		SetAddressBookEntry(name string, addr types.UnlockHash) error
+
+		// RemoveAddressBookEntry removes a named payee address from the
+		// address book.
+		RemoveAddressBookEntry(name string) error
+
+		// UnlockConditionsTemplates returns the multisig templates of the
+		// wallet.
+		UnlockConditionsTemplates() []UnlockConditionsTemplate
+
+		// SetUnlockConditionsTemplate stores a multisig template, replacing
+		// any template of the same name.
+		SetUnlockConditionsTemplate(UnlockConditionsTemplate) error
+
+		// RemoveUnlockConditionsTemplate removes the multisig template with
+		// the given name.
+		RemoveUnlockConditionsTemplate(name string) error
+
+		// NewTemplateAddress returns the unlock conditions of a new address
+		// generated from the named multisig template.
+		NewTemplateAddress(name string) (types.UnlockConditions, error)
+
+		// BuildUnsignedTransaction returns a transaction that sends the
+		// outputs using the outputs of watch-only addresses. The signatures
+		// of the transaction have their covered fields set but are empty, so
+		// that they can be filled in offline by SignTransaction.
+		BuildUnsignedTransaction(outputs []types.SiacoinOutput) (types.Transaction, error)
+
+		// BroadcastSigned submits a transaction that was built by
+		// BuildUnsignedTransaction and signed offline to the transaction
+		// pool.
+		BroadcastSigned(txn types.Transaction) error
+
+		// CreateAtomicSwap funds and broadcasts a swap contract that pays
+		// value, minus the siafund fee, to redeemAddr if the secret of
+		// secretHash is revealed before timelock, and refunds it to the
+		// wallet otherwise. If secretHash is empty, the wallet generates
+		// the secret. The transactions are returned so that the contract
+		// can be sent to the counterparty for auditing.
+		CreateAtomicSwap(value types.Currency, redeemAddr types.UnlockHash, secretHash crypto.Hash, timelock types.BlockHeight) (AtomicSwap, []types.Transaction, error)
+
+		// AuditAtomicSwap returns the terms of the swap contract in txn.
+		// Swaps that pay to or refund to the wallet are tracked.
+		AuditAtomicSwap(txn types.Transaction) (AtomicSwap, error)
+
+		// RedeemAtomicSwap claims a tracked swap by revealing its secret.
+		RedeemAtomicSwap(id types.FileContractID, secret crypto.Hash) ([]types.Transaction, error)
+
+		// AtomicSwaps returns the swaps tracked by the wallet.
+		AtomicSwaps() []AtomicSwap
+
+		// SubscribePayments returns a channel that receives the payment
+		// events of the wallet, until the payments have the given number of
+		// confirmations. The channel is closed when unsubscribe is called or
+		// the wallet is closed.
+		SubscribePayments(confirmations types.BlockHeight) (events <-chan PaymentEvent, unsubscribe func(), err error)
 	}
 )
 
+// NamedWallets manages wallets that run alongside the main wallet. Each named
+// wallet has its own seed, password and persist directory. Other modules,
+// such as the host and the renter, only use the main wallet.
+type NamedWallets interface {
+	// CreateNamedWallet creates a new wallet with the given name. The wallet
+	// must be initialized with a seed before it can be used.
+	CreateNamedWallet(name string) (Wallet, error)
+
+	// NamedWallet returns the wallet with the given name.
+	NamedWallet(name string) (Wallet, error)
+
+	// NamedWalletNames returns the names of the wallets in alphabetical
+	// order.
+	NamedWalletNames() []string
+
+	// Close closes all of the named wallets.
+	Close() error
+}
+
 // CalculateWalletTransactionID is a helper function for determining the id of
 // a wallet transaction.
 func CalculateWalletTransactionID(tid types.TransactionID, oid types.OutputID) WalletTransactionID {
 	return WalletTransactionID(crypto.HashAll(tid, oid))
 }
 
+// Summary returns the effect of the transaction on the wallet.
+func (pt ProcessedTransaction) Summary() (ts TransactionSummary) {
+	related := make(map[types.UnlockHash]struct{})
+	addRelated := func(walletAddress bool, uh types.UnlockHash) {
+		if _, exists := related[uh]; !walletAddress && !exists {
+			related[uh] = struct{}{}
+			ts.RelatedAddresses = append(ts.RelatedAddresses, uh)
+		}
+	}
+
+	for _, input := range pt.Inputs {
+		addRelated(input.WalletAddress, input.RelatedAddress)
+		if !input.WalletAddress {
+			continue
+		}
+		switch input.FundType {
+		case types.SpecifierSiacoinInput:
+			ts.OutgoingSiacoins = ts.OutgoingSiacoins.Add(input.Value)
+		case types.SpecifierSiafundInput:
+			ts.OutgoingSiafunds = ts.OutgoingSiafunds.Add(input.Value)
+		}
+	}
+	for _, output := range pt.Outputs {
+		if output.FundType == types.SpecifierMinerFee {
+			ts.MinerFees = ts.MinerFees.Add(output.Value)
+			continue
+		}
+		addRelated(output.WalletAddress, output.RelatedAddress)
+		if !output.WalletAddress {
+			continue
+		}
+		switch output.FundType {
+		case types.SpecifierSiacoinOutput, types.SpecifierMinerPayout, types.SpecifierClaimOutput:
+			ts.IncomingSiacoins = ts.IncomingSiacoins.Add(output.Value)
+		case types.SpecifierSiafundOutput:
+			ts.IncomingSiafunds = ts.IncomingSiafunds.Add(output.Value)
+		}
+	}
+	return ts
+}
+
+// WalletHistory returns the history entries of pts, which must be the
+// confirmed transactions of the wallet in chronological order. The running
+// balances start at zero, so pts should begin at the genesis block.
+func WalletHistory(pts []ProcessedTransaction) []HistoryEntry {
+	// applyNet adds incoming to balance and subtracts outgoing from it. The
+	// balance is clamped at zero in case pts is not the complete history.
+	applyNet := func(balance, incoming, outgoing types.Currency) types.Currency {
+		balance = balance.Add(incoming)
+		if balance.Cmp(outgoing) < 0 {
+			return types.ZeroCurrency
+		}
+		return balance.Sub(outgoing)
+	}
+
+	history := make([]HistoryEntry, 0, len(pts))
+	var scBalance, sfBalance types.Currency
+	for _, pt := range pts {
+		ts := pt.Summary()
+		scBalance = applyNet(scBalance, ts.IncomingSiacoins, ts.OutgoingSiacoins)
+		sfBalance = applyNet(sfBalance, ts.IncomingSiafunds, ts.OutgoingSiafunds)
+		history = append(history, HistoryEntry{
+			TransactionID:         pt.TransactionID,
+			ConfirmationHeight:    pt.ConfirmationHeight,
+			ConfirmationTimestamp: pt.ConfirmationTimestamp,
+			TransactionSummary:    ts,
+			SiacoinBalance:        scBalance,
+			SiafundBalance:        sfBalance,
+		})
+	}
+	return history
+}
+
+// siacoinString returns c as an exact decimal number of siacoins, without
+// trailing zeros.
+func siacoinString(c types.Currency) string {
+	s := new(big.Rat).SetFrac(c.Big(), types.SiacoinPrecision.Big()).FloatString(24)
+	return strings.TrimRight(strings.TrimRight(s, "0"), ".")
+}
+
+// WriteHistoryCSV writes history to w as CSV with a header row. Siacoin
+// amounts are written as exact decimal numbers of siacoins, timestamps in
+// RFC 3339 format in UTC, and the related addresses separated by spaces.
+func WriteHistoryCSV(w io.Writer, history []HistoryEntry) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{
+		"transaction id", "height", "timestamp",
+		"incoming siacoins", "outgoing siacoins", "miner fees",
+		"incoming siafunds", "outgoing siafunds",
+		"siacoin balance", "siafund balance", "related addresses", "memo",
+	})
+	for _, he := range history {
+		related := make([]string, len(he.RelatedAddresses))
+		for i, uh := range he.RelatedAddresses {
+			related[i] = uh.String()
+		}
+		cw.Write([]string{
+			he.TransactionID.String(),
+			strconv.FormatUint(uint64(he.ConfirmationHeight), 10),
+			time.Unix(int64(he.ConfirmationTimestamp), 0).UTC().Format(time.RFC3339),
+			siacoinString(he.IncomingSiacoins),
+			siacoinString(he.OutgoingSiacoins),
+			siacoinString(he.MinerFees),
+			he.IncomingSiafunds.String(),
+			he.OutgoingSiafunds.String(),
+			siacoinString(he.SiacoinBalance),
+			he.SiafundBalance.String(),
+			strings.Join(related, " "),
+			he.Memo,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// MergeTransactionSignatures combines the signatures of copies of the same
+// transaction that were signed by different cosigners. Duplicate signatures
+// are dropped, as are signatures beyond the number that each input requires.
+func MergeTransactionSignatures(txns []types.Transaction) (types.Transaction, error) {
+	if len(txns) == 0 {
+		return types.Transaction{}, ErrNoTransactions
+	}
+
+	// Determine how many signatures each input requires.
+	merged := txns[0]
+	required := make(map[crypto.Hash]uint64)
+	for _, sci := range merged.SiacoinInputs {
+		required[crypto.Hash(sci.ParentID)] = sci.UnlockConditions.SignaturesRequired
+	}
+	for _, fcr := range merged.FileContractRevisions {
+		required[crypto.Hash(fcr.ParentID)] = fcr.UnlockConditions.SignaturesRequired
+	}
+	for _, sfi := range merged.SiafundInputs {
+		required[crypto.Hash(sfi.ParentID)] = sfi.UnlockConditions.SignaturesRequired
+	}
+
+	type sigKey struct {
+		parentID crypto.Hash
+		index    uint64
+	}
+	seen := make(map[sigKey]struct{})
+	counts := make(map[crypto.Hash]uint64)
+	merged.TransactionSignatures = nil
+	id := merged.ID()
+	for _, txn := range txns {
+		if txn.ID() != id {
+			return types.Transaction{}, ErrMismatchedTransactions
+		}
+		for _, sig := range txn.TransactionSignatures {
+			key := sigKey{sig.ParentID, sig.PublicKeyIndex}
+			if _, exists := seen[key]; exists || counts[sig.ParentID] >= required[sig.ParentID] {
+				continue
+			}
+			seen[key] = struct{}{}
+			counts[sig.ParentID]++
+			merged.TransactionSignatures = append(merged.TransactionSignatures, sig)
+		}
+	}
+	return merged, nil
+}
+
 // SeedToString converts a wallet seed to a human friendly string.
 func SeedToString(seed Seed, did mnemonics.DictionaryID) (string, error) {
 	fullChecksum := crypto.HashObject(seed)
@@ -411,9 +1087,12 @@ func SeedToString(seed Seed, did mnemonics.DictionaryID) (string, error) {
 	return phrase.String(), nil
 }
 
-// StringToSeed converts a string to a wallet seed.
+// StringToSeed converts a string to a wallet seed. Words may be separated by
+// any whitespace, so that seeds that were printed over several lines can be
+// entered as they were printed.
 func StringToSeed(str string, did mnemonics.DictionaryID) (Seed, error) {
 	// Decode the string into the checksummed byte slice.
+	str = strings.Join(strings.Fields(str), " ")
 	checksumSeedBytes, err := mnemonics.FromString(str, did)
 	if err != nil {
 		return Seed{}, err
@@ -428,3 +1107,17 @@ func StringToSeed(str string, did mnemonics.DictionaryID) (Seed, error) {
 	}
 	return seed, nil
 }
+
+// StringToSeedAnyDictionary converts a string to a wallet seed, trying each of
+// the SeedDictionaries in turn. The checksum of the seed makes it very
+// unlikely that a phrase decodes to a valid seed in more than one dictionary.
+// The dictionary of the phrase is returned along with the seed.
+func StringToSeedAnyDictionary(str string) (Seed, mnemonics.DictionaryID, error) {
+	for _, did := range SeedDictionaries {
+		seed, err := StringToSeed(str, did)
+		if err == nil {
+			return seed, did, nil
+		}
+	}
+	return Seed{}, "", ErrUnknownSeedDictionary
+}
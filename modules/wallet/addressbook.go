@@ -0,0 +1,134 @@
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	errAddressBookName         = errors.New("address book names cannot be empty or look like an address")
+	errUnknownAddressBookEntry = errors.New("address book does not have an entry with that name")
+	errUnknownWalletAddress    = errors.New("address does not belong to the wallet")
+)
+
+// AddressLabels returns the labels of the wallet's addresses, sorted by
+// address in byte-order.
+func (w *Wallet) AddressLabels() []modules.AddressLabel {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	labels := make([]modules.AddressLabel, 0, len(w.addrLabels))
+	for addr, label := range w.addrLabels {
+		labels = append(labels, modules.AddressLabel{
+			Address: addr,
+			Label:   label,
+		})
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return bytes.Compare(labels[i].Address[:], labels[j].Address[:]) < 0
+	})
+	return labels
+}
+
+// SetAddressLabel labels an address of the wallet, which may be a watch-only
+// address. An empty label removes the label of the address. The wallet must
+// be unlocked, as only then are all of its addresses known.
+func (w *Wallet) SetAddressLabel(addr types.UnlockHash, label string) error {
+	if err := w.tg.Add(); err != nil {
+		return err
+	}
+	defer w.tg.Done()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.unlocked {
+		return modules.ErrLockedWallet
+	}
+	_, isKey := w.keys[addr]
+	_, isWatched := w.watchedAddrs[addr]
+	if !isKey && !isWatched {
+		return errUnknownWalletAddress
+	}
+
+	if label == "" {
+		if err := dbDeleteAddrLabel(w.dbTx, addr); err != nil {
+			return err
+		}
+		delete(w.addrLabels, addr)
+	} else {
+		if err := dbPutAddrLabel(w.dbTx, addr, label); err != nil {
+			return err
+		}
+		w.addrLabels[addr] = label
+	}
+	w.syncDB()
+	return nil
+}
+
+// AddressBook returns the named payee addresses of the wallet, sorted by
+// name.
+func (w *Wallet) AddressBook() []modules.AddressBookEntry {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	entries := make([]modules.AddressBookEntry, 0, len(w.addressBook))
+	for name, addr := range w.addressBook {
+		entries = append(entries, modules.AddressBookEntry{
+			Name:    name,
+			Address: addr,
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
+
+// SetAddressBookEntry adds a named payee address to the address book,
+// replacing any address of the same name. Names that could be mistaken for an
+// address are not allowed, so that a name can be used wherever an address is
+// expected.
+func (w *Wallet) SetAddressBookEntry(name string, addr types.UnlockHash) error {
+	if err := w.tg.Add(); err != nil {
+		return err
+	}
+	defer w.tg.Done()
+	var uh types.UnlockHash
+	if name == "" || uh.LoadString(name) == nil {
+		return errAddressBookName
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := dbPutAddressBookEntry(w.dbTx, name, addr); err != nil {
+		return err
+	}
+	w.addressBook[name] = addr
+	w.syncDB()
+	return nil
+}
+
+// RemoveAddressBookEntry removes a named payee address from the address
+// book.
+func (w *Wallet) RemoveAddressBookEntry(name string) error {
+	if err := w.tg.Add(); err != nil {
+		return err
+	}
+	defer w.tg.Done()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, exists := w.addressBook[name]; !exists {
+		return errUnknownAddressBookEntry
+	}
+	if err := dbDeleteAddressBookEntry(w.dbTx, name); err != nil {
+		return err
+	}
+	delete(w.addressBook, name)
+	w.syncDB()
+	return nil
+}
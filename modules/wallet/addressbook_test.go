@@ -0,0 +1,88 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestAddressBook labels addresses of the wallet, adds payees to the address
+// book, and checks that both persist.
+func TestAddressBook(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// Label an address of the wallet.
+	uc, err := wt.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.SetAddressLabel(types.UnlockHash{1}, "foo"); err != errUnknownWalletAddress {
+		t.Fatal("expected errUnknownWalletAddress, got", err)
+	}
+	if err := wt.wallet.SetAddressLabel(uc.UnlockHash(), "savings"); err != nil {
+		t.Fatal(err)
+	}
+	labels := wt.wallet.AddressLabels()
+	if len(labels) != 1 || labels[0].Address != uc.UnlockHash() || labels[0].Label != "savings" {
+		t.Fatal("wrong labels:", labels)
+	}
+
+	// Add payees to the address book.
+	if err := wt.wallet.SetAddressBookEntry("", types.UnlockHash{1}); err != errAddressBookName {
+		t.Fatal("expected errAddressBookName, got", err)
+	}
+	if err := wt.wallet.SetAddressBookEntry(types.UnlockHash{2}.String(), types.UnlockHash{1}); err != errAddressBookName {
+		t.Fatal("expected errAddressBookName, got", err)
+	}
+	if err := wt.wallet.SetAddressBookEntry("bob", types.UnlockHash{2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.SetAddressBookEntry("alice", types.UnlockHash{1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.RemoveAddressBookEntry("carol"); err != errUnknownAddressBookEntry {
+		t.Fatal("expected errUnknownAddressBookEntry, got", err)
+	}
+	expected := []modules.AddressBookEntry{
+		{Name: "alice", Address: types.UnlockHash{1}},
+		{Name: "bob", Address: types.UnlockHash{2}},
+	}
+	if book := wt.wallet.AddressBook(); len(book) != 2 || book[0] != expected[0] || book[1] != expected[1] {
+		t.Fatal("wrong address book:", book)
+	}
+
+	// Removing a label or an entry should persist as well.
+	if err := wt.wallet.RemoveAddressBookEntry("bob"); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.SetAddressLabel(uc.UnlockHash(), ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.SetAddressLabel(uc.UnlockHash(), "checking"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := wt.wallet.Close(); err != nil {
+		t.Fatal(err)
+	}
+	w, err := New(wt.cs, wt.tpool, wt.wallet.persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt.wallet = w
+	if book := w.AddressBook(); len(book) != 1 || book[0] != expected[0] {
+		t.Fatal("address book was not persisted:", book)
+	}
+	labels = w.AddressLabels()
+	if len(labels) != 1 || labels[0].Label != "checking" {
+		t.Fatal("labels were not persisted:", labels)
+	}
+}
@@ -0,0 +1,115 @@
+package wallet
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	errNegativeAutoLockTimeout = errors.New("auto-lock timeout cannot be negative")
+)
+
+// notifyLock sends a lock event to the lock event subscribers. Events are
+// dropped for subscribers whose buffer is full.
+func (w *Wallet) notifyLock(le modules.LockEvent) {
+	for c := range w.lockSubscribers {
+		select {
+		case c <- le:
+		default:
+		}
+	}
+}
+
+// AutoLock returns the state of the auto-lock timer of the wallet.
+func (w *Wallet) AutoLock() modules.AutoLockStatus {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	status := modules.AutoLockStatus{
+		Timeout: w.autoLockTimeout,
+	}
+	if w.unlocked && w.autoLock != nil {
+		status.TimeUntilLock = w.autoLockDeadline.Sub(time.Now())
+		if status.TimeUntilLock < 0 {
+			status.TimeUntilLock = 0
+		}
+	}
+	if !w.unlockedSince.IsZero() {
+		status.UnlockedSince = types.Timestamp(w.unlockedSince.Unix())
+	}
+	if !w.lastAutoLock.IsZero() {
+		status.LastAutoLock = types.Timestamp(w.lastAutoLock.Unix())
+	}
+	return status
+}
+
+// SetAutoLockTimeout sets the time after which the wallet locks itself once
+// it is unlocked. A zero timeout disables the auto-lock. If the wallet is
+// unlocked, its auto-lock timer is restarted with the new timeout, or stopped
+// if the timeout is zero.
+func (w *Wallet) SetAutoLockTimeout(timeout time.Duration) error {
+	if err := w.tg.Add(); err != nil {
+		return err
+	}
+	defer w.tg.Done()
+	if timeout < 0 {
+		return errNegativeAutoLockTimeout
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := dbPutAutoLockTimeout(w.dbTx, timeout); err != nil {
+		return err
+	}
+	w.autoLockTimeout = timeout
+	w.syncDB()
+	if !w.unlocked {
+		return nil
+	}
+	if timeout > 0 {
+		w.startAutoLock(timeout)
+	} else if w.autoLock != nil {
+		w.autoLock.Stop()
+		w.autoLock = nil
+	}
+	return nil
+}
+
+// SubscribeLockEvents returns a channel that receives an event whenever the
+// wallet locks itself because its auto-lock timeout elapsed. The channel is
+// buffered, and events are dropped if the buffer is full. The channel is
+// closed when unsubscribe is called or the wallet is closed.
+func (w *Wallet) SubscribeLockEvents() (<-chan modules.LockEvent, func(), error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, nil, err
+	}
+	defer w.tg.Done()
+
+	c := make(chan modules.LockEvent, lockEventBuffer)
+	stop := make(chan struct{})
+	w.mu.Lock()
+	w.lockSubscribers[c] = struct{}{}
+	w.mu.Unlock()
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() { close(stop) })
+	}
+	go w.threadedUnsubscribeLockEvents(c, stop)
+	return c, unsubscribe, nil
+}
+
+// threadedUnsubscribeLockEvents removes a lock event subscriber and closes its
+// channel when stop is closed or the wallet is closed.
+func (w *Wallet) threadedUnsubscribeLockEvents(c chan modules.LockEvent, stop chan struct{}) {
+	select {
+	case <-stop:
+	case <-w.tg.StopChan():
+	}
+	w.mu.Lock()
+	delete(w.lockSubscribers, c)
+	close(c)
+	w.mu.Unlock()
+}
@@ -0,0 +1,283 @@
+package wallet
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/persist"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/NebulousLabs/bolt"
+)
+
+const (
+	// backupManifestFile and backupDBFile are the names of the files of a
+	// backup archive.
+	backupManifestFile = "manifest.json"
+	backupDBFile       = dbFile
+)
+
+var (
+	backupMetadata = persist.Metadata{
+		Header:  "Wallet Backup",
+		Version: "1.0.0",
+	}
+
+	errBadBackup         = errors.New("file is not a wallet backup")
+	errUnencryptedBackup = errors.New("backup does not contain an encrypted wallet")
+)
+
+// backupManifest is the first file of a backup archive, identifying the
+// archive and the state of the wallet when it was taken.
+type backupManifest struct {
+	persist.Metadata
+	Height    types.BlockHeight `json:"height"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+// createBackup writes a backup archive to dst. The archive is a tar file
+// holding a manifest and a snapshot of the wallet database, which contains the
+// encrypted seeds and keys, the address metadata, and the history of the
+// wallet. The caller must hold the wallet lock.
+func (w *Wallet) createBackup(dst io.Writer) error {
+	// Commit pending changes, so that the snapshot is consistent.
+	w.syncDB()
+	height, err := dbGetConsensusHeight(w.dbTx)
+	if err != nil {
+		return err
+	}
+	manifest, err := json.Marshal(backupManifest{
+		Metadata:  backupMetadata,
+		Height:    height,
+		Timestamp: time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(dst)
+	err = tw.WriteHeader(&tar.Header{
+		Name:    backupManifestFile,
+		Mode:    0600,
+		Size:    int64(len(manifest)),
+		ModTime: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := tw.Write(manifest); err != nil {
+		return err
+	}
+	err = tw.WriteHeader(&tar.Header{
+		Name:    backupDBFile,
+		Mode:    0600,
+		Size:    w.dbTx.Size(),
+		ModTime: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := w.dbTx.WriteTo(tw); err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// CreateBackup writes a backup archive of the wallet to backupFilepath. The
+// wallet keeps running while the backup is taken.
+func (w *Wallet) CreateBackup(backupFilepath string) error {
+	if err := w.tg.Add(); err != nil {
+		return err
+	}
+	defer w.tg.Done()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	f, err := os.Create(backupFilepath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := w.createBackup(f); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// extractBackup checks the manifest of the backup archive at src and copies
+// its database to a temporary file, returning the name of the file.
+func (w *Wallet) extractBackup(src string) (string, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	tr := tar.NewReader(f)
+
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != backupManifestFile {
+		return "", errBadBackup
+	}
+	var manifest backupManifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return "", errBadBackup
+	}
+	if manifest.Header != backupMetadata.Header {
+		return "", errBadBackup
+	} else if manifest.Version != backupMetadata.Version {
+		return "", persist.ErrBadVersion
+	}
+
+	hdr, err = tr.Next()
+	if err != nil || hdr.Name != backupDBFile {
+		return "", errBadBackup
+	}
+	tmp, err := ioutil.TempFile(w.persistDir, "restore")
+	if err != nil {
+		return "", err
+	}
+	defer tmp.Close()
+	if _, err := io.Copy(tmp, tr); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+// dbRestore replaces the contents of the wallet database with the contents of
+// the backup database src. The outputs and history of the wallet are cleared,
+// and the consensus change ID is reset, so that they are rebuilt by a rescan.
+func dbRestore(tx, src *bolt.Tx) error {
+	for _, name := range dbBuckets {
+		if err := tx.DeleteBucket(name); err != nil {
+			return err
+		}
+		b, err := tx.CreateBucket(name)
+		if err != nil {
+			return err
+		}
+		sb := src.Bucket(name)
+		if sb == nil {
+			continue
+		}
+		switch string(name) {
+		case string(bucketSiacoinOutputs), string(bucketSiafundOutputs), string(bucketSpentOutputs), string(bucketProcessedTransactions):
+			continue
+		}
+		// Values of src are only valid during its transaction, so they
+		// are copied.
+		err = sb.ForEach(func(k, v []byte) error {
+			return b.Put(append([]byte(nil), k...), append([]byte(nil), v...))
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if err := dbPutConsensusHeight(tx, 0); err != nil {
+		return err
+	}
+	if err := dbPutConsensusChangeID(tx, modules.ConsensusChangeBeginning); err != nil {
+		return err
+	}
+	return dbPutSiafundPool(tx, types.ZeroCurrency)
+}
+
+// RestoreBackup replaces the wallet with the backup archive at
+// backupFilepath, which must have been encrypted with masterKey. The backup
+// is checked before the wallet is modified. The wallet is then unlocked,
+// rescanning the blockchain to rebuild the outputs and history of the
+// restored keys.
+func (w *Wallet) RestoreBackup(masterKey crypto.TwofishKey, backupFilepath string) error {
+	if err := w.tg.Add(); err != nil {
+		return err
+	}
+	defer w.tg.Done()
+	if !w.scanLock.TryLock() {
+		return errScanInProgress
+	}
+	defer w.scanLock.Unlock()
+
+	tmp, err := w.extractBackup(backupFilepath)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	backupDB, err := persist.OpenDatabaseReadOnly(dbMetadata, tmp)
+	if err != nil {
+		return err
+	}
+	defer backupDB.Close()
+	err = backupDB.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(bucketWallet) == nil || tx.Bucket(bucketWallet).Get(keyEncryptionVerification) == nil {
+			return errUnencryptedBackup
+		}
+		if err := checkMasterKey(tx, masterKey); err != nil {
+			return err
+		}
+		// Check that the metadata of the backup can be loaded.
+		scratch := &Wallet{
+			watchedAddrs:  make(map[types.UnlockHash]types.UnlockConditions),
+			addrLabels:    make(map[types.UnlockHash]string),
+			addressBook:   make(map[string]types.UnlockHash),
+			ucTemplates:   make(map[string]modules.UnlockConditionsTemplate),
+			txnMemos:      make(map[types.TransactionID]string),
+			scheduledTxns: make(map[types.TransactionID]modules.ScheduledTransaction),
+			atomicSwaps:   make(map[types.FileContractID]modules.AtomicSwap),
+		}
+		return scratch.loadPersist(tx)
+	})
+	if err != nil {
+		return err
+	}
+
+	w.cs.Unsubscribe(w)
+	w.tpool.Unsubscribe(w)
+	w.mu.Lock()
+	// Restore the database in a transaction of its own, so that a failed
+	// restore leaves the wallet untouched.
+	w.syncDB()
+	err = backupDB.View(func(src *bolt.Tx) error {
+		return dbRestore(w.dbTx, src)
+	})
+	if err != nil {
+		w.dbTx.Rollback()
+		var beginErr error
+		if w.dbTx, beginErr = w.db.Begin(true); beginErr != nil {
+			w.log.Severe("ERROR: failed to start database update:", beginErr)
+		}
+		subscribed := w.subscribed
+		ccid := dbGetConsensusChangeID(w.dbTx)
+		w.mu.Unlock()
+		// Resume tracking the blockchain with the unchanged wallet.
+		if subscribed {
+			if err := w.cs.ConsensusSetSubscribe(w, ccid); err != nil {
+				w.log.Println("ERROR: could not resubscribe after a failed restore:", err)
+			} else {
+				w.tpool.TransactionPoolSubscribe(w)
+			}
+		}
+		return err
+	}
+	w.lock()
+	w.clearState()
+	w.unconfirmedSets = make(map[modules.TransactionSetID][]types.TransactionID)
+	w.unconfirmedSpends = make(map[modules.TransactionSetID][]types.OutputID)
+	w.droppedSpends = make(map[types.OutputID]struct{})
+	err = w.loadPersist(w.dbTx)
+	w.encrypted = true
+	w.subscribed = false
+	w.syncDB()
+	w.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	w.log.Println("INFO: Restored the wallet from a backup.")
+	return w.managedUnlock(masterKey)
+}
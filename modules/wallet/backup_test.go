@@ -0,0 +1,127 @@
+package wallet
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestBackupRestore backs up a wallet and restores the backup into another
+// wallet, which should end up with the same keys, metadata and balance.
+func TestBackupRestore(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	uc, err := wt.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.SetAddressLabel(uc.UnlockHash(), "savings"); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.SetAddressBookEntry("bob", types.UnlockHash{2}); err != nil {
+		t.Fatal(err)
+	}
+	dir := build.TempDir(modules.WalletDir, t.Name()+"-backup")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	backup := filepath.Join(dir, "wallet.backup")
+	if err := wt.wallet.CreateBackup(backup); err != nil {
+		t.Fatal(err)
+	}
+
+	// Create a second wallet with its own seed and metadata.
+	w, err := New(wt.cs, wt.tpool, filepath.Join(dir, "restored"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		w.Close()
+	}()
+	key := crypto.TwofishKey{1}
+	if _, err := w.Encrypt(key); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Unlock(key); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetAddressBookEntry("carol", types.UnlockHash{3}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Bad backups and passwords are rejected without modifying the wallet.
+	notBackup := filepath.Join(dir, "notbackup")
+	if err := ioutil.WriteFile(notBackup, []byte("foo"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.RestoreBackup(wt.walletMasterKey, notBackup); err != errBadBackup {
+		t.Fatal("expected errBadBackup, got", err)
+	}
+	if err := w.RestoreBackup(key, backup); err != modules.ErrBadEncryptionKey {
+		t.Fatal("expected ErrBadEncryptionKey, got", err)
+	}
+	if book := w.AddressBook(); len(book) != 1 || book[0].Name != "carol" || !w.Unlocked() {
+		t.Fatal("wallet was modified by a failed restore:", book)
+	}
+
+	// Restore the backup.
+	if err := w.RestoreBackup(wt.walletMasterKey, backup); err != nil {
+		t.Fatal(err)
+	}
+	if !w.Unlocked() {
+		t.Fatal("restored wallet should be unlocked")
+	}
+	if book := w.AddressBook(); len(book) != 1 || book[0].Name != "bob" {
+		t.Fatal("address book was not restored:", book)
+	}
+	if labels := w.AddressLabels(); len(labels) != 1 || labels[0].Label != "savings" {
+		t.Fatal("labels were not restored:", labels)
+	}
+	seed, _, err := w.PrimarySeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedSeed, _, err := wt.wallet.PrimarySeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seed != expectedSeed {
+		t.Fatal("seed was not restored")
+	}
+	balance, _, _ := w.ConfirmedBalance()
+	expected, _, _ := wt.wallet.ConfirmedBalance()
+	if balance.IsZero() || balance.Cmp(expected) != 0 {
+		t.Fatal("rescan did not restore the balance:", balance, expected)
+	}
+	if txns, err := w.Transactions(0, wt.cs.Height()); err != nil || len(txns) == 0 {
+		t.Fatal("rescan did not restore the history:", err)
+	}
+
+	// The restored wallet is persisted.
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	w, err = New(wt.cs, wt.tpool, filepath.Join(dir, "restored"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Unlock(wt.walletMasterKey); err != nil {
+		t.Fatal(err)
+	}
+	if book := w.AddressBook(); len(book) != 1 || book[0].Name != "bob" {
+		t.Fatal("restored address book was not persisted:", book)
+	}
+}
@@ -0,0 +1,179 @@
+package wallet
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	errBumpNotNeeded      = errors.New("transaction already pays the recommended fee")
+	errBumpTooExpensive   = errors.New("change output is not worth the fee needed to bump the transaction")
+	errNoBumpableOutput   = errors.New("transaction has no unspent output of the wallet to fund a fee bump")
+	errUnknownUnconfirmed = errors.New("transaction is not an unconfirmed transaction of the wallet")
+)
+
+// transactionFeePerByte returns the fee per byte paid by txn.
+func transactionFeePerByte(txn types.Transaction) types.Currency {
+	var fees types.Currency
+	for _, fee := range txn.MinerFees {
+		fees = fees.Add(fee)
+	}
+	return fees.Div64(uint64(len(encoding.Marshal(txn))))
+}
+
+// bumpOutput returns the index of the largest spendable siacoin output of txn
+// that belongs to the wallet, which can fund a child transaction paying a
+// higher fee.
+func (w *Wallet) bumpOutput(height types.BlockHeight, txn types.Transaction) (uint64, bool) {
+	var index uint64
+	var value types.Currency
+	found := false
+	for i, sco := range txn.SiacoinOutputs {
+		if w.checkOutput(w.dbTx, height, txn.SiacoinOutputID(uint64(i)), sco) != nil {
+			continue
+		}
+		if !found || sco.Value.Cmp(value) > 0 {
+			index, value, found = uint64(i), sco.Value, true
+		}
+	}
+	return index, found
+}
+
+// BumpableTransactions returns the IDs of the unconfirmed transactions of the
+// wallet that are stuck, paying less than the fee needed to be confirmed
+// within minFeeTargetBlocks blocks, and have an unspent output of the wallet,
+// usually their change, with which BumpFee can raise their fee.
+func (w *Wallet) BumpableTransactions() []types.TransactionID {
+	if err := w.tg.Add(); err != nil {
+		return nil
+	}
+	defer w.tg.Done()
+
+	feePerByte := w.estimateFee(minFeeTargetBlocks)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	height, err := dbGetConsensusHeight(w.dbTx)
+	if err != nil {
+		return nil
+	}
+	var ids []types.TransactionID
+	for _, pt := range w.unconfirmedProcessedTransactions {
+		if transactionFeePerByte(pt.Transaction).Cmp(feePerByte) >= 0 {
+			continue
+		}
+		if _, ok := w.bumpOutput(height, pt.Transaction); ok {
+			ids = append(ids, pt.TransactionID)
+		}
+	}
+	return ids
+}
+
+// BumpFee raises the fee of an unconfirmed transaction of the wallet by
+// spending one of its outputs, usually the change, in a child transaction.
+// The child pays enough of a fee for the parent and child together to pay the
+// fee recommended for the next block, so that miners are paid to confirm the
+// parent along with the child. The parent and child are returned.
+func (w *Wallet) BumpFee(txid types.TransactionID) ([]types.Transaction, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer w.tg.Done()
+
+	feePerByte := w.estimateFee(bumpFeeTargetBlocks)
+	w.mu.Lock()
+	if !w.unlocked {
+		w.mu.Unlock()
+		return nil, modules.ErrLockedWallet
+	}
+	txnSet, err := w.createBumpTransaction(txid, feePerByte)
+	if err != nil {
+		w.mu.Unlock()
+		return nil, err
+	}
+	w.syncDB()
+	w.mu.Unlock()
+
+	if err := w.tpool.AcceptTransactionSet(txnSet); err != nil {
+		// Release the output, so that the bump can be attempted again.
+		w.mu.Lock()
+		dbDeleteSpentOutput(w.dbTx, types.OutputID(txnSet[1].SiacoinInputs[0].ParentID))
+		w.syncDB()
+		w.mu.Unlock()
+		return nil, build.ExtendErr("unable to get transaction accepted", err)
+	}
+	w.log.Printf("Bumped the fee of transaction %v with child %v", txid, txnSet[1].ID())
+	return txnSet, nil
+}
+
+// createBumpTransaction creates the child transaction that raises the fee of
+// the unconfirmed transaction txid to feePerByte, and marks the output it
+// spends as spent. It returns the parent and the child.
+func (w *Wallet) createBumpTransaction(txid types.TransactionID, feePerByte types.Currency) ([]types.Transaction, error) {
+	height, err := dbGetConsensusHeight(w.dbTx)
+	if err != nil {
+		return nil, err
+	}
+	var parent types.Transaction
+	found := false
+	for _, pt := range w.unconfirmedProcessedTransactions {
+		if pt.TransactionID == txid {
+			parent, found = pt.Transaction, true
+			break
+		}
+	}
+	if !found {
+		return nil, errUnknownUnconfirmed
+	}
+	if transactionFeePerByte(parent).Cmp(feePerByte) >= 0 {
+		return nil, errBumpNotNeeded
+	}
+	index, ok := w.bumpOutput(height, parent)
+	if !ok {
+		return nil, errNoBumpableOutput
+	}
+	sco := parent.SiacoinOutputs[index]
+	scoid := parent.SiacoinOutputID(index)
+	uc := w.keys[sco.UnlockHash].UnlockConditions
+
+	// Create the child. It is first signed with the whole output as its fee,
+	// which does not make it smaller than the final transaction, to determine
+	// the size of the transaction set.
+	refundAddr, err := w.nextPrimarySeedAddress(w.dbTx)
+	if err != nil {
+		return nil, err
+	}
+	child := types.Transaction{
+		SiacoinInputs: []types.SiacoinInput{{
+			ParentID:         scoid,
+			UnlockConditions: uc,
+		}},
+		SiacoinOutputs: []types.SiacoinOutput{{
+			Value:      sco.Value,
+			UnlockHash: refundAddr.UnlockHash(),
+		}},
+		MinerFees: []types.Currency{sco.Value},
+	}
+	addSignatures(&child, types.FullCoveredFields, uc, crypto.Hash(scoid), w.keys[sco.UnlockHash])
+	size := len(encoding.Marshal(parent)) + len(encoding.Marshal(child))
+	fee := feePerByte.Mul64(uint64(size))
+	for _, parentFee := range parent.MinerFees {
+		fee = fee.Sub(parentFee)
+	}
+	if fee.Cmp(sco.Value) >= 0 {
+		return nil, errBumpTooExpensive
+	}
+	child.SiacoinOutputs[0].Value = sco.Value.Sub(fee)
+	child.MinerFees[0] = fee
+	child.TransactionSignatures = nil
+	addSignatures(&child, types.FullCoveredFields, uc, crypto.Hash(scoid), w.keys[sco.UnlockHash])
+
+	if err := w.markOutputSpent(types.OutputID(scoid), height); err != nil {
+		return nil, err
+	}
+	return []types.Transaction{parent, child}, nil
+}
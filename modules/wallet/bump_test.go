@@ -0,0 +1,86 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestBumpFee checks that a transaction without a fee can have its fee bumped
+// through its change output.
+func TestBumpFee(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// Send coins without a fee. The set's parent splits an output of the
+	// wallet, returning the change to the wallet, while the child spends
+	// exactly the amount sent.
+	amount := types.SiacoinPrecision.Mul64(100)
+	tb := wt.wallet.StartTransaction()
+	if err := tb.FundSiacoins(amount); err != nil {
+		t.Fatal(err)
+	}
+	tb.AddSiacoinOutput(types.SiacoinOutput{Value: amount})
+	txnSet, err := tb.Sign(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.tpool.AcceptTransactionSet(txnSet); err != nil {
+		t.Fatal(err)
+	}
+	parent, child := txnSet[0], txnSet[1]
+
+	bumpable := wt.wallet.BumpableTransactions()
+	if len(bumpable) != 1 || bumpable[0] != parent.ID() {
+		t.Fatal("only the parent should be bumpable:", bumpable)
+	}
+	if _, err := wt.wallet.BumpFee(child.ID()); err != errNoBumpableOutput {
+		t.Fatal("expected errNoBumpableOutput, got", err)
+	}
+	if _, err := wt.wallet.BumpFee(types.TransactionID{}); err != errUnknownUnconfirmed {
+		t.Fatal("expected errUnknownUnconfirmed, got", err)
+	}
+
+	bumpSet, err := wt.wallet.BumpFee(parent.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bumpSet) != 2 || bumpSet[0].ID() != parent.ID() {
+		t.Fatal("wrong bump set:", bumpSet)
+	}
+	feePerByte := wt.wallet.estimateFee(bumpFeeTargetBlocks)
+	var fees types.Currency
+	var size uint64
+	for _, txn := range bumpSet {
+		for _, fee := range txn.MinerFees {
+			fees = fees.Add(fee)
+		}
+		size += uint64(len(encoding.Marshal(txn)))
+	}
+	if fees.Div64(size).Cmp(feePerByte) < 0 {
+		t.Fatal("bump does not pay the recommended fee:", fees)
+	}
+	if bumpable := wt.wallet.BumpableTransactions(); len(bumpable) != 0 {
+		t.Fatal("no transaction should be bumpable after the bump:", bumpable)
+	}
+	if _, err := wt.wallet.BumpFee(parent.ID()); err != errNoBumpableOutput {
+		t.Fatal("expected errNoBumpableOutput, got", err)
+	}
+
+	// The parent and its fee bump are confirmed together.
+	if err := wt.addBlockNoPayout(); err != nil {
+		t.Fatal(err)
+	}
+	for _, txn := range []types.Transaction{parent, child, bumpSet[1]} {
+		if pt, ok := wt.wallet.Transaction(txn.ID()); !ok || pt.ConfirmationHeight != wt.cs.Height() {
+			t.Fatal("transaction was not confirmed:", txn.ID())
+		}
+	}
+}
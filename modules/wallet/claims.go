@@ -0,0 +1,77 @@
+package wallet
+
+import (
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// spendableClaimBalance returns the siafunds of the wallet that can be spent
+// and the siacoin claim that spending them would release.
+func (w *Wallet) spendableClaimBalance() (siafunds, claim types.Currency, err error) {
+	consensusHeight, err := dbGetConsensusHeight(w.dbTx)
+	if err != nil {
+		return types.Currency{}, types.Currency{}, err
+	}
+	siafundPool, err := dbGetSiafundPool(w.dbTx)
+	if err != nil {
+		return types.Currency{}, types.Currency{}, err
+	}
+	err = dbForEachSiafundOutput(w.dbTx, func(sfoid types.SiafundOutputID, sfo types.SiafundOutput) {
+		key, exists := w.keys[sfo.UnlockHash]
+		if !exists || consensusHeight < key.UnlockConditions.Timelock {
+			return
+		}
+		// Skip outputs that have recently been spent by the wallet.
+		if spendHeight, err := dbGetSpentOutput(w.dbTx, types.OutputID(sfoid)); err == nil && spendHeight+RespendTimeout > consensusHeight {
+			return
+		}
+		if sfo.ClaimStart.Cmp(siafundPool) > 0 {
+			return
+		}
+		siafunds = siafunds.Add(sfo.Value)
+		claim = claim.Add(siafundPool.Sub(sfo.ClaimStart).Mul(sfo.Value).Div(types.SiafundCount))
+	})
+	return siafunds, claim, err
+}
+
+// threadedSweepClaims sends the wallet's siafunds to a new address of the
+// wallet once the siacoin claim of the siafunds exceeds claimSweepThreshold.
+// Spending the siafunds releases the claim, which becomes part of the
+// wallet's siacoin balance after types.MaturityDelay blocks.
+func (w *Wallet) threadedSweepClaims() {
+	if err := w.tg.Add(); err != nil {
+		return
+	}
+	defer w.tg.Done()
+
+	w.mu.Lock()
+	if !w.unlocked {
+		w.mu.Unlock()
+		return
+	}
+	siafunds, claim, err := w.spendableClaimBalance()
+	if err != nil {
+		w.mu.Unlock()
+		w.log.Println("WARN: couldn't compute siafund claim balance:", err)
+		return
+	} else if claim.Cmp(claimSweepThreshold) < 0 {
+		w.mu.Unlock()
+		return
+	}
+	uc, err := w.nextPrimarySeedAddress(w.dbTx)
+	w.syncDB()
+	w.mu.Unlock()
+	if err != nil {
+		w.log.Println("WARN: couldn't create address for siafund claim sweep:", err)
+		return
+	}
+
+	txnSet, err := w.SendSiafunds(siafunds, uc.UnlockHash())
+	if err != nil {
+		w.log.Println("WARN: couldn't sweep siafund claim of", claim.HumanString(), "::", err)
+		return
+	}
+	w.log.Println("Sweeping siafund claim of", claim.HumanString(), "into the wallet, IDs:")
+	for _, txn := range txnSet {
+		w.log.Println("\t", txn.ID())
+	}
+}
@@ -0,0 +1,109 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules/miner"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestSweepClaims checks that the wallet spends its siafunds to itself once
+// their siacoin claim reaches claimSweepThreshold, and that the released claim
+// is attributed to the wallet.
+func TestSweepClaims(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// Load the siafunds into the wallet.
+	err = wt.wallet.LoadSiagKeys(wt.walletMasterKey, []string{"../../types/siag0of1of1.siakey"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// need to reset the miner as well, since it depends on the wallet
+	wt.miner, err = miner.New(wt.cs, wt.tpool, wt.wallet, wt.wallet.persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Grow the siafund pool with the tax of a file contract.
+	payout := types.SiacoinPrecision.Mul64(10e3)
+	tax := types.Tax(wt.cs.Height()+1, payout)
+	outputs := []types.SiacoinOutput{{Value: payout.Sub(tax), UnlockHash: types.UnlockHash{}}}
+	tb := wt.wallet.StartTransaction()
+	if err := tb.FundSiacoins(payout); err != nil {
+		t.Fatal(err)
+	}
+	tb.AddFileContract(types.FileContract{
+		WindowStart:        wt.cs.Height() + 100,
+		WindowEnd:          wt.cs.Height() + 200,
+		Payout:             payout,
+		ValidProofOutputs:  outputs,
+		MissedProofOutputs: outputs,
+	})
+	txns, err := tb.Sign(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.tpool.AcceptTransactionSet(txns); err != nil {
+		t.Fatal(err)
+	}
+	// Claims are only swept on the consensus changes of a synced consensus
+	// set.
+	if err := wt.waitForSync(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	_, siafunds, claim := wt.wallet.ConfirmedBalance()
+	if claim.Cmp(claimSweepThreshold) < 0 {
+		t.Fatalf("claim of %v is below the sweep threshold", claim)
+	}
+
+	// The wallet should sweep the claim.
+	err = build.Retry(50, 100*time.Millisecond, func() error {
+		if len(wt.tpool.TransactionList()) == 0 {
+			return errors.New("claim was not swept")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	_, newSiafunds, newClaim := wt.wallet.ConfirmedBalance()
+	if !newSiafunds.Equals(siafunds) {
+		t.Fatalf("siafund balance changed from %v to %v", siafunds, newSiafunds)
+	}
+	if !newClaim.IsZero() {
+		t.Fatal("claim balance remains after the sweep:", newClaim)
+	}
+
+	// The released claim should be attributed to the wallet.
+	pts, err := wt.wallet.Transactions(wt.cs.Height(), wt.cs.Height())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var released types.Currency
+	for _, pt := range pts {
+		for _, po := range pt.Outputs {
+			if po.FundType == types.SpecifierClaimOutput && po.WalletAddress {
+				released = released.Add(po.Value)
+			}
+		}
+	}
+	if !released.Equals(claim) {
+		t.Fatalf("released claim is %v, expected %v", released, claim)
+	}
+}
@@ -6,16 +6,46 @@ import (
 )
 
 const (
-	// defragThreshold is the number of outputs a wallet is allowed before it is
-	// defragmented.
+	// defragThreshold is the default number of outputs a wallet is allowed
+	// before it is defragmented.
 	defragThreshold = 50
 
-	// defragBatchSize defines how many outputs are combined during one defrag.
+	// defragBatchSize defines how many outputs are combined during one defrag
+	// by default.
 	defragBatchSize = 35
 
-	// defragStartIndex is the number of outputs to skip over when performing a
-	// defrag.
+	// defragStartIndex is the default number of outputs to skip over when
+	// performing a defrag.
 	defragStartIndex = 10
+
+	// minFeeTargetBlocks is the number of blocks within which a transaction
+	// paying the minimum fee recommended by the transaction pool is expected
+	// to be confirmed, if the transaction pool is not congested.
+	minFeeTargetBlocks = 3
+
+	// defaultFeeTargetBlocks is the number of blocks within which the
+	// transactions sent by the wallet are expected to be confirmed.
+	defaultFeeTargetBlocks = 2
+
+	// bumpFeeTargetBlocks is the number of blocks within which a transaction
+	// whose fee is bumped is expected to be confirmed.
+	bumpFeeTargetBlocks = 1
+
+	// swapWindowDelay is the number of blocks after its creation at which
+	// the redemption window of a swap contract opens. The contract must be
+	// confirmed before then.
+	swapWindowDelay = 6
+
+	// maxPaymentConfirmations is the largest number of confirmations for
+	// which payment events are sent.
+	maxPaymentConfirmations = 144
+
+	// maxMemoLength is the largest number of bytes of a transaction memo.
+	maxMemoLength = 1024
+
+	// lockEventBuffer is the number of lock events that are buffered for a
+	// subscriber.
+	lockEventBuffer = 16
 )
 
 var (
@@ -34,6 +64,15 @@ var (
 		Standard: uint64(4000),
 		Testing:  uint64(40),
 	}).(uint64)
+
+	// claimSweepThreshold is the siacoin claim balance at which the wallet
+	// sends its siafunds to itself, releasing the claim into its siacoin
+	// balance.
+	claimSweepThreshold = build.Select(build.Var{
+		Dev:      types.SiacoinPrecision.Mul64(100),
+		Standard: types.SiacoinPrecision.Mul64(1000),
+		Testing:  types.SiacoinPrecision.Mul64(10),
+	}).(types.Currency)
 )
 
 // dustValue is the quantity below which a Currency is considered to be Dust.
@@ -44,16 +83,11 @@ func dustValue() types.Currency {
 	return types.SiacoinPrecision
 }
 
-// defragFee is the miner fee paid to miners when performing a defrag
-// transaction.
-//
-// TODO: These need to be functions of the wallet that interact with the
-// transaction pool.
-func defragFee() types.Currency {
-	// 35 outputs at an estimated 250 bytes needed per output means about a 10kb
-	// total transaction, much larger than your average transaction. So you need
-	// a lot of fees.
-	return types.SiacoinPrecision.Mul64(10)
+// defragMaxFeePerByte is the default highest fee per byte at which the wallet
+// defragments itself. It matches the fee of 10 siacoins that used to be paid
+// for a defrag transaction of about 10kb.
+func defragMaxFeePerByte() types.Currency {
+	return types.SiacoinPrecision.Div64(1e3)
 }
 
 func init() {
@@ -35,6 +35,30 @@ var (
 	// bucketWallet contains various fields needed by the wallet, such as its
 	// UID, EncryptionVerification, and PrimarySeedFile.
 	bucketWallet = []byte("bucketWallet")
+	// bucketWatchedAddrs maps the UnlockHash of a watch-only address to its
+	// UnlockConditions. The UnlockConditions are empty if only the address
+	// was imported.
+	bucketWatchedAddrs = []byte("bucketWatchedAddrs")
+	// bucketAddrLabels maps the UnlockHash of an address of the wallet to the
+	// label that the user gave it.
+	bucketAddrLabels = []byte("bucketAddrLabels")
+	// bucketAddressBook maps the name of a payee to its UnlockHash.
+	bucketAddressBook = []byte("bucketAddressBook")
+	// bucketTimelockedAddrs maps the UnlockHash of a timelocked address of
+	// the wallet to its UnlockConditions.
+	bucketTimelockedAddrs = []byte("bucketTimelockedAddrs")
+	// bucketScheduledTxns maps the ID of a scheduled transaction set to the
+	// ScheduledTransaction.
+	bucketScheduledTxns = []byte("bucketScheduledTxns")
+	// bucketAtomicSwaps maps the FileContractID of a swap contract tracked
+	// by the wallet to its AtomicSwap.
+	bucketAtomicSwaps = []byte("bucketAtomicSwaps")
+	// bucketUCTemplates maps the name of a multisig template to its
+	// UnlockConditionsTemplate.
+	bucketUCTemplates = []byte("bucketUCTemplates")
+	// bucketTxnMemos maps the ID of a wallet transaction to the memo that the
+	// user attached to it.
+	bucketTxnMemos = []byte("bucketTxnMemos")
 
 	dbBuckets = [][]byte{
 		bucketProcessedTransactions,
@@ -42,6 +66,14 @@ var (
 		bucketSiafundOutputs,
 		bucketSpentOutputs,
 		bucketWallet,
+		bucketWatchedAddrs,
+		bucketAddrLabels,
+		bucketAddressBook,
+		bucketTimelockedAddrs,
+		bucketScheduledTxns,
+		bucketAtomicSwaps,
+		bucketUCTemplates,
+		bucketTxnMemos,
 	}
 
 	// these keys are used in bucketWallet
@@ -54,6 +86,9 @@ var (
 	keySpendableKeyFiles      = []byte("keySpendableKeyFiles")
 	keyAuxiliarySeedFiles     = []byte("keyAuxiliarySeedFiles")
 	keySiafundPool            = []byte("keySiafundPool")
+	keyDefragSettings         = []byte("keyDefragSettings")
+	keyScanGapLimit           = []byte("keyScanGapLimit")
+	keyAutoLockTimeout        = []byte("keyAutoLockTimeout")
 
 	errNoKey = errors.New("key does not exist")
 )
@@ -116,6 +151,9 @@ func dbReset(tx *bolt.Tx) error {
 	dbPutConsensusHeight(tx, 0)
 	dbPutConsensusChangeID(tx, modules.ConsensusChangeBeginning)
 	dbPutSiafundPool(tx, types.ZeroCurrency)
+	dbPutDefragSettings(tx, defaultDefragSettings())
+	dbPutScanGapLimit(tx, defaultScanGapLimit)
+	dbPutAutoLockTimeout(tx, 0)
 
 	return nil
 }
@@ -204,6 +242,80 @@ func dbDeleteSpentOutput(tx *bolt.Tx, id types.OutputID) error {
 	return dbDelete(tx.Bucket(bucketSpentOutputs), id)
 }
 
+func dbPutWatchedAddr(tx *bolt.Tx, uh types.UnlockHash, uc types.UnlockConditions) error {
+	return dbPut(tx.Bucket(bucketWatchedAddrs), uh, uc)
+}
+func dbDeleteWatchedAddr(tx *bolt.Tx, uh types.UnlockHash) error {
+	return dbDelete(tx.Bucket(bucketWatchedAddrs), uh)
+}
+func dbForEachWatchedAddr(tx *bolt.Tx, fn func(types.UnlockHash, types.UnlockConditions)) error {
+	return dbForEach(tx.Bucket(bucketWatchedAddrs), fn)
+}
+
+func dbPutTimelockedAddr(tx *bolt.Tx, uh types.UnlockHash, uc types.UnlockConditions) error {
+	return dbPut(tx.Bucket(bucketTimelockedAddrs), uh, uc)
+}
+func dbForEachTimelockedAddr(tx *bolt.Tx, fn func(types.UnlockHash, types.UnlockConditions)) error {
+	return dbForEach(tx.Bucket(bucketTimelockedAddrs), fn)
+}
+
+func dbPutScheduledTxn(tx *bolt.Tx, st modules.ScheduledTransaction) error {
+	return dbPut(tx.Bucket(bucketScheduledTxns), st.ID, st)
+}
+func dbDeleteScheduledTxn(tx *bolt.Tx, id types.TransactionID) error {
+	return dbDelete(tx.Bucket(bucketScheduledTxns), id)
+}
+func dbForEachScheduledTxn(tx *bolt.Tx, fn func(types.TransactionID, modules.ScheduledTransaction)) error {
+	return dbForEach(tx.Bucket(bucketScheduledTxns), fn)
+}
+
+func dbPutAtomicSwap(tx *bolt.Tx, swap modules.AtomicSwap) error {
+	return dbPut(tx.Bucket(bucketAtomicSwaps), swap.ID, swap)
+}
+func dbForEachAtomicSwap(tx *bolt.Tx, fn func(types.FileContractID, modules.AtomicSwap)) error {
+	return dbForEach(tx.Bucket(bucketAtomicSwaps), fn)
+}
+
+func dbPutAddrLabel(tx *bolt.Tx, uh types.UnlockHash, label string) error {
+	return dbPut(tx.Bucket(bucketAddrLabels), uh, label)
+}
+func dbDeleteAddrLabel(tx *bolt.Tx, uh types.UnlockHash) error {
+	return dbDelete(tx.Bucket(bucketAddrLabels), uh)
+}
+func dbForEachAddrLabel(tx *bolt.Tx, fn func(types.UnlockHash, string)) error {
+	return dbForEach(tx.Bucket(bucketAddrLabels), fn)
+}
+
+func dbPutAddressBookEntry(tx *bolt.Tx, name string, uh types.UnlockHash) error {
+	return dbPut(tx.Bucket(bucketAddressBook), name, uh)
+}
+func dbDeleteAddressBookEntry(tx *bolt.Tx, name string) error {
+	return dbDelete(tx.Bucket(bucketAddressBook), name)
+}
+func dbForEachAddressBookEntry(tx *bolt.Tx, fn func(string, types.UnlockHash)) error {
+	return dbForEach(tx.Bucket(bucketAddressBook), fn)
+}
+
+func dbPutUCTemplate(tx *bolt.Tx, t modules.UnlockConditionsTemplate) error {
+	return dbPut(tx.Bucket(bucketUCTemplates), t.Name, t)
+}
+func dbDeleteUCTemplate(tx *bolt.Tx, name string) error {
+	return dbDelete(tx.Bucket(bucketUCTemplates), name)
+}
+func dbForEachUCTemplate(tx *bolt.Tx, fn func(string, modules.UnlockConditionsTemplate)) error {
+	return dbForEach(tx.Bucket(bucketUCTemplates), fn)
+}
+
+func dbPutTxnMemo(tx *bolt.Tx, txid types.TransactionID, memo string) error {
+	return dbPut(tx.Bucket(bucketTxnMemos), txid, memo)
+}
+func dbDeleteTxnMemo(tx *bolt.Tx, txid types.TransactionID) error {
+	return dbDelete(tx.Bucket(bucketTxnMemos), txid)
+}
+func dbForEachTxnMemo(tx *bolt.Tx, fn func(types.TransactionID, string)) error {
+	return dbForEach(tx.Bucket(bucketTxnMemos), fn)
+}
+
 // bucketProcessedTransactions works a little differently: the key is
 // meaningless, only used to order the transactions chronologically.
 
@@ -333,6 +445,49 @@ func dbPutSiafundPool(tx *bolt.Tx, pool types.Currency) error {
 	return tx.Bucket(bucketWallet).Put(keySiafundPool, encoding.Marshal(pool))
 }
 
+// dbGetDefragSettings returns the settings that control defragmentation.
+func dbGetDefragSettings(tx *bolt.Tx) (settings modules.DefragSettings, err error) {
+	err = encoding.Unmarshal(tx.Bucket(bucketWallet).Get(keyDefragSettings), &settings)
+	return
+}
+
+// dbPutDefragSettings stores the settings that control defragmentation.
+func dbPutDefragSettings(tx *bolt.Tx, settings modules.DefragSettings) error {
+	return tx.Bucket(bucketWallet).Put(keyDefragSettings, encoding.Marshal(settings))
+}
+
+// dbGetScanGapLimit returns the gap limit used when scanning for the keys of
+// a seed.
+func dbGetScanGapLimit(tx *bolt.Tx) (limit uint64, err error) {
+	err = encoding.Unmarshal(tx.Bucket(bucketWallet).Get(keyScanGapLimit), &limit)
+	return
+}
+
+// dbPutScanGapLimit stores the gap limit used when scanning for the keys of a
+// seed.
+func dbPutScanGapLimit(tx *bolt.Tx, limit uint64) error {
+	return tx.Bucket(bucketWallet).Put(keyScanGapLimit, encoding.Marshal(limit))
+}
+
+// dbGetAutoLockTimeout returns the timeout after which the wallet locks itself
+// once it is unlocked. Databases restored from backups taken before the
+// timeout was stored have no timeout.
+func dbGetAutoLockTimeout(tx *bolt.Tx) (time.Duration, error) {
+	b := tx.Bucket(bucketWallet).Get(keyAutoLockTimeout)
+	if b == nil {
+		return 0, nil
+	}
+	var timeout uint64
+	err := encoding.Unmarshal(b, &timeout)
+	return time.Duration(timeout), err
+}
+
+// dbPutAutoLockTimeout stores the timeout after which the wallet locks itself
+// once it is unlocked.
+func dbPutAutoLockTimeout(tx *bolt.Tx, timeout time.Duration) error {
+	return tx.Bucket(bucketWallet).Put(keyAutoLockTimeout, encoding.Marshal(uint64(timeout)))
+}
+
 // COMPATv121: these types were stored in the db in v1.2.2 and earlier.
 type (
 	v121ProcessedInput struct {
@@ -5,16 +5,33 @@ import (
 	"sort"
 
 	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
 )
 
 var (
-	errDefragNotNeeded = errors.New("defragging not needed, wallet is already sufficiently defragged")
+	errBadDefragSettings  = errors.New("defrag batch size must be at least 2, and the threshold must be larger than the batch size plus the start index")
+	errDefragNotNeeded    = errors.New("defragging not needed, wallet is already sufficiently defragged")
+	errDefragTooExpensive = errors.New("outputs are not worth the fee needed to defragment them")
 )
 
+// defaultDefragSettings returns the defrag settings of a new wallet.
+func defaultDefragSettings() modules.DefragSettings {
+	return modules.DefragSettings{
+		Enabled:       true,
+		Threshold:     defragThreshold,
+		BatchSize:     defragBatchSize,
+		StartIndex:    defragStartIndex,
+		MaxFeePerByte: defragMaxFeePerByte(),
+	}
+}
+
 // createDefragTransaction creates a transaction that spends multiple existing
-// wallet outputs into a single new address.
-func (w *Wallet) createDefragTransaction() ([]types.Transaction, error) {
+// wallet outputs into a single new address. The wallet must have more than
+// threshold spendable outputs. The fee covers the size of the transaction set
+// at feePerByte.
+func (w *Wallet) createDefragTransaction(threshold uint64, feePerByte types.Currency) ([]types.Transaction, error) {
 	consensusHeight, err := dbGetConsensusHeight(w.dbTx)
 	if err != nil {
 		return nil, err
@@ -34,16 +51,24 @@ func (w *Wallet) createDefragTransaction() ([]types.Transaction, error) {
 	sort.Sort(sort.Reverse(so))
 
 	// Only defrag if there are enough outputs to merit defragging.
-	if len(so.ids) <= defragThreshold {
+	if uint64(len(so.ids)) <= threshold {
 		return nil, errDefragNotNeeded
 	}
 
-	// Skip over the 'defragStartIndex' largest outputs, so that the user can
-	// still reasonably use their wallet while the defrag is happening.
+	// Skip over the 'StartIndex' largest outputs, so that the user can still
+	// reasonably use their wallet while the defrag is happening.
+	start := w.defragSettings.StartIndex
+	end := start + w.defragSettings.BatchSize
+	if end > uint64(len(so.ids)) {
+		end = uint64(len(so.ids))
+	}
+	if end < start+2 {
+		return nil, errDefragNotNeeded
+	}
 	var amount types.Currency
 	var parentTxn types.Transaction
 	var spentScoids []types.SiacoinOutputID
-	for i := defragStartIndex; i < defragStartIndex+defragBatchSize; i++ {
+	for i := start; i < end; i++ {
 		scoid := so.ids[i]
 		sco := so.outputs[i]
 
@@ -77,8 +102,9 @@ func (w *Wallet) createDefragTransaction() ([]types.Transaction, error) {
 		addSignatures(&parentTxn, types.FullCoveredFields, sci.UnlockConditions, crypto.Hash(sci.ParentID), w.keys[sci.UnlockConditions.UnlockHash()])
 	}
 
-	// Create the defrag transaction.
-	fee := defragFee()
+	// Create the defrag transaction. It is first signed with the whole amount
+	// as its fee, which does not make it smaller than the final transaction,
+	// to determine the size of the transaction set.
 	refundAddr, err := w.nextPrimarySeedAddress(w.dbTx)
 	if err != nil {
 		return nil, err
@@ -89,22 +115,31 @@ func (w *Wallet) createDefragTransaction() ([]types.Transaction, error) {
 			UnlockConditions: parentUnlockConditions,
 		}},
 		SiacoinOutputs: []types.SiacoinOutput{{
-			Value:      amount.Sub(fee),
+			Value:      amount,
 			UnlockHash: refundAddr.UnlockHash(),
 		}},
-		MinerFees: []types.Currency{fee},
+		MinerFees: []types.Currency{amount},
 	}
 	addSignatures(&txn, types.FullCoveredFields, parentUnlockConditions, crypto.Hash(parentTxn.SiacoinOutputID(0)), w.keys[parentUnlockConditions.UnlockHash()])
+	size := len(encoding.Marshal(parentTxn)) + len(encoding.Marshal(txn))
+	fee := feePerByte.Mul64(uint64(size))
+	if fee.Cmp(amount) >= 0 {
+		return nil, errDefragTooExpensive
+	}
+	txn.SiacoinOutputs[0].Value = amount.Sub(fee)
+	txn.MinerFees[0] = fee
+	txn.TransactionSignatures = nil
+	addSignatures(&txn, types.FullCoveredFields, parentUnlockConditions, crypto.Hash(parentTxn.SiacoinOutputID(0)), w.keys[parentUnlockConditions.UnlockHash()])
 
 	// Mark all outputs that were spent as spent.
 	for _, scoid := range spentScoids {
-		if err = dbPutSpentOutput(w.dbTx, types.OutputID(scoid), consensusHeight); err != nil {
+		if err = w.markOutputSpent(types.OutputID(scoid), consensusHeight); err != nil {
 			return nil, err
 		}
 	}
 	// Mark the parent output as spent. Must be done after the transaction is
 	// finished because otherwise the txid and output id will change.
-	if err = dbPutSpentOutput(w.dbTx, types.OutputID(parentTxn.SiacoinOutputID(0)), consensusHeight); err != nil {
+	if err = w.markOutputSpent(types.OutputID(parentTxn.SiacoinOutputID(0)), consensusHeight); err != nil {
 		return nil, err
 	}
 
@@ -112,10 +147,10 @@ func (w *Wallet) createDefragTransaction() ([]types.Transaction, error) {
 	return []types.Transaction{parentTxn, txn}, nil
 }
 
-// threadedDefragWallet computes the sum of the 15 largest outputs in the wallet and
-// sends that sum to itself, effectively defragmenting the wallet. This defrag
-// operation is only performed if the wallet has greater than defragThreshold
-// outputs.
+// threadedDefragWallet sends a batch of the wallet's outputs, skipping the
+// largest ones, to itself, effectively defragmenting the wallet. This defrag
+// operation is only performed if it is enabled, the wallet has more outputs
+// than the defrag threshold, and fees are low enough.
 func (w *Wallet) threadedDefragWallet() {
 	err := w.tg.Add()
 	if err != nil {
@@ -123,7 +158,19 @@ func (w *Wallet) threadedDefragWallet() {
 	}
 	defer w.tg.Done()
 
-	// Check that a defrag makes sense.
+	// Check that a defrag makes sense. The fee must be estimated without
+	// holding the wallet lock, as the transaction pool may be waiting on the
+	// wallet.
+	w.mu.RLock()
+	settings := w.defragSettings
+	w.mu.RUnlock()
+	if !settings.Enabled {
+		return
+	}
+	feePerByte := w.estimateFee(minFeeTargetBlocks)
+	if feePerByte.Cmp(settings.MaxFeePerByte) > 0 {
+		return
+	}
 	w.mu.Lock()
 	if !w.unlocked {
 		// Can't defrag if the wallet is locked.
@@ -132,9 +179,9 @@ func (w *Wallet) threadedDefragWallet() {
 	}
 
 	// Create the defrag transaction.
-	txnSet, err := w.createDefragTransaction()
+	txnSet, err := w.createDefragTransaction(w.defragSettings.Threshold, feePerByte)
 	w.mu.Unlock()
-	if err == errDefragNotNeeded {
+	if err == errDefragNotNeeded || err == errDefragTooExpensive {
 		// benign
 		return
 	} else if err != nil {
@@ -152,3 +199,60 @@ func (w *Wallet) threadedDefragWallet() {
 		w.log.Println("\t", txn.ID())
 	}
 }
+
+// Defragment sends a batch of the wallet's outputs, skipping the StartIndex
+// largest ones, to a new address of the wallet. Unlike the automatic
+// defragmentation, it ignores the threshold and fee limit of the defrag
+// settings, and only needs two outputs to consolidate. Dust outputs are never
+// consolidated, as they are not worth the fee needed to spend them.
+func (w *Wallet) Defragment() ([]types.Transaction, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer w.tg.Done()
+
+	feePerByte := w.estimateFee(minFeeTargetBlocks)
+	w.mu.Lock()
+	if !w.unlocked {
+		w.mu.Unlock()
+		return nil, modules.ErrLockedWallet
+	}
+	txnSet, err := w.createDefragTransaction(w.defragSettings.StartIndex+1, feePerByte)
+	w.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if err := w.tpool.AcceptTransactionSet(txnSet); err != nil {
+		return nil, err
+	}
+	return txnSet, nil
+}
+
+// DefragSettings returns the settings that control how the wallet
+// consolidates its outputs.
+func (w *Wallet) DefragSettings() modules.DefragSettings {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.defragSettings
+}
+
+// SetDefragSettings changes the settings that control how the wallet
+// consolidates its outputs.
+func (w *Wallet) SetDefragSettings(settings modules.DefragSettings) error {
+	if err := w.tg.Add(); err != nil {
+		return err
+	}
+	defer w.tg.Done()
+	if settings.BatchSize < 2 || settings.Threshold <= settings.BatchSize+settings.StartIndex {
+		return errBadDefragSettings
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := dbPutDefragSettings(w.dbTx, settings); err != nil {
+		return err
+	}
+	w.defragSettings = settings
+	w.syncDB()
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
 )
 
@@ -54,6 +55,77 @@ func TestDefragWallet(t *testing.T) {
 	}
 }
 
+// defragSettingsEqual reports whether two sets of defrag settings are equal.
+func defragSettingsEqual(a, b modules.DefragSettings) bool {
+	return a.Enabled == b.Enabled && a.Threshold == b.Threshold && a.BatchSize == b.BatchSize &&
+		a.StartIndex == b.StartIndex && a.MaxFeePerByte.Equals(b.MaxFeePerByte)
+}
+
+// TestDefragment checks that the defrag settings are validated and persisted,
+// and that an on-demand defrag consolidates a batch of outputs.
+func TestDefragment(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	if settings := wt.wallet.DefragSettings(); !defragSettingsEqual(settings, defaultDefragSettings()) {
+		t.Fatal("wrong default defrag settings:", settings)
+	}
+	bad := modules.DefragSettings{Threshold: 10, BatchSize: 5, StartIndex: 5}
+	if err := wt.wallet.SetDefragSettings(bad); err != errBadDefragSettings {
+		t.Fatal("expected errBadDefragSettings, got", err)
+	}
+	bad = modules.DefragSettings{Threshold: 10, BatchSize: 1}
+	if err := wt.wallet.SetDefragSettings(bad); err != errBadDefragSettings {
+		t.Fatal("expected errBadDefragSettings, got", err)
+	}
+
+	// Disable automatic defragmentation so that it does not interfere.
+	settings := modules.DefragSettings{
+		Threshold:     10,
+		BatchSize:     5,
+		MaxFeePerByte: types.NewCurrency64(1),
+	}
+	if err := wt.wallet.SetDefragSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < int(settings.BatchSize); i++ {
+		if _, err := wt.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	txns, err := wt.wallet.Defragment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txns) != 2 || uint64(len(txns[0].SiacoinInputs)) != settings.BatchSize {
+		t.Fatal("defrag did not spend a full batch of outputs")
+	}
+	if txns[1].MinerFees[0].IsZero() {
+		t.Fatal("defrag did not pay a fee")
+	}
+
+	// The settings should persist.
+	if err := wt.wallet.Close(); err != nil {
+		t.Fatal(err)
+	}
+	w, err := New(wt.cs, wt.tpool, wt.wallet.persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt.wallet = w
+	if s := w.DefragSettings(); !defragSettingsEqual(s, settings) {
+		t.Fatal("defrag settings were not persisted:", s)
+	}
+}
+
 // TestDefragWalletDust verifies that dust outputs do not trigger the defrag
 // operation.
 func TestDefragWalletDust(t *testing.T) {
@@ -16,10 +16,11 @@ import (
 )
 
 var (
-	errAlreadyUnlocked   = errors.New("wallet has already been unlocked")
-	errReencrypt         = errors.New("wallet is already encrypted, cannot encrypt again")
-	errUnencryptedWallet = errors.New("wallet has not been encrypted yet")
-	errScanInProgress    = errors.New("another wallet rescan is already underway")
+	errAlreadyUnlocked    = errors.New("wallet has already been unlocked")
+	errBadAutoLockTimeout = errors.New("auto-lock timeout must be positive")
+	errReencrypt          = errors.New("wallet is already encrypted, cannot encrypt again")
+	errUnencryptedWallet  = errors.New("wallet has not been encrypted yet")
+	errScanInProgress     = errors.New("another wallet rescan is already underway")
 
 	// verificationPlaintext is the plaintext used to verify encryption keys.
 	// By storing the corresponding ciphertext for a given key, we can later
@@ -183,7 +184,9 @@ func (w *Wallet) managedUnlock(masterKey crypto.TwofishKey) error {
 			}
 			w.integrateSpendableKey(masterKey, sk)
 		}
-		return nil
+
+		// timelocked addresses
+		return w.integrateTimelockedKeys()
 	}()
 	if err != nil {
 		return err
@@ -219,11 +222,21 @@ func (w *Wallet) managedUnlock(masterKey crypto.TwofishKey) error {
 			return fmt.Errorf("wallet subscription failed: %v", err)
 		}
 		w.tpool.TransactionPoolSubscribe(w)
+
+		// Broadcast the scheduled sets that came due while the wallet was
+		// not running, instead of waiting for the next block.
+		if w.cs.Synced() {
+			go w.threadedBroadcastScheduled(w.cs.Height(), w.cs.CurrentBlock().Timestamp)
+		}
 	}
 
 	w.mu.Lock()
 	w.unlocked = true
 	w.subscribed = true
+	w.unlockedSince = time.Now()
+	if w.autoLockTimeout > 0 {
+		w.startAutoLock(w.autoLockTimeout)
+	}
 	w.mu.Unlock()
 	return nil
 }
@@ -334,18 +347,35 @@ func (w *Wallet) Reset() error {
 	if err != nil {
 		return err
 	}
-	w.wipeSecrets()
-	w.keys = make(map[types.UnlockHash]spendableKey)
-	w.lookahead = make(map[types.UnlockHash]uint64)
-	w.seeds = []modules.Seed{}
-	w.unconfirmedProcessedTransactions = []modules.ProcessedTransaction{}
-	w.unlocked = false
+	w.lock()
+	w.clearState()
+	w.defragSettings = defaultDefragSettings()
+	w.scanGapLimit = defaultScanGapLimit
+	w.autoLockTimeout = 0
 	w.encrypted = false
 	w.subscribed = false
 
 	return nil
 }
 
+// clearState clears the keys, seeds, metadata and unconfirmed transactions
+// that the wallet holds in memory, after its database has been replaced.
+func (w *Wallet) clearState() {
+	w.keys = make(map[types.UnlockHash]spendableKey)
+	w.lookahead = make(map[types.UnlockHash]uint64)
+	w.watchedAddrs = make(map[types.UnlockHash]types.UnlockConditions)
+	w.addrLabels = make(map[types.UnlockHash]string)
+	w.addressBook = make(map[string]types.UnlockHash)
+	w.ucTemplates = make(map[string]modules.UnlockConditionsTemplate)
+	w.txnMemos = make(map[types.TransactionID]string)
+	w.scheduledTxns = make(map[types.TransactionID]modules.ScheduledTransaction)
+	w.atomicSwaps = make(map[types.FileContractID]modules.AtomicSwap)
+	w.recentPayments = make(map[types.OutputID]modules.PaymentEvent)
+	w.unconfirmedPayments = make(map[types.OutputID]struct{})
+	w.seeds = []modules.Seed{}
+	w.unconfirmedProcessedTransactions = []modules.ProcessedTransaction{}
+}
+
 // InitFromSeed functions like Init, but using a specified seed. Unlike Init,
 // the blockchain will be scanned to determine the seed's progress. For this
 // reason, InitFromSeed should not be called until the blockchain is fully
@@ -370,8 +400,15 @@ func (w *Wallet) InitFromSeed(masterKey crypto.TwofishKey, seed modules.Seed) er
 	}
 	defer w.scanLock.Unlock()
 
+	return w.managedInitFromSeed(masterKey, seed)
+}
+
+// managedInitFromSeed scans the blockchain to determine the progress of the
+// seed and initializes the wallet with it. The caller must hold the scan lock.
+func (w *Wallet) managedInitFromSeed(masterKey crypto.TwofishKey, seed modules.Seed) error {
 	// estimate the primarySeedProgress by scanning the blockchain
 	s := newSeedScanner(seed, w.log)
+	s.gapLimit = w.ScanGapLimit()
 	if err := s.scan(w.cs); err != nil {
 		return err
 	}
@@ -390,6 +427,41 @@ func (w *Wallet) InitFromSeed(masterKey crypto.TwofishKey, seed modules.Seed) er
 	return err
 }
 
+// Recover restores a wallet from its seed. The wallet is initialized from the
+// seed as in InitFromSeed and then unlocked, which rescans the blockchain from
+// the beginning and rebuilds the outputs, balances, and transaction history of
+// the seed's addresses. Like InitFromSeed, Recover can only be called on a
+// wallet that has not been encrypted; a wallet with damaged files should be
+// Reset first.
+func (w *Wallet) Recover(masterKey crypto.TwofishKey, seed modules.Seed) error {
+	if err := w.tg.Add(); err != nil {
+		return err
+	}
+	defer w.tg.Done()
+
+	if !w.cs.Synced() {
+		return errors.New("cannot recover from seed until blockchain is synced")
+	}
+
+	// If masterKey is blank, use the hash of the seed.
+	if masterKey == (crypto.TwofishKey{}) {
+		masterKey = crypto.TwofishKey(crypto.HashObject(seed))
+	}
+
+	// Hold the scan lock across both scans so that the wallet cannot be
+	// unlocked before it has been initialized.
+	if !w.scanLock.TryLock() {
+		return errScanInProgress
+	}
+	defer w.scanLock.Unlock()
+
+	w.log.Println("INFO: Recovering wallet from seed.")
+	if err := w.managedInitFromSeed(masterKey, seed); err != nil {
+		return err
+	}
+	return w.managedUnlock(masterKey)
+}
+
 // Unlocked indicates whether the wallet is locked or unlocked.
 func (w *Wallet) Unlocked() bool {
 	w.mu.RLock()
@@ -406,13 +478,54 @@ func (w *Wallet) Lock() error {
 		return modules.ErrLockedWallet
 	}
 	w.log.Println("INFO: Locking wallet.")
+	w.lock()
+	return nil
+}
+
+// lock wipes the secrets of an unlocked wallet and stops the auto-lock timer.
+func (w *Wallet) lock() {
+	if w.autoLock != nil {
+		w.autoLock.Stop()
+		w.autoLock = nil
+	}
 
 	// Wipe all of the seeds and secret keys. They will be replaced upon
 	// calling 'Unlock' again. Note that since the public keys are not wiped,
 	// we can continue processing blocks.
 	w.wipeSecrets()
 	w.unlocked = false
-	return nil
+}
+
+// startAutoLock starts an auto-lock timer that locks the wallet once timeout
+// has elapsed, replacing the current timer.
+func (w *Wallet) startAutoLock(timeout time.Duration) {
+	if w.autoLock != nil {
+		w.autoLock.Stop()
+	}
+	w.autoLockID++
+	id := w.autoLockID
+	w.autoLockDeadline = time.Now().Add(timeout)
+	w.autoLock = time.AfterFunc(timeout, func() {
+		w.managedAutoLock(id)
+	})
+}
+
+// managedAutoLock locks the wallet when the auto-lock timer with the given id
+// fires, unless the wallet has been locked since the timer was started. The
+// lock event subscribers are notified.
+func (w *Wallet) managedAutoLock(id uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.unlocked || w.autoLock == nil || w.autoLockID != id {
+		return
+	}
+	w.log.Println("INFO: Auto-lock timeout elapsed, locking wallet.")
+	w.lock()
+	w.lastAutoLock = time.Now()
+	w.notifyLock(modules.LockEvent{
+		UnlockedSince: types.Timestamp(w.unlockedSince.Unix()),
+		Timestamp:     types.Timestamp(w.lastAutoLock.Unix()),
+	})
 }
 
 // managedChangeKey safely performs the database operations required to change
@@ -580,3 +693,31 @@ func (w *Wallet) Unlock(masterKey crypto.TwofishKey) error {
 	// lock, also grab the subscriber status.
 	return w.managedUnlock(masterKey)
 }
+
+// UnlockWithTimeout unlocks the wallet like Unlock, and locks it again once
+// the timeout has elapsed, overriding the auto-lock timeout of the wallet.
+// Calling Lock before then cancels the timeout.
+func (w *Wallet) UnlockWithTimeout(masterKey crypto.TwofishKey, timeout time.Duration) error {
+	if err := w.tg.Add(); err != nil {
+		return err
+	}
+	defer w.tg.Done()
+
+	if timeout <= 0 {
+		return errBadAutoLockTimeout
+	}
+	if !w.scanLock.TryLock() {
+		return errScanInProgress
+	}
+	defer w.scanLock.Unlock()
+
+	w.log.Println("INFO: Unlocking wallet for", timeout)
+	if err := w.managedUnlock(masterKey); err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.startAutoLock(timeout)
+	return nil
+}
@@ -2,6 +2,7 @@ package wallet
 
 import (
 	"bytes"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -249,6 +250,181 @@ func TestLock(t *testing.T) {
 	}
 }
 
+// TestUnlockWithTimeout checks that the wallet locks itself once the auto-lock
+// timeout elapses, and that spending fails while it is locked.
+func TestUnlockWithTimeout(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	err = wt.wallet.Lock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = wt.wallet.UnlockWithTimeout(wt.walletMasterKey, 0)
+	if err != errBadAutoLockTimeout {
+		t.Fatal("expected errBadAutoLockTimeout, got", err)
+	}
+	err = wt.wallet.UnlockWithTimeout(wt.walletMasterKey, 200*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !wt.wallet.Unlocked() {
+		t.Fatal("wallet should be unlocked")
+	}
+	_, err = wt.wallet.SendSiacoins(types.SiacoinPrecision, types.UnlockHash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Wait for the timeout to elapse.
+	time.Sleep(500 * time.Millisecond)
+	if wt.wallet.Unlocked() {
+		t.Fatal("wallet should have locked itself")
+	}
+	_, err = wt.wallet.SendSiacoins(types.SiacoinPrecision, types.UnlockHash{})
+	if err != modules.ErrLockedWallet {
+		t.Fatal("expected ErrLockedWallet, got", err)
+	}
+	tb := wt.wallet.StartTransaction()
+	err = tb.FundSiacoins(types.SiacoinPrecision)
+	if err != modules.ErrLockedWallet {
+		t.Fatal("expected ErrLockedWallet, got", err)
+	}
+	tb.Drop()
+
+	// Locking the wallet should cancel the timeout.
+	err = wt.wallet.UnlockWithTimeout(wt.walletMasterKey, 200*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = wt.wallet.Lock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = wt.wallet.Unlock(wt.walletMasterKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(500 * time.Millisecond)
+	if !wt.wallet.Unlocked() {
+		t.Fatal("wallet was locked by a cancelled timeout")
+	}
+}
+
+// TestAutoLock checks that the auto-lock timeout is applied when the wallet is
+// unlocked, that it persists, and that an event is sent when the wallet locks
+// itself.
+func TestAutoLock(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	if err := wt.wallet.SetAutoLockTimeout(-time.Second); err != errNegativeAutoLockTimeout {
+		t.Fatal("expected errNegativeAutoLockTimeout, got", err)
+	}
+	if status := wt.wallet.AutoLock(); status.Timeout != 0 || status.TimeUntilLock != 0 {
+		t.Fatal("wallet should not have an auto-lock timer:", status)
+	}
+	events, unsubscribe, err := wt.wallet.SubscribeLockEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribe()
+
+	// Setting the timeout starts the timer of an unlocked wallet.
+	if err := wt.wallet.SetAutoLockTimeout(time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	status := wt.wallet.AutoLock()
+	if status.Timeout != time.Hour || status.TimeUntilLock <= 0 || status.TimeUntilLock > time.Hour {
+		t.Fatal("wrong auto-lock status:", status)
+	}
+
+	// Locking the wallet stops the timer, and unlocking it starts it again.
+	if err := wt.wallet.SetAutoLockTimeout(200 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	if status := wt.wallet.AutoLock(); status.TimeUntilLock != 0 {
+		t.Fatal("locked wallet should not have an auto-lock timer:", status)
+	}
+	select {
+	case le := <-events:
+		t.Fatal("explicit lock was reported as an auto-lock:", le)
+	default:
+	}
+	if err := wt.wallet.Unlock(wt.walletMasterKey); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case le := <-events:
+		if le.UnlockedSince == 0 || le.Timestamp < le.UnlockedSince {
+			t.Fatal("wrong lock event:", le)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no lock event was sent")
+	}
+	if wt.wallet.Unlocked() {
+		t.Fatal("wallet should have locked itself")
+	}
+	if status := wt.wallet.AutoLock(); status.LastAutoLock == 0 {
+		t.Fatal("auto-lock was not recorded:", status)
+	}
+
+	// UnlockWithTimeout overrides the timeout, and a zero timeout disables
+	// the auto-lock.
+	if err := wt.wallet.UnlockWithTimeout(wt.walletMasterKey, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if status := wt.wallet.AutoLock(); status.TimeUntilLock < time.Minute {
+		t.Fatal("UnlockWithTimeout did not override the auto-lock timeout:", status)
+	}
+	if err := wt.wallet.SetAutoLockTimeout(0); err != nil {
+		t.Fatal(err)
+	}
+	if status := wt.wallet.AutoLock(); status.TimeUntilLock != 0 {
+		t.Fatal("auto-lock timer was not stopped:", status)
+	}
+
+	// The timeout should persist.
+	if err := wt.wallet.SetAutoLockTimeout(time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for range events {
+	}
+	w, err := New(wt.cs, wt.tpool, wt.wallet.persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt.wallet = w
+	if err := w.Unlock(wt.walletMasterKey); err != nil {
+		t.Fatal(err)
+	}
+	wt.miner, err = miner.New(wt.cs, wt.tpool, wt.wallet, wt.wallet.persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := w.AutoLock(); status.Timeout != time.Hour || status.TimeUntilLock <= 0 {
+		t.Fatal("auto-lock timeout was not persisted:", status)
+	}
+}
+
 // TestInitFromSeedConcurrentUnlock verifies that calling InitFromSeed and
 // then Unlock() concurrently results in the correct balance.
 func TestInitFromSeedConcurrentUnlock(t *testing.T) {
@@ -381,6 +557,71 @@ func TestInitFromSeed(t *testing.T) {
 	}
 }
 
+// TestRecover tests recovering a wallet from its seed.
+func TestRecover(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	// create a wallet with some money and transaction history
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+	seed, _, err := wt.wallet.PrimarySeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	origBal, _, _ := wt.wallet.ConfirmedBalance()
+	origTxns, err := wt.wallet.Transactions(0, wt.cs.Height())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// recover the seed into a blank wallet
+	dir := filepath.Join(build.TempDir(modules.WalletDir, t.Name()+"-new"), modules.WalletDir)
+	w, err := New(wt.cs, wt.tpool, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	err = build.Retry(100, 10*time.Millisecond, func() error {
+		if !wt.cs.Synced() {
+			return errors.New("consensus set is not synced")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.Recover(crypto.TwofishKey{}, seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !w.Unlocked() {
+		t.Fatal("wallet should be unlocked after recovery")
+	}
+
+	// the balance and transaction history should match the original wallet
+	newBal, _, _ := w.ConfirmedBalance()
+	if newBal.Cmp(origBal) != 0 {
+		t.Fatalf("wallet should have correct balance after recovery: wanted %v, got %v", origBal, newBal)
+	}
+	newTxns, err := w.Transactions(0, wt.cs.Height())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(newTxns) != len(origTxns) {
+		t.Fatalf("wallet should have the same transactions after recovery: wanted %v, got %v", len(origTxns), len(newTxns))
+	}
+
+	// the wallet can only be recovered once
+	err = w.Recover(crypto.TwofishKey{}, seed)
+	if err != errReencrypt {
+		t.Fatal("expected errReencrypt, got", err)
+	}
+}
+
 // TestReset tests that Reset resets a wallet correctly.
 func TestReset(t *testing.T) {
 	if testing.Short() {
@@ -0,0 +1,56 @@
+package wallet
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	errBadFeeTarget = errors.New("fee target must be at least one block")
+)
+
+// estimateFee returns the recommended fee per byte for a transaction that
+// should be confirmed within targetBlocks blocks. The transaction pool
+// recommends a maximum fee, which is derived from the fees of recent blocks
+// and targets the next block, and a minimum fee, which targets
+// minFeeTargetBlocks blocks. Every block's worth of transactions already in
+// the pool delays a transaction that pays the minimum fee by another block, so
+// the fee is interpolated between the maximum for a target of one block and
+// the minimum for a target of minFeeTargetBlocks plus the size of the backlog.
+func (w *Wallet) estimateFee(targetBlocks uint64) types.Currency {
+	min, max := w.tpool.FeeEstimation()
+	if max.Cmp(min) < 0 {
+		max = min
+	}
+
+	// Determine how many blocks are needed to clear the transaction pool.
+	var poolSize uint64
+	for _, txn := range w.tpool.TransactionList() {
+		poolSize += uint64(len(encoding.Marshal(txn)))
+	}
+	backlog := poolSize / types.BlockSizeLimit
+
+	minTarget := minFeeTargetBlocks + backlog
+	if targetBlocks >= minTarget {
+		return min
+	} else if targetBlocks <= 1 {
+		return max
+	}
+	return max.Sub(max.Sub(min).Mul64(targetBlocks - 1).Div64(minTarget - 1))
+}
+
+// EstimateFee returns the recommended fee per byte for a transaction that
+// should be confirmed within targetBlocks blocks, based on the fees of recent
+// blocks and the number of transactions waiting in the transaction pool.
+func (w *Wallet) EstimateFee(targetBlocks int) (types.Currency, error) {
+	if err := w.tg.Add(); err != nil {
+		return types.Currency{}, err
+	}
+	defer w.tg.Done()
+	if targetBlocks < 1 {
+		return types.Currency{}, errBadFeeTarget
+	}
+	return w.estimateFee(uint64(targetBlocks)), nil
+}
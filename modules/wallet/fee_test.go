@@ -0,0 +1,42 @@
+package wallet
+
+import (
+	"testing"
+)
+
+// TestEstimateFee checks that the estimated fee decreases as the target number
+// of blocks grows, from the maximum to the minimum recommended by the
+// transaction pool.
+func TestEstimateFee(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	if _, err := wt.wallet.EstimateFee(0); err != errBadFeeTarget {
+		t.Fatal("expected errBadFeeTarget, got", err)
+	}
+
+	min, max := wt.tpool.FeeEstimation()
+	if fee, err := wt.wallet.EstimateFee(1); err != nil {
+		t.Fatal(err)
+	} else if !fee.Equals(max) {
+		t.Fatalf("fee for the next block is %v, expected %v", fee, max)
+	}
+	if fee, err := wt.wallet.EstimateFee(minFeeTargetBlocks); err != nil {
+		t.Fatal(err)
+	} else if !fee.Equals(min) {
+		t.Fatalf("fee for %v blocks is %v, expected %v", minFeeTargetBlocks, fee, min)
+	}
+	fee, err := wt.wallet.EstimateFee(defaultFeeTargetBlocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fee.Cmp(min) <= 0 || fee.Cmp(max) >= 0 {
+		t.Fatalf("fee for %v blocks is %v, expected a fee between %v and %v", defaultFeeTargetBlocks, fee, min, max)
+	}
+}
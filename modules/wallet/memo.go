@@ -0,0 +1,85 @@
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	errMemoTooLong        = errors.New("transaction memos cannot be longer than 1024 bytes")
+	errUnknownTransaction = errors.New("transaction is not a transaction of the wallet")
+)
+
+// isWalletTransaction returns whether txid is a confirmed or unconfirmed
+// transaction of the wallet.
+func (w *Wallet) isWalletTransaction(txid types.TransactionID) bool {
+	for _, pt := range w.unconfirmedProcessedTransactions {
+		if pt.TransactionID == txid {
+			return true
+		}
+	}
+	it := dbProcessedTransactionsIterator(w.dbTx)
+	for it.next() {
+		if it.value().TransactionID == txid {
+			return true
+		}
+	}
+	return false
+}
+
+// TransactionMemos returns the memos that the user attached to the
+// transactions of the wallet, sorted by transaction ID in byte-order.
+func (w *Wallet) TransactionMemos() []modules.TransactionMemo {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	memos := make([]modules.TransactionMemo, 0, len(w.txnMemos))
+	for txid, memo := range w.txnMemos {
+		memos = append(memos, modules.TransactionMemo{
+			TransactionID: txid,
+			Memo:          memo,
+		})
+	}
+	sort.Slice(memos, func(i, j int) bool {
+		return bytes.Compare(memos[i].TransactionID[:], memos[j].TransactionID[:]) < 0
+	})
+	return memos
+}
+
+// SetTransactionMemo attaches a memo to a sent or received transaction of the
+// wallet, replacing any previous memo. An empty memo removes the memo of the
+// transaction. Memos are only stored locally, and are kept when the blockchain
+// is rescanned.
+func (w *Wallet) SetTransactionMemo(txid types.TransactionID, memo string) error {
+	if err := w.tg.Add(); err != nil {
+		return err
+	}
+	defer w.tg.Done()
+	if len(memo) > maxMemoLength {
+		return errMemoTooLong
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if memo == "" {
+		if err := dbDeleteTxnMemo(w.dbTx, txid); err != nil {
+			return err
+		}
+		delete(w.txnMemos, txid)
+		w.syncDB()
+		return nil
+	}
+	if !w.isWalletTransaction(txid) {
+		return errUnknownTransaction
+	}
+	if err := dbPutTxnMemo(w.dbTx, txid, memo); err != nil {
+		return err
+	}
+	w.txnMemos[txid] = memo
+	w.syncDB()
+	return nil
+}
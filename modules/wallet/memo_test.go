@@ -0,0 +1,75 @@
+package wallet
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules/miner"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestTransactionMemos attaches memos to a transaction of the wallet and
+// checks that they persist.
+func TestTransactionMemos(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	txns, err := wt.wallet.SendSiacoins(types.SiacoinPrecision, types.UnlockHash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	txid := txns[len(txns)-1].ID()
+
+	if err := wt.wallet.SetTransactionMemo(types.TransactionID{1}, "rent"); err != errUnknownTransaction {
+		t.Fatal("expected errUnknownTransaction, got", err)
+	}
+	if err := wt.wallet.SetTransactionMemo(txid, strings.Repeat("a", maxMemoLength+1)); err != errMemoTooLong {
+		t.Fatal("expected errMemoTooLong, got", err)
+	}
+	// Memos can be attached to unconfirmed transactions, and are kept once
+	// the transaction is confirmed.
+	if err := wt.wallet.SetTransactionMemo(txid, "rent"); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.addBlockNoPayout(); err != nil {
+		t.Fatal(err)
+	}
+	memos := wt.wallet.TransactionMemos()
+	if len(memos) != 1 || memos[0].TransactionID != txid || memos[0].Memo != "rent" {
+		t.Fatal("wrong memos:", memos)
+	}
+
+	// The memo should persist.
+	if err := wt.wallet.Close(); err != nil {
+		t.Fatal(err)
+	}
+	w, err := New(wt.cs, wt.tpool, wt.wallet.persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt.wallet = w
+	if err := w.Unlock(wt.walletMasterKey); err != nil {
+		t.Fatal(err)
+	}
+	wt.miner, err = miner.New(wt.cs, wt.tpool, wt.wallet, wt.wallet.persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if memos := w.TransactionMemos(); len(memos) != 1 || memos[0].Memo != "rent" {
+		t.Fatal("memo was not persisted:", memos)
+	}
+
+	// An empty memo removes the memo.
+	if err := w.SetTransactionMemo(txid, ""); err != nil {
+		t.Fatal(err)
+	}
+	if memos := w.TransactionMemos(); len(memos) != 0 {
+		t.Fatal("memo was not removed:", memos)
+	}
+}
@@ -0,0 +1,43 @@
+package wallet
+
+import (
+	"bytes"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// SignMessage signs msg with a key of addr, proving that the wallet controls
+// addr. The signature can be checked with types.VerifyMessage.
+func (w *Wallet) SignMessage(addr types.UnlockHash, msg []byte) (types.MessageSignature, error) {
+	if err := w.tg.Add(); err != nil {
+		return types.MessageSignature{}, err
+	}
+	defer w.tg.Done()
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if !w.unlocked {
+		return types.MessageSignature{}, modules.ErrLockedWallet
+	}
+	sk, exists := w.keys[addr]
+	if !exists || len(sk.SecretKeys) == 0 {
+		return types.MessageSignature{}, errUnknownWalletAddress
+	}
+
+	// Find the public key of the first secret key in the unlock conditions.
+	pk := sk.SecretKeys[0].PublicKey()
+	for i, spk := range sk.UnlockConditions.PublicKeys {
+		if spk.Algorithm != types.SignatureEd25519 || !bytes.Equal(spk.Key, pk[:]) {
+			continue
+		}
+		sig := crypto.SignHash(types.MessageHash(msg), sk.SecretKeys[0])
+		return types.MessageSignature{
+			UnlockConditions: sk.UnlockConditions,
+			PublicKeyIndex:   uint64(i),
+			Signature:        sig[:],
+		}, nil
+	}
+	return types.MessageSignature{}, errUnknownWalletAddress
+}
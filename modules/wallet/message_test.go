@@ -0,0 +1,42 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestSignMessage signs a message with a wallet address and verifies it.
+func TestSignMessage(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	uc, err := wt.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := uc.UnlockHash()
+	msg := []byte("withdrawal address for account 1234")
+	sig, err := wt.wallet.SignMessage(addr, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := types.VerifyMessage(addr, msg, sig); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := wt.wallet.SignMessage(types.UnlockHash{1}, msg); err != errUnknownWalletAddress {
+		t.Fatal("expected errUnknownWalletAddress, got", err)
+	}
+	wt.wallet.Lock()
+	if _, err := wt.wallet.SignMessage(addr, msg); err != modules.ErrLockedWallet {
+		t.Fatal("expected ErrLockedWallet, got", err)
+	}
+}
@@ -1,11 +1,54 @@
 package wallet
 
 import (
+	"errors"
+
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/NebulousLabs/bolt"
+)
+
+var (
+	// errZeroOutput is returned when one of the outputs of a transaction
+	// sending to many addresses has no value.
+	errZeroOutput = errors.New("cannot send zero siacoins to an address")
 )
 
+// BalanceBreakdown returns the confirmed siacoin balance of the wallet, its
+// unconfirmed inflows and outflows, and the delayed outputs of the wallet, such
+// as miner payouts, that have not matured yet.
+func (w *Wallet) BalanceBreakdown() (modules.BalanceBreakdown, error) {
+	if err := w.tg.Add(); err != nil {
+		return modules.BalanceBreakdown{}, err
+	}
+	defer w.tg.Done()
+
+	var bb modules.BalanceBreakdown
+	bb.Confirmed, _, _ = w.ConfirmedBalance()
+	bb.UnconfirmedOutgoing, bb.UnconfirmedIncoming = w.UnconfirmedBalance()
+
+	// Collect the delayed outputs that mature after the current height. The
+	// consensus set must not be called while holding the wallet's lock.
+	var delayed []modules.DelayedSiacoinOutput
+	height := w.cs.Height()
+	for h := height + 1; h <= height+types.MaturityDelay; h++ {
+		delayed = append(delayed, w.cs.DelayedOutputsAtHeight(h)...)
+	}
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	bb.Maturing = types.ZeroCurrency
+	for _, dsco := range delayed {
+		if w.isWalletAddress(dsco.SiacoinOutput.UnlockHash) {
+			bb.Maturing = bb.Maturing.Add(dsco.SiacoinOutput.Value)
+			bb.MaturingOutputs = append(bb.MaturingOutputs, dsco)
+		}
+	}
+	return bb, nil
+}
+
 // sortedOutputs is a struct containing a slice of siacoin outputs and their
 // corresponding ids. sortedOutputs can be sorted using the sort package.
 type sortedOutputs struct {
@@ -67,6 +110,71 @@ func (w *Wallet) UnconfirmedBalance() (outgoingSiacoins types.Currency, incoming
 	return
 }
 
+// UnspentOutputs returns the confirmed siacoin and siafund outputs of the
+// wallet that have not been spent, including those of watch-only addresses.
+// Outputs that are spent by unconfirmed transactions are still returned.
+func (w *Wallet) UnspentOutputs() ([]modules.UnspentOutput, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer w.tg.Done()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	consensusHeight, err := dbGetConsensusHeight(w.dbTx)
+	if err != nil {
+		return nil, err
+	}
+
+	confirmed, err := dbOutputConfirmationHeights(w.dbTx)
+	if err != nil {
+		return nil, err
+	}
+	unspent := func(id types.OutputID, fundType types.Specifier, uh types.UnlockHash, value types.Currency) modules.UnspentOutput {
+		uo := modules.UnspentOutput{
+			ID:         id,
+			FundType:   fundType,
+			UnlockHash: uh,
+			Value:      value,
+		}
+		if height, exists := confirmed[id]; exists && height <= consensusHeight {
+			uo.ConfirmationHeight = height
+			uo.Confirmations = consensusHeight - height + 1
+		}
+		_, exists := w.keys[uh]
+		uo.WatchOnly = !exists
+		return uo
+	}
+
+	var outputs []modules.UnspentOutput
+	err = dbForEachSiacoinOutput(w.dbTx, func(scoid types.SiacoinOutputID, sco types.SiacoinOutput) {
+		outputs = append(outputs, unspent(types.OutputID(scoid), types.SpecifierSiacoinOutput, sco.UnlockHash, sco.Value))
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = dbForEachSiafundOutput(w.dbTx, func(sfoid types.SiafundOutputID, sfo types.SiafundOutput) {
+		outputs = append(outputs, unspent(types.OutputID(sfoid), types.SpecifierSiafundOutput, sfo.UnlockHash, sfo.Value))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return outputs, nil
+}
+
+// dbOutputConfirmationHeights returns the confirmation heights of the outputs
+// in the history of the wallet. The confirmation height of an output is that
+// of the transaction that created it.
+func dbOutputConfirmationHeights(tx *bolt.Tx) (map[types.OutputID]types.BlockHeight, error) {
+	heights := make(map[types.OutputID]types.BlockHeight)
+	err := dbForEachProcessedTransaction(tx, func(pt modules.ProcessedTransaction) {
+		for _, output := range pt.Outputs {
+			heights[output.ID] = pt.ConfirmationHeight
+		}
+	})
+	return heights, err
+}
+
 // SendSiacoins creates a transaction sending 'amount' to 'dest'. The transaction
 // is submitted to the transaction pool and is also returned.
 func (w *Wallet) SendSiacoins(amount types.Currency, dest types.UnlockHash) ([]types.Transaction, error) {
@@ -74,12 +182,64 @@ func (w *Wallet) SendSiacoins(amount types.Currency, dest types.UnlockHash) ([]t
 		return nil, err
 	}
 	defer w.tg.Done()
+	return w.managedSendSiacoins(amount, dest, nil, 0)
+}
+
+// SendSiacoinsFromOutputs works like SendSiacoins, but only spends the siacoin
+// outputs in ids. Any change is sent to a new address of the wallet.
+func (w *Wallet) SendSiacoinsFromOutputs(amount types.Currency, dest types.UnlockHash, ids []types.SiacoinOutputID) ([]types.Transaction, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer w.tg.Done()
+	if len(ids) == 0 {
+		return nil, errUnknownOutput
+	}
+	return w.managedSendSiacoins(amount, dest, ids, 0)
+}
+
+// SendSiacoinsWithConfirmations works like SendSiacoins, but only spends
+// siacoin outputs that have been confirmed by at least minConfirmations
+// blocks.
+func (w *Wallet) SendSiacoinsWithConfirmations(amount types.Currency, dest types.UnlockHash, minConfirmations types.BlockHeight) ([]types.Transaction, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer w.tg.Done()
+	return w.managedSendSiacoins(amount, dest, nil, minConfirmations)
+}
+
+// managedSendSiacoins implements SendSiacoins, SendSiacoinsFromOutputs and
+// SendSiacoinsWithConfirmations. If ids is empty, any of the wallet's outputs
+// with at least minConfirmations confirmations may be spent.
+func (w *Wallet) managedSendSiacoins(amount types.Currency, dest types.UnlockHash, ids []types.SiacoinOutputID, minConfirmations types.BlockHeight) ([]types.Transaction, error) {
+	txnSet, txnBuilder, err := w.managedBuildSiacoins(amount, dest, ids, minConfirmations)
+	if err != nil {
+		return nil, err
+	}
+	err = w.tpool.AcceptTransactionSet(txnSet)
+	if err != nil {
+		w.log.Println("Attempt to send coins has failed - transaction pool rejected transaction:", err)
+		txnBuilder.Drop()
+		return nil, build.ExtendErr("unable to get transaction accepted", err)
+	}
+	w.log.Println("Submitted a siacoin transfer transaction set for value", amount.HumanString(), "with fees", transactionSetFee(txnSet).HumanString(), "IDs:")
+	for _, txn := range txnSet {
+		w.log.Println("\t", txn.ID())
+	}
+	return txnSet, nil
+}
+
+// managedBuildSiacoins funds and signs a transaction set sending 'amount' to
+// 'dest', without submitting it to the transaction pool. The caller must
+// either submit the set or drop the returned builder.
+func (w *Wallet) managedBuildSiacoins(amount types.Currency, dest types.UnlockHash, ids []types.SiacoinOutputID, minConfirmations types.BlockHeight) ([]types.Transaction, modules.TransactionBuilder, error) {
 	if !w.unlocked {
 		w.log.Println("Attempt to send coins has failed - wallet is locked")
-		return nil, modules.ErrLockedWallet
+		return nil, nil, modules.ErrLockedWallet
 	}
 
-	_, tpoolFee := w.tpool.FeeEstimation()
+	tpoolFee := w.estimateFee(defaultFeeTargetBlocks)
 	tpoolFee = tpoolFee.Mul64(750) // Estimated transaction size in bytes
 	output := types.SiacoinOutput{
 		Value:      amount,
@@ -87,47 +247,82 @@ func (w *Wallet) SendSiacoins(amount types.Currency, dest types.UnlockHash) ([]t
 	}
 
 	txnBuilder := w.StartTransaction()
-	err := txnBuilder.FundSiacoins(amount.Add(tpoolFee))
+	var err error
+	switch {
+	case len(ids) != 0:
+		err = txnBuilder.FundSiacoinsFromOutputs(amount.Add(tpoolFee), ids)
+	case minConfirmations != 0:
+		err = txnBuilder.FundSiacoinsWithConfirmations(amount.Add(tpoolFee), minConfirmations)
+	default:
+		err = txnBuilder.FundSiacoins(amount.Add(tpoolFee))
+	}
 	if err != nil {
 		w.log.Println("Attempt to send coins has failed - failed to fund transaction:", err)
-		return nil, build.ExtendErr("unable to fund transaction", err)
+		return nil, nil, build.ExtendErr("unable to fund transaction", err)
 	}
 	txnBuilder.AddMinerFee(tpoolFee)
 	txnBuilder.AddSiacoinOutput(output)
 	txnSet, err := txnBuilder.Sign(true)
 	if err != nil {
 		w.log.Println("Attempt to send coins has failed - failed to sign transaction:", err)
-		return nil, build.ExtendErr("unable to sign transaction", err)
+		txnBuilder.Drop()
+		return nil, nil, build.ExtendErr("unable to sign transaction", err)
+	}
+	return txnSet, txnBuilder, nil
+}
+
+// SendSiacoinsMulti creates a transaction that includes the specified
+// outputs. All of the outputs share a single transaction and a single miner
+// fee. The transaction is submitted to the transaction pool and is also
+// returned.
+func (w *Wallet) SendSiacoinsMulti(outputs []types.SiacoinOutput) ([]types.Transaction, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer w.tg.Done()
+	txnSet, txnBuilder, err := w.managedBuildSiacoinsMulti(outputs)
+	if err != nil {
+		return nil, err
 	}
 	err = w.tpool.AcceptTransactionSet(txnSet)
 	if err != nil {
 		w.log.Println("Attempt to send coins has failed - transaction pool rejected transaction:", err)
+		txnBuilder.Drop()
 		return nil, build.ExtendErr("unable to get transaction accepted", err)
 	}
-	w.log.Println("Submitted a siacoin transfer transaction set for value", amount.HumanString(), "with fees", tpoolFee.HumanString(), "IDs:")
+	fee := transactionSetFee(txnSet)
+	var value types.Currency
+	for _, sco := range outputs {
+		value = value.Add(sco.Value)
+	}
+	w.log.Println("Submitted a siacoin transfer transaction set to", len(outputs), "addresses for value", value.HumanString(), "with fees", fee.HumanString(), "IDs:")
 	for _, txn := range txnSet {
 		w.log.Println("\t", txn.ID())
 	}
 	return txnSet, nil
 }
 
-// SendSiacoinsMulti creates a transaction that includes the specified
-// outputs. The transaction is submitted to the transaction pool and is also
-// returned.
-func (w *Wallet) SendSiacoinsMulti(outputs []types.SiacoinOutput) ([]types.Transaction, error) {
-	if err := w.tg.Add(); err != nil {
-		return nil, err
-	}
-	defer w.tg.Done()
+// managedBuildSiacoinsMulti funds and signs a transaction set sending to all
+// of the outputs, without submitting it to the transaction pool. The caller
+// must either submit the set or drop the returned builder.
+func (w *Wallet) managedBuildSiacoinsMulti(outputs []types.SiacoinOutput) ([]types.Transaction, modules.TransactionBuilder, error) {
 	if !w.unlocked {
 		w.log.Println("Attempt to send coins has failed - wallet is locked")
-		return nil, modules.ErrLockedWallet
+		return nil, nil, modules.ErrLockedWallet
+	}
+	if len(outputs) == 0 {
+		return nil, nil, errNoOutputs
+	}
+	for _, sco := range outputs {
+		if sco.Value.IsZero() {
+			return nil, nil, errZeroOutput
+		}
 	}
 
 	txnBuilder := w.StartTransaction()
 
 	// Add estimated transaction fee.
-	_, tpoolFee := w.tpool.FeeEstimation()
+	tpoolFee := w.estimateFee(defaultFeeTargetBlocks)
 	tpoolFee = tpoolFee.Mul64(2)                              // We don't want send-to-many transactions to fail.
 	tpoolFee = tpoolFee.Mul64(1000 + 60*uint64(len(outputs))) // Estimated transaction size in bytes
 	txnBuilder.AddMinerFee(tpoolFee)
@@ -142,7 +337,7 @@ func (w *Wallet) SendSiacoinsMulti(outputs []types.SiacoinOutput) ([]types.Trans
 	}
 	err := txnBuilder.FundSiacoins(totalCost)
 	if err != nil {
-		return nil, build.ExtendErr("unable to fund transaction", err)
+		return nil, nil, build.ExtendErr("unable to fund transaction", err)
 	}
 
 	for _, sco := range outputs {
@@ -152,14 +347,10 @@ func (w *Wallet) SendSiacoinsMulti(outputs []types.SiacoinOutput) ([]types.Trans
 	txnSet, err := txnBuilder.Sign(true)
 	if err != nil {
 		w.log.Println("Attempt to send coins has failed - failed to sign transaction:", err)
-		return nil, build.ExtendErr("unable to sign transaction", err)
+		txnBuilder.Drop()
+		return nil, nil, build.ExtendErr("unable to sign transaction", err)
 	}
-	err = w.tpool.AcceptTransactionSet(txnSet)
-	if err != nil {
-		w.log.Println("Attempt to send coins has failed - transaction pool rejected transaction:", err)
-		return nil, build.ExtendErr("unable to get transaction accepted", err)
-	}
-	return txnSet, nil
+	return txnSet, txnBuilder, nil
 }
 
 // SendSiafunds creates a transaction sending 'amount' to 'dest'. The transaction
@@ -173,7 +364,7 @@ func (w *Wallet) SendSiafunds(amount types.Currency, dest types.UnlockHash) ([]t
 		return nil, modules.ErrLockedWallet
 	}
 
-	_, tpoolFee := w.tpool.FeeEstimation()
+	tpoolFee := w.estimateFee(defaultFeeTargetBlocks)
 	tpoolFee = tpoolFee.Mul64(750) // Estimated transaction size in bytes
 	tpoolFee = tpoolFee.Mul64(5)   // use large fee to ensure siafund transactions are selected by miners
 	output := types.SiafundOutput{
@@ -188,16 +379,19 @@ func (w *Wallet) SendSiafunds(amount types.Currency, dest types.UnlockHash) ([]t
 	}
 	err = txnBuilder.FundSiafunds(amount)
 	if err != nil {
+		txnBuilder.Drop()
 		return nil, err
 	}
 	txnBuilder.AddMinerFee(tpoolFee)
 	txnBuilder.AddSiafundOutput(output)
 	txnSet, err := txnBuilder.Sign(true)
 	if err != nil {
+		txnBuilder.Drop()
 		return nil, err
 	}
 	err = w.tpool.AcceptTransactionSet(txnSet)
 	if err != nil {
+		txnBuilder.Drop()
 		return nil, err
 	}
 	w.log.Println("Submitted a siafund transfer transaction set for value", amount.HumanString(), "with fees", tpoolFee.HumanString(), "IDs:")
@@ -4,6 +4,7 @@ import (
 	"sort"
 	"testing"
 
+	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
 )
 
@@ -36,7 +37,10 @@ func TestSendSiacoins(t *testing.T) {
 	// unconfirmed siacoins - incoming unconfirmed siacoins should equal 5000 +
 	// fee.
 	sendValue := types.SiacoinPrecision.Mul64(3)
-	_, tpoolFee := wt.wallet.tpool.FeeEstimation()
+	tpoolFee, err := wt.wallet.EstimateFee(defaultFeeTargetBlocks)
+	if err != nil {
+		t.Fatal(err)
+	}
 	tpoolFee = tpoolFee.Mul64(750)
 	_, err = wt.wallet.SendSiacoins(sendValue, types.UnlockHash{})
 	if err != nil {
@@ -152,6 +156,206 @@ func TestIntegrationSpendUnconfirmed(t *testing.T) {
 	}
 }
 
+// TestSendSiacoinsFromOutputs lists the unspent outputs of the wallet and
+// spends from one of them.
+func TestSendSiacoinsFromOutputs(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	outputs, err := wt.wallet.UnspentOutputs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var chosen modules.UnspentOutput
+	for _, uo := range outputs {
+		if uo.FundType == types.SpecifierSiacoinOutput {
+			chosen = uo
+			break
+		}
+	}
+	if chosen.Value.IsZero() || chosen.WatchOnly {
+		t.Fatal("wallet has no spendable siacoin outputs:", outputs)
+	}
+	if chosen.Confirmations == 0 || chosen.ConfirmationHeight+chosen.Confirmations != wt.cs.Height()+1 {
+		t.Fatal("wrong confirmations for output:", chosen)
+	}
+
+	// Unknown outputs cannot be spent.
+	tb := wt.wallet.StartTransaction()
+	if err := tb.FundSiacoinsFromOutputs(types.NewCurrency64(1), []types.SiacoinOutputID{{1}}); err != errUnknownOutput {
+		t.Fatal("expected errUnknownOutput, got", err)
+	}
+	tb.Drop()
+
+	// Only the chosen output should be spent.
+	id := types.SiacoinOutputID(chosen.ID)
+	if _, err := wt.wallet.SendSiacoinsFromOutputs(chosen.Value, types.UnlockHash{}, []types.SiacoinOutputID{id}); err == nil {
+		t.Fatal("output was able to pay more than its value plus fees")
+	}
+	txns, err := wt.wallet.SendSiacoinsFromOutputs(chosen.Value.Div64(2), types.UnlockHash{}, []types.SiacoinOutputID{id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, txn := range txns {
+		for _, sci := range txn.SiacoinInputs {
+			if sci.ParentID != id && sci.ParentID != txns[0].SiacoinOutputID(0) {
+				t.Fatal("wallet spent an output that was not chosen:", sci.ParentID)
+			}
+		}
+	}
+}
+
+// TestSendSiacoinsWithConfirmations checks that sends requiring a minimum
+// number of confirmations only spend old enough outputs.
+func TestSendSiacoinsWithConfirmations(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// No output has more confirmations than there are blocks.
+	tb := wt.wallet.StartTransaction()
+	if err := tb.FundSiacoinsWithConfirmations(types.NewCurrency64(1), wt.cs.Height()+2); err != errUnconfirmedFunds {
+		t.Fatal("expected errUnconfirmedFunds, got", err)
+	}
+	tb.Drop()
+
+	outputs, err := wt.wallet.UnspentOutputs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	confirmations := make(map[types.SiacoinOutputID]types.BlockHeight)
+	for _, uo := range outputs {
+		confirmations[types.SiacoinOutputID(uo.ID)] = uo.Confirmations
+	}
+	const minConfirmations = 3
+	txns, err := wt.wallet.SendSiacoinsWithConfirmations(types.SiacoinPrecision, types.UnlockHash{}, minConfirmations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, txn := range txns {
+		for _, sci := range txn.SiacoinInputs {
+			if sci.ParentID == txns[0].SiacoinOutputID(0) {
+				continue
+			}
+			if c := confirmations[sci.ParentID]; c < minConfirmations {
+				t.Fatalf("spent an output with %v confirmations", c)
+			}
+		}
+	}
+}
+
+// TestSendSiacoinsMulti sends siacoins to many addresses in a single
+// transaction.
+func TestSendSiacoinsMulti(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	if _, err := wt.wallet.SendSiacoinsMulti(nil); err != errNoOutputs {
+		t.Fatal("expected errNoOutputs, got", err)
+	}
+	outputs := []types.SiacoinOutput{{Value: types.ZeroCurrency}}
+	if _, err := wt.wallet.SendSiacoinsMulti(outputs); err != errZeroOutput {
+		t.Fatal("expected errZeroOutput, got", err)
+	}
+
+	outputs = nil
+	for i := 0; i < 30; i++ {
+		outputs = append(outputs, types.SiacoinOutput{
+			Value:      types.SiacoinPrecision.Mul64(uint64(i + 1)),
+			UnlockHash: types.UnlockHash{byte(i)},
+		})
+	}
+	txns, err := wt.wallet.SendSiacoinsMulti(outputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every output is paid by the last transaction of the set, which has a
+	// single miner fee.
+	txn := txns[len(txns)-1]
+	if len(txn.MinerFees) != 1 {
+		t.Fatal("expected a single miner fee, got", txn.MinerFees)
+	}
+	for _, sco := range outputs {
+		var found bool
+		for _, out := range txn.SiacoinOutputs {
+			found = found || (out.UnlockHash == sco.UnlockHash && out.Value.Equals(sco.Value))
+		}
+		if !found {
+			t.Fatal("transaction does not pay", sco)
+		}
+	}
+}
+
+// TestBalanceBreakdown checks that miner payouts are reported as maturing until
+// they are added to the confirmed balance.
+func TestBalanceBreakdown(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// Every block mined by the tester pays the wallet, so the payouts of the
+	// last MaturityDelay blocks are maturing.
+	bb, err := wt.wallet.BalanceBreakdown()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bb.MaturingOutputs) != int(types.MaturityDelay) {
+		t.Fatalf("expected %v maturing outputs, got %v", types.MaturityDelay, len(bb.MaturingOutputs))
+	}
+	height := wt.cs.Height()
+	total := types.ZeroCurrency
+	next := types.ZeroCurrency
+	for _, dsco := range bb.MaturingOutputs {
+		if dsco.MaturityHeight <= height || dsco.MaturityHeight > height+types.MaturityDelay {
+			t.Fatal("maturing output has a wrong maturity height:", dsco.MaturityHeight)
+		}
+		if dsco.MaturityHeight == height+1 {
+			next = next.Add(dsco.SiacoinOutput.Value)
+		}
+		total = total.Add(dsco.SiacoinOutput.Value)
+	}
+	if !total.Equals(bb.Maturing) {
+		t.Fatal("maturing balance does not match the maturing outputs")
+	}
+
+	// The next block moves the oldest payout into the confirmed balance.
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	bb2, err := wt.wallet.BalanceBreakdown()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bb2.Confirmed.Equals(bb.Confirmed.Add(next)) {
+		t.Fatalf("confirmed balance should grow by %v, went from %v to %v", next, bb.Confirmed, bb2.Confirmed)
+	}
+	if len(bb2.MaturingOutputs) != int(types.MaturityDelay) {
+		t.Fatal("wrong number of maturing outputs after a block:", len(bb2.MaturingOutputs))
+	}
+}
+
 // TestIntegrationSortedOutputsSorting checks that the outputs are being correctly sorted
 // by the currency value.
 func TestIntegrationSortedOutputsSorting(t *testing.T) {
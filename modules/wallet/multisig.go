@@ -0,0 +1,212 @@
+package wallet
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	errBadSignaturesRequired = errors.New("signatures required must be between 1 and the number of public keys")
+	errNoCosigners           = errors.New("a multisig address needs at least one cosigner public key")
+	errNothingToSign         = errors.New("wallet cannot add a signature to any of the requested inputs")
+	errUnknownInput          = errors.New("transaction does not have an input with the requested parent id")
+)
+
+// secretKeyFor returns the secret key of the wallet that corresponds to the
+// public key, if the wallet has it. Only keys generated from seeds are
+// considered, as each of them is stored under the unlock hash of the unlock
+// conditions that hold just that key.
+func (w *Wallet) secretKeyFor(spk types.SiaPublicKey) (crypto.SecretKey, bool) {
+	uc := types.UnlockConditions{
+		PublicKeys:         []types.SiaPublicKey{spk},
+		SignaturesRequired: 1,
+	}
+	key, exists := w.keys[uc.UnlockHash()]
+	if !exists || len(key.SecretKeys) != 1 {
+		return crypto.SecretKey{}, false
+	}
+	return key.SecretKeys[0], true
+}
+
+// NewMultisigAddress returns M-of-N unlock conditions, where M is
+// signaturesRequired and the N public keys are a new key from the wallet's
+// primary seed followed by the public keys of the cosigners. The unlock
+// conditions must be shared with the cosigners, who need them to spend from
+// the address. The wallet does not track the balance of multisig addresses.
+func (w *Wallet) NewMultisigAddress(signaturesRequired uint64, cosigners []types.SiaPublicKey) (types.UnlockConditions, error) {
+	if err := w.tg.Add(); err != nil {
+		return types.UnlockConditions{}, err
+	}
+	defer w.tg.Done()
+	return w.managedNewMultisigAddress(signaturesRequired, cosigners)
+}
+
+// checkMultisigParams checks that M-of-N unlock conditions can be built from
+// a new wallet key and the public keys of the cosigners.
+func checkMultisigParams(signaturesRequired uint64, cosigners []types.SiaPublicKey) error {
+	if len(cosigners) == 0 {
+		return errNoCosigners
+	} else if signaturesRequired == 0 || signaturesRequired > uint64(len(cosigners))+1 {
+		return errBadSignaturesRequired
+	}
+	return nil
+}
+
+// managedNewMultisigAddress implements NewMultisigAddress.
+func (w *Wallet) managedNewMultisigAddress(signaturesRequired uint64, cosigners []types.SiaPublicKey) (types.UnlockConditions, error) {
+	if err := checkMultisigParams(signaturesRequired, cosigners); err != nil {
+		return types.UnlockConditions{}, err
+	}
+
+	w.mu.Lock()
+	uc, err := w.nextPrimarySeedAddress(w.dbTx)
+	w.syncDB() // ensure durability of reported address
+	w.mu.Unlock()
+	if err != nil {
+		return types.UnlockConditions{}, err
+	}
+	return types.UnlockConditions{
+		PublicKeys:         append(uc.PublicKeys, cosigners...),
+		SignaturesRequired: signaturesRequired,
+	}, nil
+}
+
+// SignTransaction adds the wallet's signatures to the siacoin and siafund
+// inputs of the transaction whose parent ids are in toSign, or to every input
+// if toSign is empty. Transaction signatures with an empty Signature, such as
+// those requested by BuildUnsignedTransaction, are filled in using their
+// covered fields. Any other signatures that are added cover the whole
+// transaction, so signatures from cosigners can be added in any order, and no
+// input receives more signatures than its unlock conditions require. An error
+// is returned if the wallet could not add any signatures.
+func (w *Wallet) SignTransaction(txn *types.Transaction, toSign []crypto.Hash) error {
+	if err := w.tg.Add(); err != nil {
+		return err
+	}
+	defer w.tg.Done()
+
+	// Collect the unlock conditions of the inputs to sign.
+	inputs := make(map[crypto.Hash]types.UnlockConditions)
+	var all []crypto.Hash
+	for _, sci := range txn.SiacoinInputs {
+		inputs[crypto.Hash(sci.ParentID)] = sci.UnlockConditions
+		all = append(all, crypto.Hash(sci.ParentID))
+	}
+	for _, sfi := range txn.SiafundInputs {
+		inputs[crypto.Hash(sfi.ParentID)] = sfi.UnlockConditions
+		all = append(all, crypto.Hash(sfi.ParentID))
+	}
+	if len(toSign) == 0 {
+		toSign = all
+	}
+	for _, parentID := range toSign {
+		if _, exists := inputs[parentID]; !exists {
+			return errUnknownInput
+		}
+	}
+
+	// Collect the secret keys and external signers of the public keys that
+	// the wallet can sign for. External signers may wait for confirmation
+	// from the user, so they are called without holding the lock.
+	w.mu.RLock()
+	if !w.unlocked {
+		w.mu.RUnlock()
+		return modules.ErrLockedWallet
+	}
+	keys := make(map[string]crypto.SecretKey)
+	signers := make(map[string]modules.Signer)
+	for _, parentID := range toSign {
+		for _, spk := range inputs[parentID].PublicKeys {
+			if sk, exists := w.secretKeyFor(spk); exists {
+				keys[spk.String()] = sk
+			} else if s, exists := w.signers[spk.String()]; exists {
+				signers[spk.String()] = s.signer
+			}
+		}
+	}
+	w.mu.RUnlock()
+
+	// Sign a copy of the transaction, so that the transaction is left
+	// unchanged if an external signer fails.
+	t := *txn
+	t.TransactionSignatures = append([]types.TransactionSignature(nil), txn.TransactionSignatures...)
+	canSign := func(spk types.SiaPublicKey) bool {
+		_, hasKey := keys[spk.String()]
+		_, hasSigner := signers[spk.String()]
+		return hasKey || hasSigner
+	}
+	sign := func(sigIndex int, spk types.SiaPublicKey) error {
+		hash := t.SigHash(sigIndex)
+		if sk, exists := keys[spk.String()]; exists {
+			encodedSig := crypto.SignHash(hash, sk)
+			t.TransactionSignatures[sigIndex].Signature = encodedSig[:]
+			return nil
+		}
+		sig, err := signers[spk.String()].SignHash(spk, hash, t, sigIndex)
+		if err != nil {
+			return build.ExtendErr("external signer failed", err)
+		}
+		if err := verifySignerSignature(spk, hash, sig); err != nil {
+			return err
+		}
+		t.TransactionSignatures[sigIndex].Signature = sig
+		return nil
+	}
+
+	signed := false
+	for _, parentID := range toSign {
+		uc := inputs[parentID]
+
+		// Fill in the requested signatures that the wallet can provide, and
+		// find the public keys that have already signed the input or have
+		// been asked to.
+		used := make(map[uint64]struct{})
+		for i, sig := range t.TransactionSignatures {
+			if sig.ParentID != parentID {
+				continue
+			}
+			used[sig.PublicKeyIndex] = struct{}{}
+			if len(sig.Signature) != 0 || sig.PublicKeyIndex >= uint64(len(uc.PublicKeys)) {
+				continue
+			}
+			if !canSign(uc.PublicKeys[sig.PublicKeyIndex]) {
+				continue
+			}
+			if err := sign(i, uc.PublicKeys[sig.PublicKeyIndex]); err != nil {
+				return err
+			}
+			signed = true
+		}
+
+		for i, spk := range uc.PublicKeys {
+			if uint64(len(used)) >= uc.SignaturesRequired {
+				break
+			}
+			if _, exists := used[uint64(i)]; exists {
+				continue
+			}
+			if !canSign(spk) {
+				continue
+			}
+			t.TransactionSignatures = append(t.TransactionSignatures, types.TransactionSignature{
+				ParentID:       parentID,
+				CoveredFields:  types.CoveredFields{WholeTransaction: true},
+				PublicKeyIndex: uint64(i),
+			})
+			if err := sign(len(t.TransactionSignatures)-1, spk); err != nil {
+				return err
+			}
+			used[uint64(i)] = struct{}{}
+			signed = true
+		}
+	}
+	if !signed {
+		return errNothingToSign
+	}
+	*txn = t
+	return nil
+}
@@ -0,0 +1,127 @@
+package wallet
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestMultisigSpend creates a 2-of-2 multisig address shared by two wallets,
+// funds it, and spends from it by merging the signatures of both wallets.
+func TestMultisigSpend(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// Create a cosigning wallet on the same consensus set.
+	dir := filepath.Join(build.TempDir(modules.WalletDir, t.Name()+"-cosigner"), modules.WalletDir)
+	cosigner, err := New(wt.cs, wt.tpool, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cosigner.Close()
+	key := crypto.TwofishKey{1}
+	if _, err := cosigner.Encrypt(key); err != nil {
+		t.Fatal(err)
+	}
+	if err := cosigner.Unlock(key); err != nil {
+		t.Fatal(err)
+	}
+	cosignerUC, err := cosigner.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Check the validation of multisig parameters.
+	if _, err := wt.wallet.NewMultisigAddress(2, nil); err != errNoCosigners {
+		t.Fatal("expected errNoCosigners, got", err)
+	}
+	if _, err := wt.wallet.NewMultisigAddress(3, cosignerUC.PublicKeys); err != errBadSignaturesRequired {
+		t.Fatal("expected errBadSignaturesRequired, got", err)
+	}
+	uc, err := wt.wallet.NewMultisigAddress(2, cosignerUC.PublicKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(uc.PublicKeys) != 2 || uc.SignaturesRequired != 2 {
+		t.Fatal("wrong unlock conditions:", uc)
+	}
+
+	// Fund the multisig address.
+	amount := types.SiacoinPrecision.Mul64(10)
+	txns, err := wt.wallet.SendSiacoins(amount, uc.UnlockHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	var parentID types.SiacoinOutputID
+	for _, txn := range txns {
+		for i, sco := range txn.SiacoinOutputs {
+			if sco.UnlockHash == uc.UnlockHash() {
+				parentID = txn.SiacoinOutputID(uint64(i))
+			}
+		}
+	}
+
+	// Each wallet signs its own copy of the spending transaction.
+	txn := types.Transaction{
+		SiacoinInputs: []types.SiacoinInput{{
+			ParentID:         parentID,
+			UnlockConditions: uc,
+		}},
+		SiacoinOutputs: []types.SiacoinOutput{{
+			Value:      amount,
+			UnlockHash: types.UnlockHash{},
+		}},
+	}
+	txn1, txn2 := txn, txn
+	if err := wt.wallet.SignTransaction(&txn1, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.SignTransaction(&txn1, nil); err != errNothingToSign {
+		t.Fatal("expected errNothingToSign, got", err)
+	}
+	if err := cosigner.SignTransaction(&txn2, []crypto.Hash{crypto.Hash(parentID)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cosigner.SignTransaction(&txn2, []crypto.Hash{{1}}); err != errUnknownInput {
+		t.Fatal("expected errUnknownInput, got", err)
+	}
+
+	// Neither copy is valid on its own.
+	if err := wt.tpool.AcceptTransactionSet([]types.Transaction{txn1}); err == nil {
+		t.Fatal("transaction with a single signature was accepted")
+	}
+
+	// Merging the same signatures twice should not add duplicates.
+	merged, err := modules.MergeTransactionSignatures([]types.Transaction{txn1, txn2, txn1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged.TransactionSignatures) != 2 {
+		t.Fatal("expected 2 signatures, got", len(merged.TransactionSignatures))
+	}
+	if err := wt.tpool.AcceptTransactionSet([]types.Transaction{merged}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Different transactions cannot be merged.
+	txn2.SiacoinOutputs = []types.SiacoinOutput{{
+		Value:      amount.Sub(types.NewCurrency64(1)),
+		UnlockHash: types.UnlockHash{},
+	}}
+	if _, err := modules.MergeTransactionSignatures([]types.Transaction{txn1, txn2}); err != modules.ErrMismatchedTransactions {
+		t.Fatal("expected ErrMismatchedTransactions, got", err)
+	}
+}
@@ -0,0 +1,135 @@
+package wallet
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+const (
+	// maxWalletNameLen is the maximum length of the name of a named wallet.
+	maxWalletNameLen = 64
+)
+
+var (
+	errInvalidWalletName = errors.New("wallet names must be between 1 and 64 characters and contain only letters, digits, '-' and '_'")
+	errNoNamedWallet     = errors.New("no wallet with that name")
+	errNamedWalletExists = errors.New("a wallet with that name already exists")
+)
+
+// NamedWallets is a set of wallets identified by name. Each wallet is
+// persisted in a subdirectory of the persist directory of the set, named
+// after the wallet.
+type NamedWallets struct {
+	cs         modules.ConsensusSet
+	tpool      modules.TransactionPool
+	persistDir string
+
+	wallets map[string]*Wallet
+	mu      sync.Mutex
+}
+
+// validWalletName returns an error if name cannot be used as the name of a
+// wallet. Names are used as directory names, so they are restricted to
+// characters that are safe in paths on every platform.
+func validWalletName(name string) error {
+	if len(name) == 0 || len(name) > maxWalletNameLen {
+		return errInvalidWalletName
+	}
+	for _, c := range name {
+		if !('a' <= c && c <= 'z') && !('A' <= c && c <= 'Z') && !('0' <= c && c <= '9') && c != '-' && c != '_' {
+			return errInvalidWalletName
+		}
+	}
+	return nil
+}
+
+// NewNamedWallets loads the named wallets in persistDir, creating the
+// directory if it does not exist.
+func NewNamedWallets(cs modules.ConsensusSet, tpool modules.TransactionPool, persistDir string) (*NamedWallets, error) {
+	if err := os.MkdirAll(persistDir, 0700); err != nil {
+		return nil, err
+	}
+	nw := &NamedWallets{
+		cs:         cs,
+		tpool:      tpool,
+		persistDir: persistDir,
+		wallets:    make(map[string]*Wallet),
+	}
+
+	fis, err := ioutil.ReadDir(persistDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, fi := range fis {
+		if !fi.IsDir() || validWalletName(fi.Name()) != nil {
+			continue
+		}
+		w, err := New(cs, tpool, filepath.Join(persistDir, fi.Name()))
+		if err != nil {
+			return nil, build.ComposeErrors(err, nw.Close())
+		}
+		nw.wallets[fi.Name()] = w
+	}
+	return nw, nil
+}
+
+// CreateNamedWallet creates a new wallet with the given name.
+func (nw *NamedWallets) CreateNamedWallet(name string) (modules.Wallet, error) {
+	if err := validWalletName(name); err != nil {
+		return nil, err
+	}
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	if _, exists := nw.wallets[name]; exists {
+		return nil, errNamedWalletExists
+	}
+	w, err := New(nw.cs, nw.tpool, filepath.Join(nw.persistDir, name))
+	if err != nil {
+		return nil, err
+	}
+	nw.wallets[name] = w
+	return w, nil
+}
+
+// NamedWallet returns the wallet with the given name.
+func (nw *NamedWallets) NamedWallet(name string) (modules.Wallet, error) {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	w, exists := nw.wallets[name]
+	if !exists {
+		return nil, errNoNamedWallet
+	}
+	return w, nil
+}
+
+// NamedWalletNames returns the names of the wallets in alphabetical order.
+func (nw *NamedWallets) NamedWalletNames() []string {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	names := make([]string, 0, len(nw.wallets))
+	for name := range nw.wallets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Close closes all of the named wallets.
+func (nw *NamedWallets) Close() error {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	var errs []error
+	for _, w := range nw.wallets {
+		if err := w.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return build.JoinErrors(errs, "; ")
+}
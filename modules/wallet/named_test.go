@@ -0,0 +1,84 @@
+package wallet
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// TestNamedWallets creates named wallets and checks that they are loaded
+// again, independently of each other, when the set is reopened.
+func TestNamedWallets(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	dir := filepath.Join(wt.persistDir, modules.NamedWalletsDir)
+	nw, err := NewNamedWallets(wt.cs, wt.tpool, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"", "../escape", "cold wallet"} {
+		if _, err := nw.CreateNamedWallet(name); err != errInvalidWalletName {
+			t.Fatalf("expected errInvalidWalletName for %q, got %v", name, err)
+		}
+	}
+	hot, err := nw.CreateNamedWallet("hot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := nw.CreateNamedWallet("cold-watch"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := nw.CreateNamedWallet("hot"); err != errNamedWalletExists {
+		t.Fatal("expected errNamedWalletExists, got", err)
+	}
+	if _, err := nw.NamedWallet("mining"); err != errNoNamedWallet {
+		t.Fatal("expected errNoNamedWallet, got", err)
+	}
+
+	// Initialize one of the wallets.
+	key := crypto.GenerateTwofishKey()
+	seed, err := hot.Encrypt(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := nw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopen the set.
+	nw, err = NewNamedWallets(wt.cs, wt.tpool, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nw.Close()
+	if names := nw.NamedWalletNames(); !reflect.DeepEqual(names, []string{"cold-watch", "hot"}) {
+		t.Fatal("wrong wallet names:", names)
+	}
+	hot, err = nw.NamedWallet("hot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := hot.Unlock(key); err != nil {
+		t.Fatal(err)
+	}
+	if pseed, _, err := hot.PrimarySeed(); err != nil || pseed != seed {
+		t.Fatal("wrong primary seed after reopening:", err)
+	}
+	cold, err := nw.NamedWallet("cold-watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cold.Encrypted() {
+		t.Fatal("initializing one named wallet should not affect the others")
+	}
+}
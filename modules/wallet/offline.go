@@ -0,0 +1,144 @@
+package wallet
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	errNoOutputs          = errors.New("a transaction needs at least one output")
+	errNoWatchOnlyOutputs = errors.New("watch-only addresses do not have enough spendable outputs; outputs of addresses imported without a public key cannot be spent")
+	errMissingSignatures  = errors.New("transaction has signatures that have not been filled in")
+)
+
+// BuildUnsignedTransaction returns a transaction that sends the outputs and
+// pays an estimated miner fee using the outputs of watch-only addresses that
+// were imported with a public key. Any change is returned to the address of
+// the largest input. For every signature that is needed, the transaction holds
+// a TransactionSignature whose covered fields are set but whose Signature is
+// empty. The signatures are meant to be filled in by the holder of the secret
+// keys, for example with SignTransaction on an offline wallet, before the
+// transaction is given to BroadcastSigned. The inputs are marked as spent, so
+// they are not reused by later calls until RespendTimeout blocks have passed.
+func (w *Wallet) BuildUnsignedTransaction(outputs []types.SiacoinOutput) (types.Transaction, error) {
+	if err := w.tg.Add(); err != nil {
+		return types.Transaction{}, err
+	}
+	defer w.tg.Done()
+	if len(outputs) == 0 {
+		return types.Transaction{}, errNoOutputs
+	}
+
+	// Estimate the transaction fee.
+	tpoolFee := w.estimateFee(defaultFeeTargetBlocks)
+	tpoolFee = tpoolFee.Mul64(1000 + 60*uint64(len(outputs))) // Estimated transaction size in bytes
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	consensusHeight, err := dbGetConsensusHeight(w.dbTx)
+	if err != nil {
+		return types.Transaction{}, err
+	}
+
+	amount := tpoolFee
+	for _, sco := range outputs {
+		amount = amount.Add(sco.Value)
+	}
+
+	// Collect a value-sorted set of the siacoin outputs that can be spent.
+	var so sortedOutputs
+	err = dbForEachSiacoinOutput(w.dbTx, func(scoid types.SiacoinOutputID, sco types.SiacoinOutput) {
+		uc, exists := w.watchedAddrs[sco.UnlockHash]
+		if !exists || uc.UnlockHash() != sco.UnlockHash {
+			return
+		}
+		if checkOutputUnlockable(w.dbTx, consensusHeight, scoid, sco, uc) == nil {
+			so.ids = append(so.ids, scoid)
+			so.outputs = append(so.outputs, sco)
+		}
+	})
+	if err != nil {
+		return types.Transaction{}, err
+	}
+	sort.Sort(sort.Reverse(so))
+
+	txn := types.Transaction{
+		SiacoinOutputs: append([]types.SiacoinOutput(nil), outputs...),
+		MinerFees:      []types.Currency{tpoolFee},
+	}
+	var fund types.Currency
+	for i := range so.ids {
+		txn.SiacoinInputs = append(txn.SiacoinInputs, types.SiacoinInput{
+			ParentID:         so.ids[i],
+			UnlockConditions: w.watchedAddrs[so.outputs[i].UnlockHash],
+		})
+		fund = fund.Add(so.outputs[i].Value)
+		if fund.Cmp(amount) >= 0 {
+			break
+		}
+	}
+	if fund.Cmp(amount) < 0 {
+		return types.Transaction{}, errNoWatchOnlyOutputs
+	}
+	if !fund.Equals(amount) {
+		txn.SiacoinOutputs = append(txn.SiacoinOutputs, types.SiacoinOutput{
+			Value:      fund.Sub(amount),
+			UnlockHash: so.outputs[0].UnlockHash,
+		})
+	}
+
+	// Request a signature for every input.
+	for _, sci := range txn.SiacoinInputs {
+		for i := uint64(0); i < sci.UnlockConditions.SignaturesRequired; i++ {
+			txn.TransactionSignatures = append(txn.TransactionSignatures, types.TransactionSignature{
+				ParentID:       crypto.Hash(sci.ParentID),
+				CoveredFields:  types.CoveredFields{WholeTransaction: true},
+				PublicKeyIndex: i,
+			})
+		}
+		if err := w.markOutputSpent(types.OutputID(sci.ParentID), consensusHeight); err != nil {
+			return types.Transaction{}, err
+		}
+	}
+	return txn, nil
+}
+
+// BroadcastSigned submits a transaction that was built by
+// BuildUnsignedTransaction and signed offline to the transaction pool. The
+// wallet outputs spent by the transaction are marked as spent.
+func (w *Wallet) BroadcastSigned(txn types.Transaction) error {
+	if err := w.tg.Add(); err != nil {
+		return err
+	}
+	defer w.tg.Done()
+
+	for _, sig := range txn.TransactionSignatures {
+		if len(sig.Signature) == 0 {
+			return errMissingSignatures
+		}
+	}
+	err := w.tpool.AcceptTransactionSet([]types.Transaction{txn})
+	if err != nil {
+		return build.ExtendErr("unable to get transaction accepted", err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	consensusHeight, err := dbGetConsensusHeight(w.dbTx)
+	if err != nil {
+		return err
+	}
+	for _, sci := range txn.SiacoinInputs {
+		if !w.isWalletAddress(sci.UnlockConditions.UnlockHash()) {
+			continue
+		}
+		if err := w.markOutputSpent(types.OutputID(sci.ParentID), consensusHeight); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package wallet
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestOfflineSigning builds an unsigned transaction with a watch-only wallet,
+// signs it with the wallet that holds the keys, and broadcasts it with the
+// watch-only wallet.
+func TestOfflineSigning(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// Fund an address of the offline wallet.
+	uc, err := wt.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	amount := types.SiacoinPrecision.Mul64(100)
+	if _, err := wt.wallet.SendSiacoins(amount, uc.UnlockHash()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Watch the address with the online wallet.
+	dir := filepath.Join(build.TempDir(modules.WalletDir, t.Name()+"-online"), modules.WalletDir)
+	online, err := New(wt.cs, wt.tpool, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer online.Close()
+	key := crypto.TwofishKey{1}
+	if _, err := online.Encrypt(key); err != nil {
+		t.Fatal(err)
+	}
+	if err := online.Unlock(key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := online.AddWatchPublicKeys(uc.PublicKeys, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// Build the unsigned transaction.
+	if _, err := online.BuildUnsignedTransaction(nil); err != errNoOutputs {
+		t.Fatal("expected errNoOutputs, got", err)
+	}
+	outputs := []types.SiacoinOutput{{
+		Value:      amount.Div64(2),
+		UnlockHash: types.UnlockHash{},
+	}}
+	txn, err := online.BuildUnsignedTransaction(outputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txn.TransactionSignatures) != len(txn.SiacoinInputs) {
+		t.Fatal("expected a signature request for every input")
+	}
+	for _, sig := range txn.TransactionSignatures {
+		if len(sig.Signature) != 0 || !sig.CoveredFields.WholeTransaction {
+			t.Fatal("bad signature request:", sig)
+		}
+	}
+	if _, err := online.BuildUnsignedTransaction(outputs); err != errNoWatchOnlyOutputs {
+		t.Fatal("expected errNoWatchOnlyOutputs when respending, got", err)
+	}
+	if err := online.BroadcastSigned(txn); err != errMissingSignatures {
+		t.Fatal("expected errMissingSignatures, got", err)
+	}
+
+	// Sign the transaction offline. The requested signatures should be
+	// filled in rather than added.
+	if err := online.SignTransaction(&txn, nil); err != errNothingToSign {
+		t.Fatal("expected errNothingToSign, got", err)
+	}
+	numSigs := len(txn.TransactionSignatures)
+	if err := wt.wallet.SignTransaction(&txn, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(txn.TransactionSignatures) != numSigs {
+		t.Fatal("signing added signatures instead of filling in the requested ones")
+	}
+
+	// Broadcast the signed transaction.
+	if err := online.BroadcastSigned(txn); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	expected := amount.Sub(amount.Div64(2)).Sub(txn.MinerFees[0])
+	if sc, _, _ := online.ConfirmedBalance(); !sc.Equals(expected) {
+		t.Fatalf("watch-only balance is %v, expected %v", sc, expected)
+	}
+}
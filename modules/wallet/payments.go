@@ -0,0 +1,266 @@
+package wallet
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/NebulousLabs/bolt"
+)
+
+var (
+	errBadPaymentConfirmations = errors.New("payment confirmations must be at most 144")
+)
+
+type (
+	// A paymentSubscriber receives payment events through c. Events are
+	// queued so that a slow subscriber does not block the wallet.
+	paymentSubscriber struct {
+		confirmations types.BlockHeight
+		c             chan modules.PaymentEvent
+
+		mu     sync.Mutex
+		queue  []modules.PaymentEvent
+		notify chan struct{}
+		stop   chan struct{}
+	}
+
+	// A paymentUpdate is a payment event along with the number of
+	// confirmations that the payment had before the event.
+	paymentUpdate struct {
+		event             modules.PaymentEvent
+		prevConfirmations types.BlockHeight
+	}
+)
+
+// push queues events for delivery to the subscriber.
+func (ps *paymentSubscriber) push(events []modules.PaymentEvent) {
+	if len(events) == 0 {
+		return
+	}
+	ps.mu.Lock()
+	ps.queue = append(ps.queue, events...)
+	ps.mu.Unlock()
+	select {
+	case ps.notify <- struct{}{}:
+	default:
+	}
+}
+
+// incomingPayments returns the outputs of txn that pay the wallet, without
+// their confirmation height. The outputs of transactions that spend outputs
+// of the wallet are change rather than payments.
+func (w *Wallet) incomingPayments(txn types.Transaction) []modules.PaymentEvent {
+	for _, sci := range txn.SiacoinInputs {
+		if w.isWalletAddress(sci.UnlockConditions.UnlockHash()) {
+			return nil
+		}
+	}
+	for _, sfi := range txn.SiafundInputs {
+		if w.isWalletAddress(sfi.UnlockConditions.UnlockHash()) {
+			return nil
+		}
+	}
+	var pes []modules.PaymentEvent
+	for i, sco := range txn.SiacoinOutputs {
+		if w.isWalletAddress(sco.UnlockHash) {
+			pes = append(pes, modules.PaymentEvent{
+				ID:            types.OutputID(txn.SiacoinOutputID(uint64(i))),
+				TransactionID: txn.ID(),
+				FundType:      types.SpecifierSiacoinOutput,
+				Address:       sco.UnlockHash,
+				Value:         sco.Value,
+			})
+		}
+	}
+	for i, sfo := range txn.SiafundOutputs {
+		if w.isWalletAddress(sfo.UnlockHash) {
+			pes = append(pes, modules.PaymentEvent{
+				ID:            types.OutputID(txn.SiafundOutputID(uint64(i))),
+				TransactionID: txn.ID(),
+				FundType:      types.SpecifierSiafundOutput,
+				Address:       sfo.UnlockHash,
+				Value:         sfo.Value,
+			})
+		}
+	}
+	return pes
+}
+
+// notifyPayments sends the updates to the payment subscribers. Updates of
+// unconfirmed payments are sent to every subscriber, and updates of confirmed
+// payments to the subscribers that were still waiting for more
+// confirmations.
+func (w *Wallet) notifyPayments(updates []paymentUpdate) {
+	for ps := range w.paymentSubscribers {
+		var events []modules.PaymentEvent
+		for _, u := range updates {
+			if u.event.Confirmations == 0 || u.prevConfirmations < ps.confirmations {
+				events = append(events, u.event)
+			}
+		}
+		ps.push(events)
+	}
+}
+
+// updatePayments updates the confirmations of the recent payments of the
+// wallet with the reverted and applied blocks of cc. Subscribers are only
+// notified if the wallet is synced, so that they are not flooded with old
+// payments while the blockchain is scanned. updatePayments must be called
+// before the consensus height is updated.
+func (w *Wallet) updatePayments(tx *bolt.Tx, cc modules.ConsensusChange) error {
+	height, err := dbGetConsensusHeight(tx)
+	if err != nil {
+		return err
+	}
+	prev := make(map[types.OutputID]types.BlockHeight, len(w.recentPayments))
+	for id, pe := range w.recentPayments {
+		prev[id] = pe.Confirmations
+	}
+
+	var updates []paymentUpdate
+	for _, block := range cc.RevertedBlocks {
+		for id, pe := range w.recentPayments {
+			if pe.ConfirmationHeight == height {
+				delete(w.recentPayments, id)
+				pe.ConfirmationHeight = 0
+				pe.Confirmations = 0
+				updates = append(updates, paymentUpdate{event: pe, prevConfirmations: prev[id]})
+			}
+		}
+		if block.ID() != types.GenesisID {
+			height--
+		}
+	}
+	for _, block := range cc.AppliedBlocks {
+		if block.ID() != types.GenesisID {
+			height++
+		}
+		for _, txn := range block.Transactions {
+			for _, pe := range w.incomingPayments(txn) {
+				pe.ConfirmationHeight = height
+				w.recentPayments[pe.ID] = pe
+				delete(w.unconfirmedPayments, pe.ID)
+			}
+		}
+	}
+	for id, pe := range w.recentPayments {
+		// A rescan restarts from the genesis block.
+		if pe.ConfirmationHeight > height {
+			delete(w.recentPayments, id)
+			continue
+		}
+		confirmations := height - pe.ConfirmationHeight + 1
+		if confirmations == pe.Confirmations {
+			continue
+		}
+		pe.Confirmations = confirmations
+		updates = append(updates, paymentUpdate{event: pe, prevConfirmations: prev[id]})
+		if confirmations >= maxPaymentConfirmations {
+			delete(w.recentPayments, id)
+		} else {
+			w.recentPayments[id] = pe
+		}
+	}
+
+	if cc.Synced {
+		sort.Slice(updates, func(i, j int) bool {
+			if updates[i].event.ConfirmationHeight != updates[j].event.ConfirmationHeight {
+				return updates[i].event.ConfirmationHeight < updates[j].event.ConfirmationHeight
+			}
+			return updates[i].event.ID.String() < updates[j].event.ID.String()
+		})
+		w.notifyPayments(updates)
+	}
+	return nil
+}
+
+// updateUnconfirmedPayments notifies the subscribers of the payments that
+// have appeared in the transaction pool since the last update.
+func (w *Wallet) updateUnconfirmedPayments() {
+	unconfirmed := make(map[types.OutputID]struct{})
+	var updates []paymentUpdate
+	for _, pt := range w.unconfirmedProcessedTransactions {
+		for _, pe := range w.incomingPayments(pt.Transaction) {
+			unconfirmed[pe.ID] = struct{}{}
+			if _, exists := w.unconfirmedPayments[pe.ID]; !exists {
+				updates = append(updates, paymentUpdate{event: pe})
+			}
+		}
+	}
+	w.unconfirmedPayments = unconfirmed
+	w.notifyPayments(updates)
+}
+
+// SubscribePayments returns a channel that receives the payment events of the
+// wallet. Events are sent when a payment appears in the transaction pool and
+// whenever its number of confirmations changes, until it has at least the
+// given number of confirmations. Events are queued until they are received,
+// so the channel should be drained promptly. The channel is closed when
+// unsubscribe is called or the wallet is closed.
+func (w *Wallet) SubscribePayments(confirmations types.BlockHeight) (<-chan modules.PaymentEvent, func(), error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, nil, err
+	}
+	defer w.tg.Done()
+	if confirmations > maxPaymentConfirmations {
+		return nil, nil, errBadPaymentConfirmations
+	}
+
+	ps := &paymentSubscriber{
+		confirmations: confirmations,
+		c:             make(chan modules.PaymentEvent),
+		notify:        make(chan struct{}, 1),
+		stop:          make(chan struct{}),
+	}
+	w.mu.Lock()
+	w.paymentSubscribers[ps] = struct{}{}
+	w.mu.Unlock()
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			w.mu.Lock()
+			delete(w.paymentSubscribers, ps)
+			w.mu.Unlock()
+			close(ps.stop)
+		})
+	}
+	go w.threadedDeliverPayments(ps)
+	return ps.c, unsubscribe, nil
+}
+
+// threadedDeliverPayments sends the queued events of a subscriber to its
+// channel, closing the channel when the subscription ends.
+func (w *Wallet) threadedDeliverPayments(ps *paymentSubscriber) {
+	defer close(ps.c)
+	if err := w.tg.Add(); err != nil {
+		return
+	}
+	defer w.tg.Done()
+
+	for {
+		select {
+		case <-ps.notify:
+		case <-ps.stop:
+			return
+		case <-w.tg.StopChan():
+			return
+		}
+		ps.mu.Lock()
+		events := ps.queue
+		ps.queue = nil
+		ps.mu.Unlock()
+		for _, pe := range events {
+			select {
+			case ps.c <- pe:
+			case <-ps.stop:
+				return
+			case <-w.tg.StopChan():
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package wallet
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestSubscribePayments checks the payment events received by a wallet that
+// is paid by another wallet.
+func TestSubscribePayments(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// Create a merchant wallet on the same consensus set.
+	dir := filepath.Join(build.TempDir(modules.WalletDir, t.Name()+"-merchant"), modules.WalletDir)
+	merchant, err := New(wt.cs, wt.tpool, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer merchant.Close()
+	key := crypto.TwofishKey{1}
+	if _, err := merchant.Encrypt(key); err != nil {
+		t.Fatal(err)
+	}
+	if err := merchant.Unlock(key); err != nil {
+		t.Fatal(err)
+	}
+	uc, err := merchant.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := merchant.SubscribePayments(maxPaymentConfirmations + 1); err != errBadPaymentConfirmations {
+		t.Fatal("expected errBadPaymentConfirmations, got", err)
+	}
+	events, unsubscribe, err := merchant.SubscribePayments(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nextEvent := func() (modules.PaymentEvent, bool) {
+		select {
+		case pe := <-events:
+			return pe, true
+		case <-time.After(time.Second):
+			return modules.PaymentEvent{}, false
+		}
+	}
+
+	// The payment is reported when it enters the transaction pool, and at
+	// each confirmation until it has two.
+	amount := types.SiacoinPrecision.Mul64(100)
+	if _, err := wt.wallet.SendSiacoins(amount, uc.UnlockHash()); err != nil {
+		t.Fatal(err)
+	}
+	pe, ok := nextEvent()
+	if !ok || pe.Confirmations != 0 || pe.Address != uc.UnlockHash() || pe.Value.Cmp(amount) != 0 {
+		t.Fatal("wrong unconfirmed payment event:", pe, ok)
+	}
+	for confirmations := types.BlockHeight(1); confirmations <= 2; confirmations++ {
+		if err := wt.addBlockNoPayout(); err != nil {
+			t.Fatal(err)
+		}
+		next, ok := nextEvent()
+		if !ok || next.ID != pe.ID || next.Confirmations != confirmations {
+			t.Fatal("wrong confirmed payment event:", next, ok)
+		}
+	}
+	if err := wt.addBlockNoPayout(); err != nil {
+		t.Fatal(err)
+	}
+	if pe, ok := nextEvent(); ok {
+		t.Fatal("payment was reported beyond the requested confirmations:", pe)
+	}
+
+	// Change outputs of the paying wallet are not payments.
+	changeEvents, unsubscribeChange, err := wt.wallet.SubscribePayments(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribeChange()
+	if _, err := wt.wallet.SendSiacoins(amount, uc.UnlockHash()); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.addBlockNoPayout(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case pe := <-changeEvents:
+		t.Fatal("change was reported as a payment:", pe)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// The channel is closed when the subscription ends.
+	unsubscribe()
+	for range events {
+	}
+}
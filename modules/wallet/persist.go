@@ -2,7 +2,6 @@ package wallet
 
 import (
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 
@@ -70,6 +69,15 @@ func (w *Wallet) openDB(filename string) (err error) {
 		if wb.Get(keySiafundPool) == nil {
 			wb.Put(keySiafundPool, encoding.Marshal(types.ZeroCurrency))
 		}
+		if wb.Get(keyDefragSettings) == nil {
+			wb.Put(keyDefragSettings, encoding.Marshal(defaultDefragSettings()))
+		}
+		if wb.Get(keyScanGapLimit) == nil {
+			wb.Put(keyScanGapLimit, encoding.Marshal(defaultScanGapLimit))
+		}
+		if wb.Get(keyAutoLockTimeout) == nil {
+			wb.Put(keyAutoLockTimeout, encoding.Marshal(uint64(0)))
+		}
 
 		// check whether wallet is encrypted
 		w.encrypted = tx.Bucket(bucketWallet).Get(keyEncryptionVerification) != nil
@@ -111,29 +119,73 @@ func (w *Wallet) initPersist() error {
 	}
 	w.tg.AfterStop(func() { w.db.Close() })
 
-	return nil
-}
+	// Load the metadata of the wallet that is not secret.
+	err = w.db.View(w.loadPersist)
+	if err != nil {
+		return err
+	}
 
-// createBackup copies the wallet database to dst.
-func (w *Wallet) createBackup(dst io.Writer) error {
-	_, err := w.dbTx.WriteTo(dst)
-	return err
+	return nil
 }
 
-// CreateBackup creates a backup file at the desired filepath.
-func (w *Wallet) CreateBackup(backupFilepath string) error {
-	if err := w.tg.Add(); err != nil {
+// loadPersist loads the watch-only addresses, the defrag settings, the scan
+// gap limit, the auto-lock timeout, the address labels, the address book, the
+// multisig templates, the transaction memos, the scheduled transactions and
+// the atomic swaps of the wallet from tx. Unlike keys, they are not secret, so they are available
+// before the wallet is unlocked.
+func (w *Wallet) loadPersist(tx *bolt.Tx) error {
+	var err error
+	w.defragSettings, err = dbGetDefragSettings(tx)
+	if err != nil {
 		return err
 	}
-	defer w.tg.Done()
-	w.mu.Lock()
-	defer w.mu.Unlock()
-	f, err := os.Create(backupFilepath)
+	w.scanGapLimit, err = dbGetScanGapLimit(tx)
+	if err != nil {
+		return err
+	}
+	w.autoLockTimeout, err = dbGetAutoLockTimeout(tx)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-	return w.createBackup(f)
+	err = dbForEachAddrLabel(tx, func(uh types.UnlockHash, label string) {
+		w.addrLabels[uh] = label
+	})
+	if err != nil {
+		return err
+	}
+	err = dbForEachAddressBookEntry(tx, func(name string, uh types.UnlockHash) {
+		w.addressBook[name] = uh
+	})
+	if err != nil {
+		return err
+	}
+	err = dbForEachUCTemplate(tx, func(name string, t modules.UnlockConditionsTemplate) {
+		w.ucTemplates[name] = t
+	})
+	if err != nil {
+		return err
+	}
+	err = dbForEachTxnMemo(tx, func(txid types.TransactionID, memo string) {
+		w.txnMemos[txid] = memo
+	})
+	if err != nil {
+		return err
+	}
+	err = dbForEachScheduledTxn(tx, func(id types.TransactionID, st modules.ScheduledTransaction) {
+		w.scheduledTxns[id] = st
+	})
+	if err != nil {
+		return err
+	}
+	err = dbForEachAtomicSwap(tx, func(id types.FileContractID, swap modules.AtomicSwap) {
+		w.atomicSwaps[id] = swap
+	})
+	if err != nil {
+		return err
+	}
+	return dbForEachWatchedAddr(tx, func(uh types.UnlockHash, uc types.UnlockConditions) {
+		w.watchedAddrs[uh] = uc
+	})
 }
 
 // compat112Persist is the structure of the wallet.json file used in v1.1.2
@@ -186,6 +238,7 @@ func (w *Wallet) convertPersistFrom112To120(dbFilename, compatFilename string) e
 		// triggered
 		dbPutConsensusHeight(tx, 0)
 		dbPutConsensusChangeID(tx, modules.ConsensusChangeBeginning)
+		dbPutDefragSettings(tx, defaultDefragSettings())
 		return nil
 	})
 	w.encrypted = true
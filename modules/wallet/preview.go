@@ -0,0 +1,87 @@
+package wallet
+
+import (
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// transactionSetFee returns the sum of the miner fees of a transaction set.
+func transactionSetFee(txnSet []types.Transaction) types.Currency {
+	var fee types.Currency
+	for _, txn := range txnSet {
+		for _, mf := range txn.MinerFees {
+			fee = fee.Add(mf)
+		}
+	}
+	return fee
+}
+
+// previewTransactionSet describes a transaction set that sends 'sent'
+// siacoins. The inputs of the set are the outputs that it spends from outside
+// of the set, and its change is the value of the outputs that are not spent
+// within the set, less the value sent.
+func previewTransactionSet(txnSet []types.Transaction, sent types.Currency) modules.TransactionPreview {
+	created := make(map[types.SiacoinOutputID]types.Currency)
+	for _, txn := range txnSet {
+		for i, sco := range txn.SiacoinOutputs {
+			created[txn.SiacoinOutputID(uint64(i))] = sco.Value
+		}
+	}
+
+	var tp modules.TransactionPreview
+	for _, txn := range txnSet {
+		tp.Size += uint64(len(encoding.Marshal(txn)))
+		for _, sci := range txn.SiacoinInputs {
+			if _, exists := created[sci.ParentID]; exists {
+				delete(created, sci.ParentID)
+			} else {
+				tp.Inputs = append(tp.Inputs, sci.ParentID)
+			}
+		}
+	}
+	tp.Fee = transactionSetFee(txnSet)
+	var unspent types.Currency
+	for _, value := range created {
+		unspent = unspent.Add(value)
+	}
+	tp.Change = unspent.Sub(sent)
+	return tp
+}
+
+// PreviewSiacoins builds the transaction set that SendSiacoins would send,
+// and describes it without submitting it to the transaction pool. If ids is
+// not empty, only the outputs in ids are spent, as in SendSiacoinsFromOutputs.
+// Otherwise, if minConfirmations is not zero, only outputs with enough
+// confirmations are spent, as in SendSiacoinsWithConfirmations.
+func (w *Wallet) PreviewSiacoins(amount types.Currency, dest types.UnlockHash, ids []types.SiacoinOutputID, minConfirmations types.BlockHeight) (modules.TransactionPreview, error) {
+	if err := w.tg.Add(); err != nil {
+		return modules.TransactionPreview{}, err
+	}
+	defer w.tg.Done()
+	txnSet, txnBuilder, err := w.managedBuildSiacoins(amount, dest, ids, minConfirmations)
+	if err != nil {
+		return modules.TransactionPreview{}, err
+	}
+	txnBuilder.Drop()
+	return previewTransactionSet(txnSet, amount), nil
+}
+
+// PreviewSiacoinsMulti builds the transaction set that SendSiacoinsMulti would
+// send, and describes it without submitting it to the transaction pool.
+func (w *Wallet) PreviewSiacoinsMulti(outputs []types.SiacoinOutput) (modules.TransactionPreview, error) {
+	if err := w.tg.Add(); err != nil {
+		return modules.TransactionPreview{}, err
+	}
+	defer w.tg.Done()
+	txnSet, txnBuilder, err := w.managedBuildSiacoinsMulti(outputs)
+	if err != nil {
+		return modules.TransactionPreview{}, err
+	}
+	txnBuilder.Drop()
+	var sent types.Currency
+	for _, sco := range outputs {
+		sent = sent.Add(sco.Value)
+	}
+	return previewTransactionSet(txnSet, sent), nil
+}
@@ -0,0 +1,82 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestPreviewSiacoins previews a siacoin transfer and checks that nothing is
+// broadcast and no outputs are left marked as spent.
+func TestPreviewSiacoins(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	outputs, err := wt.wallet.UnspentOutputs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := make(map[types.SiacoinOutputID]types.Currency)
+	for _, uo := range outputs {
+		values[types.SiacoinOutputID(uo.ID)] = uo.Value
+	}
+
+	amount := types.SiacoinPrecision.Mul64(100)
+	tp, err := wt.wallet.PreviewSiacoins(amount, types.UnlockHash{}, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tp.Size == 0 || tp.Fee.IsZero() || len(tp.Inputs) == 0 {
+		t.Fatal("incomplete preview:", tp)
+	}
+	var spent types.Currency
+	for _, id := range tp.Inputs {
+		value, exists := values[id]
+		if !exists {
+			t.Fatal("preview spends an output that is not in the wallet:", id)
+		}
+		spent = spent.Add(value)
+	}
+	if !spent.Equals(amount.Add(tp.Fee).Add(tp.Change)) {
+		t.Fatal("inputs do not pay for the amount, fee and change:", spent, tp)
+	}
+	if len(wt.tpool.TransactionList()) != 0 {
+		t.Fatal("preview was broadcast")
+	}
+
+	// The previewed inputs should still be spendable.
+	txns, err := wt.wallet.SendSiacoinsFromOutputs(amount, types.UnlockHash{}, tp.Inputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fee types.Currency
+	for _, txn := range txns {
+		for _, mf := range txn.MinerFees {
+			fee = fee.Add(mf)
+		}
+	}
+	if !fee.Equals(tp.Fee) {
+		t.Fatal("preview fee does not match the fee of the transaction:", tp.Fee, fee)
+	}
+
+	// Preview a transfer to many addresses.
+	tp, err = wt.wallet.PreviewSiacoinsMulti([]types.SiacoinOutput{
+		{Value: amount, UnlockHash: types.UnlockHash{1}},
+		{Value: amount, UnlockHash: types.UnlockHash{2}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tp.Size == 0 || tp.Fee.IsZero() || len(tp.Inputs) == 0 {
+		t.Fatal("incomplete preview:", tp)
+	}
+	if _, err := wt.wallet.PreviewSiacoinsMulti(nil); err != errNoOutputs {
+		t.Fatal("expected errNoOutputs, got", err)
+	}
+}
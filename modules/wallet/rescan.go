@@ -0,0 +1,96 @@
+package wallet
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	errRescanHeight = errors.New("rescan height is above the current block height")
+)
+
+// rescan clears the outputs of the wallet and its history from fromHeight
+// onwards, and rebuilds them by resubscribing to the consensus set from the
+// beginning. The output diffs of every block are needed to rebuild the
+// outputs, but the history of blocks below fromHeight is kept as it is rather
+// than replayed. Keys and seeds are not touched. The caller must hold the
+// scanLock. If the wallet has not subscribed yet, the rescan happens when it
+// is first unlocked.
+func (w *Wallet) rescan(fromHeight types.BlockHeight) error {
+	w.cs.Unsubscribe(w)
+	w.tpool.Unsubscribe(w)
+
+	w.mu.Lock()
+	for _, bucket := range [][]byte{bucketSiacoinOutputs, bucketSiafundOutputs} {
+		if err := w.dbTx.DeleteBucket(bucket); err != nil {
+			w.mu.Unlock()
+			return err
+		}
+		if _, err := w.dbTx.CreateBucket(bucket); err != nil {
+			w.mu.Unlock()
+			return err
+		}
+	}
+	for {
+		pt, err := dbGetLastProcessedTransaction(w.dbTx)
+		if err != nil || pt.ConfirmationHeight < fromHeight {
+			break // bucket is empty, or the rest of the history is kept
+		}
+		if err := dbDeleteLastProcessedTransaction(w.dbTx); err != nil {
+			w.mu.Unlock()
+			return err
+		}
+	}
+	dbPutConsensusHeight(w.dbTx, 0)
+	dbPutConsensusChangeID(w.dbTx, modules.ConsensusChangeBeginning)
+	dbPutSiafundPool(w.dbTx, types.ZeroCurrency)
+	w.unconfirmedSets = make(map[modules.TransactionSetID][]types.TransactionID)
+	w.unconfirmedSpends = make(map[modules.TransactionSetID][]types.OutputID)
+	w.droppedSpends = make(map[types.OutputID]struct{})
+	w.unconfirmedProcessedTransactions = nil
+	w.rescanHeight = fromHeight
+	subscribed := w.subscribed
+	w.mu.Unlock()
+
+	if !subscribed {
+		return nil
+	}
+	err := w.cs.ConsensusSetSubscribe(w, modules.ConsensusChangeBeginning)
+	w.mu.Lock()
+	w.rescanHeight = 0
+	w.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	w.tpool.TransactionPoolSubscribe(w)
+	return nil
+}
+
+// Rescan rebuilds the outputs of the wallet and its transaction history from
+// fromHeight onwards, without deleting the wallet's files. History below
+// fromHeight is kept. A rescan from height 0 rebuilds all of the history,
+// which is useful if the wallet's view of the blockchain has become
+// inconsistent.
+func (w *Wallet) Rescan(fromHeight types.BlockHeight) error {
+	if err := w.tg.Add(); err != nil {
+		return err
+	}
+	defer w.tg.Done()
+	if !w.scanLock.TryLock() {
+		return errScanInProgress
+	}
+	defer w.scanLock.Unlock()
+
+	if fromHeight > w.cs.Height() {
+		return errRescanHeight
+	}
+	w.mu.RLock()
+	unlocked := w.unlocked
+	w.mu.RUnlock()
+	if !unlocked {
+		return modules.ErrLockedWallet
+	}
+	return w.rescan(fromHeight)
+}
@@ -0,0 +1,83 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestRescan checks that a rescan rebuilds the outputs and history of the
+// wallet.
+func TestRescan(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// Add some history that is not a miner payout.
+	uc, err := wt.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.wallet.SendSiacoins(types.SiacoinPrecision.Mul64(100), uc.UnlockHash()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	height := wt.cs.Height()
+	balance, _, _ := wt.wallet.ConfirmedBalance()
+	history, err := wt.wallet.Transactions(0, height)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate a wallet whose outputs are inconsistent with the blockchain.
+	wt.wallet.mu.Lock()
+	wt.wallet.dbTx.DeleteBucket(bucketSiacoinOutputs)
+	wt.wallet.dbTx.CreateBucket(bucketSiacoinOutputs)
+	wt.wallet.mu.Unlock()
+	if sc, _, _ := wt.wallet.ConfirmedBalance(); !sc.IsZero() {
+		t.Fatal("balance should be zero after deleting the outputs:", sc)
+	}
+
+	if err := wt.wallet.Rescan(height + 1); err != errRescanHeight {
+		t.Fatal("expected errRescanHeight, got", err)
+	}
+
+	// Rescan both the whole blockchain and just its last blocks. The balance
+	// and history should be the same as before.
+	for _, from := range []types.BlockHeight{0, height - 1} {
+		if err := wt.wallet.Rescan(from); err != nil {
+			t.Fatal(err)
+		}
+		if sc, _, _ := wt.wallet.ConfirmedBalance(); !sc.Equals(balance) {
+			t.Fatalf("rescan from %v: balance is %v, expected %v", from, sc, balance)
+		}
+		txns, err := wt.wallet.Transactions(0, height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(txns) != len(history) {
+			t.Fatalf("rescan from %v: history has %v transactions, expected %v", from, len(txns), len(history))
+		}
+		for i := range txns {
+			if txns[i].TransactionID != history[i].TransactionID || txns[i].ConfirmationHeight != history[i].ConfirmationHeight {
+				t.Fatalf("rescan from %v: history differs at transaction %v", from, i)
+			}
+		}
+	}
+
+	// A rescan needs the keys of the wallet.
+	if err := wt.wallet.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.Rescan(0); err != modules.ErrLockedWallet {
+		t.Fatal("expected ErrLockedWallet, got", err)
+	}
+}
@@ -9,10 +9,9 @@ import (
 	"github.com/NebulousLabs/Sia/types"
 )
 
-const scanMultiplier = 4 // how many more keys to generate after each scan iteration
-
 // numInitialKeys is the number of keys generated by the seedScanner before
-// scanning the blockchain for the first time.
+// scanning the blockchain for the first time, when using the default gap
+// limit.
 var numInitialKeys = func() uint64 {
 	switch build.Release {
 	case "dev":
@@ -41,7 +40,14 @@ var maxScanKeys = func() uint64 {
 	}
 }()
 
-var errMaxKeys = fmt.Errorf("refused to generate more than %v keys from seed", maxScanKeys)
+// defaultScanGapLimit is the default number of unused keys that the
+// seedScanner keeps beyond the largest key index it has found.
+var defaultScanGapLimit = numInitialKeys / 2
+
+var (
+	errMaxKeys     = fmt.Errorf("refused to generate more than %v keys from seed", maxScanKeys)
+	errBadGapLimit = fmt.Errorf("scan gap limit must be between 1 and %v", maxScanKeys/2)
+)
 
 // A scannedOutput is an output found in the blockchain that was generated
 // from a given seed.
@@ -55,7 +61,10 @@ type scannedOutput struct {
 // seed.
 type seedScanner struct {
 	dustThreshold    types.Currency              // minimum value of outputs to be included
+	gapLimit         uint64                      // number of keys to keep beyond largestIndexSeen
 	keys             map[types.UnlockHash]uint64 // map address to seed index
+	keysExhausted    bool                        // whether the gap limit could not be kept because of maxScanKeys
+	keysExtended     bool                        // whether keys were generated during the current pass
 	largestIndexSeen uint64                      // largest index that has appeared in the blockchain
 	seed             modules.Seed
 	siacoinOutputs   map[types.SiacoinOutputID]scannedOutput
@@ -76,6 +85,24 @@ func (s *seedScanner) generateKeys(n uint64) {
 	}
 }
 
+// extendKeys generates more keys if fewer than gapLimit keys follow the
+// largest index seen. To avoid generating keys for every new index found,
+// keys are generated up to twice the gap limit beyond the largest index.
+func (s *seedScanner) extendKeys() {
+	if s.numKeys() > s.largestIndexSeen+s.gapLimit {
+		return
+	}
+	target := s.largestIndexSeen + 1 + 2*s.gapLimit
+	if target > maxScanKeys {
+		target = maxScanKeys
+		s.keysExhausted = true
+	}
+	if target > s.numKeys() {
+		s.generateKeys(target - s.numKeys())
+		s.keysExtended = true
+	}
+}
+
 // ProcessConsensusChange scans the blockchain for information relevant to the
 // seedScanner.
 func (s *seedScanner) ProcessConsensusChange(cc modules.ConsensusChange) {
@@ -136,42 +163,46 @@ func (s *seedScanner) ProcessConsensusChange(cc modules.ConsensusChange) {
 			}
 		}
 	}
+
+	// keep gapLimit keys beyond the largest index seen, so that the keys
+	// that follow it are found in the rest of the pass
+	s.extendKeys()
 }
 
 // scan subscribes s to cs and scans the blockchain for addresses that belong
 // to s's seed. If scan returns errMaxKeys, additional keys may need to be
 // generated to find all the addresses.
 func (s *seedScanner) scan(cs modules.ConsensusSet) error {
-	// generate a bunch of keys and scan the blockchain looking for them. If
-	// none of the 'upper' half of the generated keys are found, we are done;
-	// otherwise, generate more keys and try again (bounded by a sane
-	// default).
+	// generate keys up to twice the gap limit and scan the blockchain looking
+	// for them. Whenever a key is found, more keys are generated so that
+	// gapLimit unused keys follow the largest index seen. Outputs of the new
+	// keys may be in blocks that were already scanned, so the blockchain is
+	// scanned again until a pass does not generate any keys.
 	//
 	// NOTE: since scanning is very slow, we aim to only scan once, which
 	// means generating many keys.
-	var numKeys uint64 = numInitialKeys
-	for s.numKeys() < maxScanKeys {
-		s.generateKeys(numKeys)
+	s.extendKeys()
+	for {
+		s.keysExtended = false
 		if err := cs.ConsensusSetSubscribe(s, modules.ConsensusChangeBeginning); err != nil {
 			return err
 		}
 		cs.Unsubscribe(s)
-		if s.largestIndexSeen < s.numKeys()/2 {
-			return nil
-		}
-		// increase number of keys generated each iteration, capping so that
-		// we do not exceed maxScanKeys
-		numKeys *= scanMultiplier
-		if numKeys > maxScanKeys-s.numKeys() {
-			numKeys = maxScanKeys - s.numKeys()
+		if !s.keysExtended {
+			break
 		}
+		s.log.Debugln("Seed scanner generated keys up to index", s.numKeys(), "- rescanning")
+	}
+	if s.keysExhausted {
+		return errMaxKeys
 	}
-	return errMaxKeys
+	return nil
 }
 
 // newSeedScanner returns a new seedScanner.
 func newSeedScanner(seed modules.Seed, log *persist.Logger) *seedScanner {
 	return &seedScanner{
+		gapLimit:       defaultScanGapLimit,
 		seed:           seed,
 		keys:           make(map[types.UnlockHash]uint64),
 		siacoinOutputs: make(map[types.SiacoinOutputID]scannedOutput),
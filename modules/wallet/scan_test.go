@@ -3,7 +3,6 @@ package wallet
 import (
 	"testing"
 
-	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/types"
 	"github.com/NebulousLabs/fastrand"
@@ -75,59 +74,109 @@ func TestScanLargeIndex(t *testing.T) {
 	}
 }
 
-// TestScanLoop tests that the scan loop will continue to run as long as it
-// finds indices in the upper half of the last set of generated keys.
+// TestScanLoop tests that the scanner extends its keys by the gap limit as it
+// finds used keys, and rescans the blockchain for keys that were generated
+// after the blocks using them were scanned.
 func TestScanLoop(t *testing.T) {
-	if testing.Short() || !build.VLONG {
+	if testing.Short() {
 		t.SkipNow()
 	}
 
 	// create a wallet
-	wt, err := createWalletTester("TestScanLoop")
+	wt, err := createWalletTester(t.Name())
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer wt.closeWt()
 
-	// send money to ourselves at four specific indices. This should cause the
-	// scanner to loop exactly three times.
-	indices := []uint64{500, 2500, 8000, 100000}
-	for _, index := range indices {
-		wt.wallet.mu.Lock()
-		dbPutPrimarySeedProgress(wt.wallet.dbTx, index)
-		wt.wallet.mu.Unlock()
-		if err != nil {
+	// send money to the keys of the seed at specific indices, each in its
+	// own block.
+	// 1600 is only in range of the gap limit once 700 has been found, which
+	// happens in a later block. 3000 is too far beyond 1600 to be found with
+	// the default gap limit.
+	seed, _, err := wt.wallet.PrimarySeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, index := range []uint64{1600, 700, 3000} {
+		addr := generateSpendableKey(seed, index).UnlockConditions.UnlockHash()
+		if _, err := wt.wallet.SendSiacoins(types.SiacoinPrecision, addr); err != nil {
 			t.Fatal(err)
 		}
-
-		uc, err := wt.wallet.NextAddress()
-		if err != nil {
+		if _, err := wt.miner.AddBlock(); err != nil {
 			t.Fatal(err)
 		}
-		_, err = wt.wallet.SendSiacoins(types.SiacoinPrecision, uc.UnlockHash())
-		if err != nil {
-			t.Fatal(err)
+	}
+
+	// found reports whether the scanner found an output at index.
+	found := func(ss *seedScanner, index uint64) bool {
+		for _, so := range ss.siacoinOutputs {
+			if so.seedIndex == index {
+				return true
+			}
 		}
+		return false
 	}
-	wt.miner.AddBlock()
 
-	// create seed scanner and scan the block
-	seed, _, _ := wt.wallet.PrimarySeed()
 	ss := newSeedScanner(seed, wt.wallet.log)
-	err = ss.scan(wt.cs)
+	if ss.gapLimit != 500 {
+		t.Fatal("test assumes a default gap limit of 500, got", ss.gapLimit)
+	}
+	if err := ss.scan(wt.cs); err != nil {
+		t.Fatal(err)
+	}
+	if !found(ss, 700) || !found(ss, 1600) {
+		t.Error("scanner did not find the outputs within the gap limit")
+	}
+	if found(ss, 3000) {
+		t.Error("scanner found an output beyond the gap limit")
+	}
+
+	// with a larger gap limit, the last output is found as well
+	ss = newSeedScanner(seed, wt.wallet.log)
+	ss.gapLimit = 1500
+	if err := ss.scan(wt.cs); err != nil {
+		t.Fatal(err)
+	}
+	if !found(ss, 3000) {
+		t.Error("scanner did not find the output within the larger gap limit")
+	}
+}
+
+// TestScanGapLimit tests setting the gap limit used by seed scans.
+func TestScanGapLimit(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
 	if err != nil {
 		t.Fatal(err)
 	}
+	defer wt.closeWt()
+
+	if wt.wallet.ScanGapLimit() != defaultScanGapLimit {
+		t.Fatal("wrong default gap limit:", wt.wallet.ScanGapLimit())
+	}
+	if err := wt.wallet.SetScanGapLimit(0); err != errBadGapLimit {
+		t.Fatal("expected errBadGapLimit, got", err)
+	}
+	if err := wt.wallet.SetScanGapLimit(maxScanKeys); err != errBadGapLimit {
+		t.Fatal("expected errBadGapLimit, got", err)
+	}
+	if err := wt.wallet.SetScanGapLimit(2000); err != nil {
+		t.Fatal(err)
+	}
 
-	// the scanner should have generated a specific number of keys
-	expected := numInitialKeys + (numInitialKeys * scanMultiplier) + (numInitialKeys * scanMultiplier * scanMultiplier)
-	if uint64(len(ss.keys)) != expected {
-		t.Errorf("expected %v keys, got %v", expected, len(ss.keys))
+	// the gap limit is persisted
+	if err := wt.wallet.Close(); err != nil {
+		t.Fatal(err)
+	}
+	w, err := New(wt.cs, wt.tpool, wt.wallet.persistDir)
+	if err != nil {
+		t.Fatal(err)
 	}
-	// the largest index seen should be the penultimate element (+2, since 2
-	// addresses are generated when sending coins). The last element should
-	// not be seen, because it was outside the scanning range.
-	if ss.largestIndexSeen != indices[len(indices)-2]+2 {
-		t.Errorf("expected largest index to be %v, got %v", indices[len(indices)-2]+2, ss.largestIndexSeen)
+	wt.wallet = w
+	if w.ScanGapLimit() != 2000 {
+		t.Fatal("gap limit was not persisted:", w.ScanGapLimit())
 	}
 }
@@ -0,0 +1,192 @@
+package wallet
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	errEmptySchedule         = errors.New("a scheduled transaction needs a height or a timestamp")
+	errNoScheduledTxns       = errors.New("no transactions to schedule")
+	errScheduledTxnExists    = errors.New("transaction set is already scheduled")
+	errUnknownScheduledTxn   = errors.New("no transaction set with that id is scheduled")
+	errUnsignedScheduledTxns = errors.New("scheduled transactions must be fully signed")
+)
+
+// spentBySchedule returns true if one of the scheduled transactions spends
+// the output, in which case the wallet must not spend it elsewhere.
+func (w *Wallet) spentBySchedule(id types.OutputID) bool {
+	for _, st := range w.scheduledTxns {
+		for _, txn := range st.Transactions {
+			for _, sci := range txn.SiacoinInputs {
+				if types.OutputID(sci.ParentID) == id {
+					return true
+				}
+			}
+			for _, sfi := range txn.SiafundInputs {
+				if types.OutputID(sfi.ParentID) == id {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// ScheduleTransaction stores a signed transaction set that is broadcast at the
+// first block whose height is at least height and whose timestamp is at least
+// timestamp. The transactions must be valid at the scheduled height apart from
+// their inputs, which allows them to spend timelocked outputs. Outputs of the
+// wallet that the transactions spend are not used by other transactions of
+// the wallet until the schedule is cancelled. The schedule persists across
+// restarts.
+func (w *Wallet) ScheduleTransaction(txns []types.Transaction, height types.BlockHeight, timestamp types.Timestamp) (modules.ScheduledTransaction, error) {
+	if err := w.tg.Add(); err != nil {
+		return modules.ScheduledTransaction{}, err
+	}
+	defer w.tg.Done()
+
+	if len(txns) == 0 {
+		return modules.ScheduledTransaction{}, errNoScheduledTxns
+	} else if height == 0 && timestamp == 0 {
+		return modules.ScheduledTransaction{}, errEmptySchedule
+	}
+	validHeight := height
+	if current := w.cs.Height(); validHeight < current {
+		validHeight = current
+	}
+	for _, txn := range txns {
+		for _, sig := range txn.TransactionSignatures {
+			if len(sig.Signature) == 0 {
+				return modules.ScheduledTransaction{}, errUnsignedScheduledTxns
+			}
+		}
+		if err := txn.StandaloneValid(validHeight); err != nil {
+			return modules.ScheduledTransaction{}, err
+		}
+	}
+
+	st := modules.ScheduledTransaction{
+		ID:           txns[len(txns)-1].ID(),
+		Transactions: txns,
+		Height:       height,
+		Timestamp:    timestamp,
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, exists := w.scheduledTxns[st.ID]; exists {
+		return modules.ScheduledTransaction{}, errScheduledTxnExists
+	}
+	if err := dbPutScheduledTxn(w.dbTx, st); err != nil {
+		return modules.ScheduledTransaction{}, err
+	}
+	w.scheduledTxns[st.ID] = st
+	w.syncDB()
+	return st, nil
+}
+
+// ScheduledTransactions returns the transaction sets that are waiting to be
+// broadcast, sorted by their scheduled height and timestamp.
+func (w *Wallet) ScheduledTransactions() []modules.ScheduledTransaction {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	sts := make([]modules.ScheduledTransaction, 0, len(w.scheduledTxns))
+	for _, st := range w.scheduledTxns {
+		sts = append(sts, st)
+	}
+	sort.Slice(sts, func(i, j int) bool {
+		if sts[i].Height != sts[j].Height {
+			return sts[i].Height < sts[j].Height
+		}
+		return sts[i].Timestamp < sts[j].Timestamp
+	})
+	return sts
+}
+
+// CancelScheduledTransaction removes a transaction set that has not been
+// broadcast yet, releasing the outputs that it spends.
+func (w *Wallet) CancelScheduledTransaction(id types.TransactionID) error {
+	if err := w.tg.Add(); err != nil {
+		return err
+	}
+	defer w.tg.Done()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, exists := w.scheduledTxns[id]; !exists {
+		return errUnknownScheduledTxn
+	}
+	if err := dbDeleteScheduledTxn(w.dbTx, id); err != nil {
+		return err
+	}
+	delete(w.scheduledTxns, id)
+	w.syncDB()
+	return nil
+}
+
+// threadedBroadcastScheduled broadcasts the scheduled transaction sets that
+// are due at the given height and block timestamp. Sets that are accepted by
+// the transaction pool are removed from the schedule, and the outputs they
+// spend are marked as spent. Sets that are rejected are retried at the next
+// block, until they are cancelled.
+func (w *Wallet) threadedBroadcastScheduled(height types.BlockHeight, timestamp types.Timestamp) {
+	if err := w.tg.Add(); err != nil {
+		return
+	}
+	defer w.tg.Done()
+
+	w.mu.RLock()
+	var due []modules.ScheduledTransaction
+	for _, st := range w.scheduledTxns {
+		if st.Height <= height && st.Timestamp <= timestamp {
+			due = append(due, st)
+		}
+	}
+	w.mu.RUnlock()
+
+	for _, st := range due {
+		err := w.tpool.AcceptTransactionSet(st.Transactions)
+		if err == modules.ErrDuplicateTransactionSet {
+			err = nil
+		}
+
+		w.mu.Lock()
+		if _, exists := w.scheduledTxns[st.ID]; !exists {
+			// The set was cancelled or broadcast in the meantime.
+			w.mu.Unlock()
+			continue
+		}
+		if err != nil {
+			w.log.Println("WARN: scheduled transaction set", st.ID, "was rejected:", err)
+			st.LastError = err.Error()
+			if err := dbPutScheduledTxn(w.dbTx, st); err != nil {
+				w.log.Println("ERROR: couldn't update scheduled transaction set:", err)
+			}
+			w.scheduledTxns[st.ID] = st
+		} else {
+			w.log.Println("Broadcast scheduled transaction set", st.ID)
+			for _, txn := range st.Transactions {
+				for _, sci := range txn.SiacoinInputs {
+					if w.isWalletAddress(sci.UnlockConditions.UnlockHash()) {
+						w.markOutputSpent(types.OutputID(sci.ParentID), height)
+					}
+				}
+				for _, sfi := range txn.SiafundInputs {
+					if w.isWalletAddress(sfi.UnlockConditions.UnlockHash()) {
+						w.markOutputSpent(types.OutputID(sfi.ParentID), height)
+					}
+				}
+			}
+			if err := dbDeleteScheduledTxn(w.dbTx, st.ID); err != nil {
+				w.log.Println("ERROR: couldn't remove scheduled transaction set:", err)
+			}
+			delete(w.scheduledTxns, st.ID)
+		}
+		w.syncDB()
+		w.mu.Unlock()
+	}
+}
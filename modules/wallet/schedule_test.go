@@ -0,0 +1,173 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules/miner"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// signedTransactionSet returns a signed transaction set that sends amount to
+// the void, without submitting it.
+func (wt *walletTester) signedTransactionSet(amount types.Currency) ([]types.Transaction, error) {
+	tb := wt.wallet.StartTransaction()
+	if err := tb.FundSiacoins(amount); err != nil {
+		return nil, err
+	}
+	tb.AddSiacoinOutput(types.SiacoinOutput{Value: amount, UnlockHash: types.UnlockHash{}})
+	return tb.Sign(true)
+}
+
+// TestScheduleTransaction schedules a transaction set, and checks that the
+// schedule persists and that the set is broadcast at the scheduled height.
+func TestScheduleTransaction(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	txns, err := wt.signedTransactionSet(types.SiacoinPrecision.Mul64(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	height := wt.cs.Height() + 2
+	if _, err := wt.wallet.ScheduleTransaction(nil, height, 0); err != errNoScheduledTxns {
+		t.Fatal("expected errNoScheduledTxns, got", err)
+	}
+	if _, err := wt.wallet.ScheduleTransaction(txns, 0, 0); err != errEmptySchedule {
+		t.Fatal("expected errEmptySchedule, got", err)
+	}
+	st, err := wt.wallet.ScheduleTransaction(txns, height, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.wallet.ScheduleTransaction(txns, height, 0); err != errScheduledTxnExists {
+		t.Fatal("expected errScheduledTxnExists, got", err)
+	}
+	if !wt.wallet.spentBySchedule(types.OutputID(txns[0].SiacoinInputs[0].ParentID)) {
+		t.Fatal("input of the scheduled transaction is not reserved")
+	}
+
+	// A cancelled schedule releases its inputs.
+	if err := wt.wallet.CancelScheduledTransaction(st.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.CancelScheduledTransaction(st.ID); err != errUnknownScheduledTxn {
+		t.Fatal("expected errUnknownScheduledTxn, got", err)
+	}
+	if wt.wallet.spentBySchedule(types.OutputID(txns[0].SiacoinInputs[0].ParentID)) {
+		t.Fatal("input of the cancelled transaction is still reserved")
+	}
+	if _, err := wt.wallet.ScheduleTransaction(txns, height, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// The schedule should persist.
+	if err := wt.wallet.Close(); err != nil {
+		t.Fatal(err)
+	}
+	w, err := New(wt.cs, wt.tpool, wt.wallet.persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt.wallet = w
+	if err := w.Unlock(wt.walletMasterKey); err != nil {
+		t.Fatal(err)
+	}
+	wt.miner, err = miner.New(wt.cs, wt.tpool, wt.wallet, wt.wallet.persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sts := w.ScheduledTransactions(); len(sts) != 1 || sts[0].ID != st.ID {
+		t.Fatal("schedule was not persisted:", sts)
+	}
+
+	// The set should be broadcast once the height is reached, which the
+	// wallet only checks while the consensus set is synced.
+	if err := wt.waitForSync(); err != nil {
+		t.Fatal(err)
+	}
+	inPool := func() bool {
+		for _, txn := range wt.tpool.TransactionList() {
+			if txn.ID() == st.ID {
+				return true
+			}
+		}
+		return false
+	}
+	for wt.cs.Height() < height-1 {
+		if err := wt.addBlockNoPayout(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if inPool() {
+		t.Fatal("transaction was broadcast before the scheduled height")
+	}
+	if err := wt.addBlockNoPayout(); err != nil {
+		t.Fatal(err)
+	}
+	err = build.Retry(50, 100*time.Millisecond, func() error {
+		if !inPool() || len(w.ScheduledTransactions()) != 0 {
+			return errors.New("scheduled transaction was not broadcast")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestBroadcastScheduledOnStartup checks that a scheduled transaction set that
+// is already due is broadcast when the wallet starts, without waiting for the
+// next block.
+func TestBroadcastScheduledOnStartup(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+	if err := wt.waitForSync(); err != nil {
+		t.Fatal(err)
+	}
+
+	txns, err := wt.signedTransactionSet(types.SiacoinPrecision.Mul64(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := wt.wallet.ScheduleTransaction(txns, wt.cs.Height(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.Close(); err != nil {
+		t.Fatal(err)
+	}
+	w, err := New(wt.cs, wt.tpool, wt.wallet.persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt.wallet = w
+	if err := w.Unlock(wt.walletMasterKey); err != nil {
+		t.Fatal(err)
+	}
+	err = build.Retry(50, 100*time.Millisecond, func() error {
+		for _, txn := range wt.tpool.TransactionList() {
+			if txn.ID() == st.ID && len(w.ScheduledTransactions()) == 0 {
+				return nil
+			}
+		}
+		return errors.New("due transaction was not broadcast on startup")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -230,6 +230,7 @@ func (w *Wallet) LoadSeed(masterKey crypto.TwofishKey, seed modules.Seed) error
 
 	// scan blockchain to determine how many keys to generate for the seed
 	s := newSeedScanner(seed, w.log)
+	s.gapLimit = w.ScanGapLimit()
 	if err := s.scan(w.cs); err != nil {
 		return err
 	}
@@ -306,7 +307,8 @@ func (w *Wallet) LoadSeed(masterKey crypto.TwofishKey, seed modules.Seed) error
 // SweepSeed scans the blockchain for outputs generated from seed and creates
 // a transaction that transfers them to the wallet. Note that this incurs a
 // transaction fee. It returns the total value of the outputs, minus the fee.
-// If only siafunds were found, the fee is deducted from the wallet.
+// If only siafunds were found, the fee is deducted from the wallet. The
+// siacoin claims of swept siafunds are also paid to the wallet.
 func (w *Wallet) SweepSeed(seed modules.Seed) (coins, funds types.Currency, err error) {
 	if err = w.tg.Add(); err != nil {
 		return
@@ -332,6 +334,7 @@ func (w *Wallet) SweepSeed(seed modules.Seed) (coins, funds types.Currency, err
 	// get an address to spend into
 	w.mu.Lock()
 	uc, err := w.nextPrimarySeedAddress(w.dbTx)
+	w.syncDB() // ensure durability of the address receiving the swept outputs
 	w.mu.Unlock()
 	if err != nil {
 		return
@@ -340,6 +343,7 @@ func (w *Wallet) SweepSeed(seed modules.Seed) (coins, funds types.Currency, err
 	// scan blockchain for outputs, filtering out 'dust' (outputs that cost
 	// more in fees than they are worth)
 	s := newSeedScanner(seed, w.log)
+	s.gapLimit = w.ScanGapLimit()
 	_, maxFee := w.tpool.FeeEstimation()
 	const outputSize = 350 // approx. size in bytes of an output and accompanying signature
 	const maxOutputs = 50  // approx. number of outputs that a transaction can handle
@@ -397,6 +401,7 @@ func (w *Wallet) SweepSeed(seed modules.Seed) (coins, funds types.Currency, err
 			tb.AddSiafundInput(types.SiafundInput{
 				ParentID:         types.SiafundOutputID(output.id),
 				UnlockConditions: sk.UnlockConditions,
+				ClaimUnlockHash:  uc.UnlockHash(),
 			})
 			// add a signature for the input
 			sweptFunds = sweptFunds.Add(output.value)
@@ -499,3 +504,35 @@ func (w *Wallet) SweepSeed(seed modules.Seed) (coins, funds types.Currency, err
 	}
 	return
 }
+
+// ScanGapLimit returns the number of unused keys that are generated beyond
+// the largest key index found when scanning the blockchain for the addresses
+// of a seed.
+func (w *Wallet) ScanGapLimit() uint64 {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.scanGapLimit
+}
+
+// SetScanGapLimit sets the number of unused keys that are generated beyond
+// the largest key index found when scanning the blockchain for the addresses
+// of a seed. A larger gap limit finds addresses that were handed out far
+// beyond the last used address, at the cost of generating more keys.
+func (w *Wallet) SetScanGapLimit(limit uint64) error {
+	if err := w.tg.Add(); err != nil {
+		return err
+	}
+	defer w.tg.Done()
+	if limit == 0 || limit > maxScanKeys/2 {
+		return errBadGapLimit
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := dbPutScanGapLimit(w.dbTx, limit); err != nil {
+		return err
+	}
+	w.scanGapLimit = limit
+	w.syncDB()
+	return nil
+}
@@ -292,6 +292,14 @@ func TestSweepSeedFunds(t *testing.T) {
 	if funds.Cmp(types.NewCurrency64(12)) != 0 {
 		t.Errorf("expected to sweep %v funds, got %v", 12, funds)
 	}
+	// The claim of the swept siafunds should be paid to the wallet.
+	for _, txn := range wt.tpool.TransactionList() {
+		for _, sfi := range txn.SiafundInputs {
+			if !wt.wallet.isWalletAddress(sfi.ClaimUnlockHash) {
+				t.Error("siafund claim is not paid to the wallet:", sfi.ClaimUnlockHash)
+			}
+		}
+	}
 	// add a block without earning its payout
 	wt.addBlockNoPayout()
 
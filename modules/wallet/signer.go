@@ -0,0 +1,193 @@
+package wallet
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+const (
+	// deviceMaxResponseLen is the maximum size of a response sent by a
+	// signing device.
+	deviceMaxResponseLen = 1 << 16
+)
+
+var (
+	// deviceRequestPublicKeys asks a signing device for its public keys.
+	deviceRequestPublicKeys = types.Specifier{'P', 'u', 'b', 'l', 'i', 'c', 'K', 'e', 'y', 's'}
+
+	// deviceRequestSign asks a signing device to sign a hash.
+	deviceRequestSign = types.Specifier{'S', 'i', 'g', 'n', 'H', 'a', 's', 'h'}
+
+	errBadSignerSignature   = errors.New("external signer returned an invalid signature")
+	errNoSignerKeys         = errors.New("external signer does not hold any public keys")
+	errUnsupportedSignerKey = errors.New("external signer holds a public key that is not ed25519")
+)
+
+type (
+	// signerKey is a public key held by an external signer.
+	signerKey struct {
+		spk    types.SiaPublicKey
+		signer modules.Signer
+	}
+
+	// deviceRequest is sent to a signing device. PublicKey, Hash,
+	// Transaction and SigIndex are only set when asking for a signature.
+	deviceRequest struct {
+		Type        types.Specifier
+		PublicKey   types.SiaPublicKey
+		Hash        crypto.Hash
+		Transaction types.Transaction
+		SigIndex    uint64
+	}
+
+	// deviceResponse is returned by a signing device. Error is set if the
+	// device could not fulfil the request, for example because the user
+	// rejected the transaction.
+	deviceResponse struct {
+		Error      string
+		PublicKeys []types.SiaPublicKey
+		Signature  []byte
+	}
+
+	// A DeviceSigner is a modules.Signer that forwards requests to an
+	// external device, such as a hardware wallet, over a serial or HID
+	// transport. Each request and response is a length-prefixed object in
+	// the Sia encoding. The device is sent the transaction with the hash, so
+	// that it can display the transaction and ask for confirmation.
+	DeviceSigner struct {
+		mu sync.Mutex
+		rw io.ReadWriteCloser
+	}
+)
+
+// verifySignerSignature returns an error if sig is not a valid signature of
+// hash by spk.
+func verifySignerSignature(spk types.SiaPublicKey, hash crypto.Hash, sig []byte) error {
+	if spk.Algorithm != types.SignatureEd25519 || len(spk.Key) != crypto.PublicKeySize || len(sig) != crypto.SignatureSize {
+		return errBadSignerSignature
+	}
+	var pk crypto.PublicKey
+	var cryptoSig crypto.Signature
+	copy(pk[:], spk.Key)
+	copy(cryptoSig[:], sig)
+	if crypto.VerifyHash(hash, pk, cryptoSig) != nil {
+		return errBadSignerSignature
+	}
+	return nil
+}
+
+// AddSigner registers an external signer with the wallet. SignTransaction
+// asks the signer for the signatures of its public keys that the wallet
+// cannot produce itself. Signers are not persisted, and must be added again
+// after the wallet is restarted.
+func (w *Wallet) AddSigner(s modules.Signer) error {
+	if err := w.tg.Add(); err != nil {
+		return err
+	}
+	defer w.tg.Done()
+
+	spks, err := s.PublicKeys()
+	if err != nil {
+		return err
+	} else if len(spks) == 0 {
+		return errNoSignerKeys
+	}
+	for _, spk := range spks {
+		if spk.Algorithm != types.SignatureEd25519 {
+			return errUnsupportedSignerKey
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, spk := range spks {
+		w.signers[spk.String()] = signerKey{spk: spk, signer: s}
+	}
+	return nil
+}
+
+// SignerPublicKeys returns the public keys of the external signers of the
+// wallet.
+func (w *Wallet) SignerPublicKeys() []types.SiaPublicKey {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	spks := make([]types.SiaPublicKey, 0, len(w.signers))
+	for _, sk := range w.signers {
+		spks = append(spks, sk.spk)
+	}
+	sort.Slice(spks, func(i, j int) bool {
+		return spks[i].String() < spks[j].String()
+	})
+	return spks
+}
+
+// NewDeviceSigner returns a DeviceSigner that communicates with a device over
+// rw.
+func NewDeviceSigner(rw io.ReadWriteCloser) *DeviceSigner {
+	return &DeviceSigner{rw: rw}
+}
+
+// OpenDeviceSigner opens the device file at path, such as a serial port or a
+// hidraw device, and returns a DeviceSigner that communicates over it.
+func OpenDeviceSigner(path string) (*DeviceSigner, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	return NewDeviceSigner(f), nil
+}
+
+// call sends a request to the device and returns its response.
+func (ds *DeviceSigner) call(req deviceRequest) (deviceResponse, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	var resp deviceResponse
+	if err := encoding.WriteObject(ds.rw, req); err != nil {
+		return resp, err
+	}
+	if err := encoding.ReadObject(ds.rw, &resp, deviceMaxResponseLen); err != nil {
+		return resp, err
+	}
+	if resp.Error != "" {
+		return resp, errors.New("device: " + resp.Error)
+	}
+	return resp, nil
+}
+
+// PublicKeys returns the public keys held by the device.
+func (ds *DeviceSigner) PublicKeys() ([]types.SiaPublicKey, error) {
+	resp, err := ds.call(deviceRequest{Type: deviceRequestPublicKeys})
+	if err != nil {
+		return nil, err
+	}
+	return resp.PublicKeys, nil
+}
+
+// SignHash asks the device to sign hash with the secret key of spk. The
+// device is expected to display txn and wait for the user to confirm it.
+func (ds *DeviceSigner) SignHash(spk types.SiaPublicKey, hash crypto.Hash, txn types.Transaction, sigIndex int) ([]byte, error) {
+	resp, err := ds.call(deviceRequest{
+		Type:        deviceRequestSign,
+		PublicKey:   spk,
+		Hash:        hash,
+		Transaction: txn,
+		SigIndex:    uint64(sigIndex),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Signature, nil
+}
+
+// Close closes the connection to the device.
+func (ds *DeviceSigner) Close() error {
+	return ds.rw.Close()
+}
@@ -0,0 +1,127 @@
+package wallet
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// serveTestDevice answers the requests of a DeviceSigner on conn, signing
+// with sk. Signature requests are rejected while reject is true.
+func serveTestDevice(conn net.Conn, sk crypto.SecretKey, pk crypto.PublicKey, reject *bool) {
+	defer conn.Close()
+	spk := types.Ed25519PublicKey(pk)
+	for {
+		var req deviceRequest
+		if err := encoding.ReadObject(conn, &req, 1<<20); err != nil {
+			return
+		}
+		var resp deviceResponse
+		switch {
+		case req.Type == deviceRequestPublicKeys:
+			resp.PublicKeys = []types.SiaPublicKey{spk}
+		case req.Type == deviceRequestSign && *reject:
+			resp.Error = "rejected by user"
+		case req.Type == deviceRequestSign && req.Transaction.SigHash(int(req.SigIndex)) != req.Hash:
+			resp.Error = "hash does not match transaction"
+		case req.Type == deviceRequestSign:
+			sig := crypto.SignHash(req.Hash, sk)
+			resp.Signature = sig[:]
+		default:
+			resp.Error = "unknown request"
+		}
+		if err := encoding.WriteObject(conn, resp); err != nil {
+			return
+		}
+	}
+}
+
+// TestDeviceSigner spends the outputs of a watch-only address whose key is
+// held by an external device.
+func TestDeviceSigner(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// Connect a device and watch the address of its key.
+	sk, pk := crypto.GenerateKeyPair()
+	reject := true
+	client, device := net.Pipe()
+	go serveTestDevice(device, sk, pk, &reject)
+	ds := NewDeviceSigner(client)
+	defer ds.Close()
+	if err := wt.wallet.AddSigner(ds); err != nil {
+		t.Fatal(err)
+	}
+	spk := types.Ed25519PublicKey(pk)
+	spks := wt.wallet.SignerPublicKeys()
+	if len(spks) != 1 || spks[0].String() != spk.String() {
+		t.Fatal("wrong signer public keys:", spks)
+	}
+	addrs, err := wt.wallet.AddWatchPublicKeys(spks, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Fund the address and build a transaction spending from it.
+	amount := types.SiacoinPrecision.Mul64(100)
+	if _, err := wt.wallet.SendSiacoins(amount, addrs[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	txn, err := wt.wallet.BuildUnsignedTransaction([]types.SiacoinOutput{{
+		Value:      amount.Div64(2),
+		UnlockHash: types.UnlockHash{},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A rejected request leaves the transaction unchanged.
+	unsigned := txn
+	unsigned.TransactionSignatures = append([]types.TransactionSignature(nil), txn.TransactionSignatures...)
+	if err := wt.wallet.SignTransaction(&txn, nil); err == nil {
+		t.Fatal("expected an error when the device rejects the transaction")
+	}
+	if !reflect.DeepEqual(txn, unsigned) {
+		t.Fatal("transaction was changed by a failed signing")
+	}
+
+	// Once confirmed, the device signs the transaction.
+	reject = false
+	if err := wt.wallet.SignTransaction(&txn, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.BroadcastSigned(txn); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestVerifySignerSignature checks that invalid signatures from external
+// signers are rejected.
+func TestVerifySignerSignature(t *testing.T) {
+	sk, pk := crypto.GenerateKeyPair()
+	spk := types.Ed25519PublicKey(pk)
+	hash := crypto.HashObject("foo")
+	sig := crypto.SignHash(hash, sk)
+	if err := verifySignerSignature(spk, hash, sig[:]); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifySignerSignature(spk, crypto.HashObject("bar"), sig[:]); err != errBadSignerSignature {
+		t.Fatal("expected errBadSignerSignature for a different hash, got", err)
+	}
+	if err := verifySignerSignature(spk, hash, sig[:10]); err != errBadSignerSignature {
+		t.Fatal("expected errBadSignerSignature for a short signature, got", err)
+	}
+}
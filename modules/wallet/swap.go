@@ -0,0 +1,384 @@
+package wallet
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/NebulousLabs/bolt"
+	"github.com/NebulousLabs/fastrand"
+)
+
+// An atomic swap is built from a file contract whose file is the 32 byte
+// secret. The Merkle root of the secret, which is the BLAKE2b hash of a zero
+// byte followed by the secret, is the hashlock: a storage proof of the
+// contract must reveal the secret, and pays the valid proof output to the
+// redeemer. The end of the proof window is the timelock: if no proof is
+// submitted, the consensus set pays the missed proof output back to the
+// initiator, so refunds need no transaction. The contract has an empty unlock
+// hash, which cannot be satisfied, so it can never be revised.
+//
+// A swap with another blockchain is only atomic if that blockchain can lock
+// coins with the same hash function.
+
+const (
+	// swapSecretSize is the size of the secret of a swap, which is the file
+	// size of its contract.
+	swapSecretSize = crypto.HashSize
+)
+
+var (
+	errNotAtomicSwap     = errors.New("transaction does not contain a swap contract")
+	errSwapRedeemed      = errors.New("swap has already been redeemed")
+	errSwapTimelock      = errors.New("swap timelock must be after the redemption window opens")
+	errSwapWindowClosed  = errors.New("swap can no longer be redeemed")
+	errSwapWindowNotOpen = errors.New("swap cannot be redeemed before its redemption window opens")
+	errUnknownAtomicSwap = errors.New("no swap with that id is tracked by the wallet")
+	errWrongSwapSecret   = errors.New("secret does not match the secret hash of the swap")
+	errZeroSwapValue     = errors.New("swap value must be greater than zero")
+)
+
+// swapSecretHash returns the hashlock of a swap with the given secret.
+func swapSecretHash(secret crypto.Hash) crypto.Hash {
+	return crypto.MerkleRoot(secret[:])
+}
+
+// swapFromContract returns the swap described by a file contract, and false
+// if the contract is not a swap contract.
+func swapFromContract(id types.FileContractID, fc types.FileContract) (modules.AtomicSwap, bool) {
+	if fc.FileSize != swapSecretSize || fc.UnlockHash != (types.UnlockHash{}) || fc.RevisionNumber != 0 {
+		return modules.AtomicSwap{}, false
+	}
+	if len(fc.ValidProofOutputs) != 1 || len(fc.MissedProofOutputs) != 1 {
+		return modules.AtomicSwap{}, false
+	}
+	if fc.ValidProofOutputs[0].Value.Cmp(fc.MissedProofOutputs[0].Value) != 0 {
+		return modules.AtomicSwap{}, false
+	}
+	return modules.AtomicSwap{
+		ID:            id,
+		Value:         fc.ValidProofOutputs[0].Value,
+		SecretHash:    fc.FileMerkleRoot,
+		RedeemAddress: fc.ValidProofOutputs[0].UnlockHash,
+		RefundAddress: fc.MissedProofOutputs[0].UnlockHash,
+		WindowStart:   fc.WindowStart,
+		Timelock:      fc.WindowEnd,
+	}, true
+}
+
+// swapWithStatus returns swap with the status it has at the given height.
+func swapWithStatus(swap modules.AtomicSwap, height types.BlockHeight) modules.AtomicSwap {
+	switch {
+	case swap.Redeemed:
+		swap.Status = modules.AtomicSwapRedeemed
+	case swap.Confirmed && height >= swap.Timelock:
+		swap.Status = modules.AtomicSwapRefunded
+	case swap.Confirmed:
+		swap.Status = modules.AtomicSwapActive
+	case height >= swap.WindowStart:
+		swap.Status = modules.AtomicSwapExpired
+	default:
+		swap.Status = modules.AtomicSwapPending
+	}
+	return swap
+}
+
+// putAtomicSwap stores a swap tracked by the wallet.
+func (w *Wallet) putAtomicSwap(tx *bolt.Tx, swap modules.AtomicSwap) error {
+	swap.Status = ""
+	if err := dbPutAtomicSwap(tx, swap); err != nil {
+		return err
+	}
+	w.atomicSwaps[swap.ID] = swap
+	return nil
+}
+
+// trackAtomicSwap starts tracking swap. If the swap is already tracked, its
+// confirmation state and known secret are kept.
+func (w *Wallet) trackAtomicSwap(swap modules.AtomicSwap) error {
+	if old, exists := w.atomicSwaps[swap.ID]; exists {
+		swap.Confirmed = old.Confirmed
+		swap.ConfirmationHeight = old.ConfirmationHeight
+		swap.Redeemed = old.Redeemed
+		if swap.Secret == (crypto.Hash{}) {
+			swap.Secret = old.Secret
+		}
+	}
+	return w.putAtomicSwap(w.dbTx, swap)
+}
+
+// updateAtomicSwaps records the confirmation and redemption of swap contracts
+// in the reverted and applied blocks of cc. Swap contracts that pay to or
+// refund to the wallet are tracked as soon as they are confirmed. A secret
+// revealed by a redemption stays known even if the redemption is reverted.
+// updateAtomicSwaps must be called before the consensus height is updated.
+func (w *Wallet) updateAtomicSwaps(tx *bolt.Tx, cc modules.ConsensusChange) error {
+	height, err := dbGetConsensusHeight(tx)
+	if err != nil {
+		return err
+	}
+	for _, block := range cc.RevertedBlocks {
+		for _, txn := range block.Transactions {
+			for i := range txn.FileContracts {
+				swap, exists := w.atomicSwaps[txn.FileContractID(uint64(i))]
+				if !exists {
+					continue
+				}
+				swap.Confirmed = false
+				swap.ConfirmationHeight = 0
+				if err := w.putAtomicSwap(tx, swap); err != nil {
+					return err
+				}
+			}
+			for _, sp := range txn.StorageProofs {
+				swap, exists := w.atomicSwaps[sp.ParentID]
+				if !exists {
+					continue
+				}
+				swap.Redeemed = false
+				if err := w.putAtomicSwap(tx, swap); err != nil {
+					return err
+				}
+			}
+		}
+		if block.ID() != types.GenesisID {
+			height--
+		}
+	}
+	for _, block := range cc.AppliedBlocks {
+		if block.ID() != types.GenesisID {
+			height++
+		}
+		for _, txn := range block.Transactions {
+			for i, fc := range txn.FileContracts {
+				swap, ok := swapFromContract(txn.FileContractID(uint64(i)), fc)
+				if !ok {
+					continue
+				}
+				if old, exists := w.atomicSwaps[swap.ID]; exists {
+					swap.Secret = old.Secret
+				} else if !w.isWalletAddress(swap.RedeemAddress) && !w.isWalletAddress(swap.RefundAddress) {
+					continue
+				}
+				swap.Confirmed = true
+				swap.ConfirmationHeight = height
+				if err := w.putAtomicSwap(tx, swap); err != nil {
+					return err
+				}
+			}
+			for _, sp := range txn.StorageProofs {
+				swap, exists := w.atomicSwaps[sp.ParentID]
+				if !exists {
+					continue
+				}
+				copy(swap.Secret[:], sp.Segment[:swapSecretSize])
+				swap.Redeemed = true
+				if err := w.putAtomicSwap(tx, swap); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// CreateAtomicSwap funds and broadcasts a swap contract with a payout of
+// value. The contract pays value minus the siafund fee to redeemAddr if the
+// secret of secretHash is revealed before the timelock height, and refunds it
+// to a new address of the wallet otherwise. Its redemption window opens
+// swapWindowDelay blocks from now. If secretHash is empty, the wallet
+// generates a secret, which is stored with the swap. The swap is tracked by
+// the wallet, and the transactions are returned so that the contract can be
+// sent to the counterparty for auditing.
+func (w *Wallet) CreateAtomicSwap(value types.Currency, redeemAddr types.UnlockHash, secretHash crypto.Hash, timelock types.BlockHeight) (modules.AtomicSwap, []types.Transaction, error) {
+	if err := w.tg.Add(); err != nil {
+		return modules.AtomicSwap{}, nil, err
+	}
+	defer w.tg.Done()
+	if !w.Unlocked() {
+		return modules.AtomicSwap{}, nil, modules.ErrLockedWallet
+	}
+
+	height := w.cs.Height()
+	windowStart := height + swapWindowDelay
+	if value.IsZero() {
+		return modules.AtomicSwap{}, nil, errZeroSwapValue
+	} else if timelock <= windowStart {
+		return modules.AtomicSwap{}, nil, errSwapTimelock
+	}
+	var secret crypto.Hash
+	if secretHash == (crypto.Hash{}) {
+		fastrand.Read(secret[:])
+		secretHash = swapSecretHash(secret)
+	}
+	refundUC, err := w.NextAddress()
+	if err != nil {
+		return modules.AtomicSwap{}, nil, err
+	}
+
+	fc := types.FileContract{
+		FileSize:       swapSecretSize,
+		FileMerkleRoot: secretHash,
+		WindowStart:    windowStart,
+		WindowEnd:      timelock,
+		Payout:         value,
+		ValidProofOutputs: []types.SiacoinOutput{{
+			Value:      types.PostTax(height, value),
+			UnlockHash: redeemAddr,
+		}},
+		MissedProofOutputs: []types.SiacoinOutput{{
+			Value:      types.PostTax(height, value),
+			UnlockHash: refundUC.UnlockHash(),
+		}},
+	}
+	tpoolFee := w.estimateFee(defaultFeeTargetBlocks)
+	tpoolFee = tpoolFee.Mul64(1000) // Estimated transaction size in bytes
+	txnBuilder := w.StartTransaction()
+	if err := txnBuilder.FundSiacoins(value.Add(tpoolFee)); err != nil {
+		return modules.AtomicSwap{}, nil, build.ExtendErr("unable to fund transaction", err)
+	}
+	txnBuilder.AddMinerFee(tpoolFee)
+	index := txnBuilder.AddFileContract(fc)
+	txnSet, err := txnBuilder.Sign(true)
+	if err != nil {
+		txnBuilder.Drop()
+		return modules.AtomicSwap{}, nil, build.ExtendErr("unable to sign transaction", err)
+	}
+	if err := w.tpool.AcceptTransactionSet(txnSet); err != nil {
+		txnBuilder.Drop()
+		return modules.AtomicSwap{}, nil, build.ExtendErr("unable to get transaction accepted", err)
+	}
+
+	swap, _ := swapFromContract(txnSet[len(txnSet)-1].FileContractID(index), fc)
+	swap.Secret = secret
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.trackAtomicSwap(swap); err != nil {
+		return modules.AtomicSwap{}, nil, err
+	}
+	w.syncDB()
+	w.log.Println("Submitted swap contract", swap.ID, "for value", value.HumanString())
+	return swapWithStatus(w.atomicSwaps[swap.ID], height), txnSet, nil
+}
+
+// AuditAtomicSwap returns the terms of the first swap contract in txn, such
+// as a contract that was created by the counterparty of a swap. If the
+// contract pays to or refunds to the wallet, the wallet tracks the swap, and
+// its status shows whether the contract has been confirmed.
+func (w *Wallet) AuditAtomicSwap(txn types.Transaction) (modules.AtomicSwap, error) {
+	if err := w.tg.Add(); err != nil {
+		return modules.AtomicSwap{}, err
+	}
+	defer w.tg.Done()
+
+	for i, fc := range txn.FileContracts {
+		swap, ok := swapFromContract(txn.FileContractID(uint64(i)), fc)
+		if !ok {
+			continue
+		}
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if w.isWalletAddress(swap.RedeemAddress) || w.isWalletAddress(swap.RefundAddress) {
+			if err := w.trackAtomicSwap(swap); err != nil {
+				return modules.AtomicSwap{}, err
+			}
+			w.syncDB()
+			swap = w.atomicSwaps[swap.ID]
+		}
+		height, err := dbGetConsensusHeight(w.dbTx)
+		if err != nil {
+			return modules.AtomicSwap{}, err
+		}
+		return swapWithStatus(swap, height), nil
+	}
+	return modules.AtomicSwap{}, errNotAtomicSwap
+}
+
+// RedeemAtomicSwap submits a storage proof that reveals the secret of a
+// tracked swap, paying the swap to its redeem address. The wallet pays the
+// miner fee of the proof.
+func (w *Wallet) RedeemAtomicSwap(id types.FileContractID, secret crypto.Hash) ([]types.Transaction, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer w.tg.Done()
+	if !w.Unlocked() {
+		return nil, modules.ErrLockedWallet
+	}
+
+	w.mu.RLock()
+	swap, exists := w.atomicSwaps[id]
+	w.mu.RUnlock()
+	height := w.cs.Height()
+	if !exists {
+		return nil, errUnknownAtomicSwap
+	} else if swapSecretHash(secret) != swap.SecretHash {
+		return nil, errWrongSwapSecret
+	} else if swap.Redeemed {
+		return nil, errSwapRedeemed
+	} else if height+1 < swap.WindowStart {
+		return nil, errSwapWindowNotOpen
+	} else if height+1 >= swap.Timelock {
+		return nil, errSwapWindowClosed
+	}
+
+	// The contract's file is a single segment, so the proof is just the
+	// secret.
+	sp := types.StorageProof{
+		ParentID: id,
+	}
+	copy(sp.Segment[:], secret[:])
+	tpoolFee := w.estimateFee(defaultFeeTargetBlocks)
+	tpoolFee = tpoolFee.Mul64(uint64(len(encoding.Marshal(sp)) + 300))
+	txnBuilder := w.StartTransaction()
+	if err := txnBuilder.FundSiacoins(tpoolFee); err != nil {
+		return nil, build.ExtendErr("unable to fund transaction", err)
+	}
+	txnBuilder.AddMinerFee(tpoolFee)
+	txnBuilder.AddStorageProof(sp)
+	txnSet, err := txnBuilder.Sign(true)
+	if err != nil {
+		txnBuilder.Drop()
+		return nil, build.ExtendErr("unable to sign transaction", err)
+	}
+	if err := w.tpool.AcceptTransactionSet(txnSet); err != nil {
+		txnBuilder.Drop()
+		return nil, build.ExtendErr("unable to get transaction accepted", err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	swap = w.atomicSwaps[id]
+	swap.Secret = secret
+	if err := w.putAtomicSwap(w.dbTx, swap); err != nil {
+		return nil, err
+	}
+	w.syncDB()
+	w.log.Println("Submitted redemption of swap", id)
+	return txnSet, nil
+}
+
+// AtomicSwaps returns the swaps tracked by the wallet, sorted by their
+// timelock.
+func (w *Wallet) AtomicSwaps() []modules.AtomicSwap {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	height, _ := dbGetConsensusHeight(w.dbTx)
+	swaps := make([]modules.AtomicSwap, 0, len(w.atomicSwaps))
+	for _, swap := range w.atomicSwaps {
+		swaps = append(swaps, swapWithStatus(swap, height))
+	}
+	sort.Slice(swaps, func(i, j int) bool {
+		if swaps[i].Timelock != swaps[j].Timelock {
+			return swaps[i].Timelock < swaps[j].Timelock
+		}
+		return swaps[i].ID.String() < swaps[j].ID.String()
+	})
+	return swaps
+}
@@ -0,0 +1,172 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/miner"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/NebulousLabs/fastrand"
+)
+
+// swapStatus returns the status of a swap tracked by the wallet.
+func (wt *walletTester) swapStatus(id types.FileContractID) (modules.AtomicSwap, bool) {
+	for _, swap := range wt.wallet.AtomicSwaps() {
+		if swap.ID == id {
+			return swap, true
+		}
+	}
+	return modules.AtomicSwap{}, false
+}
+
+// TestAtomicSwap creates, audits and redeems swaps, and checks that a swap
+// which is not redeemed is refunded at its timelock.
+func TestAtomicSwap(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+	// Storage proofs of partial segments are only valid after the hardfork
+	// at height 10 of the testing release.
+	for wt.cs.Height() < 10 {
+		if err := wt.addBlockNoPayout(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	uc, err := wt.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	value := types.SiacoinPrecision.Mul64(100)
+	timelock := wt.cs.Height() + swapWindowDelay + 10
+	if _, _, err := wt.wallet.CreateAtomicSwap(types.ZeroCurrency, uc.UnlockHash(), crypto.Hash{}, timelock); err != errZeroSwapValue {
+		t.Fatal("expected errZeroSwapValue, got", err)
+	}
+	if _, _, err := wt.wallet.CreateAtomicSwap(value, uc.UnlockHash(), crypto.Hash{}, wt.cs.Height()+swapWindowDelay); err != errSwapTimelock {
+		t.Fatal("expected errSwapTimelock, got", err)
+	}
+
+	// Initiate a swap, letting the wallet generate the secret.
+	swap, txns, err := wt.wallet.CreateAtomicSwap(value, uc.UnlockHash(), crypto.Hash{}, timelock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if swap.Status != modules.AtomicSwapPending || swapSecretHash(swap.Secret) != swap.SecretHash {
+		t.Fatal("wrong swap:", swap)
+	}
+	if swap.Value.Cmp(types.PostTax(wt.cs.Height(), value)) != 0 || swap.RedeemAddress != uc.UnlockHash() {
+		t.Fatal("wrong swap terms:", swap)
+	}
+	audit, err := wt.wallet.AuditAtomicSwap(txns[len(txns)-1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if audit.ID != swap.ID || audit.SecretHash != swap.SecretHash || audit.Timelock != timelock {
+		t.Fatal("audit does not match the swap:", audit, swap)
+	}
+	if _, err := wt.wallet.AuditAtomicSwap(types.Transaction{}); err != errNotAtomicSwap {
+		t.Fatal("expected errNotAtomicSwap, got", err)
+	}
+	if _, err := wt.wallet.RedeemAtomicSwap(swap.ID, swap.Secret); err != errSwapWindowNotOpen {
+		t.Fatal("expected errSwapWindowNotOpen, got", err)
+	}
+
+	// Participate in a swap whose secret is only known by its hash.
+	var secret crypto.Hash
+	fastrand.Read(secret[:])
+	swap2, _, err := wt.wallet.CreateAtomicSwap(value, uc.UnlockHash(), swapSecretHash(secret), timelock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if swap2.Secret != (crypto.Hash{}) {
+		t.Fatal("swap should not know its secret")
+	}
+
+	// A swap that is not redeemed is refunded.
+	swap3, _, err := wt.wallet.CreateAtomicSwap(value, uc.UnlockHash(), crypto.Hash{}, wt.cs.Height()+swapWindowDelay+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := wt.addBlockNoPayout(); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := wt.swapStatus(swap.ID); s.Status != modules.AtomicSwapActive || !s.Confirmed {
+		t.Fatal("swap should be active:", s)
+	}
+	if _, err := wt.wallet.RedeemAtomicSwap(swap.ID, secret); err != errWrongSwapSecret {
+		t.Fatal("expected errWrongSwapSecret, got", err)
+	}
+	if _, err := wt.wallet.RedeemAtomicSwap(types.FileContractID{}, secret); err != errUnknownAtomicSwap {
+		t.Fatal("expected errUnknownAtomicSwap, got", err)
+	}
+	for wt.cs.Height()+1 < swap.WindowStart {
+		if err := wt.addBlockNoPayout(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := wt.wallet.RedeemAtomicSwap(swap.ID, swap.Secret); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.wallet.RedeemAtomicSwap(swap2.ID, secret); err != nil {
+		t.Fatal(err)
+	}
+	// Forget the secret of the second swap, which should be recovered from
+	// its redemption.
+	wt.wallet.mu.Lock()
+	s2 := wt.wallet.atomicSwaps[swap2.ID]
+	s2.Secret = crypto.Hash{}
+	wt.wallet.atomicSwaps[swap2.ID] = s2
+	wt.wallet.mu.Unlock()
+	if err := wt.addBlockNoPayout(); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := wt.swapStatus(swap.ID); s.Status != modules.AtomicSwapRedeemed {
+		t.Fatal("swap should be redeemed:", s)
+	}
+	if s, _ := wt.swapStatus(swap2.ID); s.Status != modules.AtomicSwapRedeemed || s.Secret != secret {
+		t.Fatal("secret was not recovered from the redemption:", s)
+	}
+	if _, err := wt.wallet.RedeemAtomicSwap(swap.ID, swap.Secret); err != errSwapRedeemed {
+		t.Fatal("expected errSwapRedeemed, got", err)
+	}
+	if s, _ := wt.swapStatus(swap3.ID); s.Status != modules.AtomicSwapActive {
+		t.Fatal("swap should be active until its timelock:", s)
+	}
+	if err := wt.addBlockNoPayout(); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := wt.swapStatus(swap3.ID); s.Status != modules.AtomicSwapRefunded {
+		t.Fatal("swap should be refunded:", s)
+	}
+
+	// The swaps should persist.
+	if err := wt.wallet.Close(); err != nil {
+		t.Fatal(err)
+	}
+	w, err := New(wt.cs, wt.tpool, wt.wallet.persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt.wallet = w
+	if err := w.Unlock(wt.walletMasterKey); err != nil {
+		t.Fatal(err)
+	}
+	wt.miner, err = miner.New(wt.cs, wt.tpool, wt.wallet, wt.wallet.persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if swaps := w.AtomicSwaps(); len(swaps) != 3 {
+		t.Fatal("swaps were not persisted:", swaps)
+	}
+	if s, _ := wt.swapStatus(swap.ID); s.Secret != swap.Secret {
+		t.Fatal("secret was not persisted:", s)
+	}
+}
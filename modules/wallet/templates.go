@@ -0,0 +1,94 @@
+package wallet
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	errTemplateName    = errors.New("multisig templates must have a name")
+	errUnknownTemplate = errors.New("wallet does not have a multisig template with that name")
+)
+
+// UnlockConditionsTemplates returns the multisig templates of the wallet,
+// sorted by name.
+func (w *Wallet) UnlockConditionsTemplates() []modules.UnlockConditionsTemplate {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	templates := make([]modules.UnlockConditionsTemplate, 0, len(w.ucTemplates))
+	for _, t := range w.ucTemplates {
+		templates = append(templates, t)
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name < templates[j].Name
+	})
+	return templates
+}
+
+// SetUnlockConditionsTemplate stores a multisig template, replacing any
+// template of the same name. The parameters of the template are checked in the
+// same way as those of NewMultisigAddress.
+func (w *Wallet) SetUnlockConditionsTemplate(t modules.UnlockConditionsTemplate) error {
+	if err := w.tg.Add(); err != nil {
+		return err
+	}
+	defer w.tg.Done()
+	if t.Name == "" {
+		return errTemplateName
+	}
+	if err := checkMultisigParams(t.SignaturesRequired, t.Cosigners); err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := dbPutUCTemplate(w.dbTx, t); err != nil {
+		return err
+	}
+	w.ucTemplates[t.Name] = t
+	w.syncDB()
+	return nil
+}
+
+// RemoveUnlockConditionsTemplate removes the multisig template with the given
+// name. Addresses that were generated from the template are not affected.
+func (w *Wallet) RemoveUnlockConditionsTemplate(name string) error {
+	if err := w.tg.Add(); err != nil {
+		return err
+	}
+	defer w.tg.Done()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, exists := w.ucTemplates[name]; !exists {
+		return errUnknownTemplate
+	}
+	if err := dbDeleteUCTemplate(w.dbTx, name); err != nil {
+		return err
+	}
+	delete(w.ucTemplates, name)
+	w.syncDB()
+	return nil
+}
+
+// NewTemplateAddress returns the unlock conditions of a new multisig address
+// generated from the named template. Each call uses a new key from the
+// wallet's primary seed, so every address is different even though the
+// cosigners are the same.
+func (w *Wallet) NewTemplateAddress(name string) (types.UnlockConditions, error) {
+	if err := w.tg.Add(); err != nil {
+		return types.UnlockConditions{}, err
+	}
+	defer w.tg.Done()
+	w.mu.RLock()
+	t, exists := w.ucTemplates[name]
+	w.mu.RUnlock()
+	if !exists {
+		return types.UnlockConditions{}, errUnknownTemplate
+	}
+	return w.managedNewMultisigAddress(t.SignaturesRequired, t.Cosigners)
+}
@@ -0,0 +1,105 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/miner"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestUnlockConditionsTemplates stores a multisig template, generates
+// addresses from it, and checks that the template persists.
+func TestUnlockConditionsTemplates(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	var cosigners []types.SiaPublicKey
+	for i := 0; i < 2; i++ {
+		uc, err := wt.wallet.NextAddress()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cosigners = append(cosigners, uc.PublicKeys...)
+	}
+	template := modules.UnlockConditionsTemplate{
+		Name:               "treasury",
+		SignaturesRequired: 2,
+		Cosigners:          cosigners,
+	}
+
+	// Check the validation of templates.
+	if err := wt.wallet.SetUnlockConditionsTemplate(modules.UnlockConditionsTemplate{SignaturesRequired: 1, Cosigners: cosigners}); err != errTemplateName {
+		t.Fatal("expected errTemplateName, got", err)
+	}
+	bad := template
+	bad.SignaturesRequired = 4
+	if err := wt.wallet.SetUnlockConditionsTemplate(bad); err != errBadSignaturesRequired {
+		t.Fatal("expected errBadSignaturesRequired, got", err)
+	}
+	if _, err := wt.wallet.NewTemplateAddress(template.Name); err != errUnknownTemplate {
+		t.Fatal("expected errUnknownTemplate, got", err)
+	}
+
+	// Every address generated from the template is different, and requires
+	// the signatures of the template.
+	if err := wt.wallet.SetUnlockConditionsTemplate(template); err != nil {
+		t.Fatal(err)
+	}
+	uc1, err := wt.wallet.NewTemplateAddress(template.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uc2, err := wt.wallet.NewTemplateAddress(template.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uc1.UnlockHash() == uc2.UnlockHash() {
+		t.Fatal("template generated the same address twice")
+	}
+	for _, uc := range []types.UnlockConditions{uc1, uc2} {
+		if len(uc.PublicKeys) != 3 || uc.SignaturesRequired != 2 {
+			t.Fatal("wrong unlock conditions:", uc)
+		}
+		if uc.PublicKeys[1].String() != cosigners[0].String() || uc.PublicKeys[2].String() != cosigners[1].String() {
+			t.Fatal("unlock conditions do not hold the cosigners of the template:", uc)
+		}
+	}
+
+	// The template should persist.
+	if err := wt.wallet.Close(); err != nil {
+		t.Fatal(err)
+	}
+	w, err := New(wt.cs, wt.tpool, wt.wallet.persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt.wallet = w
+	if err := w.Unlock(wt.walletMasterKey); err != nil {
+		t.Fatal(err)
+	}
+	wt.miner, err = miner.New(wt.cs, wt.tpool, wt.wallet, wt.wallet.persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates := w.UnlockConditionsTemplates()
+	if len(templates) != 1 || templates[0].Name != template.Name || len(templates[0].Cosigners) != 2 {
+		t.Fatal("template was not persisted:", templates)
+	}
+
+	if err := w.RemoveUnlockConditionsTemplate(template.Name); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.RemoveUnlockConditionsTemplate(template.Name); err != errUnknownTemplate {
+		t.Fatal("expected errUnknownTemplate, got", err)
+	}
+	if len(w.UnlockConditionsTemplates()) != 0 {
+		t.Fatal("template was not removed")
+	}
+}
@@ -0,0 +1,124 @@
+package wallet
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	errTimelockInPast = errors.New("timelock must be above the current block height")
+)
+
+// integrateTimelockedKeys adds the keys of the wallet's timelocked addresses
+// to the set of spendable keys. The keys of the seeds must already have been
+// integrated.
+func (w *Wallet) integrateTimelockedKeys() error {
+	return dbForEachTimelockedAddr(w.dbTx, func(uh types.UnlockHash, uc types.UnlockConditions) {
+		if len(uc.PublicKeys) != 1 {
+			return
+		}
+		sk, exists := w.secretKeyFor(uc.PublicKeys[0])
+		if !exists {
+			return
+		}
+		w.keys[uh] = spendableKey{
+			UnlockConditions: uc,
+			SecretKeys:       []crypto.SecretKey{sk},
+		}
+	})
+}
+
+// NewTimelockedAddress returns unlock conditions for a new key from the
+// wallet's primary seed that cannot be spent before the timelock height.
+// Outputs sent to the address are tracked by the wallet and can be spent once
+// the timelock has passed. The unlock conditions are stored in the wallet's
+// database; they cannot be recovered from the seed alone, as the seed does not
+// determine the timelock.
+func (w *Wallet) NewTimelockedAddress(timelock types.BlockHeight) (types.UnlockConditions, error) {
+	if err := w.tg.Add(); err != nil {
+		return types.UnlockConditions{}, err
+	}
+	defer w.tg.Done()
+
+	if timelock <= w.cs.Height() {
+		return types.UnlockConditions{}, errTimelockInPast
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	uc, err := w.nextPrimarySeedAddress(w.dbTx)
+	if err != nil {
+		return types.UnlockConditions{}, err
+	}
+	key := w.keys[uc.UnlockHash()]
+	uc.Timelock = timelock
+	if err := dbPutTimelockedAddr(w.dbTx, uc.UnlockHash(), uc); err != nil {
+		return types.UnlockConditions{}, err
+	}
+	w.keys[uc.UnlockHash()] = spendableKey{
+		UnlockConditions: uc,
+		SecretKeys:       key.SecretKeys,
+	}
+	w.syncDB() // ensure durability of reported address
+	return uc, nil
+}
+
+// TimelockedBalances returns the balances of the wallet's confirmed outputs
+// whose unlock conditions have a timelock above the current height, grouped
+// by that height and sorted in ascending order. These outputs are included in
+// the confirmed balance, but cannot be spent yet.
+func (w *Wallet) TimelockedBalances() ([]modules.TimelockedBalance, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer w.tg.Done()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	consensusHeight, err := dbGetConsensusHeight(w.dbTx)
+	if err != nil {
+		return nil, err
+	}
+	balances := make(map[types.BlockHeight]modules.TimelockedBalance)
+	unlockHeight := func(uh types.UnlockHash) (types.BlockHeight, bool) {
+		key, exists := w.keys[uh]
+		if !exists || key.UnlockConditions.Timelock <= consensusHeight {
+			return 0, false
+		}
+		return key.UnlockConditions.Timelock, true
+	}
+	err = dbForEachSiacoinOutput(w.dbTx, func(_ types.SiacoinOutputID, sco types.SiacoinOutput) {
+		if height, locked := unlockHeight(sco.UnlockHash); locked {
+			tb := balances[height]
+			tb.Siacoins = tb.Siacoins.Add(sco.Value)
+			balances[height] = tb
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = dbForEachSiafundOutput(w.dbTx, func(_ types.SiafundOutputID, sfo types.SiafundOutput) {
+		if height, locked := unlockHeight(sfo.UnlockHash); locked {
+			tb := balances[height]
+			tb.Siafunds = tb.Siafunds.Add(sfo.Value)
+			balances[height] = tb
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tbs := make([]modules.TimelockedBalance, 0, len(balances))
+	for height, tb := range balances {
+		tb.UnlockHeight = height
+		tbs = append(tbs, tb)
+	}
+	sort.Slice(tbs, func(i, j int) bool {
+		return tbs[i].UnlockHeight < tbs[j].UnlockHeight
+	})
+	return tbs, nil
+}
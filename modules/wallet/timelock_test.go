@@ -0,0 +1,83 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestTimelockedAddress sends coins to a timelocked address of the wallet and
+// checks that they are reported as timelocked, and can only be spent once
+// the timelock has passed.
+func TestTimelockedAddress(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	if _, err := wt.wallet.NewTimelockedAddress(wt.cs.Height()); err != errTimelockInPast {
+		t.Fatal("expected errTimelockInPast, got", err)
+	}
+	timelock := wt.cs.Height() + 5
+	uc, err := wt.wallet.NewTimelockedAddress(timelock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uc.Timelock != timelock || len(uc.PublicKeys) != 1 {
+		t.Fatal("wrong unlock conditions:", uc)
+	}
+
+	// Fund the address.
+	amount := types.SiacoinPrecision.Mul64(100)
+	txns, err := wt.wallet.SendSiacoins(amount, uc.UnlockHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	var locked types.SiacoinOutputID
+	for _, txn := range txns {
+		for i, sco := range txn.SiacoinOutputs {
+			if sco.UnlockHash == uc.UnlockHash() {
+				locked = txn.SiacoinOutputID(uint64(i))
+			}
+		}
+	}
+
+	// The coins should be reported as timelocked, including after the keys
+	// of the wallet are reloaded.
+	if err := wt.wallet.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.Unlock(wt.walletMasterKey); err != nil {
+		t.Fatal(err)
+	}
+	tbs, err := wt.wallet.TimelockedBalances()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tbs) != 1 || tbs[0].UnlockHeight != timelock || !tbs[0].Siacoins.Equals(amount) {
+		t.Fatal("wrong timelocked balances:", tbs)
+	}
+	if _, err := wt.wallet.SendSiacoinsFromOutputs(amount.Div64(2), types.UnlockHash{}, []types.SiacoinOutputID{locked}); err == nil {
+		t.Fatal("timelocked output was spent before the timelock")
+	}
+
+	// Once the timelock has passed, the coins can be spent.
+	for wt.cs.Height() < timelock {
+		if _, err := wt.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if tbs, err := wt.wallet.TimelockedBalances(); err != nil || len(tbs) != 0 {
+		t.Fatal("coins are still timelocked:", tbs, err)
+	}
+	if _, err := wt.wallet.SendSiacoinsFromOutputs(amount.Div64(2), types.UnlockHash{}, []types.SiacoinOutputID{locked}); err != nil {
+		t.Fatal(err)
+	}
+}
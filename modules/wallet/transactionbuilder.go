@@ -28,6 +28,23 @@ var (
 
 	// errDustOutput indicates an output is not spendable because it is dust.
 	errDustOutput = errors.New("output is too small")
+
+	// errWatchOnlyOutput indicates an output belongs to a watch-only address,
+	// meaning that the wallet cannot sign for it.
+	errWatchOnlyOutput = errors.New("output belongs to a watch-only address")
+
+	// errScheduledOutput indicates an output is spent by a transaction that
+	// is scheduled to be broadcast.
+	errScheduledOutput = errors.New("output is spent by a scheduled transaction")
+
+	// errUnknownOutput indicates that an output chosen by the caller is not
+	// one of the wallet's spendable siacoin outputs.
+	errUnknownOutput = errors.New("output is not a spendable siacoin output of the wallet")
+
+	// errUnconfirmedFunds indicates that the wallet would have enough funds if
+	// outputs with fewer than the required number of confirmations could be
+	// spent.
+	errUnconfirmedFunds = errors.New("not enough funds with the required number of confirmations")
 )
 
 // transactionBuilder allows transactions to be manually constructed, including
@@ -93,6 +110,19 @@ func addSignatures(txn *types.Transaction, cf types.CoveredFields, uc types.Unlo
 
 // checkOutput is a helper function used to determine if an output is usable.
 func (w *Wallet) checkOutput(tx *bolt.Tx, currentHeight types.BlockHeight, id types.SiacoinOutputID, output types.SiacoinOutput) error {
+	key, exists := w.keys[output.UnlockHash]
+	if !exists {
+		return errWatchOnlyOutput
+	}
+	if w.spentBySchedule(types.OutputID(id)) {
+		return errScheduledOutput
+	}
+	return checkOutputUnlockable(tx, currentHeight, id, output, key.UnlockConditions)
+}
+
+// checkOutputUnlockable is a helper function used to determine if an output
+// with the provided unlock conditions can be spent at currentHeight.
+func checkOutputUnlockable(tx *bolt.Tx, currentHeight types.BlockHeight, id types.SiacoinOutputID, output types.SiacoinOutput, uc types.UnlockConditions) error {
 	// Check that an output is not dust
 	if output.Value.Cmp(dustValue()) < 0 {
 		return errDustOutput
@@ -104,8 +134,7 @@ func (w *Wallet) checkOutput(tx *bolt.Tx, currentHeight types.BlockHeight, id ty
 			return errSpendHeightTooHigh
 		}
 	}
-	outputUnlockConditions := w.keys[output.UnlockHash].UnlockConditions
-	if currentHeight < outputUnlockConditions.Timelock {
+	if currentHeight < uc.Timelock {
 		return errOutputTimelock
 	}
 
@@ -117,8 +146,38 @@ func (w *Wallet) checkOutput(tx *bolt.Tx, currentHeight types.BlockHeight, id ty
 // correct value. The siacoin input will not be signed until 'Sign' is called
 // on the transaction builder.
 func (tb *transactionBuilder) FundSiacoins(amount types.Currency) error {
+	return tb.fundSiacoins(amount, nil, 0)
+}
+
+// FundSiacoinsWithConfirmations works like FundSiacoins, but only spends
+// siacoin outputs that have been confirmed by at least minConfirmations
+// blocks, so that the transaction cannot be invalidated by a reorg of fewer
+// blocks removing one of its inputs.
+func (tb *transactionBuilder) FundSiacoinsWithConfirmations(amount types.Currency, minConfirmations types.BlockHeight) error {
+	return tb.fundSiacoins(amount, nil, minConfirmations)
+}
+
+// FundSiacoinsFromOutputs works like FundSiacoins, but only spends the siacoin
+// outputs in ids. An error is returned if any of the outputs is not a
+// spendable siacoin output of the wallet.
+func (tb *transactionBuilder) FundSiacoinsFromOutputs(amount types.Currency, ids []types.SiacoinOutputID) error {
+	allowed := make(map[types.SiacoinOutputID]struct{})
+	for _, id := range ids {
+		allowed[id] = struct{}{}
+	}
+	return tb.fundSiacoins(amount, allowed, 0)
+}
+
+// fundSiacoins implements FundSiacoins, FundSiacoinsFromOutputs and
+// FundSiacoinsWithConfirmations. If allowed is nil, any of the wallet's
+// siacoin outputs may be spent. If minConfirmations is not zero, unconfirmed
+// outputs and outputs with fewer confirmations are not spent.
+func (tb *transactionBuilder) fundSiacoins(amount types.Currency, allowed map[types.SiacoinOutputID]struct{}, minConfirmations types.BlockHeight) error {
 	tb.wallet.mu.Lock()
 	defer tb.wallet.mu.Unlock()
+	if !tb.wallet.unlocked {
+		return modules.ErrLockedWallet
+	}
 
 	consensusHeight, err := dbGetConsensusHeight(tb.wallet.dbTx)
 	if err != nil {
@@ -146,6 +205,42 @@ func (tb *transactionBuilder) FundSiacoins(amount types.Currency) error {
 			so.outputs = append(so.outputs, sco)
 		}
 	}
+	// Only keep the outputs that have enough confirmations. The value of the
+	// others is tracked to provide the user with a more useful error message.
+	var youngFund types.Currency
+	if minConfirmations > 0 {
+		heights, err := dbOutputConfirmationHeights(tb.wallet.dbTx)
+		if err != nil {
+			return err
+		}
+		var confirmed sortedOutputs
+		for i, scoid := range so.ids {
+			height, exists := heights[types.OutputID(scoid)]
+			if exists && height <= consensusHeight && consensusHeight-height+1 >= minConfirmations {
+				confirmed.ids = append(confirmed.ids, scoid)
+				confirmed.outputs = append(confirmed.outputs, so.outputs[i])
+			} else if tb.wallet.checkOutput(tb.wallet.dbTx, consensusHeight, scoid, so.outputs[i]) == nil {
+				youngFund = youngFund.Add(so.outputs[i].Value)
+			}
+		}
+		so = confirmed
+	}
+	// Restrict the set to the outputs chosen by the caller.
+	if allowed != nil {
+		var chosen sortedOutputs
+		for i, scoid := range so.ids {
+			_, isAllowed := allowed[scoid]
+			_, isSpendable := tb.wallet.keys[so.outputs[i].UnlockHash]
+			if isAllowed && isSpendable {
+				chosen.ids = append(chosen.ids, scoid)
+				chosen.outputs = append(chosen.outputs, so.outputs[i])
+			}
+		}
+		if len(chosen.ids) != len(allowed) {
+			return errUnknownOutput
+		}
+		so = chosen
+	}
 	sort.Sort(sort.Reverse(so))
 
 	// Create and fund a parent transaction that will add the correct amount of
@@ -187,6 +282,9 @@ func (tb *transactionBuilder) FundSiacoins(amount types.Currency) error {
 	if potentialFund.Cmp(amount) >= 0 && fund.Cmp(amount) < 0 {
 		return modules.ErrIncompleteTransactions
 	}
+	if fund.Add(youngFund).Cmp(amount) >= 0 && fund.Cmp(amount) < 0 {
+		return errUnconfirmedFunds
+	}
 	if fund.Cmp(amount) < 0 {
 		return modules.ErrLowBalance
 	}
@@ -223,7 +321,7 @@ func (tb *transactionBuilder) FundSiacoins(amount types.Currency) error {
 	}
 	// Mark the parent output as spent. Must be done after the transaction is
 	// finished because otherwise the txid and output id will change.
-	err = dbPutSpentOutput(tb.wallet.dbTx, types.OutputID(parentTxn.SiacoinOutputID(0)), consensusHeight)
+	err = tb.wallet.markOutputSpent(types.OutputID(parentTxn.SiacoinOutputID(0)), consensusHeight)
 	if err != nil {
 		return err
 	}
@@ -240,7 +338,7 @@ func (tb *transactionBuilder) FundSiacoins(amount types.Currency) error {
 
 	// Mark all outputs that were spent as spent.
 	for _, scoid := range spentScoids {
-		err = dbPutSpentOutput(tb.wallet.dbTx, types.OutputID(scoid), consensusHeight)
+		err = tb.wallet.markOutputSpent(types.OutputID(scoid), consensusHeight)
 		if err != nil {
 			return err
 		}
@@ -255,6 +353,9 @@ func (tb *transactionBuilder) FundSiacoins(amount types.Currency) error {
 func (tb *transactionBuilder) FundSiafunds(amount types.Currency) error {
 	tb.wallet.mu.Lock()
 	defer tb.wallet.mu.Unlock()
+	if !tb.wallet.unlocked {
+		return modules.ErrLockedWallet
+	}
 
 	consensusHeight, err := dbGetConsensusHeight(tb.wallet.dbTx)
 	if err != nil {
@@ -277,6 +378,14 @@ func (tb *transactionBuilder) FundSiafunds(amount types.Currency) error {
 			return err
 		}
 
+		// Skip outputs of watch-only addresses, and outputs that scheduled
+		// transactions will spend.
+		if _, exists := tb.wallet.keys[sfo.UnlockHash]; !exists {
+			continue
+		} else if tb.wallet.spentBySchedule(types.OutputID(sfoid)) {
+			continue
+		}
+
 		// Check that this output has not recently been spent by the wallet.
 		spendHeight, err := dbGetSpentOutput(tb.wallet.dbTx, types.OutputID(sfoid))
 		if err != nil {
@@ -371,7 +480,7 @@ func (tb *transactionBuilder) FundSiafunds(amount types.Currency) error {
 
 	// Mark all outputs that were spent as spent.
 	for _, sfoid := range spentSfoids {
-		err = dbPutSpentOutput(tb.wallet.dbTx, types.OutputID(sfoid), consensusHeight)
+		err = tb.wallet.markOutputSpent(types.OutputID(sfoid), consensusHeight)
 		if err != nil {
 			return err
 		}
@@ -473,6 +582,9 @@ func (tb *transactionBuilder) Drop() {
 		for _, sci := range txn.SiacoinInputs {
 			dbDeleteSpentOutput(tb.wallet.dbTx, types.OutputID(sci.ParentID))
 		}
+		for _, sfi := range txn.SiafundInputs {
+			dbDeleteSpentOutput(tb.wallet.dbTx, types.OutputID(sfi.ParentID))
+		}
 	}
 
 	tb.parents = nil
@@ -546,6 +658,9 @@ func (tb *transactionBuilder) Sign(wholeTransaction bool) ([]types.Transaction,
 	// signature.
 	tb.wallet.mu.RLock()
 	defer tb.wallet.mu.RUnlock()
+	if !tb.wallet.unlocked {
+		return nil, modules.ErrLockedWallet
+	}
 	for _, inputIndex := range tb.siacoinInputs {
 		input := tb.transaction.SiacoinInputs[inputIndex]
 		key, ok := tb.wallet.keys[input.UnlockConditions.UnlockHash()]
@@ -66,10 +66,12 @@ func (w *Wallet) advanceSeedLookahead(index uint64) (bool, error) {
 }
 
 // isWalletAddress is a helper function that checks if an UnlockHash is
-// derived from one of the wallet's spendable keys or future keys.
+// derived from one of the wallet's spendable keys or future keys, or is
+// watched by the wallet.
 func (w *Wallet) isWalletAddress(uh types.UnlockHash) bool {
 	_, exists := w.keys[uh]
-	return exists
+	_, watched := w.watchedAddrs[uh]
+	return exists || watched
 }
 
 // updateLookahead uses a consensus change to update the seed progress if one of the outputs
@@ -154,40 +156,17 @@ func (w *Wallet) updateConfirmedSet(tx *bolt.Tx, cc modules.ConsensusChange) err
 // blocks in the consensus change.
 func (w *Wallet) revertHistory(tx *bolt.Tx, reverted []types.Block) error {
 	for _, block := range reverted {
-		// Remove any transactions that have been reverted.
-		for i := len(block.Transactions) - 1; i >= 0; i-- {
-			// If the transaction is relevant to the wallet, it will be the
-			// most recent transaction in bucketProcessedTransactions.
-			txid := block.Transactions[i].ID()
-			pt, err := dbGetLastProcessedTransaction(tx)
-			if err != nil {
-				break // bucket is empty
-			}
-			if txid == pt.TransactionID {
-				w.log.Println("A wallet transaction has been reverted due to a reorg:", txid)
-				if err := dbDeleteLastProcessedTransaction(tx); err != nil {
-					w.log.Severe("Could not revert transaction:", err)
-				}
-			}
+		consensusHeight, err := dbGetConsensusHeight(tx)
+		if err != nil {
+			return err
 		}
-
-		// Remove the miner payout transaction if applicable.
-		for i, mp := range block.MinerPayouts {
-			if w.isWalletAddress(mp.UnlockHash) {
-				w.log.Println("Miner payout has been reverted due to a reorg:", block.MinerPayoutID(uint64(i)), "::", mp.Value.HumanString())
-				if err := dbDeleteLastProcessedTransaction(tx); err != nil {
-					w.log.Severe("Could not revert transaction:", err)
-				}
-				break // there will only ever be one miner transaction
-			}
+		// The history of blocks below the rescan height is not replayed.
+		if consensusHeight >= w.rescanHeight {
+			w.revertBlockHistory(tx, block)
 		}
 
 		// decrement the consensus height
 		if block.ID() != types.GenesisID {
-			consensusHeight, err := dbGetConsensusHeight(tx)
-			if err != nil {
-				return err
-			}
 			err = dbPutConsensusHeight(tx, consensusHeight-1)
 			if err != nil {
 				return err
@@ -197,6 +176,38 @@ func (w *Wallet) revertHistory(tx *bolt.Tx, reverted []types.Block) error {
 	return nil
 }
 
+// revertBlockHistory removes the transactions of a reverted block from the
+// history of the wallet.
+func (w *Wallet) revertBlockHistory(tx *bolt.Tx, block types.Block) {
+	// Remove any transactions that have been reverted.
+	for i := len(block.Transactions) - 1; i >= 0; i-- {
+		// If the transaction is relevant to the wallet, it will be the
+		// most recent transaction in bucketProcessedTransactions.
+		txid := block.Transactions[i].ID()
+		pt, err := dbGetLastProcessedTransaction(tx)
+		if err != nil {
+			break // bucket is empty
+		}
+		if txid == pt.TransactionID {
+			w.log.Println("A wallet transaction has been reverted due to a reorg:", txid)
+			if err := dbDeleteLastProcessedTransaction(tx); err != nil {
+				w.log.Severe("Could not revert transaction:", err)
+			}
+		}
+	}
+
+	// Remove the miner payout transaction if applicable.
+	for i, mp := range block.MinerPayouts {
+		if w.isWalletAddress(mp.UnlockHash) {
+			w.log.Println("Miner payout has been reverted due to a reorg:", block.MinerPayoutID(uint64(i)), "::", mp.Value.HumanString())
+			if err := dbDeleteLastProcessedTransaction(tx); err != nil {
+				w.log.Severe("Could not revert transaction:", err)
+			}
+			break // there will only ever be one miner transaction
+		}
+	}
+}
+
 // applyHistory applies any transaction history that was introduced by the
 // applied blocks.
 func (w *Wallet) applyHistory(tx *bolt.Tx, cc modules.ConsensusChange) error {
@@ -229,6 +240,11 @@ func (w *Wallet) applyHistory(tx *bolt.Tx, cc modules.ConsensusChange) error {
 				return err
 			}
 		}
+		// The history of blocks below the rescan height was kept by the
+		// rescan.
+		if consensusHeight < w.rescanHeight {
+			continue
+		}
 
 		relevant := false
 		for _, mp := range block.MinerPayouts {
@@ -341,14 +357,16 @@ func (w *Wallet) applyHistory(tx *bolt.Tx, cc modules.ConsensusChange) error {
 					return fmt.Errorf("could not get siafund pool: %v", err)
 				}
 
+				// The claim is paid to the ClaimUnlockHash, which need not
+				// belong to the owner of the siafunds.
 				sfo := spentSiafundOutputs[sfi.ParentID]
 				po := modules.ProcessedOutput{
-					ID:             types.OutputID(sfi.ParentID),
+					ID:             types.OutputID(sfi.ParentID.SiaClaimOutputID()),
 					FundType:       types.SpecifierClaimOutput,
 					MaturityHeight: consensusHeight + types.MaturityDelay,
-					WalletAddress:  w.isWalletAddress(sfi.UnlockConditions.UnlockHash()),
+					WalletAddress:  w.isWalletAddress(sfi.ClaimUnlockHash),
 					RelatedAddress: sfi.ClaimUnlockHash,
-					Value:          siafundPool.Sub(sfo.ClaimStart).Mul(sfo.Value),
+					Value:          siafundPool.Sub(sfo.ClaimStart).Mul(sfo.Value).Div(types.SiafundCount),
 				}
 				pt.Outputs = append(pt.Outputs, po)
 				// Log any wallet-relevant outputs.
@@ -404,19 +422,88 @@ func (w *Wallet) ProcessConsensusChange(cc modules.ConsensusChange) {
 	if err := w.updateConfirmedSet(w.dbTx, cc); err != nil {
 		w.log.Println("ERROR: failed to update confirmed set:", err)
 	}
+	if err := w.updateAtomicSwaps(w.dbTx, cc); err != nil {
+		w.log.Println("ERROR: failed to update atomic swaps:", err)
+	}
+	if err := w.updatePayments(w.dbTx, cc); err != nil {
+		w.log.Println("ERROR: failed to update payments:", err)
+	}
 	if err := w.revertHistory(w.dbTx, cc.RevertedBlocks); err != nil {
 		w.log.Println("ERROR: failed to revert consensus change:", err)
 	}
 	if err := w.applyHistory(w.dbTx, cc); err != nil {
 		w.log.Println("ERROR: failed to apply consensus change:", err)
 	}
+	if err := w.releaseDroppedSpends(w.dbTx); err != nil {
+		w.log.Println("ERROR: failed to release outputs of dropped transactions:", err)
+	}
 	if err := dbPutConsensusChangeID(w.dbTx, cc.ID); err != nil {
 		w.log.Println("ERROR: failed to update consensus change ID:", err)
 	}
 
 	if cc.Synced {
 		go w.threadedDefragWallet()
+		go w.threadedSweepClaims()
+		if height, err := dbGetConsensusHeight(w.dbTx); err == nil && len(cc.AppliedBlocks) > 0 {
+			go w.threadedBroadcastScheduled(height, cc.AppliedBlocks[len(cc.AppliedBlocks)-1].Timestamp)
+		}
+	}
+}
+
+// markOutputSpent marks an output of the wallet as spent at height, so that it
+// is not used to fund other transactions until RespendTimeout blocks have
+// passed. An output that was spent by a dropped transaction set is no longer
+// released.
+func (w *Wallet) markOutputSpent(id types.OutputID, height types.BlockHeight) error {
+	delete(w.droppedSpends, id)
+	return dbPutSpentOutput(w.dbTx, id, height)
+}
+
+// markUnconfirmedSpends marks the outputs of the wallet that are spent by the
+// transactions as spent at the current height, and returns their ids.
+func (w *Wallet) markUnconfirmedSpends(tx *bolt.Tx, txns []types.Transaction) ([]types.OutputID, error) {
+	height, err := dbGetConsensusHeight(tx)
+	if err != nil {
+		return nil, err
+	}
+	var spends []types.OutputID
+	for _, txn := range txns {
+		for _, sci := range txn.SiacoinInputs {
+			if _, err := dbGetSiacoinOutput(tx, sci.ParentID); err == nil {
+				spends = append(spends, types.OutputID(sci.ParentID))
+			}
+		}
+		for _, sfi := range txn.SiafundInputs {
+			if _, err := dbGetSiafundOutput(tx, sfi.ParentID); err == nil {
+				spends = append(spends, types.OutputID(sfi.ParentID))
+			}
+		}
+	}
+	for _, id := range spends {
+		if err := w.markOutputSpent(id, height); err != nil {
+			return spends, err
+		}
+	}
+	return spends, nil
+}
+
+// releaseDroppedSpends releases the outputs that were spent by transaction
+// sets that the transaction pool dropped, so that they can fund new
+// transactions without waiting for RespendTimeout. Outputs that were spent by
+// a block are no longer outputs of the wallet, and are skipped.
+func (w *Wallet) releaseDroppedSpends(tx *bolt.Tx) error {
+	for id := range w.droppedSpends {
+		delete(w.droppedSpends, id)
+		_, scErr := dbGetSiacoinOutput(tx, types.SiacoinOutputID(id))
+		_, sfErr := dbGetSiafundOutput(tx, types.SiafundOutputID(id))
+		if scErr != nil && sfErr != nil {
+			continue
+		}
+		if err := dbDeleteSpentOutput(tx, id); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 // ReceiveUpdatedUnconfirmedTransactions updates the wallet's unconfirmed
@@ -434,6 +521,13 @@ func (w *Wallet) ReceiveUpdatedUnconfirmedTransactions(diff *modules.Transaction
 			droppedTransactions[txids[i]] = struct{}{}
 		}
 		delete(w.unconfirmedSets, diff.RevertedTransactions[i])
+
+		// The outputs spent by the set are released at the next consensus
+		// change, unless another set spends them in the meantime.
+		for _, id := range w.unconfirmedSpends[diff.RevertedTransactions[i]] {
+			w.droppedSpends[id] = struct{}{}
+		}
+		delete(w.unconfirmedSpends, diff.RevertedTransactions[i])
 	}
 
 	// Skip the reallocation if we can, otherwise reallocate the
@@ -466,6 +560,15 @@ func (w *Wallet) ReceiveUpdatedUnconfirmedTransactions(diff *modules.Transaction
 		// to the wallet, but overhead should be low.
 		w.unconfirmedSets[uts.ID] = uts.IDs
 
+		// Mark the outputs of the wallet that the set spends as spent, so
+		// that they are not used to fund other transactions while the set is
+		// in the transaction pool.
+		spends, err := w.markUnconfirmedSpends(w.dbTx, uts.Transactions)
+		if err != nil {
+			w.log.Println("ERROR: failed to mark outputs spent by unconfirmed transactions:", err)
+		}
+		w.unconfirmedSpends[uts.ID] = spends
+
 		// Get the values for the spent outputs.
 		spentSiacoinOutputs := make(map[types.SiacoinOutputID]types.SiacoinOutput)
 		for _, scod := range uts.Change.SiacoinOutputDiffs {
@@ -526,4 +629,5 @@ func (w *Wallet) ReceiveUpdatedUnconfirmedTransactions(diff *modules.Transaction
 			w.unconfirmedProcessedTransactions = append(w.unconfirmedProcessedTransactions, pt)
 		}
 	}
+	w.updateUnconfirmedPayments()
 }
@@ -71,3 +71,66 @@ func TestUpdate(t *testing.T) {
 		t.Fatal("transaction was not removed")
 	}
 }
+
+// TestReleaseDroppedSpends checks that the outputs spent by a transaction set
+// are released once the transaction pool drops the set.
+func TestReleaseDroppedSpends(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// Grab a block before the set is created, so that the block does not
+	// confirm the set.
+	block, target, err := wt.miner.BlockForWork()
+	if err != nil {
+		t.Fatal(err)
+	}
+	txns, err := wt.wallet.SendSiacoins(types.SiacoinPrecision.Mul64(100), types.UnlockHash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := txns[0].SiacoinInputs[0].ParentID
+	wt.wallet.mu.Lock()
+	var setID modules.TransactionSetID
+	for sid, spends := range wt.wallet.unconfirmedSpends {
+		for _, spent := range spends {
+			if spent == types.OutputID(id) {
+				setID = sid
+			}
+		}
+	}
+	wt.wallet.mu.Unlock()
+	if setID == (modules.TransactionSetID{}) {
+		t.Fatal("wallet is not tracking the outputs spent by the set")
+	}
+	fundFromOutput := func() error {
+		tb := wt.wallet.StartTransaction()
+		defer tb.Drop()
+		return tb.FundSiacoinsFromOutputs(types.SiacoinPrecision, []types.SiacoinOutputID{id})
+	}
+	if err := fundFromOutput(); err == nil {
+		t.Fatal("output spent by an unconfirmed set was used again")
+	}
+
+	// Drop the set from the transaction pool. The output stays reserved until
+	// the next block shows that it was not spent.
+	wt.tpool.PurgeTransactionPool()
+	wt.wallet.ReceiveUpdatedUnconfirmedTransactions(&modules.TransactionPoolDiff{
+		RevertedTransactions: []modules.TransactionSetID{setID},
+	})
+	if err := fundFromOutput(); err == nil {
+		t.Fatal("output was released before the next block")
+	}
+	solvedBlock, _ := wt.miner.SolveBlock(block, target)
+	if err := wt.cs.AcceptBlock(solvedBlock); err != nil {
+		t.Fatal(err)
+	}
+	if err := fundFromOutput(); err != nil {
+		t.Fatal("output of a dropped set was not released:", err)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/NebulousLabs/bolt"
 
@@ -58,6 +59,21 @@ type Wallet struct {
 	subscribed  bool
 	primarySeed modules.Seed
 
+	// autoLock locks the wallet when the timeout passed to UnlockWithTimeout,
+	// or else autoLockTimeout, elapses. autoLockID identifies the most recent
+	// timer, so that a timer that fires after the wallet has been locked and
+	// unlocked again is ignored. autoLockDeadline is the time at which the
+	// timer fires. unlockedSince is the time the wallet was last unlocked,
+	// and lastAutoLock the last time it locked itself. lockSubscribers
+	// receive an event whenever the wallet locks itself.
+	autoLock         *time.Timer
+	autoLockID       uint64
+	autoLockTimeout  time.Duration
+	autoLockDeadline time.Time
+	unlockedSince    time.Time
+	lastAutoLock     time.Time
+	lockSubscribers  map[chan modules.LockEvent]struct{}
+
 	// The wallet's dependencies.
 	cs    modules.ConsensusSet
 	tpool modules.TransactionPool
@@ -72,6 +88,54 @@ type Wallet struct {
 	keys      map[types.UnlockHash]spendableKey
 	lookahead map[types.UnlockHash]uint64
 
+	// watchedAddrs contains the watch-only addresses of the wallet. Their
+	// outputs and history are tracked, but the wallet has no keys to spend
+	// them. The unlock conditions are empty unless a public key was
+	// imported.
+	watchedAddrs map[types.UnlockHash]types.UnlockConditions
+
+	// defragSettings control when and how the wallet consolidates its
+	// outputs.
+	defragSettings modules.DefragSettings
+
+	// scanGapLimit is the number of unused keys that the seed scanner keeps
+	// beyond the largest key index it has found in the blockchain.
+	scanGapLimit uint64
+
+	// scheduledTxns contains the signed transaction sets that the wallet
+	// broadcasts once their scheduled height and time have been reached.
+	scheduledTxns map[types.TransactionID]modules.ScheduledTransaction
+
+	// atomicSwaps contains the swap contracts that pay to or refund to the
+	// wallet, along with their secrets once they are known.
+	atomicSwaps map[types.FileContractID]modules.AtomicSwap
+
+	// paymentSubscribers receive the payment events of the wallet.
+	// recentPayments contains the confirmed payments that have fewer than
+	// maxPaymentConfirmations confirmations, and unconfirmedPayments the IDs
+	// of the payments in the transaction pool. None of them are persisted.
+	paymentSubscribers  map[*paymentSubscriber]struct{}
+	recentPayments      map[types.OutputID]modules.PaymentEvent
+	unconfirmedPayments map[types.OutputID]struct{}
+
+	// signers contains the external signers of the wallet, indexed by the
+	// string form of the public keys they sign for. They are not persisted.
+	signers map[string]signerKey
+
+	// rescanHeight is the height from which the history of the wallet is
+	// rebuilt during a rescan. History below it was kept by the rescan, so
+	// blocks below it only update the outputs of the wallet.
+	rescanHeight types.BlockHeight
+
+	// addrLabels contains the labels of the wallet's addresses, addressBook
+	// maps the names of payees to their addresses, ucTemplates maps the
+	// names of multisig templates to the templates, and txnMemos contains
+	// the memos of the wallet's transactions.
+	addrLabels  map[types.UnlockHash]string
+	addressBook map[string]types.UnlockHash
+	ucTemplates map[string]modules.UnlockConditionsTemplate
+	txnMemos    map[types.TransactionID]string
+
 	// unconfirmedProcessedTransactions tracks unconfirmed transactions.
 	//
 	// TODO: Replace this field with a linked list. Currently when a new
@@ -81,6 +145,13 @@ type Wallet struct {
 	unconfirmedSets                  map[modules.TransactionSetID][]types.TransactionID
 	unconfirmedProcessedTransactions []modules.ProcessedTransaction
 
+	// unconfirmedSpends contains the outputs of the wallet spent by each
+	// transaction set of the transaction pool. The outputs of sets that are
+	// dropped are moved to droppedSpends, and are released at the next
+	// consensus change unless a block spent them.
+	unconfirmedSpends map[modules.TransactionSetID][]types.OutputID
+	droppedSpends     map[types.OutputID]struct{}
+
 	// The wallet's database tracks its seeds, keys, outputs, and
 	// transactions. A global db transaction is maintained in memory to avoid
 	// excessive disk writes. Any operations involving dbTx must hold an
@@ -119,10 +190,26 @@ func New(cs modules.ConsensusSet, tpool modules.TransactionPool, persistDir stri
 		cs:    cs,
 		tpool: tpool,
 
-		keys:      make(map[types.UnlockHash]spendableKey),
-		lookahead: make(map[types.UnlockHash]uint64),
+		keys:         make(map[types.UnlockHash]spendableKey),
+		lookahead:    make(map[types.UnlockHash]uint64),
+		watchedAddrs: make(map[types.UnlockHash]types.UnlockConditions),
+		addrLabels:   make(map[types.UnlockHash]string),
+		addressBook:  make(map[string]types.UnlockHash),
+		ucTemplates:  make(map[string]modules.UnlockConditionsTemplate),
+		txnMemos:     make(map[types.TransactionID]string),
+
+		scheduledTxns: make(map[types.TransactionID]modules.ScheduledTransaction),
+		atomicSwaps:   make(map[types.FileContractID]modules.AtomicSwap),
+		signers:       make(map[string]signerKey),
+
+		paymentSubscribers:  make(map[*paymentSubscriber]struct{}),
+		lockSubscribers:     make(map[chan modules.LockEvent]struct{}),
+		recentPayments:      make(map[types.OutputID]modules.PaymentEvent),
+		unconfirmedPayments: make(map[types.OutputID]struct{}),
 
-		unconfirmedSets: make(map[modules.TransactionSetID][]types.TransactionID),
+		unconfirmedSets:   make(map[modules.TransactionSetID][]types.TransactionID),
+		unconfirmedSpends: make(map[modules.TransactionSetID][]types.OutputID),
+		droppedSpends:     make(map[types.OutputID]struct{}),
 
 		persistDir: persistDir,
 	}
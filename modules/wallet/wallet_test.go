@@ -1,6 +1,7 @@
 package wallet
 
 import (
+	"errors"
 	"path/filepath"
 	"testing"
 	"time"
@@ -140,6 +141,17 @@ func (wt *walletTester) closeWt() error {
 	return build.JoinErrors(errs, "; ")
 }
 
+// waitForSync waits for the consensus set of the tester to be synced. The
+// wallet only acts on the consensus changes of a synced consensus set.
+func (wt *walletTester) waitForSync() error {
+	return build.Retry(100, 10*time.Millisecond, func() error {
+		if !wt.cs.Synced() {
+			return errors.New("consensus set is not synced")
+		}
+		return nil
+	})
+}
+
 // TestNilInputs tries starting the wallet using nil inputs.
 func TestNilInputs(t *testing.T) {
 	testdir := build.TempDir(modules.WalletDir, t.Name())
@@ -0,0 +1,152 @@
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	errWatchOwnedAddress = errors.New("address is already controlled by the wallet")
+	errUnknownWatchAddr  = errors.New("address is not watched by the wallet")
+)
+
+// managedAddWatch adds the addresses to the set of watch-only addresses and
+// rescans the blockchain unless the addresses are unused.
+func (w *Wallet) managedAddWatch(addrs map[types.UnlockHash]types.UnlockConditions, unused bool) error {
+	if !w.scanLock.TryLock() {
+		return errScanInProgress
+	}
+	defer w.scanLock.Unlock()
+
+	w.mu.Lock()
+	// Duplication is detected by looking at the set of keys, which is only
+	// complete while the wallet is unlocked.
+	if !w.unlocked {
+		w.mu.Unlock()
+		return modules.ErrLockedWallet
+	}
+	for uh := range addrs {
+		if _, exists := w.keys[uh]; exists {
+			w.mu.Unlock()
+			return errWatchOwnedAddress
+		}
+	}
+	for uh, uc := range addrs {
+		// Do not replace known unlock conditions with empty ones.
+		if known, exists := w.watchedAddrs[uh]; exists && known.UnlockHash() == uh {
+			uc = known
+		}
+		if err := dbPutWatchedAddr(w.dbTx, uh, uc); err != nil {
+			w.mu.Unlock()
+			return err
+		}
+		w.watchedAddrs[uh] = uc
+	}
+	w.syncDB()
+	w.mu.Unlock()
+
+	if unused {
+		return nil
+	}
+	return w.rescan(0)
+}
+
+// AddWatchAddresses instructs the wallet to track the outputs and history of
+// the addresses without being able to spend them. Outputs of these addresses
+// cannot be used in unsigned transactions, because their unlock conditions
+// are unknown. If unused is true, the wallet assumes that the addresses have
+// never appeared on the blockchain and skips the rescan.
+func (w *Wallet) AddWatchAddresses(addrs []types.UnlockHash, unused bool) error {
+	if err := w.tg.Add(); err != nil {
+		return err
+	}
+	defer w.tg.Done()
+
+	watched := make(map[types.UnlockHash]types.UnlockConditions)
+	for _, uh := range addrs {
+		watched[uh] = types.UnlockConditions{}
+	}
+	return w.managedAddWatch(watched, unused)
+}
+
+// AddWatchPublicKeys instructs the wallet to track the outputs and history of
+// the standard addresses of the public keys, returning the addresses. Their
+// outputs can be spent by unsigned transactions, which must then be signed
+// by the holder of the secret keys. If unused is true, the wallet assumes
+// that the addresses have never appeared on the blockchain and skips the
+// rescan.
+func (w *Wallet) AddWatchPublicKeys(spks []types.SiaPublicKey, unused bool) ([]types.UnlockHash, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer w.tg.Done()
+
+	var addrs []types.UnlockHash
+	watched := make(map[types.UnlockHash]types.UnlockConditions)
+	for _, spk := range spks {
+		uc := types.UnlockConditions{
+			PublicKeys:         []types.SiaPublicKey{spk},
+			SignaturesRequired: 1,
+		}
+		addrs = append(addrs, uc.UnlockHash())
+		watched[uc.UnlockHash()] = uc
+	}
+	return addrs, w.managedAddWatch(watched, unused)
+}
+
+// RemoveWatchAddresses instructs the wallet to stop tracking the watch-only
+// addresses. If unused is true, the wallet assumes that the addresses never
+// appeared on the blockchain and skips the rescan that removes their outputs
+// and history.
+func (w *Wallet) RemoveWatchAddresses(addrs []types.UnlockHash, unused bool) error {
+	if err := w.tg.Add(); err != nil {
+		return err
+	}
+	defer w.tg.Done()
+	if !w.scanLock.TryLock() {
+		return errScanInProgress
+	}
+	defer w.scanLock.Unlock()
+
+	w.mu.Lock()
+	for _, uh := range addrs {
+		if _, exists := w.watchedAddrs[uh]; !exists {
+			w.mu.Unlock()
+			return errUnknownWatchAddr
+		}
+	}
+	for _, uh := range addrs {
+		if err := dbDeleteWatchedAddr(w.dbTx, uh); err != nil {
+			w.mu.Unlock()
+			return err
+		}
+		delete(w.watchedAddrs, uh)
+	}
+	w.syncDB()
+	w.mu.Unlock()
+
+	if unused {
+		return nil
+	}
+	return w.rescan(0)
+}
+
+// WatchAddresses returns the watch-only addresses of the wallet, sorted in
+// byte-order.
+func (w *Wallet) WatchAddresses() []types.UnlockHash {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	addrs := make([]types.UnlockHash, 0, len(w.watchedAddrs))
+	for addr := range w.watchedAddrs {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return bytes.Compare(addrs[i][:], addrs[j][:]) < 0
+	})
+	return addrs
+}
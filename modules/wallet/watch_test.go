@@ -0,0 +1,113 @@
+package wallet
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestWatchOnly tracks an address of one wallet in a second, watch-only
+// wallet.
+func TestWatchOnly(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// Fund an address of the signing wallet.
+	uc, err := wt.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	amount := types.SiacoinPrecision.Mul64(100)
+	if _, err := wt.wallet.SendSiacoins(amount, uc.UnlockHash()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Create the watch-only wallet.
+	dir := filepath.Join(build.TempDir(modules.WalletDir, t.Name()+"-watch"), modules.WalletDir)
+	watcher, err := New(wt.cs, wt.tpool, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := crypto.TwofishKey{1}
+	if _, err := watcher.Encrypt(key); err != nil {
+		t.Fatal(err)
+	}
+	if err := watcher.Unlock(key); err != nil {
+		t.Fatal(err)
+	}
+	own, err := watcher.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := watcher.AddWatchAddresses([]types.UnlockHash{own.UnlockHash()}, true); err != errWatchOwnedAddress {
+		t.Fatal("expected errWatchOwnedAddress, got", err)
+	}
+
+	// Watch the funded address; the rescan should find its balance and
+	// history.
+	addrs, err := watcher.AddWatchPublicKeys(uc.PublicKeys, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) != 1 || addrs[0] != uc.UnlockHash() {
+		t.Fatal("wrong watch address:", addrs)
+	}
+	if wa := watcher.WatchAddresses(); len(wa) != 1 || wa[0] != uc.UnlockHash() {
+		t.Fatal("wrong watch addresses:", wa)
+	}
+	if sc, _, _ := watcher.ConfirmedBalance(); !sc.Equals(amount) {
+		t.Fatalf("watch-only balance is %v, expected %v", sc, amount)
+	}
+	txns, err := watcher.Transactions(0, wt.cs.Height())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txns) != 1 {
+		t.Fatal("expected 1 watch-only transaction, got", len(txns))
+	}
+
+	// The watch-only wallet cannot send the funds itself.
+	if _, err := watcher.SendSiacoins(amount.Div64(2), types.UnlockHash{}); err == nil {
+		t.Fatal("watch-only wallet was able to send watched funds")
+	}
+
+	// Removing the address should remove its balance.
+	if err := watcher.RemoveWatchAddresses(addrs, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := watcher.RemoveWatchAddresses(addrs, false); err != errUnknownWatchAddr {
+		t.Fatal("expected errUnknownWatchAddr, got", err)
+	}
+	if sc, _, _ := watcher.ConfirmedBalance(); !sc.IsZero() {
+		t.Fatal("balance remains after removing the watch-only address:", sc)
+	}
+
+	// Watch-only addresses should persist.
+	if err := watcher.AddWatchAddresses(addrs, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := watcher.Close(); err != nil {
+		t.Fatal(err)
+	}
+	watcher, err = New(wt.cs, wt.tpool, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Close()
+	if wa := watcher.WatchAddresses(); len(wa) != 1 || wa[0] != uc.UnlockHash() {
+		t.Fatal("watch addresses were not persisted:", wa)
+	}
+}
@@ -0,0 +1,149 @@
+package modules
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestProcessedTransactionSummary checks that the summary of a processed
+// transaction totals the funds moving in and out of the wallet.
+func TestProcessedTransactionSummary(t *testing.T) {
+	t.Parallel()
+
+	wallet := types.UnlockHash{1}
+	other := types.UnlockHash{2}
+	pt := ProcessedTransaction{
+		Inputs: []ProcessedInput{
+			{FundType: types.SpecifierSiacoinInput, WalletAddress: true, RelatedAddress: wallet, Value: types.NewCurrency64(100)},
+			{FundType: types.SpecifierSiacoinInput, WalletAddress: false, RelatedAddress: other, Value: types.NewCurrency64(50)},
+			{FundType: types.SpecifierSiafundInput, WalletAddress: true, RelatedAddress: wallet, Value: types.NewCurrency64(3)},
+		},
+		Outputs: []ProcessedOutput{
+			{FundType: types.SpecifierSiacoinOutput, WalletAddress: true, RelatedAddress: wallet, Value: types.NewCurrency64(40)},
+			{FundType: types.SpecifierSiacoinOutput, WalletAddress: false, RelatedAddress: other, Value: types.NewCurrency64(100)},
+			{FundType: types.SpecifierClaimOutput, WalletAddress: true, RelatedAddress: wallet, Value: types.NewCurrency64(5)},
+			{FundType: types.SpecifierSiafundOutput, WalletAddress: true, RelatedAddress: wallet, Value: types.NewCurrency64(1)},
+			{FundType: types.SpecifierMinerFee, Value: types.NewCurrency64(10)},
+		},
+	}
+
+	ts := pt.Summary()
+	if !ts.IncomingSiacoins.Equals64(45) {
+		t.Error("wrong incoming siacoins:", ts.IncomingSiacoins)
+	}
+	if !ts.OutgoingSiacoins.Equals64(100) {
+		t.Error("wrong outgoing siacoins:", ts.OutgoingSiacoins)
+	}
+	if !ts.IncomingSiafunds.Equals64(1) || !ts.OutgoingSiafunds.Equals64(3) {
+		t.Error("wrong siafunds:", ts.IncomingSiafunds, ts.OutgoingSiafunds)
+	}
+	if !ts.MinerFees.Equals64(10) {
+		t.Error("wrong miner fees:", ts.MinerFees)
+	}
+	if len(ts.RelatedAddresses) != 1 || ts.RelatedAddresses[0] != other {
+		t.Error("wrong related addresses:", ts.RelatedAddresses)
+	}
+}
+
+// TestWalletHistory checks the running balances of the wallet history and its
+// CSV encoding.
+func TestWalletHistory(t *testing.T) {
+	t.Parallel()
+
+	wallet := types.UnlockHash{1}
+	other := types.UnlockHash{2}
+	pts := []ProcessedTransaction{{
+		TransactionID:         types.TransactionID{1},
+		ConfirmationHeight:    3,
+		ConfirmationTimestamp: 1500000000,
+		Outputs: []ProcessedOutput{
+			{FundType: types.SpecifierMinerPayout, WalletAddress: true, RelatedAddress: wallet, Value: types.SiacoinPrecision.Mul64(300)},
+		},
+	}, {
+		TransactionID:         types.TransactionID{2},
+		ConfirmationHeight:    7,
+		ConfirmationTimestamp: 1500000600,
+		Inputs: []ProcessedInput{
+			{FundType: types.SpecifierSiacoinInput, WalletAddress: true, RelatedAddress: wallet, Value: types.SiacoinPrecision.Mul64(300)},
+		},
+		Outputs: []ProcessedOutput{
+			{FundType: types.SpecifierSiacoinOutput, WalletAddress: false, RelatedAddress: other, Value: types.SiacoinPrecision.Mul64(100)},
+			{FundType: types.SpecifierSiacoinOutput, WalletAddress: true, RelatedAddress: wallet, Value: types.SiacoinPrecision.Mul64(199).Add(types.SiacoinPrecision.Div64(2))},
+			{FundType: types.SpecifierMinerFee, Value: types.SiacoinPrecision.Div64(2)},
+		},
+	}}
+
+	history := WalletHistory(pts)
+	if len(history) != 2 {
+		t.Fatal("wrong number of history entries:", len(history))
+	}
+	if history[0].SiacoinBalance.Cmp(types.SiacoinPrecision.Mul64(300)) != 0 {
+		t.Error("wrong balance after the first transaction:", history[0].SiacoinBalance)
+	}
+	if history[1].SiacoinBalance.Cmp(types.SiacoinPrecision.Mul64(199).Add(types.SiacoinPrecision.Div64(2))) != 0 {
+		t.Error("wrong balance after the second transaction:", history[1].SiacoinBalance)
+	}
+
+	// Outgoing funds that the history does not account for do not make the
+	// balance negative.
+	if b := WalletHistory(pts[1:])[0].SiacoinBalance; !b.IsZero() {
+		t.Error("partial history should clamp the balance at zero, got", b)
+	}
+
+	history[1].Memo = "rent"
+	var buf bytes.Buffer
+	if err := WriteHistoryCSV(&buf, history); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatal("wrong number of CSV records:", len(records))
+	}
+	exp := []string{
+		types.TransactionID{2}.String(), "7", "2017-07-14T02:50:00Z",
+		"199.5", "300", "0.5", "0", "0", "199.5", "0", other.String(), "rent",
+	}
+	if !reflect.DeepEqual(records[2], exp) {
+		t.Errorf("wrong CSV record:\n%v\nexpected:\n%v", records[2], exp)
+	}
+}
+
+// TestSeedDictionaries checks that seeds survive a round trip through each of
+// the seed dictionaries, and that the dictionary of a phrase is detected.
+func TestSeedDictionaries(t *testing.T) {
+	t.Parallel()
+
+	seed := Seed{1, 2, 3, 4, 5, 6, 7, 8}
+	for _, did := range SeedDictionaries {
+		str, err := SeedToString(seed, did)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := StringToSeed(str, did)
+		if err != nil || decoded != seed {
+			t.Fatalf("%v: seed did not survive a round trip: %v", did, err)
+		}
+
+		// Seeds copied from numbered rows are split over several lines.
+		words := strings.Fields(str)
+		multiline := strings.Join(words[:10], " ") + "\n" + strings.Join(words[10:], "  ")
+		decoded, detected, err := StringToSeedAnyDictionary(multiline)
+		if err != nil || decoded != seed || detected != did {
+			t.Fatalf("%v: dictionary was not detected (got %v): %v", did, detected, err)
+		}
+
+		// A changed word should fail the checksum.
+		words[0], words[1] = words[1], words[0]
+		if _, _, err := StringToSeedAnyDictionary(strings.Join(words, " ")); err != ErrUnknownSeedDictionary {
+			t.Fatalf("%v: expected ErrUnknownSeedDictionary, got %v", did, err)
+		}
+	}
+}
@@ -87,3 +87,35 @@ func OpenDatabase(md Metadata, filename string) (*BoltDatabase, error) {
 
 	return boltDB, nil
 }
+
+// OpenDatabaseReadOnly opens a database for reading only and validates its
+// metadata. Unlike OpenDatabase, the database must already exist and have
+// metadata, as nothing can be written to it. Bolt does not allow a database
+// to be opened for reading while another process has it open for writing.
+func OpenDatabaseReadOnly(md Metadata, filename string) (*BoltDatabase, error) {
+	db, err := bolt.Open(filename, 0600, &bolt.Options{Timeout: 3 * time.Second, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the metadata.
+	boltDB := &BoltDatabase{
+		Metadata: md,
+		DB:       db,
+	}
+	err = db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("Metadata"))
+		if bucket == nil || string(bucket.Get([]byte("Header"))) != md.Header {
+			return ErrBadHeader
+		}
+		if string(bucket.Get([]byte("Version"))) != md.Version {
+			return ErrBadVersion
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return boltDB, nil
+}
@@ -17,6 +17,15 @@ var (
 		Long:  "Print the current state of consensus such as current block, block height, and target.",
 		Run:   wrap(consensuscmd),
 	}
+
+	consensusVerifyCmd = &cobra.Command{
+		Use:   "verify",
+		Short: "Verify the consensus database",
+		Long: `Replay every block in the blockchain from the genesis block and compare the
+result to the stored consensus state. This can take a long time, and is useful
+after a crash or when disk corruption is suspected.`,
+		Run: wrap(consensusverifycmd),
+	}
 )
 
 // consensuscmd is the handler for the command `siac consensus`.
@@ -47,6 +56,18 @@ Progress (estimated): %.1f%%
 	}
 }
 
+// consensusverifycmd is the handler for the command `siac consensus verify`.
+// Verifies the consensus database by replaying the blockchain.
+func consensusverifycmd() {
+	fmt.Println("Verifying the consensus database, this may take a while...")
+	var cvp api.ConsensusVerifyPOST
+	err := postResp("/consensus/verify", "", &cvp)
+	if err != nil {
+		die("Consensus verification failed:", err)
+	}
+	fmt.Printf("Consensus database is consistent up to height %v.\n", cvp.Height)
+}
+
 // estimatedHeightAt returns the estimated block height for the given time.
 // Block height is estimated by calculating the minutes since a known block in
 // the past and dividing by 10 minutes (the block time).
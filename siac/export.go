@@ -26,6 +26,14 @@ var (
 			"file. Intended for upload to `https://rankings.sia.tech/`.",
 		Run: wrap(renterexportcontracttxnscmd),
 	}
+
+	renterExportKeysCmd = &cobra.Command{
+		Use:   "keys [destination]",
+		Short: "export the keys that the renter encrypts files with",
+		Long: "Export the renter's master key and the key of every file in JSON format to " +
+			"the specified file. The file contains secrets and should be kept safe.",
+		Run: wrap(renterexportkeyscmd),
+	}
 )
 
 // renterexportcontracttxnscmd is the handler for the command `siac renter export contract-txns`.
@@ -51,3 +59,24 @@ func renterexportcontracttxnscmd(destination string) {
 	}
 	fmt.Println("Exported contract data to", destination)
 }
+
+// renterexportkeyscmd is the handler for the command `siac renter export keys`.
+// Exports the renter's encryption keys to JSON.
+func renterexportkeyscmd(destination string) {
+	var keys api.RenterKeys
+	err := getAPI("/renter/keys", &keys)
+	if err != nil {
+		die("Could not retrieve keys:", err)
+	}
+	destination = abs(destination)
+	file, err := os.OpenFile(destination, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		die("Could not export to file:", err)
+	}
+	defer file.Close()
+	err = json.NewEncoder(file).Encode(keys)
+	if err != nil {
+		die("Could not export to file:", err)
+	}
+	fmt.Println("Exported keys to", destination)
+}
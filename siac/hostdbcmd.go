@@ -3,8 +3,11 @@ package main
 import (
 	"fmt"
 	"math/big"
+	"net/url"
 	"os"
+	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -33,6 +36,30 @@ var (
 		Long:  "View detailed information about a host, including things like a score breakdown.",
 		Run:   wrap(hostdbviewcmd),
 	}
+
+	hostdbFilterCmd = &cobra.Command{
+		Use:   "filter",
+		Short: "View the host filter.",
+		Long:  "View the filter that restricts the hosts that the renter forms contracts with and\nuploads to.",
+		Run:   wrap(hostdbfiltercmd),
+	}
+
+	hostdbFilterSetCmd = &cobra.Command{
+		Use:   "set [blacklist|whitelist] [hosts]",
+		Short: "Set the host filter.",
+		Long: `Exclude hosts from contract formation and uploads (blacklist), or use only
+those hosts (whitelist). [hosts] is a comma-separated list of host public keys,
+such as ed25519:d1f2..., and IP ranges in CIDR notation, such as 10.0.0.0/8.
+Existing contracts with excluded hosts are not renewed.`,
+		Run: wrap(hostdbfiltersetcmd),
+	}
+
+	hostdbFilterDisableCmd = &cobra.Command{
+		Use:   "disable",
+		Short: "Disable the host filter.",
+		Long:  "Disable the host filter, allowing the renter to use any host.",
+		Run:   wrap(hostdbfilterdisablecmd),
+	}
 )
 
 func hostdbcmd() {
@@ -271,6 +298,12 @@ func hostdbviewcmd(pubkey string) {
 
 	fmt.Println("  Public Key:", info.Entry.PublicKeyString)
 	fmt.Println("  Block First Seen:", info.Entry.FirstSeen)
+	if info.Entry.Subnet != "" {
+		fmt.Println("  Subnet:", info.Entry.Subnet)
+	}
+	if rtt := info.Entry.AverageRTT(); rtt > 0 {
+		fmt.Println("  Round-Trip Time:", rtt.Round(time.Millisecond))
+	}
 
 	fmt.Println("\n  Host Settings:")
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
@@ -290,6 +323,7 @@ func hostdbviewcmd(pubkey string) {
 	fmt.Fprintf(w, "\t\tAge:\t %.3f\n", info.ScoreBreakdown.AgeAdjustment)
 	fmt.Fprintf(w, "\t\tBurn:\t %.3f\n", info.ScoreBreakdown.BurnAdjustment)
 	fmt.Fprintf(w, "\t\tCollateral:\t %.3f\n", info.ScoreBreakdown.CollateralAdjustment)
+	fmt.Fprintf(w, "\t\tLatency:\t %.3f\n", info.ScoreBreakdown.LatencyAdjustment)
 	fmt.Fprintf(w, "\t\tPrice:\t %.3f\n", info.ScoreBreakdown.PriceAdjustment*1e6)
 	fmt.Fprintf(w, "\t\tStorage:\t %.3f\n", info.ScoreBreakdown.StorageRemainingAdjustment)
 	fmt.Fprintf(w, "\t\tUptime:\t %.3f\n", info.ScoreBreakdown.UptimeAdjustment)
@@ -323,3 +357,55 @@ func hostdbviewcmd(pubkey string) {
 
 	fmt.Println()
 }
+
+// hostdbfiltercmd displays the host filter.
+func hostdbfiltercmd() {
+	var hf api.HostdbFilterGET
+	if err := getAPI("/hostdb/filter", &hf); err != nil {
+		die("Could not fetch the host filter:", err)
+	}
+	if hf.Mode == modules.HostFilterModeDisable {
+		fmt.Println("The host filter is disabled.")
+		return
+	}
+	fmt.Println("Filter mode:", hf.Mode)
+	for _, host := range hf.Hosts {
+		fmt.Println("  " + host)
+	}
+	for _, netRange := range hf.NetRanges {
+		fmt.Println("  " + netRange)
+	}
+}
+
+// hostdbfiltersetcmd sets the mode and the hosts of the host filter. Entries
+// containing a slash are IP ranges, and the others are public keys.
+func hostdbfiltersetcmd(mode, hosts string) {
+	var keys, netRanges []string
+	for _, entry := range strings.Split(hosts, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		} else if strings.Contains(entry, "/") {
+			netRanges = append(netRanges, entry)
+		} else {
+			keys = append(keys, entry)
+		}
+	}
+	vals := url.Values{
+		"mode":      {mode},
+		"hosts":     {strings.Join(keys, ",")},
+		"netranges": {strings.Join(netRanges, ",")},
+	}
+	if err := post("/hostdb/filter", vals.Encode()); err != nil {
+		die("Could not set the host filter:", err)
+	}
+	fmt.Println("Host filter set")
+}
+
+// hostdbfilterdisablecmd disables the host filter.
+func hostdbfilterdisablecmd() {
+	vals := url.Values{"mode": {modules.HostFilterModeDisable}}
+	if err := post("/hostdb/filter", vals.Encode()); err != nil {
+		die("Could not disable the host filter:", err)
+	}
+	fmt.Println("Host filter disabled")
+}
@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"reflect"
+	"strings"
 
 	"github.com/bgentry/speakeasy"
 	"github.com/spf13/cobra"
@@ -18,12 +20,29 @@ import (
 
 var (
 	// Flags.
-	addr              string // override default API address
-	initPassword      bool   // supply a custom password when creating a wallet
-	initForce         bool   // destroy and reencrypt the wallet on init if it already exists
-	hostVerbose       bool   // display additional host info
-	renterShowHistory bool   // Show download history in addition to download queue.
-	renterListVerbose bool   // Show additional info about uploaded files.
+	addr              string   // override default API address
+	initPassword      bool     // supply a custom password when creating a wallet
+	initForce         bool     // destroy and reencrypt the wallet on init if it already exists
+	seedDictionary    string   // dictionary used when displaying seeds
+	timelockPubkey    string   // public key of a timelocked address of another party
+	swapSecretHash    string   // secret hash of an atomic swap chosen by the counterparty
+	minConfirmations  uint64   // minimum confirmations of the outputs spent by a send
+	sendPreview       bool     // describe a send instead of broadcasting it
+	hostVerbose       bool     // display additional host info
+	renterShowHistory bool     // Show transfer history in addition to the download or upload queue.
+	renterListVerbose bool     // Show additional info about uploaded files.
+	uploadData        int      // data pieces of an upload, 0 for the default
+	uploadParity      int      // parity pieces of an upload, 0 for the default
+	uploadPieceSize   uint64   // piece size of an upload, 0 for the default
+	uploadPriority    int      // priority of an upload
+	uploadClass       string   // redundancy class of an upload
+	uploadRecursive   bool     // upload the files inside a folder
+	uploadInclude     []string // patterns of the files of a folder to upload
+	uploadExclude     []string // patterns of the files of a folder to skip
+	limitHostDownload string   // maximum download speed of each connection to a host
+	limitHostUpload   string   // maximum upload speed of each connection to a host
+	costRedundancy    string   // redundancy of the data in a cost estimate
+	walletName        string   // named wallet used by wallet commands
 
 	// Globals.
 	rootCmd *cobra.Command // Root command cobra object, used by bash completion cmd.
@@ -58,10 +77,24 @@ func decodeError(resp *http.Response) error {
 	return apiErr
 }
 
+// walletCall adds the name of the wallet selected with --wallet to calls to
+// the wallet API.
+func walletCall(call string) string {
+	if walletName == "" || (call != "/wallet" && !strings.HasPrefix(call, "/wallet/") && !strings.HasPrefix(call, "/wallet?")) {
+		return call
+	}
+	sep := "?"
+	if strings.Contains(call, "?") {
+		sep = "&"
+	}
+	return call + sep + "wallet=" + url.QueryEscape(walletName)
+}
+
 // apiGet wraps a GET request with a status code check, such that if the GET does
 // not return 2xx, the error will be read and returned. The response body is
 // not closed.
 func apiGet(call string) (*http.Response, error) {
+	call = walletCall(call)
 	if host, port, _ := net.SplitHostPort(addr); host == "" {
 		addr = net.JoinHostPort("localhost", port)
 	}
@@ -133,6 +166,7 @@ func get(call string) error {
 // does not return 2xx, the error will be read and returned. The response body
 // is not closed.
 func apiPost(call, vals string) (*http.Response, error) {
+	call = walletCall(call)
 	if host, port, _ := net.SplitHostPort(addr); host == "" {
 		addr = net.JoinHostPort("localhost", port)
 	}
@@ -255,42 +289,77 @@ func main() {
 	hostCmd.Flags().BoolVarP(&hostVerbose, "verbose", "v", false, "Display detailed host info")
 
 	root.AddCommand(hostdbCmd)
-	hostdbCmd.AddCommand(hostdbViewCmd)
+	hostdbCmd.AddCommand(hostdbViewCmd, hostdbFilterCmd)
+	hostdbFilterCmd.AddCommand(hostdbFilterSetCmd, hostdbFilterDisableCmd)
 	hostdbCmd.Flags().IntVarP(&hostdbNumHosts, "numhosts", "n", 0, "Number of hosts to display from the hostdb")
 	hostdbCmd.Flags().BoolVarP(&hostdbVerbose, "verbose", "v", false, "Display full hostdb information")
 
 	root.AddCommand(minerCmd)
 	minerCmd.AddCommand(minerStartCmd, minerStopCmd)
 
-	root.AddCommand(walletCmd)
-	walletCmd.AddCommand(walletAddressCmd, walletAddressesCmd, walletChangepasswordCmd, walletInitCmd, walletInitSeedCmd,
-		walletLoadCmd, walletLockCmd, walletSeedsCmd, walletSendCmd, walletSweepCmd,
+	root.AddCommand(walletCmd, walletsCmd)
+	walletsCmd.AddCommand(walletsCreateCmd)
+	walletCmd.PersistentFlags().StringVarP(&walletName, "wallet", "w", "", "Name of the wallet to use instead of the main wallet")
+	walletCmd.AddCommand(walletAddressCmd, walletAddressesCmd, walletAddressBookCmd, walletAutoLockCmd, walletBackupCmd, walletBumpFeeCmd, walletChangepasswordCmd, walletExportCmd, walletGapLimitCmd, walletInitCmd, walletInitSeedCmd,
+		walletLabelCmd, walletLoadCmd, walletLockCmd, walletMemoCmd, walletSignMessageCmd, walletVerifyMessageCmd, walletRescanCmd, walletRestoreCmd, walletSeedsCmd, walletSendCmd, walletSwapCmd, walletSweepCmd, walletTemplatesCmd, walletTimelockCmd,
 		walletBalanceCmd, walletTransactionsCmd, walletUnlockCmd)
+	walletAddressBookCmd.AddCommand(walletAddressBookAddCmd, walletAddressBookRemoveCmd)
+	walletAutoLockCmd.AddCommand(walletAutoLockSetCmd)
+	walletTemplatesCmd.AddCommand(walletTemplatesAddCmd, walletTemplatesAddressCmd, walletTemplatesRemoveCmd)
 	walletInitCmd.Flags().BoolVarP(&initPassword, "password", "p", false, "Prompt for a custom password")
 	walletInitCmd.Flags().BoolVarP(&initForce, "force", "", false, "destroy the existing wallet and re-encrypt")
+	walletInitCmd.Flags().StringVarP(&seedDictionary, "dictionary", "", "english", "Dictionary of the recovery seed: english, german or japanese")
 	walletInitSeedCmd.Flags().BoolVarP(&initForce, "force", "", false, "destroy the existing wallet")
+	walletSeedsCmd.Flags().StringVarP(&seedDictionary, "dictionary", "", "english", "Dictionary of the seeds: english, german or japanese")
+	walletTimelockCmd.Flags().StringVarP(&timelockPubkey, "pubkey", "", "", "Public key of the party that can spend from the address, as ed25519:<hex>")
+	walletSwapCmd.AddCommand(walletSwapAuditCmd, walletSwapCreateCmd, walletSwapRedeemCmd)
+	walletSwapCreateCmd.Flags().StringVarP(&swapSecretHash, "secrethash", "", "", "Secret hash chosen by the counterparty, instead of a generated secret")
 	walletLoadCmd.AddCommand(walletLoad033xCmd, walletLoadSeedCmd, walletLoadSiagCmd)
-	walletSendCmd.AddCommand(walletSendSiacoinsCmd, walletSendSiafundsCmd)
+	walletSendCmd.AddCommand(walletSendBatchCmd, walletSendSiacoinsCmd, walletSendSiafundsCmd)
+	walletSendSiacoinsCmd.Flags().Uint64VarP(&minConfirmations, "minconfirmations", "", 0, "Only spend outputs with at least this many confirmations")
+	walletSendSiacoinsCmd.Flags().BoolVarP(&sendPreview, "preview", "", false, "Print the size, fee, inputs and change of the transaction without sending it")
 
 	root.AddCommand(renterCmd)
 	renterCmd.AddCommand(renterFilesDeleteCmd, renterFilesDownloadCmd,
 		renterDownloadsCmd, renterAllowanceCmd, renterSetAllowanceCmd,
 		renterContractsCmd, renterFilesListCmd, renterFilesRenameCmd,
 		renterFilesUploadCmd, renterUploadsCmd, renterExportCmd,
-		renterPricesCmd)
-
+		renterPricesCmd, renterDirCmd, renterRateLimitsCmd,
+		renterSetRateLimitsCmd, renterEstimateCmd, renterBackupCmd,
+		renterRestoreCmd, renterShareLinkCmd, renterDownloadLinkCmd,
+		renterWatchCmd, renterSnapshotsCmd, renterSpendingCmd, renterVerifyCmd,
+		renterPauseCmd, renterResumeCmd, renterClassesCmd, renterSetClassCmd,
+		renterForecastCmd)
+
+	renterClassesCmd.AddCommand(renterClassesSetCmd, renterClassesDeleteCmd)
 	renterContractsCmd.AddCommand(renterContractsViewCmd)
+	renterDirCmd.AddCommand(renterDirCreateCmd, renterDirDeleteCmd, renterDirRenameCmd)
 	renterAllowanceCmd.AddCommand(renterAllowanceCancelCmd)
+	renterSnapshotsCmd.AddCommand(renterSnapshotsCreateCmd, renterSnapshotsRecoverCmd)
+	renterUploadsCmd.AddCommand(renterUploadsPauseCmd, renterUploadsResumeCmd, renterUploadsCancelCmd, renterUploadsPriorityCmd)
 
 	renterCmd.Flags().BoolVarP(&renterListVerbose, "verbose", "v", false, "Show additional file info such as redundancy")
 	renterDownloadsCmd.Flags().BoolVarP(&renterShowHistory, "history", "H", false, "Show download history in addition to the download queue")
+	renterUploadsCmd.Flags().BoolVarP(&renterShowHistory, "history", "H", false, "Show the uploads since siad started in addition to the upload queue")
 	renterFilesListCmd.Flags().BoolVarP(&renterListVerbose, "verbose", "v", false, "Show additional file info such as redundancy")
-	renterExportCmd.AddCommand(renterExportContractTxnsCmd)
+	renterFilesUploadCmd.Flags().IntVarP(&uploadData, "datapieces", "", 0, "Number of data pieces of each chunk")
+	renterFilesUploadCmd.Flags().IntVarP(&uploadParity, "paritypieces", "", 0, "Number of parity pieces of each chunk")
+	renterFilesUploadCmd.Flags().Uint64VarP(&uploadPieceSize, "piecesize", "", 0, "Size of each piece in bytes")
+	renterFilesUploadCmd.Flags().StringVarP(&uploadClass, "class", "", "", "Redundancy class of the upload, instead of --datapieces and --paritypieces")
+	renterFilesUploadCmd.Flags().IntVarP(&uploadPriority, "priority", "", 0, "Priority of the upload; files with a higher priority are uploaded first")
+	renterFilesUploadCmd.Flags().BoolVarP(&uploadRecursive, "recursive", "r", false, "Upload the files inside a folder and its subfolders")
+	renterFilesUploadCmd.Flags().StringArrayVarP(&uploadInclude, "include", "", nil, "Only upload the files of a folder that match this pattern")
+	renterFilesUploadCmd.Flags().StringArrayVarP(&uploadExclude, "exclude", "", nil, "Skip the files and folders of a folder that match this pattern")
+	renterEstimateCmd.Flags().StringVarP(&costRedundancy, "redundancy", "", "3", "Redundancy of the data on the network")
+	renterSetRateLimitsCmd.Flags().StringVarP(&limitHostDownload, "host-download", "", "", "Maximum download speed of each connection to a host")
+	renterSetRateLimitsCmd.Flags().StringVarP(&limitHostUpload, "host-upload", "", "", "Maximum upload speed of each connection to a host")
+	renterExportCmd.AddCommand(renterExportContractTxnsCmd, renterExportKeysCmd)
 
 	root.AddCommand(gatewayCmd)
 	gatewayCmd.AddCommand(gatewayConnectCmd, gatewayDisconnectCmd, gatewayAddressCmd, gatewayListCmd)
 
 	root.AddCommand(consensusCmd)
+	consensusCmd.AddCommand(consensusVerifyCmd)
 
 	root.AddCommand(bashcomplCmd)
 	root.AddCommand(mangenCmd)
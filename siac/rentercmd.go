@@ -1,10 +1,15 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
+	"strings"
 	"text/tabwriter"
 	"time"
 
@@ -29,6 +34,47 @@ var (
 		Run:   wrap(renteruploadscmd),
 	}
 
+	renterUploadsPauseCmd = &cobra.Command{
+		Use:   "pause [id]",
+		Short: "Pause an upload",
+		Long: `Stop uploading and repairing the file of the upload with the given ID until the
+upload is resumed. The IDs of uploads are listed by 'siac renter uploads'.
+Streaming uploads cannot be paused.`,
+		Run: wrap(renteruploadspausecmd),
+	}
+
+	renterUploadsResumeCmd = &cobra.Command{
+		Use:   "resume [id]",
+		Short: "Resume a paused upload",
+		Long:  "Resume the upload with the given ID, which was paused by 'siac renter uploads pause'.",
+		Run:   wrap(renteruploadsresumecmd),
+	}
+
+	renterUploadsCancelCmd = &cobra.Command{
+		Use:   "cancel [id]",
+		Short: "Cancel an upload",
+		Long:  "Stop the upload with the given ID and delete its file from the renter.",
+		Run:   wrap(renteruploadscancelcmd),
+	}
+
+	renterUploadsPriorityCmd = &cobra.Command{
+		Use:   "priority [id] [priority]",
+		Short: "Set the priority of an upload",
+		Long: `Set the priority of the upload with the given ID. The pieces of files with a
+higher priority are uploaded and repaired before those of files with a lower
+priority. Uploads have a priority of 0 unless one is given with
+'siac renter upload --priority'. Negative priorities are allowed.`,
+		Run: wrap(renteruploadsprioritycmd),
+	}
+
+	renterWatchCmd = &cobra.Command{
+		Use:   "watch",
+		Short: "Watch the progress of uploads and downloads",
+		Long: `Print the progress of uploads and downloads as pieces are transferred, until
+interrupted.`,
+		Run: wrap(renterwatchcmd),
+	}
+
 	renterDownloadsCmd = &cobra.Command{
 		Use:   "downloads",
 		Short: "View the download queue",
@@ -67,6 +113,25 @@ have a reasonable number (>30) of hosts in your hostdb.`,
 		Run: wrap(rentersetallowancecmd),
 	}
 
+	renterRateLimitsCmd = &cobra.Command{
+		Use:   "ratelimits",
+		Short: "View the bandwidth limits",
+		Long:  "View the limits of the bandwidth used to download from and upload to hosts.",
+		Run:   wrap(renterratelimitscmd),
+	}
+
+	renterSetRateLimitsCmd = &cobra.Command{
+		Use:   "setratelimits [maxdownloadspeed] [maxuploadspeed]",
+		Short: "Set the bandwidth limits",
+		Long: `Limit the bandwidth used to download from and upload to hosts. The limits
+apply to all transfers together. The limits of each connection to a host can be
+set with --host-download and --host-upload.
+
+Speeds are given in bytes per second, with a unit (500KB, 2MiB, etc.). A speed
+of 0 is unlimited.`,
+		Run: wrap(rentersetratelimitscmd),
+	}
+
 	renterContractsCmd = &cobra.Command{
 		Use:   "contracts",
 		Short: "View the Renter's contracts",
@@ -74,6 +139,31 @@ have a reasonable number (>30) of hosts in your hostdb.`,
 		Run:   wrap(rentercontractscmd),
 	}
 
+	renterClassesCmd = &cobra.Command{
+		Use:   "classes",
+		Short: "View the redundancy classes of the renter",
+		Long: `List the redundancy classes of the renter. A redundancy class is a named
+erasure coding layout that files can be uploaded with, using 'siac renter
+upload --class', or assigned to later with 'siac renter setclass'.`,
+		Run: wrap(renterclassescmd),
+	}
+
+	renterClassesSetCmd = &cobra.Command{
+		Use:   "set [name] [datapieces] [paritypieces]",
+		Short: "Define or change a redundancy class",
+		Long: `Define a redundancy class whose files are split into [datapieces] pieces, plus
+[paritypieces] pieces of redundancy. If the class exists, its files are
+converted to the new layout.`,
+		Run: wrap(renterclassessetcmd),
+	}
+
+	renterClassesDeleteCmd = &cobra.Command{
+		Use:   "delete [name]",
+		Short: "Delete a redundancy class",
+		Long:  "Delete a redundancy class that is not assigned to any file.",
+		Run:   wrap(renterclassesdeletecmd),
+	}
+
 	renterContractsViewCmd = &cobra.Command{
 		Use:   "view [contract-id]",
 		Short: "View details of the specified contract",
@@ -81,6 +171,35 @@ have a reasonable number (>30) of hosts in your hostdb.`,
 		Run:   wrap(rentercontractsviewcmd),
 	}
 
+	renterDirCmd = &cobra.Command{
+		Use:   "dir [path]",
+		Short: "View a directory",
+		Long: `View the size and health of a directory, and of the directories and files
+directly within it. Use / for the root directory.`,
+		Run: wrap(renterdircmd),
+	}
+
+	renterDirCreateCmd = &cobra.Command{
+		Use:   "create [path]",
+		Short: "Create a directory",
+		Long:  "Create an empty directory.",
+		Run:   wrap(renterdircreatecmd),
+	}
+
+	renterDirDeleteCmd = &cobra.Command{
+		Use:   "delete [path]",
+		Short: "Delete a directory",
+		Long:  "Delete a directory, along with the files and directories within it. Does not delete the files on disk.",
+		Run:   wrap(renterdirdeletecmd),
+	}
+
+	renterDirRenameCmd = &cobra.Command{
+		Use:   "rename [path] [newpath]",
+		Short: "Rename a directory",
+		Long:  "Rename a directory, moving the files and directories within it.",
+		Run:   wrap(renterdirrenamecmd),
+	}
+
 	renterFilesDeleteCmd = &cobra.Command{
 		Use:     "delete [path]",
 		Aliases: []string{"rm"},
@@ -115,8 +234,19 @@ have a reasonable number (>30) of hosts in your hostdb.`,
 	renterFilesUploadCmd = &cobra.Command{
 		Use:   "upload [source] [path]",
 		Short: "Upload a file",
-		Long:  "Upload a file to [path] on the Sia network.",
-		Run:   wrap(renterfilesuploadcmd),
+		Long: `Upload a file to [path] on the Sia network. The erasure coding of the file can
+be set with --datapieces and --paritypieces, which must be given together, or
+with the --class of the file, and --piecesize. Files with a higher --priority
+are uploaded first.
+
+A folder is uploaded with --recursive, which uploads every file inside it into
+the folder [path], keeping the paths of the files relative to [source]. Only
+files that match an --include pattern are uploaded, if any are given, and files
+and folders that match an --exclude pattern are skipped. Patterns are shell
+globs that are matched against the relative path of a file or, if they contain
+no '/', against its name, and may be repeated. The aggregate progress of the
+upload is reported until all files are uploaded.`,
+		Run: wrap(renterfilesuploadcmd),
 	}
 
 	renterPricesCmd = &cobra.Command{
@@ -125,6 +255,137 @@ have a reasonable number (>30) of hosts in your hostdb.`,
 		Long:  "Display the estimated prices of storing files, retrieving files, and creating a set of contracts",
 		Run:   wrap(renterpricescmd),
 	}
+
+	renterEstimateCmd = &cobra.Command{
+		Use:   "estimate [size] [duration]",
+		Short: "Estimate the cost of uploading and storing data",
+		Long: `Estimate the cost of uploading [size] of data and storing it for [duration],
+using the prices of the hosts that contracts would be formed with. The
+redundancy of the data can be set with --redundancy.
+
+size is given with a unit (500MB, 2GiB, etc.)
+
+duration is given in either blocks (b), hours (h), days (d), or weeks (w).`,
+		Run: wrap(renterestimatecmd),
+	}
+
+	renterBackupCmd = &cobra.Command{
+		Use:   "backup [destination]",
+		Short: "Back up the renter's files and contracts",
+		Long: `Write an encrypted backup of the renter's files, contracts and allowance to
+[destination]. The backup can be restored with 'siac renter restore' on any
+node whose wallet has the same seed, even if this node is lost.`,
+		Run: wrap(renterbackupcmd),
+	}
+
+	renterRestoreCmd = &cobra.Command{
+		Use:   "restore [source]",
+		Short: "Restore the renter's files and contracts from a backup",
+		Long: `Add the files, contracts and allowance of a backup created by 'siac renter
+backup' to the renter. The wallet must be unlocked, and must have the same seed
+as the wallet of the node that created the backup. Files whose path is already
+in use are restored under a new path.`,
+		Run: wrap(renterrestorecmd),
+	}
+
+	renterSnapshotsCmd = &cobra.Command{
+		Use:   "snapshots",
+		Short: "View the snapshots of the renter stored on its hosts",
+		Long: `List the snapshots of the renter's files, contracts and allowance that are
+stored on its hosts. A snapshot is created every day, and can be recovered with
+'siac renter snapshots recover' from the wallet seed alone.`,
+		Run: wrap(rentersnapshotscmd),
+	}
+
+	renterSnapshotsCreateCmd = &cobra.Command{
+		Use:   "create",
+		Short: "Store a snapshot of the renter on its hosts",
+		Long: `Store an encrypted snapshot of the renter's files, contracts and allowance on
+its hosts. The location of the snapshot is recorded in a transaction, so the
+wallet must be unlocked and able to pay the transaction fee.`,
+		Run: wrap(rentersnapshotscreatecmd),
+	}
+
+	renterSnapshotsRecoverCmd = &cobra.Command{
+		Use:   "recover",
+		Short: "Restore the renter from its most recent snapshot",
+		Long: `Find the most recent snapshot of the renter among the transactions of the
+wallet, and add its files, contracts and allowance to the renter. The wallet
+must have been restored from the seed of the node that created the snapshot,
+and the node must be synced.`,
+		Run: wrap(rentersnapshotsrecovercmd),
+	}
+
+	renterSpendingCmd = &cobra.Command{
+		Use:   "spending",
+		Short: "View where the renter's money went",
+		Long: `Show how much each contract has spent on storage, uploads and downloads, and
+the share of that spending that is attributed to each file. Spending on the
+sectors of deleted files and of snapshots is shown as unattributed.`,
+		Run: wrap(renterspendingcmd),
+	}
+
+	renterForecastCmd = &cobra.Command{
+		Use:   "forecast",
+		Short: "Forecast when the funds of the renter's contracts run out",
+		Long: `Show the data stored in each contract, its remaining funds and duration, and
+the height at which its funds run out if it keeps spending at its average rate
+so far. Contracts whose funds run out before they are renewed are marked; the
+allowance should be increased before uploads to their hosts start to fail.`,
+		Run: wrap(renterforecastcmd),
+	}
+
+	renterVerifyCmd = &cobra.Command{
+		Use:   "verify [path]",
+		Short: "Check that the hosts of a file still store it",
+		Long: `Challenge each host that stores pieces of the file at [path] to prove that it
+still stores random segments of a few of the pieces, without downloading the
+file. Hosts that fail any challenge, or that cannot be challenged, are
+reported.`,
+		Run: wrap(renterverifycmd),
+	}
+
+	renterPauseCmd = &cobra.Command{
+		Use:   "pause",
+		Short: "Pause all background activity of the renter",
+		Long: `Pause the uploads, repairs and snapshots of the renter, and the formation and
+renewal of its contracts, until 'siac renter resume' is run. Downloads are not
+affected. The renter stays paused when siad is restarted.`,
+		Run: wrap(renterpausecmd),
+	}
+
+	renterResumeCmd = &cobra.Command{
+		Use:   "resume",
+		Short: "Resume the background activity of the renter",
+		Long: `Resume the background activity of a renter paused by 'siac renter pause'.
+Contracts are formed and renewed again after the next block.`,
+		Run: wrap(renterresumecmd),
+	}
+
+	renterSetClassCmd = &cobra.Command{
+		Use:   "setclass [path] [class]",
+		Short: "Assign a file to a redundancy class",
+		Long: `Assign the file at [path] to a redundancy class. The file is uploaded again
+with the layout of the class in the background, and keeps its current layout
+until the conversion is complete. Use "" to stop converting the file.`,
+		Run: wrap(rentersetclasscmd),
+	}
+
+	renterShareLinkCmd = &cobra.Command{
+		Use:   "sharelink [path]",
+		Short: "Print a share link for a file",
+		Long: `Print a link from which other renters can download the file at [path] with
+'siac renter downloadlink'. Anyone with the link can decrypt the file.`,
+		Run: wrap(rentersharelinkcmd),
+	}
+
+	renterDownloadLinkCmd = &cobra.Command{
+		Use:   "downloadlink [link] [destination]",
+		Short: "Download a file from a share link",
+		Long: `Download the file that a share link points to to [destination]. The renter
+must have contracts with the hosts that store the file.`,
+		Run: wrap(renterdownloadlinkcmd),
+	}
 )
 
 // abs returns the absolute representation of a path.
@@ -146,6 +407,10 @@ func rentercmd() {
 	if err != nil {
 		die("Could not get renter info:", err)
 	}
+	if rg.Paused {
+		fmt.Println("Renter is paused. Run 'siac renter resume' to resume uploads, repairs and contract formation.")
+		fmt.Println()
+	}
 	fm := rg.FinancialMetrics
 	unspent := fm.ContractSpending.Sub(fm.DownloadSpending).Sub(fm.StorageSpending).Sub(fm.UploadSpending)
 	fmt.Printf(`Renter info:
@@ -159,25 +424,35 @@ func rentercmd() {
 		currencyUnits(fm.DownloadSpending), currencyUnits(unspent),
 		currencyUnits(fm.ContractSpending))
 
+	dc := rg.DownloadCache
+	fmt.Printf(`Download Cache:
+	Size:   %v of %v (%v chunks)
+	Hits:   %v
+	Misses: %v
+
+`, filesizeUnits(int64(dc.Size)), filesizeUnits(int64(dc.MaxSize)), dc.Chunks, dc.Hits, dc.Misses)
+
 	// also list files
 	renterfileslistcmd()
 }
 
 // renteruploadscmd is the handler for the command `siac renter uploads`.
-// Lists files currently uploading.
+// Lists files currently uploading, and optionally the uploads that finished
+// since siad started if the -H or --history flag is specified.
 func renteruploadscmd() {
 	var rf api.RenterFiles
 	err := getAPI("/renter/files", &rf)
 	if err != nil {
 		die("Could not get upload queue:", err)
 	}
+	var queue api.RenterUploadQueue
+	err = getAPI("/renter/uploads", &queue)
+	if err != nil {
+		die("Could not get upload queue:", err)
+	}
 
-	// TODO: add a --history flag to the uploads command to mirror the --history
-	//       flag in the downloads command. This hasn't been done yet because the
-	//       call to /renter/files includes files that have been shared with you,
-	//       not just files you've uploaded.
-
-	// Filter out files that have been uploaded.
+	// The upload queue only contains the uploads started since siad started,
+	// so the files that are uploading are found in the list of files.
 	var filteredFiles []modules.FileInfo
 	for _, fi := range rf.Files {
 		if !fi.Available {
@@ -186,12 +461,104 @@ func renteruploadscmd() {
 	}
 	if len(filteredFiles) == 0 {
 		fmt.Println("No files are uploading.")
+	} else {
+		fmt.Println("Uploading", len(filteredFiles), "files:")
+		for _, file := range filteredFiles {
+			status := fmt.Sprintf("uploading, %0.2f%%", file.UploadProgress)
+			for _, u := range queue.Uploads {
+				if u.SiaPath == file.SiaPath && !u.Complete && u.Error == "" {
+					if u.Paused {
+						status += ", paused"
+					} else {
+						status += ", " + transferSpeed(u.Speed, u.ETA)
+					}
+					if u.Priority != 0 {
+						status += fmt.Sprintf(", priority %v", u.Priority)
+					}
+					status += fmt.Sprintf(", upload %v", u.ID)
+					break
+				}
+			}
+			fmt.Printf("%13s  %s (%s)\n", filesizeUnits(int64(file.Filesize)), file.SiaPath, status)
+		}
+	}
+	if !renterShowHistory {
 		return
 	}
-	fmt.Println("Uploading", len(filteredFiles), "files:")
-	for _, file := range filteredFiles {
-		fmt.Printf("%13s  %s (uploading, %0.2f%%)\n", filesizeUnits(int64(file.Filesize)), file.SiaPath, file.UploadProgress)
+	fmt.Println()
+	// Filter out uploads that are in progress.
+	var uploaded []modules.UploadInfo
+	for _, u := range queue.Uploads {
+		if u.Complete || u.Error != "" {
+			uploaded = append(uploaded, u)
+		}
+	}
+	if len(uploaded) == 0 {
+		fmt.Println("No files uploaded.")
+		return
+	}
+	fmt.Println("Uploaded", len(uploaded), "files:")
+	for _, u := range uploaded {
+		if u.Error != "" {
+			fmt.Printf("%s: %s (failed: %s)\n", u.StartTime.Format("Jan 02 03:04 PM"), u.SiaPath, u.Error)
+			continue
+		}
+		fmt.Printf("%s: %s\n", u.StartTime.Format("Jan 02 03:04 PM"), u.SiaPath)
+	}
+}
+
+// renteruploadsaction performs an action on the upload with the given ID.
+func renteruploadsaction(id string, values url.Values) {
+	if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+		die("Could not parse upload ID:", err)
+	}
+	if err := post("/renter/uploads/"+id, values.Encode()); err != nil {
+		die("Could not "+values.Get("action")+" the upload:", err)
+	}
+}
+
+// renteruploadspausecmd is the handler for the command `siac renter uploads
+// pause [id]`. Pauses an upload.
+func renteruploadspausecmd(id string) {
+	renteruploadsaction(id, url.Values{"action": {"pause"}})
+	fmt.Println("Paused upload", id)
+}
+
+// renteruploadsresumecmd is the handler for the command `siac renter uploads
+// resume [id]`. Resumes a paused upload.
+func renteruploadsresumecmd(id string) {
+	renteruploadsaction(id, url.Values{"action": {"resume"}})
+	fmt.Println("Resumed upload", id)
+}
+
+// renteruploadscancelcmd is the handler for the command `siac renter uploads
+// cancel [id]`. Cancels an upload and deletes its file.
+func renteruploadscancelcmd(id string) {
+	renteruploadsaction(id, url.Values{"action": {"cancel"}})
+	fmt.Println("Canceled upload", id)
+}
+
+// renteruploadsprioritycmd is the handler for the command `siac renter
+// uploads priority [id] [priority]`. Sets the priority of an upload.
+func renteruploadsprioritycmd(id, priority string) {
+	if _, err := strconv.Atoi(priority); err != nil {
+		die("Could not parse priority:", err)
+	}
+	renteruploadsaction(id, url.Values{"action": {"priority"}, "priority": {priority}})
+	fmt.Printf("Set the priority of upload %v to %v\n", id, priority)
+}
+
+// transferSpeed formats the speed of a transfer and the number of seconds
+// until it completes.
+func transferSpeed(speed, eta uint64) string {
+	if speed == 0 {
+		return "stalled"
 	}
+	s := filesizeUnits(int64(speed)) + "/s"
+	if eta != 0 {
+		s += ", " + (time.Duration(eta) * time.Second).String() + " left"
+	}
+	return s
 }
 
 // renterdownloadscmd is the handler for the command `siac renter downloads`.
@@ -215,7 +582,7 @@ func renterdownloadscmd() {
 	} else {
 		fmt.Println("Downloading", len(downloading), "files:")
 		for _, file := range downloading {
-			fmt.Printf("%s: %5.1f%% %s -> %s\n", file.StartTime.Format("Jan 02 03:04 PM"), 100*float64(file.Received)/float64(file.Filesize), file.SiaPath, file.Destination)
+			fmt.Printf("%s: %5.1f%% %s -> %s (%s)\n", file.StartTime.Format("Jan 02 03:04 PM"), 100*float64(file.Received)/float64(file.Filesize), file.SiaPath, file.Destination, transferSpeed(file.Speed, file.ETA))
 		}
 	}
 	if !renterShowHistory {
@@ -239,6 +606,67 @@ func renterdownloadscmd() {
 	}
 }
 
+// renterwatchcmd is the handler for the command `siac renter watch`. It
+// prints a progress bar each time an upload or download makes progress. The
+// progress of a transfer overwrites the line of its previous progress, unless
+// another transfer made progress in between.
+func renterwatchcmd() {
+	resp, err := apiGet("/renter/events")
+	if err != nil {
+		die("Could not watch transfers:", err)
+	}
+	defer resp.Body.Close()
+	dec := json.NewDecoder(resp.Body)
+	var lastID uint64
+	for {
+		var event api.RenterTransferEvent
+		if err := dec.Decode(&event); err != nil {
+			die("Could not watch transfers:", err)
+		}
+		var id, done, total, speed, eta uint64
+		var kind, siapath, errStr string
+		var complete bool
+		if d := event.Download; d != nil {
+			id, done, total, speed, eta = d.ID, d.Received, d.Filesize, d.Speed, d.ETA
+			kind, siapath, errStr, complete = "download", d.SiaPath, d.Error, d.Complete
+		} else if u := event.Upload; u != nil {
+			id, done, total, speed, eta = u.ID, u.Uploaded, u.Total, u.Speed, u.ETA
+			kind, siapath, errStr, complete = "upload", u.SiaPath, u.Error, u.Complete
+		} else {
+			continue
+		}
+
+		status := transferSpeed(speed, eta)
+		if errStr != "" {
+			status = "failed: " + errStr
+		} else if complete {
+			status = "complete"
+			done = total
+		}
+		if id == lastID {
+			fmt.Print("\r")
+		} else if lastID != 0 {
+			fmt.Println()
+		}
+		lastID = id
+		fmt.Printf("%-8s %s %s (%s)\033[K", kind, progressBar(done, total), siapath, status)
+	}
+}
+
+// progressBar returns a progress bar of done out of total.
+func progressBar(done, total uint64) string {
+	const width = 30
+	var fraction float64
+	if total != 0 {
+		fraction = float64(done) / float64(total)
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+	filled := int(fraction * width)
+	return fmt.Sprintf("[%s%s] %5.1f%%", strings.Repeat("=", filled), strings.Repeat(" ", width-filled), 100*fraction)
+}
+
 // renterallowancecmd displays the current allowance.
 func renterallowancecmd() {
 	var rg api.RenterGET
@@ -281,6 +709,366 @@ func rentersetallowancecmd(amount, period string) {
 	fmt.Println("Allowance updated.")
 }
 
+// renterestimatecmd is the handler for the command `siac renter estimate
+// [size] [duration]`. Estimates the cost of uploading and storing data.
+func renterestimatecmd(size, duration string) {
+	bytes, err := parseFilesize(size)
+	if err != nil {
+		die("Could not parse size:", err)
+	}
+	blocks, err := parsePeriod(duration)
+	if err != nil {
+		die("Could not parse duration:", err)
+	}
+	values := url.Values{}
+	values.Set("size", bytes)
+	values.Set("redundancy", costRedundancy)
+	values.Set("duration", blocks)
+	var est api.RenterEstimateGET
+	err = getAPI("/renter/estimate?"+values.Encode(), &est)
+	if err != nil {
+		die("Could not estimate the cost:", err)
+	}
+
+	fmt.Printf("Estimated cost with %v hosts:\n", est.Hosts)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\tStorage:\t", currencyUnits(est.Storage))
+	fmt.Fprintln(w, "\tUpload:\t", currencyUnits(est.Upload))
+	fmt.Fprintln(w, "\tContract Fees:\t", currencyUnits(est.ContractFees))
+	fmt.Fprintln(w, "\tTotal:\t", currencyUnits(est.Total))
+	w.Flush()
+}
+
+// renterbackupcmd is the handler for the command `siac renter backup
+// [destination]`. Writes a backup of the renter to destination.
+func renterbackupcmd(destination string) {
+	destination = abs(destination)
+	err := get("/renter/backup?destination=" + url.QueryEscape(destination))
+	if err != nil {
+		die("Could not back up the renter:", err)
+	}
+	fmt.Println("Wrote renter backup to", destination)
+}
+
+// renterrestorecmd is the handler for the command `siac renter restore
+// [source]`. Restores the renter from the backup at source.
+func renterrestorecmd(source string) {
+	var rl api.RenterLoad
+	err := postResp("/renter/restore", url.Values{"source": {abs(source)}}.Encode(), &rl)
+	if err != nil {
+		die("Could not restore the renter:", err)
+	}
+	fmt.Printf("Restored %v files:\n", len(rl.FilesAdded))
+	for _, siapath := range rl.FilesAdded {
+		fmt.Println("\t" + siapath)
+	}
+}
+
+// rentersnapshotscmd is the handler for the command `siac renter snapshots`.
+// Lists the snapshots of the renter.
+func rentersnapshotscmd() {
+	var rs api.RenterSnapshots
+	if err := getAPI("/renter/snapshots", &rs); err != nil {
+		die("Could not get the snapshots:", err)
+	}
+	if len(rs.Snapshots) == 0 {
+		fmt.Println("No snapshots.")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Created\tFiles\tSize\tHosts\tTransaction")
+	for _, s := range rs.Snapshots {
+		created := time.Unix(int64(s.Timestamp), 0).Format("2006-01-02 15:04")
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", created, s.NumFiles, filesizeUnits(int64(s.Size)), len(s.Hosts), s.TransactionID)
+	}
+	w.Flush()
+}
+
+// renterspendingcmd is the handler for the command `siac renter spending`.
+// Reports the spending of each contract and each file.
+func renterspendingcmd() {
+	var report modules.RenterSpendingReport
+	if err := getAPI("/renter/spending", &report); err != nil {
+		die("Could not get the spending report:", err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Host\tSectors\tStorage\tUpload\tDownload\tFees\tRemaining")
+	for _, c := range report.Contracts {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n", c.NetAddress, c.Sectors,
+			currencyUnits(c.StorageSpending), currencyUnits(c.UploadSpending),
+			currencyUnits(c.DownloadSpending), currencyUnits(c.Fees),
+			currencyUnits(c.RemainingFunds))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "File\tSectors\tStorage\tUpload\tDownload")
+	for _, f := range report.Files {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", f.SiaPath, f.Sectors,
+			currencyUnits(f.StorageSpending), currencyUnits(f.UploadSpending),
+			currencyUnits(f.DownloadSpending))
+	}
+	u := report.Unattributed
+	fmt.Fprintf(w, "(unattributed)\t\t%v\t%v\t%v\n", currencyUnits(u.StorageSpending),
+		currencyUnits(u.UploadSpending), currencyUnits(u.DownloadSpending))
+	w.Flush()
+}
+
+// renterforecastcmd is the handler for the command `siac renter forecast`.
+// Forecasts when the funds of each contract run out.
+func renterforecastcmd() {
+	var forecast modules.RenterForecast
+	if err := getAPI("/renter/forecast", &forecast); err != nil {
+		die("Could not get the forecast:", err)
+	}
+	if len(forecast.Contracts) == 0 {
+		fmt.Println("No contracts.")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Host\tSize\tRemaining Funds\tSpent per Block\tRemaining Blocks\tRenews At\tFunds Run Out At")
+	var depleting int
+	for _, cf := range forecast.Contracts {
+		depletion := "never"
+		if cf.DepletionHeight != 0 {
+			depletion = fmt.Sprint(cf.DepletionHeight)
+		}
+		if cf.DepletesBeforeRenewal {
+			depletion += " (before renewal)"
+			depleting++
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n", cf.NetAddress, filesizeUnits(int64(cf.Size)),
+			currencyUnits(cf.RemainingFunds), currencyUnits(cf.SpendingRate), cf.RemainingBlocks,
+			cf.RenewHeight, depletion)
+	}
+	w.Flush()
+	if depleting > 0 {
+		fmt.Printf("\n%v of %v contracts will run out of funds before they are renewed at the current rate.\n", depleting, len(forecast.Contracts))
+		fmt.Println("Consider increasing the allowance with 'siac renter setallowance'.")
+	}
+}
+
+// renterpausecmd is the handler for the command `siac renter pause`. Pauses
+// all background activity of the renter.
+func renterpausecmd() {
+	err := post("/renter/pause", "")
+	if err != nil {
+		die("Could not pause the renter:", err)
+	}
+	fmt.Println("Renter paused.")
+}
+
+// renterresumecmd is the handler for the command `siac renter resume`.
+// Resumes the background activity of the renter.
+func renterresumecmd() {
+	err := post("/renter/resume", "")
+	if err != nil {
+		die("Could not resume the renter:", err)
+	}
+	fmt.Println("Renter resumed.")
+}
+
+// renterclassescmd is the handler for the command `siac renter classes`.
+// Lists the redundancy classes of the renter.
+func renterclassescmd() {
+	var rc api.RenterClasses
+	if err := getAPI("/renter/classes", &rc); err != nil {
+		die("Could not get the redundancy classes:", err)
+	}
+	if len(rc.Classes) == 0 {
+		fmt.Println("No redundancy classes.")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Name\tData Pieces\tParity Pieces\tRedundancy")
+	for _, c := range rc.Classes {
+		redundancy := float64(c.DataPieces+c.ParityPieces) / float64(c.DataPieces)
+		fmt.Fprintf(w, "%v\t%v\t%v\t%.2f\n", c.Name, c.DataPieces, c.ParityPieces, redundancy)
+	}
+	w.Flush()
+}
+
+// renterclassessetcmd is the handler for the command `siac renter classes set
+// [name] [datapieces] [paritypieces]`. Defines or changes a redundancy class.
+func renterclassessetcmd(name, dataPieces, parityPieces string) {
+	values := url.Values{}
+	values.Set("action", "set")
+	values.Set("datapieces", dataPieces)
+	values.Set("paritypieces", parityPieces)
+	err := post("/renter/classes/"+url.PathEscape(name), values.Encode())
+	if err != nil {
+		die("Could not set the redundancy class:", err)
+	}
+	fmt.Printf("Redundancy class %v is %v-of-%v.\n", name, dataPieces, parityPieces)
+}
+
+// renterclassesdeletecmd is the handler for the command `siac renter classes
+// delete [name]`. Deletes a redundancy class.
+func renterclassesdeletecmd(name string) {
+	err := post("/renter/classes/"+url.PathEscape(name), "action=delete")
+	if err != nil {
+		die("Could not delete the redundancy class:", err)
+	}
+	fmt.Printf("Deleted redundancy class %v.\n", name)
+}
+
+// rentersetclasscmd is the handler for the command `siac renter setclass
+// [path] [class]`. Assigns a file to a redundancy class.
+func rentersetclasscmd(path, class string) {
+	err := post("/renter/class/"+path, "class="+url.QueryEscape(class))
+	if err != nil {
+		die("Could not set the redundancy class of the file:", err)
+	}
+	if class == "" {
+		fmt.Printf("Removed the redundancy class of %v.\n", path)
+		return
+	}
+	fmt.Printf("Assigned %v to redundancy class %v.\n", path, class)
+}
+
+// renterverifycmd is the handler for the command `siac renter verify [path]`.
+// Challenges the hosts of a file to prove that they still store its pieces.
+func renterverifycmd(path string) {
+	var fv modules.FileVerification
+	if err := postResp("/renter/verify/"+path, "", &fv); err != nil {
+		die("Could not verify file:", err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Host\tPieces\tChallenges\tFailures\tStatus")
+	var failed int
+	for _, hv := range fv.Hosts {
+		status := "ok"
+		if hv.Error != "" {
+			status = hv.Error
+			failed++
+		} else if hv.Failures > 0 {
+			status = "FAILED"
+			failed++
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", hv.NetAddress, hv.Pieces, hv.Challenges, hv.Failures, status)
+	}
+	w.Flush()
+	fmt.Printf("\n%v of %v hosts failed verification.\n", failed, len(fv.Hosts))
+}
+
+// rentersnapshotscreatecmd is the handler for the command `siac renter
+// snapshots create`. Stores a snapshot of the renter on its hosts.
+func rentersnapshotscreatecmd() {
+	var s modules.RenterSnapshot
+	if err := postResp("/renter/snapshots", "", &s); err != nil {
+		die("Could not create a snapshot:", err)
+	}
+	fmt.Printf("Stored a snapshot of %v files on %v hosts.\n", s.NumFiles, len(s.Hosts))
+}
+
+// rentersnapshotsrecovercmd is the handler for the command `siac renter
+// snapshots recover`. Restores the renter from its most recent snapshot.
+func rentersnapshotsrecovercmd() {
+	var rl api.RenterLoad
+	if err := postResp("/renter/snapshots/recover", "", &rl); err != nil {
+		die("Could not recover the renter:", err)
+	}
+	fmt.Printf("Restored %v files:\n", len(rl.FilesAdded))
+	for _, siapath := range rl.FilesAdded {
+		fmt.Println("\t" + siapath)
+	}
+}
+
+// rentersharelinkcmd is the handler for the command `siac renter sharelink
+// [path]`. Prints a share link for the file at path.
+func rentersharelinkcmd(path string) {
+	var rsl api.RenterShareLink
+	if err := getAPI("/renter/sharelink/"+path, &rsl); err != nil {
+		die("Could not create a share link:", err)
+	}
+	fmt.Println(rsl.Link)
+}
+
+// renterdownloadlinkcmd is the handler for the command `siac renter
+// downloadlink [link] [destination]`. Downloads the file that link points to
+// to destination.
+func renterdownloadlinkcmd(link, destination string) {
+	destination = abs(destination)
+	resp, err := apiGet("/renter/link/" + link)
+	if err != nil {
+		die("Could not download file:", err)
+	}
+	defer resp.Body.Close()
+	f, err := os.Create(destination)
+	if err != nil {
+		die("Could not create destination:", err)
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		die("Could not download file:", err)
+	}
+	if err := f.Close(); err != nil {
+		die("Could not write destination:", err)
+	}
+	fmt.Println("Downloaded file to", destination)
+}
+
+// speedUnits returns a human readable representation of a rate limit.
+func speedUnits(speed int64) string {
+	if speed == 0 {
+		return "unlimited"
+	}
+	return filesizeUnits(speed) + "/s"
+}
+
+// parseSpeed converts a rate limit such as 2MB to a number of bytes per
+// second. 0 is unlimited.
+func parseSpeed(speed string) (string, error) {
+	if speed == "0" {
+		return speed, nil
+	}
+	return parseFilesize(speed)
+}
+
+// renterratelimitscmd displays the current bandwidth limits.
+func renterratelimitscmd() {
+	var rg api.RenterGET
+	err := getAPI("/renter", &rg)
+	if err != nil {
+		die("Could not get rate limits:", err)
+	}
+	limits := rg.Settings.RateLimits
+	fmt.Printf(`Rate limits:
+	Download:          %v
+	Upload:            %v
+	Download per host: %v
+	Upload per host:   %v
+`, speedUnits(limits.MaxDownloadSpeed), speedUnits(limits.MaxUploadSpeed),
+		speedUnits(limits.MaxHostDownloadSpeed), speedUnits(limits.MaxHostUploadSpeed))
+}
+
+// rentersetratelimitscmd allows the user to set the bandwidth limits.
+func rentersetratelimitscmd(download, upload string) {
+	speeds := []struct {
+		param, speed string
+	}{
+		{"maxdownloadspeed", download},
+		{"maxuploadspeed", upload},
+		{"maxhostdownloadspeed", limitHostDownload},
+		{"maxhostuploadspeed", limitHostUpload},
+	}
+	values := url.Values{}
+	for _, s := range speeds {
+		// Per-host limits that are not supplied keep their current value.
+		if s.speed == "" {
+			continue
+		}
+		bps, err := parseSpeed(s.speed)
+		if err != nil {
+			die("Could not parse "+s.param+":", err)
+		}
+		values.Set(s.param, bps)
+	}
+	err := post("/renter", values.Encode())
+	if err != nil {
+		die("Could not set rate limits:", err)
+	}
+	fmt.Println("Rate limits updated.")
+}
+
 // byValue sorts contracts by their value in siacoins, high to low. If two
 // contracts have the same value, they are sorted by their host's address.
 type byValue []api.RenterContract
@@ -432,6 +1220,72 @@ func (s bySiaPath) Len() int           { return len(s) }
 func (s bySiaPath) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
 func (s bySiaPath) Less(i, j int) bool { return s[i].SiaPath < s[j].SiaPath }
 
+// renterdircmd is the handler for the command `siac renter dir [path]`.
+// Displays a directory and the directories and files within it.
+func renterdircmd(path string) {
+	var rd api.RenterDirectory
+	err := getAPI("/renter/dir/"+strings.Trim(path, "/"), &rd)
+	if err != nil {
+		die("Could not get directory:", err)
+	}
+	dirName := func(siapath string) string {
+		return "/" + siapath
+	}
+	minRedundancy := func(r float64) string {
+		if r == -1 {
+			return "-"
+		}
+		return fmt.Sprintf("%.2f", r)
+	}
+	fmt.Printf("Directory %s: %v files, %s, minimum redundancy %s, %.2f%% uploaded\n",
+		dirName(rd.Directory.SiaPath), rd.Directory.NumFiles, filesizeUnits(int64(rd.Directory.Size)),
+		minRedundancy(rd.Directory.MinRedundancy), rd.Directory.UploadProgress)
+	if len(rd.Directories) == 0 && len(rd.Files) == 0 {
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Size\tFiles\tAvailable\tProgress\tRedundancy\tSia path")
+	for _, dir := range rd.Directories {
+		fmt.Fprintf(w, "%9s\t%v\t%s\t%.2f%%\t%s\t%s/\n", filesizeUnits(int64(dir.Size)), dir.NumFiles,
+			yesNo(dir.Available), dir.UploadProgress, minRedundancy(dir.MinRedundancy), dirName(dir.SiaPath))
+	}
+	for _, file := range rd.Files {
+		fmt.Fprintf(w, "%9s\t-\t%s\t%.2f%%\t%.2f\t%s\n", filesizeUnits(int64(file.Filesize)),
+			yesNo(file.Available), file.UploadProgress, file.Redundancy, dirName(file.SiaPath))
+	}
+	w.Flush()
+}
+
+// renterdircreatecmd is the handler for the command `siac renter dir create
+// [path]`. Creates an empty directory.
+func renterdircreatecmd(path string) {
+	err := post("/renter/dir/"+path, "action=create")
+	if err != nil {
+		die("Could not create directory:", err)
+	}
+	fmt.Printf("Created directory %s\n", path)
+}
+
+// renterdirdeletecmd is the handler for the command `siac renter dir delete
+// [path]`. Deletes a directory and everything within it.
+func renterdirdeletecmd(path string) {
+	err := post("/renter/dir/"+path, "action=delete")
+	if err != nil {
+		die("Could not delete directory:", err)
+	}
+	fmt.Printf("Deleted directory %s\n", path)
+}
+
+// renterdirrenamecmd is the handler for the command `siac renter dir rename
+// [path] [newpath]`. Renames a directory.
+func renterdirrenamecmd(path, newpath string) {
+	err := post("/renter/dir/"+path, "action=rename&newsiapath="+url.QueryEscape(newpath))
+	if err != nil {
+		die("Could not rename directory:", err)
+	}
+	fmt.Printf("Renamed directory %s to %s\n", path, newpath)
+}
+
 // renterfileslistcmd is the handler for the command `siac renter list`.
 // Lists files known to the renter on the network.
 func renterfileslistcmd() {
@@ -447,7 +1301,7 @@ func renterfileslistcmd() {
 	fmt.Println("Tracking", len(rf.Files), "files:")
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 	if renterListVerbose {
-		fmt.Fprintln(w, "File size\tAvailable\tProgress\tRedundancy\tRenewing\tSia path")
+		fmt.Fprintln(w, "File size\tAvailable\tProgress\tRedundancy\tHealth\tRenewing\tSia path")
 	}
 	sort.Sort(bySiaPath(rf.Files))
 	for _, file := range rf.Files {
@@ -463,11 +1317,20 @@ func renterfileslistcmd() {
 			if file.UploadProgress == -1 {
 				uploadProgressStr = "-"
 			}
-			fmt.Fprintf(w, "\t%s\t%8s\t%10s\t%s", availableStr, uploadProgressStr, redundancyStr, renewingStr)
+			healthStr := fmt.Sprintf("%.2f%%", file.Health)
+			fmt.Fprintf(w, "\t%s\t%8s\t%10s\t%7s\t%s", availableStr, uploadProgressStr, redundancyStr, healthStr, renewingStr)
 		}
 		fmt.Fprintf(w, "\t%s", file.SiaPath)
 		if !renterListVerbose && !file.Available {
 			fmt.Fprintf(w, " (uploading, %0.2f%%)", file.UploadProgress)
+		} else if file.Repairing && file.Available {
+			fmt.Fprintf(w, " (repairing, %0.2f%%)", file.RepairProgress)
+		}
+		if file.Converting {
+			fmt.Fprintf(w, " (converting to %v, %0.2f%%)", file.Class, file.ConversionProgress)
+		}
+		if file.AtRisk {
+			fmt.Fprint(w, " (at risk)")
 		}
 		fmt.Fprintln(w, "")
 	}
@@ -486,9 +1349,32 @@ func renterfilesrenamecmd(path, newpath string) {
 
 // renterfilesuploadcmd is the handler for the command `siac renter upload
 // [source] [path]`. Uploads the [source] file to [path] on the Sia network.
-// If [source] is a directory, all files inside it will be uploaded and named
-// relative to [path].
+// If [source] is a directory and --recursive is set, all files inside it
+// that pass the --include and --exclude patterns will be uploaded into the
+// directory [path], keeping the structure of its subdirectories.
 func renterfilesuploadcmd(source, path string) {
+	// uploadValues returns the parameters of the upload of file.
+	uploadValues := func(file string) string {
+		values := url.Values{}
+		values.Set("source", abs(file))
+		if uploadData != 0 || uploadParity != 0 {
+			values.Set("datapieces", strconv.Itoa(uploadData))
+			values.Set("paritypieces", strconv.Itoa(uploadParity))
+		}
+		if uploadClass != "" {
+			values.Set("class", uploadClass)
+		}
+		if uploadPieceSize != 0 {
+			values.Set("piecesize", strconv.FormatUint(uploadPieceSize, 10))
+		}
+		if uploadPriority != 0 {
+			values.Set("priority", strconv.Itoa(uploadPriority))
+		}
+		values["include"] = uploadInclude
+		values["exclude"] = uploadExclude
+		return values.Encode()
+	}
+
 	stat, err := os.Stat(source)
 	if err != nil {
 		die("Could not stat file or folder:", err)
@@ -496,36 +1382,21 @@ func renterfilesuploadcmd(source, path string) {
 
 	if stat.IsDir() {
 		// folder
-		var files []string
-		err := filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				fmt.Println("Warning: skipping file:", err)
-				return nil
-			}
-			if info.IsDir() {
-				return nil
-			}
-			files = append(files, path)
-			return nil
-		})
+		if !uploadRecursive {
+			die("Could not upload folder: use --recursive to upload the files inside", abs(source))
+		}
+		err = post("/renter/upload/"+path, uploadValues(source))
 		if err != nil {
-			die("Could not read folder:", err)
-		} else if len(files) == 0 {
-			die("Nothing to upload.")
-		}
-		for _, file := range files {
-			fpath, _ := filepath.Rel(source, file)
-			fpath = filepath.Join(path, fpath)
-			fpath = filepath.ToSlash(fpath)
-			err = post("/renter/upload/"+fpath, "source="+abs(file))
-			if err != nil {
-				die("Could not upload file:", err)
-			}
+			die("Could not upload folder:", err)
 		}
-		fmt.Printf("Uploaded %d files into '%s'.\n", len(files), path)
+		fmt.Printf("Uploading folder '%s' into '%s'.\n", abs(source), path)
+		uploadprogress(path)
 	} else {
 		// single file
-		err = post("/renter/upload/"+path, "source="+abs(source))
+		if len(uploadInclude) > 0 || len(uploadExclude) > 0 {
+			die("Could not upload file: --include and --exclude only apply to folders")
+		}
+		err = post("/renter/upload/"+path, uploadValues(source))
 		if err != nil {
 			die("Could not upload file:", err)
 		}
@@ -533,6 +1404,26 @@ func renterfilesuploadcmd(source, path string) {
 	}
 }
 
+// uploadprogress prints the aggregate upload progress of the files in the
+// directory siapath until all of them are fully uploaded. Interrupting it
+// does not stop the uploads.
+func uploadprogress(siapath string) {
+	fmt.Println("Uploads continue in the background if this command is interrupted.")
+	for range time.Tick(time.Second) {
+		var rd api.RenterDirectory
+		err := getAPI("/renter/dir/"+strings.Trim(siapath, "/"), &rd)
+		if err != nil {
+			continue // benign
+		}
+		d := rd.Directory
+		fmt.Printf("\rUploading... %5.1f%% of %v files (%v)    ", d.UploadProgress, d.NumFiles, filesizeUnits(int64(d.Size)))
+		if d.UploadProgress >= 100 {
+			break
+		}
+	}
+	fmt.Printf("\nUploaded all files into '%s'.\n", siapath)
+}
+
 // renterpricescmd is the handler for the command `siac renter prices`, which
 // displays the prices of various storage operations.
 func renterpricescmd() {
@@ -1,13 +1,24 @@
 package main
 
 import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"io"
 	"math/big"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/bgentry/speakeasy"
 	"github.com/spf13/cobra"
 
 	"github.com/NebulousLabs/Sia/api"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
 )
 
@@ -45,6 +56,60 @@ The smallest unit of siacoins is the hasting. One siacoin is 10^24 hastings. Oth
 		Run:   wrap(walletaddressescmd),
 	}
 
+	walletAddressBookCmd = &cobra.Command{
+		Use:   "addressbook",
+		Short: "List the named payee addresses",
+		Long: `List the named payee addresses of the address book. The names can be used
+in place of addresses when sending siacoins or siafunds.`,
+		Run: wrap(walletaddressbookcmd),
+	}
+
+	walletAddressBookAddCmd = &cobra.Command{
+		Use:   "add [name] [address]",
+		Short: "Add a named payee address",
+		Long:  "Add a named payee address to the address book, replacing any address of the same name.",
+		Run:   wrap(walletaddressbookaddcmd),
+	}
+
+	walletAddressBookRemoveCmd = &cobra.Command{
+		Use:   "remove [name]",
+		Short: "Remove a named payee address",
+		Long:  "Remove a named payee address from the address book.",
+		Run:   wrap(walletaddressbookremovecmd),
+	}
+
+	walletAutoLockCmd = &cobra.Command{
+		Use:   "autolock",
+		Short: "Show the auto-lock timer of the wallet",
+		Long:  "Show the time after which the wallet locks itself, and the time left before it does.",
+		Run:   wrap(walletautolockcmd),
+	}
+
+	walletAutoLockSetCmd = &cobra.Command{
+		Use:   "set [timeout]",
+		Short: "Set the auto-lock timeout of the wallet",
+		Long: `Set the time after which the wallet locks itself once it is unlocked, for
+example "10m" or "1h30m". A timeout of "0" disables the auto-lock.`,
+		Run: wrap(walletautolocksetcmd),
+	}
+
+	walletBackupCmd = &cobra.Command{
+		Use:   "backup [destination]",
+		Short: "Back up the wallet",
+		Long: `Write a backup archive of the wallet, with its seeds, keys, labels, address
+book and history, to a file. The wallet keeps running while the backup is taken.`,
+		Run: wrap(walletbackupcmd),
+	}
+
+	walletBumpFeeCmd = &cobra.Command{
+		Use:   "bumpfee [txid]",
+		Short: "Bump the fee of a stuck transaction",
+		Long: `Raise the fee of an unconfirmed transaction by spending its change in a
+child transaction that pays for both. Without a transaction ID, list the
+stuck transactions whose fee can be bumped.`,
+		Run: walletbumpfeecmd,
+	}
+
 	walletChangepasswordCmd = &cobra.Command{
 		Use:   "change-password",
 		Short: "Change the wallet password",
@@ -52,6 +117,33 @@ The smallest unit of siacoins is the hasting. One siacoin is 10^24 hastings. Oth
 		Run:   wrap(walletchangepasswordcmd),
 	}
 
+	walletsCmd = &cobra.Command{
+		Use:   "wallets",
+		Short: "List the named wallets",
+		Long: `List the named wallets of siad. Named wallets run alongside the main wallet,
+each with its own seed and password. Select a named wallet in wallet commands
+with the --wallet flag, for example 'siac wallet --wallet cold unlock'.`,
+		Run: wrap(walletslistcmd),
+	}
+
+	walletsCreateCmd = &cobra.Command{
+		Use:   "create [name]",
+		Short: "Create a named wallet",
+		Long: `Create a new named wallet. Names may contain letters, digits, '-' and '_'.
+Initialize the wallet with 'siac wallet --wallet [name] init'.`,
+		Run: wrap(walletscreatecmd),
+	}
+
+	walletExportCmd = &cobra.Command{
+		Use:   "export [csv|json]",
+		Short: "Export the transaction history",
+		Long: `Write the confirmed transaction history of the wallet to stdout in CSV or JSON
+format, for use with bookkeeping and tax software. Each transaction is listed
+with its timestamp, incoming and outgoing funds, miner fees, the addresses
+outside of the wallet that it involves, and the running balance of the wallet.`,
+		Run: wrap(walletexportcmd),
+	}
+
 	walletInitCmd = &cobra.Command{
 		Use:   "init",
 		Short: "Initialize and encrypt a new wallet",
@@ -60,11 +152,23 @@ By default the wallet encryption / unlock password is the same as the generated
 		Run: wrap(walletinitcmd),
 	}
 
+	walletGapLimitCmd = &cobra.Command{
+		Use:   "gaplimit [limit]",
+		Short: "Set the gap limit used when recovering a seed",
+		Long: `Set the number of unused addresses that are searched for beyond the last used
+address when scanning the blockchain for the addresses of a seed, as done by
+init-seed, load seed and sweep. Raise the gap limit if many addresses of the
+seed were handed out without being used.`,
+		Run: wrap(walletgaplimitcmd),
+	}
+
 	walletInitSeedCmd = &cobra.Command{
 		Use:   "init-seed",
 		Short: "Initialize and encrypt a new wallet using a pre-existing seed",
-		Long:  `Initialize and encrypt a new wallet using a pre-existing seed.`,
-		Run:   wrap(walletinitseedcmd),
+		Long: `Initialize and encrypt a new wallet using a pre-existing seed. The blockchain
+is scanned for the addresses of the seed, searching up to the gap limit set
+with 'wallet gaplimit' beyond the last used address.`,
+		Run: wrap(walletinitseedcmd),
 	}
 
 	walletLoadCmd = &cobra.Command{
@@ -96,6 +200,73 @@ By default the wallet encryption / unlock password is the same as the generated
 		Run:     wrap(walletloadsiagcmd),
 	}
 
+	walletLabelCmd = &cobra.Command{
+		Use:   "label [address] [label]",
+		Short: "Label an address of the wallet",
+		Long: `Label an address of the wallet. The labels are shown by 'siac wallet addresses'.
+An empty label ("") removes the label of the address.`,
+		Run: wrap(walletlabelcmd),
+	}
+
+	walletMemoCmd = &cobra.Command{
+		Use:   "memo [transactionid] [memo]",
+		Short: "Attach a memo to a transaction of the wallet",
+		Long: `Attach a note to a sent or received transaction of the wallet. Memos are only
+stored locally, and are shown by 'siac wallet transactions'. An empty memo ("")
+removes the memo of the transaction.`,
+		Run: wrap(walletmemocmd),
+	}
+
+	walletSignMessageCmd = &cobra.Command{
+		Use:   "sign-message [address] [message]",
+		Short: "Sign a message with a wallet address",
+		Long: `Sign a message with the key of a wallet address, proving that you control the
+address without moving any coins. The signature is printed as JSON and can be
+checked with 'siac wallet verify-message'.`,
+		Run: wrap(walletsignmessagecmd),
+	}
+
+	walletVerifyMessageCmd = &cobra.Command{
+		Use:   "verify-message [address] [message] [signature]",
+		Short: "Verify a message signed with an address",
+		Long: `Verify that a message was signed with the key of an address. 'signature' is the
+JSON printed by 'siac wallet sign-message'.`,
+		Run: wrap(walletverifymessagecmd),
+	}
+
+	walletSwapCmd = &cobra.Command{
+		Use:   "swap",
+		Short: "List the atomic swaps of the wallet",
+		Long: `List the atomic swaps tracked by the wallet, with their status and any known
+secret.`,
+		Run: wrap(walletswapcmd),
+	}
+
+	walletSwapCreateCmd = &cobra.Command{
+		Use:   "create [amount] [address] [timelock]",
+		Short: "Create an atomic swap",
+		Long: `Lock amount in a swap contract that pays [address] if the secret is revealed
+before the [timelock] height, and refunds the wallet otherwise. The wallet
+generates the secret unless --secrethash is given. The contract transaction is
+printed so that it can be sent to the counterparty for auditing.`,
+		Run: wrap(walletswapcreatecmd),
+	}
+
+	walletSwapAuditCmd = &cobra.Command{
+		Use:   "audit [transaction]",
+		Short: "Audit the contract of an atomic swap",
+		Long: `Print the terms of the swap contract in a transaction created by
+'siac wallet swap create'. Swaps that pay the wallet are tracked.`,
+		Run: wrap(walletswapauditcmd),
+	}
+
+	walletSwapRedeemCmd = &cobra.Command{
+		Use:   "redeem [id] [secret]",
+		Short: "Redeem an atomic swap",
+		Long:  "Redeem an atomic swap tracked by the wallet by revealing its secret.",
+		Run:   wrap(walletswapredeemcmd),
+	}
+
 	walletLockCmd = &cobra.Command{
 		Use:   "lock",
 		Short: "Lock the wallet",
@@ -121,22 +292,55 @@ By default the wallet encryption / unlock password is the same as the generated
 	walletSendSiacoinsCmd = &cobra.Command{
 		Use:   "siacoins [amount] [dest]",
 		Short: "Send siacoins to an address",
-		Long: `Send siacoins to an address. 'dest' must be a 76-byte hexadecimal address.
+		Long: `Send siacoins to an address. 'dest' must be a 76-byte hexadecimal address,
+or the name of an entry of the address book.
 'amount' can be specified in units, e.g. 1.23KS. Run 'wallet --help' for a list of units.
 If no unit is supplied, hastings will be assumed.
+With --minconfirmations, only outputs confirmed by at least that many blocks
+are spent. With --preview, the size, fee, inputs and change of the transaction
+are printed, but nothing is sent.
 
 A miner fee of 10 SC is levied on all transactions.`,
 		Run: wrap(walletsendsiacoinscmd),
 	}
 
+	walletSendBatchCmd = &cobra.Command{
+		Use:   "batch [file]",
+		Short: "Send siacoins to many addresses in one transaction",
+		Long: `Send siacoins to many addresses in a single transaction that pays a single
+miner fee. Each line of 'file' holds an amount and a destination, separated by
+whitespace, in the same format as 'wallet send siacoins'. Empty lines and lines
+starting with '#' are ignored.`,
+		Run: wrap(walletsendbatchcmd),
+	}
+
 	walletSendSiafundsCmd = &cobra.Command{
 		Use:   "siafunds [amount] [dest]",
 		Short: "Send siafunds",
 		Long: `Send siafunds to an address, and transfer the claim siacoins to your wallet.
+'dest' can also be the name of an entry of the address book.
 Run 'wallet send --help' to see a list of available units.`,
 		Run: wrap(walletsendsiafundscmd),
 	}
 
+	walletRestoreCmd = &cobra.Command{
+		Use:   "restore [source]",
+		Short: "Restore the wallet from a backup",
+		Long: `Replace the wallet with a backup created by 'siac wallet backup'. The
+wallet is unlocked with the password of the backup, and the blockchain is
+rescanned to rebuild its balance and history.`,
+		Run: wrap(walletrestorecmd),
+	}
+
+	walletRescanCmd = &cobra.Command{
+		Use:   "rescan [height]",
+		Short: "Rebuild the wallet's balance and history",
+		Long: `Rebuild the outputs of the wallet and its transaction history from 'height'
+onwards, keeping the history below it. A rescan from height 0 rebuilds all of
+the history. The wallet's files are not deleted.`,
+		Run: wrap(walletrescancmd),
+	}
+
 	walletSweepCmd = &cobra.Command{
 		Use:   "sweep",
 		Short: "Sweep siacoins and siafunds from a seed.",
@@ -152,6 +356,52 @@ will be sent to your wallet.`,
 		Run:   wrap(walletbalancecmd),
 	}
 
+	walletTemplatesCmd = &cobra.Command{
+		Use:   "templates",
+		Short: "List the multisig templates",
+		Long: `List the multisig templates of the wallet. A template saves the parameters of
+a recurring multisig setup, from which new addresses can be generated.`,
+		Run: wrap(wallettemplatescmd),
+	}
+
+	walletTemplatesAddCmd = &cobra.Command{
+		Use:   "add [name] [signaturesrequired] [publickeys]",
+		Short: "Add a multisig template",
+		Long: `Add a multisig template, replacing any template of the same name. 'publickeys'
+is a comma-separated list of the public keys of the cosigners, in the form
+ed25519:<hex key>. Each address generated from the template requires
+'signaturesrequired' signatures from a new wallet key and the cosigners.`,
+		Run: wrap(wallettemplatesaddcmd),
+	}
+
+	walletTemplatesAddressCmd = &cobra.Command{
+		Use:   "address [name]",
+		Short: "Generate an address from a multisig template",
+		Long: `Generate a new multisig address from a template, using a new wallet key. The
+printed unlock conditions must be shared with the cosigners.`,
+		Run: wrap(wallettemplatesaddresscmd),
+	}
+
+	walletTemplatesRemoveCmd = &cobra.Command{
+		Use:   "remove [name]",
+		Short: "Remove a multisig template",
+		Long:  "Remove a multisig template. Addresses generated from it are not affected.",
+		Run:   wrap(wallettemplatesremovecmd),
+	}
+
+	walletTimelockCmd = &cobra.Command{
+		Use:   "timelock [height]",
+		Short: "Get an address that cannot be spent before a height",
+		Long: `Generate a new wallet address that cannot be spent before 'height'. Coins and
+funds sent to the address are part of the wallet's balance, but are shown as
+timelocked by 'wallet balance' until the height is reached. The address cannot
+be recovered from the seed, so keep the printed unlock conditions.
+
+With --pubkey, the address is that of another party's public key instead, for
+sending them coins or funds that vest at 'height'.`,
+		Run: wrap(wallettimelockcmd),
+	}
+
 	walletTransactionsCmd = &cobra.Command{
 		Use:   "transactions",
 		Short: "View transactions",
@@ -183,15 +433,167 @@ func walletaddresscmd() {
 	fmt.Printf("Created new address: %s\n", addr.Address)
 }
 
-// walletaddressescmd fetches the list of addresses that the wallet knows.
+// walletaddressescmd fetches the list of addresses that the wallet knows,
+// along with their labels.
 func walletaddressescmd() {
 	addrs := new(api.WalletAddressesGET)
 	err := getAPI("/wallet/addresses", addrs)
 	if err != nil {
 		die("Failed to fetch addresses:", err)
 	}
+	var wlg api.WalletLabelsGET
+	err = getAPI("/wallet/labels", &wlg)
+	if err != nil {
+		die("Failed to fetch address labels:", err)
+	}
+	labels := make(map[types.UnlockHash]string)
+	for _, l := range wlg.Labels {
+		labels[l.Address] = l.Label
+	}
 	for _, addr := range addrs.Addresses {
-		fmt.Println(addr)
+		if label, exists := labels[addr]; exists {
+			fmt.Println(addr, label)
+		} else {
+			fmt.Println(addr)
+		}
+	}
+}
+
+// walletaddressbookcmd lists the named payee addresses of the address book.
+func walletaddressbookcmd() {
+	var book api.WalletAddressBookGET
+	err := getAPI("/wallet/addressbook", &book)
+	if err != nil {
+		die("Could not get address book:", err)
+	}
+	if len(book.Entries) == 0 {
+		fmt.Println("The address book is empty.")
+		return
+	}
+	for _, entry := range book.Entries {
+		fmt.Println(entry.Address, entry.Name)
+	}
+}
+
+// walletaddressbookaddcmd adds a named payee address to the address book.
+func walletaddressbookaddcmd(name, addr string) {
+	values := url.Values{}
+	values.Set("name", name)
+	values.Set("address", addr)
+	err := post("/wallet/addressbook", values.Encode())
+	if err != nil {
+		die("Could not add address book entry:", err)
+	}
+	fmt.Printf("Added %v to the address book as '%v'\n", addr, name)
+}
+
+// walletaddressbookremovecmd removes a named payee address from the address
+// book.
+func walletaddressbookremovecmd(name string) {
+	values := url.Values{}
+	values.Set("name", name)
+	values.Set("remove", "true")
+	err := post("/wallet/addressbook", values.Encode())
+	if err != nil {
+		die("Could not remove address book entry:", err)
+	}
+	fmt.Printf("Removed '%v' from the address book\n", name)
+}
+
+// walletlabelcmd labels an address of the wallet.
+func walletlabelcmd(addr, label string) {
+	values := url.Values{}
+	values.Set("address", addr)
+	values.Set("label", label)
+	err := post("/wallet/labels", values.Encode())
+	if err != nil {
+		die("Could not label address:", err)
+	}
+}
+
+// walletmemocmd attaches a memo to a transaction of the wallet.
+func walletmemocmd(txid, memo string) {
+	values := url.Values{}
+	values.Set("transactionid", txid)
+	values.Set("memo", memo)
+	err := post("/wallet/memos", values.Encode())
+	if err != nil {
+		die("Could not set memo:", err)
+	}
+}
+
+// resolveAddress returns dest if it is an address, and otherwise the address
+// of the address book entry named dest.
+func resolveAddress(dest string) string {
+	var uh types.UnlockHash
+	if uh.LoadString(dest) == nil {
+		return dest
+	}
+	var book api.WalletAddressBookGET
+	err := getAPI("/wallet/addressbook", &book)
+	if err != nil {
+		die("Could not get address book:", err)
+	}
+	for _, entry := range book.Entries {
+		if entry.Name == dest {
+			return entry.Address.String()
+		}
+	}
+	die("'" + dest + "' is neither an address nor the name of an address book entry")
+	return ""
+}
+
+// walletbackupcmd writes a backup archive of the wallet to destination.
+func walletbackupcmd(destination string) {
+	destination = abs(destination)
+	err := get("/wallet/backup?destination=" + url.QueryEscape(destination))
+	if err != nil {
+		die("Could not back up wallet:", err)
+	}
+	fmt.Println("Wrote wallet backup to", destination)
+}
+
+// walletrestorecmd replaces the wallet with the backup at source.
+func walletrestorecmd(source string) {
+	password, err := speakeasy.Ask("Backup password: ")
+	if err != nil {
+		die("Reading password failed:", err)
+	}
+	fmt.Println("Restoring the wallet and rescanning the blockchain. This may take a while...")
+	vals := url.Values{"source": {abs(source)}, "encryptionpassword": {password}}
+	if err := post("/wallet/restore", vals.Encode()); err != nil {
+		die("Could not restore wallet:", err)
+	}
+	fmt.Println("Wallet restored and unlocked")
+}
+
+// walletbumpfeecmd bumps the fee of a stuck transaction, or lists the
+// transactions whose fee can be bumped.
+func walletbumpfeecmd(cmd *cobra.Command, args []string) {
+	switch len(args) {
+	case 0:
+		var wbg api.WalletBumpFeeGET
+		if err := getAPI("/wallet/bumpfee", &wbg); err != nil {
+			die("Could not get stuck transactions:", err)
+		}
+		if len(wbg.TransactionIDs) == 0 {
+			fmt.Println("No transactions are stuck.")
+			return
+		}
+		fmt.Println("Stuck transactions:")
+		for _, txid := range wbg.TransactionIDs {
+			fmt.Println("\t", txid)
+		}
+	case 1:
+		var wbp api.WalletBumpFeePOST
+		if err := postResp("/wallet/bumpfee", "id="+args[0], &wbp); err != nil {
+			die("Could not bump fee:", err)
+		}
+		fmt.Println("Submitted a child transaction paying the fee of", args[0]+":")
+		fmt.Println("\t", wbp.TransactionIDs[len(wbp.TransactionIDs)-1])
+	default:
+		cmd.UsageFunc()(cmd)
+		os.Exit(exitCodeUsage)
 	}
 }
 
@@ -213,10 +615,136 @@ func walletchangepasswordcmd() {
 	fmt.Println("Password changed sucessfully.")
 }
 
+// walletexportcmd writes the wallet history to stdout in the given format.
+func walletexportcmd(format string) {
+	if format != "csv" && format != "json" {
+		die("Format must be 'csv' or 'json'")
+	}
+	resp, err := apiGet("/wallet/history?format=" + format)
+	if err != nil {
+		die("Could not fetch transaction history:", err)
+	}
+	defer resp.Body.Close()
+	if _, err := io.Copy(os.Stdout, resp.Body); err != nil {
+		die("Could not write transaction history:", err)
+	}
+}
+
+// walletgaplimitcmd sets the gap limit used when scanning for the addresses of
+// a seed.
+func walletgaplimitcmd(limit string) {
+	if err := post("/wallet/gaplimit", "gaplimit="+limit); err != nil {
+		die("Could not set gap limit:", err)
+	}
+	fmt.Println("Gap limit set to", limit)
+}
+
+// walletsignmessagecmd signs a message with a wallet address and prints the
+// signature.
+func walletsignmessagecmd(addr, message string) {
+	var wmsp api.WalletMessageSignPOST
+	vals := url.Values{"address": {resolveAddress(addr)}, "message": {message}}
+	if err := postResp("/wallet/message/sign", vals.Encode(), &wmsp); err != nil {
+		die("Could not sign message:", err)
+	}
+	sig, err := json.Marshal(wmsp.Signature)
+	if err != nil {
+		die("Could not encode signature:", err)
+	}
+	fmt.Println(string(sig))
+}
+
+// walletverifymessagecmd verifies a message signed with an address.
+func walletverifymessagecmd(addr, message, signature string) {
+	var wmvp api.WalletMessageVerifyPOST
+	vals := url.Values{"address": {resolveAddress(addr)}, "message": {message}, "signature": {signature}}
+	if err := postResp("/wallet/message/verify", vals.Encode(), &wmvp); err != nil {
+		die("Could not verify message:", err)
+	}
+	if !wmvp.Valid {
+		die("Signature is not valid")
+	}
+	fmt.Println("Signature is valid")
+}
+
+// printSwap prints the terms and status of an atomic swap.
+func printSwap(swap modules.AtomicSwap) {
+	fmt.Printf(`ID:             %v
+Status:         %v
+Value:          %v
+Secret hash:    %v
+Redeem address: %v
+Refund address: %v
+Window start:   %v
+Timelock:       %v
+`, swap.ID, swap.Status, currencyUnits(swap.Value), swap.SecretHash, swap.RedeemAddress, swap.RefundAddress, swap.WindowStart, swap.Timelock)
+	if swap.Secret != (crypto.Hash{}) {
+		fmt.Println("Secret:        ", swap.Secret)
+	}
+}
+
+// walletswapcmd lists the atomic swaps of the wallet.
+func walletswapcmd() {
+	var wsg api.WalletSwapsGET
+	if err := getAPI("/wallet/swaps", &wsg); err != nil {
+		die("Could not get swaps:", err)
+	}
+	if len(wsg.Swaps) == 0 {
+		fmt.Println("The wallet has no swaps.")
+		return
+	}
+	for i, swap := range wsg.Swaps {
+		if i > 0 {
+			fmt.Println()
+		}
+		printSwap(swap)
+	}
+}
+
+// walletswapcreatecmd creates an atomic swap.
+func walletswapcreatecmd(amount, addr, timelock string) {
+	hastings, err := parseCurrency(amount)
+	if err != nil {
+		die("Could not parse amount:", err)
+	}
+	vals := url.Values{
+		"amount":        {hastings},
+		"redeemaddress": {resolveAddress(addr)},
+		"timelock":      {timelock},
+		"secrethash":    {swapSecretHash},
+	}
+	var wscp api.WalletSwapCreatePOST
+	if err := postResp("/wallet/swap/create", vals.Encode(), &wscp); err != nil {
+		die("Could not create swap:", err)
+	}
+	printSwap(wscp.Swap)
+	fmt.Println()
+	fmt.Println("Contract transaction:")
+	fmt.Println(base64.StdEncoding.EncodeToString(wscp.Transaction))
+}
+
+// walletswapauditcmd prints the terms of the swap contract in a transaction.
+func walletswapauditcmd(txn string) {
+	var wsap api.WalletSwapAuditPOST
+	if err := postResp("/wallet/swap/audit", url.Values{"transaction": {txn}}.Encode(), &wsap); err != nil {
+		die("Could not audit swap:", err)
+	}
+	printSwap(wsap.Swap)
+}
+
+// walletswapredeemcmd redeems an atomic swap.
+func walletswapredeemcmd(id, secret string) {
+	vals := url.Values{"id": {id}, "secret": {secret}}
+	if err := post("/wallet/swap/redeem", vals.Encode()); err != nil {
+		die("Could not redeem swap:", err)
+	}
+	fmt.Println("Submitted the redemption of swap", id)
+}
+
 // walletinitcmd encrypts the wallet with the given password
 func walletinitcmd() {
 	var er api.WalletInitPOST
-	qs := fmt.Sprintf("dictionary=%s", "english")
+	qs := fmt.Sprintf("dictionary=%s", seedDictionary)
 	if initPassword {
 		password, err := speakeasy.Ask("Wallet password: ")
 		if err != nil {
@@ -231,7 +759,9 @@ func walletinitcmd() {
 	if err != nil {
 		die("Error when encrypting wallet:", err)
 	}
-	fmt.Printf("Recovery seed:\n%s\n\n", er.PrimarySeed)
+	fmt.Println("Recovery seed:")
+	printSeed(er.PrimarySeed)
+	fmt.Println()
 	if initPassword {
 		fmt.Printf("Wallet encrypted with given password\n")
 	} else {
@@ -245,7 +775,7 @@ func walletinitseedcmd() {
 	if err != nil {
 		die("Reading seed failed:", err)
 	}
-	qs := fmt.Sprintf("&seed=%s&dictionary=%s", seed, "english")
+	qs := fmt.Sprintf("&seed=%s", seed)
 	if initPassword {
 		password, err := speakeasy.Ask("Wallet password: ")
 		if err != nil {
@@ -291,7 +821,7 @@ func walletloadseedcmd() {
 	if err != nil {
 		die("Reading password failed:", err)
 	}
-	qs := fmt.Sprintf("encryptionpassword=%s&seed=%s&dictionary=%s", password, seed, "english")
+	qs := fmt.Sprintf("encryptionpassword=%s&seed=%s", password, seed)
 	err = post("/wallet/seed", qs)
 	if err != nil {
 		die("Could not add seed:", err)
@@ -321,15 +851,24 @@ func walletlockcmd() {
 	}
 }
 
+// walletrescancmd rebuilds the wallet's outputs and history from a height.
+func walletrescancmd(height string) {
+	err := post("/wallet/rescan", "startheight="+height)
+	if err != nil {
+		die("Could not rescan wallet:", err)
+	}
+	fmt.Println("Wallet rescan complete.")
+}
+
 // walletseedcmd returns the current seed {
 func walletseedscmd() {
 	var seedInfo api.WalletSeedsGET
-	err := getAPI("/wallet/seeds", &seedInfo)
+	err := getAPI("/wallet/seeds?dictionary="+seedDictionary, &seedInfo)
 	if err != nil {
 		die("Error retrieving the current seed:", err)
 	}
 	fmt.Println("Primary Seed:")
-	fmt.Println(seedInfo.PrimarySeed)
+	printSeed(seedInfo.PrimarySeed)
 	if len(seedInfo.AllSeeds) == 1 {
 		// AllSeeds includes the primary seed
 		return
@@ -341,8 +880,24 @@ func walletseedscmd() {
 			continue
 		}
 		fmt.Println() // extra newline for readability
-		fmt.Println(seed)
+		printSeed(seed)
+	}
+}
+
+// printSeed prints a seed phrase on a single line, followed by its words in
+// numbered rows, which are easier to copy onto paper and to check against.
+// The seed can be entered again in either form.
+func printSeed(seed string) {
+	fmt.Println(seed)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	words := strings.Fields(seed)
+	for i, word := range words {
+		fmt.Fprintf(w, "%2d. %s\t", i+1, word)
+		if i%5 == 4 || i == len(words)-1 {
+			fmt.Fprintln(w)
+		}
 	}
+	w.Flush()
 }
 
 // walletsendsiacoinscmd sends siacoins to a destination address.
@@ -351,15 +906,96 @@ func walletsendsiacoinscmd(amount, dest string) {
 	if err != nil {
 		die("Could not parse amount:", err)
 	}
-	err = post("/wallet/siacoins", fmt.Sprintf("amount=%s&destination=%s", hastings, dest))
+	dest = resolveAddress(dest)
+	query := fmt.Sprintf("amount=%s&destination=%s", hastings, dest)
+	if minConfirmations != 0 {
+		query += fmt.Sprintf("&minconfirmations=%d", minConfirmations)
+	}
+	if sendPreview {
+		var wspp api.WalletSiacoinsPreviewPOST
+		err = postResp("/wallet/siacoins", query+"&preview=true", &wspp)
+		if err != nil {
+			die("Could not preview transaction:", err)
+		}
+		fmt.Printf(`Sending %s hastings to %s would create %v bytes of transactions.
+Fee:    %s
+Change: %s
+Inputs:
+`, hastings, dest, wspp.Size, currencyUnits(wspp.Fee), currencyUnits(wspp.Change))
+		for _, id := range wspp.Inputs {
+			fmt.Println("\t", id)
+		}
+		return
+	}
+	err = post("/wallet/siacoins", query)
 	if err != nil {
 		die("Could not send siacoins:", err)
 	}
 	fmt.Printf("Sent %s hastings to %s\n", hastings, dest)
 }
 
+// walletsendbatchcmd sends siacoins to the destinations listed in a file using
+// a single transaction.
+func walletsendbatchcmd(path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		die("Could not open file:", err)
+	}
+	defer f.Close()
+
+	var outputs []types.SiacoinOutput
+	total := types.ZeroCurrency
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			die(fmt.Sprintf("Line %v: expected an amount and a destination", line))
+		}
+		hastings, err := parseCurrency(fields[0])
+		if err != nil {
+			die(fmt.Sprintf("Line %v: could not parse amount:", line), err)
+		}
+		var value types.Currency
+		if _, err := fmt.Sscan(hastings, &value); err != nil {
+			die(fmt.Sprintf("Line %v: could not parse amount:", line), err)
+		}
+		var dest types.UnlockHash
+		if err := dest.LoadString(resolveAddress(fields[1])); err != nil {
+			die(fmt.Sprintf("Line %v: could not parse destination:", line), err)
+		}
+		outputs = append(outputs, types.SiacoinOutput{Value: value, UnlockHash: dest})
+		total = total.Add(value)
+	}
+	if err := scanner.Err(); err != nil {
+		die("Could not read file:", err)
+	}
+	if len(outputs) == 0 {
+		die("File does not list any destinations")
+	}
+
+	outputsJSON, err := json.Marshal(outputs)
+	if err != nil {
+		die("Could not encode outputs:", err)
+	}
+	values := url.Values{}
+	values.Set("outputs", string(outputsJSON))
+	var resp api.WalletSiacoinsPOST
+	if err := postResp("/wallet/siacoins", values.Encode(), &resp); err != nil {
+		die("Could not send siacoins:", err)
+	}
+	fmt.Printf("Sent %v to %v addresses\n", currencyUnits(total), len(outputs))
+	for _, id := range resp.TransactionIDs {
+		fmt.Println(id)
+	}
+}
+
 // walletsendsiafundscmd sends siafunds to a destination address.
 func walletsendsiafundscmd(amount, dest string) {
+	dest = resolveAddress(dest)
 	err := post("/wallet/siafunds", fmt.Sprintf("amount=%s&destination=%s", amount, dest))
 	if err != nil {
 		die("Could not send siafunds:", err)
@@ -391,6 +1027,12 @@ Unlock the wallet to view balance
 		return
 	}
 
+	var balance api.WalletBalanceGET
+	err = getAPI("/wallet/balance", &balance)
+	if err != nil {
+		die("Could not get balance breakdown:", err)
+	}
+
 	unconfirmedBalance := status.ConfirmedSiacoinBalance.Add(status.UnconfirmedIncomingSiacoins).Sub(status.UnconfirmedOutgoingSiacoins)
 	var delta string
 	if unconfirmedBalance.Cmp(status.ConfirmedSiacoinBalance) >= 0 {
@@ -403,14 +1045,33 @@ Unlock the wallet to view balance
 %s, Unlocked
 Confirmed Balance:   %v
 Unconfirmed Delta:  %v
+Maturing:            %v (%v outputs)
 Exact:               %v H
 Siafunds:            %v SF
 Siafund Claims:      %v H
 
 Estimated Fee:       %v / KB
 `, encStatus, currencyUnits(status.ConfirmedSiacoinBalance), delta,
+		currencyUnits(balance.Maturing), len(balance.MaturingOutputs),
 		status.ConfirmedSiacoinBalance, status.SiafundBalance, status.SiacoinClaimBalance,
 		fees.Maximum.Mul64(1e3).HumanString())
+
+	var timelocked api.WalletTimelockedGET
+	err = getAPI("/wallet/timelocked", &timelocked)
+	if err != nil {
+		die("Could not get timelocked balances:", err)
+	}
+	if len(timelocked.Balances) == 0 {
+		return
+	}
+	fmt.Println()
+	fmt.Println("Timelocked:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  Unlock Height\tSiacoins\tSiafunds")
+	for _, tb := range timelocked.Balances {
+		fmt.Fprintf(w, "  %v\t%v\t%v SF\n", tb.UnlockHeight, currencyUnits(tb.Siacoins), tb.Siafunds)
+	}
+	w.Flush()
 }
 
 // walletsweepcmd sweeps coins and funds from a seed.
@@ -421,13 +1082,129 @@ func walletsweepcmd() {
 	}
 
 	var swept api.WalletSweepPOST
-	err = postResp("/wallet/sweep/seed", fmt.Sprintf("seed=%s&dictionary=%s", seed, "english"), &swept)
+	err = postResp("/wallet/sweep/seed", fmt.Sprintf("seed=%s", seed), &swept)
 	if err != nil {
 		die("Could not sweep seed:", err)
 	}
 	fmt.Printf("Swept %v and %v SF from seed.\n", currencyUnits(swept.Coins), swept.Funds)
 }
 
+// wallettimelockcmd prints a new timelocked address and its unlock
+// conditions.
+func wallettimelockcmd(height string) {
+	qs := "timelock=" + height
+	if timelockPubkey != "" {
+		qs += "&publickey=" + timelockPubkey
+	}
+	var wta api.WalletTimelockedAddressPOST
+	err := postResp("/wallet/timelocked/address", qs, &wta)
+	if err != nil {
+		die("Could not generate timelocked address:", err)
+	}
+	ucJSON, err := json.MarshalIndent(wta.UnlockConditions, "", "  ")
+	if err != nil {
+		die("Could not encode unlock conditions:", err)
+	}
+	fmt.Printf("Address: %v\nUnlock conditions:\n%s\n", wta.Address, ucJSON)
+}
+
+// walletautolockcmd prints the auto-lock timer of the wallet.
+func walletautolockcmd() {
+	var wag api.WalletAutoLockGET
+	err := getAPI("/wallet/autolock", &wag)
+	if err != nil {
+		die("Could not get auto-lock timer:", err)
+	}
+	if wag.Timeout == 0 {
+		fmt.Println("Auto-lock: disabled")
+	} else {
+		fmt.Println("Auto-lock:", time.Duration(wag.Timeout)*time.Second)
+	}
+	if wag.TimeUntilLock != 0 {
+		fmt.Println("Locks in:", time.Duration(wag.TimeUntilLock)*time.Second)
+	}
+	if wag.LastAutoLock != 0 {
+		fmt.Println("Last auto-lock:", time.Unix(int64(wag.LastAutoLock), 0).Format(time.RFC1123))
+	}
+}
+
+// walletautolocksetcmd sets the auto-lock timeout of the wallet.
+func walletautolocksetcmd(timeout string) {
+	d, err := time.ParseDuration(timeout)
+	if err != nil || d < 0 {
+		die("Could not parse timeout:", timeout)
+	}
+	err = post("/wallet/autolock", "timeout="+fmt.Sprint(uint64(d/time.Second)))
+	if err != nil {
+		die("Could not set auto-lock timeout:", err)
+	}
+	if d == 0 {
+		fmt.Println("Auto-lock disabled")
+	} else {
+		fmt.Println("Auto-lock timeout set to", d)
+	}
+}
+
+// wallettemplatescmd lists the multisig templates of the wallet.
+func wallettemplatescmd() {
+	var wtg api.WalletTemplatesGET
+	err := getAPI("/wallet/templates", &wtg)
+	if err != nil {
+		die("Could not get multisig templates:", err)
+	}
+	if len(wtg.Templates) == 0 {
+		fmt.Println("The wallet has no multisig templates.")
+		return
+	}
+	for _, t := range wtg.Templates {
+		fmt.Printf("%v: %v of %v signatures\n", t.Name, t.SignaturesRequired, len(t.Cosigners)+1)
+		for _, spk := range t.Cosigners {
+			fmt.Println("\t", spk)
+		}
+	}
+}
+
+// wallettemplatesaddcmd adds a multisig template to the wallet.
+func wallettemplatesaddcmd(name, signaturesRequired, publicKeys string) {
+	values := url.Values{}
+	values.Set("name", name)
+	values.Set("signaturesrequired", signaturesRequired)
+	values.Set("publickeys", publicKeys)
+	err := post("/wallet/templates", values.Encode())
+	if err != nil {
+		die("Could not add multisig template:", err)
+	}
+	fmt.Printf("Added multisig template '%v'\n", name)
+}
+
+// wallettemplatesaddresscmd generates a multisig address from a template.
+func wallettemplatesaddresscmd(name string) {
+	values := url.Values{}
+	values.Set("name", name)
+	var wtap api.WalletTemplatesAddressPOST
+	err := postResp("/wallet/templates/address", values.Encode(), &wtap)
+	if err != nil {
+		die("Could not generate multisig address:", err)
+	}
+	ucJSON, err := json.MarshalIndent(wtap.UnlockConditions, "", "  ")
+	if err != nil {
+		die("Could not encode unlock conditions:", err)
+	}
+	fmt.Printf("Address: %v\nUnlock conditions:\n%s\n", wtap.Address, ucJSON)
+}
+
+// wallettemplatesremovecmd removes a multisig template from the wallet.
+func wallettemplatesremovecmd(name string) {
+	values := url.Values{}
+	values.Set("name", name)
+	values.Set("remove", "true")
+	err := post("/wallet/templates", values.Encode())
+	if err != nil {
+		die("Could not remove multisig template:", err)
+	}
+	fmt.Printf("Removed multisig template '%v'\n", name)
+}
+
 // wallettransactionscmd lists all of the transactions related to the wallet,
 // providing a net flow of siacoins and siafunds for each.
 func wallettransactionscmd() {
@@ -437,39 +1214,21 @@ func wallettransactionscmd() {
 		die("Could not fetch transaction history:", err)
 	}
 
+	memos := make(map[types.TransactionID]string)
+	for _, tm := range wtg.Memos {
+		memos[tm.TransactionID] = tm.Memo
+	}
+
 	fmt.Println("    [height]                                                   [transaction id]    [net siacoins]   [net siafunds]")
 	txns := append(wtg.ConfirmedTransactions, wtg.UnconfirmedTransactions...)
 	for _, txn := range txns {
-		// Determine the number of outgoing siacoins and siafunds.
-		var outgoingSiacoins types.Currency
-		var outgoingSiafunds types.Currency
-		for _, input := range txn.Inputs {
-			if input.FundType == types.SpecifierSiacoinInput && input.WalletAddress {
-				outgoingSiacoins = outgoingSiacoins.Add(input.Value)
-			}
-			if input.FundType == types.SpecifierSiafundInput && input.WalletAddress {
-				outgoingSiafunds = outgoingSiafunds.Add(input.Value)
-			}
-		}
-
-		// Determine the number of incoming siacoins and siafunds.
-		var incomingSiacoins types.Currency
-		var incomingSiafunds types.Currency
-		for _, output := range txn.Outputs {
-			if output.FundType == types.SpecifierMinerPayout {
-				incomingSiacoins = incomingSiacoins.Add(output.Value)
-			}
-			if output.FundType == types.SpecifierSiacoinOutput && output.WalletAddress {
-				incomingSiacoins = incomingSiacoins.Add(output.Value)
-			}
-			if output.FundType == types.SpecifierSiafundOutput && output.WalletAddress {
-				incomingSiafunds = incomingSiafunds.Add(output.Value)
-			}
-		}
+		// Determine the number of incoming and outgoing siacoins and
+		// siafunds.
+		summary := txn.Summary()
 
 		// Convert the siacoins to a float.
-		incomingSiacoinsFloat, _ := new(big.Rat).SetFrac(incomingSiacoins.Big(), types.SiacoinPrecision.Big()).Float64()
-		outgoingSiacoinsFloat, _ := new(big.Rat).SetFrac(outgoingSiacoins.Big(), types.SiacoinPrecision.Big()).Float64()
+		incomingSiacoinsFloat, _ := new(big.Rat).SetFrac(summary.IncomingSiacoins.Big(), types.SiacoinPrecision.Big()).Float64()
+		outgoingSiacoinsFloat, _ := new(big.Rat).SetFrac(summary.OutgoingSiacoins.Big(), types.SiacoinPrecision.Big()).Float64()
 
 		// Print the results.
 		if txn.ConfirmationHeight < 1e9 {
@@ -479,12 +1238,41 @@ func wallettransactionscmd() {
 		}
 		fmt.Printf("%67v%15.2f SC", txn.TransactionID, incomingSiacoinsFloat-outgoingSiacoinsFloat)
 		// For siafunds, need to avoid having a negative types.Currency.
-		if incomingSiafunds.Cmp(outgoingSiafunds) >= 0 {
-			fmt.Printf("%14v SF\n", incomingSiafunds.Sub(outgoingSiafunds))
+		if summary.IncomingSiafunds.Cmp(summary.OutgoingSiafunds) >= 0 {
+			fmt.Printf("%14v SF\n", summary.IncomingSiafunds.Sub(summary.OutgoingSiafunds))
 		} else {
-			fmt.Printf("-%14v SF\n", outgoingSiafunds.Sub(incomingSiafunds))
+			fmt.Printf("-%14v SF\n", summary.OutgoingSiafunds.Sub(summary.IncomingSiafunds))
 		}
+		if memo, ok := memos[txn.TransactionID]; ok {
+			fmt.Printf("%12v %v\n", "", memo)
+		}
+	}
+}
+
+// walletslistcmd lists the named wallets.
+func walletslistcmd() {
+	var wg api.WalletsGET
+	if err := getAPI("/wallets", &wg); err != nil {
+		die("Could not get named wallets:", err)
+	}
+	if len(wg.Wallets) == 0 {
+		fmt.Println("No named wallets.")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Name\tEncrypted\tUnlocked")
+	for _, nw := range wg.Wallets {
+		fmt.Fprintf(w, "%v\t%v\t%v\n", nw.Name, yesNo(nw.Encrypted), yesNo(nw.Unlocked))
+	}
+	w.Flush()
+}
+
+// walletscreatecmd creates a named wallet.
+func walletscreatecmd(name string) {
+	if err := post("/wallets/create", "name="+url.QueryEscape(name)); err != nil {
+		die("Could not create wallet:", err)
 	}
+	fmt.Printf("Created wallet %v. Initialize it with 'siac wallet --wallet %v init'.\n", name, name)
 }
 
 // walletunlockcmd unlocks a saved wallet
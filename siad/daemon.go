@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -14,14 +15,17 @@ import (
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/modules/consensus"
+	"github.com/NebulousLabs/Sia/modules/davserver"
 	"github.com/NebulousLabs/Sia/modules/explorer"
 	"github.com/NebulousLabs/Sia/modules/gateway"
 	"github.com/NebulousLabs/Sia/modules/host"
 	"github.com/NebulousLabs/Sia/modules/miner"
 	"github.com/NebulousLabs/Sia/modules/renter"
+	"github.com/NebulousLabs/Sia/modules/s3gateway"
 	"github.com/NebulousLabs/Sia/modules/transactionpool"
 	"github.com/NebulousLabs/Sia/modules/wallet"
 	"github.com/NebulousLabs/Sia/profile"
+	"github.com/NebulousLabs/Sia/types"
 
 	"github.com/bgentry/speakeasy"
 	"github.com/spf13/cobra"
@@ -51,6 +55,30 @@ func verifyAPISecurity(config Config) error {
 	return nil
 }
 
+// verifyS3Security checks that the S3 gateway either authenticates requests
+// or only listens on the loopback address.
+func verifyS3Security(config Config) error {
+	if !strings.Contains(config.Siad.Modules, "s") || config.Siad.S3AccessKey != "" {
+		return nil
+	}
+	if !modules.NetAddress(config.Siad.S3Addr).IsLoopback() {
+		return errors.New("you must set --s3-access-key to bind the S3 gateway to a non-localhost address")
+	}
+	return nil
+}
+
+// verifyDAVSecurity checks that the WebDAV server either authenticates
+// requests or only listens on the loopback address.
+func verifyDAVSecurity(config Config) error {
+	if !strings.Contains(config.Siad.Modules, "d") || config.Siad.DAVUser != "" {
+		return nil
+	}
+	if !modules.NetAddress(config.Siad.DAVAddr).IsLoopback() {
+		return errors.New("you must set --dav-user to bind the WebDAV server to a non-localhost address")
+	}
+	return nil
+}
+
 // processNetAddr adds a ':' to a bare integer, so that it is a proper port
 // number.
 func processNetAddr(addr string) string {
@@ -66,7 +94,7 @@ func processNetAddr(addr string) string {
 // invalid module character.
 func processModules(modules string) (string, error) {
 	modules = strings.ToLower(modules)
-	validModules := "cghmrtwe"
+	validModules := "cghmrtwesd"
 	invalidModules := modules
 	for _, m := range validModules {
 		invalidModules = strings.Replace(invalidModules, string(m), "", 1)
@@ -99,16 +127,35 @@ func processConfig(config Config) (Config, error) {
 	config.Siad.APIaddr = processNetAddr(config.Siad.APIaddr)
 	config.Siad.RPCaddr = processNetAddr(config.Siad.RPCaddr)
 	config.Siad.HostAddr = processNetAddr(config.Siad.HostAddr)
+	config.Siad.S3Addr = processNetAddr(config.Siad.S3Addr)
+	config.Siad.DAVAddr = processNetAddr(config.Siad.DAVAddr)
 	config.Siad.Modules, err1 = processModules(config.Siad.Modules)
 	config.Siad.Profile, err2 = processProfileFlags(config.Siad.Profile)
 	err3 := verifyAPISecurity(config)
-	err := build.JoinErrors([]error{err1, err2, err3}, ", and ")
+	err4 := verifyS3Security(config)
+	err5 := verifyDAVSecurity(config)
+	err := build.JoinErrors([]error{err1, err2, err3, err4, err5}, ", and ")
 	if err != nil {
 		return Config{}, err
 	}
 	return config, nil
 }
 
+// loadNetworkConfig reads a network configuration from a json file.
+func loadNetworkConfig(filename string) (*types.NetworkConfig, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var nc types.NetworkConfig
+	err = json.NewDecoder(file).Decode(&nc)
+	if err != nil {
+		return nil, errors.New("could not decode network config: " + err.Error())
+	}
+	return &nc, nil
+}
+
 // startDaemon uses the config parameters to initialize Sia modules and start
 // siad.
 func startDaemon(config Config) (err error) {
@@ -129,6 +176,28 @@ func startDaemon(config Config) (err error) {
 		return err
 	}
 
+	// Prompt user for the secret key of the S3 gateway.
+	if strings.Contains(config.Siad.Modules, "s") && config.Siad.S3AccessKey != "" {
+		config.S3SecretKey, err = speakeasy.Ask("Enter S3 secret key: ")
+		if err != nil {
+			return err
+		}
+		if config.S3SecretKey == "" {
+			return errors.New("S3 secret key cannot be blank")
+		}
+	}
+
+	// Prompt user for the password of the WebDAV server.
+	if strings.Contains(config.Siad.Modules, "d") && config.Siad.DAVUser != "" {
+		config.DAVPassword, err = speakeasy.Ask("Enter WebDAV password: ")
+		if err != nil {
+			return err
+		}
+		if config.DAVPassword == "" {
+			return errors.New("WebDAV password cannot be blank")
+		}
+	}
+
 	// Print a startup message.
 	fmt.Println("Loading...")
 	loadStart := time.Now()
@@ -167,7 +236,16 @@ func startDaemon(config Config) (err error) {
 	if strings.Contains(config.Siad.Modules, "c") {
 		i++
 		fmt.Printf("(%d/%d) Loading consensus...\n", i, len(config.Siad.Modules))
-		cs, err = consensus.New(g, !config.Siad.NoBootstrap, filepath.Join(config.Siad.SiaDir, modules.ConsensusDir))
+		csConfig := consensus.Config{
+			PruneDepth: types.BlockHeight(config.Siad.PruneDepth),
+		}
+		if config.Siad.NetworkConfig != "" {
+			csConfig.Network, err = loadNetworkConfig(config.Siad.NetworkConfig)
+			if err != nil {
+				return err
+			}
+		}
+		cs, err = consensus.NewWithConfig(g, !config.Siad.NoBootstrap, filepath.Join(config.Siad.SiaDir, modules.ConsensusDir), csConfig)
 		if err != nil {
 			return err
 		}
@@ -212,6 +290,7 @@ func startDaemon(config Config) (err error) {
 		}()
 	}
 	var w modules.Wallet
+	var nw modules.NamedWallets
 	if strings.Contains(config.Siad.Modules, "w") {
 		i++
 		fmt.Printf("(%d/%d) Loading wallet...\n", i, len(config.Siad.Modules))
@@ -226,6 +305,27 @@ func startDaemon(config Config) (err error) {
 				fmt.Println("Error during wallet shutdown:", err)
 			}
 		}()
+		if config.Siad.SignerDevice != "" {
+			ds, err := wallet.OpenDeviceSigner(config.Siad.SignerDevice)
+			if err != nil {
+				return err
+			}
+			defer ds.Close()
+			if err := w.AddSigner(ds); err != nil {
+				return err
+			}
+		}
+		nw, err = wallet.NewNamedWallets(cs, tpool, filepath.Join(config.Siad.SiaDir, modules.NamedWalletsDir))
+		if err != nil {
+			return err
+		}
+		defer func() {
+			fmt.Println("Closing named wallets...")
+			err := nw.Close()
+			if err != nil {
+				fmt.Println("Error during named wallet shutdown:", err)
+			}
+		}()
 	}
 	var m modules.Miner
 	if strings.Contains(config.Siad.Modules, "m") {
@@ -276,6 +376,46 @@ func startDaemon(config Config) (err error) {
 		}()
 	}
 
+	if strings.Contains(config.Siad.Modules, "s") {
+		i++
+		fmt.Printf("(%d/%d) Loading S3 gateway...\n", i, len(config.Siad.Modules))
+		creds := modules.S3Credentials{
+			AccessKeyID:     config.Siad.S3AccessKey,
+			SecretAccessKey: config.S3SecretKey,
+		}
+		s3, err := s3gateway.New(r, config.Siad.S3Addr, creds, filepath.Join(config.Siad.SiaDir, modules.S3GatewayDir))
+		if err != nil {
+			return err
+		}
+		defer func() {
+			fmt.Println("Closing S3 gateway...")
+			err := s3.Close()
+			if err != nil {
+				fmt.Println("Error during S3 gateway shutdown:", err)
+			}
+		}()
+	}
+
+	if strings.Contains(config.Siad.Modules, "d") {
+		i++
+		fmt.Printf("(%d/%d) Loading WebDAV server...\n", i, len(config.Siad.Modules))
+		creds := modules.DAVCredentials{
+			Username: config.Siad.DAVUser,
+			Password: config.DAVPassword,
+		}
+		dav, err := davserver.New(r, config.Siad.DAVAddr, creds, filepath.Join(config.Siad.SiaDir, modules.DAVServerDir))
+		if err != nil {
+			return err
+		}
+		defer func() {
+			fmt.Println("Closing WebDAV server...")
+			err := dav.Close()
+			if err != nil {
+				fmt.Println("Error during WebDAV server shutdown:", err)
+			}
+		}()
+	}
+
 	// Create the Sia API
 	a := api.New(
 		config.Siad.RequiredUserAgent,
@@ -288,6 +428,7 @@ func startDaemon(config Config) (err error) {
 		r,
 		tpool,
 		w,
+		nw,
 	)
 
 	// connect the API to the server
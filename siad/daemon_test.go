@@ -39,6 +39,8 @@ func TestUnitProcessModules(t *testing.T) {
 		{"t", "t"},
 		{"w", "w"},
 		{"e", "e"},
+		{"s", "s"},
+		{"d", "d"},
 		{"C", "c"},
 		{"G", "g"},
 		{"H", "h"},
@@ -47,6 +49,8 @@ func TestUnitProcessModules(t *testing.T) {
 		{"T", "t"},
 		{"W", "w"},
 		{"E", "e"},
+		{"S", "s"},
+		{"D", "d"},
 	}
 	for _, testVal := range testVals {
 		out, err := processModules(testVal.in)
@@ -200,3 +204,73 @@ func TestVerifyAPISecurity(t *testing.T) {
 		t.Error("public + securityOff with authentication was rejected:", err)
 	}
 }
+
+// TestVerifyS3Security checks that the verifyS3Security function is correctly
+// banning the use of a non-loopback address for the S3 gateway without an
+// access key.
+func TestVerifyS3Security(t *testing.T) {
+	// Check that the loopback address is accepted without an access key.
+	var loopback Config
+	loopback.Siad.Modules = "s"
+	loopback.Siad.S3Addr = "127.0.0.1:9983"
+	if err := verifyS3Security(loopback); err != nil {
+		t.Error("loopback without access key was rejected:", err)
+	}
+
+	// Check that a public hostname is rejected without an access key.
+	var public Config
+	public.Siad.Modules = "s"
+	public.Siad.S3Addr = "sia.tech:9983"
+	if err := verifyS3Security(public); err == nil {
+		t.Error("public without access key was accepted")
+	}
+
+	// Check that a public hostname is accepted with an access key.
+	public.Siad.S3AccessKey = "foo"
+	if err := verifyS3Security(public); err != nil {
+		t.Error("public with access key was rejected:", err)
+	}
+
+	// Check that the address is ignored if the gateway is not enabled.
+	var disabled Config
+	disabled.Siad.Modules = "r"
+	disabled.Siad.S3Addr = "sia.tech:9983"
+	if err := verifyS3Security(disabled); err != nil {
+		t.Error("address of disabled gateway was rejected:", err)
+	}
+}
+
+// TestVerifyDAVSecurity checks that the verifyDAVSecurity function is
+// correctly banning the use of a non-loopback address for the WebDAV server
+// without a username.
+func TestVerifyDAVSecurity(t *testing.T) {
+	// Check that the loopback address is accepted without a username.
+	var loopback Config
+	loopback.Siad.Modules = "d"
+	loopback.Siad.DAVAddr = "127.0.0.1:9984"
+	if err := verifyDAVSecurity(loopback); err != nil {
+		t.Error("loopback without username was rejected:", err)
+	}
+
+	// Check that a public hostname is rejected without a username.
+	var public Config
+	public.Siad.Modules = "d"
+	public.Siad.DAVAddr = "sia.tech:9984"
+	if err := verifyDAVSecurity(public); err == nil {
+		t.Error("public without username was accepted")
+	}
+
+	// Check that a public hostname is accepted with a username.
+	public.Siad.DAVUser = "foo"
+	if err := verifyDAVSecurity(public); err != nil {
+		t.Error("public with username was rejected:", err)
+	}
+
+	// Check that the address is ignored if the server is not enabled.
+	var disabled Config
+	disabled.Siad.Modules = "r"
+	disabled.Siad.DAVAddr = "sia.tech:9984"
+	if err := verifyDAVSecurity(disabled); err != nil {
+		t.Error("address of disabled server was rejected:", err)
+	}
+}
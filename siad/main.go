@@ -29,18 +29,33 @@ type Config struct {
 	// --authenticate-api flag is set.
 	APIPassword string
 
+	// The S3SecretKey is input by the user after the daemon starts up, if the
+	// S3 gateway is enabled and the --s3-access-key flag is set.
+	S3SecretKey string
+
+	// The DAVPassword is input by the user after the daemon starts up, if the
+	// WebDAV server is enabled and the --dav-user flag is set.
+	DAVPassword string
+
 	// The Siad variables are referenced directly by cobra, and are set
 	// according to the flags.
 	Siad struct {
 		APIaddr      string
 		RPCaddr      string
 		HostAddr     string
+		S3Addr       string
+		DAVAddr      string
 		AllowAPIBind bool
 
 		Modules           string
 		NoBootstrap       bool
+		PruneDepth        uint64
+		NetworkConfig     string
 		RequiredUserAgent string
 		AuthenticateAPI   bool
+		SignerDevice      string
+		S3AccessKey       string
+		DAVUser           string
 
 		Profile    string
 		ProfileDir string
@@ -127,7 +142,25 @@ Explorer (e):
 	the blockchain.
 	The explorer requires the consenus set.
 	Example:
-		siad -M gce`)
+		siad -M gce
+S3 Gateway (s):
+	The S3 gateway serves a subset of the Amazon S3 API on --s3-addr, so
+	that S3 tools can store objects with the renter. Buckets are the
+	top-level directories of the renter. Requests must be signed with
+	--s3-access-key and the secret key that siad asks for, unless no access
+	key is set, in which case the gateway must listen on localhost.
+	The S3 gateway requires the renter.
+	Example:
+		siad -M gctwrs
+WebDAV Server (d):
+	The WebDAV server serves the files of the renter on --dav-addr, so that
+	operating systems can mount them as a network drive. Directories of
+	the renter are collections. Requests must authenticate as --dav-user
+	with the password that siad asks for, unless no user is set, in which
+	case the server must listen on localhost.
+	The WebDAV server requires the renter.
+	Example:
+		siad -M gctwrd`)
 }
 
 // main establishes a set of commands and flags using the cobra package.
@@ -163,8 +196,15 @@ func main() {
 	root.Flags().StringVarP(&globalConfig.Siad.APIaddr, "api-addr", "", "localhost:9980", "which host:port the API server listens on")
 	root.Flags().StringVarP(&globalConfig.Siad.SiaDir, "sia-directory", "d", "", "location of the sia directory")
 	root.Flags().BoolVarP(&globalConfig.Siad.NoBootstrap, "no-bootstrap", "", false, "disable bootstrapping on this run")
+	root.Flags().Uint64VarP(&globalConfig.Siad.PruneDepth, "prune-depth", "", 0, "only keep the bodies of this many recent blocks, 0 keeps every block")
+	root.Flags().StringVarP(&globalConfig.Siad.NetworkConfig, "network-config", "", "", "json file overriding the consensus constants, for private test networks (use with --no-bootstrap)")
+	root.Flags().StringVarP(&globalConfig.Siad.SignerDevice, "signer-device", "", "", "serial or HID device of a hardware wallet that signs for the wallet")
 	root.Flags().StringVarP(&globalConfig.Siad.Profile, "profile", "", "", "enable profiling with flags 'cmt' for CPU, memory, trace")
 	root.Flags().StringVarP(&globalConfig.Siad.RPCaddr, "rpc-addr", "", ":9981", "which port the gateway listens on")
+	root.Flags().StringVarP(&globalConfig.Siad.S3Addr, "s3-addr", "", "localhost:9983", "which host:port the S3 gateway listens on")
+	root.Flags().StringVarP(&globalConfig.Siad.S3AccessKey, "s3-access-key", "", "", "access key ID that requests to the S3 gateway must be signed with")
+	root.Flags().StringVarP(&globalConfig.Siad.DAVAddr, "dav-addr", "", "localhost:9984", "which host:port the WebDAV server listens on")
+	root.Flags().StringVarP(&globalConfig.Siad.DAVUser, "dav-user", "", "", "username that requests to the WebDAV server must authenticate with")
 	root.Flags().StringVarP(&globalConfig.Siad.Modules, "modules", "M", "cghrtw", "enabled modules, see 'siad modules' for more info")
 	root.Flags().BoolVarP(&globalConfig.Siad.AuthenticateAPI, "authenticate-api", "", false, "enable API password protection")
 	root.Flags().BoolVarP(&globalConfig.Siad.AllowAPIBind, "disable-api-security", "", false, "allow siad to listen on a non-localhost address (DANGEROUS)")
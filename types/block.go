@@ -8,6 +8,7 @@ import (
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
 )
 
 const (
@@ -45,6 +46,16 @@ type (
 	BlockHeight uint64
 	BlockID     crypto.Hash
 	BlockNonce  [8]byte
+
+	// A TransactionProof proves that a transaction is a leaf of a block's
+	// Merkle tree. Lightweight clients can use the proof to verify that a
+	// transaction was confirmed while only knowing the block header.
+	TransactionProof struct {
+		Transaction Transaction   `json:"transaction"`
+		LeafIndex   uint64        `json:"leafindex"`
+		NumLeaves   uint64        `json:"numleaves"`
+		HashSet     []crypto.Hash `json:"hashset"`
+	}
 )
 
 // CalculateCoinbase calculates the coinbase for a given height. The coinbase
@@ -142,6 +153,45 @@ func (b Block) MerkleRoot() crypto.Hash {
 	return tree.Root()
 }
 
+// TransactionProof returns a proof that the transaction at index i of the
+// block is a part of the block's Merkle root. The leaves of the Merkle tree
+// are the miner payouts followed by the transactions, so the leaf index of
+// the transaction is offset by the number of miner payouts.
+func (b Block) TransactionProof(i int) TransactionProof {
+	if build.DEBUG && (i < 0 || i >= len(b.Transactions)) {
+		panic("transaction proof requested for out of bounds transaction")
+	}
+	leafIndex := uint64(len(b.MinerPayouts) + i)
+	tree := crypto.NewTree()
+	tree.SetIndex(leafIndex)
+	for _, payout := range b.MinerPayouts {
+		tree.PushObject(payout)
+	}
+	for _, txn := range b.Transactions {
+		tree.PushObject(txn)
+	}
+	_, proofSet, _, numLeaves := tree.Prove()
+
+	// The first element of the proof set is the leaf itself, which is
+	// reconstructed from the transaction during verification.
+	hashSet := make([]crypto.Hash, len(proofSet)-1)
+	for j, p := range proofSet[1:] {
+		copy(hashSet[j][:], p)
+	}
+	return TransactionProof{
+		Transaction: b.Transactions[i],
+		LeafIndex:   leafIndex,
+		NumLeaves:   numLeaves,
+		HashSet:     hashSet,
+	}
+}
+
+// Verify returns true if the proof shows that the transaction is a part of
+// the block with the provided header.
+func (tp TransactionProof) Verify(h BlockHeader) bool {
+	return crypto.VerifySegment(encoding.Marshal(tp.Transaction), tp.HashSet, tp.NumLeaves, tp.LeafIndex, h.MerkleRoot)
+}
+
 // MinerPayoutID returns the ID of the miner payout at the given index, which
 // is calculated by hashing the concatenation of the BlockID and the payout
 // index.
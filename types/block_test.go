@@ -231,3 +231,45 @@ func TestBlockMinerPayoutID(t *testing.T) {
 		knownIDs[id] = struct{}{}
 	}
 }
+
+// TestBlockTransactionProof checks that transaction proofs verify against the
+// header of the block they were created from, and not against other headers.
+func TestBlockTransactionProof(t *testing.T) {
+	b := Block{
+		MinerPayouts: []SiacoinOutput{
+			{Value: CalculateCoinbase(0)},
+			{Value: CalculateCoinbase(1)},
+		},
+	}
+	for i := 0; i < 5; i++ {
+		b.Transactions = append(b.Transactions, Transaction{
+			ArbitraryData: [][]byte{{byte(i)}},
+		})
+	}
+	h := b.Header()
+	for i := range b.Transactions {
+		tp := b.TransactionProof(i)
+		if tp.LeafIndex != uint64(len(b.MinerPayouts)+i) {
+			t.Error("wrong leaf index:", tp.LeafIndex)
+		}
+		if tp.NumLeaves != uint64(len(b.MinerPayouts)+len(b.Transactions)) {
+			t.Error("wrong number of leaves:", tp.NumLeaves)
+		}
+		if !tp.Verify(h) {
+			t.Error("valid transaction proof did not verify", i)
+		}
+
+		// Tamper with the transaction.
+		tp.Transaction.ArbitraryData = [][]byte{{255}}
+		if tp.Verify(h) {
+			t.Error("proof for a modified transaction verified", i)
+		}
+	}
+
+	// A proof should not verify against the header of a different block.
+	tp := b.TransactionProof(0)
+	b.Transactions = b.Transactions[1:]
+	if tp.Verify(b.Header()) {
+		t.Error("transaction proof verified against the wrong header")
+	}
+}
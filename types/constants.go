@@ -47,6 +47,12 @@ var (
 	OakDecayDenom    int64
 	OakMaxRise       *big.Rat
 	OakMaxDrop       *big.Rat
+
+	// Version bits constants. Deployments change state at the end of every
+	// VersionBitsWindow blocks, and lock in when at least VersionBitsThreshold
+	// blocks of a window signal for them.
+	VersionBitsWindow    BlockHeight
+	VersionBitsThreshold uint64
 )
 
 // init checks which build constant is in place and initializes the variables
@@ -77,6 +83,9 @@ func init() {
 		OakMaxRise = big.NewRat(102, 100)
 		OakMaxDrop = big.NewRat(100, 102)
 
+		VersionBitsWindow = 20
+		VersionBitsThreshold = 16
+
 		GenesisSiafundAllocation = []SiafundOutput{
 			{
 				Value:      NewCurrency64(2000),
@@ -119,6 +128,9 @@ func init() {
 		OakMaxRise = big.NewRat(10001, 10e3)
 		OakMaxDrop = big.NewRat(10e3, 10001)
 
+		VersionBitsWindow = 10
+		VersionBitsThreshold = 8
+
 		GenesisSiafundAllocation = []SiafundOutput{
 			{
 				Value:      NewCurrency64(2000),
@@ -222,6 +234,12 @@ func init() {
 		OakMaxRise = big.NewRat(1004, 1e3)
 		OakMaxDrop = big.NewRat(1e3, 1004)
 
+		// Deployments are evaluated every 2016 blocks, or about two weeks,
+		// and lock in once 95% of the blocks in a window signal for them.
+		// This follows the version bits scheme used by Bitcoin.
+		VersionBitsWindow = 2016
+		VersionBitsThreshold = 1916
+
 		GenesisSiafundAllocation = []SiafundOutput{
 			{
 				Value:      NewCurrency64(2),
@@ -414,7 +432,12 @@ func init() {
 		}
 	}
 
-	// Create the genesis block.
+	createGenesisBlock()
+}
+
+// createGenesisBlock creates the genesis block from the genesis timestamp and
+// siafund allocation.
+func createGenesisBlock() {
 	GenesisBlock = Block{
 		Timestamp: GenesisTimestamp,
 		Transactions: []Transaction{
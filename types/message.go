@@ -0,0 +1,60 @@
+package types
+
+// message.go contains the types and functions for signing arbitrary messages
+// with the keys of an address, which lets the owner of an address prove that
+// they control it without moving any coins.
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/crypto"
+)
+
+const (
+	// messageSignaturePrefix is hashed along with a message before it is
+	// signed, so that a message signature can never be used to sign a
+	// transaction or any other object.
+	messageSignaturePrefix = "Sia Signed Message:\n"
+)
+
+var (
+	ErrMessageAddressMismatch  = errors.New("unlock conditions of the message signature do not match the address")
+	ErrInvalidMessageSignature = errors.New("message signature is invalid")
+)
+
+// A MessageSignature is a signature of an arbitrary message by one of the
+// public keys of UnlockConditions. The address whose ownership is proven is the
+// unlock hash of UnlockConditions.
+type MessageSignature struct {
+	UnlockConditions UnlockConditions `json:"unlockconditions"`
+	PublicKeyIndex   uint64           `json:"publickeyindex"`
+	Signature        []byte           `json:"signature"`
+}
+
+// MessageHash returns the hash that is signed by a MessageSignature of msg.
+func MessageHash(msg []byte) crypto.Hash {
+	return crypto.HashAll(messageSignaturePrefix, msg)
+}
+
+// VerifyMessage returns nil if sig is a valid signature of msg by a key of
+// addr. Only ed25519 keys are supported.
+func VerifyMessage(addr UnlockHash, msg []byte, sig MessageSignature) error {
+	if sig.UnlockConditions.UnlockHash() != addr {
+		return ErrMessageAddressMismatch
+	}
+	if sig.PublicKeyIndex >= uint64(len(sig.UnlockConditions.PublicKeys)) {
+		return ErrInvalidPubKeyIndex
+	}
+	spk := sig.UnlockConditions.PublicKeys[sig.PublicKeyIndex]
+	if spk.Algorithm != SignatureEd25519 || len(spk.Key) != crypto.PublicKeySize || len(sig.Signature) != crypto.SignatureSize {
+		return ErrInvalidMessageSignature
+	}
+	var pk crypto.PublicKey
+	var cryptoSig crypto.Signature
+	copy(pk[:], spk.Key)
+	copy(cryptoSig[:], sig.Signature)
+	if crypto.VerifyHash(MessageHash(msg), pk, cryptoSig) != nil {
+		return ErrInvalidMessageSignature
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/crypto"
+)
+
+// TestVerifyMessage checks that message signatures are only valid for the
+// signed message and the address of their unlock conditions.
+func TestVerifyMessage(t *testing.T) {
+	sk, pk := crypto.GenerateKeyPair()
+	uc := UnlockConditions{
+		PublicKeys:         []SiaPublicKey{Ed25519PublicKey(pk)},
+		SignaturesRequired: 1,
+	}
+	addr := uc.UnlockHash()
+	msg := []byte("I control this address")
+	cryptoSig := crypto.SignHash(MessageHash(msg), sk)
+	sig := MessageSignature{
+		UnlockConditions: uc,
+		Signature:        cryptoSig[:],
+	}
+	if err := VerifyMessage(addr, msg, sig); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := VerifyMessage(addr, []byte("something else"), sig); err != ErrInvalidMessageSignature {
+		t.Error("expected ErrInvalidMessageSignature for a different message, got", err)
+	}
+	if err := VerifyMessage(UnlockHash{1}, msg, sig); err != ErrMessageAddressMismatch {
+		t.Error("expected ErrMessageAddressMismatch for a different address, got", err)
+	}
+	badIndex := sig
+	badIndex.PublicKeyIndex = 1
+	if err := VerifyMessage(addr, msg, badIndex); err != ErrInvalidPubKeyIndex {
+		t.Error("expected ErrInvalidPubKeyIndex, got", err)
+	}
+
+	// A transaction signature of the same key is not a message signature.
+	txn := Transaction{
+		ArbitraryData:         [][]byte{msg},
+		TransactionSignatures: []TransactionSignature{{CoveredFields: FullCoveredFields}},
+	}
+	txnSig := crypto.SignHash(txn.SigHash(0), sk)
+	sig.Signature = txnSig[:]
+	if err := VerifyMessage(addr, msg, sig); err != ErrInvalidMessageSignature {
+		t.Error("expected ErrInvalidMessageSignature for a transaction signature, got", err)
+	}
+}
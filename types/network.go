@@ -0,0 +1,56 @@
+package types
+
+import (
+	"errors"
+)
+
+// network.go allows the consensus constants to be overridden at runtime, so
+// that private test networks with fast blocks can be created without
+// recompiling. Fields that are left at their zero value keep the value set by
+// the build tags.
+
+var (
+	// ErrBadSiafundAllocation is returned when a network configuration
+	// allocates a number of siafunds other than SiafundCount.
+	ErrBadSiafundAllocation = errors.New("genesis siafund allocation must add up to the siafund count")
+)
+
+// A NetworkConfig contains the consensus constants of a network. Every node of
+// a network must use the same configuration.
+type NetworkConfig struct {
+	BlockFrequency           BlockHeight     `json:"blockfrequency"`
+	MaturityDelay            BlockHeight     `json:"maturitydelay"`
+	GenesisTimestamp         Timestamp       `json:"genesistimestamp"`
+	GenesisSiafundAllocation []SiafundOutput `json:"genesissiafundallocation"`
+	RootTarget               Target          `json:"roottarget"`
+}
+
+// Apply overrides the consensus constants with the non-zero fields of the
+// configuration and recreates the genesis block. Apply must be called before
+// any module is created, as modules read the constants when they start.
+func (nc NetworkConfig) Apply() error {
+	if len(nc.GenesisSiafundAllocation) != 0 {
+		total := ZeroCurrency
+		for _, sfo := range nc.GenesisSiafundAllocation {
+			total = total.Add(sfo.Value)
+		}
+		if !total.Equals(SiafundCount) {
+			return ErrBadSiafundAllocation
+		}
+		GenesisSiafundAllocation = nc.GenesisSiafundAllocation
+	}
+	if nc.BlockFrequency != 0 {
+		BlockFrequency = nc.BlockFrequency
+	}
+	if nc.MaturityDelay != 0 {
+		MaturityDelay = nc.MaturityDelay
+	}
+	if nc.GenesisTimestamp != 0 {
+		GenesisTimestamp = nc.GenesisTimestamp
+	}
+	if nc.RootTarget != (Target{}) {
+		RootTarget = nc.RootTarget
+	}
+	createGenesisBlock()
+	return nil
+}
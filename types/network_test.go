@@ -0,0 +1,47 @@
+package types
+
+import (
+	"testing"
+)
+
+// TestNetworkConfigApply checks that a network configuration overrides the
+// consensus constants and the genesis block. The test is not parallel, as it
+// modifies the constants.
+func TestNetworkConfigApply(t *testing.T) {
+	oldFrequency, oldDelay, oldTarget := BlockFrequency, MaturityDelay, RootTarget
+	oldTimestamp, oldAllocation := GenesisTimestamp, GenesisSiafundAllocation
+	oldID := GenesisID
+	defer func() {
+		BlockFrequency, MaturityDelay, RootTarget = oldFrequency, oldDelay, oldTarget
+		GenesisTimestamp, GenesisSiafundAllocation = oldTimestamp, oldAllocation
+		createGenesisBlock()
+	}()
+
+	// A siafund allocation that does not add up to the siafund count should
+	// be rejected without modifying the constants.
+	err := NetworkConfig{
+		MaturityDelay:            oldDelay + 1,
+		GenesisSiafundAllocation: []SiafundOutput{{Value: NewCurrency64(1)}},
+	}.Apply()
+	if err != ErrBadSiafundAllocation {
+		t.Fatal("expected ErrBadSiafundAllocation, got", err)
+	}
+	if MaturityDelay != oldDelay {
+		t.Fatal("rejected network config modified the constants")
+	}
+
+	// Zero fields should keep their values.
+	err = NetworkConfig{
+		MaturityDelay:    oldDelay + 1,
+		GenesisTimestamp: oldTimestamp + 1,
+	}.Apply()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if MaturityDelay != oldDelay+1 || BlockFrequency != oldFrequency || RootTarget != oldTarget {
+		t.Fatal("network config was not applied correctly")
+	}
+	if GenesisBlock.Timestamp != oldTimestamp+1 || GenesisID == oldID || GenesisID != GenesisBlock.ID() {
+		t.Fatal("genesis block was not recreated")
+	}
+}
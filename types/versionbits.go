@@ -0,0 +1,69 @@
+package types
+
+// versionbits.go defines how miners signal readiness for rule changes. Sia
+// block headers have no version field, and adding one would change the block
+// id, so blocks signal through the arbitrary data of their transactions
+// instead. A signal is the SpecifierVersionBits specifier followed by a 64 bit
+// mask, where each set bit signals for the deployment using that bit.
+
+import (
+	"bytes"
+
+	"github.com/NebulousLabs/Sia/encoding"
+)
+
+type (
+	// A Deployment is a rule change that is activated by miner signaling.
+	// Blocks signal for a deployment between StartHeight and TimeoutHeight.
+	// If not enough blocks signal by TimeoutHeight, the deployment fails.
+	Deployment struct {
+		Name          string
+		Bit           uint8
+		StartHeight   BlockHeight
+		TimeoutHeight BlockHeight
+	}
+)
+
+var (
+	// SpecifierVersionBits is the prefix of arbitrary data that signals for
+	// deployments.
+	SpecifierVersionBits = Specifier{'v', 'e', 'r', 's', 'i', 'o', 'n', ' ', 'b', 'i', 't', 's'}
+
+	// Deployments is the set of deployments known to this version of Sia.
+	// Each deployment must use a different bit, between 0 and 63.
+	Deployments []Deployment
+)
+
+// VersionBitsArbitraryData returns the arbitrary data that signals for the
+// deployments whose bits are set in 'bits'.
+func VersionBitsArbitraryData(bits uint64) []byte {
+	return append(SpecifierVersionBits[:], encoding.Marshal(bits)...)
+}
+
+// SignalingBits returns the bits of the deployments that a block at the given
+// height should signal for.
+func SignalingBits(height BlockHeight) (bits uint64) {
+	for _, d := range Deployments {
+		if height >= d.StartHeight && height < d.TimeoutHeight {
+			bits |= 1 << d.Bit
+		}
+	}
+	return bits
+}
+
+// VersionBits returns the deployment bits that the block signals for.
+// Malformed signals are ignored.
+func (b Block) VersionBits() (bits uint64) {
+	for _, txn := range b.Transactions {
+		for _, arb := range txn.ArbitraryData {
+			if len(arb) != SpecifierLen+8 || !bytes.HasPrefix(arb, SpecifierVersionBits[:]) {
+				continue
+			}
+			var signal uint64
+			if encoding.Unmarshal(arb[SpecifierLen:], &signal) == nil {
+				bits |= signal
+			}
+		}
+	}
+	return bits
+}
@@ -0,0 +1,58 @@
+package types
+
+import (
+	"testing"
+)
+
+// TestBlockVersionBits checks that the version bits of a block are read from
+// the arbitrary data of its transactions.
+func TestBlockVersionBits(t *testing.T) {
+	var b Block
+	if b.VersionBits() != 0 {
+		t.Fatal("empty block signals for deployments")
+	}
+
+	b.Transactions = []Transaction{
+		{ArbitraryData: [][]byte{[]byte("unrelated"), VersionBitsArbitraryData(1 << 3)}},
+		{ArbitraryData: [][]byte{VersionBitsArbitraryData(1 << 5)}},
+	}
+	if b.VersionBits() != 1<<3|1<<5 {
+		t.Fatal("wrong version bits:", b.VersionBits())
+	}
+
+	// Signals with trailing data are malformed and ignored.
+	b.Transactions = []Transaction{
+		{ArbitraryData: [][]byte{append(VersionBitsArbitraryData(1), 0)}},
+	}
+	if b.VersionBits() != 0 {
+		t.Fatal("malformed signal was not ignored")
+	}
+}
+
+// TestSignalingBits checks that blocks signal for deployments only between
+// their start and timeout heights.
+func TestSignalingBits(t *testing.T) {
+	defer func(deployments []Deployment) {
+		Deployments = deployments
+	}(Deployments)
+	Deployments = []Deployment{
+		{Name: "a", Bit: 0, StartHeight: 10, TimeoutHeight: 20},
+		{Name: "b", Bit: 4, StartHeight: 15, TimeoutHeight: 30},
+	}
+
+	tests := []struct {
+		height BlockHeight
+		bits   uint64
+	}{
+		{9, 0},
+		{10, 1},
+		{15, 1 | 1<<4},
+		{20, 1 << 4},
+		{30, 0},
+	}
+	for _, test := range tests {
+		if bits := SignalingBits(test.height); bits != test.bits {
+			t.Errorf("wrong bits at height %v: got %b, expected %b", test.height, bits, test.bits)
+		}
+	}
+}